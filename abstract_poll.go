@@ -17,33 +17,268 @@ package gopolls
 import (
 	"fmt"
 	"reflect"
+	"sort"
 )
 
 // AbstractPoll describes any poll.
 // It has a method PollType which returns the type as a string.
 // Most operations dealing with polls do type assertions / switches and operate depending on the string of PollType().
 //
-// Constants are defined for implemented poll types: MedianPollType, SchulzePollType and BasicPollType.
+// Constants are defined for implemented poll types: MedianPollType, SchulzePollType, BasicPollType and
+// ScorePollType.
 //
 // The method AddVote should add a vote to the poll and return an error if the type is not supported
 // (of type PollError).
 // This method is not allowed to be called concurrently by multiple goroutines for the same poll
-// instance.
+// instance, use NewSynchronizedPoll if votes must be added concurrently.
 //
 // It is also recommended to implement the VoteGenerator interface to create votes for Aye, No and Abstention
 // for a given poll.
+// AddVote usually accepts all votes of the correct type, even if they're not valid for the poll (for example a
+// ranking with the wrong length). To reject such votes before adding them implement VoteValidator.
 type AbstractPoll interface {
 	PollType() string
 	AddVote(vote AbstractVote) error
 }
 
+// VoteValidator is used to describe polls that can test if a vote is valid before it is added.
+//
+// ValidateVote must not change the poll, it should return a PollTypeError if the vote has the wrong type and a
+// PollingSemanticError if the vote is not valid for the poll.
+// All polls implemented at the moment also implement this interface.
+type VoteValidator interface {
+	AbstractPoll
+	ValidateVote(vote AbstractVote) error
+}
+
+// VoteIterator is used to describe polls that give access to their votes without a type switch on the poll type.
+//
+// ForEachVote calls f for each vote in the poll (in the order in which the votes appear in the poll), if f returns
+// an error the iteration stops and the error is returned. f must not change the poll. NumVotes returns the number of
+// votes in the poll.
+// All polls implemented at the moment also implement this interface, see CollectVotes and VoteCount for helpers.
+type VoteIterator interface {
+	AbstractPoll
+	ForEachVote(f func(vote AbstractVote) error) error
+	NumVotes() int
+}
+
+// VoteEditor is used to describe polls that allow to correct votes after they have been added.
+//
+// HasVoteFrom returns true if the voter with the given name has a vote in the poll (by default AddVote doesn't check
+// for duplicates, so a voter can have multiple votes, see DuplicateVoterGuard). RemoveVote removes the vote of a
+// voter and returns it, if the voter has no vote a VoteNotFoundError is returned. ReplaceVote replaces the vote of
+// the voter of vote, if the voter has no vote yet it is added.
+// All polls implemented at the moment also implement this interface.
+type VoteEditor interface {
+	AbstractPoll
+	HasVoteFrom(voterName string) bool
+	RemoveVote(voterName string) (AbstractVote, error)
+	ReplaceVote(vote AbstractVote) error
+}
+
+// indexedVotes gives access to the votes of a poll by their index, it is implemented by all polls of this package.
+// It is used by the helpers that are shared by these polls (forEachVote and voteIndex), so each poll only has to
+// implement the access to its slice of votes.
+type indexedVotes interface {
+	NumVotes() int
+	voteAt(i int) AbstractVote
+}
+
+// forEachVote implements VoteIterator.ForEachVote for the polls of this package, it calls f for each vote in the
+// order of the indices.
+func forEachVote(votes indexedVotes, f func(vote AbstractVote) error) error {
+	for i := 0; i < votes.NumVotes(); i++ {
+		if err := f(votes.voteAt(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// voteIndex returns the index of the first vote of the voter with the given name or -1 if there is no such vote.
+func voteIndex(votes indexedVotes, voterName string) int {
+	for i := 0; i < votes.NumVotes(); i++ {
+		if voter := votes.voteAt(i).GetVoter(); voter != nil && voter.Name == voterName {
+			return i
+		}
+	}
+	return -1
+}
+
+// CollectVotes returns all votes of a poll that implements VoteIterator.
+//
+// The returned slice is a new slice, so changing it doesn't change the poll. For a SynchronizedPoll the votes of the
+// wrapped poll are collected while holding the lock. If the poll doesn't implement VoteIterator a PollTypeError is
+// returned.
+func CollectVotes(poll AbstractPoll) ([]AbstractVote, error) {
+	if syncPoll, isSync := poll.(SynchronizedPoll); isSync {
+		var res []AbstractVote
+		err := syncPoll.WithLock(func(wrapped AbstractPoll) error {
+			var collectErr error
+			res, collectErr = CollectVotes(wrapped)
+			return collectErr
+		})
+		return res, err
+	}
+	iterator, ok := poll.(VoteIterator)
+	if !ok {
+		return nil, NewPollTypeError("can't get votes for poll of type %s", reflect.TypeOf(poll))
+	}
+	res := make([]AbstractVote, 0, iterator.NumVotes())
+	// the function never returns an error
+	_ = iterator.ForEachVote(func(vote AbstractVote) error {
+		res = append(res, vote)
+		return nil
+	})
+	return res, nil
+}
+
+// VoteCount returns the number of votes of a poll that implements VoteIterator.
+//
+// A SynchronizedPoll is handled as in CollectVotes. If the poll doesn't implement VoteIterator a PollTypeError is
+// returned.
+func VoteCount(poll AbstractPoll) (int, error) {
+	if syncPoll, isSync := poll.(SynchronizedPoll); isSync {
+		var res int
+		err := syncPoll.WithLock(func(wrapped AbstractPoll) error {
+			var countErr error
+			res, countErr = VoteCount(wrapped)
+			return countErr
+		})
+		return res, err
+	}
+	iterator, ok := poll.(VoteIterator)
+	if !ok {
+		return -1, NewPollTypeError("can't count votes for poll of type %s", reflect.TypeOf(poll))
+	}
+	return iterator.NumVotes(), nil
+}
+
+// checkedWeightSum returns the sum of the effective weights of the voters of all votes for which include returns
+// true (nil includes all votes). If the sum overflows an error wrapping ErrWeightOverflow is returned.
+//
+// It implements CheckedWeightSum for all polls.
+func checkedWeightSum(iterator VoteIterator, include func(vote AbstractVote) bool) (Weight, error) {
+	var sum Weight
+	err := iterator.ForEachVote(func(vote AbstractVote) error {
+		if include != nil && !include(vote) {
+			return nil
+		}
+		var addErr error
+		sum, addErr = addEffectiveWeight(sum, vote.GetVoter())
+		return addErr
+	})
+	if err != nil {
+		return NoWeight, err
+	}
+	return sum, nil
+}
+
+// FilterVotes returns a new poll that contains only the votes of poll for which keep returns true, it is used to
+// evaluate a poll for a subset of the voters (for example only the voters that were present when the poll took
+// place).
+//
+// The filtered poll is created with DeepClone, so the original poll is not changed and the votes of the new poll
+// point to the same voters as the original. The order of the votes is preserved and sealed votes are filtered as
+// well, thus the results of the new poll are the same as the results of a new poll to which only the kept votes
+// were added.
+// For a SynchronizedPoll the wrapped poll is filtered while holding the lock, the result is not synchronized.
+//
+// Supported polls are *BasicPoll, *MedianPoll, *SchulzePoll and *ScorePoll, for all other types a PollTypeError is
+// returned.
+func FilterVotes(poll AbstractPoll, keep func(voter *Voter) bool) (AbstractPoll, error) {
+	if syncPoll, isSync := poll.(SynchronizedPoll); isSync {
+		var res AbstractPoll
+		err := syncPoll.WithLock(func(wrapped AbstractPoll) error {
+			var filterErr error
+			res, filterErr = FilterVotes(wrapped, keep)
+			return filterErr
+		})
+		return res, err
+	}
+	switch typedPoll := poll.(type) {
+	case *BasicPoll:
+		res := typedPoll.DeepClone()
+		votes := res.Votes[:0]
+		for _, vote := range res.Votes {
+			if keep(vote.Voter) {
+				votes = append(votes, vote)
+			}
+		}
+		res.Votes = votes
+		res.SealedVoteBuffer.filter(keep)
+		return res, nil
+	case *MedianPoll:
+		res := typedPoll.DeepClone()
+		votes := res.Votes[:0]
+		for _, vote := range res.Votes {
+			if keep(vote.Voter) {
+				votes = append(votes, vote)
+			}
+		}
+		res.Votes = votes
+		res.SealedVoteBuffer.filter(keep)
+		return res, nil
+	case *SchulzePoll:
+		res := typedPoll.DeepClone()
+		votes := res.Votes[:0]
+		for _, vote := range res.Votes {
+			if keep(vote.Voter) {
+				votes = append(votes, vote)
+			}
+		}
+		res.Votes = votes
+		res.SealedVoteBuffer.filter(keep)
+		return res, nil
+	case *ScorePoll:
+		res := typedPoll.DeepClone()
+		votes := res.Votes[:0]
+		for _, vote := range res.Votes {
+			if keep(vote.Voter) {
+				votes = append(votes, vote)
+			}
+		}
+		res.Votes = votes
+		res.SealedVoteBuffer.filter(keep)
+		return res, nil
+	default:
+		return nil, NewPollTypeError("can't filter votes of poll of type %s", reflect.TypeOf(poll))
+	}
+}
+
 // PollMap is a mapping from poll name to the poll with that name.
 type PollMap map[string]AbstractPoll
 
+// SortedNames returns the names of all polls in the map, sorted in increasing order.
+//
+// It should be used whenever the iteration order is visible to the user (output or error messages).
+func (polls PollMap) SortedNames() []string {
+	res := make([]string, 0, len(polls))
+	for name := range polls {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// restrictToGroup returns a new map containing only the polls for the skeletons in group.
+func (polls PollMap) restrictToGroup(group *PollGroup) PollMap {
+	res := make(PollMap, len(group.Skeletons))
+	for _, skel := range group.Skeletons {
+		name := skel.GetName()
+		if poll, has := polls[name]; has {
+			res[name] = poll
+		}
+	}
+	return res
+}
+
 const (
 	MedianPollType  = "median-poll"
 	SchulzePollType = "schulze-poll"
 	BasicPollType   = "basic-poll"
+	ScorePollType   = "score-poll"
 )
 
 // PollTypeError is an error returned if a skeleton / poll has an invalid  / unsupported type, for example if a
@@ -88,6 +323,7 @@ type SkeletonConverter func(skel AbstractPollSkeleton) (AbstractPoll, error)
 // NewDefaultSkeletonConverter is a generator function that returns a new SkeletonConverter.
 // It does the following translations:
 // A MoneyPollSkel gets translated to a MedianPol, it checks if the value described is >= 0 (< 0 is not allowed).
+// The min value and step of the skeleton are copied to the poll (MedianPoll.MinValue and MedianPoll.Step).
 // A PollSkeleton is translated to a BasicPoll or SchulzePoll.
 // A BasicPoll is returned if the PollSkeleton has exactly two options,otherwise a SchulzePoll is created.
 // If the number of options in the PollSkeleton is < 2 an error is returned.
@@ -97,8 +333,73 @@ type SkeletonConverter func(skel AbstractPollSkeleton) (AbstractPoll, error)
 // Note: A poll with two options is independent of the actual content of the two options, it is assumed that the first
 // option represents Aye/Yes in some way and the second one No.
 func NewDefaultSkeletonConverter(convertToBasic bool) SkeletonConverter {
+	return NewDefaultSkeletonConverterWithNoOption(convertToBasic, LastSchulzeNoOption)
+}
+
+// LastSchulzeNoOption can be used with NewDefaultSkeletonConverterWithNoOption to set the NoOptionIndex of each
+// SchulzePoll to its last option.
+const LastSchulzeNoOption = -2
+
+// NewDefaultSkeletonConverterWithNoOption works as NewDefaultSkeletonConverter but sets the NoOptionIndex of each
+// created SchulzePoll to noOptionIndex.
+// LastSchulzeNoOption sets it to the last option (this is the default of NewDefaultSkeletonConverter) and
+// NoSchulzeNoOption creates polls without a no option.
+// If noOptionIndex is not a valid option index for a poll a PollTypeError is returned.
+//
+// The metadata of the skeleton is copied to the poll, see PollMetadata.
+func NewDefaultSkeletonConverterWithNoOption(convertToBasic bool, noOptionIndex int) SkeletonConverter {
+	opts := NewConverterOptions()
+	if !convertToBasic {
+		opts.BasicThreshold = 0
+	}
+	opts.NoOptionIndex = noOptionIndex
+	return NewSkeletonConverterWithOptions(opts)
+}
+
+// ConverterOptions are used to create a SkeletonConverter with NewSkeletonConverterWithOptions, they describe how a
+// PollSkeleton is converted (a MoneyPollSkeleton is always converted to a MedianPoll).
+//
+// A PollSkeleton with at most BasicThreshold options is converted to a BasicPoll, a skeleton with more options to a
+// SchulzePoll. The BasicPoll has the fixed answers Aye, No and Abstention independent of the options of the skeleton,
+// thus it is assumed that the first option represents Aye in some way and the second one No (additional options, for
+// example an explicit abstention, are ignored). A threshold < 2 disables BasicPolls.
+// If ForceSchulze[name] is true the skeleton with this name is always converted to a SchulzePoll.
+// NoOptionIndex is the NoOptionIndex of each created SchulzePoll, see NewDefaultSkeletonConverterWithNoOption.
+//
+// Hook is consulted first for each PollSkeleton (if it is not nil): If it returns a poll or an error they're returned
+// by the converter, if it returns nil, nil the skeleton is converted as described above. This way single polls can be
+// converted differently, for example to a ScorePoll.
+type ConverterOptions struct {
+	BasicThreshold int
+	ForceSchulze   map[string]bool
+	NoOptionIndex  int
+	Hook           func(skel *PollSkeleton) (AbstractPoll, error)
+}
+
+// NewConverterOptions returns the options of DefaultSkeletonConverter: BasicThreshold is 2, ForceSchulze is empty,
+// NoOptionIndex is LastSchulzeNoOption and Hook is nil.
+func NewConverterOptions() ConverterOptions {
+	return ConverterOptions{
+		BasicThreshold: 2,
+		ForceSchulze:   make(map[string]bool),
+		NoOptionIndex:  LastSchulzeNoOption,
+		Hook:           nil,
+	}
+}
+
+// NewSkeletonConverterWithOptions returns a SkeletonConverter that converts the skeletons as described by opts, see
+// ConverterOptions. Otherwise it works as NewDefaultSkeletonConverter, DefaultSkeletonConverter uses the options
+// returned by NewConverterOptions.
+//
+// The metadata of the skeleton is copied to the poll, see PollMetadata.
+func NewSkeletonConverterWithOptions(opts ConverterOptions) SkeletonConverter {
 	return func(skel AbstractPollSkeleton) (AbstractPoll, error) {
-		return defaultSkeletonConverterGenerator(convertToBasic, skel)
+		poll, err := convertSkeletonWithOptions(opts, skel)
+		if err != nil {
+			return nil, err
+		}
+		copySkeletonMeta(skel, poll)
+		return poll, nil
 	}
 }
 
@@ -112,7 +413,7 @@ func NewDefaultSkeletonConverter(convertToBasic bool) SkeletonConverter {
 // It is just NewDefaultSkeletonConverter(true).
 var DefaultSkeletonConverter = NewDefaultSkeletonConverter(true)
 
-func defaultSkeletonConverterGenerator(convertToBasic bool, skel AbstractPollSkeleton) (AbstractPoll, error) {
+func convertSkeletonWithOptions(opts ConverterOptions, skel AbstractPollSkeleton) (AbstractPoll, error) {
 	switch typedSkel := skel.(type) {
 	case *MoneyPollSkeleton:
 		value := typedSkel.Value
@@ -121,22 +422,44 @@ func defaultSkeletonConverterGenerator(convertToBasic bool, skel AbstractPollSke
 				NewPollTypeError("value for median poll is not allowed to be < 0! got %d for poll \"%s\"",
 					value.ValueCents, typedSkel.Name)
 		}
-		return NewMedianPoll(MedianUnit(value.ValueCents), make([]*MedianVote, 0, defaultVotesSize)), nil
+		if typedSkel.MinValue.ValueCents < 0 || typedSkel.Step.ValueCents < 0 {
+			return nil,
+				NewPollTypeError("min value and step for median poll are not allowed to be < 0! got %d and %d for poll \"%s\"",
+					typedSkel.MinValue.ValueCents, typedSkel.Step.ValueCents, typedSkel.Name)
+		}
+		poll := NewMedianPoll(MedianUnit(value.ValueCents), make([]*MedianVote, 0, defaultVotesSize))
+		poll.MinValue, poll.Step = MedianUnit(typedSkel.MinValue.ValueCents), MedianUnit(typedSkel.Step.ValueCents)
+		return poll, nil
 
 	case *PollSkeleton:
+		if opts.Hook != nil {
+			poll, hookErr := opts.Hook(typedSkel)
+			if hookErr != nil || poll != nil {
+				return poll, hookErr
+			}
+		}
 		numOptions := len(typedSkel.Options)
-		switch numOptions {
-		case 0, 1:
+		switch {
+		case numOptions < 2:
 			return nil,
 				NewPollTypeError("got only %d options, but at least two options are required. poll is \"%s\"",
 					numOptions, typedSkel.Name)
-		case 2:
-			if convertToBasic {
-				return NewBasicPoll(make([]*BasicVote, 0, defaultVotesSize)), nil
-			}
-			fallthrough
+		case numOptions <= opts.BasicThreshold && !opts.ForceSchulze[typedSkel.Name]:
+			return NewBasicPoll(make([]*BasicVote, 0, defaultVotesSize)), nil
 		default:
-			return NewSchulzePoll(numOptions, make([]*SchulzeVote, 0, defaultVotesSize)), nil
+			poll := NewSchulzePoll(numOptions, make([]*SchulzeVote, 0, defaultVotesSize))
+			noOptionIndex := opts.NoOptionIndex
+			switch {
+			case noOptionIndex == LastSchulzeNoOption:
+				// already set by NewSchulzePoll
+			case noOptionIndex == NoSchulzeNoOption || (noOptionIndex >= 0 && noOptionIndex < numOptions):
+				poll.NoOptionIndex = noOptionIndex
+			default:
+				return nil,
+					NewPollTypeError("invalid index %d for no option, poll \"%s\" has %d options",
+						noOptionIndex, typedSkel.Name, numOptions)
+			}
+			return poll, nil
 		}
 	default:
 		return nil, NewPollTypeError("only money polls (median) and basic polls (e.g. normal poll, schulze are supported). Got type %s",
@@ -148,6 +471,7 @@ func defaultSkeletonConverterGenerator(convertToBasic bool, skel AbstractPollSke
 // It uses a SkeletonConverter function to do the actual conversion and returns an error if any of the skeletons
 // in the list is not "valid".
 // If converterFunction is nil DefaultSkeletonConverter is used.
+// If the converter doesn't set the metadata of a poll the metadata of the skeleton is copied, see PollMetadata.
 //
 // ConvertSkeletonMapToEmptyPolls is a function that does the same for maps.
 func ConvertSkeletonsToPolls(skeletons []AbstractPollSkeleton, converterFunction SkeletonConverter) ([]AbstractPoll, error) {
@@ -161,6 +485,7 @@ func ConvertSkeletonsToPolls(skeletons []AbstractPollSkeleton, converterFunction
 		if pollErr != nil {
 			return nil, pollErr
 		}
+		copySkeletonMeta(skeleton, emptyPoll)
 		res[i] = emptyPoll
 	}
 
@@ -171,8 +496,10 @@ func ConvertSkeletonsToPolls(skeletons []AbstractPollSkeleton, converterFunction
 // It uses a SkeletonConverter function to do the actual conversion and returns an error if any of the skeletons
 // in the map is not "valid".
 // If converterFunction is nil DefaultSkeletonConverter is used.
+// The metadata is copied as in ConvertSkeletonsToPolls.
 //
-// ConvertSkeletonsToPolls is a function that does the same for lists.
+// ConvertSkeletonsToPolls is a function that does the same for lists, ConvertCollection converts a whole collection
+// and keeps the groups.
 func ConvertSkeletonMapToEmptyPolls(skeletons PollSkeletonMap, converterFunction SkeletonConverter) (PollMap, error) {
 	if converterFunction == nil {
 		converterFunction = DefaultSkeletonConverter
@@ -184,6 +511,7 @@ func ConvertSkeletonMapToEmptyPolls(skeletons PollSkeletonMap, converterFunction
 		if pollErr != nil {
 			return nil, pollErr
 		}
+		copySkeletonMeta(skeleton, emptyPoll)
 		res[name] = emptyPoll
 	}
 
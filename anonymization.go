@@ -0,0 +1,230 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// AnonymizationMap is the reversible mapping created by an Anonymizer.
+//
+// Pseudonyms maps the name of each original voter to its pseudonymous copy, Originals maps each pseudonym to the
+// original voter.
+// Delegation sources that are not voters themselves get a pseudonym too, DelegationSources maps these pseudonyms to
+// the original names.
+type AnonymizationMap struct {
+	Pseudonyms        map[string]*Voter
+	Originals         map[string]*Voter
+	DelegationSources map[string]string
+}
+
+// Pseudonym returns the pseudonym for the voter with the given name and true, or "" and false if the voter is
+// unknown.
+func (mapping AnonymizationMap) Pseudonym(name string) (string, bool) {
+	voter, has := mapping.Pseudonyms[name]
+	if !has {
+		return "", false
+	}
+	return voter.Name, true
+}
+
+// Original returns the name of the original voter for a pseudonym and true, or "" and false if the pseudonym is
+// unknown.
+func (mapping AnonymizationMap) Original(pseudonym string) (string, bool) {
+	voter, has := mapping.Originals[pseudonym]
+	if !has {
+		return "", false
+	}
+	return voter.Name, true
+}
+
+// DefaultPseudonymPrefix is the prefix used by NewAnonymizer.
+const DefaultPseudonymPrefix = "Voter "
+
+// Anonymizer creates pseudonymous copies of voters, see Anonymize.
+//
+// Prefix is the prefix of each pseudonym, it is followed by a number with at least three digits (for example
+// "Voter 001").
+// If Rand is not nil the voters are shuffled before the numbers are assigned, thus the pseudonyms don't reveal the
+// order of the original voters (which is often sorted by name).
+// If Rand is nil the numbers are assigned in the order of the voters.
+type Anonymizer struct {
+	Prefix string
+	Rand   *rand.Rand
+}
+
+// NewAnonymizer returns a new Anonymizer with prefix DefaultPseudonymPrefix that shuffles the voters.
+func NewAnonymizer() *Anonymizer {
+	return &Anonymizer{
+		Prefix: DefaultPseudonymPrefix,
+		Rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Anonymize returns pseudonymous copies of voters (in the same order as voters) and the mapping between original
+// voters and pseudonyms.
+//
+// The copies have the same weight as the original voters, the sources of their delegations are replaced by
+// pseudonyms as well.
+// The original voters are not changed.
+// If a voter name appears multiple times a DuplicateError is returned.
+//
+// To anonymize a VoterMap use the voters of VoterMap.SortedNames.
+func (anonymizer *Anonymizer) Anonymize(voters []*Voter) ([]*Voter, AnonymizationMap, error) {
+	mapping := AnonymizationMap{
+		Pseudonyms:        make(map[string]*Voter, len(voters)),
+		Originals:         make(map[string]*Voter, len(voters)),
+		DelegationSources: make(map[string]string),
+	}
+	for _, voter := range voters {
+		if _, has := mapping.Pseudonyms[voter.Name]; has {
+			return nil, AnonymizationMap{}, NewDuplicateError(fmt.Sprintf("duplicate entry for voter %s", voter.Name))
+		}
+		mapping.Pseudonyms[voter.Name] = nil
+	}
+	// collect names that need a pseudonym: all voters and delegation sources that are no voters
+	names := make([]string, 0, len(voters))
+	for _, voter := range voters {
+		names = append(names, voter.Name)
+	}
+	otherSources := make(map[string]struct{})
+	for _, voter := range voters {
+		for _, delegation := range voter.Delegations {
+			if _, isVoter := mapping.Pseudonyms[delegation.Source]; isVoter {
+				continue
+			}
+			if _, has := otherSources[delegation.Source]; !has {
+				otherSources[delegation.Source] = struct{}{}
+				names = append(names, delegation.Source)
+			}
+		}
+	}
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	if anonymizer.Rand != nil {
+		anonymizer.Rand.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+	}
+	digits := len(strconv.Itoa(len(names)))
+	if digits < 3 {
+		digits = 3
+	}
+	pseudonyms := make(map[string]string, len(names))
+	for number, index := range order {
+		name := names[index]
+		pseudonym := fmt.Sprintf("%s%0*d", anonymizer.Prefix, digits, number+1)
+		pseudonyms[name] = pseudonym
+		if _, isOtherSource := otherSources[name]; isOtherSource {
+			mapping.DelegationSources[pseudonym] = name
+		}
+	}
+
+	res := make([]*Voter, len(voters))
+	for i, voter := range voters {
+		pseudonymous := NewVoter(pseudonyms[voter.Name], voter.Weight)
+		pseudonymous.FractionalWeight = voter.FractionalWeight
+		if len(voter.Delegations) > 0 {
+			pseudonymous.Delegations = make([]Delegation, len(voter.Delegations))
+			for j, delegation := range voter.Delegations {
+				pseudonymous.Delegations[j] = NewDelegation(pseudonyms[delegation.Source], delegation.Weight)
+			}
+		}
+		res[i] = pseudonymous
+		mapping.Pseudonyms[voter.Name] = pseudonymous
+		mapping.Originals[pseudonymous.Name] = voter
+	}
+	return res, mapping, nil
+}
+
+// setVoterOfVote sets the voter of a vote of one of the types implemented in this package.
+func setVoterOfVote(vote AbstractVote, voter *Voter) error {
+	switch typedVote := vote.(type) {
+	case *BasicVote:
+		typedVote.Voter = voter
+	case *MedianVote:
+		typedVote.Voter = voter
+	case *SchulzeVote:
+		typedVote.Voter = voter
+	case *ScoreVote:
+		typedVote.Voter = voter
+	default:
+		return NewPollTypeError("can't set voter of vote of type %s", reflect.TypeOf(vote))
+	}
+	return nil
+}
+
+// replaceVoters replaces the voter of each vote in polls by the voter in replacements (looked up by name).
+// The voter index of each poll is reset afterwards (see DuplicateVoterGuard.ResetVoterIndex).
+// All replacements are computed first, thus if an error is returned the polls are unchanged.
+func replaceVoters(polls PollMap, replacements map[string]*Voter) error {
+	type replacement struct {
+		vote  AbstractVote
+		voter *Voter
+	}
+	var todo []replacement
+	for _, pollName := range polls.SortedNames() {
+		votes, votesErr := CollectVotes(polls[pollName])
+		if votesErr != nil {
+			return votesErr
+		}
+		for _, vote := range votes {
+			name := vote.GetVoter().Name
+			newVoter, has := replacements[name]
+			if !has {
+				return NewPollingSemanticError(nil, "voter \"%s\" in poll \"%s\" not found in anonymization mapping",
+					name, pollName)
+			}
+			todo = append(todo, replacement{vote: vote, voter: newVoter})
+		}
+	}
+	for _, r := range todo {
+		if setErr := setVoterOfVote(r.vote, r.voter); setErr != nil {
+			return setErr
+		}
+	}
+	// the names of the voters changed, so the indices of the DuplicateVoterGuards are outdated
+	for _, poll := range polls {
+		if resetter, ok := unwrapSynchronized(poll).(interface{ ResetVoterIndex() }); ok {
+			resetter.ResetVoterIndex()
+		}
+	}
+	return nil
+}
+
+// ApplyAnonymization replaces the voter of each vote in polls by its pseudonymous copy from mapping.
+//
+// After that all results computed from the polls (for example MedianResult.ValueDetails and
+// BasicPollResult.VotersByChoice) contain only the pseudonymous voters, tallies are not changed because the weights
+// are retained.
+// The votes are changed in place.
+//
+// If a voter is not found in mapping a PollingSemanticError is returned, for polls of unsupported types a
+// PollTypeError. In both cases the polls are not changed.
+func ApplyAnonymization(polls PollMap, mapping AnonymizationMap) error {
+	return replaceVoters(polls, mapping.Pseudonyms)
+}
+
+// RevertAnonymization is the inverse of ApplyAnonymization, it replaces each pseudonymous voter by the original
+// voter from mapping.
+func RevertAnonymization(polls PollMap, mapping AnonymizationMap) error {
+	return replaceVoters(polls, mapping.Originals)
+}
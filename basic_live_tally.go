@@ -0,0 +1,173 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// liveTallyEntry stores the position of a vote in the poll and the weight it was counted with.
+type liveTallyEntry struct {
+	index  int
+	weight Weight
+}
+
+// BasicPollLiveTally wraps a BasicPoll and keeps its result up to date while votes are added and removed, each
+// operation runs in O(1).
+//
+// Each voter (identified by name) can have at most one vote, adding a vote for a voter that already voted replaces
+// the earlier vote.
+// The counters are updated with the weight the vote was counted with, so changing the weight of a voter after its vote
+// was added doesn't make the counters inconsistent.
+// The result is always equal to the result of calling Tally on the wrapped poll, but note that RemoveVote changes the
+// order of the votes in the poll.
+//
+// The poll must not be changed directly while it is wrapped by a live tally, a live tally is not safe for concurrent
+// use.
+type BasicPollLiveTally struct {
+	poll    *BasicPoll
+	result  *BasicPollResult
+	entries map[string]liveTallyEntry
+}
+
+// NewBasicPollLiveTally returns a new live tally for poll, the votes already in the poll are counted.
+//
+// If a voter appears multiple times in poll a DuplicateError is returned, if the weights overflow an error wrapping
+// ErrWeightOverflow.
+func NewBasicPollLiveTally(poll *BasicPoll) (*BasicPollLiveTally, error) {
+	res := poll.Tally()
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	entries := make(map[string]liveTallyEntry, len(poll.Votes))
+	for i, vote := range poll.Votes {
+		if _, has := entries[vote.Voter.Name]; has {
+			return nil, NewDuplicateError(fmt.Sprintf("voter \"%s\" has multiple votes in poll", vote.Voter.Name))
+		}
+		entries[vote.Voter.Name] = liveTallyEntry{index: i, weight: vote.Voter.EffectiveWeight()}
+	}
+	return &BasicPollLiveTally{
+		poll:    poll,
+		result:  res,
+		entries: entries,
+	}, nil
+}
+
+// Poll returns the wrapped poll.
+func (live *BasicPollLiveTally) Poll() *BasicPoll {
+	return live.poll
+}
+
+// Result returns a copy of the current result.
+func (live *BasicPollLiveTally) Result() *BasicPollResult {
+	numberVoters, weightedVotes := *live.result.NumberVoters, *live.result.WeightedVotes
+	return &BasicPollResult{
+		NumberVoters:  &numberVoters,
+		WeightedVotes: &weightedVotes,
+		VotersCount:   live.result.VotersCount,
+		VotesSum:      live.result.VotesSum,
+	}
+}
+
+// HasVoted returns true if the voter with the given name has a vote in the poll.
+func (live *BasicPollLiveTally) HasVoted(voterName string) bool {
+	_, has := live.entries[voterName]
+	return has
+}
+
+// AddVote adds a vote to the poll and updates the result, the vote must be of type *BasicVote.
+//
+// If the voter already voted the earlier vote is replaced and its counters are decreased.
+// If the weights would overflow an error wrapping ErrWeightOverflow is returned and nothing is changed.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+func (live *BasicPollLiveTally) AddVote(vote AbstractVote) error {
+	basicVote, ok := vote.(*BasicVote)
+	if !ok {
+		return NewPollTypeError("can't add vote to BasicPollLiveTally, vote must be of type *BasicVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if closedErr := live.poll.checkOpenForVoter("add vote", basicVote.Voter); closedErr != nil {
+		return closedErr
+	}
+	weight, weightErr := basicVote.Voter.CheckedEffectiveWeight()
+	if weightErr != nil {
+		return weightErr
+	}
+	name := basicVote.Voter.Name
+	previous, hasPrevious := live.entries[name]
+	// the weight sum without the previous vote, all counters are bounded by the sum
+	sum := live.result.VotesSum
+	if hasPrevious {
+		sum -= previous.weight
+	}
+	if _, sumErr := AddWeight(sum, weight); sumErr != nil {
+		return sumErr
+	}
+	if hasPrevious {
+		live.decreaseCounters(live.poll.Votes[previous.index].Choice, previous.weight)
+		live.poll.Votes[previous.index] = basicVote
+		live.entries[name] = liveTallyEntry{index: previous.index, weight: weight}
+	} else {
+		live.poll.Votes = append(live.poll.Votes, basicVote)
+		live.entries[name] = liveTallyEntry{index: len(live.poll.Votes) - 1, weight: weight}
+	}
+	live.increaseCounters(basicVote.Choice, weight)
+	return nil
+}
+
+// RemoveVote removes the vote of the voter with the given name and updates the result.
+//
+// It returns false if the voter has no vote in the poll.
+// The last vote of the poll is moved to the position of the removed vote.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+func (live *BasicPollLiveTally) RemoveVote(voterName string) (bool, error) {
+	if closedErr := live.poll.checkOpen("remove vote", voterName); closedErr != nil {
+		return false, closedErr
+	}
+	entry, has := live.entries[voterName]
+	if !has {
+		return false, nil
+	}
+	votes := live.poll.Votes
+	live.decreaseCounters(votes[entry.index].Choice, entry.weight)
+	last := len(votes) - 1
+	if entry.index != last {
+		moved := votes[last]
+		votes[entry.index] = moved
+		movedEntry := live.entries[moved.Voter.Name]
+		movedEntry.index = entry.index
+		live.entries[moved.Voter.Name] = movedEntry
+	}
+	votes[last] = nil
+	live.poll.Votes = votes[:last]
+	delete(live.entries, voterName)
+	return true, nil
+}
+
+func (live *BasicPollLiveTally) increaseCounters(choice BasicPollAnswer, weight Weight) {
+	// overflows are checked in AddVote
+	live.result.NumberVoters.Increase(choice, 1)
+	live.result.WeightedVotes.Increase(choice, weight)
+	live.result.VotersCount++
+	live.result.VotesSum += weight
+}
+
+func (live *BasicPollLiveTally) decreaseCounters(choice BasicPollAnswer, weight Weight) {
+	live.result.NumberVoters.decrease(choice, 1)
+	live.result.WeightedVotes.decrease(choice, weight)
+	live.result.VotersCount--
+	live.result.VotesSum -= weight
+}
@@ -16,7 +16,9 @@ package gopolls
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -37,11 +39,30 @@ func (a BasicPollAnswer) String() string {
 		return "aye"
 	case Abstention:
 		return "abstention"
+	case InvalidBasicPollAnswer:
+		return "invalid"
 	default:
 		return fmt.Sprintf("Unkown poll answer %d", a)
 	}
 }
 
+// ParseBasicPollAnswer parses an answer from a string as returned by BasicPollAnswer.String(), i.e. "no", "aye" or
+// "abstention".
+//
+// A PollingSyntaxError is returned if s is none of these strings.
+func ParseBasicPollAnswer(s string) (BasicPollAnswer, error) {
+	switch s {
+	case "no":
+		return No, nil
+	case "aye":
+		return Aye, nil
+	case "abstention":
+		return Abstention, nil
+	default:
+		return -1, NewPollingSyntaxError(nil, "invalid poll answer \"%s\", must be \"no\", \"aye\" or \"abstention\"", s)
+	}
+}
+
 // IsValid tests if the answer is valid, i.e. one of the constants No, Aye, Abstention.
 func (a BasicPollAnswer) IsValid() bool {
 	switch a {
@@ -54,9 +75,11 @@ func (a BasicPollAnswer) IsValid() bool {
 
 // BasicVote is a vote for a BasicPoll.
 // It is described by the answer and the voter. It implements the interface AbstractVote.
+// The source of the vote is stored in the embedded VoteSourceHolder, see SourcedVote.
 type BasicVote struct {
 	Voter  *Voter
 	Choice BasicPollAnswer
+	VoteSourceHolder
 }
 
 // NewBasicVote returns a new BasicVote.
@@ -89,12 +112,15 @@ func NewBasicVote(voter *Voter, choice BasicPollAnswer) *BasicVote {
 // Because this style might be confusing for people not familiar with the Schulze method the acceptance of the ranking
 // style can be disabled with AllowRankingStyle = false.
 //
+// If KeepRaw is true the parsed string is stored as the source of each vote, see VoteSource.
+//
 // It also implements ParserCustomizer.
 type BasicVoteParser struct {
 	NoValues          LowerStringSet
 	AyeValues         LowerStringSet
 	AbstentionValues  LowerStringSet
 	AllowRankingStyle bool
+	KeepRaw           bool
 }
 
 // NewBasicVoteParser returns a new BasicVoteParser with the default strings as described in the type description
@@ -108,15 +134,46 @@ func NewBasicVoteParser() *BasicVoteParser {
 		AyeValues:         NewLowerStringSet(ayeDefaults),
 		AbstentionValues:  NewLowerStringSet(abstentionDefaults),
 		AllowRankingStyle: true,
+		KeepRaw:           false,
 	}
 }
 
-// CustomizeForPoll implements ParserCustomizer and returns just the same parser again
-// if a *BasicPoll is given.
+// copyParser returns a shallow copy of the parser, the sets are not copied.
+func (parser *BasicVoteParser) copyParser() *BasicVoteParser {
+	return &BasicVoteParser{
+		NoValues:          parser.NoValues,
+		AyeValues:         parser.AyeValues,
+		AbstentionValues:  parser.AbstentionValues,
+		AllowRankingStyle: parser.AllowRankingStyle,
+		KeepRaw:           parser.KeepRaw,
+	}
+}
+
+// WithAnswers returns a shallow copy of the parser with the sets of valid strings replaced.
+//
+// For each of the arguments no, aye and abstention a new set is created, so the sets of the original parser are
+// never changed. If one of the arguments is nil the set of the original parser is used in the copy.
+func (parser *BasicVoteParser) WithAnswers(no, aye, abstention []string) *BasicVoteParser {
+	res := parser.copyParser()
+	if no != nil {
+		res.NoValues = NewLowerStringSet(no)
+	}
+	if aye != nil {
+		res.AyeValues = NewLowerStringSet(aye)
+	}
+	if abstention != nil {
+		res.AbstentionValues = NewLowerStringSet(abstention)
+	}
+	return res
+}
+
+// CustomizeForPoll implements ParserCustomizer and returns a shallow copy of the parser if a *BasicPoll is given.
+//
+// The copy is returned so that changing AllowRankingStyle on a customized parser does not change the template.
 func (parser *BasicVoteParser) CustomizeForPoll(poll AbstractPoll) (ParserCustomizer, error) {
 	if _, ok := poll.(*BasicPoll); ok {
-		// is a valid basic poll, nothing there to be done
-		return parser, nil
+		// is a valid basic poll, nothing to adjust
+		return parser.copyParser(), nil
 	}
 	return nil, NewPollTypeError("can't customize BasicVoteParser for type %s, expected type *BasicPoll",
 		reflect.TypeOf(poll))
@@ -141,7 +198,7 @@ func (parser *BasicVoteParser) basicStyle(s string, voter *Voter) (*BasicVote, b
 }
 
 func (parser *BasicVoteParser) rankingStyle(s string, voter *Voter) (*BasicVote, bool) {
-	ranking, rankingErr := parseSchulzeRanking(s, 2)
+	ranking, rankingErr := parseSchulzeRanking(s, 2, -1, false)
 	if rankingErr != nil {
 		return nil, false
 	}
@@ -165,7 +222,7 @@ func (parser *BasicVoteParser) ParseFromString(s string, voter *Voter) (Abstract
 
 	vote, ok = parser.basicStyle(s, voter)
 	if ok {
-		return vote, nil
+		return keepRawSource(vote, s, parser.KeepRaw), nil
 	}
 
 	// try ranking style, but only if this is allowed
@@ -176,7 +233,7 @@ func (parser *BasicVoteParser) ParseFromString(s string, voter *Voter) (Abstract
 	}
 	vote, ok = parser.rankingStyle(s, voter)
 	if ok {
-		return vote, nil
+		return keepRawSource(vote, s, parser.KeepRaw), nil
 	}
 
 	// no style matched ==> error
@@ -185,6 +242,93 @@ func (parser *BasicVoteParser) ParseFromString(s string, voter *Voter) (Abstract
 	return nil, err
 }
 
+// BasicVoteFormatter implements VoteFormatter for a BasicVote, the choice is formatted with No, Aye or Abstention.
+//
+// NewBasicVoteFormatter uses "no", "aye" and "abstention", NewBasicVoteFormatterFromParser chooses the strings from
+// the sets of a BasicVoteParser.
+type BasicVoteFormatter struct {
+	No         string
+	Aye        string
+	Abstention string
+}
+
+// NewBasicVoteFormatter returns a new formatter with the strings "no", "aye" and "abstention", they are accepted by a
+// parser returned by NewBasicVoteParser.
+func NewBasicVoteFormatter() *BasicVoteFormatter {
+	return &BasicVoteFormatter{
+		No:         "no",
+		Aye:        "aye",
+		Abstention: "abstention",
+	}
+}
+
+// NewBasicVoteFormatterFromParser returns a new formatter that uses strings from the sets of parser, so the
+// formatted strings are always accepted by parser.
+//
+// For each answer the string from the formatter returned by NewBasicVoteFormatter is used if it is contained in the
+// set, otherwise the smallest string of the set is used. Because the parser tests NoValues first, then AyeValues
+// and then AbstentionValues, strings that are also contained in a set tested before are never used.
+// If no string can be found the string for this answer is empty and FormatVote returns an error for it.
+func NewBasicVoteFormatterFromParser(parser *BasicVoteParser) *BasicVoteFormatter {
+	defaults := NewBasicVoteFormatter()
+	return &BasicVoteFormatter{
+		No:         chooseAnswerString(parser.NoValues, defaults.No),
+		Aye:        chooseAnswerString(parser.AyeValues, defaults.Aye, parser.NoValues),
+		Abstention: chooseAnswerString(parser.AbstentionValues, defaults.Abstention, parser.NoValues, parser.AyeValues),
+	}
+}
+
+// chooseAnswerString returns preferred if it is contained in values and in none of the excluded sets, otherwise the
+// smallest string with this property is returned. If there is no such string an empty string is returned.
+func chooseAnswerString(values LowerStringSet, preferred string, excluded ...LowerStringSet) string {
+	isExcluded := func(s string) bool {
+		for _, set := range excluded {
+			if set.ContainsLowercase(s) {
+				return true
+			}
+		}
+		return false
+	}
+	if values.ContainsLowercase(preferred) && !isExcluded(preferred) {
+		return preferred
+	}
+	candidates := make([]string, 0, len(values))
+	for value := range values {
+		if !isExcluded(value) {
+			candidates = append(candidates, value)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Strings(candidates)
+	return candidates[0]
+}
+
+// FormatVote implements VoteFormatter, vote must be a *BasicVote.
+func (formatter *BasicVoteFormatter) FormatVote(vote AbstractVote) (string, error) {
+	basicVote, ok := vote.(*BasicVote)
+	if !ok {
+		return "", NewPollTypeError("can't format vote of type %s with BasicVoteFormatter, expected type *BasicVote",
+			reflect.TypeOf(vote))
+	}
+	var res string
+	switch basicVote.Choice {
+	case No:
+		res = formatter.No
+	case Aye:
+		res = formatter.Aye
+	case Abstention:
+		res = formatter.Abstention
+	default:
+		return "", NewPollingSemanticError(nil, "invalid choice for basic vote: %s", basicVote.Choice)
+	}
+	if res == "" {
+		return "", NewPollingSemanticError(nil, "no string for choice %s given", basicVote.Choice)
+	}
+	return res, nil
+}
+
 // GetVoter returns the voter of the vote.
 func (vote *BasicVote) GetVoter() *Voter {
 	return vote.Voter
@@ -195,17 +339,65 @@ func (vote *BasicVote) VoteType() string {
 	return BasicVoteType
 }
 
+// Equals tests if two votes are equal, i.e. have the same choice and equal voters (see Voter.Equals).
+func (vote *BasicVote) Equals(other *BasicVote) bool {
+	return vote.Choice == other.Choice && votersEqual(vote.Voter, other.Voter)
+}
+
 // BasicPoll is a poll with the options No, Yes and Abstention, for details see BasicPollAnswer.
 // It implements the interface AbstractPoll.
 //
 // This type also implements VoteGenerator.
+// Sealed votes are stored in the embedded SealedVoteBuffer, see Reveal.
+// Duplicate votes can be rejected with the embedded DuplicateVoterGuard.
+// Application data can be attached with the embedded PollMetadata.
+// The poll can be closed with the embedded PollCloser, a closed poll rejects new votes.
 type BasicPoll struct {
 	Votes []*BasicVote
+	SealedVoteBuffer
+	DuplicateVoterGuard
+	PollMetadata
+	PollCloser
 }
 
 // NewBasicPoll returns a new BasicPoll with the given votes.
 func NewBasicPoll(votes []*BasicVote) *BasicPoll {
-	return &BasicPoll{votes}
+	return &BasicPoll{Votes: votes}
+}
+
+// DeepClone returns a copy of the poll with a new votes slice and new vote objects.
+// The voter objects are not copied, the votes of the clone point to the same voters as the original.
+func (poll *BasicPoll) DeepClone() *BasicPoll {
+	votes := make([]*BasicVote, len(poll.Votes))
+	for i, vote := range poll.Votes {
+		votes[i] = NewBasicVote(vote.Voter, vote.Choice)
+		votes[i].VoteSourceHolder = vote.VoteSourceHolder.clone()
+	}
+	res := NewBasicPoll(votes)
+	res.SealedVoteBuffer = poll.SealedVoteBuffer.clone()
+	res.DuplicateVoterGuard = poll.DuplicateVoterGuard.clone()
+	res.PollMetadata = poll.PollMetadata.clone()
+	res.PollCloser = poll.PollCloser.clone()
+	return res
+}
+
+// AddSealed implements SealedVoteAcceptor and adds a sealed vote to the embedded SealedVoteBuffer.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+func (poll *BasicPoll) AddSealed(vote *SealedVote) error {
+	return poll.SealedVoteBuffer.addSealed(&poll.PollCloser, vote)
+}
+
+// Equals tests if two polls contain equal votes (in the same order).
+func (poll *BasicPoll) Equals(other *BasicPoll) bool {
+	if len(poll.Votes) != len(other.Votes) {
+		return false
+	}
+	for i, vote := range poll.Votes {
+		if !vote.Equals(other.Votes[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 // PollType returns the constant BasicPollType.
@@ -214,16 +406,127 @@ func (poll *BasicPoll) PollType() string {
 }
 
 // AddVote adds a vote to the poll, the vote must be of type *BasicVote.
+//
+// If RejectDuplicateVoters is true a DuplicateError is returned if the voter already has a vote, see
+// DuplicateVoterGuard.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
 func (poll *BasicPoll) AddVote(vote AbstractVote) error {
 	asBasicVote, ok := vote.(*BasicVote)
 	if !ok {
 		return NewPollTypeError("can't add vote to BasicPoll, vote must be of type *BasicVote, got type %s",
 			reflect.TypeOf(vote))
 	}
+	if closedErr := poll.checkOpenForVoter("add vote", asBasicVote.Voter); closedErr != nil {
+		return closedErr
+	}
+	if duplicateErr := poll.checkNewVoter(asBasicVote.Voter, len(poll.Votes), poll.forEachVoter); duplicateErr != nil {
+		return duplicateErr
+	}
 	poll.Votes = append(poll.Votes, asBasicVote)
 	return nil
 }
 
+// ForEachVote implements VoteIterator, it calls f for each vote in the order in which they appear in the poll.
+func (poll *BasicPoll) ForEachVote(f func(vote AbstractVote) error) error {
+	return forEachVote(poll, f)
+}
+
+// NumVotes implements VoteIterator and returns the number of votes in the poll.
+func (poll *BasicPoll) NumVotes() int {
+	return len(poll.Votes)
+}
+
+// voteAt returns the vote with index i, it implements indexedVotes.
+func (poll *BasicPoll) voteAt(i int) AbstractVote {
+	return poll.Votes[i]
+}
+
+// forEachVoter calls f with the voter of each vote, used by DuplicateVoterGuard.
+func (poll *BasicPoll) forEachVoter(f func(voter *Voter)) {
+	for _, vote := range poll.Votes {
+		f(vote.Voter)
+	}
+}
+
+// HasVoteFrom implements VoteEditor and returns true if the voter with the given name has a vote in the poll.
+func (poll *BasicPoll) HasVoteFrom(voterName string) bool {
+	return voteIndex(poll, voterName) >= 0
+}
+
+// RemoveVote implements VoteEditor and removes the vote of the voter with the given name, the removed vote is
+// returned.
+//
+// If the voter has multiple votes only the first one is removed. The order of the remaining votes is retained.
+// If the voter has no vote a VoteNotFoundError is returned.
+func (poll *BasicPoll) RemoveVote(voterName string) (AbstractVote, error) {
+	if closedErr := poll.checkOpen("remove vote", voterName); closedErr != nil {
+		return nil, closedErr
+	}
+	index := voteIndex(poll, voterName)
+	if index < 0 {
+		return nil, NewVoteNotFoundError(voterName)
+	}
+	res := poll.Votes[index]
+	poll.Votes = append(poll.Votes[:index], poll.Votes[index+1:]...)
+	poll.ResetVoterIndex()
+	return res, nil
+}
+
+// ReplaceVote implements VoteEditor, the vote must be of type *BasicVote.
+//
+// The vote replaces the first vote of its voter (at the same position), all other votes of the voter are removed.
+// If the voter has no vote yet the vote is added.
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if the vote has no voter.
+func (poll *BasicPoll) ReplaceVote(vote AbstractVote) error {
+	asBasicVote, ok := vote.(*BasicVote)
+	if !ok {
+		return NewPollTypeError("can't replace vote in BasicPoll, vote must be of type *BasicVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asBasicVote.Voter == nil {
+		return NewPollingSemanticError(nil, "can't replace vote in BasicPoll, vote has no voter")
+	}
+	name := asBasicVote.Voter.Name
+	if closedErr := poll.checkOpen("replace vote", name); closedErr != nil {
+		return closedErr
+	}
+	index := voteIndex(poll, name)
+	if index < 0 {
+		return poll.AddVote(vote)
+	}
+	poll.Votes[index] = asBasicVote
+	// remove all other votes of the voter
+	filtered := poll.Votes[:index+1]
+	for _, other := range poll.Votes[index+1:] {
+		if other.Voter == nil || other.Voter.Name != name {
+			filtered = append(filtered, other)
+		}
+	}
+	poll.Votes = filtered
+	poll.ResetVoterIndex()
+	return nil
+}
+
+// ValidateVote tests if vote can be added to the poll, it must be of type *BasicVote with a voter and a valid
+// choice (see BasicPollAnswer.IsValid).
+//
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if it is invalid.
+func (poll *BasicPoll) ValidateVote(vote AbstractVote) error {
+	asBasicVote, ok := vote.(*BasicVote)
+	if !ok {
+		return NewPollTypeError("invalid vote for BasicPoll, vote must be of type *BasicVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asBasicVote.Voter == nil {
+		return NewPollingSemanticError(nil, "vote for BasicPoll has no voter")
+	}
+	if !asBasicVote.Choice.IsValid() {
+		return NewPollingSemanticError(nil, "invalid choice %d of voter \"%s\" for BasicPoll",
+			asBasicVote.Choice, asBasicVote.Voter.Name)
+	}
+	return nil
+}
+
 // GenerateVoteFromBasicAnswer implements VoteGenerator and returns a BasicVote.
 func (poll *BasicPoll) GenerateVoteFromBasicAnswer(voter *Voter, answer BasicPollAnswer) (AbstractVote, error) {
 	switch answer {
@@ -288,16 +591,40 @@ func NewBasicPollCounter() *BasicPollCounter {
 
 // Increase increases the counter given the choice, the counter increased depends on choice.
 // inc is the value by which the counter is increased.
+//
+// Overflows are not detected, use CheckedIncrease for this.
 func (counter *BasicPollCounter) Increase(choice BasicPollAnswer, inc Weight) {
+	*counter.counterFor(choice) += inc
+}
+
+// CheckedIncrease works as Increase, but if the counter would overflow it is not changed and an error wrapping
+// ErrWeightOverflow is returned.
+func (counter *BasicPollCounter) CheckedIncrease(choice BasicPollAnswer, inc Weight) error {
+	target := counter.counterFor(choice)
+	sum, err := AddWeight(*target, inc)
+	if err != nil {
+		return err
+	}
+	*target = sum
+	return nil
+}
+
+// decrease decreases the counter given the choice by dec, the counter must be >= dec.
+func (counter *BasicPollCounter) decrease(choice BasicPollAnswer, dec Weight) {
+	*counter.counterFor(choice) -= dec
+}
+
+// counterFor returns a pointer to the counter for the given choice, all invalid choices are counted in NumInvalid.
+func (counter *BasicPollCounter) counterFor(choice BasicPollAnswer) *Weight {
 	switch choice {
 	case No:
-		counter.NumNoes += inc
+		return &counter.NumNoes
 	case Aye:
-		counter.NumAyes += inc
+		return &counter.NumAyes
 	case Abstention:
-		counter.NumAbstention += inc
+		return &counter.NumAbstention
 	default:
-		counter.NumInvalid += inc
+		return &counter.NumInvalid
 	}
 }
 
@@ -309,6 +636,61 @@ func (counter *BasicPollCounter) Equals(other *BasicPollCounter) bool {
 		counter.NumInvalid == other.NumInvalid
 }
 
+// percentageBase returns the number of ayes + noes (+ abstentions if includeAbstentions is true), invalid votes are
+// never included. The sum is computed as an uint64, thus it can't overflow.
+func (counter *BasicPollCounter) percentageBase(includeAbstentions bool) uint64 {
+	res := uint64(counter.NumAyes) + uint64(counter.NumNoes)
+	if includeAbstentions {
+		res += uint64(counter.NumAbstention)
+	}
+	return res
+}
+
+// percentOf returns value / percentageBase(includeAbstentions), 0 if the base is 0.
+func (counter *BasicPollCounter) percentOf(value Weight, includeAbstentions bool) *big.Rat {
+	base := counter.percentageBase(includeAbstentions)
+	if base == 0 {
+		return big.NewRat(0, 1)
+	}
+	return new(big.Rat).SetFrac(new(big.Int).SetUint64(uint64(value)), new(big.Int).SetUint64(base))
+}
+
+// PercentAyes returns the share of ayes in ayes + noes (+ abstentions if includeAbstentions is true), invalid votes are
+// never counted. If there are no such votes 0 is returned.
+//
+// Use FormatPercentage to format the result.
+func (counter *BasicPollCounter) PercentAyes(includeAbstentions bool) *big.Rat {
+	return counter.percentOf(counter.NumAyes, includeAbstentions)
+}
+
+// PercentNoes returns the share of noes, see PercentAyes.
+func (counter *BasicPollCounter) PercentNoes(includeAbstentions bool) *big.Rat {
+	return counter.percentOf(counter.NumNoes, includeAbstentions)
+}
+
+// PercentAbstentions returns the share of abstentions in ayes + noes + abstentions, 0 if there are no such votes.
+func (counter *BasicPollCounter) PercentAbstentions() *big.Rat {
+	return counter.percentOf(counter.NumAbstention, true)
+}
+
+// Summary returns a summary of the counter with the percentages formatted by FormatPercentage, for example
+// "Ayes: 12 (60.000%), Noes: 7 (35.000%), Abstentions: 1 (5.000%)".
+//
+// If includeAbstentions is false the percentages are relative to ayes + noes and the abstentions are written without
+// a percentage, for example "Ayes: 12 (63.158%), Noes: 7 (36.842%), Abstentions: 1". Invalid votes are never part of
+// the summary. If there are no votes all percentages are 0.000%.
+func (counter *BasicPollCounter) Summary(includeAbstentions bool) string {
+	ayes := FormatPercentage(counter.PercentAyes(includeAbstentions))
+	noes := FormatPercentage(counter.PercentNoes(includeAbstentions))
+	if !includeAbstentions {
+		return fmt.Sprintf("Ayes: %d (%s%%), Noes: %d (%s%%), Abstentions: %d",
+			counter.NumAyes, ayes, counter.NumNoes, noes, counter.NumAbstention)
+	}
+	return fmt.Sprintf("Ayes: %d (%s%%), Noes: %d (%s%%), Abstentions: %d (%s%%)",
+		counter.NumAyes, ayes, counter.NumNoes, noes, counter.NumAbstention,
+		FormatPercentage(counter.PercentAbstentions()))
+}
+
 // BasicPollResult is the result of evaluating a BasicPoll.
 //
 // It stores two instances of BasicPollCounter: NumberVoters counts how often an answer was taken, independent
@@ -317,13 +699,30 @@ func (counter *BasicPollCounter) Equals(other *BasicPollCounter) bool {
 // these voters.
 //
 // WeightSum is the sum of the weights of all votes in the poll, VotersCount the number of voters (as a weight).
+//
+// ZeroWeight is only set by TallyWithZeroWeightPolicy with ExcludeZeroWeightFromCounts, it counts how often an
+// answer was taken by voters with weight 0 (these votes are not counted in NumberVoters and VotersCount then).
+// Otherwise it is nil.
+//
+// VotersByChoice is only set by TallyDetailed, it maps each answer to the voters that have chosen it (in the order of
+// the votes in the poll), all invalid answers are mapped by InvalidBasicPollAnswer. Otherwise it is nil.
+//
+// Err is nil unless the weights of the votes overflow, in this case it wraps ErrWeightOverflow and all counters are
+// 0.
 type BasicPollResult struct {
-	NumberVoters  *BasicPollCounter
-	WeightedVotes *BasicPollCounter
-	VotersCount   Weight
-	VotesSum      Weight
+	NumberVoters   *BasicPollCounter
+	WeightedVotes  *BasicPollCounter
+	VotersCount    Weight
+	VotesSum       Weight
+	ZeroWeight     *BasicPollCounter
+	VotersByChoice map[BasicPollAnswer][]*Voter
+	Err            error
 }
 
+// InvalidBasicPollAnswer is the key for all invalid answers in BasicPollResult.VotersByChoice, it is never a valid
+// answer.
+const InvalidBasicPollAnswer BasicPollAnswer = -1
+
 // NewBasicPollResult returns a new BasicPollResult with all values set to 0.
 func NewBasicPollResult() *BasicPollResult {
 	return &BasicPollResult{
@@ -334,19 +733,138 @@ func NewBasicPollResult() *BasicPollResult {
 	}
 }
 
+// Equals tests if two results store the same state.
+func (res *BasicPollResult) Equals(other *BasicPollResult) bool {
+	return res.NumberVoters.Equals(other.NumberVoters) &&
+		res.WeightedVotes.Equals(other.WeightedVotes) &&
+		res.VotersCount == other.VotersCount &&
+		res.VotesSum == other.VotesSum &&
+		zeroWeightCountersEqual(res.ZeroWeight, other.ZeroWeight) &&
+		votersByChoiceEqual(res.VotersByChoice, other.VotersByChoice)
+}
+
+// PercentAyes returns the weighted share of ayes, see BasicPollCounter.PercentAyes.
+// Use NumberVoters.PercentAyes for the unweighted share.
+func (res *BasicPollResult) PercentAyes(includeAbstentions bool) *big.Rat {
+	return res.WeightedVotes.PercentAyes(includeAbstentions)
+}
+
+// PercentNoes returns the weighted share of noes, see BasicPollCounter.PercentNoes.
+// Use NumberVoters.PercentNoes for the unweighted share.
+func (res *BasicPollResult) PercentNoes(includeAbstentions bool) *big.Rat {
+	return res.WeightedVotes.PercentNoes(includeAbstentions)
+}
+
+// Summary returns a summary of the weighted votes, see BasicPollCounter.Summary.
+// Use NumberVoters.Summary for a summary of the unweighted votes.
+func (res *BasicPollResult) Summary(includeAbstentions bool) string {
+	return res.WeightedVotes.Summary(includeAbstentions)
+}
+
+// votersByChoiceEqual tests if two (possibly nil) VotersByChoice maps are equal, the voters are compared with
+// Voter.Equals.
+func votersByChoiceEqual(a, b map[BasicPollAnswer][]*Voter) bool {
+	if a == nil || b == nil {
+		return (a == nil) == (b == nil)
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for choice, voters := range a {
+		otherVoters, has := b[choice]
+		if !has || len(voters) != len(otherVoters) {
+			return false
+		}
+		for i, voter := range voters {
+			if !votersEqual(voter, otherVoters[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// zeroWeightCountersEqual tests if two (possibly nil) counters are equal.
+func zeroWeightCountersEqual(a, b *BasicPollCounter) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equals(b)
+}
+
 func (res *BasicPollResult) increaseCounters(vote *BasicVote) {
 	res.NumberVoters.Increase(vote.Choice, 1)
-	res.WeightedVotes.Increase(vote.Choice, vote.Voter.Weight)
+	res.WeightedVotes.Increase(vote.Choice, vote.Voter.EffectiveWeight())
 	res.VotersCount += 1
-	res.VotesSum += vote.Voter.Weight
+	res.VotesSum += vote.Voter.EffectiveWeight()
+}
+
+// CheckedWeightSum returns the sum of the effective weights of all votes, if the sum overflows an error wrapping
+// ErrWeightOverflow is returned.
+func (poll *BasicPoll) CheckedWeightSum() (Weight, error) {
+	return checkedWeightSum(poll, nil)
 }
 
 // Tally counts how often a certain answer was taken.
 // Note that invalid votes might occur and will be counted in the NumInvalid fields.
+//
+// If the weights of the votes overflow (see CheckedWeightSum) the returned result is empty and has its Err set.
 func (poll *BasicPoll) Tally() *BasicPollResult {
 	res := NewBasicPollResult()
+	// all counters are bounded by the weight sum, so it is sufficient to check the sum for overflows
+	if _, sumErr := poll.CheckedWeightSum(); sumErr != nil {
+		res.Err = sumErr
+		return res
+	}
 	for _, vote := range poll.Votes {
 		res.increaseCounters(vote)
 	}
 	return res
 }
+
+// TallyDetailed works as Tally but also sets VotersByChoice in the result, this way it is possible to tell which
+// voter voted what (roll-call vote).
+//
+// Because this information is sensitive Tally doesn't compute it. The result contains the voters from the votes,
+// so if the poll has been anonymized (see ApplyAnonymization) the result contains only the pseudonymous voters.
+// If the weights overflow VotersByChoice is empty.
+func (poll *BasicPoll) TallyDetailed() *BasicPollResult {
+	res := poll.Tally()
+	res.VotersByChoice = make(map[BasicPollAnswer][]*Voter)
+	if res.Err != nil {
+		return res
+	}
+	for _, vote := range poll.Votes {
+		choice := vote.Choice
+		if !choice.IsValid() {
+			choice = InvalidBasicPollAnswer
+		}
+		res.VotersByChoice[choice] = append(res.VotersByChoice[choice], vote.Voter)
+	}
+	return res
+}
+
+// TallyWithZeroWeightPolicy works as Tally but handles votes of voters with weight 0 according to policy.
+//
+// AllowZeroWeight and RejectZeroWeightAtParse (voters should already be rejected when parsing) are the same as
+// Tally. With ExcludeZeroWeightFromCounts the votes of voters with weight 0 are not counted in NumberVoters and
+// VotersCount, instead they're counted in ZeroWeight.
+func (poll *BasicPoll) TallyWithZeroWeightPolicy(policy ZeroWeightPolicy) *BasicPollResult {
+	if policy != ExcludeZeroWeightFromCounts {
+		return poll.Tally()
+	}
+	res := NewBasicPollResult()
+	res.ZeroWeight = NewBasicPollCounter()
+	if _, sumErr := poll.CheckedWeightSum(); sumErr != nil {
+		res.Err = sumErr
+		return res
+	}
+	for _, vote := range poll.Votes {
+		if vote.Voter.HasZeroWeight() {
+			res.ZeroWeight.Increase(vote.Choice, 1)
+		} else {
+			res.increaseCounters(vote)
+		}
+	}
+	return res
+}
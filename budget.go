@@ -0,0 +1,138 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ErrMedianUnitOverflow is returned (wrapped) if a sum of MedianUnit values can't be represented as a MedianUnit,
+// i.e. the sum is >= NoMedianUnitValue.
+var ErrMedianUnitOverflow = NewPollingSemanticError(nil, "median unit overflow")
+
+// AddMedianUnit returns a + b, if the sum is >= NoMedianUnitValue an error wrapping ErrMedianUnitOverflow is
+// returned.
+func AddMedianUnit(a, b MedianUnit) (MedianUnit, error) {
+	sum := a + b
+	if sum < a || sum == NoMedianUnitValue {
+		return NoMedianUnitValue, fmt.Errorf("can't compute %d + %d: %w", a, b, ErrMedianUnitOverflow)
+	}
+	return sum, nil
+}
+
+// BudgetEntry is the result of a single poll in EvaluateWithBudgetDetails.
+//
+// Value is the MajorityValue of the poll (0 if no value had a majority), Capped the value that is approved given
+// the budget and Remaining the budget that is left after this poll.
+type BudgetEntry struct {
+	Poll      string
+	Result    *MedianResult
+	Value     MedianUnit
+	Capped    MedianUnit
+	Remaining MedianUnit
+}
+
+// BudgetEvaluation is the result of EvaluateWithBudgetDetails.
+//
+// Entries contains one entry for each poll in the order of the priorities, Requested is the sum of all winning
+// values (before capping) and Remaining the budget that is left after all polls.
+type BudgetEvaluation struct {
+	Budget    MedianUnit
+	Requested MedianUnit
+	Remaining MedianUnit
+	Entries   []BudgetEntry
+}
+
+// EvaluateWithBudgetDetails tallies all median polls and makes sure that the sum of the approved values doesn't
+// exceed budget.
+//
+// Each poll is tallied with the default majority (see MedianPoll.Tally, this also sorts the votes). Then the polls
+// are processed in the order given by priorities: The value of each poll is capped to the budget that is still
+// remaining, thus polls with a higher priority (earlier in priorities) get their value first.
+//
+// Each poll must appear exactly once in priorities: A DuplicateError is returned if a name appears multiple times and
+// a PollingSemanticError if a poll is missing or priorities contains a name that is not a poll. If a poll can't be
+// tallied (see MedianResult.Err) or the sum of the winning values overflows (see ErrMedianUnitOverflow) an error is
+// returned too.
+func EvaluateWithBudgetDetails(polls map[string]*MedianPoll, priorities []string, budget MedianUnit) (*BudgetEvaluation, error) {
+	found := make(map[string]struct{}, len(priorities))
+	for _, name := range priorities {
+		if _, has := found[name]; has {
+			return nil, NewDuplicateError(fmt.Sprintf("poll \"%s\" was found multiple times in priorities", name))
+		}
+		if _, has := polls[name]; !has {
+			return nil, NewPollingSemanticError(nil, "poll \"%s\" from priorities is not a median poll", name)
+		}
+		found[name] = struct{}{}
+	}
+	if len(found) != len(polls) {
+		var missing []string
+		for name := range polls {
+			if _, has := found[name]; !has {
+				missing = append(missing, name)
+			}
+		}
+		sort.Strings(missing)
+		return nil, NewPollingSemanticError(nil, "polls %v are missing in priorities", missing)
+	}
+
+	res := &BudgetEvaluation{
+		Budget:    budget,
+		Requested: 0,
+		Remaining: budget,
+		Entries:   make([]BudgetEntry, 0, len(priorities)),
+	}
+	for _, name := range priorities {
+		result := polls[name].Tally(NoWeight)
+		if result.Err != nil {
+			return nil, fmt.Errorf("can't tally poll \"%s\": %w", name, result.Err)
+		}
+		value := result.MajorityValue
+		if value == NoMedianUnitValue {
+			value = 0
+		}
+		var sumErr error
+		if res.Requested, sumErr = AddMedianUnit(res.Requested, value); sumErr != nil {
+			return nil, fmt.Errorf("sum of values is too big in poll \"%s\": %w", name, sumErr)
+		}
+		capped := value
+		if capped > res.Remaining {
+			capped = res.Remaining
+		}
+		res.Remaining -= capped
+		res.Entries = append(res.Entries, BudgetEntry{
+			Poll:      name,
+			Result:    result,
+			Value:     value,
+			Capped:    capped,
+			Remaining: res.Remaining,
+		})
+	}
+	return res, nil
+}
+
+// EvaluateWithBudget works as EvaluateWithBudgetDetails but only returns the capped value for each poll.
+func EvaluateWithBudget(polls map[string]*MedianPoll, priorities []string, budget MedianUnit) (map[string]MedianUnit, error) {
+	evaluation, err := EvaluateWithBudgetDetails(polls, priorities, budget)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]MedianUnit, len(evaluation.Entries))
+	for _, entry := range evaluation.Entries {
+		res[entry.Poll] = entry.Capped
+	}
+	return res, nil
+}
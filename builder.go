@@ -0,0 +1,323 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CollectionBuilderError is returned by CollectionBuilder.Build if a step of the builder is invalid.
+//
+// Step is the number of the step (starting with 1, each call to Group, Poll, PollWithOptions, MoneyPoll,
+// MoneyPollWithRange and MoneyPollValue is a step), Description describes the step (for example
+// `poll "Statute change"`) and Err is the original error, it is returned by Unwrap.
+// Errors in the title of the collection have Step 0.
+type CollectionBuilderError struct {
+	Step        int
+	Description string
+	Err         error
+}
+
+func (err CollectionBuilderError) Error() string {
+	return fmt.Sprintf("builder step %d (%s): %s", err.Step, err.Description, err.Err.Error())
+}
+
+// Unwrap returns the wrapped error.
+func (err CollectionBuilderError) Unwrap() error {
+	return err.Err
+}
+
+// collectionBuildState is the state while building a collection.
+type collectionBuildState struct {
+	builder  *CollectionBuilder
+	coll     *PollSkeletonCollection
+	names    map[string]struct{}
+	numSkels int
+}
+
+// builderStep is a single step of a CollectionBuilder, apply adds the group / poll to the state.
+type builderStep struct {
+	description string
+	apply       func(state *collectionBuildState) error
+}
+
+// CollectionBuilder is used to build a PollSkeletonCollection in code, for example in tests or for generated
+// agendas.
+//
+// Example:
+//
+//	coll, err := NewCollectionBuilder("Meeting").
+//	    Group("Finance").MoneyPoll("New laptop", "1500 €").
+//	    Group("Statute").Poll("Statute change", "Yes", "No").
+//	    Build()
+//
+// The steps are only validated in Build, they're validated in the same way the PollCollectionParser validates a
+// parsed collection: The limits from Parser are applied (except the limits that refer to lines and bytes of the input,
+// i.e. MaxNumLines, MaxLineLength and MaxTotalBytes) and each title, name and option must be valid in the collection
+// format, thus the dump of the collection can be parsed again. In addition to the parser the names of the polls must
+// be unique.
+// PollCollectionParser.ParseCollectionYAML uses a builder as well, thus both formats are validated in the same way.
+//
+// Parser is the parser that defines the limits and defaults to NewPollCollectionParser(), CurrencyParser is used to
+// parse the values of MoneyPoll and defaults to DefaultCurrencyHandler. Both can be changed before Build is called.
+type CollectionBuilder struct {
+	Parser         *PollCollectionParser
+	CurrencyParser CurrencyParser
+	title          string
+	steps          []builderStep
+}
+
+// NewCollectionBuilder returns a new builder for a collection with the given title.
+func NewCollectionBuilder(title string) *CollectionBuilder {
+	return &CollectionBuilder{
+		Parser:         NewPollCollectionParser(),
+		CurrencyParser: DefaultCurrencyHandler,
+		title:          title,
+		steps:          nil,
+	}
+}
+
+func (builder *CollectionBuilder) addStep(description string,
+	apply func(state *collectionBuildState) error) *CollectionBuilder {
+	builder.steps = append(builder.steps, builderStep{description: description, apply: apply})
+	return builder
+}
+
+// validateBuilderLine tests if s is parsed from the line prefix + s by rx, i.e. if it is a valid title / name / option.
+func validateBuilderLine(rx *regexp.Regexp, prefix, s, what string) error {
+	match := rx.FindStringSubmatch(prefix + s)
+	if len(match) == 0 || match[1] != s {
+		return NewPollingSyntaxError(nil, "invalid %s \"%s\", it must be a single line without leading or trailing spaces",
+			what, s)
+	}
+	return nil
+}
+
+// lastGroup returns the last group of the collection, it returns an error if there is no group yet.
+func (state *collectionBuildState) lastGroup() (*PollGroup, error) {
+	if len(state.coll.Groups) == 0 {
+		return nil, NewPollingSyntaxError(nil, "poll must be added to a group, call Group first")
+	}
+	return state.coll.getLastPollGroup(), nil
+}
+
+// addSkeleton validates the name of the skeleton and the number of polls and adds it to the last group.
+func (state *collectionBuildState) addSkeleton(skel AbstractPollSkeleton) error {
+	group, groupErr := state.lastGroup()
+	if groupErr != nil {
+		return groupErr
+	}
+	name := skel.GetName()
+	if nameErr := validateBuilderLine(pollLineRx, "### ", name, "poll name"); nameErr != nil {
+		return nameErr
+	}
+	if pollAnnotationsRx.MatchString(name) {
+		return NewPollingSyntaxError(nil, "poll name \"%s\" would be parsed as a poll with annotations", name)
+	}
+	if nameErr := state.builder.Parser.validatePollName(name); nameErr != nil {
+		return nameErr
+	}
+	if _, has := state.names[name]; has {
+		return NewDuplicateError(fmt.Sprintf("duplicate entry for poll %s", name))
+	}
+	state.numSkels++
+	if numPollsErr := state.builder.Parser.validateNumPolls(state.numSkels); numPollsErr != nil {
+		return numPollsErr
+	}
+	state.names[name] = struct{}{}
+	group.Skeletons = append(group.Skeletons, skel)
+	return nil
+}
+
+// Group adds a new group, all polls added after this step are added to this group.
+func (builder *CollectionBuilder) Group(title string) *CollectionBuilder {
+	return builder.addStep(fmt.Sprintf("group \"%s\"", title), func(state *collectionBuildState) error {
+		// the parser doesn't allow a group without polls (except the last one)
+		if len(state.coll.Groups) > 0 && state.coll.getLastPollGroup().NumSkeletons() == 0 {
+			return NewPollingSyntaxError(nil, "group \"%s\" contains no polls", state.coll.getLastPollGroup().Title)
+		}
+		if titleErr := validateBuilderLine(groupLineRx, "## ", title, "group title"); titleErr != nil {
+			return titleErr
+		}
+		if titleErr := state.builder.Parser.validateGroupName(title); titleErr != nil {
+			return titleErr
+		}
+		state.coll.Groups = append(state.coll.Groups, NewPollGroup(title))
+		return nil
+	})
+}
+
+// Poll adds a poll with the given options to the last group.
+//
+// The number of options must be at least Parser.MinNumOptions and at most Parser.MaxNumOptions. An option must not
+// contain Parser.OptionDescriptionSeparator, use PollWithOptions to add options with descriptions.
+func (builder *CollectionBuilder) Poll(name string, options ...string) *CollectionBuilder {
+	skelOptions := make([]SkeletonOption, len(options))
+	for i, option := range options {
+		skelOptions[i] = SkeletonOption{Name: option}
+	}
+	return builder.PollWithOptions(name, skelOptions...)
+}
+
+// PollWithOptions works as Poll but each option can have a description.
+//
+// A description must be a single line without leading or trailing spaces, it must not look like an option, group or
+// poll line because it is written in an indented line after the option (see PollSkeleton.Dump).
+func (builder *CollectionBuilder) PollWithOptions(name string, options ...SkeletonOption) *CollectionBuilder {
+	optionsCopy := make([]SkeletonOption, len(options))
+	copy(optionsCopy, options)
+	return builder.addStep(fmt.Sprintf("poll \"%s\"", name), func(state *collectionBuildState) error {
+		parser := state.builder.Parser
+		skel := NewPollSkeleton(name)
+		for _, option := range optionsCopy {
+			if optionErr := validateBuilderOption(parser, option); optionErr != nil {
+				return optionErr
+			}
+			skel.AddOption(option.Name, option.Description)
+			if optionErr := parser.validateNewOption(skel.Options); optionErr != nil {
+				return optionErr
+			}
+		}
+		// at least one option is always required, otherwise the poll can't be parsed
+		if len(skel.Options) == 0 || (parser.MinNumOptions >= 0 && len(skel.Options) < parser.MinNumOptions) {
+			return NewPollingSyntaxError(nil, "poll \"%s\" contains only %d option(s), expected at least %d",
+				name, len(skel.Options), parser.MinNumOptions)
+		}
+		return state.addSkeleton(skel)
+	})
+}
+
+// validateBuilderOption tests if the name and the description of an option are parsed back unchanged.
+func validateBuilderOption(parser *PollCollectionParser, option SkeletonOption) error {
+	if optionErr := validateBuilderLine(optionLineRx, "* ", option.Name, "option"); optionErr != nil {
+		return optionErr
+	}
+	if parser.OptionDescriptionSeparator != "" && strings.Contains(option.Name, parser.OptionDescriptionSeparator) {
+		return NewPollingSyntaxError(nil, "option \"%s\" contains the description separator \"%s\"",
+			option.Name, parser.OptionDescriptionSeparator)
+	}
+	description := option.Description
+	if description == "" {
+		return nil
+	}
+	if strings.ContainsAny(description, "\r\n") || strings.TrimSpace(description) != description {
+		return NewPollingSyntaxError(nil,
+			"invalid description \"%s\", it must be a single line without leading or trailing spaces", description)
+	}
+	if optionLineRx.MatchString(description) || groupLineRx.MatchString(description) ||
+		pollLineRx.MatchString(description) {
+		return NewPollingSyntaxError(nil, "description \"%s\" would be parsed as an option, group or poll",
+			description)
+	}
+	return nil
+}
+
+// MoneyPoll adds a poll about a currency value to the last group, the value is parsed with CurrencyParser.
+func (builder *CollectionBuilder) MoneyPoll(name string, value string) *CollectionBuilder {
+	return builder.addStep(fmt.Sprintf("money poll \"%s\"", name), func(state *collectionBuildState) error {
+		currency, currencyErr := state.builder.CurrencyParser.Parse(value)
+		if currencyErr != nil {
+			return NewPollingSyntaxError(currencyErr, "Can't parse money value")
+		}
+		return state.addMoneySkeleton(name, currency)
+	})
+}
+
+// MoneyPollValue works as MoneyPoll but the value is given as a CurrencyValue.
+func (builder *CollectionBuilder) MoneyPollValue(name string, value CurrencyValue) *CollectionBuilder {
+	return builder.addStep(fmt.Sprintf("money poll \"%s\"", name), func(state *collectionBuildState) error {
+		return state.addMoneySkeleton(name, value)
+	})
+}
+
+// MoneyPollWithRange works as MoneyPoll but also sets the min value and step of the poll (see MoneyPollSkeleton),
+// all values are parsed with CurrencyParser. An empty minValue or step means that there is no such restriction.
+// The same rules as in the polls file apply: The min value must not be greater than the value and the step must
+// be > 0.
+func (builder *CollectionBuilder) MoneyPollWithRange(name, value, minValue, step string) *CollectionBuilder {
+	return builder.addStep(fmt.Sprintf("money poll \"%s\"", name), func(state *collectionBuildState) error {
+		currency, currencyErr := state.builder.CurrencyParser.Parse(value)
+		if currencyErr != nil {
+			return NewPollingSyntaxError(currencyErr, "Can't parse money value")
+		}
+		skel := NewMoneyPollSkeleton(name, currency)
+		if minValue != "" {
+			var minErr error
+			if skel.MinValue, minErr = parseMoneyRangeValue("min", minValue, state.builder.CurrencyParser); minErr != nil {
+				return minErr
+			}
+			if minErr = checkMoneyRangeMin(skel.MinValue, currency); minErr != nil {
+				return minErr
+			}
+		}
+		if step != "" {
+			var stepErr error
+			if skel.Step, stepErr = parseMoneyRangeValue("step", step, state.builder.CurrencyParser); stepErr != nil {
+				return stepErr
+			}
+		}
+		return state.addMoneyPollSkeleton(skel)
+	})
+}
+
+func (state *collectionBuildState) addMoneySkeleton(name string, value CurrencyValue) error {
+	return state.addMoneyPollSkeleton(NewMoneyPollSkeleton(name, value))
+}
+
+func (state *collectionBuildState) addMoneyPollSkeleton(skel *MoneyPollSkeleton) error {
+	value := skel.Value
+	if value.ValueCents < 0 {
+		return NewPollingSemanticError(nil, "value %d describes a negative value, can't be used in a median poll",
+			value.ValueCents)
+	}
+	if valueErr := state.builder.Parser.validateMoneyValue(value); valueErr != nil {
+		return valueErr
+	}
+	return state.addSkeleton(skel)
+}
+
+// Build validates all steps and returns the collection.
+//
+// If a step is invalid an error of type CollectionBuilderError is returned that wraps the original error, for example
+// a PollingSyntaxError, ParserValidationError or DuplicateError.
+func (builder *CollectionBuilder) Build() (*PollSkeletonCollection, error) {
+	if builder.Parser == nil {
+		builder.Parser = NewPollCollectionParser()
+	}
+	if builder.CurrencyParser == nil {
+		builder.CurrencyParser = DefaultCurrencyHandler
+	}
+	titleErr := validateBuilderLine(headLineRx, "# ", builder.title, "title")
+	if titleErr == nil {
+		titleErr = builder.Parser.validateTitle(builder.title)
+	}
+	if titleErr != nil {
+		return nil, CollectionBuilderError{Step: 0, Description: fmt.Sprintf("title \"%s\"", builder.title), Err: titleErr}
+	}
+	state := &collectionBuildState{
+		builder:  builder,
+		coll:     NewPollSkeletonCollection(builder.title),
+		names:    make(map[string]struct{}),
+		numSkels: 0,
+	}
+	for i, step := range builder.steps {
+		if stepErr := step.apply(state); stepErr != nil {
+			return nil, CollectionBuilderError{Step: i + 1, Description: step.description, Err: stepErr}
+		}
+	}
+	return state.coll, nil
+}
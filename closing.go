@@ -0,0 +1,109 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ErrPollClosed is returned (wrapped) by AddVote, RemoveVote, ReplaceVote and AddSealed of a closed poll, see
+// PollCloser.
+var ErrPollClosed = NewPollingSemanticError(nil, "poll is closed")
+
+// ErrReopenNotAllowed is returned by PollCloser.Reopen if AllowReopen is false.
+var ErrReopenNotAllowed = NewPollingSemanticError(nil, "reopening the poll is not allowed")
+
+// PollCloser is embedded in all polls of this package, it is used to close a poll (for example when the deadline
+// for a poll has passed).
+//
+// Once Close has been called AddVote, RemoveVote, ReplaceVote and AddSealed return an error wrapping ErrPollClosed,
+// so late votes are rejected deterministically (also by a BasicPollLiveTally). Closing doesn't change the votes,
+// so the poll can still be tallied.
+// Reopen opens the poll again, to avoid reopening a poll by accident this is only allowed if AllowReopen is true.
+//
+// The state is not compared by the Equals methods of the polls, DeepClone copies it.
+// A poll wrapped in a SynchronizedPoll should be closed with ClosePoll, this way closing is serialized with AddVote.
+type PollCloser struct {
+	AllowReopen bool
+	closed      bool
+}
+
+// Close closes the poll, closing a closed poll does nothing.
+func (closer *PollCloser) Close() {
+	closer.closed = true
+}
+
+// Closed returns true if the poll has been closed (and not reopened).
+func (closer *PollCloser) Closed() bool {
+	return closer.closed
+}
+
+// Reopen opens a closed poll again, if AllowReopen is false ErrReopenNotAllowed is returned and the poll stays
+// closed.
+func (closer *PollCloser) Reopen() error {
+	if !closer.AllowReopen {
+		return ErrReopenNotAllowed
+	}
+	closer.closed = false
+	return nil
+}
+
+// clone returns a copy of the closer, used in DeepClone of the polls.
+func (closer *PollCloser) clone() PollCloser {
+	return PollCloser{AllowReopen: closer.AllowReopen, closed: closer.closed}
+}
+
+// checkOpen returns an error wrapping ErrPollClosed if the poll is closed, action describes what was tried
+// (for example "add vote") and voterName is the name of the voter of the vote.
+func (closer *PollCloser) checkOpen(action, voterName string) error {
+	if !closer.closed {
+		return nil
+	}
+	return fmt.Errorf("can't %s of voter \"%s\": %w", action, voterName, ErrPollClosed)
+}
+
+// checkOpenForVoter works as checkOpen but gets the voter (which can be nil).
+func (closer *PollCloser) checkOpenForVoter(action string, voter *Voter) error {
+	if voter == nil {
+		return closer.checkOpen(action, "")
+	}
+	return closer.checkOpen(action, voter.Name)
+}
+
+// ClosablePoll is a poll that can be closed, see PollCloser.
+// All polls implemented at the moment implement this interface.
+type ClosablePoll interface {
+	AbstractPoll
+	Close()
+	Closed() bool
+	Reopen() error
+}
+
+// ClosePoll closes a poll that implements ClosablePoll.
+//
+// For a SynchronizedPoll the wrapped poll is closed while holding the lock, thus all AddVote calls that start after
+// ClosePoll returns are rejected. If the poll doesn't implement ClosablePoll a PollTypeError is returned.
+func ClosePoll(poll AbstractPoll) error {
+	if syncPoll, isSync := poll.(SynchronizedPoll); isSync {
+		return syncPoll.WithLock(ClosePoll)
+	}
+	closable, ok := poll.(ClosablePoll)
+	if !ok {
+		return NewPollTypeError("can't close poll of type %s", reflect.TypeOf(poll))
+	}
+	closable.Close()
+	return nil
+}
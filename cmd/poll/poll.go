@@ -34,7 +34,7 @@ import (
 
 const version = "v0.1.0"
 
-var currencyHandler = gopolls.SimpleEuroHandler{}
+var currencyHandler gopolls.CurrencyHandler = gopolls.SimpleEuroHandler{}
 
 // used to store the "root" path for static files and templates, avoid passing it around as argument
 // should be fine enough in this main file
@@ -43,14 +43,21 @@ var staticRoot string
 var comma rune
 var port uint64
 var host string
+var maxUploadBytes int
 
 type mainContext struct {
 	Voters         []*gopolls.Voter
 	PollCollection *gopolls.PollSkeletonCollection
+	// maps created when the voters / collection are uploaded, they're passed to gopolls.Evaluate
+	VoterMap    gopolls.VoterMap
+	SkeletonMap gopolls.PollSkeletonMap
 	// in case voters were loaded from a file this value is set to the name
 	VotersSourceFileName string
 	// in case collection was loaded from a file this value is set to this path
 	CollectionSourceFileName string
+	// warnings for the voters and the collection, see gopolls.LintVoters and gopolls.LintCollection
+	VotersWarnings     []gopolls.LintWarning
+	CollectionWarnings []gopolls.LintWarning
 
 	// if you're reading this: don't do this in any live code, it's only here for this app, you would never do that
 	// because this is a small demonstration that should be used nowhere I think it will be fine
@@ -167,6 +174,10 @@ func baseTemplates() *template.Template {
 			percentage := gopolls.ComputePercentage(a, b)
 			return gopolls.FormatPercentage(percentage) + "%"
 		},
+		// returns the pairwise comparisons of a schulze result as english sentences
+		"explainPairwise": func(res *gopolls.SchulzeResult, optionNames []string) ([]string, error) {
+			return res.ExplainPairwise(optionNames, gopolls.EnglishPairwiseFormatter)
+		},
 		"dict": func(values ...interface{}) (map[string]interface{}, error) {
 			if len(values)%2 != 0 {
 				return nil, errors.New("invalid dict call")
@@ -216,6 +227,77 @@ func (h *mainHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http.R
 	return executeTemplate(h.template, renderContext, buff)
 }
 
+type importStateHandler struct {
+	template *template.Template
+}
+
+func newImportStateHandler(base *template.Template) *importStateHandler {
+	t := readTemplate(base, "index.gohtml")
+	return &importStateHandler{t}
+}
+
+// newStateArchiver returns the archiver used to export and import the state, it uses the same currency handler as the
+// other handlers.
+func newStateArchiver() *gopolls.StateArchiver {
+	archiver := gopolls.NewStateArchiver()
+	archiver.CurrencyHandler = currencyHandler
+	archiver.MaxTotalBytes = maxUploadBytes
+	// the archive contains four files, each of them may be as big as a single upload
+	if maxUploadBytes >= 0 {
+		archiver.MaxUncompressedBytes = 4 * maxUploadBytes
+	}
+	return archiver
+}
+
+func (h *importStateHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http.Request) handlerRes {
+	renderContext := newRenderContext(context)
+
+	if r.Method == http.MethodGet {
+		return executeTemplate(h.template, renderContext, buff)
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		return newHandlerRes(http.StatusInternalServerError, err)
+	}
+
+	file, handler, formErr := r.FormFile("state-file")
+	if formErr != nil {
+		return newHandlerRes(http.StatusInternalServerError, formErr)
+	}
+
+	defer file.Close()
+
+	state, stateErr := newStateArchiver().Load(file)
+	if stateErr != nil {
+		if errors.Is(stateErr, gopolls.ErrPoll) {
+			renderContext.AdditionalData["error"] = stateErr
+			return executeTemplate(h.template, renderContext, buff)
+		}
+		return newHandlerRes(http.StatusInternalServerError, stateErr)
+	}
+	voterMap, name, hasDuplicates := gopolls.VotersToMapChecked(state.Voters)
+	if hasDuplicates {
+		renderContext.AdditionalData["error"] = gopolls.NewDuplicateError(fmt.Sprintf("duplicate voter name %s", name))
+		return executeTemplate(h.template, renderContext, buff)
+	}
+	skeletonMap, name, hasDuplicates := state.Collection.SkeletonsToMapChecked()
+	if hasDuplicates {
+		renderContext.AdditionalData["error"] = gopolls.NewDuplicateError(fmt.Sprintf("duplicate poll name %s", name))
+		return executeTemplate(h.template, renderContext, buff)
+	}
+	context.Voters = state.Voters
+	context.VoterMap = voterMap
+	context.VotersSourceFileName = handler.Filename
+	context.VotersWarnings = gopolls.LintVoters(state.Voters)
+	context.PollCollection = state.Collection
+	context.SkeletonMap = skeletonMap
+	context.CollectionSourceFileName = handler.Filename
+	context.CollectionWarnings = gopolls.LintCollection(state.Collection)
+	log.Printf("Successfuly imported %d voters and %d polls from %s\n", len(state.Voters),
+		state.Collection.NumSkeletons(), handler.Filename)
+	return newRedirectHandlerRes(http.StatusFound, "/home")
+}
+
 type aboutHandler struct {
 	template *template.Template
 }
@@ -254,7 +336,9 @@ func (h *votersHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http
 
 	// already clear voters
 	context.Voters = make([]*gopolls.Voter, 0, 0)
+	context.VoterMap = nil
 	context.VotersSourceFileName = ""
+	context.VotersWarnings = nil
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
 		return newHandlerRes(http.StatusInternalServerError, err)
@@ -270,11 +354,20 @@ func (h *votersHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http
 
 	// now try to parse from file
 	votersParser := gopolls.NewVotersParser()
+	votersParser.MaxTotalBytes = maxUploadBytes
+	votersParser.Progress = logProgress(handler.Filename)
+	votersParser.SourceName = handler.Filename
+	if r.FormValue("merge-duplicates") != "" {
+		votersParser.DuplicatePolicy = gopolls.MergeDuplicateVoters
+	}
 	voters, votersErr := votersParser.ParseVoters(file)
 
+	var voterMap gopolls.VoterMap
 	if votersErr == nil {
 		// check for duplicate names, if there are any set error to a duplicate error
-		if name, hasDuplicates := gopolls.HasDuplicateVoters(voters); hasDuplicates {
+		var name string
+		var hasDuplicates bool
+		if voterMap, name, hasDuplicates = gopolls.VotersToMapChecked(voters); hasDuplicates {
 			votersErr = gopolls.NewDuplicateError(fmt.Sprintf("duplicate voter name %s", name))
 		}
 	}
@@ -282,7 +375,9 @@ func (h *votersHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http
 	if votersErr == nil {
 		// if it is valid just redirect to voters page again
 		context.Voters = voters
+		context.VoterMap = voterMap
 		context.VotersSourceFileName = handler.Filename
+		context.VotersWarnings = gopolls.LintVoters(voters)
 		log.Printf("Successfuly parsed %d voters from %s\n", len(voters), handler.Filename)
 		res := newRedirectHandlerRes(http.StatusFound, "/voters")
 		return res
@@ -319,7 +414,9 @@ func (h *pollsHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http.
 
 	// already clear polls
 	context.PollCollection = gopolls.NewPollSkeletonCollection("dummy")
+	context.SkeletonMap = nil
 	context.CollectionSourceFileName = ""
+	context.CollectionWarnings = nil
 
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
@@ -336,11 +433,17 @@ func (h *pollsHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http.
 
 	// now try to parse
 	collectionParser := gopolls.NewPollCollectionParser()
+	collectionParser.MaxTotalBytes = maxUploadBytes
+	collectionParser.Progress = logProgress(handler.Filename)
+	collectionParser.SourceName = handler.Filename
 	collection, collectionErr := collectionParser.ParseCollectionSkeletons(file, currencyHandler)
 
+	var skeletonMap gopolls.PollSkeletonMap
 	if collectionErr == nil {
 		// now check for duplicate names in the polls, if there are any set error to a duplicate error
-		if name, hasDuplicates := collection.HasDuplicateSkeleton(); hasDuplicates {
+		var name string
+		var hasDuplicates bool
+		if skeletonMap, name, hasDuplicates = collection.SkeletonsToMapChecked(); hasDuplicates {
 			collectionErr = gopolls.NewDuplicateError(fmt.Sprintf("duplicate poll name %s", name))
 		}
 	}
@@ -348,7 +451,9 @@ func (h *pollsHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http.
 	if collectionErr == nil {
 		// just redirect to polls page again
 		context.PollCollection = collection
+		context.SkeletonMap = skeletonMap
 		context.CollectionSourceFileName = handler.Filename
+		context.CollectionWarnings = gopolls.LintCollection(collection)
 		log.Printf("Successfuly parsed %d polls from %s\n", collection.NumSkeletons(), handler.Filename)
 		res := newRedirectHandlerRes(http.StatusFound, "/polls")
 		return res
@@ -412,178 +517,124 @@ func (h *evaluationHandler) Handle(context *mainContext, buff *bytes.Buffer, r *
 
 	defer file.Close()
 
-	// try to parse the matrix
-	csvReader := gopolls.NewVotesCSVReader(file)
-	csvReader.Sep = comma
-	matrix, matrixErr := gopolls.ReadMatrixFromCSV(csvReader)
-	if matrixErr != nil {
-		return render(matrixErr)
-	}
-	votersMap, votersMapErr := gopolls.VotersToMap(context.Voters)
-	if votersMapErr != nil {
-		return render(votersMapErr)
-	}
-
-	pollsMap, pollsMapErr := context.PollCollection.SkeletonsToMap()
-	if pollsMapErr != nil {
-		return render(pollsMapErr)
-	}
-
-	polls, pollsErr := gopolls.ConvertSkeletonMapToEmptyPolls(pollsMap,
-		gopolls.DefaultSkeletonConverter)
-	if pollsErr != nil {
-		return render(pollsErr)
+	// polls with two options are basic polls unless Schulze was chosen in the form
+	converterOpts := gopolls.NewConverterOptions()
+	if r.FormValue("two-option-polls") == "schulze" {
+		converterOpts.BasicThreshold = 0
 	}
 
-	// next try to parse the results, first generate the parsers
 	// in the csv we only allow raw cents as input
-	defaultParsers := gopolls.GenerateDefaultParserTemplateMap()
-	defaultParsers[gopolls.MedianPollType] = gopolls.NewMedianVoteParser(gopolls.NewRawCentCurrencyParser())
-	parsers, parsersErr := gopolls.CustomizeParsersToMap(polls, defaultParsers)
-	if parsersErr != nil {
-		return render(parsersErr)
-	}
-
-	// parsers are of type ParserCustomizer, we need type VoteParser (this is actually a sub type)
-	parsersCasted := make(map[string]gopolls.VoteParser, len(parsers))
-	for name, p := range parsers {
-		parsersCasted[name] = p
-	}
-
-	// now add all votes
-	policies := gopolls.GeneratePoliciesMap(gopolls.IgnoreEmptyVote, polls)
-	_, _, votesErr := matrix.FillPollsWithVotes(polls, votersMap, parsersCasted, policies,
-		true, false)
-	if votesErr != nil {
-		return render(votesErr)
+	parserTemplates := gopolls.GenerateDefaultParserTemplateMap()
+	parserTemplates[gopolls.MedianPollType] = gopolls.NewMedianVoteParser(gopolls.NewRawCentCurrencyParser())
+	opts := gopolls.EvaluateOptions{
+		CurrencyHandler:   currencyHandler,
+		ParserTemplates:   parserTemplates,
+		SkeletonConverter: gopolls.NewSkeletonConverterWithOptions(converterOpts),
+		Sep:               comma,
+		ConfigureCSVReader: func(reader *gopolls.VotesCSVReader) {
+			reader.MaxTotalBytes = maxUploadBytes
+			reader.Progress = logProgress(handler.Filename)
+			reader.SourceName = handler.Filename
+			// report all malformed rows at once
+			reader.Mode = gopolls.LenientCSVMode
+		},
+		// polls can define their own policy in the polls file
+		DefaultEmptyPolicy: gopolls.IgnoreEmptyVote,
+		AllowMissingVoters: true,
+		AllowMissingPolls:  false,
+		FillOptions: []gopolls.FillOption{
+			gopolls.WithPollFilledCallback(func(pollName string, numVotes int) {
+				log.Printf("Added %d votes to poll %s\n", numVotes, pollName)
+			}),
+			gopolls.WithFillEventHandler(gopolls.LogPollEvents(standardLogger{})),
+		},
+		TallyOptions: []gopolls.TallyOption{
+			gopolls.WithTallyEventHandler(gopolls.LogPollEvents(standardLogger{})),
+		},
+		VoterMap:    context.VoterMap,
+		SkeletonMap: context.SkeletonMap,
 	}
-
-	// evaluate all polls
-	tallied, evalErr := evaluatePolls(polls)
+	evaluation, evalErr := gopolls.Evaluate(context.Voters, context.PollCollection, file, opts)
 	if evalErr != nil {
+		if evaluation.CSVReport != nil && !evaluation.CSVReport.Empty() {
+			renderContext.AdditionalData["csv_row_errors"] = evaluation.CSVReport.RowErrors
+		} else if evaluation.MatrixReport != nil && !evaluation.MatrixReport.Empty() {
+			renderContext.AdditionalData["matrix_issues"] = evaluation.MatrixReport.Issues
+		}
 		return render(evalErr)
 	}
-
 	renderContext.AdditionalData["source_file_name"] = handler.Filename
-	renderContext.AdditionalData["evaluation"] = tallied
 	renderContext.AdditionalData["title"] = context.PollCollection.Title
-	// prepare polls for nicer handling in templates, we group for each poll together:
-	// skeleton, poll, result
-	// we also create this by group
-	type templatePollEntry struct {
-		Skel   gopolls.AbstractPollSkeleton
-		Poll   gopolls.AbstractPoll
-		Result interface{}
-	}
-	type templateGroup struct {
-		Title string
-		Polls []*templatePollEntry
-	}
+	renderContext.AdditionalData["results"] = evaluation.Groups
+	renderContext.AdditionalData["empty_voters"] = evaluation.FillReport.EmptyVoters()
+	renderContext.AdditionalData["polls_without_votes"] = evaluation.FillReport.PollsWithoutVotes()
 
-	results := make([]*templateGroup, context.PollCollection.NumGroups())
+	return executeTemplate(h.evaluationResultsTemplate, renderContext, buff)
+}
 
-	for i, group := range context.PollCollection.Groups {
-		templateGroup := &templateGroup{
-			Title: group.Title,
-			Polls: make([]*templatePollEntry, group.NumSkeletons()),
+// exportCSVTemplateHandleFunc doesn't use the appHandler interface: the csv file is streamed directly to the
+// response instead of being buffered, this way also templates with many voters can be exported
+func exportCSVTemplateHandleFunc(context *mainContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Handler exportCSVTemplate called for %s\n", r.URL)
+		start := time.Now()
+		context.mutex.Lock()
+		defer context.mutex.Unlock()
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=votes.csv")
+		csvWriter := gopolls.NewVotesCSVWriter(w)
+		csvWriter.Sep = comma
+		skels := context.PollCollection.CollectSkeletons()
+		if headErr := csvWriter.WriteHead(skels); headErr != nil {
+			log.Println("Unable to write to http response", headErr)
+			return
 		}
-		results[i] = templateGroup
-		for j, pollSkell := range group.Skeletons {
-			name := pollSkell.GetName()
-			templateGroup.Polls[j] = &templatePollEntry{
-				Skel:   pollSkell,
-				Poll:   polls[name],
-				Result: tallied[name],
+		// write empty template, values will be re-used
+		values := make([]string, len(skels))
+		for _, voter := range context.Voters {
+			if rowErr := csvWriter.WriteVoterRow(voter, values); rowErr != nil {
+				log.Println("Unable to write to http response", rowErr)
+				return
 			}
 		}
+		if flushErr := csvWriter.Flush(); flushErr != nil {
+			log.Println("Unable to write to http response", flushErr)
+			return
+		}
+		log.Println("Handler done after", time.Since(start))
 	}
-
-	renderContext.AdditionalData["results"] = results
-
-	return executeTemplate(h.evaluationResultsTemplate, renderContext, buff)
 }
 
-type exportCSVTemplateHandler struct{}
-
-func newExportCSVTemplateHandler() exportCSVTemplateHandler {
-	return exportCSVTemplateHandler{}
-}
-
-func (h exportCSVTemplateHandler) Handle(context *mainContext, buff *bytes.Buffer, r *http.Request) handlerRes {
-	csvWriter := gopolls.NewVotesCSVWriter(buff)
-	csvWriter.Sep = comma
-	// write empty template
-	writeErr := csvWriter.GenerateEmptyTemplate(context.Voters, context.PollCollection.CollectSkeletons())
-	if writeErr != nil {
-		return newHandlerRes(http.StatusInternalServerError, writeErr)
+// exportStateHandleFunc streams the state archive (voters and polls) directly to the response, see
+// gopolls.StateArchiver
+func exportStateHandleFunc(context *mainContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Handler exportState called for %s\n", r.URL)
+		start := time.Now()
+		context.mutex.Lock()
+		defer context.mutex.Unlock()
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", "attachment; filename=gopolls-state.zip")
+		if err := newStateArchiver().Save(w, context.Voters, context.PollCollection, nil); err != nil {
+			log.Println("Unable to write to http response", err)
+			return
+		}
+		log.Println("Handler done after", time.Since(start))
 	}
-	res := newHandlerRes(http.StatusOK, nil)
-	res.ContentType = "text/csv"
-	res.FileName = "votes.csv"
-	return res
 }
 
-func evaluatePolls(polls gopolls.PollMap) (map[string]interface{}, error) {
-	res := make(map[string]interface{}, len(polls))
-
-	// type used for the channel to communicate
-	type pollRes struct {
-		pollName string
-		res      interface{}
-		err      error
-	}
-
-	ch := make(chan pollRes, 1)
-
-	// evaluate each poll
-	for pollName, p := range polls {
-		go func(name string, poll gopolls.AbstractPoll) {
-			var evaluated interface{}
-			var pollErr error
-			switch typedPoll := poll.(type) {
-			case *gopolls.BasicPoll:
-				if truncated := typedPoll.TruncateVoters(); len(truncated) > 0 {
-					pollErr = errors.New("there were invalid votes for a poll! should not happen")
-				} else {
-					evaluated = typedPoll.Tally()
-				}
-			case *gopolls.MedianPoll:
-				if truncated := typedPoll.TruncateVoters(); len(truncated) > 0 {
-					pollErr = errors.New("there were invalid votes for a poll! should not happen")
-				} else {
-					evaluated = typedPoll.Tally(gopolls.NoWeight)
-				}
-			case *gopolls.SchulzePoll:
-				if truncated := typedPoll.TruncateVoters(); len(truncated) > 0 {
-					pollErr = errors.New("there were invalid votes for a poll! should not happen")
-				} else {
-					evaluated = typedPoll.Tally()
-				}
-			default:
-				pollErr = fmt.Errorf("unsupported poll type %s", reflect.TypeOf(poll))
-			}
-			ch <- pollRes{
-				pollName: name,
-				res:      evaluated,
-				err:      pollErr,
-			}
-		}(pollName, p)
+// logProgress returns a progress callback for the parsers that logs the number of lines read from fileName.
+func logProgress(fileName string) gopolls.ProgressFunc {
+	return func(linesProcessed int) {
+		log.Printf("Processed %d lines of %s\n", linesProcessed, fileName)
 	}
+}
 
-	var err error
+// standardLogger implements gopolls.Logger by writing to the standard logger of the log package.
+type standardLogger struct{}
 
-	for i := 0; i < len(polls); i++ {
-		pollRes := <-ch
-		if err == nil && pollRes.err != nil {
-			err = pollRes.err
-		}
-		res[pollRes.pollName] = pollRes.res
-	}
-
-	if err != nil {
-		return nil, err
-	}
-	return res, nil
+func (standardLogger) Printf(format string, args ...interface{}) {
+	log.Printf(format, args...)
 }
 
 func main() {
@@ -599,13 +650,15 @@ func main() {
 	aboutH := newAboutHandler(base)
 	votersH := newVotersHandler(base)
 	pollsH := newPollsHandler(base)
-	csvH := newExportCSVTemplateHandler()
 	evaluateH := newEvaluationHandler(base)
+	importH := newImportStateHandler(base)
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(staticRoot))))
 	http.HandleFunc("/voters", toHandleFunc(votersH, &context))
 	http.HandleFunc("/polls", toHandleFunc(pollsH, &context))
-	http.HandleFunc("/votes/csv", toHandleFunc(csvH, &context))
+	http.HandleFunc("/votes/csv", exportCSVTemplateHandleFunc(&context))
 	http.HandleFunc("/evaluate", toHandleFunc(evaluateH, &context))
+	http.HandleFunc("/export", exportStateHandleFunc(&context))
+	http.HandleFunc("/import", toHandleFunc(importH, &context))
 	http.HandleFunc("/home", toHandleFunc(mainH, &context))
 	http.HandleFunc("/about", toHandleFunc(aboutH, &context))
 	addr := fmt.Sprintf("%s:%d", host, port)
@@ -668,6 +721,9 @@ func parseArgs() {
 	flag.StringVar(&commaVar, "comma", ";", "Comma separator for csv files, for historical reasons defaults to \";\"")
 	flag.Uint64Var(&port, "port", 8080, "The port to run the web server on, defaults to 8080")
 	flag.StringVar(&host, "host", "localhost", "The address to run the webserver on, defaults to \"localhost\"")
+	flag.IntVar(&maxUploadBytes, "max-upload-bytes", 1<<20, "Maximal size of uploaded files in bytes (-1 for no limit), defaults to 1 MiB")
+	var currencyDecimals int
+	flag.IntVar(&currencyDecimals, "currency-decimals", gopolls.DefaultCurrencyDecimalPlaces, "Number of decimal places of money values (votes for money polls are given in the smallest unit), defaults to 2")
 	// test if help was given
 	if len(os.Args) > 1 && os.Args[1] == "help" {
 		printUsage()
@@ -705,6 +761,13 @@ func parseArgs() {
 		log.Fatalf("comma separator must be a single character, got \"%s\"\n", commaVar)
 	}
 	comma = commaRunes[0]
+	if currencyDecimals != gopolls.DefaultCurrencyDecimalPlaces {
+		preciseHandler, handlerErr := gopolls.NewPreciseCurrencyHandler(currencyDecimals)
+		if handlerErr != nil {
+			log.Fatalf("invalid number of currency decimals: %v", handlerErr)
+		}
+		currencyHandler = preciseHandler
+	}
 	templateRoot = templateDir
 	staticRoot = staticDir
 }
@@ -0,0 +1,449 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file contains functions to compare two revisions of a PollSkeletonCollection (for example two drafts of an
+// agenda), see DiffCollections.
+
+// GroupRename describes a group whose title changed, see CollectionDiffOptions.DetectRenames.
+type GroupRename struct {
+	OldTitle, NewTitle string
+}
+
+// PollRename describes a poll whose name changed, see CollectionDiffOptions.DetectRenames.
+type PollRename struct {
+	OldName, NewName string
+}
+
+// PollChange describes the changes of a poll that appears in both collections (with the same name).
+//
+// If the skeleton types differ TypeChanged is true and OldType and NewType are the skeleton types, all other fields
+// are not set in this case.
+// For a PollSkeleton AddedOptions contains the options that only appear in the new skeleton (in the order of the new
+// skeleton) and RemovedOptions the options that only appear in the old skeleton (in the order of the old skeleton).
+// OptionsReordered is true if the options that appear in both skeletons appear in a different order.
+// For a MoneyPollSkeleton ValueChanged is true if the value (or the currency) changed, OldValue and NewValue are the
+// values of both skeletons.
+type PollChange struct {
+	Name             string
+	TypeChanged      bool
+	OldType, NewType string
+	AddedOptions     []string
+	RemovedOptions   []string
+	OptionsReordered bool
+	ValueChanged     bool
+	OldValue         CurrencyValue
+	NewValue         CurrencyValue
+}
+
+// OptionsChanged returns true if options were added, removed or reordered.
+func (change PollChange) OptionsChanged() bool {
+	return len(change.AddedOptions) > 0 || len(change.RemovedOptions) > 0 || change.OptionsReordered
+}
+
+// Changed returns true if there is any change.
+func (change PollChange) Changed() bool {
+	return change.TypeChanged || change.OptionsChanged() || change.ValueChanged
+}
+
+// String describes all changes (or "no changes").
+func (change PollChange) String() string {
+	w := &diffWriter{}
+	if change.TypeChanged {
+		w.change("type", change.OldType, change.NewType)
+	}
+	if len(change.AddedOptions) > 0 {
+		w.parts = append(w.parts, fmt.Sprintf("added options %s", strings.Join(change.AddedOptions, ", ")))
+	}
+	if len(change.RemovedOptions) > 0 {
+		w.parts = append(w.parts, fmt.Sprintf("removed options %s", strings.Join(change.RemovedOptions, ", ")))
+	}
+	if change.OptionsReordered {
+		w.parts = append(w.parts, "options reordered")
+	}
+	if change.ValueChanged {
+		w.change("value", change.OldValue.DefaultFormatString("."), change.NewValue.DefaultFormatString("."))
+	}
+	return w.String()
+}
+
+// CollectionDiff describes the changes between two revisions of a collection, see DiffCollections.
+//
+// TitleChanged is true if the title of the collection changed, OldTitle and NewTitle are the titles.
+// AddedGroups and RemovedGroups contain the titles of groups that only appear in one of the collections,
+// RenamedGroups the groups that were detected as renamed. AddedPolls, RemovedPolls and RenamedPolls work in the same
+// way for polls, ChangedPolls contains all polls with the same name in both collections that have changed
+// (see PollChange).
+//
+// Added and renamed entries are in the order of the new collection, removed entries in the order of the old
+// collection, thus the diff of two collections is always the same.
+type CollectionDiff struct {
+	TitleChanged       bool
+	OldTitle, NewTitle string
+	AddedGroups        []string
+	RemovedGroups      []string
+	RenamedGroups      []GroupRename
+	AddedPolls         []string
+	RemovedPolls       []string
+	RenamedPolls       []PollRename
+	ChangedPolls       []PollChange
+}
+
+// Changed returns true if there is any change.
+func (diff CollectionDiff) Changed() bool {
+	return diff.TitleChanged || len(diff.AddedGroups) > 0 || len(diff.RemovedGroups) > 0 ||
+		len(diff.RenamedGroups) > 0 || len(diff.AddedPolls) > 0 || len(diff.RemovedPolls) > 0 ||
+		len(diff.RenamedPolls) > 0 || len(diff.ChangedPolls) > 0
+}
+
+// String describes all changes (or "no changes").
+func (diff CollectionDiff) String() string {
+	w := &diffWriter{}
+	if diff.TitleChanged {
+		w.change("title", diff.OldTitle, diff.NewTitle)
+	}
+	for _, title := range diff.AddedGroups {
+		w.parts = append(w.parts, fmt.Sprintf("added group \"%s\"", title))
+	}
+	for _, title := range diff.RemovedGroups {
+		w.parts = append(w.parts, fmt.Sprintf("removed group \"%s\"", title))
+	}
+	for _, rename := range diff.RenamedGroups {
+		w.parts = append(w.parts, fmt.Sprintf("renamed group \"%s\" -> \"%s\"", rename.OldTitle, rename.NewTitle))
+	}
+	for _, name := range diff.AddedPolls {
+		w.parts = append(w.parts, fmt.Sprintf("added poll \"%s\"", name))
+	}
+	for _, name := range diff.RemovedPolls {
+		w.parts = append(w.parts, fmt.Sprintf("removed poll \"%s\"", name))
+	}
+	for _, rename := range diff.RenamedPolls {
+		w.parts = append(w.parts, fmt.Sprintf("renamed poll \"%s\" -> \"%s\"", rename.OldName, rename.NewName))
+	}
+	for _, change := range diff.ChangedPolls {
+		w.parts = append(w.parts, fmt.Sprintf("poll \"%s\": %s", change.Name, change.String()))
+	}
+	return w.String()
+}
+
+// CollectionDiffOptions describes how DiffCollectionsWithOptions matches polls and groups.
+//
+// Polls are always matched by name and groups by title. If DetectRenames is true a removed poll and an added poll
+// with the same skeleton type and the same structure (the same options in the same order, or the same money value)
+// are reported as a renamed poll instead. In the same way a removed group and an added group that contain the same
+// polls (renamed polls are considered) are reported as a renamed group.
+type CollectionDiffOptions struct {
+	DetectRenames bool
+}
+
+// DiffCollections returns the changes from oldColl to newColl, polls are matched by name and groups by title.
+//
+// It is DiffCollectionsWithOptions with DetectRenames set to false.
+func DiffCollections(oldColl, newColl *PollSkeletonCollection) CollectionDiff {
+	return DiffCollectionsWithOptions(oldColl, newColl, CollectionDiffOptions{DetectRenames: false})
+}
+
+// DiffCollectionsWithOptions returns the changes from oldColl to newColl, see CollectionDiff and
+// CollectionDiffOptions.
+//
+// A collection should not contain duplicate poll names or group titles, if it does only the first occurrence is
+// compared. The rename detection is greedy: Each removed poll (in the order of oldColl) is matched with the first
+// added poll (in the order of newColl) with the same structure that has not been matched before.
+func DiffCollectionsWithOptions(oldColl, newColl *PollSkeletonCollection,
+	options CollectionDiffOptions) CollectionDiff {
+	res := CollectionDiff{
+		TitleChanged: oldColl.Title != newColl.Title,
+		OldTitle:     oldColl.Title,
+		NewTitle:     newColl.Title,
+	}
+	oldSkels, newSkels := firstSkeletons(oldColl), firstSkeletons(newColl)
+
+	// polls that only appear in one collection, in the order of the collections
+	var removed, added []AbstractPollSkeleton
+	for _, skel := range oldColl.CollectSkeletons() {
+		if _, inNew := newSkels[skel.GetName()]; !inNew {
+			removed = appendUniqueSkeleton(removed, skel)
+		}
+	}
+	compared := make(map[string]struct{}, len(newSkels))
+	for _, skel := range newColl.CollectSkeletons() {
+		name := skel.GetName()
+		oldSkel, inOld := oldSkels[name]
+		if !inOld {
+			added = appendUniqueSkeleton(added, skel)
+			continue
+		}
+		if _, isCompared := compared[name]; isCompared {
+			continue
+		}
+		compared[name] = struct{}{}
+		if change := diffSkeletons(oldSkel, skel); change.Changed() {
+			res.ChangedPolls = append(res.ChangedPolls, change)
+		}
+	}
+
+	// renamedTo maps the old name of a renamed poll to its new name
+	renamedTo := make(map[string]string)
+	if options.DetectRenames {
+		matched := make([]bool, len(added))
+		for _, oldSkel := range removed {
+			for i, newSkel := range added {
+				if !matched[i] && sameSkeletonStructure(oldSkel, newSkel) {
+					matched[i] = true
+					renamedTo[oldSkel.GetName()] = newSkel.GetName()
+					break
+				}
+			}
+		}
+		for i, newSkel := range added {
+			if matched[i] {
+				continue
+			}
+			res.AddedPolls = append(res.AddedPolls, newSkel.GetName())
+		}
+		// the renames are reported in the order of the new collection
+		for _, newSkel := range added {
+			for _, oldSkel := range removed {
+				if renamedTo[oldSkel.GetName()] == newSkel.GetName() {
+					res.RenamedPolls = append(res.RenamedPolls, PollRename{
+						OldName: oldSkel.GetName(),
+						NewName: newSkel.GetName(),
+					})
+				}
+			}
+		}
+	} else {
+		for _, skel := range added {
+			res.AddedPolls = append(res.AddedPolls, skel.GetName())
+		}
+	}
+	for _, skel := range removed {
+		if _, isRenamed := renamedTo[skel.GetName()]; !isRenamed {
+			res.RemovedPolls = append(res.RemovedPolls, skel.GetName())
+		}
+	}
+
+	diffGroups(&res, oldColl, newColl, options.DetectRenames, renamedTo)
+	return res
+}
+
+// firstSkeletons maps the name of each skeleton in coll to the first skeleton with this name.
+func firstSkeletons(coll *PollSkeletonCollection) PollSkeletonMap {
+	res := make(PollSkeletonMap, coll.NumSkeletons())
+	for _, skel := range coll.CollectSkeletons() {
+		if _, has := res[skel.GetName()]; !has {
+			res[skel.GetName()] = skel
+		}
+	}
+	return res
+}
+
+// appendUniqueSkeleton appends skel to skels if there is no skeleton with the same name in skels.
+func appendUniqueSkeleton(skels []AbstractPollSkeleton, skel AbstractPollSkeleton) []AbstractPollSkeleton {
+	for _, other := range skels {
+		if other.GetName() == skel.GetName() {
+			return skels
+		}
+	}
+	return append(skels, skel)
+}
+
+// diffGroups computes the group changes of the diff, renamedTo maps the old names of renamed polls to the new names.
+func diffGroups(res *CollectionDiff, oldColl, newColl *PollSkeletonCollection, detectRenames bool,
+	renamedTo map[string]string) {
+	oldTitles := make(map[string]struct{}, len(oldColl.Groups))
+	for _, group := range oldColl.Groups {
+		oldTitles[group.Title] = struct{}{}
+	}
+	newTitles := make(map[string]struct{}, len(newColl.Groups))
+	for _, group := range newColl.Groups {
+		newTitles[group.Title] = struct{}{}
+	}
+	var removed, added []*PollGroup
+	for _, group := range oldColl.Groups {
+		if _, inNew := newTitles[group.Title]; !inNew && !containsGroup(removed, group.Title) {
+			removed = append(removed, group)
+		}
+	}
+	for _, group := range newColl.Groups {
+		if _, inOld := oldTitles[group.Title]; !inOld && !containsGroup(added, group.Title) {
+			added = append(added, group)
+		}
+	}
+
+	renamedFrom := make(map[string]string)
+	if detectRenames {
+		matched := make([]bool, len(removed))
+		for _, newGroup := range added {
+			newPolls := groupPollNames(newGroup, nil)
+			for i, oldGroup := range removed {
+				if !matched[i] && newGroup.NumSkeletons() > 0 &&
+					stringSlicesEqual(groupPollNames(oldGroup, renamedTo), newPolls) {
+					matched[i] = true
+					renamedFrom[newGroup.Title] = oldGroup.Title
+					break
+				}
+			}
+		}
+	}
+	for _, group := range added {
+		if oldTitle, isRenamed := renamedFrom[group.Title]; isRenamed {
+			res.RenamedGroups = append(res.RenamedGroups, GroupRename{OldTitle: oldTitle, NewTitle: group.Title})
+		} else {
+			res.AddedGroups = append(res.AddedGroups, group.Title)
+		}
+	}
+	renamedOld := make(map[string]struct{}, len(renamedFrom))
+	for _, oldTitle := range renamedFrom {
+		renamedOld[oldTitle] = struct{}{}
+	}
+	for _, group := range removed {
+		if _, isRenamed := renamedOld[group.Title]; !isRenamed {
+			res.RemovedGroups = append(res.RemovedGroups, group.Title)
+		}
+	}
+}
+
+func containsGroup(groups []*PollGroup, title string) bool {
+	for _, group := range groups {
+		if group.Title == title {
+			return true
+		}
+	}
+	return false
+}
+
+// groupPollNames returns the names of all polls in the group, names that appear in renamedTo are replaced by the
+// new name.
+func groupPollNames(group *PollGroup, renamedTo map[string]string) []string {
+	res := make([]string, len(group.Skeletons))
+	for i, skel := range group.Skeletons {
+		res[i] = skel.GetName()
+		if newName, isRenamed := renamedTo[res[i]]; isRenamed {
+			res[i] = newName
+		}
+	}
+	return res
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, s := range a {
+		if s != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameSkeletonStructure tests if two skeletons have the same type and the same options (or money value), the names
+// are not compared. Skeletons of types not defined in this package are never considered equal.
+func sameSkeletonStructure(a, b AbstractPollSkeleton) bool {
+	switch typedA := a.(type) {
+	case *PollSkeleton:
+		typedB, ok := b.(*PollSkeleton)
+		return ok && stringSlicesEqual(typedA.Options, typedB.Options)
+	case *MoneyPollSkeleton:
+		typedB, ok := b.(*MoneyPollSkeleton)
+		return ok && typedA.Value.Equals(typedB.Value)
+	default:
+		return false
+	}
+}
+
+// diffSkeletons returns the changes from oldSkel to newSkel, both must have the same name.
+func diffSkeletons(oldSkel, newSkel AbstractPollSkeleton) PollChange {
+	res := PollChange{Name: newSkel.GetName()}
+	if oldSkel.SkeletonType() != newSkel.SkeletonType() {
+		res.TypeChanged = true
+		res.OldType, res.NewType = oldSkel.SkeletonType(), newSkel.SkeletonType()
+		return res
+	}
+	switch typedOld := oldSkel.(type) {
+	case *PollSkeleton:
+		typedNew, ok := newSkel.(*PollSkeleton)
+		if ok {
+			res.AddedOptions, res.RemovedOptions, res.OptionsReordered = diffOptions(typedOld.Options, typedNew.Options)
+		}
+	case *MoneyPollSkeleton:
+		typedNew, ok := newSkel.(*MoneyPollSkeleton)
+		if ok && !typedOld.Value.Equals(typedNew.Value) {
+			res.ValueChanged = true
+			res.OldValue, res.NewValue = typedOld.Value, typedNew.Value
+		}
+	}
+	return res
+}
+
+// diffOptions compares two lists of options, see PollChange.
+func diffOptions(oldOptions, newOptions []string) (added, removed []string, reordered bool) {
+	oldSet := make(map[string]struct{}, len(oldOptions))
+	for _, option := range oldOptions {
+		oldSet[option] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(newOptions))
+	for _, option := range newOptions {
+		newSet[option] = struct{}{}
+	}
+	var oldCommon, newCommon []string
+	for _, option := range oldOptions {
+		if _, inNew := newSet[option]; inNew {
+			oldCommon = append(oldCommon, option)
+		} else {
+			removed = append(removed, option)
+		}
+	}
+	for _, option := range newOptions {
+		if _, inOld := oldSet[option]; inOld {
+			newCommon = append(newCommon, option)
+		} else {
+			added = append(added, option)
+		}
+	}
+	reordered = !stringSlicesEqual(oldCommon, newCommon)
+	return
+}
+
+// InvalidatedPolls returns the names of all polls (in the order of diff.ChangedPolls) whose structure changed in a
+// way that makes existing votes unusable.
+//
+// This is the case if the skeleton type changed, if options were added, removed or reordered (votes refer to the
+// options by their position, for example Schulze rankings) or if the money value was lowered (or the currency
+// changed): Votes for a value between the new and the old value exceed the new value, the diff doesn't contain the
+// votes so all such polls are reported.
+// Renamed polls are not reported, their structure is unchanged. Votes for removed polls can't be used either, but
+// they're listed in diff.RemovedPolls.
+func InvalidatedPolls(diff CollectionDiff) []string {
+	var res []string
+	for _, change := range diff.ChangedPolls {
+		invalid := change.TypeChanged || change.OptionsChanged()
+		if change.ValueChanged {
+			if cmp, cmpErr := change.NewValue.Cmp(change.OldValue); cmpErr != nil || cmp < 0 {
+				invalid = true
+			}
+		}
+		if invalid {
+			res = append(res, change.Name)
+		}
+	}
+	return res
+}
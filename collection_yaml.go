@@ -0,0 +1,797 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// This file contains a reader and writer for poll collections in yaml.
+//
+// Only a small subset of yaml is supported (enough to describe a collection), this way no external dependency is
+// required: Block mappings and sequences (indented with spaces), flow sequences of scalars ("[Yes, No]"), plain,
+// single and double quoted scalars and comments. Anchors, tags, flow mappings, multi-line scalars and multiple
+// documents are not supported.
+
+// yamlLine is a non-empty line of a yaml document with comments removed.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlNodeKind int8
+
+const (
+	yamlScalar yamlNodeKind = iota
+	yamlSequence
+	yamlMapping
+)
+
+func (kind yamlNodeKind) String() string {
+	switch kind {
+	case yamlScalar:
+		return "scalar"
+	case yamlSequence:
+		return "sequence"
+	case yamlMapping:
+		return "mapping"
+	default:
+		return fmt.Sprintf("yamlNodeKind(%d)", kind)
+	}
+}
+
+// yamlNode is a node in a parsed yaml document.
+//
+// Depending on kind either value, items or keys / entries is set, keys contains the keys of a mapping in the order in
+// which they appear in the document. line is the line in which the node starts.
+type yamlNode struct {
+	kind    yamlNodeKind
+	line    int
+	value   string
+	items   []*yamlNode
+	keys    []string
+	entries map[string]*yamlNode
+}
+
+func yamlSyntaxError(lineNum int, msg string, a ...interface{}) error {
+	return NewPollingSyntaxError(nil, msg, a...).WithLineNum(lineNum)
+}
+
+// stripYAMLComment removes a comment from a line, a comment starts with "#" at the beginning of the line or after a
+// whitespace. A "#" in a quoted scalar doesn't start a comment.
+func stripYAMLComment(s string) string {
+	inDouble, inSingle := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inDouble:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inDouble = false
+			}
+		case inSingle:
+			if c == '\'' {
+				inSingle = false
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		case (c == '"' || c == '\'') && startsYAMLScalar(s[:i]):
+			inDouble, inSingle = c == '"', c == '\''
+		}
+	}
+	return s
+}
+
+// startsYAMLScalar returns true if a scalar starts after prefix, i.e. if a quote after prefix starts a quoted scalar.
+func startsYAMLScalar(prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	last := prefix[len(prefix)-1]
+	if last == '[' || last == ',' {
+		return true
+	}
+	trimmed := strings.TrimRight(prefix, " ")
+	if trimmed == prefix {
+		return false
+	}
+	if trimmed == "" {
+		return true
+	}
+	switch trimmed[len(trimmed)-1] {
+	case ':', '-', '[', ',':
+		return true
+	default:
+		return false
+	}
+}
+
+// readYAMLLines reads all lines from r, the lines are validated in the same way as in ParseCollectionSkeletons.
+func (parser *PollCollectionParser) readYAMLLines(r io.Reader) ([]yamlLine, error) {
+	limited := newMaxBytesReader(r, parser.MaxTotalBytes)
+	scanner := parser.setupScanner(limited)
+	var res []yamlLine
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := cleanInputLine(scanner.Text())
+		if validateLineErr := parser.validateLine(line, lineNum); validateLineErr != nil {
+			return nil, limited.checkErr(validateLineErr)
+		}
+		reportProgress(parser.Progress, parser.ProgressInterval, lineNum)
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+		text = strings.TrimRight(stripYAMLComment(text), " \t")
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, limited.checkErr(yamlSyntaxError(lineNum, "tabs are not allowed for indentation"))
+		}
+		if indent == 0 && (text == "---" || text == "...") {
+			if text == "---" && len(res) == 0 {
+				continue
+			}
+			return nil, limited.checkErr(yamlSyntaxError(lineNum, "multiple yaml documents are not supported"))
+		}
+		res = append(res, yamlLine{num: lineNum, indent: indent, text: text})
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		if errors.Is(scanErr, bufio.ErrTooLong) {
+			scanErr = lineTooLongError(parser.MaxLineLength, parser.MaxBufferSize)
+		}
+		return nil, scanErr
+	}
+	return res, nil
+}
+
+// yamlParser parses the lines of a document into yamlNodes.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits a line of a mapping into the key and the value, only plain keys are supported.
+func splitYAMLKey(text string) (string, string, bool) {
+	if text == "" || strings.ContainsRune("\"'[{", rune(text[0])) || isYAMLSequenceItem(text) {
+		return "", "", false
+	}
+	index := strings.Index(text, ": ")
+	if index < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		index = len(text) - 1
+	}
+	if index == 0 {
+		return "", "", false
+	}
+	return text[:index], strings.TrimSpace(text[index+1:]), true
+}
+
+// parseBlock parses the node starting in the current line, the line must have the given indentation.
+func (p *yamlParser) parseBlock(indent int) (*yamlNode, error) {
+	line := p.lines[p.pos]
+	if isYAMLSequenceItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, isKey := splitYAMLKey(line.text); isKey {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	return parseYAMLScalar(line.text, line.num)
+}
+
+// parseNested parses the value of a sequence item or mapping entry that starts in the next line, if there is no such
+// value (the next line is not indented more than indent) an empty scalar is returned.
+// If sameIndent is true a sequence with the same indentation is allowed as well.
+func (p *yamlParser) parseNested(indent, lineNum int, sameIndent bool) (*yamlNode, error) {
+	if p.pos < len(p.lines) {
+		next := p.lines[p.pos]
+		if next.indent > indent || (sameIndent && next.indent == indent && isYAMLSequenceItem(next.text)) {
+			return p.parseBlock(next.indent)
+		}
+	}
+	return &yamlNode{kind: yamlScalar, line: lineNum}, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlSequence, line: p.lines[p.pos].num}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, yamlSyntaxError(line.num, "unexpected indentation")
+		}
+		if !isYAMLSequenceItem(line.text) {
+			break
+		}
+		rest := strings.TrimLeft(line.text[1:], " ")
+		var item *yamlNode
+		var itemErr error
+		if rest == "" {
+			p.pos++
+			item, itemErr = p.parseNested(indent, line.num, false)
+		} else {
+			// parse the rest of the line as if it was in its own line, this way "- name: foo" starts a mapping
+			itemIndent := indent + len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{num: line.num, indent: itemIndent, text: rest}
+			item, itemErr = p.parseBlock(itemIndent)
+		}
+		if itemErr != nil {
+			return nil, itemErr
+		}
+		node.items = append(node.items, item)
+	}
+	return node, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlMapping, line: p.lines[p.pos].num, entries: make(map[string]*yamlNode)}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, yamlSyntaxError(line.num, "unexpected indentation")
+		}
+		key, value, isKey := splitYAMLKey(line.text)
+		if !isKey {
+			return nil, yamlSyntaxError(line.num, "expected a mapping entry of the form \"key: value\"")
+		}
+		if _, has := node.entries[key]; has {
+			return nil, yamlSyntaxError(line.num, "duplicate key \"%s\"", key)
+		}
+		p.pos++
+		var entry *yamlNode
+		var entryErr error
+		if value == "" {
+			entry, entryErr = p.parseNested(indent, line.num, true)
+		} else {
+			entry, entryErr = parseYAMLScalar(value, line.num)
+		}
+		if entryErr != nil {
+			return nil, entryErr
+		}
+		node.keys = append(node.keys, key)
+		node.entries[key] = entry
+	}
+	return node, nil
+}
+
+// parseYAMLScalar parses a scalar or a flow sequence of scalars.
+func parseYAMLScalar(s string, lineNum int) (*yamlNode, error) {
+	switch s[0] {
+	case '"':
+		value, unquoteErr := strconv.Unquote(s)
+		if unquoteErr != nil {
+			return nil, NewPollingSyntaxError(unquoteErr, "invalid double quoted string %s", s).WithLineNum(lineNum)
+		}
+		return &yamlNode{kind: yamlScalar, line: lineNum, value: value}, nil
+	case '\'':
+		inner := ""
+		if len(s) >= 2 && s[len(s)-1] == '\'' {
+			inner = s[1 : len(s)-1]
+		}
+		if (inner == "" && s != "''") || strings.ContainsRune(strings.ReplaceAll(inner, "''", ""), '\'') {
+			return nil, yamlSyntaxError(lineNum, "invalid single quoted string %s", s)
+		}
+		return &yamlNode{kind: yamlScalar, line: lineNum, value: strings.ReplaceAll(inner, "''", "'")}, nil
+	case '[':
+		return parseYAMLFlowSequence(s, lineNum)
+	case '{', '|', '>', '&', '*', '!', '%', '@', '`':
+		return nil, yamlSyntaxError(lineNum, "unsupported yaml syntax \"%s\"", s)
+	default:
+		return &yamlNode{kind: yamlScalar, line: lineNum, value: s}, nil
+	}
+}
+
+// parseYAMLFlowSequence parses a sequence of the form "[a, 'b', "c"]", nested sequences are not allowed.
+func parseYAMLFlowSequence(s string, lineNum int) (*yamlNode, error) {
+	if !strings.HasSuffix(s, "]") {
+		return nil, yamlSyntaxError(lineNum, "flow sequence \"%s\" must end with \"]\"", s)
+	}
+	node := &yamlNode{kind: yamlSequence, line: lineNum}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if inner == "" {
+		return node, nil
+	}
+	start := 0
+	inDouble, inSingle := false, false
+	addItem := func(end int) error {
+		itemString := strings.TrimSpace(inner[start:end])
+		if itemString == "" {
+			return yamlSyntaxError(lineNum, "empty entry in flow sequence \"%s\"", s)
+		}
+		item, itemErr := parseYAMLScalar(itemString, lineNum)
+		if itemErr != nil {
+			return itemErr
+		}
+		if item.kind != yamlScalar {
+			return yamlSyntaxError(lineNum, "nested flow sequences are not supported")
+		}
+		node.items = append(node.items, item)
+		return nil
+	}
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case inDouble:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inDouble = false
+			}
+		case inSingle:
+			if c == '\'' {
+				inSingle = false
+			}
+		case c == ',':
+			if itemErr := addItem(i); itemErr != nil {
+				return nil, itemErr
+			}
+			start = i + 1
+		case (c == '"' || c == '\'') && strings.TrimSpace(inner[start:i]) == "":
+			inDouble, inSingle = c == '"', c == '\''
+		}
+	}
+	if itemErr := addItem(len(inner)); itemErr != nil {
+		return nil, itemErr
+	}
+	return node, nil
+}
+
+// checkKeys returns an error if the node is not a mapping or contains a key not in allowed.
+func (node *yamlNode) checkKeys(what string, allowed ...string) error {
+	if node.kind != yamlMapping {
+		return yamlSyntaxError(node.line, "expected a mapping for %s, got a %s", what, node.kind)
+	}
+	for _, key := range node.keys {
+		isAllowed := false
+		for _, allowedKey := range allowed {
+			if key == allowedKey {
+				isAllowed = true
+				break
+			}
+		}
+		if !isAllowed {
+			return yamlSyntaxError(node.entries[key].line, "unknown key \"%s\" in %s, allowed keys are %s",
+				key, what, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// scalarEntry returns the value of the entry with the given key, the entry must be a scalar.
+// The bool is false if the key doesn't exist, if required is true an error is returned in this case.
+func (node *yamlNode) scalarEntry(what, key string, required bool) (string, bool, error) {
+	entry, has := node.entries[key]
+	if !has {
+		if required {
+			return "", false, yamlSyntaxError(node.line, "%s must have a \"%s\"", what, key)
+		}
+		return "", false, nil
+	}
+	if entry.kind != yamlScalar {
+		return "", false, yamlSyntaxError(entry.line, "\"%s\" of %s must be a scalar, got a %s", key, what, entry.kind)
+	}
+	return entry.value, true, nil
+}
+
+// sequenceEntry returns the items of the entry with the given key, the entry must be a sequence (an empty value is
+// treated as an empty sequence). If the key doesn't exist nil is returned.
+func (node *yamlNode) sequenceEntry(what, key string) ([]*yamlNode, error) {
+	entry, has := node.entries[key]
+	if !has {
+		return nil, nil
+	}
+	if entry.kind == yamlScalar && entry.value == "" {
+		return nil, nil
+	}
+	if entry.kind != yamlSequence {
+		return nil, yamlSyntaxError(entry.line, "\"%s\" of %s must be a sequence, got a %s", key, what, entry.kind)
+	}
+	return entry.items, nil
+}
+
+// ParseCollectionYAML parses a collection from a yaml document, it is an alternative to ParseCollectionSkeletons.
+//
+// The document has the following form:
+//
+//	title: Meeting
+//	groups:
+//	  - title: Finance
+//	    polls:
+//	      - name: New laptop
+//	        value: 1500.00 €
+//	        step: 50.00 €
+//	        majority: 2/3
+//	      - name: Statute change
+//	        meta:
+//	          agenda-item: "4.2"
+//	        options:
+//	          - "Yes"
+//	          - name: "No"
+//	            description: keep the old statute
+//
+// A poll has either a list of options or a value, the value is parsed with currencyParser (SimpleEuroHandler if
+// nil). A poll with a value can restrict the allowed values with the optional keys "min" and "step" (see
+// MoneyPollSkeleton). An option is either a string or a mapping with a name and a description. The annotations of a
+// poll (see PollAnnotations) are given with the optional keys "majority", "quorum" and "empty". If ParseMetadata is
+// true the metadata of a poll (see PollMetadata) can be given as a mapping of strings with the key "meta" (see
+// "Statute change" above). Unknown keys are not allowed.
+// Note that yaml interprets unquoted Yes / No as booleans, this parser reads all values as strings, but other tools
+// might not, thus DumpYAML always quotes strings.
+//
+// Only a subset of yaml is supported: Block mappings and sequences (indented with spaces), flow sequences of scalars
+// (for example "options: [Yes, No]"), plain and quoted scalars and comments.
+//
+// The collection is validated by a CollectionBuilder with parser as Parser, thus the same limits as in
+// ParseCollectionSkeletons apply (MaxNumLines, MaxLineLength and MaxTotalBytes are applied to the yaml document).
+// Different from ParseCollectionSkeletons the names of the polls must be unique. CollectErrors is ignored, the
+// parser always stops at the first error.
+// Errors from the builder are not wrapped in a CollectionBuilderError, PollingSyntaxErrors have the line number of
+// the group / poll set. If SourceName is set it is added to all errors, see VotersParser.
+func (parser *PollCollectionParser) ParseCollectionYAML(r io.Reader,
+	currencyParser CurrencyParser) (*PollSkeletonCollection, error) {
+	res, err := parser.parseCollectionYAML(r, currencyParser)
+	if err != nil {
+		return nil, withSourceName(err, parser.SourceName)
+	}
+	return res, nil
+}
+
+func (parser *PollCollectionParser) parseCollectionYAML(r io.Reader,
+	currencyParser CurrencyParser) (*PollSkeletonCollection, error) {
+	if currencyParser == nil {
+		currencyParser = SimpleEuroHandler{}
+	}
+	lines, readErr := parser.readYAMLLines(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+	if len(lines) == 0 {
+		return nil, NewPollingSyntaxError(nil, "no title found, the document is empty")
+	}
+	if lines[0].indent != 0 {
+		return nil, yamlSyntaxError(lines[0].num, "unexpected indentation")
+	}
+	p := &yamlParser{lines: lines, pos: 0}
+	root, rootErr := p.parseBlock(0)
+	if rootErr != nil {
+		return nil, rootErr
+	}
+	if p.pos < len(lines) {
+		return nil, yamlSyntaxError(lines[p.pos].num, "unexpected content after end of document")
+	}
+	return parser.collectionFromYAML(root, currencyParser)
+}
+
+// ParseCollectionYAMLFromString works as ParseCollectionYAML but parses the input from a string.
+func (parser *PollCollectionParser) ParseCollectionYAMLFromString(currencyParser CurrencyParser,
+	s string) (*PollSkeletonCollection, error) {
+	return parser.ParseCollectionYAML(strings.NewReader(s), currencyParser)
+}
+
+// yamlPollAnnotations parses the annotations of a poll node.
+func yamlPollAnnotations(pollNode *yamlNode) (PollAnnotations, error) {
+	var res PollAnnotations
+	for _, key := range []string{"majority", "quorum"} {
+		value, has, valueErr := pollNode.scalarEntry("poll", key, false)
+		if valueErr != nil {
+			return res, valueErr
+		}
+		if !has {
+			continue
+		}
+		fraction, fractionErr := parseAnnotationFraction(key, value)
+		if fractionErr != nil {
+			return res, convertParserErr(fractionErr, pollNode.entries[key].line)
+		}
+		if key == "majority" {
+			res.RequiredMajority = fraction
+		} else {
+			res.Quorum = fraction
+		}
+	}
+	value, has, valueErr := pollNode.scalarEntry("poll", "empty", false)
+	if valueErr != nil {
+		return res, valueErr
+	}
+	if has {
+		policy, policyErr := ParseEmptyVotePolicy(value)
+		if policyErr != nil {
+			return res, convertParserErr(policyErr, pollNode.entries["empty"].line)
+		}
+		res.EmptyPolicy = &policy
+	}
+	return res, nil
+}
+
+// yamlPollMeta returns the metadata of a poll node (nil if there is none), the entry "meta" must be a mapping of
+// scalars. The metadata is validated as in the polls file format, see PollMetadata.
+func yamlPollMeta(pollNode *yamlNode) (map[string]string, error) {
+	metaNode, has := pollNode.entries["meta"]
+	if !has || (metaNode.kind == yamlScalar && metaNode.value == "") {
+		return nil, nil
+	}
+	if metaNode.kind != yamlMapping {
+		return nil, yamlSyntaxError(metaNode.line, "\"meta\" of poll must be a mapping, got a %s", metaNode.kind)
+	}
+	res := make(map[string]string, len(metaNode.keys))
+	for _, key := range metaNode.keys {
+		value, _, valueErr := metaNode.scalarEntry("meta", key, true)
+		if valueErr != nil {
+			return nil, valueErr
+		}
+		res[key] = value
+	}
+	if metaErr := validateMeta(res); metaErr != nil {
+		return nil, convertParserErr(metaErr, metaNode.line)
+	}
+	return res, nil
+}
+
+// yamlOptions returns the options of a poll node.
+func yamlOptions(optionNodes []*yamlNode) ([]SkeletonOption, error) {
+	res := make([]SkeletonOption, 0, len(optionNodes))
+	for _, optionNode := range optionNodes {
+		if optionNode.kind == yamlScalar {
+			res = append(res, SkeletonOption{Name: optionNode.value})
+			continue
+		}
+		if keysErr := optionNode.checkKeys("option", "name", "description"); keysErr != nil {
+			return nil, keysErr
+		}
+		name, _, nameErr := optionNode.scalarEntry("option", "name", true)
+		if nameErr != nil {
+			return nil, nameErr
+		}
+		description, _, descriptionErr := optionNode.scalarEntry("option", "description", false)
+		if descriptionErr != nil {
+			return nil, descriptionErr
+		}
+		res = append(res, SkeletonOption{Name: name, Description: description})
+	}
+	return res, nil
+}
+
+// collectionFromYAML creates the collection described by root with a CollectionBuilder.
+func (parser *PollCollectionParser) collectionFromYAML(root *yamlNode,
+	currencyParser CurrencyParser) (*PollSkeletonCollection, error) {
+	if keysErr := root.checkKeys("collection", "title", "groups"); keysErr != nil {
+		return nil, keysErr
+	}
+	title, _, titleErr := root.scalarEntry("collection", "title", true)
+	if titleErr != nil {
+		return nil, titleErr
+	}
+	builder := NewCollectionBuilder(title)
+	builder.Parser = parser
+	builder.CurrencyParser = currencyParser
+	// stepLines[i] is the line of step i of the builder (0 is the title), it is used to add the line numbers to
+	// errors from the builder
+	stepLines := []int{root.entries["title"].line}
+	// the annotations of all polls in the order in which they're added, the builder doesn't support annotations
+	var annotations []PollAnnotations
+
+	// the metadata of all polls in the order in which they're added
+	var metas []map[string]string
+	pollKeys := []string{"name", "options", "value", "min", "step", "majority", "quorum", "empty"}
+	if parser.ParseMetadata {
+		pollKeys = append(pollKeys, "meta")
+	}
+
+	groupNodes, groupsErr := root.sequenceEntry("collection", "groups")
+	if groupsErr != nil {
+		return nil, groupsErr
+	}
+	for _, groupNode := range groupNodes {
+		if keysErr := groupNode.checkKeys("group", "title", "polls"); keysErr != nil {
+			return nil, keysErr
+		}
+		groupTitle, _, groupTitleErr := groupNode.scalarEntry("group", "title", true)
+		if groupTitleErr != nil {
+			return nil, groupTitleErr
+		}
+		builder.Group(groupTitle)
+		stepLines = append(stepLines, groupNode.line)
+		pollNodes, pollsErr := groupNode.sequenceEntry("group", "polls")
+		if pollsErr != nil {
+			return nil, pollsErr
+		}
+		for _, pollNode := range pollNodes {
+			if keysErr := pollNode.checkKeys("poll", pollKeys...); keysErr != nil {
+				return nil, keysErr
+			}
+			name, _, nameErr := pollNode.scalarEntry("poll", "name", true)
+			if nameErr != nil {
+				return nil, nameErr
+			}
+			pollAnnotations, annotationsErr := yamlPollAnnotations(pollNode)
+			if annotationsErr != nil {
+				return nil, annotationsErr
+			}
+			meta, metaErr := yamlPollMeta(pollNode)
+			if metaErr != nil {
+				return nil, metaErr
+			}
+			value, hasValue, valueErr := pollNode.scalarEntry("poll", "value", false)
+			if valueErr != nil {
+				return nil, valueErr
+			}
+			minValue, hasMin, minErr := pollNode.scalarEntry("poll", "min", false)
+			if minErr != nil {
+				return nil, minErr
+			}
+			step, hasStep, stepErr := pollNode.scalarEntry("poll", "step", false)
+			if stepErr != nil {
+				return nil, stepErr
+			}
+			_, hasOptions := pollNode.entries["options"]
+			switch {
+			case hasValue && hasOptions:
+				return nil, yamlSyntaxError(pollNode.line, "poll \"%s\" has both options and a value", name)
+			case (hasMin || hasStep) && !hasValue:
+				return nil, yamlSyntaxError(pollNode.line, "poll \"%s\" has a min value or step but no value", name)
+			case hasValue:
+				builder.MoneyPollWithRange(name, value, minValue, step)
+			case hasOptions:
+				optionNodes, optionsErr := pollNode.sequenceEntry("poll", "options")
+				if optionsErr != nil {
+					return nil, optionsErr
+				}
+				options, optionsErr := yamlOptions(optionNodes)
+				if optionsErr != nil {
+					return nil, optionsErr
+				}
+				builder.PollWithOptions(name, options...)
+			default:
+				return nil, yamlSyntaxError(pollNode.line, "poll \"%s\" must have either options or a value", name)
+			}
+			stepLines = append(stepLines, pollNode.line)
+			annotations = append(annotations, pollAnnotations)
+			metas = append(metas, meta)
+		}
+	}
+
+	coll, buildErr := builder.Build()
+	if buildErr != nil {
+		var builderErr CollectionBuilderError
+		if errors.As(buildErr, &builderErr) && builderErr.Step < len(stepLines) {
+			return nil, convertParserErr(builderErr.Err, stepLines[builderErr.Step])
+		}
+		return nil, buildErr
+	}
+	for i, skel := range coll.CollectSkeletons() {
+		if annotated, ok := skel.(AnnotatedSkeleton); ok {
+			*annotated.GetAnnotations() = annotations[i]
+		}
+		if holder, ok := skel.(MetadataHolder); ok {
+			holder.SetMeta(metas[i])
+		}
+	}
+	return coll, nil
+}
+
+// yamlQuote returns s as a double quoted yaml string.
+func yamlQuote(s string) string {
+	return strconv.Quote(s)
+}
+
+// writeYAMLMeta writes the metadata of a poll as the entry "meta" of the poll mapping (nothing if there is none).
+func writeYAMLMeta(buf *bytes.Buffer, metadata *PollMetadata) error {
+	if len(metadata.Meta) == 0 {
+		return nil
+	}
+	if metaErr := validateMeta(metadata.Meta); metaErr != nil {
+		return metaErr
+	}
+	buf.WriteString("        meta:\n")
+	for _, key := range metadata.SortedMetaKeys() {
+		fmt.Fprintf(buf, "          %s: %s\n", key, yamlQuote(metadata.Meta[key]))
+	}
+	return nil
+}
+
+// writeYAMLAnnotations writes the annotations of a poll as entries of the poll mapping.
+func writeYAMLAnnotations(buf *bytes.Buffer, annotations *PollAnnotations) {
+	if annotations.RequiredMajority != nil {
+		fmt.Fprintf(buf, "        majority: %s\n", yamlQuote(annotations.RequiredMajority.RatString()))
+	}
+	if annotations.Quorum != nil {
+		fmt.Fprintf(buf, "        quorum: %s\n", yamlQuote(annotations.Quorum.RatString()))
+	}
+	if annotations.EmptyPolicy != nil {
+		fmt.Fprintf(buf, "        empty: %s\n", yamlQuote(annotations.EmptyPolicy.String()))
+	}
+}
+
+// DumpYAML writes the collection as a yaml document to w, see ParseCollectionYAML for the format.
+// All strings are written as double quoted strings, currencyFormatter is used to format the values of money polls.
+//
+// Only *MoneyPollSkeleton and *PollSkeleton are supported, for other skeletons a PollTypeError is returned (before
+// anything is written). The metadata of the polls is written with the key "meta", if it is invalid (see PollMetadata)
+// a PollingSyntaxError is returned (again before anything is written).
+// It returns the number of bytes written as well as any error writing to w.
+func (coll *PollSkeletonCollection) DumpYAML(w io.Writer, currencyFormatter CurrencyFormatter) (int, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "title: %s\n", yamlQuote(coll.Title))
+	if len(coll.Groups) == 0 {
+		buf.WriteString("groups: []\n")
+	} else {
+		buf.WriteString("groups:\n")
+	}
+	for _, group := range coll.Groups {
+		fmt.Fprintf(&buf, "  - title: %s\n", yamlQuote(group.Title))
+		if len(group.Skeletons) == 0 {
+			buf.WriteString("    polls: []\n")
+			continue
+		}
+		buf.WriteString("    polls:\n")
+		for _, skel := range group.Skeletons {
+			fmt.Fprintf(&buf, "      - name: %s\n", yamlQuote(skel.GetName()))
+			switch typedSkel := skel.(type) {
+			case *MoneyPollSkeleton:
+				writeYAMLAnnotations(&buf, &typedSkel.PollAnnotations)
+				if metaErr := writeYAMLMeta(&buf, &typedSkel.PollMetadata); metaErr != nil {
+					return 0, metaErr
+				}
+				fmt.Fprintf(&buf, "        value: %s\n", yamlQuote(currencyFormatter.Format(typedSkel.Value)))
+				if typedSkel.MinValue.ValueCents != 0 {
+					fmt.Fprintf(&buf, "        min: %s\n", yamlQuote(currencyFormatter.Format(typedSkel.MinValue)))
+				}
+				if typedSkel.Step.ValueCents != 0 {
+					fmt.Fprintf(&buf, "        step: %s\n", yamlQuote(currencyFormatter.Format(typedSkel.Step)))
+				}
+			case *PollSkeleton:
+				writeYAMLAnnotations(&buf, &typedSkel.PollAnnotations)
+				if metaErr := writeYAMLMeta(&buf, &typedSkel.PollMetadata); metaErr != nil {
+					return 0, metaErr
+				}
+				buf.WriteString("        options:\n")
+				for i, option := range typedSkel.Options {
+					description := typedSkel.Description(i)
+					if description == "" {
+						fmt.Fprintf(&buf, "          - %s\n", yamlQuote(option))
+						continue
+					}
+					fmt.Fprintf(&buf, "          - name: %s\n", yamlQuote(option))
+					fmt.Fprintf(&buf, "            description: %s\n", yamlQuote(description))
+				}
+			default:
+				return 0, NewPollTypeError("skeleton must be either *MoneyPollSkeleton or *PollSkeleton, got type %s",
+					reflect.TypeOf(skel))
+			}
+		}
+	}
+	return w.Write(buf.Bytes())
+}
@@ -24,6 +24,9 @@ import (
 // CurrencyValue represents a money value in a certain currency.
 // The value is always represented as "cents", for example 1.23 € would be represented
 // as ValueCents=123 and currency "€".
+// If a higher precision is required ValueCents can also be interpreted as a smaller unit, for example
+// PreciseCurrencyHandler with three decimal places represents 12.345 € as ValueCents=12345. The precision is not
+// stored in the value, the handler used for parsing must also be used for formatting.
 //
 // There are also interfaces defined for formatting / parsing currency values.
 type CurrencyValue struct {
@@ -61,34 +64,173 @@ func (value CurrencyValue) Copy() CurrencyValue {
 	}
 }
 
+// maxInt and minInt are the limits of the int type, they're used to detect overflows.
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
+
+// ErrCurrencyMismatch is returned (wrapped) by the arithmetic methods of CurrencyValue if the currencies of two
+// values don't match. Test for it with errors.Is.
+var ErrCurrencyMismatch = NewPollingSemanticError(nil, "currencies don't match")
+
+// ErrCurrencyOverflow is returned (wrapped) by the arithmetic methods of CurrencyValue if the result can't be
+// represented as an int. Test for it with errors.Is.
+var ErrCurrencyOverflow = NewPollingSemanticError(nil, "currency value overflow")
+
+// combineCurrency returns the currency of the result of an operation on value and other.
+//
+// An empty currency acts as a wildcard: if one of the currencies is empty the other one is returned.
+// Otherwise the currencies must be equal, if they're not an error wrapping ErrCurrencyMismatch is returned.
+func (value CurrencyValue) combineCurrency(other CurrencyValue) (string, error) {
+	switch {
+	case value.Currency == "":
+		return other.Currency, nil
+	case other.Currency == "", value.Currency == other.Currency:
+		return value.Currency, nil
+	default:
+		return "", fmt.Errorf("can't combine \"%s\" and \"%s\": %w", value.Currency, other.Currency,
+			ErrCurrencyMismatch)
+	}
+}
+
+// Add returns value + other.
+//
+// The currencies must match, an empty currency matches all other currencies (the result has the non-empty currency).
+// If they don't match an error wrapping ErrCurrencyMismatch is returned, if the result overflows an error wrapping
+// ErrCurrencyOverflow.
+func (value CurrencyValue) Add(other CurrencyValue) (CurrencyValue, error) {
+	currency, currencyErr := value.combineCurrency(other)
+	if currencyErr != nil {
+		return CurrencyValue{}, currencyErr
+	}
+	a, b := value.ValueCents, other.ValueCents
+	if (b > 0 && a > maxInt-b) || (b < 0 && a < minInt-b) {
+		return CurrencyValue{}, fmt.Errorf("can't compute %d + %d: %w", a, b, ErrCurrencyOverflow)
+	}
+	return NewCurrencyValue(a+b, currency), nil
+}
+
+// Sub returns value - other, for errors see Add.
+func (value CurrencyValue) Sub(other CurrencyValue) (CurrencyValue, error) {
+	currency, currencyErr := value.combineCurrency(other)
+	if currencyErr != nil {
+		return CurrencyValue{}, currencyErr
+	}
+	a, b := value.ValueCents, other.ValueCents
+	if (b < 0 && a > maxInt+b) || (b > 0 && a < minInt+b) {
+		return CurrencyValue{}, fmt.Errorf("can't compute %d - %d: %w", a, b, ErrCurrencyOverflow)
+	}
+	return NewCurrencyValue(a-b, currency), nil
+}
+
+// Mul returns value * factor (the currency stays the same).
+// If the result overflows an error wrapping ErrCurrencyOverflow is returned.
+func (value CurrencyValue) Mul(factor int) (CurrencyValue, error) {
+	a := value.ValueCents
+	if a == 0 || factor == 0 {
+		return NewCurrencyValue(0, value.Currency), nil
+	}
+	res := a * factor
+	if res/factor != a || (a == -1 && factor == minInt) || (factor == -1 && a == minInt) {
+		return CurrencyValue{}, fmt.Errorf("can't compute %d * %d: %w", a, factor, ErrCurrencyOverflow)
+	}
+	return NewCurrencyValue(res, value.Currency), nil
+}
+
+// Cmp compares value and other and returns -1 if value < other, 0 if value == other and +1 if value > other.
+// If the currencies don't match (see Add) an error wrapping ErrCurrencyMismatch is returned.
+func (value CurrencyValue) Cmp(other CurrencyValue) (int, error) {
+	if _, currencyErr := value.combineCurrency(other); currencyErr != nil {
+		return 0, currencyErr
+	}
+	switch {
+	case value.ValueCents < other.ValueCents:
+		return -1, nil
+	case value.ValueCents > other.ValueCents:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// SumApprovedAmounts computes the sum of the values that were approved in median polls, i.e. the sum of all
+// MajorityValue entries (interpreted as cents).
+// Results where no value got a majority (NoMedianUnitValue) are ignored.
+//
+// The returned value has the given currency. If the sum overflows an error wrapping ErrCurrencyOverflow is
+// returned.
+func SumApprovedAmounts(results map[string]*MedianResult, currency string) (CurrencyValue, error) {
+	res := NewCurrencyValue(0, currency)
+	for name, result := range results {
+		if result.MajorityValue == NoMedianUnitValue {
+			continue
+		}
+		if result.MajorityValue > MedianUnit(maxInt) {
+			return CurrencyValue{}, fmt.Errorf("value %d of poll \"%s\" is too big: %w", result.MajorityValue, name,
+				ErrCurrencyOverflow)
+		}
+		var addErr error
+		res, addErr = res.Add(NewCurrencyValue(int(result.MajorityValue), currency))
+		if addErr != nil {
+			return CurrencyValue{}, addErr
+		}
+	}
+	return res, nil
+}
+
 // DefaultFormatString returns a standard format and might be useful for formatters.
 // It returns strings of the form 0.09, 0.21, 21.42 €.
 // The separator (in the examples the dot) can be configured with sep.
+//
+// ValueCents is interpreted as cents, see DefaultFormatStringWithPrecision for values with another precision.
 func (value CurrencyValue) DefaultFormatString(sep string) string {
+	return value.DefaultFormatStringWithPrecision(sep, DefaultCurrencyDecimalPlaces)
+}
+
+// DefaultCurrencyDecimalPlaces is the number of decimal places of a CurrencyValue if it is interpreted as cents.
+const DefaultCurrencyDecimalPlaces = 2
+
+// MaxCurrencyDecimalPlaces is the maximal number of decimal places supported, 10^9 still fits in a 32 bit int.
+const MaxCurrencyDecimalPlaces = 9
+
+// currencyUnit returns 10^decimalPlaces and decimalPlaces truncated to the range [0, MaxCurrencyDecimalPlaces].
+func currencyUnit(decimalPlaces int) (int, int) {
+	if decimalPlaces < 0 {
+		decimalPlaces = 0
+	}
+	if decimalPlaces > MaxCurrencyDecimalPlaces {
+		decimalPlaces = MaxCurrencyDecimalPlaces
+	}
+	unit := 1
+	for i := 0; i < decimalPlaces; i++ {
+		unit *= 10
+	}
+	return unit, decimalPlaces
+}
+
+// DefaultFormatStringWithPrecision works as DefaultFormatString, but interprets ValueCents as a value with the given
+// number of decimal places. For example ValueCents=12345 is formatted as 12.345 with three decimal places and as
+// 1.2345 with four decimal places.
+//
+// decimalPlaces is truncated to the range [0, MaxCurrencyDecimalPlaces], if it is 0 no separator is written.
+func (value CurrencyValue) DefaultFormatStringWithPrecision(sep string, decimalPlaces int) string {
+	unit, decimalPlaces := currencyUnit(decimalPlaces)
+	sign := ""
+	// use uint64 to also handle the smallest int
+	abs := uint64(value.ValueCents)
 	if value.ValueCents < 0 {
-		positiveValue := CurrencyValue{
-			ValueCents: -value.ValueCents,
-			Currency:   value.Currency,
-		}
-		return "-" + positiveValue.DefaultFormatString(sep)
+		sign = "-"
+		abs = uint64(-(value.ValueCents + 1)) + 1
 	}
 	currencyStr := ""
 	if value.Currency != "" {
 		currencyStr = " " + value.Currency
 	}
-	switch {
-	case value.ValueCents < 10:
-		return fmt.Sprintf("0%s0%d%s", sep, value.ValueCents, currencyStr)
-	case value.ValueCents < 100:
-		return fmt.Sprintf("0%s%d%s", sep, value.ValueCents, currencyStr)
-	default:
-		fullEuro := value.ValueCents / 100
-		remainingCents := value.ValueCents % 100
-		if remainingCents < 10 {
-			return fmt.Sprintf("%d%s0%d%s", fullEuro, sep, remainingCents, currencyStr)
-		}
-		return fmt.Sprintf("%d%s%d%s", fullEuro, sep, remainingCents, currencyStr)
+	if decimalPlaces == 0 {
+		return fmt.Sprintf("%s%d%s", sign, abs, currencyStr)
 	}
+	return fmt.Sprintf("%s%d%s%0*d%s", sign, abs/uint64(unit), sep, decimalPlaces, abs%uint64(unit), currencyStr)
 }
 
 // CurrencyFormatter formats a currency value to a string.
@@ -211,3 +353,266 @@ func (h RawCentCurrencyHandler) Parse(s string) (CurrencyValue, error) {
 func (h RawCentCurrencyHandler) Format(value CurrencyValue) string {
 	return value.DefaultFormatString(".")
 }
+
+// CurrencySymbolPlacement describes where a ConfigurableCurrencyHandler writes the currency symbol.
+type CurrencySymbolPlacement int8
+
+const (
+	SymbolSuffix CurrencySymbolPlacement = iota
+	SymbolPrefix
+)
+
+// ConfigurableCurrencyHandler is an implementation of CurrencyHandler that can be configured for different locales.
+//
+// DecimalSeparator separates the cents from the rest of the value and must not be empty, ThousandsSeparator is
+// written between each group of three digits (it can be empty to disable grouping).
+// Symbol is the currency symbol accepted by Parse, Placement describes if it is written before or after the value
+// and SymbolSpace if a space is written between symbol and value.
+//
+// Format always uses the Currency of the value (if it is empty no symbol is written), so values with another
+// currency than Symbol can be formatted but not parsed back.
+// For example NewGermanEuroHandler formats values as "1.234,56 €" and NewUSDollarHandler as "$1,234.56".
+//
+// Parse accepts all strings returned by Format (for values with currency Symbol or an empty currency).
+// The symbol is optional, the cents can be given with one or two digits or be omitted.
+// The thousands separator is optional too, but if it is used all groups must be valid (i.e. "1.234,5" and "1234,5"
+// are accepted, but "12.34,5" is not for the German handler).
+// Without decimal separator a thousands separator is interpreted as such, for example "1.234" is 1234 € for the
+// German handler.
+type ConfigurableCurrencyHandler struct {
+	DecimalSeparator   string
+	ThousandsSeparator string
+	Symbol             string
+	Placement          CurrencySymbolPlacement
+	SymbolSpace        bool
+}
+
+// NewConfigurableCurrencyHandler returns a new ConfigurableCurrencyHandler.
+func NewConfigurableCurrencyHandler(decimalSeparator, thousandsSeparator, symbol string,
+	placement CurrencySymbolPlacement, symbolSpace bool) *ConfigurableCurrencyHandler {
+	return &ConfigurableCurrencyHandler{
+		DecimalSeparator:   decimalSeparator,
+		ThousandsSeparator: thousandsSeparator,
+		Symbol:             symbol,
+		Placement:          placement,
+		SymbolSpace:        symbolSpace,
+	}
+}
+
+// NewGermanEuroHandler returns a handler for values of the form "1.234,56 €".
+func NewGermanEuroHandler() *ConfigurableCurrencyHandler {
+	return NewConfigurableCurrencyHandler(",", ".", "€", SymbolSuffix, true)
+}
+
+// NewUSDollarHandler returns a handler for values of the form "$1,234.56".
+func NewUSDollarHandler() *ConfigurableCurrencyHandler {
+	return NewConfigurableCurrencyHandler(".", ",", "$", SymbolPrefix, false)
+}
+
+// groupThousands inserts sep between each group of three digits in digits.
+func groupThousands(digits, sep string) string {
+	if sep == "" || len(digits) <= 3 {
+		return digits
+	}
+	var builder strings.Builder
+	first := len(digits) % 3
+	if first == 0 {
+		first = 3
+	}
+	builder.WriteString(digits[:first])
+	for i := first; i < len(digits); i += 3 {
+		builder.WriteString(sep)
+		builder.WriteString(digits[i : i+3])
+	}
+	return builder.String()
+}
+
+// Format implements the CurrencyFormatter interface.
+func (h *ConfigurableCurrencyHandler) Format(value CurrencyValue) string {
+	sign := ""
+	// use uint64 to also handle the smallest int
+	abs := uint64(value.ValueCents)
+	if value.ValueCents < 0 {
+		sign = "-"
+		abs = uint64(-(value.ValueCents + 1)) + 1
+	}
+	number := fmt.Sprintf("%s%s%02d", groupThousands(strconv.FormatUint(abs/100, 10), h.ThousandsSeparator),
+		h.DecimalSeparator, abs%100)
+	if value.Currency == "" {
+		return sign + number
+	}
+	space := ""
+	if h.SymbolSpace {
+		space = " "
+	}
+	if h.Placement == SymbolPrefix {
+		return sign + value.Currency + space + number
+	}
+	return sign + number + space + value.Currency
+}
+
+// isDigits returns true if s is not empty and contains only the digits 0-9.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIntegerPart parses the part before the decimal separator, taking the thousands separator into account.
+func (h *ConfigurableCurrencyHandler) parseIntegerPart(s string) (string, bool) {
+	if h.ThousandsSeparator == "" || !strings.Contains(s, h.ThousandsSeparator) {
+		return s, isDigits(s)
+	}
+	groups := strings.Split(s, h.ThousandsSeparator)
+	if len(groups[0]) > 3 {
+		return "", false
+	}
+	for i, group := range groups {
+		if !isDigits(group) || (i > 0 && len(group) != 3) {
+			return "", false
+		}
+	}
+	return strings.Join(groups, ""), true
+}
+
+// Parse implements the CurrencyParser interface.
+func (h *ConfigurableCurrencyHandler) Parse(s string) (CurrencyValue, error) {
+	res := CurrencyValue{}
+	rest := strings.TrimSpace(s)
+	negative := strings.HasPrefix(rest, "-")
+	if negative {
+		rest = strings.TrimSpace(rest[1:])
+	}
+	if h.Symbol != "" {
+		switch {
+		case h.Placement == SymbolPrefix && strings.HasPrefix(rest, h.Symbol):
+			rest = strings.TrimSpace(rest[len(h.Symbol):])
+			res.Currency = h.Symbol
+		case h.Placement == SymbolSuffix && strings.HasSuffix(rest, h.Symbol):
+			rest = strings.TrimSpace(rest[:len(rest)-len(h.Symbol)])
+			res.Currency = h.Symbol
+		}
+	}
+	integerStr, centsStr := rest, ""
+	if index := strings.Index(rest, h.DecimalSeparator); h.DecimalSeparator != "" && index >= 0 {
+		integerStr, centsStr = rest[:index], rest[index+len(h.DecimalSeparator):]
+		if len(centsStr) == 0 || len(centsStr) > 2 || !isDigits(centsStr) {
+			return CurrencyValue{}, NewPollingSyntaxError(nil, "not a valid currency string: %s", s)
+		}
+	}
+	integerDigits, ok := h.parseIntegerPart(integerStr)
+	if !ok {
+		return CurrencyValue{}, NewPollingSyntaxError(nil, "not a valid currency string: %s", s)
+	}
+	integer, integerErr := strconv.Atoi(integerDigits)
+	if integerErr != nil {
+		return CurrencyValue{}, NewPollingSyntaxError(integerErr, "invalid currency integer")
+	}
+	cents := 0
+	if centsStr != "" {
+		// can't fail, only digits
+		cents, _ = strconv.Atoi(centsStr)
+		if len(centsStr) == 1 {
+			cents *= 10
+		}
+	}
+	if integer > (maxInt-cents)/100 {
+		return CurrencyValue{}, NewPollingSyntaxError(nil, "currency value is too big: %s", s)
+	}
+	res.ValueCents = integer*100 + cents
+	if negative {
+		res.ValueCents = -res.ValueCents
+	}
+	return res, nil
+}
+
+// PreciseCurrencyHandler is an implementation of CurrencyHandler that supports a configurable number of decimal
+// places, for example for values like 12.345 €.
+//
+// The value is stored in the smallest unit, for example with DecimalPlaces=3 the string "12.345 €" is parsed as
+// ValueCents=12345 and with DecimalPlaces=4 as ValueCents=123450. Thus all values (for example the values of median
+// polls and the votes for them) must be parsed / formatted with the same handler.
+// DecimalPlaces must be between 0 and MaxCurrencyDecimalPlaces, NewPreciseCurrencyHandler returns an error
+// otherwise.
+//
+// Format writes strings of the form "12.345 €" (see DefaultFormatStringWithPrecision), the separator can be
+// configured with DecimalSeparator.
+// Parse works like SimpleEuroHandler.Parse: both , and . are accepted as decimal separator, at most DecimalPlaces
+// digits are allowed after the separator and the value can be followed by Symbol.
+type PreciseCurrencyHandler struct {
+	DecimalPlaces    int
+	DecimalSeparator string
+	Symbol           string
+}
+
+// NewPreciseCurrencyHandler returns a new handler with the given number of decimal places, the separator is set to
+// "." and the symbol to "€".
+//
+// If decimalPlaces is not between 0 and MaxCurrencyDecimalPlaces a PollingSemanticError is returned.
+func NewPreciseCurrencyHandler(decimalPlaces int) (*PreciseCurrencyHandler, error) {
+	if decimalPlaces < 0 || decimalPlaces > MaxCurrencyDecimalPlaces {
+		return nil, NewPollingSemanticError(nil, "number of decimal places must be between 0 and %d, got %d",
+			MaxCurrencyDecimalPlaces, decimalPlaces)
+	}
+	return &PreciseCurrencyHandler{
+		DecimalPlaces:    decimalPlaces,
+		DecimalSeparator: ".",
+		Symbol:           "€",
+	}, nil
+}
+
+// Format implements the CurrencyFormatter interface.
+func (h *PreciseCurrencyHandler) Format(value CurrencyValue) string {
+	return value.DefaultFormatStringWithPrecision(h.DecimalSeparator, h.DecimalPlaces)
+}
+
+// Parse implements the CurrencyParser interface.
+func (h *PreciseCurrencyHandler) Parse(s string) (CurrencyValue, error) {
+	res := CurrencyValue{}
+	unit, decimalPlaces := currencyUnit(h.DecimalPlaces)
+	rest := strings.TrimSpace(s)
+	negative := strings.HasPrefix(rest, "-")
+	if negative {
+		rest = strings.TrimSpace(rest[1:])
+	}
+	if h.Symbol != "" && strings.HasSuffix(rest, h.Symbol) {
+		rest = strings.TrimSpace(rest[:len(rest)-len(h.Symbol)])
+		res.Currency = h.Symbol
+	}
+	integerStr, fractionStr := rest, ""
+	if index := strings.IndexAny(rest, ",."); index >= 0 {
+		integerStr, fractionStr = rest[:index], rest[index+1:]
+		if len(fractionStr) == 0 || len(fractionStr) > decimalPlaces || !isDigits(fractionStr) {
+			return CurrencyValue{}, NewPollingSyntaxError(nil, "not a valid currency string: %s", s)
+		}
+	}
+	if !isDigits(integerStr) {
+		return CurrencyValue{}, NewPollingSyntaxError(nil, "not a valid currency string: %s", s)
+	}
+	integer, integerErr := strconv.Atoi(integerStr)
+	if integerErr != nil {
+		return CurrencyValue{}, NewPollingSyntaxError(integerErr, "invalid currency integer")
+	}
+	fraction := 0
+	if fractionStr != "" {
+		// can't fail, only digits and at most MaxCurrencyDecimalPlaces of them
+		fraction, _ = strconv.Atoi(fractionStr)
+		for i := len(fractionStr); i < decimalPlaces; i++ {
+			fraction *= 10
+		}
+	}
+	if integer > (maxInt-fraction)/unit {
+		return CurrencyValue{}, NewPollingSyntaxError(nil, "currency value is too big: %s", s)
+	}
+	res.ValueCents = integer*unit + fraction
+	if negative {
+		res.ValueCents = -res.ValueCents
+	}
+	return res, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import "fmt"
+
+// Delegation describes weight that a voter carries for another voter (proxy voting).
+//
+// Source is the name of the voter that delegated its vote, Weight the weight that was delegated.
+type Delegation struct {
+	Source string
+	Weight Weight
+}
+
+// NewDelegation returns a new Delegation.
+func NewDelegation(source string, weight Weight) Delegation {
+	return Delegation{
+		Source: source,
+		Weight: weight,
+	}
+}
+
+// NoDelegationLimit can be used as the max number of delegations in Voter.ValidateDelegations, it means that there
+// is no limit.
+const NoDelegationLimit = -1
+
+// DelegatedWeight returns the sum of all delegated weights.
+//
+// The result is only guaranteed to be correct (no overflow) if ValidateDelegations returned nil.
+func (voter *Voter) DelegatedWeight() Weight {
+	var res Weight
+	for _, delegation := range voter.Delegations {
+		res += delegation.Weight
+	}
+	return res
+}
+
+// CheckedDelegatedWeight works like DelegatedWeight but returns an error wrapping ErrWeightOverflow if the sum
+// overflows.
+func (voter *Voter) CheckedDelegatedWeight() (Weight, error) {
+	var res Weight
+	for _, delegation := range voter.Delegations {
+		var err error
+		res, err = AddWeight(res, delegation.Weight)
+		if err != nil {
+			return NoWeight, fmt.Errorf("delegated weight of voter \"%s\" is too big: %w", voter.Name, err)
+		}
+	}
+	return res, nil
+}
+
+// EffectiveWeight returns the weight of the voter including all delegations, this is the weight used in tallies.
+//
+// The result is only guaranteed to be correct (no overflow) if ValidateDelegations returned nil.
+func (voter *Voter) EffectiveWeight() Weight {
+	return voter.Weight + voter.DelegatedWeight()
+}
+
+// CheckedEffectiveWeight works like EffectiveWeight but returns an error wrapping ErrWeightOverflow if the sum
+// overflows.
+func (voter *Voter) CheckedEffectiveWeight() (Weight, error) {
+	res := voter.Weight
+	for _, delegation := range voter.Delegations {
+		var err error
+		res, err = AddWeight(res, delegation.Weight)
+		if err != nil {
+			return NoWeight, fmt.Errorf("effective weight of voter \"%s\" is too big: %w", voter.Name, err)
+		}
+	}
+	return res, nil
+}
+
+// addEffectiveWeight returns sum + the effective weight of voter, if the sum overflows an error wrapping
+// ErrWeightOverflow is returned.
+func addEffectiveWeight(sum Weight, voter *Voter) (Weight, error) {
+	weight, weightErr := voter.CheckedEffectiveWeight()
+	if weightErr != nil {
+		return NoWeight, weightErr
+	}
+	return AddWeight(sum, weight)
+}
+
+// ValidateDelegations tests if the delegations of the voter are valid.
+//
+// A voter is not allowed to have more than maxDelegations delegations (NoDelegationLimit disables this check), it
+// can't delegate to itself and each source can appear only once.
+// Also the effective weight must be < NoWeight.
+// All errors are of type PollingSemanticError.
+func (voter *Voter) ValidateDelegations(maxDelegations int) error {
+	if maxDelegations >= 0 && len(voter.Delegations) > maxDelegations {
+		return NewPollingSemanticError(nil, "voter \"%s\" has %d delegations, only %d are allowed",
+			voter.Name, len(voter.Delegations), maxDelegations)
+	}
+	sources := make(map[string]struct{}, len(voter.Delegations))
+	sum := voter.Weight
+	for _, delegation := range voter.Delegations {
+		if delegation.Source == voter.Name {
+			return NewPollingSemanticError(nil, "voter \"%s\" can't delegate to itself", voter.Name)
+		}
+		if _, has := sources[delegation.Source]; has {
+			return NewPollingSemanticError(nil, "voter \"%s\" has multiple delegations from \"%s\"",
+				voter.Name, delegation.Source)
+		}
+		sources[delegation.Source] = struct{}{}
+		var sumErr error
+		sum, sumErr = mergeWeights(voter.Name, sum, delegation.Weight)
+		if sumErr != nil {
+			return sumErr
+		}
+	}
+	return nil
+}
+
+// DelegationReport describes which part of the weight in a poll was the voters own weight and which part was
+// delegated.
+//
+// OwnWeight is the sum of the own weights of all voters that voted, DelegatedWeight the sum of all delegated
+// weights.
+// Delegations maps the name of each voter that voted and carried delegations to these delegations.
+type DelegationReport struct {
+	OwnWeight       Weight
+	DelegatedWeight Weight
+	Delegations     map[string][]Delegation
+}
+
+// NewDelegationReport creates a report for all votes in a poll.
+//
+// Only the poll types implemented in this package are supported, for all other types a PollTypeError is returned.
+// If one of the sums overflows an error wrapping ErrWeightOverflow is returned.
+func NewDelegationReport(poll AbstractPoll) (*DelegationReport, error) {
+	votes, votesErr := CollectVotes(poll)
+	if votesErr != nil {
+		return nil, votesErr
+	}
+	res := &DelegationReport{
+		Delegations: make(map[string][]Delegation),
+	}
+	for _, vote := range votes {
+		voter := vote.GetVoter()
+		delegated, delegatedErr := voter.CheckedDelegatedWeight()
+		if delegatedErr != nil {
+			return nil, delegatedErr
+		}
+		var sumErr error
+		if res.OwnWeight, sumErr = AddWeight(res.OwnWeight, voter.Weight); sumErr != nil {
+			return nil, sumErr
+		}
+		if res.DelegatedWeight, sumErr = AddWeight(res.DelegatedWeight, delegated); sumErr != nil {
+			return nil, sumErr
+		}
+		if len(voter.Delegations) > 0 {
+			res.Delegations[voter.Name] = voter.Delegations
+		}
+	}
+	return res, nil
+}
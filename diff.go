@@ -0,0 +1,458 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ResultDetailsDiff describes the changes between two results of the same type, it is implemented by
+// *BasicPollResultDiff, *MedianResultDiff, *SchulzeResultDiff and *ScoreResultDiff.
+//
+// Changed returns true if there is any difference, String describes all differences (or "no changes").
+type ResultDetailsDiff interface {
+	Changed() bool
+	String() string
+}
+
+// weightDelta returns newWeight - oldWeight.
+func weightDelta(oldWeight, newWeight Weight) int64 {
+	return int64(newWeight) - int64(oldWeight)
+}
+
+// diffWriter collects the descriptions of changes for the String methods of the diffs.
+type diffWriter struct {
+	parts []string
+}
+
+func (w *diffWriter) delta(name string, delta int64) {
+	if delta != 0 {
+		w.parts = append(w.parts, fmt.Sprintf("%s %+d", name, delta))
+	}
+}
+
+func (w *diffWriter) change(name string, oldValue, newValue interface{}) {
+	w.parts = append(w.parts, fmt.Sprintf("%s %v -> %v", name, oldValue, newValue))
+}
+
+func (w *diffWriter) String() string {
+	if len(w.parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(w.parts, ", ")
+}
+
+// BasicPollCounterDiff contains the differences (new - old) of the counters in a BasicPollCounter.
+type BasicPollCounterDiff struct {
+	NumNoes, NumAyes, NumAbstention, NumInvalid int64
+}
+
+// newBasicPollCounterDiff returns the differences of two counters, nil counters are treated as counters with all
+// values set to 0.
+func newBasicPollCounterDiff(oldCounter, newCounter *BasicPollCounter) BasicPollCounterDiff {
+	if oldCounter == nil {
+		oldCounter = NewBasicPollCounter()
+	}
+	if newCounter == nil {
+		newCounter = NewBasicPollCounter()
+	}
+	return BasicPollCounterDiff{
+		NumNoes:       weightDelta(oldCounter.NumNoes, newCounter.NumNoes),
+		NumAyes:       weightDelta(oldCounter.NumAyes, newCounter.NumAyes),
+		NumAbstention: weightDelta(oldCounter.NumAbstention, newCounter.NumAbstention),
+		NumInvalid:    weightDelta(oldCounter.NumInvalid, newCounter.NumInvalid),
+	}
+}
+
+func (diff BasicPollCounterDiff) changed() bool {
+	return diff != BasicPollCounterDiff{}
+}
+
+func (diff BasicPollCounterDiff) write(w *diffWriter, suffix string) {
+	w.delta("noes"+suffix, diff.NumNoes)
+	w.delta("ayes"+suffix, diff.NumAyes)
+	w.delta("abstentions"+suffix, diff.NumAbstention)
+	w.delta("invalid"+suffix, diff.NumInvalid)
+}
+
+// BasicPollResultDiff describes the changes between two results of a BasicPoll, see DiffBasicPollResults.
+//
+// The deltas are computed as new - old, OldOutcome and NewOutcome are the outcomes of both results (see
+// BasicPollResult.Outcome). A ZeroWeight counter that is nil is treated as a counter with all values set to 0.
+type BasicPollResultDiff struct {
+	NumberVoters  BasicPollCounterDiff
+	WeightedVotes BasicPollCounterDiff
+	ZeroWeight    BasicPollCounterDiff
+	VotersCount   int64
+	VotesSum      int64
+	OldOutcome    BasicPollOutcome
+	NewOutcome    BasicPollOutcome
+}
+
+// DiffBasicPollResults returns the changes from oldResult to newResult.
+func DiffBasicPollResults(oldResult, newResult *BasicPollResult) *BasicPollResultDiff {
+	return &BasicPollResultDiff{
+		NumberVoters:  newBasicPollCounterDiff(oldResult.NumberVoters, newResult.NumberVoters),
+		WeightedVotes: newBasicPollCounterDiff(oldResult.WeightedVotes, newResult.WeightedVotes),
+		ZeroWeight:    newBasicPollCounterDiff(oldResult.ZeroWeight, newResult.ZeroWeight),
+		VotersCount:   weightDelta(oldResult.VotersCount, newResult.VotersCount),
+		VotesSum:      weightDelta(oldResult.VotesSum, newResult.VotesSum),
+		OldOutcome:    oldResult.Outcome(),
+		NewOutcome:    newResult.Outcome(),
+	}
+}
+
+// Changed returns true if any counter or the outcome changed.
+func (diff *BasicPollResultDiff) Changed() bool {
+	return diff.NumberVoters.changed() || diff.WeightedVotes.changed() || diff.ZeroWeight.changed() ||
+		diff.VotersCount != 0 ||
+		diff.VotesSum != 0 || diff.OldOutcome != diff.NewOutcome
+}
+
+func (diff *BasicPollResultDiff) String() string {
+	var w diffWriter
+	if diff.OldOutcome != diff.NewOutcome {
+		w.change("outcome", diff.OldOutcome, diff.NewOutcome)
+	}
+	diff.WeightedVotes.write(&w, " (weighted)")
+	diff.NumberVoters.write(&w, " (voters)")
+	diff.ZeroWeight.write(&w, " (zero weight)")
+	w.delta("votes sum", diff.VotesSum)
+	w.delta("voters count", diff.VotersCount)
+	return w.String()
+}
+
+// MedianResultDiff describes the changes between two results of a MedianPoll, see DiffMedianResults.
+//
+// The deltas are computed as new - old, OldMajorityValue and NewMajorityValue are the MajorityValue of both results.
+type MedianResultDiff struct {
+	WeightSum        int64
+	AbstentionWeight int64
+	RequiredMajority int64
+	OldMajorityValue MedianUnit
+	NewMajorityValue MedianUnit
+}
+
+// DiffMedianResults returns the changes from oldResult to newResult.
+func DiffMedianResults(oldResult, newResult *MedianResult) *MedianResultDiff {
+	return &MedianResultDiff{
+		WeightSum:        weightDelta(oldResult.WeightSum, newResult.WeightSum),
+		AbstentionWeight: weightDelta(oldResult.AbstentionWeight, newResult.AbstentionWeight),
+		RequiredMajority: weightDelta(oldResult.RequiredMajority, newResult.RequiredMajority),
+		OldMajorityValue: oldResult.MajorityValue,
+		NewMajorityValue: newResult.MajorityValue,
+	}
+}
+
+// Changed returns true if any weight or the majority value changed.
+func (diff *MedianResultDiff) Changed() bool {
+	return diff.WeightSum != 0 || diff.AbstentionWeight != 0 || diff.RequiredMajority != 0 ||
+		diff.OldMajorityValue != diff.NewMajorityValue
+}
+
+// formatMajorityValue returns the value as a string or "none" for NoMedianUnitValue.
+func formatMajorityValue(value MedianUnit) string {
+	if value == NoMedianUnitValue {
+		return "none"
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+func (diff *MedianResultDiff) String() string {
+	var w diffWriter
+	if diff.OldMajorityValue != diff.NewMajorityValue {
+		w.change("majority value", formatMajorityValue(diff.OldMajorityValue),
+			formatMajorityValue(diff.NewMajorityValue))
+	}
+	w.delta("weight sum", diff.WeightSum)
+	w.delta("abstention weight", diff.AbstentionWeight)
+	w.delta("required majority", diff.RequiredMajority)
+	return w.String()
+}
+
+// SchulzeCellChange describes a changed entry in a SchulzeMatrix.
+type SchulzeCellChange struct {
+	Row, Column int
+	Old, New    Weight
+}
+
+// diffSchulzeMatrices returns all changed cells of two matrices of the same dimension, sorted by row and column.
+func diffSchulzeMatrices(oldMatrix, newMatrix SchulzeMatrix) []SchulzeCellChange {
+	var res []SchulzeCellChange
+	for i, row := range oldMatrix {
+		for j, oldValue := range row {
+			if newValue := newMatrix[i][j]; oldValue != newValue {
+				res = append(res, SchulzeCellChange{Row: i, Column: j, Old: oldValue, New: newValue})
+			}
+		}
+	}
+	return res
+}
+
+// SchulzeResultDiff describes the changes between two results of a SchulzePoll, see DiffSchulzeResults.
+//
+// WeightSum is the difference new - old. OldRankedGroups and NewRankedGroups are the winner groups of both results,
+// DChanges and PChanges contain all changed cells of the matrices d and p.
+type SchulzeResultDiff struct {
+	WeightSum       int64
+	OldRankedGroups SchulzeWinsList
+	NewRankedGroups SchulzeWinsList
+	DChanges        []SchulzeCellChange
+	PChanges        []SchulzeCellChange
+}
+
+// DiffSchulzeResults returns the changes from oldResult to newResult.
+//
+// If the results have a different number of options a PollingSemanticError is returned.
+func DiffSchulzeResults(oldResult, newResult *SchulzeResult) (*SchulzeResultDiff, error) {
+	if len(oldResult.D) != len(newResult.D) || len(oldResult.P) != len(newResult.P) {
+		return nil, NewPollingSemanticError(nil, "can't compare schulze results with %d and %d options",
+			len(oldResult.D), len(newResult.D))
+	}
+	return &SchulzeResultDiff{
+		WeightSum:       weightDelta(oldResult.WeightSum, newResult.WeightSum),
+		OldRankedGroups: oldResult.RankedGroups,
+		NewRankedGroups: newResult.RankedGroups,
+		DChanges:        diffSchulzeMatrices(oldResult.D, newResult.D),
+		PChanges:        diffSchulzeMatrices(oldResult.P, newResult.P),
+	}, nil
+}
+
+// RankingChanged returns true if the ranked groups changed.
+func (diff *SchulzeResultDiff) RankingChanged() bool {
+	return !diff.OldRankedGroups.Equals(diff.NewRankedGroups)
+}
+
+// Changed returns true if the weight sum, the ranking or any cell of d or p changed.
+func (diff *SchulzeResultDiff) Changed() bool {
+	return diff.WeightSum != 0 || diff.RankingChanged() || len(diff.DChanges) > 0 || len(diff.PChanges) > 0
+}
+
+func (diff *SchulzeResultDiff) String() string {
+	var w diffWriter
+	if diff.RankingChanged() {
+		w.change("ranking", diff.OldRankedGroups, diff.NewRankedGroups)
+	}
+	w.delta("weight sum", diff.WeightSum)
+	for _, cell := range diff.DChanges {
+		w.change(fmt.Sprintf("d[%d][%d]", cell.Row, cell.Column), cell.Old, cell.New)
+	}
+	for _, cell := range diff.PChanges {
+		w.change(fmt.Sprintf("p[%d][%d]", cell.Row, cell.Column), cell.Old, cell.New)
+	}
+	return w.String()
+}
+
+// ScoreResultDiff describes the changes between two results of a ScorePoll, see DiffScoreResults.
+//
+// WeightSum and ScoreSums contain the differences new - old (one entry for each option in ScoreSums),
+// OldRankedGroups and NewRankedGroups are the ranked groups of both results. OldWinner and NewWinner are the winners
+// of the runoffs, -1 if a result has no runoff.
+type ScoreResultDiff struct {
+	WeightSum       int64
+	ScoreSums       []int64
+	OldRankedGroups [][]int
+	NewRankedGroups [][]int
+	OldWinner       int
+	NewWinner       int
+}
+
+// scoreRunoffWinner returns the winner of the runoff of result or -1 if there is no runoff.
+func scoreRunoffWinner(result *ScoreResult) int {
+	if result.Runoff == nil {
+		return -1
+	}
+	return result.Runoff.Winner
+}
+
+// DiffScoreResults returns the changes from oldResult to newResult.
+//
+// If the results have a different number of options a PollingSemanticError is returned.
+func DiffScoreResults(oldResult, newResult *ScoreResult) (*ScoreResultDiff, error) {
+	if len(oldResult.ScoreSums) != len(newResult.ScoreSums) {
+		return nil, NewPollingSemanticError(nil, "can't compare score results with %d and %d options",
+			len(oldResult.ScoreSums), len(newResult.ScoreSums))
+	}
+	scoreSums := make([]int64, len(oldResult.ScoreSums))
+	for i, oldSum := range oldResult.ScoreSums {
+		// the sums are the weighted sums of uint8 values, so they can't get too big for an int64
+		scoreSums[i] = int64(newResult.ScoreSums[i]) - int64(oldSum)
+	}
+	return &ScoreResultDiff{
+		WeightSum:       weightDelta(oldResult.WeightSum, newResult.WeightSum),
+		ScoreSums:       scoreSums,
+		OldRankedGroups: oldResult.RankedGroups,
+		NewRankedGroups: newResult.RankedGroups,
+		OldWinner:       scoreRunoffWinner(oldResult),
+		NewWinner:       scoreRunoffWinner(newResult),
+	}, nil
+}
+
+// RankingChanged returns true if the ranked groups changed.
+func (diff *ScoreResultDiff) RankingChanged() bool {
+	return !SchulzeWinsList(diff.OldRankedGroups).Equals(diff.NewRankedGroups)
+}
+
+// Changed returns true if the weight sum, any score sum, the ranking or the winner changed.
+func (diff *ScoreResultDiff) Changed() bool {
+	if diff.WeightSum != 0 || diff.RankingChanged() || diff.OldWinner != diff.NewWinner {
+		return true
+	}
+	for _, delta := range diff.ScoreSums {
+		if delta != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (diff *ScoreResultDiff) String() string {
+	var w diffWriter
+	if diff.OldWinner != diff.NewWinner {
+		w.change("winner", diff.OldWinner, diff.NewWinner)
+	}
+	if diff.RankingChanged() {
+		w.change("ranking", diff.OldRankedGroups, diff.NewRankedGroups)
+	}
+	w.delta("weight sum", diff.WeightSum)
+	for i, delta := range diff.ScoreSums {
+		w.delta(fmt.Sprintf("score sum of option %d", i), delta)
+	}
+	return w.String()
+}
+
+// ResultDiffStatus describes how the result of a poll changed, see ResultDiff.
+type ResultDiffStatus int8
+
+const (
+	ResultUnchanged ResultDiffStatus = iota
+	ResultChanged
+	ResultAdded
+	ResultRemoved
+	ResultTypeChanged
+)
+
+func (status ResultDiffStatus) String() string {
+	switch status {
+	case ResultUnchanged:
+		return "unchanged"
+	case ResultChanged:
+		return "changed"
+	case ResultAdded:
+		return "added"
+	case ResultRemoved:
+		return "removed"
+	case ResultTypeChanged:
+		return "type changed"
+	default:
+		return fmt.Sprintf("ResultDiffStatus(%d)", status)
+	}
+}
+
+// ResultDiff describes how the result of a single poll changed, see DiffResults.
+//
+// Old and New are the results, Old is nil for an added poll and New is nil for a removed poll.
+// Details is only set if both results exist and have the same type.
+type ResultDiff struct {
+	Status  ResultDiffStatus
+	Old     AbstractPollResult
+	New     AbstractPollResult
+	Details ResultDetailsDiff
+}
+
+func (diff ResultDiff) String() string {
+	switch diff.Status {
+	case ResultChanged:
+		return diff.Details.String()
+	case ResultTypeChanged:
+		return fmt.Sprintf("type changed: %s -> %s", diff.Old.ResultType(), diff.New.ResultType())
+	default:
+		return diff.Status.String()
+	}
+}
+
+// DiffResultDetails returns the changes from oldResult to newResult.
+//
+// Both results must have the same type and must be one of the result types from this package, otherwise a
+// PollTypeError is returned. Errors from DiffSchulzeResults and DiffScoreResults are returned too.
+func DiffResultDetails(oldResult, newResult AbstractPollResult) (ResultDetailsDiff, error) {
+	if reflect.TypeOf(oldResult) != reflect.TypeOf(newResult) {
+		return nil, NewPollTypeError("can't compare results of type %s and %s", reflect.TypeOf(oldResult),
+			reflect.TypeOf(newResult))
+	}
+	switch typedOld := oldResult.(type) {
+	case *BasicPollResult:
+		return DiffBasicPollResults(typedOld, newResult.(*BasicPollResult)), nil
+	case *MedianResult:
+		return DiffMedianResults(typedOld, newResult.(*MedianResult)), nil
+	case *SchulzeResult:
+		return DiffSchulzeResults(typedOld, newResult.(*SchulzeResult))
+	case *ScoreResult:
+		return DiffScoreResults(typedOld, newResult.(*ScoreResult))
+	default:
+		return nil, NewPollTypeError("can't compare results of type %s", reflect.TypeOf(oldResult))
+	}
+}
+
+// DiffResults compares the results of a re-count (newResults) with the original results (oldResults), both map the
+// poll name to the result.
+//
+// The returned map contains an entry for each poll in oldResults or newResults. Polls that only appear in
+// newResults are reported as ResultAdded, polls that only appear in oldResults as ResultRemoved. If the results of a
+// poll have different types the status is ResultTypeChanged, otherwise Details is set (see DiffResultDetails) and
+// the status is ResultChanged or ResultUnchanged.
+//
+// The polls are compared sorted by name, the first error from DiffResultDetails is returned (except for results of
+// different types).
+func DiffResults(oldResults, newResults map[string]AbstractPollResult) (map[string]ResultDiff, error) {
+	names := make([]string, 0, len(oldResults)+len(newResults))
+	for name := range oldResults {
+		names = append(names, name)
+	}
+	for name := range newResults {
+		if _, inOld := oldResults[name]; !inOld {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	res := make(map[string]ResultDiff, len(names))
+	for _, name := range names {
+		oldResult, inOld := oldResults[name]
+		newResult, inNew := newResults[name]
+		diff := ResultDiff{Old: oldResult, New: newResult}
+		switch {
+		case !inOld:
+			diff.Status = ResultAdded
+		case !inNew:
+			diff.Status = ResultRemoved
+		case reflect.TypeOf(oldResult) != reflect.TypeOf(newResult):
+			diff.Status = ResultTypeChanged
+		default:
+			details, err := DiffResultDetails(oldResult, newResult)
+			if err != nil {
+				return nil, fmt.Errorf("can't compare results of poll \"%s\": %w", name, err)
+			}
+			diff.Details = details
+			if details.Changed() {
+				diff.Status = ResultChanged
+			}
+		}
+		res[name] = diff
+	}
+	return res, nil
+}
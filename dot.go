@@ -0,0 +1,90 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DOTOption is an option for SchulzeResult.WriteDOT, see WithDOTRawMatrix.
+type DOTOption func(options *dotOptions)
+
+type dotOptions struct {
+	rawMatrix bool
+}
+
+// WithDOTRawMatrix returns an option that writes the edges of the matrix d (the number of voters that prefer one
+// option over the other) instead of the strongest paths p.
+func WithDOTRawMatrix() DOTOption {
+	return func(options *dotOptions) {
+		options.rawMatrix = true
+	}
+}
+
+// dotEscape escapes s so that it can be used in a quoted DOT string.
+func dotEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+// WriteDOT writes the beat-path graph of the result as a Graphviz digraph to w.
+//
+// The graph contains one node per option, labeled with the name from optionNames or with the index of the option if
+// optionNames is nil. There is an edge i -> j if p[i][j] > p[j][i], labeled with the strength p[i][j]. With the
+// option WithDOTRawMatrix the edges are computed from d instead.
+//
+// The nodes are written in the order of the options and the edges are sorted by (i, j), so the output is
+// deterministic. If optionNames is not nil and its length doesn't match the number of options a PollingSemanticError
+// is returned, if the result has an error (see Err) this error is returned. In both cases nothing is written.
+// Otherwise any error from writing to w is returned.
+func (schulzeRes *SchulzeResult) WriteDOT(w io.Writer, optionNames []string, options ...DOTOption) error {
+	var opts dotOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	if schulzeRes.Err != nil {
+		return schulzeRes.Err
+	}
+	m := schulzeRes.P
+	if opts.rawMatrix {
+		m = schulzeRes.D
+	}
+	n := len(m)
+	if optionNames != nil && len(optionNames) != n {
+		return NewPollingSemanticError(nil, "can't write schulze result with %d options, got %d option names",
+			n, len(optionNames))
+	}
+	var builder strings.Builder
+	builder.WriteString("digraph schulze {\n")
+	for i := 0; i < n; i++ {
+		label := fmt.Sprintf("%d", i)
+		if optionNames != nil {
+			label = optionNames[i]
+		}
+		fmt.Fprintf(&builder, "\tn%d [label=\"%s\"];\n", i, dotEscape(label))
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if m[i][j] > m[j][i] {
+				fmt.Fprintf(&builder, "\tn%d -> n%d [label=\"%d\"];\n", i, j, m[i][j])
+			}
+		}
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// DuplicateVoterGuard is embedded in all polls of this package, if RejectDuplicateVoters is set to true AddVote
+// returns a DuplicateError if the poll already contains a vote from a voter with the same name.
+//
+// By default duplicates are not checked (the csv files can't contain duplicate votes anyway), so the guard is opt-in.
+// The names of the voters are stored in a set, so AddVote stays O(1) amortized. The set is built on the first
+// AddVote and rebuilt after RemoveVote and ReplaceVote or if the number of votes changed since the last AddVote (for
+// example because Votes was changed directly). If Votes is changed directly without changing the number of votes
+// call ResetVoterIndex (ApplyAnonymization and RevertAnonymization already do this).
+// Votes without a voter are never reported as duplicates.
+//
+// The guard is not compared by the Equals methods of the polls, DeepClone copies RejectDuplicateVoters.
+// To check a poll that has already been filled use FindDuplicateVoters.
+type DuplicateVoterGuard struct {
+	RejectDuplicateVoters bool
+	names                 map[string]struct{}
+	numIndexed            int
+}
+
+// ResetVoterIndex clears the set of voter names, it is rebuilt on the next call of AddVote.
+func (guard *DuplicateVoterGuard) ResetVoterIndex() {
+	guard.names = nil
+	guard.numIndexed = 0
+}
+
+// clone returns a copy of the guard with an empty set of names, used in DeepClone of the polls.
+func (guard *DuplicateVoterGuard) clone() DuplicateVoterGuard {
+	return DuplicateVoterGuard{RejectDuplicateVoters: guard.RejectDuplicateVoters}
+}
+
+// checkNewVoter is called by AddVote before a vote of voter is appended to a poll that currently contains numVotes
+// votes. If RejectDuplicateVoters is false it does nothing. Otherwise it returns a DuplicateError if there is already
+// a vote of the voter, if not the voter is added to the set.
+// forEachVoter is used to rebuild the set, it must call f with the voter of each vote in the poll.
+func (guard *DuplicateVoterGuard) checkNewVoter(voter *Voter, numVotes int,
+	forEachVoter func(f func(voter *Voter))) error {
+	if !guard.RejectDuplicateVoters {
+		return nil
+	}
+	if guard.names == nil || guard.numIndexed != numVotes {
+		guard.names = make(map[string]struct{}, numVotes)
+		forEachVoter(func(voter *Voter) {
+			if voter != nil {
+				guard.names[voter.Name] = struct{}{}
+			}
+		})
+	}
+	if voter != nil {
+		if _, has := guard.names[voter.Name]; has {
+			return NewDuplicateError(fmt.Sprintf("duplicate vote for voter %s", voter.Name))
+		}
+		guard.names[voter.Name] = struct{}{}
+	}
+	guard.numIndexed = numVotes + 1
+	return nil
+}
+
+// FindDuplicateVoters returns the (sorted) names of all voters that have more than one vote in the poll.
+//
+// The poll must implement VoteIterator (all polls of this package do), a SynchronizedPoll is checked while holding
+// its lock. Otherwise a PollTypeError is returned.
+// Votes without a voter are ignored.
+func FindDuplicateVoters(poll AbstractPoll) ([]string, error) {
+	if syncPoll, isSync := poll.(SynchronizedPoll); isSync {
+		var res []string
+		err := syncPoll.WithLock(func(wrapped AbstractPoll) error {
+			var findErr error
+			res, findErr = FindDuplicateVoters(wrapped)
+			return findErr
+		})
+		return res, err
+	}
+	iterator, ok := poll.(VoteIterator)
+	if !ok {
+		return nil, NewPollTypeError("can't find duplicate voters, poll of type %s doesn't implement VoteIterator",
+			reflect.TypeOf(poll))
+	}
+	counts := make(map[string]int, iterator.NumVotes())
+	iterErr := iterator.ForEachVote(func(vote AbstractVote) error {
+		if voter := vote.GetVoter(); voter != nil {
+			counts[voter.Name]++
+		}
+		return nil
+	})
+	if iterErr != nil {
+		return nil, iterErr
+	}
+	res := make([]string, 0)
+	for name, count := range counts {
+		if count > 1 {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
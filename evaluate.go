@@ -0,0 +1,274 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"io"
+)
+
+// EvaluationStage describes a stage of Evaluate and EvaluateFromReaders, it is used to label errors (see
+// EvaluationError).
+type EvaluationStage string
+
+const (
+	StageParsingVoters     EvaluationStage = "parsing voters"
+	StageParsingCollection EvaluationStage = "parsing collection"
+	StageMappingVoters     EvaluationStage = "mapping voters"
+	StageConvertingPolls   EvaluationStage = "converting polls"
+	StageReadingCSV        EvaluationStage = "reading csv"
+	StageValidatingMatrix  EvaluationStage = "validating matrix"
+	StageCreatingParsers   EvaluationStage = "creating parsers"
+	StageFillingPolls      EvaluationStage = "filling polls"
+	StageTallying          EvaluationStage = "tallying"
+)
+
+// EvaluationError is returned by Evaluate and EvaluateFromReaders, Stage is the stage in which the error Err
+// occurred.
+//
+// Err is returned by Unwrap, so for example errors.Is(err, ErrPoll) can be used to test if Err is an error from this
+// package.
+type EvaluationError struct {
+	Stage EvaluationStage
+	Err   error
+}
+
+func newEvaluationError(stage EvaluationStage, err error) EvaluationError {
+	return EvaluationError{
+		Stage: stage,
+		Err:   err,
+	}
+}
+
+func (err EvaluationError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Stage, err.Err.Error())
+}
+
+// Unwrap returns the wrapped error.
+func (err EvaluationError) Unwrap() error {
+	return err.Err
+}
+
+// EvaluateOptions describes how Evaluate and EvaluateFromReaders parse and evaluate the input, the zero value uses
+// the defaults described below.
+//
+// CurrencyHandler is used to parse the values of money polls in the collection, it defaults to
+// DefaultCurrencyHandler.
+// ParserTemplates are the templates used to parse the votes in the csv file (see CustomizeParsersToMap), they default
+// to GenerateDefaultParserTemplateMap with the median parser using CurrencyHandler. A SchulzeVoteParser is
+// customized with the options of the poll, so rankings can also be given by option names.
+//
+// VotersParser and CollectionParser are used to parse the voters and the collection (only for EvaluateFromReaders),
+// they can be used to set limits. They default to NewVotersParser and NewPollCollectionParser.
+// Sep is the separator of the csv file, it defaults to the default of NewVotesCSVReader. ConfigureCSVReader is
+// called with the csv reader before reading, it can be used to set limits or the mode (see VotesCSVReader).
+//
+// DefaultEmptyPolicy is the EmptyVotePolicy used for polls that don't define one, see PoliciesFromCollection.
+// AllowMissingVoters and AllowMissingPolls are passed to PollMatrix.FillPollsWithVotes.
+// SkeletonConverter converts the skeletons to polls, it defaults to DefaultSkeletonConverter.
+//
+// FillOptions are passed to PollMatrix.FillPollsWithVotes (votes are always validated, see WithVoteValidation),
+// TallyOptions to TallyCollection.
+//
+// VoterMap and SkeletonMap can be set to maps that have already been created for the voters and the collection (for
+// example with VotersToMapChecked and PollSkeletonCollection.SkeletonsToMapChecked), in this case they're used instead
+// of creating the maps again. They must contain exactly the voters and skeletons given to Evaluate.
+type EvaluateOptions struct {
+	CurrencyHandler    CurrencyHandler
+	ParserTemplates    map[string]ParserCustomizer
+	VotersParser       *VotersParser
+	CollectionParser   *PollCollectionParser
+	Sep                rune
+	ConfigureCSVReader func(reader *VotesCSVReader)
+	DefaultEmptyPolicy EmptyVotePolicy
+	AllowMissingVoters bool
+	AllowMissingPolls  bool
+	SkeletonConverter  SkeletonConverter
+	FillOptions        []FillOption
+	TallyOptions       []TallyOption
+	VoterMap           VoterMap
+	SkeletonMap        PollSkeletonMap
+}
+
+func (opts EvaluateOptions) getCurrencyHandler() CurrencyHandler {
+	if opts.CurrencyHandler == nil {
+		return DefaultCurrencyHandler
+	}
+	return opts.CurrencyHandler
+}
+
+func (opts EvaluateOptions) getParserTemplates() map[string]ParserCustomizer {
+	if opts.ParserTemplates != nil {
+		return opts.ParserTemplates
+	}
+	res := GenerateDefaultParserTemplateMap()
+	res[MedianPollType] = NewMedianVoteParser(opts.getCurrencyHandler())
+	return res
+}
+
+func (opts EvaluateOptions) getSkeletonConverter() SkeletonConverter {
+	if opts.SkeletonConverter == nil {
+		return DefaultSkeletonConverter
+	}
+	return opts.SkeletonConverter
+}
+
+// EvaluationResult is the result of Evaluate and EvaluateFromReaders.
+//
+// It contains the input (Voters, Collection and the Matrix read from the csv file), the Polls created from the
+// collection and filled with the votes, the reports of the intermediate steps (CSVReport, MatrixReport and
+// FillReport) and the results: Groups contains the tallied polls in the order of the collection (see
+// TallyCollection), Results maps each poll name to its result.
+//
+// If an error occurs the result contains everything computed before the error, all other fields are nil.
+type EvaluationResult struct {
+	Voters       []*Voter
+	Collection   *PollSkeletonCollection
+	Matrix       *PollMatrix
+	Polls        PollMap
+	CSVReport    *CSVReadReport
+	MatrixReport *MatrixValidationReport
+	FillReport   *FillReport
+	Groups       []TalliedGroup
+	Results      map[string]AbstractPollResult
+}
+
+// EvaluateFromReaders parses the voters from votersR and the collection from collectionR and then evaluates the
+// votes from the csv file votesCSV, see Evaluate.
+//
+// All errors are of type EvaluationError, the result is never nil.
+func EvaluateFromReaders(votersR, collectionR, votesCSV io.Reader, opts EvaluateOptions) (*EvaluationResult, error) {
+	votersParser := opts.VotersParser
+	if votersParser == nil {
+		votersParser = NewVotersParser()
+	}
+	voters, votersErr := votersParser.ParseVoters(votersR)
+	if votersErr != nil {
+		return &EvaluationResult{}, newEvaluationError(StageParsingVoters, votersErr)
+	}
+	collectionParser := opts.CollectionParser
+	if collectionParser == nil {
+		collectionParser = NewPollCollectionParser()
+	}
+	collection, collectionErr := collectionParser.ParseCollectionSkeletons(collectionR, opts.getCurrencyHandler())
+	if collectionErr != nil {
+		return &EvaluationResult{Voters: voters}, newEvaluationError(StageParsingCollection, collectionErr)
+	}
+	return Evaluate(voters, collection, votesCSV, opts)
+}
+
+// Evaluate evaluates the votes from the csv file votesCSV given the voters and the collection.
+//
+// It does the following steps: The skeletons of the collection are converted to polls and the csv file is read
+// (matrices with polls in rows are transposed, see PollMatrix.DetectOrientation). The matrix is validated with
+// PollMatrix.Validate, then the polls are filled (see PollMatrix.FillPollsWithVotesWithReport) and tallied (see
+// TallyCollection). See EvaluateOptions for the options.
+//
+// If the csv reader is in LenientCSVMode all malformed rows are reported in the CSVReport of the result, in this case
+// an error is returned as well. The same holds for the problems found while validating the matrix (MatrixReport).
+//
+// All errors are of type EvaluationError, the result is never nil.
+func Evaluate(voters []*Voter, collection *PollSkeletonCollection, votesCSV io.Reader,
+	opts EvaluateOptions) (*EvaluationResult, error) {
+	res := &EvaluationResult{
+		Voters:     voters,
+		Collection: collection,
+	}
+	voterMap := opts.VoterMap
+	if voterMap == nil {
+		var voterMapErr error
+		if voterMap, voterMapErr = VotersToMap(voters); voterMapErr != nil {
+			return res, newEvaluationError(StageMappingVoters, voterMapErr)
+		}
+	}
+
+	skeletons := opts.SkeletonMap
+	if skeletons == nil {
+		var skeletonsErr error
+		if skeletons, skeletonsErr = collection.SkeletonsToMap(); skeletonsErr != nil {
+			return res, newEvaluationError(StageConvertingPolls, skeletonsErr)
+		}
+	}
+	polls, pollsErr := ConvertSkeletonMapToEmptyPolls(skeletons, opts.getSkeletonConverter())
+	if pollsErr != nil {
+		return res, newEvaluationError(StageConvertingPolls, pollsErr)
+	}
+	res.Polls = polls
+
+	csvReader := NewVotesCSVReader(votesCSV)
+	if opts.Sep != 0 {
+		csvReader.Sep = opts.Sep
+	}
+	if opts.ConfigureCSVReader != nil {
+		opts.ConfigureCSVReader(csvReader)
+	}
+	matrix, csvReport, matrixErr := ReadMatrixFromCSVWithReport(csvReader)
+	if matrixErr != nil {
+		return res, newEvaluationError(StageReadingCSV, matrixErr)
+	}
+	res.CSVReport = csvReport
+	if !csvReport.Empty() {
+		return res, newEvaluationError(StageReadingCSV, withSourceName(csvReport.AsError(), csvReader.SourceName))
+	}
+
+	// allow csv files with polls in rows, if the orientation can't be detected Validate reports the problems
+	if orientation, detectErr := matrix.DetectOrientation(voterMap, polls); detectErr == nil && orientation == PollsInRows {
+		if matrix, matrixErr = matrix.Transpose(); matrixErr != nil {
+			return res, newEvaluationError(StageValidatingMatrix, matrixErr)
+		}
+	}
+	res.Matrix = matrix
+	policies := PoliciesFromCollection(collection, opts.DefaultEmptyPolicy)
+	res.MatrixReport = matrix.Validate(voterMap, polls, policies)
+	if !res.MatrixReport.Empty() {
+		return res, newEvaluationError(StageValidatingMatrix, res.MatrixReport.AsError())
+	}
+
+	customized, customizeErr := CustomizeParsersToMap(polls, opts.getParserTemplates())
+	if customizeErr != nil {
+		return res, newEvaluationError(StageCreatingParsers, customizeErr)
+	}
+	parsers := make(map[string]VoteParser, len(customized))
+	for name, parser := range customized {
+		// allow rankings with option names for schulze polls
+		if schulzeParser, ok := parser.(*SchulzeVoteParser); ok {
+			if skel, isPollSkel := skeletons[name].(*PollSkeleton); isPollSkel {
+				parser = schulzeParser.WithOptions(skel.Options)
+			}
+		}
+		parsers[name] = parser
+	}
+
+	fillOptions := append([]FillOption{WithVoteValidation()}, opts.FillOptions...)
+	_, _, fillReport, fillErr := matrix.FillPollsWithVotesWithReport(polls, voterMap, parsers, policies,
+		opts.AllowMissingVoters, opts.AllowMissingPolls, fillOptions...)
+	res.FillReport = fillReport
+	if fillErr != nil {
+		return res, newEvaluationError(StageFillingPolls, fillErr)
+	}
+
+	groups, tallyErr := TallyCollection(collection, polls, opts.TallyOptions...)
+	if tallyErr != nil {
+		return res, newEvaluationError(StageTallying, tallyErr)
+	}
+	res.Groups = groups
+	res.Results = make(map[string]AbstractPollResult, len(polls))
+	for _, group := range groups {
+		for _, tallied := range group.Polls {
+			res.Results[tallied.Skeleton.GetName()] = tallied.Result
+		}
+	}
+	return res, nil
+}
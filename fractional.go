@@ -0,0 +1,504 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// This file contains an opt-in implementation for fractional voter weights, for example weights proportional to
+// shares like 2.5.
+// Weight is an integer type and all tallies in this package work with integers, changing this would break the API.
+// Instead a voter can have a FractionalWeight (a *big.Rat) and the polls have additional tally methods (TallyRat)
+// that accumulate these rational weights without any precision loss.
+// Voters without a FractionalWeight are handled exactly as in the integer tallies, so the result of TallyRat is the
+// same as the result of Tally (converted to rationals) if no voter has a fractional weight.
+
+// ratWeightRx is the regex used to validate a fractional weight in ParseRatWeight.
+var ratWeightRx = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+
+// maxRatWeightDecimals is the number of decimal places used by FormatRatWeight if a weight can't be represented
+// exactly in decimal notation.
+const maxRatWeightDecimals = 10
+
+// WeightToRat converts an integer weight to a rational.
+func WeightToRat(w Weight) *big.Rat {
+	return new(big.Rat).SetInt64(int64(w))
+}
+
+// RatToWeight returns the weight r rounded down to an integer.
+//
+// If r is negative or the rounded weight is >= NoWeight an error wrapping ErrWeightOverflow is returned.
+func RatToWeight(r *big.Rat) (Weight, error) {
+	if r.Sign() < 0 {
+		return NoWeight, NewPollingSemanticError(nil, "weight %s is negative", r.RatString())
+	}
+	floor := new(big.Int).Quo(r.Num(), r.Denom())
+	if !floor.IsInt64() || floor.Int64() >= int64(NoWeight) {
+		return NoWeight, fmt.Errorf("can't convert %s to a weight: %w", r.RatString(), ErrWeightOverflow)
+	}
+	return Weight(floor.Int64()), nil
+}
+
+// ParseRatWeight parses a fractional weight in decimal notation, for example "2.5" or "3".
+//
+// A PollingSyntaxError is returned if s is not a non-negative decimal number (fractions like "5/2" or exponents are
+// not allowed) or if the integer part of s is >= NoWeight.
+func ParseRatWeight(s string) (*big.Rat, error) {
+	if !ratWeightRx.MatchString(s) {
+		return nil, NewPollingSyntaxError(nil, "invalid weight \"%s\", must be a decimal number like 2.5", s)
+	}
+	res, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, NewPollingSyntaxError(nil, "invalid weight \"%s\"", s)
+	}
+	if _, err := RatToWeight(res); err != nil {
+		return nil, NewPollingSyntaxError(err, "weight %s is too big", s)
+	}
+	return res, nil
+}
+
+// FormatRatWeight formats a rational weight in decimal notation, for example "2.5" or "3".
+//
+// The weight is written without trailing zeros if it can be represented exactly with at most ten decimal places,
+// otherwise it is rounded to ten decimal places. Weights parsed with ParseRatWeight are always written exactly.
+func FormatRatWeight(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// find the smallest number of decimal places such that 10^places is a multiple of the denominator
+	pow := big.NewInt(1)
+	ten := big.NewInt(10)
+	mod := new(big.Int)
+	for places := 1; places <= maxRatWeightDecimals; places++ {
+		pow.Mul(pow, ten)
+		if mod.Mod(pow, r.Denom()).Sign() == 0 {
+			return r.FloatString(places)
+		}
+	}
+	return r.FloatString(maxRatWeightDecimals)
+}
+
+// ownRatWeight returns the FractionalWeight of the voter or its Weight if FractionalWeight is nil, delegations are
+// not included.
+func (voter *Voter) ownRatWeight() *big.Rat {
+	if voter.FractionalWeight != nil {
+		return new(big.Rat).Set(voter.FractionalWeight)
+	}
+	return WeightToRat(voter.Weight)
+}
+
+// RatWeight returns the weight of the voter including all delegations as a rational, this is the weight used in
+// the TallyRat methods.
+//
+// It is the FractionalWeight (or Weight if FractionalWeight is nil) plus the weights of all delegations, it can't
+// overflow.
+func (voter *Voter) RatWeight() *big.Rat {
+	res := voter.ownRatWeight()
+	for _, delegation := range voter.Delegations {
+		res.Add(res, WeightToRat(delegation.Weight))
+	}
+	return res
+}
+
+// ratWeightsEqual tests if two (possibly nil) fractional weights are equal.
+func ratWeightsEqual(a, b *big.Rat) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+// RatBasicPollCounter works as BasicPollCounter but counts rational weights.
+//
+// All counters are never nil if created with NewRatBasicPollCounter.
+type RatBasicPollCounter struct {
+	NumNoes, NumAyes, NumAbstention, NumInvalid *big.Rat
+}
+
+// NewRatBasicPollCounter returns a new RatBasicPollCounter with all counters set to 0.
+func NewRatBasicPollCounter() *RatBasicPollCounter {
+	return &RatBasicPollCounter{
+		NumNoes:       new(big.Rat),
+		NumAyes:       new(big.Rat),
+		NumAbstention: new(big.Rat),
+		NumInvalid:    new(big.Rat),
+	}
+}
+
+// RatCounter converts the counter to a RatBasicPollCounter.
+func (counter *BasicPollCounter) RatCounter() *RatBasicPollCounter {
+	return &RatBasicPollCounter{
+		NumNoes:       WeightToRat(counter.NumNoes),
+		NumAyes:       WeightToRat(counter.NumAyes),
+		NumAbstention: WeightToRat(counter.NumAbstention),
+		NumInvalid:    WeightToRat(counter.NumInvalid),
+	}
+}
+
+// Increase increases the counter given the choice by inc, all invalid choices are counted in NumInvalid.
+func (counter *RatBasicPollCounter) Increase(choice BasicPollAnswer, inc *big.Rat) {
+	var target *big.Rat
+	switch choice {
+	case No:
+		target = counter.NumNoes
+	case Aye:
+		target = counter.NumAyes
+	case Abstention:
+		target = counter.NumAbstention
+	default:
+		target = counter.NumInvalid
+	}
+	target.Add(target, inc)
+}
+
+// Equals tests if two counter objects store the same state.
+func (counter *RatBasicPollCounter) Equals(other *RatBasicPollCounter) bool {
+	return counter.NumNoes.Cmp(other.NumNoes) == 0 &&
+		counter.NumAyes.Cmp(other.NumAyes) == 0 &&
+		counter.NumAbstention.Cmp(other.NumAbstention) == 0 &&
+		counter.NumInvalid.Cmp(other.NumInvalid) == 0
+}
+
+// percentOf returns value / (ayes + noes (+ abstentions if includeAbstentions is true)), 0 if the base is 0.
+func (counter *RatBasicPollCounter) percentOf(value *big.Rat, includeAbstentions bool) *big.Rat {
+	base := new(big.Rat).Add(counter.NumAyes, counter.NumNoes)
+	if includeAbstentions {
+		base.Add(base, counter.NumAbstention)
+	}
+	if base.Sign() == 0 {
+		return big.NewRat(0, 1)
+	}
+	return base.Quo(value, base)
+}
+
+// PercentAyes returns the share of ayes, see BasicPollCounter.PercentAyes.
+func (counter *RatBasicPollCounter) PercentAyes(includeAbstentions bool) *big.Rat {
+	return counter.percentOf(counter.NumAyes, includeAbstentions)
+}
+
+// PercentNoes returns the share of noes, see BasicPollCounter.PercentAyes.
+func (counter *RatBasicPollCounter) PercentNoes(includeAbstentions bool) *big.Rat {
+	return counter.percentOf(counter.NumNoes, includeAbstentions)
+}
+
+// PercentAbstentions returns the share of abstentions in ayes + noes + abstentions, 0 if there are no such votes.
+func (counter *RatBasicPollCounter) PercentAbstentions() *big.Rat {
+	return counter.percentOf(counter.NumAbstention, true)
+}
+
+// RatBasicPollResult is the result of BasicPoll.TallyRat.
+//
+// NumberVoters counts how often an answer was taken (independent of the weights), WeightedVotes sums up the
+// rational weights of the voters (see Voter.RatWeight). VotesSum is the sum of the weights of all votes and
+// VotersCount the number of votes.
+type RatBasicPollResult struct {
+	NumberVoters  *BasicPollCounter
+	WeightedVotes *RatBasicPollCounter
+	VotersCount   Weight
+	VotesSum      *big.Rat
+}
+
+// NewRatBasicPollResult returns a new RatBasicPollResult with all counters set to 0.
+func NewRatBasicPollResult() *RatBasicPollResult {
+	return &RatBasicPollResult{
+		NumberVoters:  NewBasicPollCounter(),
+		WeightedVotes: NewRatBasicPollCounter(),
+		VotersCount:   0,
+		VotesSum:      new(big.Rat),
+	}
+}
+
+// RatResult converts an integer result to a RatBasicPollResult, ZeroWeight, VotersByChoice and Err are not part of
+// the converted result.
+func (res *BasicPollResult) RatResult() *RatBasicPollResult {
+	numberVoters := *res.NumberVoters
+	return &RatBasicPollResult{
+		NumberVoters:  &numberVoters,
+		WeightedVotes: res.WeightedVotes.RatCounter(),
+		VotersCount:   res.VotersCount,
+		VotesSum:      WeightToRat(res.VotesSum),
+	}
+}
+
+// Equals tests if two results store the same state.
+func (res *RatBasicPollResult) Equals(other *RatBasicPollResult) bool {
+	return res.NumberVoters.Equals(other.NumberVoters) &&
+		res.WeightedVotes.Equals(other.WeightedVotes) &&
+		res.VotersCount == other.VotersCount &&
+		res.VotesSum.Cmp(other.VotesSum) == 0
+}
+
+// PassesMajority tests if the ayes satisfy the majority rule, see BasicPollResult.PassesMajority.
+func (res *RatBasicPollResult) PassesMajority(majority *big.Rat, strict bool) bool {
+	return MeetsRatMajority(res.WeightedVotes.NumAyes,
+		new(big.Rat).Add(res.WeightedVotes.NumAyes, res.WeightedVotes.NumNoes), majority, strict)
+}
+
+// TallyRat works as Tally but uses the rational weights of the voters (see Voter.RatWeight).
+//
+// The sums are computed without any precision loss and can't overflow.
+func (poll *BasicPoll) TallyRat() *RatBasicPollResult {
+	res := NewRatBasicPollResult()
+	for _, vote := range poll.Votes {
+		w := vote.Voter.RatWeight()
+		res.NumberVoters.Increase(vote.Choice, 1)
+		res.WeightedVotes.Increase(vote.Choice, w)
+		res.VotersCount++
+		res.VotesSum.Add(res.VotesSum, w)
+	}
+	return res
+}
+
+// ComputeRatMajority works as ComputeMajority for rational weights: It returns majority * votesSum, a value needs a
+// weight strictly greater than the result.
+//
+// Unlike ComputeMajority the result is not rounded.
+func ComputeRatMajority(majority, votesSum *big.Rat) *big.Rat {
+	return new(big.Rat).Mul(majority, votesSum)
+}
+
+// MeetsRatMajority works as MeetsMajority for rational weights.
+func MeetsRatMajority(votes, total, majority *big.Rat, strict bool) bool {
+	cmp := votes.Cmp(ComputeRatMajority(majority, total))
+	if strict {
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// ComputeRatPercentage works as ComputePercentage for rational weights, if votesSum is 0 the result is 0.
+func ComputeRatPercentage(votes, votesSum *big.Rat) *big.Rat {
+	if votesSum.Sign() == 0 {
+		return big.NewRat(0, 1)
+	}
+	return new(big.Rat).Quo(votes, votesSum)
+}
+
+// RatMedianResult is the result of MedianPoll.TallyRat.
+//
+// WeightSum is the sum of the weights of all votes that didn't abstain, AbstentionWeight the sum of the weights of
+// all abstentions. RequiredMajority is the weight that the winning value must exceed (strictly), MajorityValue the
+// highest value that reached it (NoMedianUnitValue if there is no such value).
+type RatMedianResult struct {
+	WeightSum        *big.Rat
+	AbstentionWeight *big.Rat
+	RequiredMajority *big.Rat
+	MajorityValue    MedianUnit
+}
+
+// NewRatMedianResult returns a new RatMedianResult with all weights set to 0 and MajorityValue set to
+// NoMedianUnitValue.
+func NewRatMedianResult() *RatMedianResult {
+	return &RatMedianResult{
+		WeightSum:        new(big.Rat),
+		AbstentionWeight: new(big.Rat),
+		RequiredMajority: new(big.Rat),
+		MajorityValue:    NoMedianUnitValue,
+	}
+}
+
+// Equals tests if two results store the same state.
+func (res *RatMedianResult) Equals(other *RatMedianResult) bool {
+	return res.WeightSum.Cmp(other.WeightSum) == 0 &&
+		res.AbstentionWeight.Cmp(other.AbstentionWeight) == 0 &&
+		res.RequiredMajority.Cmp(other.RequiredMajority) == 0 &&
+		res.MajorityValue == other.MajorityValue
+}
+
+// TallyRat works as Tally but uses the rational weights of the voters (see Voter.RatWeight).
+//
+// majority is the majority as a rational (for example FiftyPercentMajority), if it is nil FiftyPercentMajority is
+// used. The highest value with an accumulated weight > majority * WeightSum (strictly) wins, see ComputeRatMajority.
+// Abstentions and TruncateInTally are handled as in Tally.
+//
+// This method will also make sure that the polls are sorted (AssureSorted).
+func (poll *MedianPoll) TallyRat(majority *big.Rat) *RatMedianResult {
+	poll.AssureSorted()
+	res := NewRatMedianResult()
+	if majority == nil {
+		majority = FiftyPercentMajority
+	}
+	for _, vote := range poll.Votes {
+		if vote.Abstain {
+			res.AbstentionWeight.Add(res.AbstentionWeight, vote.Voter.RatWeight())
+		} else {
+			res.WeightSum.Add(res.WeightSum, vote.Voter.RatWeight())
+		}
+	}
+	res.RequiredMajority = ComputeRatMajority(majority, res.WeightSum)
+	currentWeight := new(big.Rat)
+	for _, vote := range poll.Votes {
+		if vote.Abstain {
+			continue
+		}
+		currentWeight.Add(currentWeight, vote.Voter.RatWeight())
+		if currentWeight.Cmp(res.RequiredMajority) > 0 {
+			value := vote.Value
+			if poll.TruncateInTally && value > poll.Value {
+				value = poll.Value
+			}
+			res.MajorityValue = value
+			break
+		}
+	}
+	return res
+}
+
+// RatSchulzeMatrix works as SchulzeMatrix but stores rational weights.
+type RatSchulzeMatrix [][]*big.Rat
+
+// NewRatSchulzeMatrix returns a new matrix of dimension n × n with all entries set to 0.
+func NewRatSchulzeMatrix(n int) RatSchulzeMatrix {
+	res := make(RatSchulzeMatrix, n)
+	for i := 0; i < n; i++ {
+		res[i] = make([]*big.Rat, n)
+		for j := 0; j < n; j++ {
+			res[i][j] = new(big.Rat)
+		}
+	}
+	return res
+}
+
+// RatMatrix converts the matrix to a RatSchulzeMatrix.
+func (m SchulzeMatrix) RatMatrix() RatSchulzeMatrix {
+	res := make(RatSchulzeMatrix, len(m))
+	for i, row := range m {
+		res[i] = make([]*big.Rat, len(row))
+		for j, w := range row {
+			res[i][j] = WeightToRat(w)
+		}
+	}
+	return res
+}
+
+// Equals tests if two matrices are equal.
+func (m RatSchulzeMatrix) Equals(other RatSchulzeMatrix) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for i, row := range m {
+		if len(row) != len(other[i]) {
+			return false
+		}
+		for j, w := range row {
+			if w.Cmp(other[i][j]) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RatSchulzeResult is the result of SchulzePoll.TallyRat.
+//
+// D and P have the same meaning as in SchulzeResult (P is computed with the variant SchulzeWinningVotes) and
+// RankedGroups is the ranking computed from P. WeightSum is the sum of the weights of all votes, InvalidVotesCount
+// and InvalidWeight describe the votes that were ignored because their ranking doesn't have the length NumOptions.
+type RatSchulzeResult struct {
+	D, P              RatSchulzeMatrix
+	RankedGroups      SchulzeWinsList
+	WeightSum         *big.Rat
+	InvalidVotesCount int
+	InvalidWeight     *big.Rat
+}
+
+// Equals tests if two results store the same state.
+func (schulzeRes *RatSchulzeResult) Equals(other *RatSchulzeResult) bool {
+	return schulzeRes.D.Equals(other.D) &&
+		schulzeRes.P.Equals(other.P) &&
+		schulzeRes.RankedGroups.Equals(other.RankedGroups) &&
+		schulzeRes.WeightSum.Cmp(other.WeightSum) == 0 &&
+		schulzeRes.InvalidVotesCount == other.InvalidVotesCount &&
+		schulzeRes.InvalidWeight.Cmp(other.InvalidWeight) == 0
+}
+
+// TallyRat works as Tally but uses the rational weights of the voters (see Voter.RatWeight).
+//
+// It always uses the variant SchulzeWinningVotes. Votes with an invalid ranking are ignored as in Tally.
+func (poll *SchulzePoll) TallyRat() *RatSchulzeResult {
+	n := poll.NumOptions
+	res := &RatSchulzeResult{
+		D:             NewRatSchulzeMatrix(n),
+		P:             NewRatSchulzeMatrix(n),
+		WeightSum:     new(big.Rat),
+		InvalidWeight: new(big.Rat),
+	}
+	for _, vote := range poll.Votes {
+		w := vote.Voter.RatWeight()
+		res.WeightSum.Add(res.WeightSum, w)
+		ranking := vote.Ranking
+		if len(ranking) != n {
+			res.InvalidVotesCount++
+			res.InvalidWeight.Add(res.InvalidWeight, w)
+			continue
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if ranking[i] < ranking[j] {
+					res.D[i][j].Add(res.D[i][j], w)
+				}
+			}
+		}
+	}
+	// compute the strength of the direct links (winning votes) and then the strongest paths
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && res.D[i][j].Cmp(res.D[j][i]) > 0 {
+				res.P[i][j].Set(res.D[i][j])
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j {
+				for k := 0; k < n; k++ {
+					if i != k && j != k {
+						res.P[j][k].Set(ratMax(res.P[j][k], ratMin(res.P[j][i], res.P[i][k])))
+					}
+				}
+			}
+		}
+	}
+	// rankP only compares the entries of p, so a matrix that stores the result of all comparisons is sufficient
+	wins := NewSchulzeMatrix(n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && res.P[i][j].Cmp(res.P[j][i]) > 0 {
+				wins[i][j] = 1
+			}
+		}
+	}
+	res.RankedGroups = poll.rankP(wins)
+	return res
+}
+
+// ratMin returns the minimum of a and b.
+func ratMin(a, b *big.Rat) *big.Rat {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// ratMax returns the maximum of a and b.
+func ratMax(a, b *big.Rat) *big.Rat {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
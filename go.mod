@@ -1,3 +1,5 @@
 module github.com/FabianWe/gopolls
 
 go 1.14
+
+require golang.org/x/text v0.3.8
@@ -0,0 +1,333 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpapi provides http handlers that expose the operations of gopolls as a JSON API.
+//
+// The handlers are bundled in an API, the voters and the poll collection are kept between requests in a Store
+// implemented by the caller (see MemoryStore for a simple implementation). The following routes are registered by
+// NewAPI:
+//
+//	POST /voters        parse a voters file, the voters are stored and returned as VotersResponse
+//	POST /polls         parse a polls file, the collection is stored and returned as CollectionResponse
+//	POST /votes         evaluate a csv file with the votes, the results are returned as EvaluationResponse
+//	GET  /csv-template  an empty csv template for the stored voters and polls
+//
+// The files can either be sent as the body of the request or as a multipart form with the file in the field FileField.
+// All errors are written as ErrorResponse.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/FabianWe/gopolls"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// FileField is the name of the form field that contains the file in a multipart request.
+const FileField = "file"
+
+// DefaultMaxMemory is the default for API.MaxMemory.
+const DefaultMaxMemory = 10 << 20
+
+// API bundles the handlers, it implements http.Handler by dispatching to the handlers (see NewAPI for the routes).
+//
+// Store is used to store the voters and the collection between requests. VotersParser and CollectionParser are used
+// to parse the uploaded files, they can be used to set limits. They're not changed by the handlers, thus they can be
+// shared between concurrent requests as long as they're not changed while the API is in use. CurrencyHandler is used
+// to parse and format the values of money polls.
+//
+// EvaluateOptions are passed to gopolls.Evaluate, the CurrencyHandler of the options is set to CurrencyHandler if it
+// is nil. Its separator is also used to write the csv template.
+//
+// MaxMemory is passed to http.Request.ParseMultipartForm, it defaults to DefaultMaxMemory.
+//
+// The handlers don't keep any state besides the Store, so different APIs can be used at the same time.
+type API struct {
+	Store            Store
+	VotersParser     *gopolls.VotersParser
+	CollectionParser *gopolls.PollCollectionParser
+	CurrencyHandler  gopolls.CurrencyHandler
+	EvaluateOptions  gopolls.EvaluateOptions
+	MaxMemory        int64
+	mux              *http.ServeMux
+}
+
+// NewAPI returns a new API using store.
+//
+// The parsers are set to gopolls.NewVotersParser and gopolls.NewPollCollectionParser, CurrencyHandler to
+// gopolls.DefaultCurrencyHandler. The EvaluateOptions are the zero value, i.e. the defaults from gopolls.Evaluate.
+func NewAPI(store Store) *API {
+	api := &API{
+		Store:            store,
+		VotersParser:     gopolls.NewVotersParser(),
+		CollectionParser: gopolls.NewPollCollectionParser(),
+		CurrencyHandler:  gopolls.DefaultCurrencyHandler,
+		EvaluateOptions:  gopolls.EvaluateOptions{},
+		MaxMemory:        DefaultMaxMemory,
+		mux:              http.NewServeMux(),
+	}
+	api.mux.Handle("/voters", api.VotersHandler())
+	api.mux.Handle("/polls", api.PollsHandler())
+	api.mux.Handle("/votes", api.VotesHandler())
+	api.mux.Handle("/csv-template", api.CSVTemplateHandler())
+	return api
+}
+
+// ServeHTTP dispatches the request to the handler registered for the path.
+//
+// To mount the API under a prefix use http.StripPrefix.
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mux.ServeHTTP(w, r)
+}
+
+// VotersHandler returns the handler for POST requests with a voters file.
+//
+// The voters are parsed with VotersParser, if they're valid and don't contain duplicate names they're stored and
+// written as VotersResponse. Otherwise the error is written with status 422 (http.StatusUnprocessableEntity).
+func (api *API) VotersHandler() http.Handler {
+	return api.postHandler(func(w http.ResponseWriter, r *http.Request, in io.Reader) {
+		voters, err := api.VotersParser.ParseVoters(in)
+		if err == nil {
+			if name, hasDuplicates := gopolls.HasDuplicateVoters(voters); hasDuplicates {
+				err = gopolls.NewDuplicateError(fmt.Sprintf("duplicate voter name %s", name))
+			}
+		}
+		if err != nil {
+			writeError(w, inputErrorStatus(err), err)
+			return
+		}
+		if storeErr := api.Store.StoreVoters(r, voters); storeErr != nil {
+			writeError(w, http.StatusInternalServerError, storeErr)
+			return
+		}
+		res := &VotersResponse{
+			Voters:   make([]VoterJSON, len(voters)),
+			Warnings: newLintWarningsJSON(gopolls.LintVoters(voters)),
+		}
+		for i, voter := range voters {
+			res.Voters[i] = newVoterJSON(voter)
+		}
+		writeJSON(w, http.StatusOK, res)
+	})
+}
+
+// PollsHandler returns the handler for POST requests with a polls file.
+//
+// The collection is parsed with CollectionParser, if it is valid and doesn't contain duplicate poll names it is
+// stored and written as CollectionResponse. Otherwise the error is written with status 422
+// (http.StatusUnprocessableEntity).
+func (api *API) PollsHandler() http.Handler {
+	return api.postHandler(func(w http.ResponseWriter, r *http.Request, in io.Reader) {
+		collection, err := api.CollectionParser.ParseCollectionSkeletons(in, api.getCurrencyHandler())
+		if err == nil {
+			if name, hasDuplicates := collection.HasDuplicateSkeleton(); hasDuplicates {
+				err = gopolls.NewDuplicateError(fmt.Sprintf("duplicate poll name %s", name))
+			}
+		}
+		if err != nil {
+			writeError(w, inputErrorStatus(err), err)
+			return
+		}
+		if storeErr := api.Store.StoreCollection(r, collection); storeErr != nil {
+			writeError(w, http.StatusInternalServerError, storeErr)
+			return
+		}
+		writeJSON(w, http.StatusOK, newCollectionResponse(collection, api.getCurrencyHandler()))
+	})
+}
+
+// VotesHandler returns the handler for POST requests with a csv file containing the votes.
+//
+// The votes are evaluated with gopolls.Evaluate against the stored voters and collection, the results are written
+// as EvaluationResponse. If the voters or the collection haven't been stored yet status 409 (http.StatusConflict)
+// is written. If the csv file is invalid the error is written with status 422 (http.StatusUnprocessableEntity),
+// the rows and matrix issues are part of the ErrorResponse.
+func (api *API) VotesHandler() http.Handler {
+	return api.postHandler(func(w http.ResponseWriter, r *http.Request, in io.Reader) {
+		voters, collection, loadErr := api.load(r)
+		if loadErr != nil {
+			writeError(w, loadErrorStatus(loadErr), loadErr)
+			return
+		}
+		opts := api.EvaluateOptions
+		if opts.CurrencyHandler == nil {
+			opts.CurrencyHandler = api.getCurrencyHandler()
+		}
+		evaluation, evalErr := gopolls.Evaluate(voters, collection, in, opts)
+		if evalErr != nil {
+			res := newErrorResponse(evalErr)
+			if evaluation.CSVReport != nil {
+				for _, rowErr := range evaluation.CSVReport.RowErrors {
+					res.CSVRowErrors = append(res.CSVRowErrors, CSVRowErrorJSON{
+						Row:             rowErr.Row,
+						NumColumns:      rowErr.NumColumns,
+						ExpectedColumns: rowErr.ExpectedColumns,
+						Message:         rowErr.String(),
+					})
+				}
+			}
+			if evaluation.MatrixReport != nil {
+				for _, issue := range evaluation.MatrixReport.Issues {
+					res.MatrixIssues = append(res.MatrixIssues, MatrixIssueJSON{
+						Type:    issue.Type.String(),
+						Row:     issue.Row,
+						Column:  issue.Column,
+						Voter:   issue.Voter,
+						Poll:    issue.Poll,
+						Message: issue.String(),
+					})
+				}
+			}
+			writeJSON(w, inputErrorStatus(evalErr), res)
+			return
+		}
+		writeJSON(w, http.StatusOK, newEvaluationResponse(evaluation))
+	})
+}
+
+// CSVTemplateHandler returns the handler for GET requests that returns an empty csv template for the stored voters
+// and collection (see gopolls.VotesCSVWriter.GenerateEmptyTemplate).
+//
+// If the voters or the collection haven't been stored yet status 409 (http.StatusConflict) is written.
+func (api *API) CSVTemplateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		voters, collection, loadErr := api.load(r)
+		if loadErr != nil {
+			writeError(w, loadErrorStatus(loadErr), loadErr)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=votes.csv")
+		csvWriter := gopolls.NewVotesCSVWriter(w)
+		if api.EvaluateOptions.Sep != 0 {
+			csvWriter.Sep = api.EvaluateOptions.Sep
+		}
+		// the template is streamed, so once writing has started an error can't be reported any more
+		_ = csvWriter.GenerateEmptyTemplate(voters, collection.CollectSkeletons())
+	})
+}
+
+func (api *API) getCurrencyHandler() gopolls.CurrencyHandler {
+	if api.CurrencyHandler == nil {
+		return gopolls.DefaultCurrencyHandler
+	}
+	return api.CurrencyHandler
+}
+
+func (api *API) getMaxMemory() int64 {
+	if api.MaxMemory <= 0 {
+		return DefaultMaxMemory
+	}
+	return api.MaxMemory
+}
+
+// load loads the voters and the collection from the store.
+func (api *API) load(r *http.Request) ([]*gopolls.Voter, *gopolls.PollSkeletonCollection, error) {
+	voters, votersErr := api.Store.LoadVoters(r)
+	if votersErr != nil {
+		return nil, nil, fmt.Errorf("can't load voters: %w", votersErr)
+	}
+	collection, collectionErr := api.Store.LoadCollection(r)
+	if collectionErr != nil {
+		return nil, nil, fmt.Errorf("can't load polls: %w", collectionErr)
+	}
+	return voters, collection, nil
+}
+
+// postHandler returns a handler that only accepts POST requests and calls handle with the uploaded file, see
+// openInput.
+func (api *API) postHandler(handle func(w http.ResponseWriter, r *http.Request, in io.Reader)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		in, closeInput, inputErr := api.openInput(r)
+		if inputErr != nil {
+			writeJSON(w, http.StatusBadRequest, &ErrorResponse{
+				Error: inputErr.Error(),
+				Kind:  RequestErrorKind,
+			})
+			return
+		}
+		defer closeInput()
+		handle(w, r, in)
+	})
+}
+
+// openInput returns the uploaded file: for a multipart form the file in FileField, otherwise the body.
+// The returned function must be called to close the file.
+func (api *API) openInput(r *http.Request) (io.Reader, func(), error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, func() {}, nil
+	}
+	if err := r.ParseMultipartForm(api.getMaxMemory()); err != nil {
+		return nil, nil, err
+	}
+	file, _, fileErr := r.FormFile(FileField)
+	if fileErr != nil {
+		return nil, nil, fmt.Errorf("can't read form field \"%s\": %w", FileField, fileErr)
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// inputErrorStatus returns http.StatusUnprocessableEntity for errors from gopolls (the input is invalid) and
+// http.StatusInternalServerError otherwise.
+func inputErrorStatus(err error) int {
+	if errors.Is(err, gopolls.ErrPoll) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// loadErrorStatus returns http.StatusConflict for ErrNotStored and http.StatusInternalServerError otherwise.
+func loadErrorStatus(err error) int {
+	if errors.Is(err, ErrNotStored) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeJSON(w, http.StatusMethodNotAllowed, &ErrorResponse{
+		Error: fmt.Sprintf("method not allowed, expected %s", allowed),
+		Kind:  RequestErrorKind,
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, newErrorResponse(err))
+}
+
+// writeJSON encodes v before writing anything, this way an encoding error can still be reported with status 500.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// nothing we can do if writing fails, the header has already been written
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -0,0 +1,349 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpapi
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+)
+
+// This file contains the types written as JSON by the handlers.
+// Like in gopolls the values are converted to these types first, this way the field names stay stable even if the
+// Go types change.
+
+// Error kinds used in ErrorResponse.Kind.
+const (
+	SyntaxErrorKind     = "syntax"
+	SemanticErrorKind   = "semantic"
+	DuplicateErrorKind  = "duplicate"
+	ValidationErrorKind = "validation"
+	PollErrorKind       = "poll"
+	RequestErrorKind    = "request"
+	NotStoredErrorKind  = "not-stored"
+	InternalErrorKind   = "internal"
+)
+
+// CSVRowErrorJSON is the JSON representation of a gopolls.CSVRowError.
+type CSVRowErrorJSON struct {
+	Row             int    `json:"row"`
+	NumColumns      int    `json:"num_columns"`
+	ExpectedColumns int    `json:"expected_columns"`
+	Message         string `json:"message"`
+}
+
+// MatrixIssueJSON is the JSON representation of a gopolls.MatrixIssue.
+type MatrixIssueJSON struct {
+	Type    string `json:"type"`
+	Row     int    `json:"row"`
+	Column  int    `json:"column"`
+	Voter   string `json:"voter,omitempty"`
+	Poll    string `json:"poll,omitempty"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is written by all handlers if an error occurs.
+//
+// Kind is one of the error kinds (for example SyntaxErrorKind) and Line is the line number of a syntax error (only
+// set if the line is known). Stage is set to the gopolls.EvaluationStage if the error occurred while evaluating the
+// votes, in this case CSVRowErrors and MatrixIssues contain the problems found in the csv file.
+type ErrorResponse struct {
+	Error        string            `json:"error"`
+	Kind         string            `json:"kind"`
+	Line         *int              `json:"line,omitempty"`
+	Stage        string            `json:"stage,omitempty"`
+	CSVRowErrors []CSVRowErrorJSON `json:"csv_row_errors,omitempty"`
+	MatrixIssues []MatrixIssueJSON `json:"matrix_issues,omitempty"`
+}
+
+// newErrorResponse returns an ErrorResponse for err, the kind is derived from the type of err.
+func newErrorResponse(err error) *ErrorResponse {
+	res := &ErrorResponse{
+		Error: err.Error(),
+		Kind:  errorKind(err),
+	}
+	var syntaxErr gopolls.PollingSyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.LineNum >= 0 {
+		line := syntaxErr.LineNum
+		res.Line = &line
+	}
+	var evalErr gopolls.EvaluationError
+	if errors.As(err, &evalErr) {
+		res.Stage = string(evalErr.Stage)
+	}
+	return res
+}
+
+// errorKind returns the error kind for err, errors not from gopolls are InternalErrorKind.
+func errorKind(err error) string {
+	var syntaxErr gopolls.PollingSyntaxError
+	var semanticErr gopolls.PollingSemanticError
+	var duplicateErr gopolls.DuplicateError
+	var validationErr *gopolls.ParserValidationError
+	switch {
+	case errors.Is(err, ErrNotStored):
+		return NotStoredErrorKind
+	case errors.As(err, &syntaxErr):
+		return SyntaxErrorKind
+	case errors.As(err, &semanticErr):
+		return SemanticErrorKind
+	case errors.As(err, &duplicateErr):
+		return DuplicateErrorKind
+	case errors.As(err, &validationErr):
+		return ValidationErrorKind
+	case errors.Is(err, gopolls.ErrPoll):
+		return PollErrorKind
+	default:
+		return InternalErrorKind
+	}
+}
+
+// DelegationJSON is the JSON representation of a gopolls.Delegation.
+type DelegationJSON struct {
+	Source string         `json:"source"`
+	Weight gopolls.Weight `json:"weight"`
+}
+
+// VoterJSON is the JSON representation of a gopolls.Voter.
+type VoterJSON struct {
+	Name        string            `json:"name"`
+	Weight      gopolls.Weight    `json:"weight"`
+	Delegations []DelegationJSON  `json:"delegations,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+func newVoterJSON(voter *gopolls.Voter) VoterJSON {
+	res := VoterJSON{
+		Name:       voter.Name,
+		Weight:     voter.Weight,
+		Attributes: voter.Attributes,
+	}
+	for _, delegation := range voter.Delegations {
+		res.Delegations = append(res.Delegations, DelegationJSON{
+			Source: delegation.Source,
+			Weight: delegation.Weight,
+		})
+	}
+	return res
+}
+
+// LintWarningJSON is the JSON representation of a gopolls.LintWarning, the indices are -1 if not set.
+type LintWarningJSON struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	VoterIndex int    `json:"voter_index"`
+	GroupIndex int    `json:"group_index"`
+	PollIndex  int    `json:"poll_index"`
+}
+
+func newLintWarningsJSON(warnings []gopolls.LintWarning) []LintWarningJSON {
+	res := make([]LintWarningJSON, len(warnings))
+	for i, warning := range warnings {
+		res[i] = LintWarningJSON{
+			Code:       warning.Code.String(),
+			Message:    warning.Msg,
+			VoterIndex: warning.VoterIndex,
+			GroupIndex: warning.GroupIndex,
+			PollIndex:  warning.PollIndex,
+		}
+	}
+	return res
+}
+
+// VotersResponse is written by the voters handler, it contains the parsed voters and the warnings found by
+// gopolls.LintVoters.
+type VotersResponse struct {
+	Voters   []VoterJSON       `json:"voters"`
+	Warnings []LintWarningJSON `json:"warnings"`
+}
+
+// CurrencyValueJSON is the JSON representation of a gopolls.CurrencyValue, Formatted is the value formatted with the
+// currency handler of the API.
+type CurrencyValueJSON struct {
+	Cents     int    `json:"cents"`
+	Currency  string `json:"currency"`
+	Formatted string `json:"formatted"`
+}
+
+func newCurrencyValueJSON(value gopolls.CurrencyValue, formatter gopolls.CurrencyFormatter) *CurrencyValueJSON {
+	return &CurrencyValueJSON{
+		Cents:     value.ValueCents,
+		Currency:  value.Currency,
+		Formatted: formatter.Format(value),
+	}
+}
+
+// SkeletonJSON is the JSON representation of a gopolls.AbstractPollSkeleton.
+//
+// Type is the skeleton type (see gopolls.AbstractPollSkeleton.SkeletonType), Options is only set for a
+// gopolls.PollSkeleton and Value only for a gopolls.MoneyPollSkeleton. MinValue and Step are the restrictions of a
+// gopolls.MoneyPollSkeleton, they're only set if they restrict the values. Descriptions contains the description of
+// each option, it is only set if at least one option has a description. The annotations are only set if they're
+// given in the polls file, the majority and quorum are encoded as fractions (for example "2/3").
+type SkeletonJSON struct {
+	Name             string                   `json:"name"`
+	Type             string                   `json:"type"`
+	Options          []string                 `json:"options,omitempty"`
+	Descriptions     []string                 `json:"descriptions,omitempty"`
+	Value            *CurrencyValueJSON       `json:"value,omitempty"`
+	MinValue         *CurrencyValueJSON       `json:"min_value,omitempty"`
+	Step             *CurrencyValueJSON       `json:"step,omitempty"`
+	RequiredMajority string                   `json:"required_majority,omitempty"`
+	Quorum           string                   `json:"quorum,omitempty"`
+	EmptyPolicy      *gopolls.EmptyVotePolicy `json:"empty_policy,omitempty"`
+}
+
+func newSkeletonJSON(skel gopolls.AbstractPollSkeleton, formatter gopolls.CurrencyFormatter) SkeletonJSON {
+	res := SkeletonJSON{
+		Name: skel.GetName(),
+		Type: skel.SkeletonType(),
+	}
+	switch typedSkel := skel.(type) {
+	case *gopolls.PollSkeleton:
+		res.Options = typedSkel.Options
+		if typedSkel.HasDescriptions() {
+			res.Descriptions = make([]string, len(typedSkel.Options))
+			for i := range typedSkel.Options {
+				res.Descriptions[i] = typedSkel.Description(i)
+			}
+		}
+	case *gopolls.MoneyPollSkeleton:
+		res.Value = newCurrencyValueJSON(typedSkel.Value, formatter)
+		if typedSkel.MinValue.ValueCents != 0 {
+			res.MinValue = newCurrencyValueJSON(typedSkel.MinValue, formatter)
+		}
+		if typedSkel.Step.ValueCents != 0 {
+			res.Step = newCurrencyValueJSON(typedSkel.Step, formatter)
+		}
+	}
+	if annotated, ok := skel.(gopolls.AnnotatedSkeleton); ok {
+		annotations := annotated.GetAnnotations()
+		if annotations.RequiredMajority != nil {
+			res.RequiredMajority = annotations.RequiredMajority.RatString()
+		}
+		if annotations.Quorum != nil {
+			res.Quorum = annotations.Quorum.RatString()
+		}
+		res.EmptyPolicy = annotations.EmptyPolicy
+	}
+	return res
+}
+
+// GroupJSON is the JSON representation of a gopolls.PollGroup.
+type GroupJSON struct {
+	Title string         `json:"title"`
+	Polls []SkeletonJSON `json:"polls"`
+}
+
+// CollectionResponse is written by the polls handler, it contains the parsed collection and the warnings found by
+// gopolls.LintCollection.
+type CollectionResponse struct {
+	Title    string            `json:"title"`
+	Groups   []GroupJSON       `json:"groups"`
+	Warnings []LintWarningJSON `json:"warnings"`
+}
+
+func newCollectionResponse(collection *gopolls.PollSkeletonCollection,
+	formatter gopolls.CurrencyFormatter) *CollectionResponse {
+	res := &CollectionResponse{
+		Title:    collection.Title,
+		Groups:   make([]GroupJSON, len(collection.Groups)),
+		Warnings: newLintWarningsJSON(gopolls.LintCollection(collection)),
+	}
+	for i, group := range collection.Groups {
+		groupJSON := GroupJSON{
+			Title: group.Title,
+			Polls: make([]SkeletonJSON, len(group.Skeletons)),
+		}
+		for j, skel := range group.Skeletons {
+			groupJSON.Polls[j] = newSkeletonJSON(skel, formatter)
+		}
+		res.Groups[i] = groupJSON
+	}
+	return res
+}
+
+// TieBreakJSON is the JSON representation of a gopolls.TieBreak.
+type TieBreakJSON struct {
+	Candidates []int  `json:"candidates"`
+	Winner     int    `json:"winner"`
+	Method     string `json:"method"`
+}
+
+// TalliedPollJSON is the JSON representation of a gopolls.TalliedPoll.
+//
+// Result is encoded with the JSON encoding of the result types from gopolls, ResultType is the type of the result
+// (see gopolls.AbstractPollResult.ResultType).
+type TalliedPollJSON struct {
+	Name       string                     `json:"name"`
+	ResultType string                     `json:"result_type"`
+	Result     gopolls.AbstractPollResult `json:"result"`
+	TieBreak   *TieBreakJSON              `json:"tie_break,omitempty"`
+}
+
+// TalliedGroupJSON is the JSON representation of a gopolls.TalliedGroup.
+type TalliedGroupJSON struct {
+	Title string            `json:"title"`
+	Polls []TalliedPollJSON `json:"polls"`
+}
+
+// EvaluationResponse is written by the votes handler, it contains the tallied groups in the order of the collection
+// and the names of the voters without votes and the polls without votes (see gopolls.FillReport).
+// Turnout is the turnout of the filled polls (see gopolls.ComputeTurnout), it is omitted if it can't be computed.
+type EvaluationResponse struct {
+	Title             string             `json:"title"`
+	Groups            []TalliedGroupJSON `json:"groups"`
+	EmptyVoters       []string           `json:"empty_voters"`
+	PollsWithoutVotes []string           `json:"polls_without_votes"`
+	Turnout           *gopolls.Turnout   `json:"turnout,omitempty"`
+}
+
+func newEvaluationResponse(evaluation *gopolls.EvaluationResult) *EvaluationResponse {
+	res := &EvaluationResponse{
+		Title:             evaluation.Collection.Title,
+		Groups:            make([]TalliedGroupJSON, len(evaluation.Groups)),
+		EmptyVoters:       evaluation.FillReport.EmptyVoters(),
+		PollsWithoutVotes: evaluation.FillReport.PollsWithoutVotes(),
+		Turnout:           nil,
+	}
+	// the voters and polls have already been validated by the evaluation, so errors are not expected here
+	if voters, votersErr := gopolls.VotersToMap(evaluation.Voters); votersErr == nil {
+		if turnout, turnoutErr := gopolls.ComputeTurnout(evaluation.Polls, voters); turnoutErr == nil {
+			res.Turnout = turnout
+		}
+	}
+	for i, group := range evaluation.Groups {
+		groupJSON := TalliedGroupJSON{
+			Title: group.Title,
+			Polls: make([]TalliedPollJSON, len(group.Polls)),
+		}
+		for j, tallied := range group.Polls {
+			pollJSON := TalliedPollJSON{
+				Name:       tallied.Skeleton.GetName(),
+				ResultType: tallied.Result.ResultType(),
+				Result:     tallied.Result,
+			}
+			if tallied.TieBreak != nil {
+				pollJSON.TieBreak = &TieBreakJSON{
+					Candidates: tallied.TieBreak.Candidates,
+					Winner:     tallied.TieBreak.Winner,
+					Method:     tallied.TieBreak.Method,
+				}
+			}
+			groupJSON.Polls[j] = pollJSON
+		}
+		res.Groups[i] = groupJSON
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpapi
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"net/http"
+	"sync"
+)
+
+// ErrNotStored is returned by a Store if nothing has been stored yet.
+var ErrNotStored = errors.New("nothing stored yet")
+
+// Store is used by the handlers to store the voters and the poll collection between requests.
+//
+// The request is passed to all methods, this way an implementation can for example store different voters / polls
+// for each session or user. Load methods must return ErrNotStored (or an error wrapping it) if nothing has been stored
+// yet.
+//
+// A Store must be safe for concurrent use because the handlers can be called concurrently.
+type Store interface {
+	LoadVoters(r *http.Request) ([]*gopolls.Voter, error)
+	StoreVoters(r *http.Request, voters []*gopolls.Voter) error
+	LoadCollection(r *http.Request) (*gopolls.PollSkeletonCollection, error)
+	StoreCollection(r *http.Request, collection *gopolls.PollSkeletonCollection) error
+}
+
+// MemoryStore is a Store that keeps a single list of voters and a single collection in memory, the request is
+// ignored.
+//
+// It is mainly useful for tests and small applications with only one user.
+type MemoryStore struct {
+	mutex      sync.RWMutex
+	voters     []*gopolls.Voter
+	collection *gopolls.PollSkeletonCollection
+}
+
+// NewMemoryStore returns a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		voters:     nil,
+		collection: nil,
+	}
+}
+
+// LoadVoters returns the stored voters or ErrNotStored.
+func (store *MemoryStore) LoadVoters(r *http.Request) ([]*gopolls.Voter, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	if store.voters == nil {
+		return nil, ErrNotStored
+	}
+	return store.voters, nil
+}
+
+// StoreVoters replaces the stored voters.
+func (store *MemoryStore) StoreVoters(r *http.Request, voters []*gopolls.Voter) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.voters = voters
+	return nil
+}
+
+// LoadCollection returns the stored collection or ErrNotStored.
+func (store *MemoryStore) LoadCollection(r *http.Request) (*gopolls.PollSkeletonCollection, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	if store.collection == nil {
+		return nil, ErrNotStored
+	}
+	return store.collection, nil
+}
+
+// StoreCollection replaces the stored collection.
+func (store *MemoryStore) StoreCollection(r *http.Request, collection *gopolls.PollSkeletonCollection) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.collection = collection
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// This file contains the JSON encoding of the poll results.
+// The results are not encoded directly but converted to an internal type first, this way the field names stay stable
+// even if the Go types change.
+
+type basicPollCounterJSON struct {
+	No         Weight `json:"no"`
+	Aye        Weight `json:"aye"`
+	Abstention Weight `json:"abstention"`
+	Invalid    Weight `json:"invalid"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// The counter is encoded as an object with the keys "no", "aye", "abstention" and "invalid".
+func (counter *BasicPollCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(basicPollCounterJSON{
+		No:         counter.NumNoes,
+		Aye:        counter.NumAyes,
+		Abstention: counter.NumAbstention,
+		Invalid:    counter.NumInvalid,
+	})
+}
+
+type basicPollResultJSON struct {
+	NumberVoters   *BasicPollCounter   `json:"number_voters"`
+	WeightedVotes  *BasicPollCounter   `json:"weighted_votes"`
+	VotersCount    Weight              `json:"voters_count"`
+	VotesSum       Weight              `json:"votes_sum"`
+	ZeroWeight     *BasicPollCounter   `json:"zero_weight,omitempty"`
+	VotersByChoice map[string][]string `json:"voters_by_choice,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// The result is encoded as an object with the keys "number_voters" and "weighted_votes" (both encoded as
+// BasicPollCounter) and "voters_count" and "votes_sum". If ZeroWeight is set it is encoded as "zero_weight".
+// If VotersByChoice is set it is encoded as "voters_by_choice", mapping each answer (see BasicPollAnswer.String) to
+// the names of the voters.
+//
+// If the result has its Err set (for example because the weights overflow) Err is returned instead.
+func (res *BasicPollResult) MarshalJSON() ([]byte, error) {
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	var votersByChoice map[string][]string
+	if res.VotersByChoice != nil {
+		votersByChoice = make(map[string][]string, len(res.VotersByChoice))
+		for choice, voters := range res.VotersByChoice {
+			names := make([]string, len(voters))
+			for i, voter := range voters {
+				names[i] = voter.Name
+			}
+			votersByChoice[choice.String()] = names
+		}
+	}
+	return json.Marshal(basicPollResultJSON{
+		NumberVoters:   res.NumberVoters,
+		WeightedVotes:  res.WeightedVotes,
+		VotersCount:    res.VotersCount,
+		VotesSum:       res.VotesSum,
+		ZeroWeight:     res.ZeroWeight,
+		VotersByChoice: votersByChoice,
+	})
+}
+
+type medianResultJSON struct {
+	WeightSum        Weight              `json:"weight_sum"`
+	AbstentionWeight Weight              `json:"abstention_weight,omitempty"`
+	RequiredMajority Weight              `json:"required_majority"`
+	MajorityValue    *MedianUnit         `json:"majority_value"`
+	ValueDetails     map[string][]string `json:"value_details"`
+	TruncatedCount   int                 `json:"truncated_count,omitempty"`
+	TruncatedWeight  Weight              `json:"truncated_weight,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// The result is encoded as an object with the keys "weight_sum", "required_majority", "majority_value" and
+// "value_details".
+// If MajorityValue is NoMedianUnitValue "majority_value" is null.
+// "value_details" maps each value (as a string, JSON only allows string keys) to the names of the voters that voted
+// for this value.
+// If votes were truncated (see MedianPoll.TruncateInTally) "truncated_count" and "truncated_weight" are included.
+// If voters abstained their weight is included as "abstention_weight".
+//
+// If the result has its Err set (for example because the weights overflow) Err is returned instead.
+func (result *MedianResult) MarshalJSON() ([]byte, error) {
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	var majorityValue *MedianUnit
+	if result.MajorityValue != NoMedianUnitValue {
+		value := result.MajorityValue
+		majorityValue = &value
+	}
+	details := make(map[string][]string, len(result.ValueDetails))
+	for value, voters := range result.ValueDetails {
+		names := make([]string, len(voters))
+		for i, voter := range voters {
+			names[i] = voter.Name
+		}
+		details[strconv.FormatUint(uint64(value), 10)] = names
+	}
+	return json.Marshal(medianResultJSON{
+		WeightSum:        result.WeightSum,
+		AbstentionWeight: result.AbstentionWeight,
+		RequiredMajority: result.RequiredMajority,
+		MajorityValue:    majorityValue,
+		ValueDetails:     details,
+		TruncatedCount:   result.TruncatedCount,
+		TruncatedWeight:  result.TruncatedWeight,
+	})
+}
+
+type schulzeResultJSON struct {
+	D             SchulzeMatrix       `json:"d"`
+	DNonStrict    SchulzeMatrix       `json:"d_non_strict"`
+	P             SchulzeMatrix       `json:"p"`
+	Margins       SchulzeMarginMatrix `json:"margins"`
+	RankedGroups  SchulzeWinsList     `json:"ranked_groups"`
+	WeightSum     Weight              `json:"weight_sum"`
+	Variant       string              `json:"variant"`
+	InvalidVotes  int                 `json:"invalid_votes_count,omitempty"`
+	InvalidWeight Weight              `json:"invalid_weight,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// The result is encoded as an object with the keys "d", "d_non_strict", "p", "margins", "ranked_groups",
+// "weight_sum" and "variant" (the String() representation of the SchulzeVariant).
+// If votes were invalid "invalid_votes_count" and "invalid_weight" are included.
+//
+// If the result has its Err set (for example because the weights overflow) Err is returned instead.
+func (schulzeRes *SchulzeResult) MarshalJSON() ([]byte, error) {
+	if schulzeRes.Err != nil {
+		return nil, schulzeRes.Err
+	}
+	return json.Marshal(schulzeResultJSON{
+		D:             schulzeRes.D,
+		DNonStrict:    schulzeRes.DNonStrict,
+		P:             schulzeRes.P,
+		Margins:       schulzeRes.Margins,
+		RankedGroups:  schulzeRes.RankedGroups,
+		WeightSum:     schulzeRes.WeightSum,
+		Variant:       schulzeRes.Variant.String(),
+		InvalidVotes:  schulzeRes.InvalidVotesCount,
+		InvalidWeight: schulzeRes.InvalidWeight,
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import "fmt"
+
+// LintCode describes the kind of a LintWarning.
+type LintCode int8
+
+const (
+	// LintZeroWeightVoter is used for a voter with weight 0.
+	LintZeroWeightVoter LintCode = iota
+	// LintDuplicateOption is used for a poll that contains the same option string more than once.
+	LintDuplicateOption
+	// LintEmptyGroup is used for a group without any polls.
+	LintEmptyGroup
+	// LintZeroMoneyValue is used for a money poll with value 0.
+	LintZeroMoneyValue
+	// LintPollNameEqualsGroupTitle is used for a poll with the same name as the title of its group.
+	LintPollNameEqualsGroupTitle
+	// LintGroupTitleEqualsCollectionTitle is used for a group with the same title as the collection.
+	LintGroupTitleEqualsCollectionTitle
+)
+
+func (code LintCode) String() string {
+	switch code {
+	case LintZeroWeightVoter:
+		return "zero-weight-voter"
+	case LintDuplicateOption:
+		return "duplicate-option"
+	case LintEmptyGroup:
+		return "empty-group"
+	case LintZeroMoneyValue:
+		return "zero-money-value"
+	case LintPollNameEqualsGroupTitle:
+		return "poll-name-equals-group-title"
+	case LintGroupTitleEqualsCollectionTitle:
+		return "group-title-equals-collection-title"
+	default:
+		return fmt.Sprintf("LintCode(%d)", code)
+	}
+}
+
+// LintWarning describes something in the voters or polls that is not an error but probably not intended, see
+// LintVoters and LintCollection.
+//
+// Code is the kind of the warning and Msg a human-readable description.
+// VoterIndex, GroupIndex and PollIndex are the positions (starting with 0) of the voter, the group and the poll in
+// its group the warning refers to, they're -1 if they don't apply to the warning.
+type LintWarning struct {
+	Code       LintCode
+	Msg        string
+	VoterIndex int
+	GroupIndex int
+	PollIndex  int
+}
+
+// newLintWarning returns a new LintWarning with all positions set to -1.
+func newLintWarning(code LintCode, msg string, args ...interface{}) LintWarning {
+	return LintWarning{
+		Code:       code,
+		Msg:        fmt.Sprintf(msg, args...),
+		VoterIndex: -1,
+		GroupIndex: -1,
+		PollIndex:  -1,
+	}
+}
+
+func (warning LintWarning) String() string {
+	return fmt.Sprintf("%s: %s", warning.Code, warning.Msg)
+}
+
+// LintVoters returns warnings for all voters with weight 0.
+//
+// It doesn't check for errors like duplicate names, see HasDuplicateVoters for that.
+func LintVoters(voters []*Voter) []LintWarning {
+	var res []LintWarning
+	for i, voter := range voters {
+		if voter.Weight == 0 {
+			warning := newLintWarning(LintZeroWeightVoter, "voter \"%s\" (number %d) has weight 0", voter.Name, i+1)
+			warning.VoterIndex = i
+			res = append(res, warning)
+		}
+	}
+	return res
+}
+
+// LintCollection returns warnings for the collection: groups without polls, polls with duplicate options, money
+// polls with value 0 and titles that are equal to the title of the enclosing group or collection.
+//
+// The parsers don't return any of these as errors. LintCollection doesn't check for errors like duplicate poll names,
+// see PollSkeletonCollection.HasDuplicateSkeleton for that.
+func LintCollection(collection *PollSkeletonCollection) []LintWarning {
+	var res []LintWarning
+	for groupIndex, group := range collection.Groups {
+		add := func(warning LintWarning, pollIndex int) {
+			warning.GroupIndex = groupIndex
+			warning.PollIndex = pollIndex
+			res = append(res, warning)
+		}
+		if group.Title == collection.Title {
+			add(newLintWarning(LintGroupTitleEqualsCollectionTitle,
+				"group \"%s\" (number %d) has the same title as the collection", group.Title, groupIndex+1), -1)
+		}
+		if len(group.Skeletons) == 0 {
+			add(newLintWarning(LintEmptyGroup, "group \"%s\" (number %d) contains no polls",
+				group.Title, groupIndex+1), -1)
+		}
+		for pollIndex, skel := range group.Skeletons {
+			name := skel.GetName()
+			if name == group.Title {
+				add(newLintWarning(LintPollNameEqualsGroupTitle,
+					"poll \"%s\" (number %d in group %d) has the same name as its group", name, pollIndex+1,
+					groupIndex+1), pollIndex)
+			}
+			switch typedSkel := skel.(type) {
+			case *MoneyPollSkeleton:
+				if typedSkel.Value.ValueCents == 0 {
+					add(newLintWarning(LintZeroMoneyValue, "money poll \"%s\" has value 0", name), pollIndex)
+				}
+			case *PollSkeleton:
+				firstPosition := make(map[string]int, len(typedSkel.Options))
+				for optionIndex, option := range typedSkel.Options {
+					if first, has := firstPosition[option]; has {
+						add(newLintWarning(LintDuplicateOption,
+							"options %d and %d of poll \"%s\" are both \"%s\"", first+1, optionIndex+1, name, option),
+							pollIndex)
+					} else {
+						firstPosition[option] = optionIndex
+					}
+				}
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Logger is the interface used by LogPollEvents, it is implemented by *log.Logger.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// Actions of a PollEvent.
+//
+// EmptyVoteIgnoredAction and EmptyVoteGeneratedAction are reported while filling polls for an empty entry that was
+// ignored or for which the EmptyVotePolicy generated a vote. ZeroWeightSkippedAction is reported for an entry of a
+// voter with weight 0 that was skipped (see WithZeroWeightPolicy) and PollSkippedAction for a poll that was skipped
+// because it has no parser (see WithSkipPollsWithoutParser).
+// InvalidVoteAction is reported by TallyCollection for a vote that is not valid for its poll (see VoteValidator),
+// VoteTruncatedAction and VoteRemovedAction are reported by TruncatePollVoters.
+const (
+	EmptyVoteIgnoredAction   = "empty-vote-ignored"
+	EmptyVoteGeneratedAction = "empty-vote-generated"
+	ZeroWeightSkippedAction  = "zero-weight-skipped"
+	PollSkippedAction        = "poll-skipped"
+	InvalidVoteAction        = "invalid-vote"
+	VoteTruncatedAction      = "vote-truncated"
+	VoteRemovedAction        = "vote-removed"
+)
+
+// PollEvent describes a decision made while filling or evaluating a poll, it is intended for audit logs.
+//
+// Poll is the name of the poll and Voter the name of the voter (empty if the event doesn't belong to a single
+// voter or the vote has no voter). Action is one of the action constants (like InvalidVoteAction) and Reason a human readable explanation.
+type PollEvent struct {
+	Poll   string
+	Voter  string
+	Action string
+	Reason string
+}
+
+// String returns a string of the form poll="..." voter="..." action=... reason="...".
+func (event PollEvent) String() string {
+	return fmt.Sprintf("poll=%q voter=%q action=%s reason=%q", event.Poll, event.Voter, event.Action, event.Reason)
+}
+
+// PollEventHandler is a function that is called for each PollEvent, see for example WithFillEventHandler and
+// WithTallyEventHandler.
+//
+// If no handler is set no events are created, so the handlers don't slow down the operations if they're not used.
+type PollEventHandler func(event PollEvent)
+
+// LogPollEvents returns a PollEventHandler that writes each event (see PollEvent.String) to logger.
+func LogPollEvents(logger Logger) PollEventHandler {
+	return func(event PollEvent) {
+		logger.Printf("%s", event)
+	}
+}
+
+// eventVoterName returns the name of voter for a PollEvent, the name is empty if voter is nil.
+func eventVoterName(voter *Voter) string {
+	if voter == nil {
+		return ""
+	}
+	return voter.Name
+}
+
+// TruncatePollVoters calls TruncateVoters of poll and returns the culprits, handler is called for each culprit (in
+// the order returned by TruncateVoters). handler can be nil.
+//
+// For a MedianPoll the values are truncated (VoteTruncatedAction), for all other polls the culprits are removed
+// (VoteRemovedAction).
+// Supported polls are *BasicPoll, *MedianPoll, *SchulzePoll and *ScorePoll, for all other types a PollTypeError is
+// returned.
+func TruncatePollVoters(pollName string, poll AbstractPoll, handler PollEventHandler) ([]AbstractVote, error) {
+	var res []AbstractVote
+	switch typedPoll := poll.(type) {
+	case *BasicPoll:
+		for _, vote := range typedPoll.TruncateVoters() {
+			res = append(res, vote)
+			if handler != nil {
+				handler(PollEvent{Poll: pollName, Voter: eventVoterName(vote.Voter), Action: VoteRemovedAction,
+					Reason: fmt.Sprintf("invalid answer %d", vote.Choice)})
+			}
+		}
+	case *MedianPoll:
+		for _, vote := range typedPoll.TruncateVoters() {
+			res = append(res, vote)
+			if handler != nil {
+				handler(PollEvent{Poll: pollName, Voter: eventVoterName(vote.Voter), Action: VoteTruncatedAction,
+					Reason: fmt.Sprintf("value %d is greater than %d", vote.Value, typedPoll.Value)})
+			}
+		}
+	case *SchulzePoll:
+		for _, vote := range typedPoll.TruncateVoters() {
+			res = append(res, vote)
+			if handler != nil {
+				handler(PollEvent{Poll: pollName, Voter: eventVoterName(vote.Voter), Action: VoteRemovedAction,
+					Reason: fmt.Sprintf("ranking has length %d instead of %d", len(vote.Ranking),
+						typedPoll.NumOptions)})
+			}
+		}
+	case *ScorePoll:
+		for _, vote := range typedPoll.TruncateVoters() {
+			res = append(res, vote)
+			if handler != nil {
+				handler(PollEvent{Poll: pollName, Voter: eventVoterName(vote.Voter), Action: VoteRemovedAction,
+					Reason: fmt.Sprintf("invalid scores %v", vote.Scores)})
+			}
+		}
+	default:
+		return nil, NewPollTypeError("can't truncate voters of poll of type %s", reflect.TypeOf(poll))
+	}
+	return res, nil
+}
+
+// reportInvalidVotes calls handler with an InvalidVoteAction event for each vote of poll that is not valid according
+// to VoteValidator. Polls that don't implement VoteValidator and VoteIterator are ignored.
+func reportInvalidVotes(pollName string, poll AbstractPoll, handler PollEventHandler) {
+	validator, isValidator := poll.(VoteValidator)
+	iterator, isIterator := poll.(VoteIterator)
+	if !isValidator || !isIterator {
+		return
+	}
+	// the function never returns an error
+	_ = iterator.ForEachVote(func(vote AbstractVote) error {
+		if validateErr := validator.ValidateVote(vote); validateErr != nil {
+			handler(PollEvent{Poll: pollName, Voter: eventVoterName(vote.GetVoter()), Action: InvalidVoteAction,
+				Reason: validateErr.Error()})
+		}
+		return nil
+	})
+}
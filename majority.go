@@ -15,6 +15,7 @@
 package gopolls
 
 import (
+	"fmt"
 	"math/big"
 )
 
@@ -30,6 +31,8 @@ var (
 // For example consider that there are 10 votes (or sum of weights). Then ComputeMajority(1/2, 10) returns 5,
 // meaning that > 5 (strictly greater!) votes are required.
 // ComputeMajority(2/3, 10) would return 6, meaning that > 6 votes are required.
+//
+// Rules like "at least two thirds" are not strict, use ComputeRequiredVotes or MeetsMajority in this case.
 func ComputeMajority(majority *big.Rat, votesSum Weight) Weight {
 	majorityFraction := big.NewRat(int64(votesSum), 1)
 	// multiply with requiredMajority
@@ -45,6 +48,166 @@ func ComputeMajority(majority *big.Rat, votesSum Weight) Weight {
 	return Weight(asInt)
 }
 
+// ComputeRequiredVotes computes the minimal weight of votes that satisfies the majority rule given the sum of all
+// weights total.
+//
+// If strict is true the votes must be strictly greater than majority * total, otherwise they must be at least
+// majority * total. For example for a total of 10: 1/2 strict returns 6 and 1/2 non-strict ("at least half") returns
+// 5. For a total of 9: 2/3 strict returns 7 and 2/3 non-strict returns 6.
+//
+// The strict rule is the one used by ComputeMajority, ComputeRequiredVotes(m, t, true) is always
+// ComputeMajority(m, t) + 1.
+// If the required weight can't be represented as a Weight (only for a strict rule with majority 1 and a total of
+// NoWeight) NoWeight is returned.
+func ComputeRequiredVotes(majority *big.Rat, total Weight, strict bool) Weight {
+	required := big.NewRat(int64(total), 1)
+	required.Mul(required, majority)
+	num := required.Num()
+	denom := required.Denom()
+	div, mod := new(big.Int), new(big.Int)
+	div.DivMod(num, denom, mod)
+	// strict: floor + 1, non-strict: ceil
+	if strict || mod.Sign() != 0 {
+		div.Add(div, big.NewInt(1))
+	}
+	asInt := div.Int64()
+	if asInt > int64(NoWeight) {
+		return NoWeight
+	}
+	return Weight(asInt)
+}
+
+// MeetsMajority tests if votes satisfy the majority rule given the sum of all weights total, see
+// ComputeRequiredVotes for the meaning of strict.
+//
+// The test is done with exact arithmetic, i.e. votes / total > majority (strict) or votes / total >= majority
+// (non-strict). If total is 0 a non-strict rule is always met and a strict rule never.
+func MeetsMajority(votes, total Weight, majority *big.Rat, strict bool) bool {
+	return meetsMajority(int64(votes), int64(total), majority, strict)
+}
+
+// meetsMajority is MeetsMajority with int64 values, this way sums of weights can be tested without overflows.
+func meetsMajority(votes, total int64, majority *big.Rat, strict bool) bool {
+	// compare votes * denom with total * num
+	lhs := new(big.Int).Mul(big.NewInt(votes), majority.Denom())
+	rhs := new(big.Int).Mul(big.NewInt(total), majority.Num())
+	cmp := lhs.Cmp(rhs)
+	if strict {
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// PassesMajority tests if the ayes satisfy the majority rule, the sum is the weight of all ayes and noes (abstentions
+// and invalid votes are ignored, just as in Outcome).
+//
+// See ComputeRequiredVotes for the meaning of strict, for example 2/3 with strict=false means "at least two thirds of
+// the ayes and noes".
+func (res *BasicPollResult) PassesMajority(majority *big.Rat, strict bool) bool {
+	ayes := int64(res.WeightedVotes.NumAyes)
+	return meetsMajority(ayes, ayes+int64(res.WeightedVotes.NumNoes), majority, strict)
+}
+
+// MajorityBase describes the weight from which a majority is computed (the denominator of the majority rule), bylaws
+// differ in this regard.
+//
+// CastVotes is the weight of all ayes, noes and abstentions, CastVotesExcludingAbstentions the weight of all ayes and
+// noes (this is the base used by PassesMajority) and EligibleWeight the weight of all voters that were allowed to
+// vote, whether they voted or not. Invalid votes are never part of the base.
+type MajorityBase int8
+
+const (
+	CastVotes MajorityBase = iota
+	CastVotesExcludingAbstentions
+	EligibleWeight
+)
+
+func (base MajorityBase) String() string {
+	switch base {
+	case CastVotes:
+		return "cast votes"
+	case CastVotesExcludingAbstentions:
+		return "cast votes excluding abstentions"
+	case EligibleWeight:
+		return "eligible weight"
+	default:
+		return fmt.Sprintf("MajorityBase(%d)", base)
+	}
+}
+
+// majorityDenominator computes the denominator for base given the weight of all ayes and noes (or all votes that
+// didn't abstain), the weight of all abstentions and the eligible weight (only used for EligibleWeight).
+//
+// A PollingSemanticError is returned if base is unknown or if eligible is smaller than the weight of all cast votes.
+func majorityDenominator(base MajorityBase, cast, abstentions, eligible Weight) (Weight, error) {
+	switch base {
+	case CastVotes:
+		return AddWeight(cast, abstentions)
+	case CastVotesExcludingAbstentions:
+		return cast, nil
+	case EligibleWeight:
+		if uint64(eligible) < uint64(cast)+uint64(abstentions) {
+			return NoWeight, NewPollingSemanticError(nil,
+				"eligible weight %d is smaller than the weight of all cast votes (%d)",
+				eligible, uint64(cast)+uint64(abstentions))
+		}
+		return eligible, nil
+	default:
+		return NoWeight, NewPollingSemanticError(nil, "invalid majority base %d", base)
+	}
+}
+
+// MajorityCheck is the result of BasicPollResult.CheckMajority.
+//
+// Base is the base that was used and Denominator the weight computed from it. RequiredWeight is the weight of ayes
+// required to pass (see ComputeRequiredVotes) and AyesWeight the weight of all ayes. Outcome is Passed if
+// AyesWeight >= RequiredWeight and Rejected otherwise, it is never Tied or NoVotes.
+type MajorityCheck struct {
+	Base           MajorityBase
+	Denominator    Weight
+	RequiredWeight Weight
+	AyesWeight     Weight
+	Outcome        BasicPollOutcome
+}
+
+// CheckMajority tests if the ayes satisfy the majority rule with the given base, see ComputeRequiredVotes for the
+// meaning of strict.
+//
+// eligible is the weight of all voters that were allowed to vote, it is only used for EligibleWeight. For example
+// with 4 ayes, 3 noes and 5 abstentions "more than half" (1/2, strict) passes for CastVotesExcludingAbstentions
+// (4 > 3.5) but fails for CastVotes (4 <= 6).
+//
+// A PollingSemanticError is returned if base is unknown or if eligible is smaller than the weight of all ayes, noes
+// and abstentions, an error wrapping ErrWeightOverflow if the denominator overflows. If the result has its Err set
+// this error is returned.
+func (res *BasicPollResult) CheckMajority(majority *big.Rat, strict bool, base MajorityBase,
+	eligible Weight) (MajorityCheck, error) {
+	if res.Err != nil {
+		return MajorityCheck{}, res.Err
+	}
+	counter := res.WeightedVotes
+	cast, castErr := AddWeight(counter.NumAyes, counter.NumNoes)
+	if castErr != nil {
+		return MajorityCheck{}, castErr
+	}
+	denominator, err := majorityDenominator(base, cast, counter.NumAbstention, eligible)
+	if err != nil {
+		return MajorityCheck{}, err
+	}
+	required := ComputeRequiredVotes(majority, denominator, strict)
+	check := MajorityCheck{
+		Base:           base,
+		Denominator:    denominator,
+		RequiredWeight: required,
+		AyesWeight:     counter.NumAyes,
+		Outcome:        Rejected,
+	}
+	if required != NoWeight && counter.NumAyes >= required {
+		check.Outcome = Passed
+	}
+	return check, nil
+}
+
 // ComputePercentage is used to calculate how many percent of the voters (or given their weight)
 // voted for a certain option.
 // To remain as exact as possible we use big.Rat values.
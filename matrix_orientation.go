@@ -0,0 +1,151 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import "fmt"
+
+// MatrixOrientation describes the layout of a PollMatrix.
+//
+// VotersInRows is the layout expected by MatchEntries and FillPollsWithVotes: The head contains the poll names and
+// each row contains the votes of one voter.
+// PollsInRows is the transposed layout: The head contains the voter names and each row contains the votes for one
+// poll.
+// DetectOrientation can be used with PollMatrix.Orient to detect the layout automatically.
+type MatrixOrientation int8
+
+const (
+	VotersInRows MatrixOrientation = iota
+	PollsInRows
+	DetectOrientation
+)
+
+func (orientation MatrixOrientation) String() string {
+	switch orientation {
+	case VotersInRows:
+		return "voters in rows"
+	case PollsInRows:
+		return "polls in rows"
+	case DetectOrientation:
+		return "detect orientation"
+	default:
+		return fmt.Sprintf("MatrixOrientation(%d)", orientation)
+	}
+}
+
+// Transpose returns a new matrix in which rows and columns are swapped, the first entry of the head is retained.
+//
+// A matrix with voters in rows becomes a matrix with polls in rows and vice versa.
+// All rows must have the same length as the head, otherwise a PollingSyntaxError is returned.
+func (m *PollMatrix) Transpose() (*PollMatrix, error) {
+	if len(m.Head) == 0 {
+		return nil, NewPollingSyntaxError(nil, "poll matrix must contain at least one column")
+	}
+	for _, row := range m.Body {
+		if len(row) != len(m.Head) {
+			return nil, NewPollingSyntaxError(nil, "number of columns in csv is invalid, expected length of %d (head), got length %d instead",
+				len(m.Head), len(row))
+		}
+	}
+	head := make([]string, len(m.Body)+1)
+	head[0] = m.Head[0]
+	for i, row := range m.Body {
+		head[i+1] = row[0]
+	}
+	body := make([][]string, len(m.Head)-1)
+	for j := range body {
+		row := make([]string, len(m.Body)+1)
+		row[0] = m.Head[j+1]
+		for i, bodyRow := range m.Body {
+			row[i+1] = bodyRow[j+1]
+		}
+		body[j] = row
+	}
+	return &PollMatrix{
+		Head: head,
+		Body: body,
+	}, nil
+}
+
+// countUnknownNames returns the number of names from the head (without the first column) that are not contained in
+// headNames plus the number of names from the first column of the body that are not contained in rowNames.
+func (m *PollMatrix) countUnknownNames(headNames, rowNames func(name string) bool) int {
+	res := 0
+	if len(m.Head) > 0 {
+		for _, name := range m.Head[1:] {
+			if !headNames(name) {
+				res++
+			}
+		}
+	}
+	for _, row := range m.Body {
+		if len(row) > 0 && !rowNames(row[0]) {
+			res++
+		}
+	}
+	return res
+}
+
+// DetectOrientation tests which orientation matches the names of the voters and polls.
+//
+// The orientation matches if all names in the head (without the first column) and all names in the first column
+// are contained in the corresponding map (for VotersInRows poll names must be in the head and voter names in the
+// first column).
+// If both orientations match (for example in a matrix without names) VotersInRows is returned.
+// If neither orientation matches a PollingSemanticError is returned.
+func (m *PollMatrix) DetectOrientation(voters VoterMap, polls PollMap) (MatrixOrientation, error) {
+	isVoter := func(name string) bool {
+		_, has := voters[name]
+		return has
+	}
+	isPoll := func(name string) bool {
+		_, has := polls[name]
+		return has
+	}
+	unknownVotersInRows := m.countUnknownNames(isPoll, isVoter)
+	if unknownVotersInRows == 0 {
+		return VotersInRows, nil
+	}
+	unknownPollsInRows := m.countUnknownNames(isVoter, isPoll)
+	if unknownPollsInRows == 0 {
+		return PollsInRows, nil
+	}
+	return VotersInRows, NewPollingSemanticError(nil,
+		"matrix matches neither orientation: with voters in rows %d name(s) are unknown, with polls in rows %d name(s) are unknown",
+		unknownVotersInRows, unknownPollsInRows)
+}
+
+// Orient returns a matrix with voters in rows (the layout expected by MatchEntries and FillPollsWithVotes) given the
+// orientation of m.
+//
+// For VotersInRows m itself is returned, for PollsInRows the transposed matrix (see Transpose).
+// For DetectOrientation the orientation is detected first, see PollMatrix.DetectOrientation.
+// An unknown orientation returns a PollingSemanticError.
+func (m *PollMatrix) Orient(orientation MatrixOrientation, voters VoterMap, polls PollMap) (*PollMatrix, error) {
+	if orientation == DetectOrientation {
+		var detectErr error
+		orientation, detectErr = m.DetectOrientation(voters, polls)
+		if detectErr != nil {
+			return nil, detectErr
+		}
+	}
+	switch orientation {
+	case VotersInRows:
+		return m, nil
+	case PollsInRows:
+		return m.Transpose()
+	default:
+		return nil, NewPollingSemanticError(nil, "invalid matrix orientation %s", orientation)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatrixIssueType describes the type of a MatrixIssue.
+type MatrixIssueType int8
+
+const (
+	EmptyHeadIssue MatrixIssueType = iota
+	InvalidRowLengthIssue
+	UnknownVoterIssue
+	DuplicateVoterIssue
+	UnknownPollIssue
+	DuplicatePollIssue
+	EmptyCellIssue
+)
+
+func (t MatrixIssueType) String() string {
+	switch t {
+	case EmptyHeadIssue:
+		return "empty head"
+	case InvalidRowLengthIssue:
+		return "invalid row length"
+	case UnknownVoterIssue:
+		return "unknown voter"
+	case DuplicateVoterIssue:
+		return "duplicate voter"
+	case UnknownPollIssue:
+		return "unknown poll"
+	case DuplicatePollIssue:
+		return "duplicate poll"
+	case EmptyCellIssue:
+		return "empty cell"
+	default:
+		return fmt.Sprintf("MatrixIssueType(%d)", t)
+	}
+}
+
+// MatrixIssue is a single problem found by PollMatrix.Validate.
+//
+// Row is the row in the csv file (the head is row 1), Column the index of the column in the head (the first poll has
+// column 1 because column 0 contains the voter names).
+// Row or Column is -1 if the issue is not related to a specific row / column.
+// Voter and Poll are the names of the voter and poll the issue is about (empty if not related to a voter / poll).
+// NumColumns is only set for InvalidRowLengthIssue and contains the number of columns found in the row.
+type MatrixIssue struct {
+	Type       MatrixIssueType
+	Row        int
+	Column     int
+	Voter      string
+	Poll       string
+	NumColumns int
+}
+
+func (issue MatrixIssue) String() string {
+	switch issue.Type {
+	case EmptyHeadIssue:
+		return "matrix must contain at least one column (voter name)"
+	case InvalidRowLengthIssue:
+		return fmt.Sprintf("row %d has %d columns", issue.Row, issue.NumColumns)
+	case UnknownVoterIssue:
+		return fmt.Sprintf("unknown voter \"%s\" in row %d", issue.Voter, issue.Row)
+	case DuplicateVoterIssue:
+		return fmt.Sprintf("duplicate voter \"%s\" in row %d", issue.Voter, issue.Row)
+	case UnknownPollIssue:
+		return fmt.Sprintf("unknown poll \"%s\" in column %d", issue.Poll, issue.Column)
+	case DuplicatePollIssue:
+		return fmt.Sprintf("duplicate poll \"%s\" in column %d", issue.Poll, issue.Column)
+	case EmptyCellIssue:
+		return fmt.Sprintf("empty vote of voter \"%s\" for poll \"%s\" in row %d, column %d",
+			issue.Voter, issue.Poll, issue.Row, issue.Column)
+	default:
+		return issue.Type.String()
+	}
+}
+
+// MatrixValidationReport contains all issues found by PollMatrix.Validate.
+//
+// The issues are sorted by row: first the issues found in the head, then the issues of each row in the body.
+type MatrixValidationReport struct {
+	Issues []MatrixIssue
+}
+
+// Empty returns true if no issues were found.
+func (report *MatrixValidationReport) Empty() bool {
+	return len(report.Issues) == 0
+}
+
+// IssuesOfType returns all issues with the given type.
+func (report *MatrixValidationReport) IssuesOfType(t MatrixIssueType) []MatrixIssue {
+	var res []MatrixIssue
+	for _, issue := range report.Issues {
+		if issue.Type == t {
+			res = append(res, issue)
+		}
+	}
+	return res
+}
+
+func (report *MatrixValidationReport) add(issue MatrixIssue) {
+	report.Issues = append(report.Issues, issue)
+}
+
+// AsError returns nil if the report is empty and a MatrixValidationError otherwise.
+func (report *MatrixValidationReport) AsError() error {
+	if report.Empty() {
+		return nil
+	}
+	return MatrixValidationError{Report: report}
+}
+
+// MatrixValidationError is the error returned by MatrixValidationReport.AsError, it contains all issues in its
+// message.
+type MatrixValidationError struct {
+	PollError
+	Report *MatrixValidationReport
+}
+
+func (err MatrixValidationError) Error() string {
+	messages := make([]string, len(err.Report.Issues))
+	for i, issue := range err.Report.Issues {
+		messages[i] = issue.String()
+	}
+	return fmt.Sprintf("found %d problem(s) in matrix: %s", len(messages), strings.Join(messages, "; "))
+}
+
+// Validate tests the matrix and returns all problems found, unlike MatchEntries it doesn't stop at the first problem.
+//
+// It reports: an empty head, rows with a wrong number of columns, unknown and duplicate voters and polls
+// (see MatchEntries) and empty cells.
+// Empty cells are only reported for polls with the policy RaiseErrorEmptyVote in policies, policies can be nil in
+// which case no empty cells are reported.
+//
+// The returned report is never nil, use its Empty method to test if there were any issues.
+func (m *PollMatrix) Validate(voters VoterMap, polls PollMap, policies PolicyMap) *MatrixValidationReport {
+	report := &MatrixValidationReport{}
+	if len(m.Head) == 0 {
+		report.add(MatrixIssue{Type: EmptyHeadIssue, Row: 1, Column: -1})
+		return report
+	}
+
+	// polls in the head, maps column to true if empty cells must be reported for that column
+	checkEmpty := make(map[int]bool, len(m.Head)-1)
+	foundPolls := make(map[string]struct{}, len(m.Head)-1)
+	for column := 1; column < len(m.Head); column++ {
+		pollName := m.Head[column]
+		if _, alreadyFound := foundPolls[pollName]; alreadyFound {
+			report.add(MatrixIssue{Type: DuplicatePollIssue, Row: 1, Column: column, Poll: pollName})
+			continue
+		}
+		foundPolls[pollName] = struct{}{}
+		if _, exists := polls[pollName]; !exists {
+			report.add(MatrixIssue{Type: UnknownPollIssue, Row: 1, Column: column, Poll: pollName})
+			continue
+		}
+		if policy, hasPolicy := policies[pollName]; hasPolicy && policy == RaiseErrorEmptyVote {
+			checkEmpty[column] = true
+		}
+	}
+
+	foundVoters := make(map[string]struct{}, len(m.Body))
+	for i, row := range m.Body {
+		// + 2 because the head is row 1
+		rowNum := i + 2
+		if len(row) != len(m.Head) {
+			report.add(MatrixIssue{Type: InvalidRowLengthIssue, Row: rowNum, Column: -1, NumColumns: len(row)})
+		}
+		if len(row) == 0 {
+			continue
+		}
+		voterName := row[0]
+		if _, alreadyFound := foundVoters[voterName]; alreadyFound {
+			report.add(MatrixIssue{Type: DuplicateVoterIssue, Row: rowNum, Column: 0, Voter: voterName})
+		} else if _, exists := voters[voterName]; !exists {
+			report.add(MatrixIssue{Type: UnknownVoterIssue, Row: rowNum, Column: 0, Voter: voterName})
+		}
+		foundVoters[voterName] = struct{}{}
+		for column := 1; column < len(row) && column < len(m.Head); column++ {
+			if checkEmpty[column] && strings.TrimSpace(row[column]) == "" {
+				report.add(MatrixIssue{Type: EmptyCellIssue, Row: rowNum, Column: column, Voter: voterName,
+					Poll: m.Head[column]})
+			}
+		}
+	}
+	return report
+}
@@ -15,7 +15,9 @@
 package gopolls
 
 import (
+	"fmt"
 	"math"
+	"math/big"
 	"reflect"
 	"sort"
 	"strconv"
@@ -45,10 +47,15 @@ func ParseMedianUnit(s string) (MedianUnit, error) {
 // MedianVote is a vote for a MedianPoll.
 //
 // The vote has a voter (weight taken into account) and the Value the voter voted for.
+// If Abstain is true the voter abstained, Value is ignored in this case (and should be 0). Abstentions are not part
+// of the weight sum the majority is computed from, see MedianPoll.Tally.
 // It implements the interface AbstractVote.
+// The source of the vote is stored in the embedded VoteSourceHolder, see SourcedVote.
 type MedianVote struct {
-	Voter *Voter
-	Value MedianUnit
+	Voter   *Voter
+	Value   MedianUnit
+	Abstain bool
+	VoteSourceHolder
 }
 
 // NewMedianVote returns a new median vote given the voter and the value the voter voted for.
@@ -59,6 +66,15 @@ func NewMedianVote(voter *Voter, value MedianUnit) *MedianVote {
 	}
 }
 
+// NewMedianAbstention returns a new median vote for a voter that abstained, the value is 0.
+func NewMedianAbstention(voter *Voter) *MedianVote {
+	return &MedianVote{
+		Voter:   voter,
+		Value:   0,
+		Abstain: true,
+	}
+}
+
 // MedianVoteParser implements VoteParser and returns an instance of MedianVote in its ParseFromString method.
 //
 // It allows a currency value to be parsed.
@@ -66,23 +82,35 @@ func NewMedianVote(voter *Voter, value MedianUnit) *MedianVote {
 //
 // The currency is not directly parsed, instead it uses any CurrencyParser, this way the style of the string
 // can be adapted to your needs.
+// The value of the vote is the ValueCents of the parsed value, thus for values with a higher precision than cents a
+// PreciseCurrencyHandler can be used (the value of the poll must be parsed with the same precision).
 //
 // It also allows to set a maxValue, that is every vote with a value > maxValue will return an error when parsed.
+// The allowed values can be further restricted with a min value and a step size (see WithRange and
+// MedianPoll.MinValue), a vote that doesn't respect them returns a PollingSemanticError.
+//
+// If KeepRaw is true the parsed string is stored as the source of each vote, see VoteSource.
 type MedianVoteParser struct {
 	parser   CurrencyParser
 	maxValue MedianUnit
+	minValue MedianUnit
+	step     MedianUnit
+	KeepRaw  bool
 }
 
 // NewMedianVoteParser returns a new MedianVoteParser given the currency parser.
 //
 // The maxValue is set to NoMedianUnitValue, meaning that it is disabled and doesn't check for a max value.
-// To enable it use WithMaxValue.
+// To enable it use WithMaxValue. The min value and step size are 0 (unrestricted), see WithRange.
 //
 // It also implements ParserCustomizer.
 func NewMedianVoteParser(currencyParser CurrencyParser) *MedianVoteParser {
 	return &MedianVoteParser{
 		parser:   currencyParser,
 		maxValue: NoMedianUnitValue,
+		minValue: 0,
+		step:     0,
+		KeepRaw:  false,
 	}
 }
 
@@ -91,13 +119,29 @@ func (parser *MedianVoteParser) WithMaxValue(maxValue MedianUnit) *MedianVotePar
 	return &MedianVoteParser{
 		parser:   parser.parser,
 		maxValue: maxValue,
+		minValue: parser.minValue,
+		step:     parser.step,
+		KeepRaw:  parser.KeepRaw,
 	}
 }
 
-// CustomizeForPoll implements ParserCustomizer and returns a new parser with maxValue set if a *MedianPoll is given.
+// WithRange returns a shallow copy of the parser with only the min value and step size set to the new values, see
+// MedianPoll.MinValue and MedianPoll.Step for the allowed values. 0 means unrestricted.
+func (parser *MedianVoteParser) WithRange(minValue, step MedianUnit) *MedianVoteParser {
+	return &MedianVoteParser{
+		parser:   parser.parser,
+		maxValue: parser.maxValue,
+		minValue: minValue,
+		step:     step,
+		KeepRaw:  parser.KeepRaw,
+	}
+}
+
+// CustomizeForPoll implements ParserCustomizer and returns a new parser with maxValue, the min value and the step
+// size set if a *MedianPoll is given.
 func (parser *MedianVoteParser) CustomizeForPoll(poll AbstractPoll) (ParserCustomizer, error) {
 	if asMedianPoll, ok := poll.(*MedianPoll); ok {
-		return parser.WithMaxValue(asMedianPoll.Value), nil
+		return parser.WithMaxValue(asMedianPoll.Value).WithRange(asMedianPoll.MinValue, asMedianPoll.Step), nil
 	}
 	return nil, NewPollTypeError("can't customize MedianVoteParser for type %s, expected type *MedianPoll",
 		reflect.TypeOf(poll))
@@ -120,7 +164,56 @@ func (parser *MedianVoteParser) ParseFromString(s string, voter *Voter) (Abstrac
 		return nil, NewPollingSemanticError(nil, "value for median vote (%d) is greatre than allowed max value (%d)",
 			asMedianUnit, parser.maxValue)
 	}
-	return NewMedianVote(voter, asMedianUnit), nil
+	if rangeErr := checkMedianRange(asMedianUnit, parser.minValue, parser.step, parser.maxValue); rangeErr != nil {
+		return nil, rangeErr
+	}
+	return keepRawSource(NewMedianVote(voter, asMedianUnit), s, parser.KeepRaw), nil
+}
+
+// MedianVoteFormatter implements VoteFormatter for a MedianVote.
+//
+// The value is formatted with Formatter (with the currency Currency), if Formatter is nil the value is written as
+// an integer (the value in cents, or the smallest unit). Use the CurrencyHandler that is used by the parser as
+// Formatter, this way the formatted strings are parsed to the same value.
+//
+// An abstention is formatted as an empty string, because the parser can't parse abstentions (an empty entry in a
+// PollMatrix is handled by the EmptyVotePolicy of the poll).
+type MedianVoteFormatter struct {
+	Formatter CurrencyFormatter
+	Currency  string
+}
+
+// NewMedianVoteFormatter returns a new MedianVoteFormatter given the currency formatter, Currency is empty.
+func NewMedianVoteFormatter(formatter CurrencyFormatter) *MedianVoteFormatter {
+	return &MedianVoteFormatter{
+		Formatter: formatter,
+		Currency:  "",
+	}
+}
+
+// FormatVote implements VoteFormatter, vote must be a *MedianVote.
+func (formatter *MedianVoteFormatter) FormatVote(vote AbstractVote) (string, error) {
+	medianVote, ok := vote.(*MedianVote)
+	if !ok {
+		return "", NewPollTypeError("can't format vote of type %s with MedianVoteFormatter, expected type *MedianVote",
+			reflect.TypeOf(vote))
+	}
+	if medianVote.Abstain {
+		return "", nil
+	}
+	if medianVote.Value > MedianUnit(maxInt) {
+		return "", NewPollingSemanticError(nil, "value for median vote (%d) is too big to be formatted", medianVote.Value)
+	}
+	if formatter.Formatter == nil {
+		return strconv.FormatUint(uint64(medianVote.Value), 10), nil
+	}
+	return formatter.Formatter.Format(NewCurrencyValue(int(medianVote.Value), formatter.Currency)), nil
+}
+
+// Equals tests if two votes are equal, i.e. have the same value, are both abstentions or not and have equal voters
+// (see Voter.Equals).
+func (vote *MedianVote) Equals(other *MedianVote) bool {
+	return vote.Value == other.Value && vote.Abstain == other.Abstain && votersEqual(vote.Voter, other.Voter)
 }
 
 // GetVoter returns the voter of the vote.
@@ -139,9 +232,18 @@ func (vote *MedianVote) VoteType() string {
 // The value that "wins" the poll is the highest value that has a majority, taking into account the weight of the
 // voters. See Tally for details.
 //
+// The allowed values can be restricted with MinValue and Step (0 means unrestricted): A vote must be either 0 (which
+// is the same as voting No), poll.Value or a value >= MinValue that is MinValue plus a multiple of Step. For example
+// with MinValue 500 and Step 50 the allowed values are 0, 500, 550, 600 and so on. These restrictions are checked by
+// ValidateVote and MedianVoteParser (see CustomizeForPoll), Tally doesn't check them.
+// If RoundOffStep is true TruncateVoters also rounds each vote >= MinValue that is not a valid step down to the next
+// valid step (see RoundToStep).
+//
 // Note: If a voter voted for a value > poll.Value this value could be chosen as the winner.
 // Because this doesn't make much sense you should take care to "truncate" the votes.
-// You can use TruncateVoters for this.
+// You can use TruncateVoters for this or set TruncateInTally to true: Tally then treats each such value as
+// poll.Value without changing the votes and reports the truncated votes in MedianResult.TruncatedCount and
+// MedianResult.TruncatedWeight.
 //
 // It also has a Sorted attribute which is set to true once the votes are sorted according to value, s.t.
 // the highest votes come first. See SortVotes and AssureSorted for this.
@@ -150,22 +252,84 @@ func (vote *MedianVote) VoteType() string {
 // The Tally method always calls AssureSorted.
 //
 // This type also implements VoteGenerator.
+// Sealed votes are stored in the embedded SealedVoteBuffer, see Reveal.
+// Duplicate votes can be rejected with the embedded DuplicateVoterGuard.
+// Application data can be attached with the embedded PollMetadata.
+// The poll can be closed with the embedded PollCloser, a closed poll rejects new votes.
 type MedianPoll struct {
-	Value  MedianUnit
-	Votes  []*MedianVote
-	Sorted bool
+	Value           MedianUnit
+	MinValue        MedianUnit
+	Step            MedianUnit
+	Votes           []*MedianVote
+	Sorted          bool
+	TruncateInTally bool
+	RoundOffStep    bool
+	SealedVoteBuffer
+	DuplicateVoterGuard
+	PollMetadata
+	PollCloser
 }
 
 // NewMedianPoll returns a new poll given the value in question and the votes for the poll.
-// Note: Read the type documentation carefully! This method will set Sorted to False and will not truncate the voters.
+// Note: Read the type documentation carefully! This method will set Sorted, TruncateInTally and RoundOffStep to False
+// and will not truncate the voters. MinValue and Step are set to 0 (unrestricted).
 func NewMedianPoll(value MedianUnit, votes []*MedianVote) *MedianPoll {
 	return &MedianPoll{
-		Value:  value,
-		Votes:  votes,
-		Sorted: false,
+		Value:           value,
+		MinValue:        0,
+		Step:            0,
+		Votes:           votes,
+		Sorted:          false,
+		TruncateInTally: false,
+		RoundOffStep:    false,
 	}
 }
 
+// DeepClone returns a copy of the poll with a new votes slice and new vote objects, so sorting the clone doesn't
+// change the original.
+// The voter objects are not copied, the votes of the clone point to the same voters as the original.
+func (poll *MedianPoll) DeepClone() *MedianPoll {
+	votes := make([]*MedianVote, len(poll.Votes))
+	for i, vote := range poll.Votes {
+		votes[i] = &MedianVote{Voter: vote.Voter, Value: vote.Value, Abstain: vote.Abstain,
+			VoteSourceHolder: vote.VoteSourceHolder.clone()}
+	}
+	res := NewMedianPoll(poll.Value, votes)
+	res.Sorted = poll.Sorted
+	res.MinValue = poll.MinValue
+	res.Step = poll.Step
+	res.TruncateInTally = poll.TruncateInTally
+	res.RoundOffStep = poll.RoundOffStep
+	res.SealedVoteBuffer = poll.SealedVoteBuffer.clone()
+	res.DuplicateVoterGuard = poll.DuplicateVoterGuard.clone()
+	res.PollMetadata = poll.PollMetadata.clone()
+	res.PollCloser = poll.PollCloser.clone()
+	return res
+}
+
+// AddSealed implements SealedVoteAcceptor and adds a sealed vote to the embedded SealedVoteBuffer.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+func (poll *MedianPoll) AddSealed(vote *SealedVote) error {
+	return poll.SealedVoteBuffer.addSealed(&poll.PollCloser, vote)
+}
+
+// Equals tests if two polls have the same value, min value and step, the same TruncateInTally and RoundOffStep and
+// contain equal votes (in the same order).
+// Sorted is not compared.
+func (poll *MedianPoll) Equals(other *MedianPoll) bool {
+	if poll.Value != other.Value || poll.MinValue != other.MinValue || poll.Step != other.Step ||
+		poll.TruncateInTally != other.TruncateInTally || poll.RoundOffStep != other.RoundOffStep ||
+		len(poll.Votes) != len(other.Votes) {
+		return false
+	}
+	for i, vote := range poll.Votes {
+		if !vote.Equals(other.Votes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // PollType returns the constant MedianPollType.
 func (poll *MedianPoll) PollType() string {
 	return MedianPollType
@@ -173,24 +337,191 @@ func (poll *MedianPoll) PollType() string {
 
 // AddVote adds a vote to the poll, the vote must be of type *MedianVote.
 //
+// If RejectDuplicateVoters is true a DuplicateError is returned if the voter already has a vote, see
+// DuplicateVoterGuard.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+//
 // Note that no vote validation is happening here! I.e. the vote can have an "invalid" value, for example a value that
 // is too large.
 // We do this because in general it is also allowed to append any vote, it is the job of the user of this library
 // to deal with invalid votes.
+// Adding a vote sets Sorted to false.
 func (poll *MedianPoll) AddVote(vote AbstractVote) error {
 	asMedianVote, ok := vote.(*MedianVote)
 	if !ok {
 		return NewPollTypeError("can't add vote to MedianPoll, vote must be of type *MedianVote, got type %s",
 			reflect.TypeOf(vote))
 	}
+	if closedErr := poll.checkOpenForVoter("add vote", asMedianVote.Voter); closedErr != nil {
+		return closedErr
+	}
+	if duplicateErr := poll.checkNewVoter(asMedianVote.Voter, len(poll.Votes), poll.forEachVoter); duplicateErr != nil {
+		return duplicateErr
+	}
 	poll.Votes = append(poll.Votes, asMedianVote)
+	poll.Sorted = false
+	return nil
+}
+
+// ForEachVote implements VoteIterator, it calls f for each vote in the order in which they appear in the poll.
+func (poll *MedianPoll) ForEachVote(f func(vote AbstractVote) error) error {
+	return forEachVote(poll, f)
+}
+
+// NumVotes implements VoteIterator and returns the number of votes in the poll.
+func (poll *MedianPoll) NumVotes() int {
+	return len(poll.Votes)
+}
+
+// voteAt returns the vote with index i, it implements indexedVotes.
+func (poll *MedianPoll) voteAt(i int) AbstractVote {
+	return poll.Votes[i]
+}
+
+// forEachVoter calls f with the voter of each vote, used by DuplicateVoterGuard.
+func (poll *MedianPoll) forEachVoter(f func(voter *Voter)) {
+	for _, vote := range poll.Votes {
+		f(vote.Voter)
+	}
+}
+
+// HasVoteFrom implements VoteEditor and returns true if the voter with the given name has a vote in the poll.
+func (poll *MedianPoll) HasVoteFrom(voterName string) bool {
+	return voteIndex(poll, voterName) >= 0
+}
+
+// RemoveVote implements VoteEditor and removes the vote of the voter with the given name, the removed vote is
+// returned.
+//
+// If the voter has multiple votes only the first one is removed. The order of the remaining votes is retained.
+// If the voter has no vote a VoteNotFoundError is returned.
+func (poll *MedianPoll) RemoveVote(voterName string) (AbstractVote, error) {
+	if closedErr := poll.checkOpen("remove vote", voterName); closedErr != nil {
+		return nil, closedErr
+	}
+	index := voteIndex(poll, voterName)
+	if index < 0 {
+		return nil, NewVoteNotFoundError(voterName)
+	}
+	res := poll.Votes[index]
+	poll.Votes = append(poll.Votes[:index], poll.Votes[index+1:]...)
+	poll.ResetVoterIndex()
+	return res, nil
+}
+
+// ReplaceVote implements VoteEditor, the vote must be of type *MedianVote.
+//
+// The vote replaces the first vote of its voter (at the same position), all other votes of the voter are removed.
+// If the voter has no vote yet the vote is added.
+// Sorted is set to false because the value of the new vote can be different.
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if the vote has no voter.
+func (poll *MedianPoll) ReplaceVote(vote AbstractVote) error {
+	asMedianVote, ok := vote.(*MedianVote)
+	if !ok {
+		return NewPollTypeError("can't replace vote in MedianPoll, vote must be of type *MedianVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asMedianVote.Voter == nil {
+		return NewPollingSemanticError(nil, "can't replace vote in MedianPoll, vote has no voter")
+	}
+	name := asMedianVote.Voter.Name
+	if closedErr := poll.checkOpen("replace vote", name); closedErr != nil {
+		return closedErr
+	}
+	index := voteIndex(poll, name)
+	if index < 0 {
+		return poll.AddVote(vote)
+	}
+	poll.Votes[index] = asMedianVote
+	// remove all other votes of the voter
+	filtered := poll.Votes[:index+1]
+	for _, other := range poll.Votes[index+1:] {
+		if other.Voter == nil || other.Voter.Name != name {
+			filtered = append(filtered, other)
+		}
+	}
+	poll.Votes = filtered
+	poll.ResetVoterIndex()
+	poll.Sorted = false
 	return nil
 }
 
+// ValidateVote tests if vote can be added to the poll, it must be of type *MedianVote with a voter and a value
+// <= poll.Value (larger values would be truncated by TruncateVoters) that respects MinValue and Step (see AllowsValue).
+// The value of an abstention is not checked.
+//
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if it is invalid.
+func (poll *MedianPoll) ValidateVote(vote AbstractVote) error {
+	asMedianVote, ok := vote.(*MedianVote)
+	if !ok {
+		return NewPollTypeError("invalid vote for MedianPoll, vote must be of type *MedianVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asMedianVote.Voter == nil {
+		return NewPollingSemanticError(nil, "vote for MedianPoll has no voter")
+	}
+	if !asMedianVote.Abstain && asMedianVote.Value > poll.Value {
+		return NewPollingSemanticError(nil, "value %d of voter \"%s\" is greater than the max value %d of the poll",
+			asMedianVote.Value, asMedianVote.Voter.Name, poll.Value)
+	}
+	if !asMedianVote.Abstain && !poll.AllowsValue(asMedianVote.Value) {
+		return NewPollingSemanticError(nil, "value %d of voter \"%s\" is not allowed, allowed values are 0 and %s",
+			asMedianVote.Value, asMedianVote.Voter.Name, describeMedianRange(poll.MinValue, poll.Step))
+	}
+	return nil
+}
+
+// AllowsValue returns true if value respects MinValue and Step, see type documentation.
+// 0 and poll.Value are always allowed, values > poll.Value are not checked.
+func (poll *MedianPoll) AllowsValue(value MedianUnit) bool {
+	return isValidMedianValue(value, poll.MinValue, poll.Step, poll.Value)
+}
+
+// RoundToStep returns the largest valid step <= value, i.e. MinValue plus a multiple of Step.
+// If Step is 0 or value < MinValue value is returned unchanged.
+func (poll *MedianPoll) RoundToStep(value MedianUnit) MedianUnit {
+	if poll.Step == 0 || value < poll.MinValue {
+		return value
+	}
+	return poll.MinValue + (value-poll.MinValue)/poll.Step*poll.Step
+}
+
+// isValidMedianValue returns true if value is 0, maxValue or a value >= minValue that is minValue plus a multiple of
+// step. maxValue can be NoMedianUnitValue.
+func isValidMedianValue(value, minValue, step, maxValue MedianUnit) bool {
+	if value == 0 || value == maxValue {
+		return true
+	}
+	return value >= minValue && (step == 0 || (value-minValue)%step == 0)
+}
+
+// describeMedianRange returns a description of the values allowed by minValue and step (without 0), for example
+// "values >= 500 in steps of 50 (500, 550, ...)".
+func describeMedianRange(minValue, step MedianUnit) string {
+	switch {
+	case step == 0:
+		return fmt.Sprintf("values >= %d", minValue)
+	case minValue == 0:
+		return fmt.Sprintf("multiples of %d", step)
+	default:
+		return fmt.Sprintf("values >= %d in steps of %d (%d, %d, ...)", minValue, step, minValue, minValue+step)
+	}
+}
+
+// checkMedianRange returns a PollingSemanticError explaining the allowed values if value is not valid, see
+// isValidMedianValue.
+func checkMedianRange(value, minValue, step, maxValue MedianUnit) error {
+	if isValidMedianValue(value, minValue, step, maxValue) {
+		return nil
+	}
+	return NewPollingSemanticError(nil, "value %d is not allowed, allowed values are 0 and %s",
+		value, describeMedianRange(minValue, step))
+}
+
 // GenerateVoteFromBasicAnswer implements VoteGenerator and returns a MedianVote.
 //
-// Abstention is not an allowed value here!
-// It will return a vote for 0 for No, a vote for poll.Value for Yes.
+// It will return a vote for 0 for No, a vote for poll.Value for Yes and an abstention (see NewMedianAbstention) for
+// Abstention.
 func (poll *MedianPoll) GenerateVoteFromBasicAnswer(voter *Voter, answer BasicPollAnswer) (AbstractVote, error) {
 	switch answer {
 	case No:
@@ -198,36 +529,70 @@ func (poll *MedianPoll) GenerateVoteFromBasicAnswer(voter *Voter, answer BasicPo
 	case Aye:
 		return NewMedianVote(voter, poll.Value), nil
 	case Abstention:
-		return nil, NewPollTypeError("abstention is not supported for median polls")
+		return NewMedianAbstention(voter), nil
 	default:
 		return nil, NewPollTypeError("invalid poll answer %d", answer)
 	}
 }
 
-// TruncateVoters identifies all votes that contain a value > poll.Value.
+// TruncateVoters identifies all votes that contain a value > poll.Value, abstentions are ignored.
 //
 // It could lead to "weird" results if the value the voters agreed upon was > poll.Value.
 // This way the poll gets filtered by updating the value of such a vote to poll.Value.
-// The result returned contains the original entries with a value > poll.Value (for logging purposes).
+// If RoundOffStep is true votes with a value >= MinValue that is not a valid step are rounded down to the next valid
+// step (see RoundToStep), votes with a value < MinValue are not changed (see ValidateVote to find them).
+// The result returned contains the original entries that were changed (for logging purposes).
 // The returned result contains shallow copies of the culprit (i.e. Value is copied and the *Voter object is re-used).
 //
 // Note: If you use this method the sorting order should be maintained, everyone who voted with a value > poll.Value
 // should be at the beginning of the slice and are now set to poll.Value. Because all other votes have a value <=
-// poll.Value this should be fine.
+// poll.Value this should be fine. Rounding down to a step maintains the order as well.
 // Thus if the votes are already sorted they should be sorted afterwards too.
 func (poll *MedianPoll) TruncateVoters() []*MedianVote {
 	culprits := make([]*MedianVote, 0)
 	for _, vote := range poll.Votes {
-		if vote.Value > poll.Value {
-			// voted for a too big value ==> truncate to poll.Value and add to "culprit" list
+		if vote.Abstain {
+			continue
+		}
+		newValue := vote.Value
+		switch {
+		case vote.Value > poll.Value:
+			// voted for a too big value ==> truncate to poll.Value
+			newValue = poll.Value
+		case poll.RoundOffStep && !poll.AllowsValue(vote.Value):
+			newValue = poll.RoundToStep(vote.Value)
+		}
+		if newValue != vote.Value {
+			// add original value to "culprit" list
 			culprit := NewMedianVote(vote.Voter, vote.Value)
 			culprits = append(culprits, culprit)
-			vote.Value = poll.Value
+			vote.Value = newValue
 		}
 	}
 	return culprits
 }
 
+// SetValue sets the value of the poll to newValue.
+//
+// If a vote (that is not an abstention) has a value greater than newValue and truncate is false a
+// PollingSemanticError is returned and the poll is not changed. If truncate is true these votes are truncated to
+// newValue (see TruncateVoters), thus the poll has the same result as a poll with value newValue to which the
+// truncated votes were added. The sorting order is maintained.
+func (poll *MedianPoll) SetValue(newValue MedianUnit, truncate bool) error {
+	if !truncate {
+		for _, vote := range poll.Votes {
+			if !vote.Abstain && vote.Value > newValue {
+				return NewPollingSemanticError(nil,
+					"can't set value of poll to %d, voter \"%s\" voted for a greater value (%d)",
+					newValue, vote.Voter.Name, vote.Value)
+			}
+		}
+	}
+	poll.Value = newValue
+	poll.TruncateVoters()
+	return nil
+}
+
 // SortVotes sorts the votes list in-place according to vote.Value (highest votes first).
 func (poll *MedianPoll) SortVotes() {
 	// sort votes according to value
@@ -246,43 +611,114 @@ func (poll *MedianPoll) AssureSorted() {
 	}
 }
 
-// WeightSum returns the sum of all voters weights.
+// WeightSum returns the sum of all voters weights, abstentions are not included (see AbstentionWeight).
+//
+// The sum might overflow, use CheckedWeightSum to detect this.
 func (poll *MedianPoll) WeightSum() Weight {
 	var sum Weight
 	for _, vote := range poll.Votes {
-		sum += vote.Voter.Weight
+		if !vote.Abstain {
+			sum += vote.Voter.EffectiveWeight()
+		}
 	}
 	return sum
 }
 
+// CheckedWeightSum works like WeightSum but returns an error wrapping ErrWeightOverflow if the sum overflows.
+func (poll *MedianPoll) CheckedWeightSum() (Weight, error) {
+	return poll.checkedSum(false)
+}
+
+// AbstentionWeight returns the sum of the weights of all voters that abstained, if the sum overflows an error
+// wrapping ErrWeightOverflow is returned.
+func (poll *MedianPoll) AbstentionWeight() (Weight, error) {
+	return poll.checkedSum(true)
+}
+
+// checkedSum returns the sum of the effective weights of all votes with vote.Abstain == abstentions.
+func (poll *MedianPoll) checkedSum(abstentions bool) (Weight, error) {
+	return checkedWeightSum(poll, func(vote AbstractVote) bool {
+		return vote.(*MedianVote).Abstain == abstentions
+	})
+}
+
 // MedianResult is the result of evaluating a median poll, see Tally method.
 //
 // The result contains the following information:
-// WeightSum is the sum of all weights from the votes.
+// WeightSum is the sum of all weights from the votes, AbstentionWeight the sum of the weights of all voters that
+// abstained. Abstentions are not included in WeightSum and ValueDetails, thus they don't count for the majority.
 // RequiredMajority is the majority that was required for the winning value.
 // MajorityValue is the highest value that had the RequiredMajority.
 // ValueDetails maps all values that occurred in at least one vote and maps it to the voters that voted for this value.
 // This map can be further analyzed with GetVotersForValue.
+// TruncatedCount is the number of votes with a value > poll.Value that were counted as poll.Value and
+// TruncatedWeight the sum of their weights, both are only set if MedianPoll.TruncateInTally is true.
+// NextHigherValue is the smallest value > MajorityValue that was voted for and NextHigherWeight the weight of all
+// voters that voted for a value >= NextHigherValue, this is how close the next-higher value was to winning.
+// If MajorityValue is the highest value voted for (or there is no majority) NextHigherValue is NoMedianUnitValue and
+// NextHigherWeight is NoWeight.
+// MarginalVoter is the voter whose vote pushed the weight over RequiredMajority, nil if no majority was found. If
+// several voters voted for MajorityValue it is the first one (in the order of the sorted votes) that was required.
+// Err is nil unless the weights of the votes overflow, in this case it wraps ErrWeightOverflow and the result is
+// empty (as returned by NewMedianResult).
 type MedianResult struct {
 	WeightSum        Weight
+	AbstentionWeight Weight
 	RequiredMajority Weight
 	MajorityValue    MedianUnit
 	ValueDetails     map[MedianUnit][]*Voter
+	TruncatedCount   int
+	TruncatedWeight  Weight
+	NextHigherValue  MedianUnit
+	NextHigherWeight Weight
+	MarginalVoter    *Voter
+	Err              error
 }
 
 // NewMedianResult returns a new MedianResult.
 //
-// The returned instance has WeightSum and RequiredMajority set to NoWeight, MajorityValue set to NoMedianUnitValue
-// and ValueDetails to an empty map.
+// The returned instance has WeightSum, AbstentionWeight, RequiredMajority and NextHigherWeight set to NoWeight,
+// MajorityValue and NextHigherValue set to NoMedianUnitValue and ValueDetails to an empty map.
 func NewMedianResult() *MedianResult {
 	return &MedianResult{
 		WeightSum:        NoWeight,
+		AbstentionWeight: NoWeight,
 		RequiredMajority: NoWeight,
 		MajorityValue:    NoMedianUnitValue,
 		ValueDetails:     make(map[MedianUnit][]*Voter),
+		NextHigherValue:  NoMedianUnitValue,
+		NextHigherWeight: NoWeight,
 	}
 }
 
+// Equals tests if two results store the same state, the voters in ValueDetails are compared with Voter.Equals.
+func (result *MedianResult) Equals(other *MedianResult) bool {
+	if result.WeightSum != other.WeightSum ||
+		result.AbstentionWeight != other.AbstentionWeight ||
+		result.RequiredMajority != other.RequiredMajority ||
+		result.MajorityValue != other.MajorityValue ||
+		result.TruncatedCount != other.TruncatedCount ||
+		result.TruncatedWeight != other.TruncatedWeight ||
+		result.NextHigherValue != other.NextHigherValue ||
+		result.NextHigherWeight != other.NextHigherWeight ||
+		!votersEqual(result.MarginalVoter, other.MarginalVoter) ||
+		len(result.ValueDetails) != len(other.ValueDetails) {
+		return false
+	}
+	for value, voters := range result.ValueDetails {
+		otherVoters, has := other.ValueDetails[value]
+		if !has || len(voters) != len(otherVoters) {
+			return false
+		}
+		for i, voter := range voters {
+			if !votersEqual(voter, otherVoters[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // addDetail adds a voter to the list of voters for the given value.
 func (result *MedianResult) addDetail(value MedianUnit, voter *Voter) {
 	votersList, has := result.ValueDetails[value]
@@ -309,6 +745,77 @@ func (result *MedianResult) GetVotersForValue(referenceValue MedianUnit) []*Vote
 	return res
 }
 
+// MedianDistributionEntry is an entry in the distribution of a median poll, see MedianResult.Distribution.
+//
+// CumulativeWeight is the sum of the weights of all voters that voted for a value >= Value.
+// Percentage is CumulativeWeight divided by the sum of all weights (see ComputePercentage).
+type MedianDistributionEntry struct {
+	Value            MedianUnit
+	CumulativeWeight Weight
+	Percentage       *big.Rat
+}
+
+// sortedValues returns all values from ValueDetails sorted descending.
+func (result *MedianResult) sortedValues() []MedianUnit {
+	values := make([]MedianUnit, 0, len(result.ValueDetails))
+	for value := range result.ValueDetails {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] > values[j]
+	})
+	return values
+}
+
+// Distribution returns the cumulative distribution of the votes.
+//
+// The result contains one entry for each distinct value that was voted for, sorted descending by value.
+// Each entry contains the weight of all voters that support at least this value and the percentage of the total
+// weight.
+// For a poll without votes an empty list is returned.
+// Note that if all votes are for 0 the result contains only one entry for 0.
+func (result *MedianResult) Distribution() []MedianDistributionEntry {
+	values := result.sortedValues()
+	res := make([]MedianDistributionEntry, len(values))
+	var cumulative Weight
+	for i, value := range values {
+		for _, voter := range result.ValueDetails[value] {
+			cumulative += voter.EffectiveWeight()
+		}
+		res[i] = MedianDistributionEntry{
+			Value:            value,
+			CumulativeWeight: cumulative,
+		}
+	}
+	// cumulative is now the sum of all weights, so we can compute the percentages
+	for i := range res {
+		res[i].Percentage = ComputePercentage(res[i].CumulativeWeight, cumulative)
+	}
+	return res
+}
+
+// ValueForMajority returns the highest value that has a weight > (strictly!) majority, i.e. the value that would
+// have won if Tally was called with this majority.
+// This way a result can be evaluated for different majorities without tallying the poll again.
+//
+// If majority is NoWeight it defaults to the sum of all voter weights divided by two, just as in Tally.
+// If no value has the required majority NoMedianUnitValue is returned.
+func (result *MedianResult) ValueForMajority(majority Weight) MedianUnit {
+	if majority == NoWeight {
+		majority = ComputeMajority(FiftyPercentMajority, result.WeightSum)
+	}
+	var cumulative Weight
+	for _, value := range result.sortedValues() {
+		for _, voter := range result.ValueDetails[value] {
+			cumulative += voter.EffectiveWeight()
+		}
+		if cumulative > majority {
+			return value
+		}
+	}
+	return NoMedianUnitValue
+}
+
 // Tally computes the result of a median poll.
 //
 // Majority can be set to the majority that the result requires. It defaults to the sum of all voter weights divided
@@ -324,16 +831,35 @@ func (result *MedianResult) GetVotersForValue(referenceValue MedianUnit) []*Vote
 // If there are no voters or majority is incorrect (for example > total weight sum) MajorityValue might be set to
 // NoMedianUnitValue.
 //
+// Votes with Abstain set are not considered for the majority, their weight is only stored in AbstentionWeight.
+// Thus the default majority is computed from the weights of all voters that didn't abstain.
+//
+// If the weights of the votes overflow (see CheckedWeightSum) the returned result is empty and has its Err set.
+//
+// If TruncateInTally is true values > poll.Value are counted as poll.Value (also in ValueDetails), the votes are not
+// changed. The number and weight of these votes are stored in TruncatedCount and TruncatedWeight.
+//
 // This method will also make sure that the polls are sorted (AssureSorted).
 // The runtime of this method is (for n = number of voters) O(n) if already sorted and O(n * log n) if not sorted.
 func (poll *MedianPoll) Tally(majority Weight) *MedianResult {
 	poll.AssureSorted()
-	weightSum := poll.WeightSum()
+	res := NewMedianResult()
+	// the cumulative weights are bounded by the weight sum, so it is sufficient to check the sum for overflows
+	weightSum, sumErr := poll.CheckedWeightSum()
+	if sumErr != nil {
+		res.Err = sumErr
+		return res
+	}
+	abstentionWeight, abstentionErr := poll.AbstentionWeight()
+	if abstentionErr != nil {
+		res.Err = abstentionErr
+		return res
+	}
+	res.AbstentionWeight = abstentionWeight
 
 	if majority == NoWeight {
 		majority = ComputeMajority(FiftyPercentMajority, weightSum)
 	}
-	res := NewMedianResult()
 	res.WeightSum = weightSum
 	res.RequiredMajority = majority
 
@@ -343,19 +869,97 @@ func (poll *MedianPoll) Tally(majority Weight) *MedianResult {
 	var currentWeight Weight
 	// foundMajority is set to true once a majority has been found
 	foundMajority := false
+	// currentValue is the value of the previous vote (if hasValue is true), previousValue is the distinct value
+	// before currentValue and weightAbove the weight of all votes for a value >= previousValue, they are used to
+	// compute NextHigherValue and NextHigherWeight
+	hasValue := false
+	var currentValue MedianUnit
+	previousValue, weightAbove := NoMedianUnitValue, NoWeight
 
 	for _, vote := range poll.Votes {
+		if vote.Abstain {
+			continue
+		}
+		value := vote.Value
+		// truncating keeps the order of the sorted votes because all other values are <= poll.Value
+		if poll.TruncateInTally && value > poll.Value {
+			value = poll.Value
+			res.TruncatedCount++
+			res.TruncatedWeight += vote.Voter.EffectiveWeight()
+		}
 		// append to details
-		res.addDetail(vote.Value, vote.Voter)
+		res.addDetail(value, vote.Voter)
+		// a new distinct value starts, the value before is the next higher one
+		if !hasValue || value != currentValue {
+			if hasValue {
+				previousValue, weightAbove = currentValue, currentWeight
+			}
+			hasValue = true
+			currentValue = value
+		}
 		// update weight sum
-		currentWeight += vote.Voter.Weight
+		currentWeight += vote.Voter.EffectiveWeight()
 		// if no majority has been found yet also update the sum and set result variable
 		if !foundMajority && currentWeight > majority {
 			// found a majority value! set in result and update foundMajority
-			res.MajorityValue = vote.Value
+			res.MajorityValue = value
+			res.MarginalVoter = vote.Voter
+			res.NextHigherValue, res.NextHigherWeight = previousValue, weightAbove
 			foundMajority = true
 		}
 	}
 
 	return res
 }
+
+// TallyWithMajority computes the result of a median poll given the majority as a rational, for example 1/2 or 2/3.
+//
+// The required weight is computed with ComputeRequiredVotes from the sum of all weights (abstentions excluded, see
+// Tally), strict describes if the rule is "more than" (strict) or "at least" (non-strict). For example for 9 voters
+// with weight one and 2/3 a value needs at least 7 votes if strict is true and at least 6 votes otherwise.
+//
+// RequiredMajority of the result is set as in Tally, i.e. the winning value has a weight > RequiredMajority.
+// A rule that doesn't require any weight (a majority of 0 that is not strict) is handled like a majority of 0 in
+// Tally.
+//
+// It is the same as TallyWithBase with CastVotesExcludingAbstentions.
+func (poll *MedianPoll) TallyWithMajority(majority *big.Rat, strict bool) *MedianResult {
+	return poll.TallyWithBase(majority, strict, CastVotesExcludingAbstentions, NoWeight)
+}
+
+// TallyWithBase works as TallyWithMajority but computes the required weight from the given base.
+//
+// For CastVotesExcludingAbstentions the base is the sum of all weights that didn't abstain, for CastVotes the
+// weight of the abstentions is included and for EligibleWeight the base is eligible (the weight of all voters that
+// were allowed to vote). Abstentions never count for a value, so a larger base only increases RequiredMajority.
+// WeightSum of the result is always the sum of the weights that didn't abstain.
+//
+// If base is unknown or eligible is smaller than the weight of all votes (abstentions included) the returned result
+// is empty and has its Err set to a PollingSemanticError.
+func (poll *MedianPoll) TallyWithBase(majority *big.Rat, strict bool, base MajorityBase,
+	eligible Weight) *MedianResult {
+	weightSum, sumErr := poll.CheckedWeightSum()
+	if sumErr != nil {
+		res := NewMedianResult()
+		res.Err = sumErr
+		return res
+	}
+	abstentionWeight, abstentionErr := poll.AbstentionWeight()
+	if abstentionErr != nil {
+		res := NewMedianResult()
+		res.Err = abstentionErr
+		return res
+	}
+	denominator, baseErr := majorityDenominator(base, weightSum, abstentionWeight, eligible)
+	if baseErr != nil {
+		res := NewMedianResult()
+		res.Err = baseErr
+		return res
+	}
+	// Tally requires a weight > threshold, so subtract one from the minimal weight
+	var threshold Weight
+	if required := ComputeRequiredVotes(majority, denominator, strict); required > 0 {
+		threshold = required - 1
+	}
+	return poll.Tally(threshold)
+}
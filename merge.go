@@ -0,0 +1,266 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MergePolls appends the votes of src to dst, for example to combine votes collected in two batches (like an
+// in-person and an online csv file).
+//
+// Both polls must have the same type, otherwise a PollTypeError is returned. Polls of this package must also be
+// compatible, if not a PollingSemanticError is returned: BasicPolls are always compatible, MedianPolls must have the
+// same Value, MinValue and Step, SchulzePolls the same NumOptions and ScorePolls the same NumOptions and MaxScore.
+// Polls of other types must implement VoteIterator and VoteEditor.
+//
+// onDuplicate describes what happens if src contains a vote of a voter (compared by name) that already voted in dst:
+// RejectDuplicateVoters returns a DuplicateError, KeepFirstDuplicateVoter keeps the vote in dst and
+// KeepLastDuplicateVoter replaces it with the vote from src (see VoteEditor.ReplaceVote). NoDuplicateVoterCheck
+// appends all votes without checking, MergeDuplicateVoters is not allowed (votes can't be merged) and returns a
+// PollingSemanticError. Duplicates within src are handled in the same way. All checks are done before dst is
+// changed, thus if an error is returned dst is unchanged (unless AddVote fails, for example because dst is closed).
+//
+// The votes are not copied, afterwards dst and src share the vote objects (use DeepClone on src if required).
+// Adding a vote to a MedianPoll sets Sorted to false.
+//
+// Sealed votes are not merged: If src still has sealed votes (see SealedVoteAcceptor) a PollingSemanticError is
+// returned, use Reveal on src first. Merging a poll into itself returns a PollingSemanticError too.
+//
+// A SynchronizedPoll can be used as dst or src, the polls are merged while holding their locks. If both polls are
+// synchronized the locks are always acquired in the same order (by the address of the SynchronizedPoll), thus
+// merging A into B and B into A concurrently doesn't deadlock.
+func MergePolls(dst, src AbstractPoll, onDuplicate DuplicateVoterPolicy) error {
+	if sameMergePoll(unwrapSynchronized(dst), unwrapSynchronized(src)) {
+		return NewPollingSemanticError(nil, "can't merge a poll into itself")
+	}
+	syncDst, isSyncDst := dst.(SynchronizedPoll)
+	syncSrc, isSyncSrc := src.(SynchronizedPoll)
+	switch {
+	case isSyncDst && isSyncSrc:
+		if mergeLockAddress(syncSrc) < mergeLockAddress(syncDst) {
+			return syncSrc.WithLock(func(wrappedSrc AbstractPoll) error {
+				return syncDst.WithLock(func(wrappedDst AbstractPoll) error {
+					return mergePolls(wrappedDst, wrappedSrc, onDuplicate)
+				})
+			})
+		}
+		return syncDst.WithLock(func(wrappedDst AbstractPoll) error {
+			return syncSrc.WithLock(func(wrappedSrc AbstractPoll) error {
+				return mergePolls(wrappedDst, wrappedSrc, onDuplicate)
+			})
+		})
+	case isSyncDst:
+		return syncDst.WithLock(func(wrapped AbstractPoll) error {
+			return mergePolls(wrapped, src, onDuplicate)
+		})
+	case isSyncSrc:
+		return syncSrc.WithLock(func(wrapped AbstractPoll) error {
+			return mergePolls(dst, wrapped, onDuplicate)
+		})
+	default:
+		return mergePolls(dst, src, onDuplicate)
+	}
+}
+
+// mergePolls implements MergePolls for polls that are not synchronized (or already locked).
+func mergePolls(dst, src AbstractPoll, onDuplicate DuplicateVoterPolicy) error {
+	if policyErr := checkMergePolicy(onDuplicate); policyErr != nil {
+		return policyErr
+	}
+	if compatibleErr := checkPollsMergeable(dst, src); compatibleErr != nil {
+		return compatibleErr
+	}
+	if sealedErr := checkNoSealedVotes(src); sealedErr != nil {
+		return sealedErr
+	}
+	editor := dst.(VoteEditor)
+	votes, votesErr := CollectVotes(src)
+	if votesErr != nil {
+		return votesErr
+	}
+	dstVotes, dstVotesErr := CollectVotes(dst)
+	if dstVotesErr != nil {
+		return dstVotesErr
+	}
+	names := make(map[string]struct{}, len(dstVotes)+len(votes))
+	for _, vote := range dstVotes {
+		if voter := vote.GetVoter(); voter != nil {
+			names[voter.Name] = struct{}{}
+		}
+	}
+	if onDuplicate == RejectDuplicateVoters {
+		if name, hasDuplicate := findMergeDuplicate(names, votes); hasDuplicate {
+			return NewDuplicateError(fmt.Sprintf("duplicate vote for voter %s", name))
+		}
+	}
+	for _, vote := range votes {
+		voter := vote.GetVoter()
+		if voter == nil || onDuplicate == NoDuplicateVoterCheck {
+			if addErr := dst.AddVote(vote); addErr != nil {
+				return addErr
+			}
+			continue
+		}
+		_, isDuplicate := names[voter.Name]
+		switch {
+		case !isDuplicate:
+			if addErr := dst.AddVote(vote); addErr != nil {
+				return addErr
+			}
+			names[voter.Name] = struct{}{}
+		case onDuplicate == KeepLastDuplicateVoter:
+			if replaceErr := editor.ReplaceVote(vote); replaceErr != nil {
+				return replaceErr
+			}
+		}
+	}
+	return nil
+}
+
+// MergePollMaps merges each poll in src into the poll with the same name in dst, see MergePolls.
+//
+// If a poll in src has no poll with the same name in dst a PollingSemanticError is returned, polls that only exist
+// in dst are not changed. The policy, the types and the sealed votes of all polls are checked before any poll is
+// changed, errors from MergePolls contain the name of the poll. The polls are merged in the order of their names, if
+// merging a poll fails the polls before it are already merged.
+func MergePollMaps(dst, src PollMap, onDuplicate DuplicateVoterPolicy) error {
+	if policyErr := checkMergePolicy(onDuplicate); policyErr != nil {
+		return policyErr
+	}
+	names := src.SortedNames()
+	for _, name := range names {
+		dstPoll, has := dst[name]
+		if !has {
+			return NewPollingSemanticError(nil, "can't merge poll \"%s\", there is no poll with this name", name)
+		}
+		compatibleErr := checkPollsMergeable(unwrapSynchronized(dstPoll), unwrapSynchronized(src[name]))
+		if compatibleErr != nil {
+			return fmt.Errorf("can't merge poll \"%s\": %w", name, compatibleErr)
+		}
+		if sealedErr := checkNoSealedVotes(unwrapSynchronized(src[name])); sealedErr != nil {
+			return fmt.Errorf("can't merge poll \"%s\": %w", name, sealedErr)
+		}
+	}
+	for _, name := range names {
+		if mergeErr := MergePolls(dst[name], src[name], onDuplicate); mergeErr != nil {
+			return fmt.Errorf("can't merge poll \"%s\": %w", name, mergeErr)
+		}
+	}
+	return nil
+}
+
+// checkMergePolicy returns a PollingSemanticError if policy can't be used in MergePolls.
+func checkMergePolicy(policy DuplicateVoterPolicy) error {
+	switch policy {
+	case NoDuplicateVoterCheck, RejectDuplicateVoters, KeepFirstDuplicateVoter, KeepLastDuplicateVoter:
+		return nil
+	case MergeDuplicateVoters:
+		return NewPollingSemanticError(nil, "votes can't be merged, MergeDuplicateVoters is not allowed")
+	default:
+		return NewPollingSemanticError(nil, "invalid duplicate voter policy %d", policy)
+	}
+}
+
+// sameMergePoll returns true if a and b are the same poll (polls with a type that can't be compared are never equal).
+func sameMergePoll(a, b AbstractPoll) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// mergeLockAddress returns the address of poll, it is used to lock two synchronized polls in a fixed order.
+// It returns 0 if poll is not a pointer.
+func mergeLockAddress(poll SynchronizedPoll) uintptr {
+	value := reflect.ValueOf(poll)
+	if value.Kind() != reflect.Ptr {
+		return 0
+	}
+	return value.Pointer()
+}
+
+// checkNoSealedVotes returns a PollingSemanticError if poll has sealed votes, see MergePolls.
+func checkNoSealedVotes(poll AbstractPoll) error {
+	if acceptor, ok := poll.(SealedVoteAcceptor); ok && acceptor.NumSealed() > 0 {
+		return NewPollingSemanticError(nil, "can't merge a poll with %d sealed votes, reveal them first",
+			acceptor.NumSealed())
+	}
+	return nil
+}
+
+// unwrapSynchronized returns the wrapped poll if poll is a SynchronizedPoll and poll otherwise.
+func unwrapSynchronized(poll AbstractPoll) AbstractPoll {
+	if syncPoll, isSync := poll.(SynchronizedPoll); isSync {
+		return syncPoll.Unwrap()
+	}
+	return poll
+}
+
+// checkPollsMergeable tests if src can be merged into dst, see MergePolls.
+func checkPollsMergeable(dst, src AbstractPoll) error {
+	if reflect.TypeOf(dst) != reflect.TypeOf(src) {
+		return NewPollTypeError("can't merge poll of type %s into poll of type %s",
+			reflect.TypeOf(src), reflect.TypeOf(dst))
+	}
+	if _, isEditor := dst.(VoteEditor); !isEditor {
+		return NewPollTypeError("can't merge polls of type %s, the type doesn't implement VoteEditor",
+			reflect.TypeOf(dst))
+	}
+	switch typedDst := dst.(type) {
+	case *MedianPoll:
+		typedSrc := src.(*MedianPoll)
+		if typedDst.Value != typedSrc.Value || typedDst.MinValue != typedSrc.MinValue || typedDst.Step != typedSrc.Step {
+			return NewPollingSemanticError(nil,
+				"can't merge median polls with different values: value %d (min %d, step %d) and %d (min %d, step %d)",
+				typedDst.Value, typedDst.MinValue, typedDst.Step, typedSrc.Value, typedSrc.MinValue, typedSrc.Step)
+		}
+	case *SchulzePoll:
+		if typedSrc := src.(*SchulzePoll); typedDst.NumOptions != typedSrc.NumOptions {
+			return NewPollingSemanticError(nil, "can't merge schulze polls with %d and %d options",
+				typedDst.NumOptions, typedSrc.NumOptions)
+		}
+	case *ScorePoll:
+		typedSrc := src.(*ScorePoll)
+		if typedDst.NumOptions != typedSrc.NumOptions || typedDst.MaxScore != typedSrc.MaxScore {
+			return NewPollingSemanticError(nil,
+				"can't merge score polls with different options / max scores: %d options (max score %d) and "+
+					"%d options (max score %d)",
+				typedDst.NumOptions, typedDst.MaxScore, typedSrc.NumOptions, typedSrc.MaxScore)
+		}
+	}
+	return nil
+}
+
+// findMergeDuplicate returns the first name of a voter in votes that is already in names or appears multiple times
+// in votes. names is not changed.
+func findMergeDuplicate(names map[string]struct{}, votes []AbstractVote) (string, bool) {
+	seen := make(map[string]struct{}, len(votes))
+	for _, vote := range votes {
+		voter := vote.GetVoter()
+		if voter == nil {
+			continue
+		}
+		if _, has := names[voter.Name]; has {
+			return voter.Name, true
+		}
+		if _, has := seen[voter.Name]; has {
+			return voter.Name, true
+		}
+		seen[voter.Name] = struct{}{}
+	}
+	return "", false
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PollMetadata contains arbitrary key / value data attached to a skeleton or poll by an application, for example
+// a database id, the number of an agenda item or the speaker.
+//
+// The metadata is not used for evaluating a poll. The skeleton converters from this package copy the metadata
+// from a skeleton to the created poll and TallyCollection copies it to TalliedPoll.Meta, so there is no need to keep
+// maps by poll name (which break when a poll is renamed).
+// In a polls file metadata is given in "key: value" lines directly after the poll line, see
+// PollCollectionParser.ParseMetadata. Keys may only contain letters, digits, "_", "-" and ".", values must be a
+// single line without leading or trailing spaces (PollSkeletonCollection.Dump returns an error otherwise).
+//
+// Meta is nil if no metadata is set. All skeletons and polls from this package embed PollMetadata and thus implement
+// MetadataHolder.
+type PollMetadata struct {
+	Meta map[string]string
+}
+
+// GetMeta returns the metadata, it is used to implement MetadataHolder by embedding PollMetadata.
+func (metadata *PollMetadata) GetMeta() map[string]string {
+	return metadata.Meta
+}
+
+// SetMeta sets the metadata, it is used to implement MetadataHolder by embedding PollMetadata.
+func (metadata *PollMetadata) SetMeta(meta map[string]string) {
+	metadata.Meta = meta
+}
+
+// clone returns a copy of the metadata with a new map, used in DeepClone of the polls.
+func (metadata *PollMetadata) clone() PollMetadata {
+	return PollMetadata{Meta: copyMeta(metadata.Meta)}
+}
+
+// SortedMetaKeys returns the keys of Meta sorted in increasing order.
+func (metadata *PollMetadata) SortedMetaKeys() []string {
+	res := make([]string, 0, len(metadata.Meta))
+	for key := range metadata.Meta {
+		res = append(res, key)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// dumpMeta writes the metadata as "key: value" lines (sorted by key) to w.
+// It returns an error if the metadata is invalid (see validateMeta), nothing is written in this case.
+func (metadata *PollMetadata) dumpMeta(w io.Writer) (int, error) {
+	if metaErr := validateMeta(metadata.Meta); metaErr != nil {
+		return 0, metaErr
+	}
+	res := 0
+	for _, key := range metadata.SortedMetaKeys() {
+		written, writeErr := fmt.Fprintf(w, "%s: %s\n", key, metadata.Meta[key])
+		res += written
+		if writeErr != nil {
+			return res, writeErr
+		}
+	}
+	return res, nil
+}
+
+// MetadataHolder is a skeleton or poll that has metadata, see PollMetadata.
+type MetadataHolder interface {
+	GetMeta() map[string]string
+	SetMeta(meta map[string]string)
+}
+
+// copyMeta returns a copy of meta, nil if meta is empty.
+func copyMeta(meta map[string]string) map[string]string {
+	if len(meta) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(meta))
+	for key, value := range meta {
+		res[key] = value
+	}
+	return res
+}
+
+// copySkeletonMeta copies the metadata of skel to poll if both implement MetadataHolder and poll has no metadata
+// yet (a converter might already have set it).
+func copySkeletonMeta(skel AbstractPollSkeleton, poll AbstractPoll) {
+	skelHolder, skelOk := skel.(MetadataHolder)
+	pollHolder, pollOk := poll.(MetadataHolder)
+	if !skelOk || !pollOk || len(pollHolder.GetMeta()) > 0 {
+		return
+	}
+	pollHolder.SetMeta(copyMeta(skelHolder.GetMeta()))
+}
+
+var metadataLineRx = regexp.MustCompile(`^\s*([\w.\-]+):\s*(.*?)\s*$`)
+
+// metadataKeyRx matches a valid metadata key, see PollMetadata.
+var metadataKeyRx = regexp.MustCompile(`^[\w.\-]+$`)
+
+// validateMeta returns a PollingSyntaxError if meta can't be written to a polls file and parsed back: Each key must
+// only contain letters, digits, "_", "-" and "." and each value must be a single line without leading or trailing
+// spaces.
+func validateMeta(meta map[string]string) error {
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !metadataKeyRx.MatchString(key) {
+			return NewPollingSyntaxError(nil, "invalid metadata key \"%s\", it may only contain letters, digits, "+
+				"\"_\", \"-\" and \".\"", key)
+		}
+		value := meta[key]
+		if strings.ContainsAny(value, "\r\n") || strings.TrimSpace(value) != value {
+			return NewPollingSyntaxError(nil, "invalid value %q for metadata key \"%s\", it must be a single line "+
+				"without leading or trailing spaces", value, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+)
+
+// NameNormalizer normalizes voter names, so that names that look the same are also treated as the same name.
+// For example "Alice " and "Alice" or "é" written as a single code point and as "e" followed by a combining accent.
+//
+// TrimSpace removes leading and trailing whitespace, CollapseWhitespace replaces each sequence of whitespace inside
+// the name by a single space (and also trims the name).
+// NFC transforms the name to the unicode normalization form C (see golang.org/x/text/unicode/norm).
+// CaseFold applies unicode case folding, thus "ALICE" and "alice" are considered the same name.
+//
+// The same normalizer should be used in all places where names are compared: VotersParser (or VotersCSVReader),
+// VotesCSVReader (or PollMatrix.NormalizeVoterNames) and VotersToMapWithNormalizer. All of these apply the
+// normalization before duplicate detection and lookups. A nil normalizer doesn't change any names, this is the
+// default everywhere.
+type NameNormalizer struct {
+	TrimSpace          bool
+	CollapseWhitespace bool
+	NFC                bool
+	CaseFold           bool
+}
+
+// NewNameNormalizer returns a new normalizer with TrimSpace, CollapseWhitespace and NFC enabled, CaseFold is
+// disabled.
+func NewNameNormalizer() *NameNormalizer {
+	return &NameNormalizer{
+		TrimSpace:          true,
+		CollapseWhitespace: true,
+		NFC:                true,
+		CaseFold:           false,
+	}
+}
+
+// Normalize returns the normalized name. If normalizer is nil name is returned unchanged.
+func (normalizer *NameNormalizer) Normalize(name string) string {
+	if normalizer == nil {
+		return name
+	}
+	if normalizer.CaseFold {
+		// a new caser is required each time, a caser is not safe for concurrent use
+		name = cases.Fold().String(name)
+	}
+	if normalizer.NFC {
+		name = norm.NFC.String(name)
+	}
+	switch {
+	case normalizer.CollapseWhitespace:
+		name = strings.Join(strings.Fields(name), " ")
+	case normalizer.TrimSpace:
+		name = strings.TrimSpace(name)
+	}
+	return name
+}
@@ -0,0 +1,196 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ParserRegistry maps poll type strings (as returned by AbstractPoll.PollType) to parser templates, see
+// ParserCustomizer and CustomizeParsers.
+//
+// It is an alternative to DefaultParserTemplateMap: Instead of changing the shared package level map each library
+// or application creates its own registry (with NewParserRegistry or DefaultRegistry), registers its custom poll types
+// once and uses this registry to customize the parsers. This way two packages in the same process can't overwrite
+// each other's templates.
+// Each registry is a namespace of its own, so registering a type in one registry doesn't change any other registry.
+//
+// Register returns an error if a template for the type already exists, use Clone to create a copy of a registry that
+// can be changed independently.
+//
+// The package level functions CustomizeParsers and CustomizeParsersToMap only accept a plain map, to customize the
+// templates of a registry use the methods Customize, CustomizeAll and CustomizeAllWithOptions (or pass the result
+// of Map).
+//
+// It is safe to use a registry concurrently, but usually templates should be registered once before the registry is
+// used.
+type ParserRegistry struct {
+	mutex     sync.RWMutex
+	templates map[string]ParserCustomizer
+}
+
+// NewParserRegistry returns a new registry without any templates.
+func NewParserRegistry() *ParserRegistry {
+	return &ParserRegistry{
+		templates: make(map[string]ParserCustomizer),
+	}
+}
+
+// DefaultRegistry returns a new registry containing the templates from GenerateDefaultParserTemplateMap, that is the
+// templates for BasicPollType, MedianPollType, SchulzePollType and ScorePollType.
+//
+// Each call returns a fresh registry, so it can be extended without changing DefaultParserTemplateMap or other
+// registries.
+func DefaultRegistry() *ParserRegistry {
+	return parserRegistryFromMap(GenerateDefaultParserTemplateMap())
+}
+
+// parserRegistryFromMap returns a registry that uses templates directly (without copying it).
+//
+// It is used to route the functions that accept a plain map (like CustomizeParsers) through the registry.
+func parserRegistryFromMap(templates map[string]ParserCustomizer) *ParserRegistry {
+	return &ParserRegistry{
+		templates: templates,
+	}
+}
+
+// Register registers template for all polls with the given poll type.
+//
+// If a template for pollType is already registered a DuplicateError is returned and the registry is not changed.
+func (registry *ParserRegistry) Register(pollType string, template ParserCustomizer) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if _, has := registry.templates[pollType]; has {
+		return NewDuplicateError(fmt.Sprintf("parser template for poll type \"%s\" already registered", pollType))
+	}
+	if registry.templates == nil {
+		registry.templates = make(map[string]ParserCustomizer)
+	}
+	registry.templates[pollType] = template
+	return nil
+}
+
+// Unregister removes the template for pollType, it returns false if there was no such template.
+func (registry *ParserRegistry) Unregister(pollType string) bool {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if _, has := registry.templates[pollType]; !has {
+		return false
+	}
+	delete(registry.templates, pollType)
+	return true
+}
+
+// Template returns the template registered for pollType, the bool is false if there is no such template.
+func (registry *ParserRegistry) Template(pollType string) (ParserCustomizer, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	template, has := registry.templates[pollType]
+	return template, has
+}
+
+// PollTypes returns the sorted poll types for which a template is registered.
+func (registry *ParserRegistry) PollTypes() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	res := make([]string, 0, len(registry.templates))
+	for pollType := range registry.templates {
+		res = append(res, pollType)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Clone returns a new registry with the same templates.
+//
+// The templates itself are not copied, but registering or removing a template in the clone doesn't change the
+// original registry.
+func (registry *ParserRegistry) Clone() *ParserRegistry {
+	return parserRegistryFromMap(registry.Map())
+}
+
+// Map returns a new map from poll type to template, it can be used for functions that expect a map (for example
+// EvaluateOptions.ParserTemplates or Reveal).
+func (registry *ParserRegistry) Map() map[string]ParserCustomizer {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	res := make(map[string]ParserCustomizer, len(registry.templates))
+	for pollType, template := range registry.templates {
+		res[pollType] = template
+	}
+	return res
+}
+
+// Customize customizes the registered templates for each poll, see CustomizeParsers.
+func (registry *ParserRegistry) Customize(polls []AbstractPoll) ([]ParserCustomizer, error) {
+	res := make([]ParserCustomizer, len(polls))
+	for i, poll := range polls {
+		// get the parserTemplate
+		parserTemplate, hasTemplate := registry.Template(poll.PollType())
+		if !hasTemplate {
+			return nil,
+				NewPollTypeError("no matching parserTemplate for type %s (name %s) found",
+					reflect.TypeOf(poll), poll.PollType())
+		}
+		// try to customize
+		customized, customizeErr := parserTemplate.CustomizeForPoll(poll)
+		if customizeErr != nil {
+			return nil, customizeErr
+		}
+		res[i] = customized
+	}
+	return res, nil
+}
+
+// CustomizeAll customizes the registered templates for each poll in polls, see CustomizeParsersToMap.
+func (registry *ParserRegistry) CustomizeAll(polls PollMap) (map[string]ParserCustomizer, error) {
+	res, _, err := registry.CustomizeAllWithOptions(polls, CustomizeOptions{})
+	return res, err
+}
+
+// CustomizeAllWithOptions works as CustomizeAll, but polls can be skipped, see CustomizeParsersToMapWithOptions.
+func (registry *ParserRegistry) CustomizeAllWithOptions(polls PollMap,
+	opts CustomizeOptions) (map[string]ParserCustomizer, []SkippedPoll, error) {
+	res := make(map[string]ParserCustomizer, len(polls))
+	var skipped []SkippedPoll
+	for _, name := range polls.SortedNames() {
+		poll := polls[name]
+		// get the parserTemplate
+		parserTemplate, hasTemplate := registry.Template(poll.PollType())
+		if !hasTemplate {
+			templateErr := NewPollTypeError("no matching parser parserTemplate for type %s (poll type %s, name %s) found",
+				reflect.TypeOf(poll), poll.PollType(), name)
+			if !opts.SkipMissingTemplates {
+				return nil, nil, templateErr
+			}
+			skipped = append(skipped, SkippedPoll{Name: name, Err: templateErr})
+			continue
+		}
+		// try to customize
+		customized, customizeErr := parserTemplate.CustomizeForPoll(poll)
+		if customizeErr != nil {
+			if !opts.SkipCustomizeErrors {
+				return nil, nil, customizeErr
+			}
+			skipped = append(skipped, SkippedPoll{Name: name, Err: customizeErr})
+			continue
+		}
+		res[name] = customized
+	}
+	return res, skipped, nil
+}
@@ -16,12 +16,17 @@ package gopolls
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -31,14 +36,16 @@ import (
 //
 // It can wrap another error (set to nil if not required) and has an optional line number, if this number is < 0
 // the line number is assumed to be unknown / not existing for this error.
+// Source is the name of the input (for example a file name) in which the error occurred, it is empty if unknown.
 type PollingSyntaxError struct {
 	PollError
 	Err     error
 	Msg     string
 	LineNum int
+	Source  string
 }
 
-// NewPollingSyntaxError returns a new PollingSyntaxError with a line number of -1.
+// NewPollingSyntaxError returns a new PollingSyntaxError with a line number of -1 and no source.
 //
 // The message can be formatted with placeholders (like fmt.Sprintf).
 func NewPollingSyntaxError(err error, msg string, a ...interface{}) PollingSyntaxError {
@@ -46,6 +53,7 @@ func NewPollingSyntaxError(err error, msg string, a ...interface{}) PollingSynta
 		Err:     err,
 		Msg:     fmt.Sprintf(msg, a...),
 		LineNum: -1,
+		Source:  "",
 	}
 }
 
@@ -55,31 +63,49 @@ func (err PollingSyntaxError) WithLineNum(lineNum int) PollingSyntaxError {
 		Err:     err.Err,
 		Msg:     err.Msg,
 		LineNum: lineNum,
+		Source:  err.Source,
+	}
+}
+
+// WithSource returns a copy of the error but with the source set to a new value.
+func (err PollingSyntaxError) WithSource(source string) PollingSyntaxError {
+	return PollingSyntaxError{
+		Err:     err.Err,
+		Msg:     err.Msg,
+		LineNum: err.LineNum,
+		Source:  source,
 	}
 }
 
 // convertParserErr wraps a call to PollingSyntaxError.WithLineNum if err is of type PollingSyntaxError.
 // We don't use errors.Is here because we want the exact type.
+// If the error already has a line number it is not changed.
 func convertParserErr(err error, lineNum int) error {
 	if err == nil {
 		return nil
 	}
 	switch e := err.(type) {
 	case PollingSyntaxError:
+		if e.LineNum >= 0 {
+			return e
+		}
 		return e.WithLineNum(lineNum)
 	default:
 		return err
 	}
 }
 
-// Error returns the error message, it contains (if given) the line number and error cause (the wrapped error) and the
-// original message.
+// Error returns the error message, it contains (if given) the source, the line number and error cause (the wrapped
+// error) and the original message.
 func (err PollingSyntaxError) Error() string {
 	errMessage := ""
-	if err.LineNum < 0 {
-		errMessage = ""
-	} else {
+	switch {
+	case err.LineNum >= 0 && err.Source != "":
+		errMessage = fmt.Sprintf("syntax error in %s, line %d: ", err.Source, err.LineNum)
+	case err.LineNum >= 0:
 		errMessage = fmt.Sprintf("syntax error in line %d: ", err.LineNum)
+	case err.Source != "":
+		errMessage = fmt.Sprintf("syntax error in %s: ", err.Source)
 	}
 	errMessage += err.Msg
 	if err.Err != nil {
@@ -129,18 +155,24 @@ func (err PollingSemanticError) Unwrap() error {
 
 // ParserValidationError is an error returned if a validation of the input files.
 // Such errors include: invalid utf-8 encoding (see ErrInvalidEncoding) or a line was longer than allowed.
+// Source is the name of the input (for example a file name) that was validated, it is empty if unknown.
 type ParserValidationError struct {
 	PollError
 	Message string
+	Source  string
 }
 
 func NewParserValidationError(msg string) *ParserValidationError {
 	return &ParserValidationError{
 		Message: msg,
+		Source:  "",
 	}
 }
 
 func (err ParserValidationError) Error() string {
+	if err.Source != "" {
+		return fmt.Sprintf("validation of parser input %s failed: %s", err.Source, err.Message)
+	}
 	return "validation of parser input failed: " + err.Message
 }
 
@@ -148,20 +180,383 @@ func (err ParserValidationError) Unwrap() error {
 	return nil
 }
 
+// Is returns true if target is a ParserValidationError without a source and with the same message.
+// This way errors.Is still detects sentinel errors like ErrInvalidEncoding after the source was set (see
+// withSourceName).
+func (err ParserValidationError) Is(target error) bool {
+	switch t := target.(type) {
+	case *ParserValidationError:
+		return t != nil && t.Source == "" && t.Message == err.Message
+	case ParserValidationError:
+		return t.Source == "" && t.Message == err.Message
+	default:
+		return false
+	}
+}
+
+// withSourceName sets the source of err to source, it is used by the parsers with a SourceName.
+//
+// The source of a PollingSyntaxError and ParserValidationError is set directly (if it isn't set already), the errors
+// in PollCollectionErrors are updated in the same way. All other errors are wrapped in an error with the source as
+// prefix, thus errors.Is and errors.As still work as before. If source is empty err is returned unchanged.
+func withSourceName(err error, source string) error {
+	if err == nil || source == "" {
+		return err
+	}
+	switch e := err.(type) {
+	case PollingSyntaxError:
+		if e.Source != "" {
+			return e
+		}
+		return e.WithSource(source)
+	case *ParserValidationError:
+		if e.Source != "" {
+			return e
+		}
+		return &ParserValidationError{Message: e.Message, Source: source}
+	case PollCollectionErrors:
+		lineErrors := make([]PollCollectionLineError, len(e.Errors))
+		for i, lineErr := range e.Errors {
+			lineErrors[i] = PollCollectionLineError{LineNum: lineErr.LineNum, Err: withSourceName(lineErr.Err, source)}
+		}
+		return PollCollectionErrors{Errors: lineErrors}
+	default:
+		return fmt.Errorf("%s: %w", source, err)
+	}
+}
+
+// PollCollectionLineError is an error that occurred in a certain line while parsing a poll collection, see
+// PollCollectionParser.CollectErrors.
+//
+// Err is the original error (usually a PollingSyntaxError or ParserValidationError), it is returned by Unwrap.
+type PollCollectionLineError struct {
+	LineNum int
+	Err     error
+}
+
+func (err PollCollectionLineError) Error() string {
+	// syntax errors already contain the line number
+	var syntaxErr PollingSyntaxError
+	if errors.As(err.Err, &syntaxErr) && syntaxErr.LineNum >= 0 {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("error in line %d: %s", err.LineNum, err.Err.Error())
+}
+
+func (err PollCollectionLineError) Unwrap() error {
+	return err.Err
+}
+
+// PollCollectionErrors is returned by PollCollectionParser.ParseCollectionSkeletons if CollectErrors is true and at
+// least one error occurred.
+//
+// Errors contains all errors in the order in which they were found in the document.
+// errors.Is and errors.As test all of the wrapped errors, as in PollMatrixErrors.
+type PollCollectionErrors struct {
+	Errors []PollCollectionLineError
+}
+
+func (err PollCollectionErrors) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, lineErr := range err.Errors {
+		messages[i] = lineErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the first error in the document.
+//
+// Use Is and As (or the functions from the errors package) to test all errors.
+func (err PollCollectionErrors) Unwrap() error {
+	if len(err.Errors) == 0 {
+		return nil
+	}
+	return err.Errors[0]
+}
+
+// Is returns true if errors.Is returns true for any of the errors.
+func (err PollCollectionErrors) Is(target error) bool {
+	for _, lineErr := range err.Errors {
+		if errors.Is(lineErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target, see errors.As.
+func (err PollCollectionErrors) As(target interface{}) bool {
+	for _, lineErr := range err.Errors {
+		if errors.As(lineErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // ErrInvalidEncoding is an error used to signal that an input string is not encoded with valid utf-8.
 var ErrInvalidEncoding = NewParserValidationError("invalid utf-8 encoding in input")
 
 ///// PARSERS /////
 
-// isIgnoredLine tests if a line should be ignored during parsing, this happens if the line is empty or starts with #.
-func isIgnoredLine(line string) bool {
+// utf8BOM is the byte order mark that is often written at the beginning of utf-8 files (for example by Excel).
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// bomStrippingReader is a reader that removes a utf-8 byte order mark from the beginning of the input (if there is
+// one). All parsers wrap their input in such a reader, so the BOM never appears in the parsed content.
+type bomStrippingReader struct {
+	r       *bufio.Reader
+	checked bool
+}
+
+func newBOMStrippingReader(r io.Reader) *bomStrippingReader {
+	return &bomStrippingReader{
+		r:       bufio.NewReader(r),
+		checked: false,
+	}
+}
+
+func (r *bomStrippingReader) Read(p []byte) (int, error) {
+	if !r.checked {
+		r.checked = true
+		// errors are ignored here, they're returned by the next read anyway
+		if start, peekErr := r.r.Peek(len(utf8BOM)); peekErr == nil && bytes.Equal(start, utf8BOM) {
+			if _, discardErr := r.r.Discard(len(utf8BOM)); discardErr != nil {
+				return 0, discardErr
+			}
+		}
+	}
+	return r.r.Read(p)
+}
+
+// maxBytesReader is a reader that returns a ParserValidationError once more than limit bytes have been read from r.
+//
+// The bytes actually read are counted, so short reads are handled correctly. Reading exactly limit bytes is allowed,
+// to detect that the limit is exceeded at most one byte more is read from r.
+// If limit is < 0 there is no limit.
+type maxBytesReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func newMaxBytesReader(r io.Reader, limit int) *maxBytesReader {
+	return &maxBytesReader{
+		r:     r,
+		limit: int64(limit),
+		read:  0,
+	}
+}
+
+// exceeded returns true if more than limit bytes have been read.
+func (r *maxBytesReader) exceeded() bool {
+	return r.limit >= 0 && r.read > r.limit
+}
+
+func (r *maxBytesReader) limitErr() error {
+	return NewParserValidationError(fmt.Sprintf("input is too big: only %d bytes are allowed", r.limit))
+}
+
+// checkErr returns the limit error if the limit was exceeded and err otherwise.
+// It is used to report the limit and not some error caused by input that was cut off.
+func (r *maxBytesReader) checkErr(err error) error {
+	if err != nil && r.exceeded() {
+		return r.limitErr()
+	}
+	return err
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.limit < 0 {
+		return r.r.Read(p)
+	}
+	if r.exceeded() {
+		return 0, r.limitErr()
+	}
+	// read at most one byte more than allowed to detect that the limit is exceeded
+	if remaining := r.limit - r.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.exceeded() {
+		return n - int(r.read-r.limit), r.limitErr()
+	}
+	return n, err
+}
+
+// lineScannerSlack is the number of bytes added to the scanner buffer to allow "\r\n" line endings, these bytes
+// must not count towards the max line length.
+const lineScannerSlack = 2
+
+// DefaultBufferSize is the default initial size of the buffer used to read lines, see VotersParser.BufferSize.
+const DefaultBufferSize = 4096
+
+// DefaultMaxBufferSize is the default max size of the buffer used to read lines if no max line length is set, see
+// VotersParser.MaxBufferSize. It is the default of the bufio package.
+const DefaultMaxBufferSize = bufio.MaxScanTokenSize
+
+// scannerBufferSizes returns the initial and the max size of the scanner buffer.
+//
+// If maxLineLength >= 0 the max size is maxLineLength (plus the bytes for the line ending), otherwise maxBufferSize
+// is used. If bufferSize or maxBufferSize are <= 0 the defaults are used. The initial size is never greater than
+// the max size and never zero.
+func scannerBufferSizes(maxLineLength, bufferSize, maxBufferSize int) (initial, max int) {
+	max = maxBufferSize
+	if max <= 0 {
+		max = DefaultMaxBufferSize
+	}
+	if maxLineLength >= 0 {
+		max = maxLineLength + lineScannerSlack
+	}
+	initial = bufferSize
+	if initial <= 0 {
+		initial = DefaultBufferSize
+	}
+	if initial > max {
+		initial = max
+	}
+	return
+}
+
+// newLineScanner returns a scanner that reads the lines from r, any BOM at the beginning of r is removed.
+//
+// The buffer of the scanner is restricted as described in scannerBufferSizes, lines that are longer will result in
+// bufio.ErrTooLong (see lineTooLongError).
+// Note that lines still should be validated (after calling cleanInputLine) because of the extra bytes for the
+// line ending.
+func newLineScanner(r io.Reader, maxLineLength, bufferSize, maxBufferSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(newBOMStrippingReader(r))
+	initial, max := scannerBufferSizes(maxLineLength, bufferSize, maxBufferSize)
+	scanner.Buffer(make([]byte, initial), max)
+	return scanner
+}
+
+// lineTooLongError returns the error that is reported if a scanner created by newLineScanner returns
+// bufio.ErrTooLong, the arguments must be the same as for newLineScanner.
+func lineTooLongError(maxLineLength, maxBufferSize int) *ParserValidationError {
+	if maxLineLength >= 0 {
+		return NewParserValidationError(fmt.Sprintf("line is too long: max allowed number of bytes in line is %d",
+			maxLineLength))
+	}
+	_, max := scannerBufferSizes(maxLineLength, 0, maxBufferSize)
+	return NewParserValidationError(fmt.Sprintf("line is too long: max number of bytes is determined by the buffer size %d",
+		max))
+}
+
+// cleanInputLine removes all trailing carriage returns from a line as returned by a scanner.
+func cleanInputLine(line string) string {
+	return strings.TrimRight(line, "\r")
+}
+
+// ProgressFunc is a callback to report the progress of a parser, linesProcessed is the number of lines read so far.
+//
+// VotersParser, PollCollectionParser and VotesCSVReader call it every ProgressInterval lines, for example to log the
+// progress of big files. The callback is only used for reporting, it must not modify any of the structures
+// returned by the parser.
+type ProgressFunc func(linesProcessed int)
+
+// DefaultProgressInterval is the default number of lines between two calls of a ProgressFunc.
+const DefaultProgressInterval = 100
+
+// reportProgress calls progress if it is not nil and lineNum is a multiple of interval, if interval <= 0 progress
+// is never called.
+func reportProgress(progress ProgressFunc, interval, lineNum int) {
+	if progress != nil && interval > 0 && lineNum%interval == 0 {
+		progress(lineNum)
+	}
+}
+
+// DefaultCommentPrefixes are the prefixes of comment lines in a voters file if nothing else is configured.
+var DefaultCommentPrefixes = []string{"#"}
+
+// isIgnoredLine tests if a line should be ignored during parsing, this happens if the line is empty or starts with
+// one of the comment prefixes. Empty prefixes are skipped, as in stripInlineComment.
+func isIgnoredLine(line string, commentPrefixes []string) bool {
 	line = strings.TrimSpace(line)
-	return line == "" || strings.HasPrefix(line, "#")
+	if line == "" {
+		return true
+	}
+	for _, prefix := range commentPrefixes {
+		if prefix != "" && strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripInlineComment removes a comment from the end of a line.
+// A comment starts with one of the comment prefixes, but only if the prefix is preceded by a whitespace.
+// This way a prefix can still be used inside of a name, for example "* Team#1".
+func stripInlineComment(line string, commentPrefixes []string) string {
+	end := len(line)
+	for _, prefix := range commentPrefixes {
+		if prefix == "" {
+			continue
+		}
+		// find the first occurrence preceded by a whitespace
+		offset := 0
+		for {
+			index := strings.Index(line[offset:], prefix)
+			if index < 0 {
+				break
+			}
+			index += offset
+			if index > 0 && (line[index-1] == ' ' || line[index-1] == '\t') {
+				if index < end {
+					end = index
+				}
+				break
+			}
+			offset = index + len(prefix)
+		}
+	}
+	return line[:end]
 }
 
 // votersLineRx is the regex used to parse a voter line, see ParseVotersLine.
 var votersLineRx = regexp.MustCompile(`^\s*[*]\s+(.+?)\s*(?::\s+(\d+)\s*)?$`)
 
+// fractionalVotersLineRx works as votersLineRx but allows weights in decimal notation, see
+// VotersParser.FractionalWeights.
+var fractionalVotersLineRx = regexp.MustCompile(`^\s*[*]\s+(.+?)\s*(?::\s+(\d+(?:\.\d+)?)\s*)?$`)
+
+// decimalWeightSuffixRx matches a weight in decimal notation at the end of a voter line, it is used to reject such
+// weights if VotersParser.FractionalWeights is not enabled (instead of making them part of the name).
+var decimalWeightSuffixRx = regexp.MustCompile(`:\s+\d+\.\d+\s*$`)
+
+// votersDelegationsRx matches the (optional) delegations at the end of a voter line, see ParseVotersLine.
+// The content must start with "+", this way names containing parentheses are still allowed.
+var votersDelegationsRx = regexp.MustCompile(`^(.*?)\s*\(\s*(\+[^)]*)\)\s*$`)
+
+// delegationRx is the regex used to parse a single delegation in a voter line.
+var delegationRx = regexp.MustCompile(`^\+\s*([^:]+?)\s*(?::\s*(\d+))?$`)
+
+// parseDelegations parses the content of the delegations of a voter (without the parentheses), for example
+// "+Bob, +Carol: 2".
+func parseDelegations(s string) ([]Delegation, error) {
+	entries := strings.Split(s, ",")
+	res := make([]Delegation, 0, len(entries))
+	for _, entry := range entries {
+		match := delegationRx.FindStringSubmatch(strings.TrimSpace(entry))
+		if len(match) == 0 {
+			return nil, NewPollingSyntaxError(nil, "delegation must be of the form \"+voter: weight\", got \"%s\"",
+				strings.TrimSpace(entry))
+		}
+		weight := Weight(1)
+		if match[2] != "" {
+			var weightErr error
+			weight, weightErr = ParseWeight(match[2])
+			if weightErr != nil {
+				return nil, NewPollingSyntaxError(weightErr, "delegation does not contain a valid integer (got %s)",
+					match[2])
+			}
+		}
+		res = append(res, NewDelegation(match[1], weight))
+	}
+	return res, nil
+}
+
 // VotersParser parses voters from a file / string.
 // See ParseVotersLine and ParseVoters for details.
 //
@@ -181,6 +576,8 @@ var votersLineRx = regexp.MustCompile(`^\s*[*]\s+(.+?)\s*(?::\s+(\d+)\s*)?$`)
 // MaxVotersNameLength is the maximal number of bytes allowed in a single voters name.
 // MaxVotersWeight is the maximal weight a voter can have, this is useful to for example avoid overflows when you have
 // many voters.
+// MaxTotalWeight is the maximal sum of the weights of all voters in a file (including delegated weights), it defaults
+// to NoWeight. Note that ParseVoters always returns a ParserValidationError if the sum overflows, i.e. is >= NoWeight.
 //
 // However MaxLineLength is probably one of the most useful limits because it finds very long lines early and
 // avoids the parsing of such lines.
@@ -190,43 +587,181 @@ var votersLineRx = regexp.MustCompile(`^\s*[*]\s+(.+?)\s*(?::\s+(\d+)\s*)?$`)
 //
 // ComputeDefaultMaxLineLength is a small helper that may be called and sets MaxLineLength depending on
 // MaxVotersNameLength and MaxVotersWeight.
+//
+// Comments can be configured with CommentPrefixes and AllowInlineComments.
+// Each line that starts with one of the CommentPrefixes is ignored, if CommentPrefixes is nil DefaultCommentPrefixes
+// is used (to disable comment lines set it to an empty slice). Empty strings in CommentPrefixes are ignored.
+// If AllowInlineComments is true a comment can also follow a voter entry, for example "* Alice: 3 ; board member".
+// Such a comment must be preceded by a whitespace, the comment is removed before the line is parsed.
+// Note that MaxLineLength is always checked against the original line, including the comment.
+//
+// DuplicatePolicy describes what ParseVoters does if a voter name appears multiple times, see ResolveDuplicateVoters.
+// It defaults to NoDuplicateVoterCheck, in which case all entries are returned.
+//
+// MaxTotalBytes is the maximal number of bytes that ParseVoters reads from its input, if the input is longer a
+// ParserValidationError is returned.
+//
+// If ParseDelegations is true a voter line can contain delegations (proxy voting), for example
+// "* Alice: 1 (+Bob, +Carol: 2)", see ParseVotersLine. MaxDelegations is the number of delegations allowed for a
+// single voter, it defaults to NoDelegationLimit. Delegations are disabled by default. If DuplicatePolicy is
+// MergeDuplicateVoters the merged voters are validated again, including MaxDelegations.
+//
+// Progress is called every ProgressInterval lines (see ProgressFunc), it is nil by default and ProgressInterval
+// defaults to DefaultProgressInterval.
+//
+// NameNormalizer is applied to the names of all voters and delegation sources, before names are validated and
+// duplicates are detected. It is nil by default, so names are not changed.
+//
+// BufferSize is the initial size of the buffer used to read lines and MaxBufferSize the size up to which the buffer
+// grows, they default to DefaultBufferSize and DefaultMaxBufferSize. If MaxLineLength is set the buffer grows up to
+// MaxLineLength instead, MaxBufferSize only applies if MaxLineLength is -1. Thus to read lines longer than
+// DefaultMaxBufferSize set either MaxLineLength or MaxBufferSize. Lines that don't fit into the buffer are reported
+// with a ParserValidationError.
+//
+// If ZeroWeightPolicy is RejectZeroWeightAtParse a voter with weight 0 (including delegations) is reported with a
+// PollingSemanticError, it defaults to AllowZeroWeight.
+//
+// If FractionalWeights is true the weight of a voter can be given in decimal notation, for example "* Alice: 2.5"
+// (see ParseRatWeight). The Weight of the voter is then the weight rounded down and FractionalWeight is set to the
+// exact weight (only if it is not an integer), see Voter.RatWeight. MaxVotersWeight is compared with the exact weight,
+// MaxTotalWeight with the rounded weights. Delegated weights are always integers. FractionalWeights is false by
+// default.
+//
+// If RequireExplicitWeight is true a voter line without a weight (for example "* Alice") is reported with a
+// PollingSyntaxError instead of using the weight 1. If RejectZeroWeight is true a voter with an explicit weight of 0
+// is reported with a ParserValidationError, in contrast to ZeroWeightPolicy (RejectZeroWeightAtParse) delegations
+// are not considered. Both errors contain the name of the voter (and the line in ParseVoters). By default
+// RequireExplicitWeight and RejectZeroWeight are false. RejectZeroWeight is not called AllowZeroWeight (with default
+// true) because AllowZeroWeight is already the name of a ZeroWeightPolicy, also this way the zero value of the field
+// keeps the default behaviour.
+//
+// SourceName is the name of the input (for example a file name), if it is not empty it is included in all errors
+// returned by ParseVoters and ParseVotersDocument (see PollingSyntaxError.Source). It is empty by default,
+// ParseVotersFile sets it to the name of the file.
 type VotersParser struct {
-	MaxNumLines         int
-	MaxNumVoters        int
-	MaxLineLength       int
-	MaxVotersNameLength int
-	MaxVotersWeight     Weight
+	MaxNumLines           int
+	MaxNumVoters          int
+	MaxLineLength         int
+	MaxVotersNameLength   int
+	MaxVotersWeight       Weight
+	MaxTotalWeight        Weight
+	MaxTotalBytes         int
+	CommentPrefixes       []string
+	AllowInlineComments   bool
+	DuplicatePolicy       DuplicateVoterPolicy
+	ParseDelegations      bool
+	MaxDelegations        int
+	Progress              ProgressFunc
+	ProgressInterval      int
+	NameNormalizer        *NameNormalizer
+	BufferSize            int
+	MaxBufferSize         int
+	ZeroWeightPolicy      ZeroWeightPolicy
+	FractionalWeights     bool
+	RequireExplicitWeight bool
+	RejectZeroWeight      bool
+	SourceName            string
 }
 
 // NewVotersParser returns a new parser with all limitations disabled.
+//
+// CommentPrefixes is set to DefaultCommentPrefixes and inline comments are not allowed.
 func NewVotersParser() *VotersParser {
 	return &VotersParser{
-		MaxNumLines:         -1,
-		MaxNumVoters:        -1,
-		MaxLineLength:       -1,
-		MaxVotersNameLength: -1,
-		MaxVotersWeight:     NoWeight,
+		MaxNumLines:           -1,
+		MaxNumVoters:          -1,
+		MaxLineLength:         -1,
+		MaxVotersNameLength:   -1,
+		MaxVotersWeight:       NoWeight,
+		MaxTotalWeight:        NoWeight,
+		MaxTotalBytes:         -1,
+		CommentPrefixes:       DefaultCommentPrefixes,
+		AllowInlineComments:   false,
+		DuplicatePolicy:       NoDuplicateVoterCheck,
+		ParseDelegations:      false,
+		MaxDelegations:        NoDelegationLimit,
+		Progress:              nil,
+		ProgressInterval:      DefaultProgressInterval,
+		NameNormalizer:        nil,
+		BufferSize:            DefaultBufferSize,
+		MaxBufferSize:         DefaultMaxBufferSize,
+		ZeroWeightPolicy:      AllowZeroWeight,
+		FractionalWeights:     false,
+		RequireExplicitWeight: false,
+		RejectZeroWeight:      false,
+		SourceName:            "",
+	}
+}
+
+// getCommentPrefixes returns the CommentPrefixes or DefaultCommentPrefixes if they're nil.
+func (parser *VotersParser) getCommentPrefixes() []string {
+	if parser.CommentPrefixes == nil {
+		return DefaultCommentPrefixes
+	}
+	return parser.CommentPrefixes
+}
+
+// lengthSlack is the number of bytes added by the ComputeDefaultMaxLineLength methods (and
+// VotesCSVReader.ComputeDefaultMaxRecordLength) to allow additional whitespaces.
+const lengthSlack = 16
+
+// maxLength returns the maximum of the given lengths.
+func maxLength(first int, lengths ...int) int {
+	res := first
+	for _, length := range lengths {
+		if length > res {
+			res = length
+		}
 	}
+	return res
+}
+
+// currencyStringLength returns the length of the value cents formatted with two decimal places and a separator after
+// every third digit of the integer part, for example "1.234.567,89" for 123456789. The currency is not included.
+func currencyStringLength(cents int) int {
+	intDigits := len(strconv.Itoa(cents)) - 2
+	if intDigits < 1 {
+		intDigits = 1
+	}
+	return intDigits + (intDigits-1)/3 + 3
 }
 
 // ComputeDefaultMaxLineLength sets MaxLineLength depending on the values of MaxVotersNameLength (if set) and
 // MaxVotersWeight.
 // It allows the whitespaces that are required in the description and adds a small constant to allow additional whitespaces,
-// but not too many.
+// but not too many. Delegations and inline comments are not taken into account, if they're allowed MaxLineLength
+// should be set manually.
 func (parser *VotersParser) ComputeDefaultMaxLineLength() {
-	if parser.MaxNumLines < 0 {
+	if parser.MaxVotersNameLength < 0 {
 		return
 	}
-	parser.MaxLineLength = parser.MaxVotersNameLength + len(strconv.FormatUint(uint64(parser.MaxVotersWeight), 10)) + 4 + 16
+	parser.MaxLineLength = parser.MaxVotersNameLength + len(strconv.FormatUint(uint64(parser.MaxVotersWeight), 10)) +
+		4 + lengthSlack
 }
 
 // ParseVotersLine parses a voter line.
 //
 // Line must be of the form "* <VOTER-NAME>: <WEIGHT>".
 // The name can consist of arbitrary letters, weight must be a positive integer.
-// The weight can also be omitted and defaults to 1.
-// The returned error will be of type ParserValidationError or PollingSyntaxError.
+// The weight can also be omitted and defaults to 1 (unless RequireExplicitWeight is true).
+// Whitespace around the name and the weight is ignored and leading zeros in the weight are allowed, thus
+// "*  Alice :  007 " is the voter "Alice" with weight 7.
+// If AllowInlineComments is true a trailing comment is removed from the line.
+//
+// If ParseDelegations is true the line can end with a list of delegations in parentheses, each delegation is of the
+// form "+<VOTER-NAME>: <WEIGHT>" where the weight can be omitted and defaults to 1, for example
+// "* Alice: 1 (+Bob, +Carol: 2)". The delegations are validated with Voter.ValidateDelegations and MaxDelegations.
+//
+// The returned error will be of type ParserValidationError or PollingSyntaxError (or PollingSemanticError for invalid
+// delegations and voters rejected by ZeroWeightPolicy).
 func (parser *VotersParser) ParseVotersLine(s string) (*Voter, error) {
+	return parser.parseVotersLine(s, -1)
+}
+
+// parseVotersLine implements ParseVotersLine, lineNum is the number of the line in ParseVoters (or -1).
+// It is included in the message of the ParserValidationError for RejectZeroWeight, the line numbers of syntax errors
+// are set by ParseVoters.
+func (parser *VotersParser) parseVotersLine(s string, lineNum int) (*Voter, error) {
 	// first validate that s is valid utf-8
 	if !utf8.ValidString(s) {
 		return nil, ErrInvalidEncoding
@@ -239,24 +774,69 @@ func (parser *VotersParser) ParseVotersLine(s string) (*Voter, error) {
 				len(s), parser.MaxLineLength))
 		}
 	}
-	match := votersLineRx.FindStringSubmatch(s)
+	if parser.AllowInlineComments {
+		s = stripInlineComment(s, parser.getCommentPrefixes())
+	}
+	var delegations []Delegation
+	if parser.ParseDelegations {
+		if delegationsMatch := votersDelegationsRx.FindStringSubmatch(s); len(delegationsMatch) > 0 {
+			var delegationsErr error
+			s = delegationsMatch[1]
+			delegations, delegationsErr = parseDelegations(delegationsMatch[2])
+			if delegationsErr != nil {
+				return nil, delegationsErr
+			}
+			for i := range delegations {
+				delegations[i].Source = parser.NameNormalizer.Normalize(delegations[i].Source)
+			}
+		}
+	}
+	lineRx := votersLineRx
+	if parser.FractionalWeights {
+		lineRx = fractionalVotersLineRx
+	} else if decimalWeightSuffixRx.MatchString(s) {
+		return nil, NewPollingSyntaxError(nil,
+			"voter line contains a weight in decimal notation, but fractional weights are not enabled")
+	}
+	match := lineRx.FindStringSubmatch(s)
 	if len(match) == 0 {
 		return nil, NewPollingSyntaxError(nil, "voter line must be of the form \"* voter: weight\"")
 	}
 	name, weightString := match[1], match[2]
-	name = strings.TrimSpace(name)
+	name = parser.NameNormalizer.Normalize(strings.TrimSpace(name))
 	weightString = strings.TrimSpace(weightString)
 	var weight Weight
+	var fractionalWeight *big.Rat
 	var weightErr error
-	if weightString == "" {
+	switch {
+	case weightString == "" && parser.RequireExplicitWeight:
+		return nil, NewPollingSyntaxError(nil, "voter \"%s\" has no weight", name)
+	case weightString == "":
 		weight = 1
-	} else {
+	case parser.FractionalWeights:
+		fractionalWeight, weightErr = ParseRatWeight(weightString)
+		if weightErr != nil {
+			return nil, NewPollingSyntaxError(weightErr, "voter line does not contain a valid weight (got %s)",
+				weightString)
+		}
+		// ParseRatWeight already checked that the weight can be rounded
+		weight, _ = RatToWeight(fractionalWeight)
+		if fractionalWeight.IsInt() {
+			fractionalWeight = nil
+		}
+	default:
 		weight, weightErr = ParseWeight(weightString)
 	}
 
 	if weightErr != nil {
 		return nil, NewPollingSyntaxError(weightErr, "voter line does not contain a valid integer (got %s)", weightString)
 	}
+	if parser.RejectZeroWeight && weight == 0 && fractionalWeight == nil {
+		if lineNum >= 0 {
+			return nil, NewParserValidationError(fmt.Sprintf("voter \"%s\" has weight 0 (line %d)", name, lineNum))
+		}
+		return nil, NewParserValidationError(fmt.Sprintf("voter \"%s\" has weight 0", name))
+	}
 
 	// now validate lengths
 	if parser.MaxVotersNameLength >= 0 {
@@ -267,17 +847,86 @@ func (parser *VotersParser) ParseVotersLine(s string) (*Voter, error) {
 		}
 	}
 
-	if parser.MaxVotersWeight != NoWeight && weight > parser.MaxVotersWeight {
+	// a fractional weight is greater than its integer part
+	if parser.MaxVotersWeight != NoWeight &&
+		(weight > parser.MaxVotersWeight || (weight == parser.MaxVotersWeight && fractionalWeight != nil)) {
 		return nil, NewParserValidationError(fmt.Sprintf("voter weight is too big, got %d but max allowed length is %d",
 			weight, parser.MaxVotersWeight))
 	}
 	res := Voter{
-		Name:   name,
-		Weight: weight,
+		Name:             name,
+		Weight:           weight,
+		FractionalWeight: fractionalWeight,
+		Delegations:      delegations,
+	}
+	if len(delegations) > 0 {
+		if delegationsErr := res.ValidateDelegations(parser.MaxDelegations); delegationsErr != nil {
+			return nil, delegationsErr
+		}
+	}
+	if parser.ZeroWeightPolicy == RejectZeroWeightAtParse && res.HasZeroWeight() {
+		return nil, NewPollingSemanticError(nil, "voter \"%s\" has weight 0", name)
 	}
 	return &res, nil
 }
 
+// votersFileState is the state while parsing a voters file, it is used to validate the number of voters and the
+// total weight.
+type votersFileState struct {
+	numVoters   int
+	totalWeight Weight
+}
+
+// parseVotersFileLine validates and parses a single line of a voters file (without the line ending), it is used by
+// ParseVoters and ParseVotersDocument.
+//
+// It returns nil and no error if the line is ignored (empty or a comment).
+func (parser *VotersParser) parseVotersFileLine(line string, lineNum int, state *votersFileState,
+	limited *maxBytesReader) (*Voter, error) {
+	if parser.MaxNumLines >= 0 && lineNum > parser.MaxNumLines {
+		return nil, NewParserValidationError(fmt.Sprintf("there are too many lines: only %d lines in voters files are allowed", parser.MaxNumLines))
+	}
+	// validate length here for all lines (ParseVotersLine does it only for lines that are not ignored)
+	if parser.MaxLineLength >= 0 && len(line) > parser.MaxLineLength {
+		return nil, NewParserValidationError(fmt.Sprintf("line is too long: got line of length %d, allowed max length is %d",
+			len(line), parser.MaxLineLength))
+	}
+	reportProgress(parser.Progress, parser.ProgressInterval, lineNum)
+	// first test if the line should be ignored
+	if isIgnoredLine(line, parser.getCommentPrefixes()) {
+		return nil, nil
+	}
+	// should not be ignored, must be a valid voter
+	voter, voterErr := parser.parseVotersLine(line, lineNum)
+	if voterErr != nil {
+		// the line might be cut off because of MaxTotalBytes, report this instead
+		return nil, limited.checkErr(convertParserErr(voterErr, lineNum))
+	}
+	state.numVoters++
+	if parser.MaxNumVoters >= 0 && state.numVoters > parser.MaxNumVoters {
+		return nil, NewParserValidationError(fmt.Sprintf("there are too many voters: only %d voters are allowed", parser.MaxNumVoters))
+	}
+	var weightErr error
+	if state.totalWeight, weightErr = addEffectiveWeight(state.totalWeight, voter); weightErr != nil {
+		return nil, NewParserValidationError(fmt.Sprintf("sum of voter weights is too big in line %d: %s",
+			lineNum, weightErr))
+	}
+	if parser.MaxTotalWeight != NoWeight && state.totalWeight > parser.MaxTotalWeight {
+		return nil, NewParserValidationError(fmt.Sprintf("sum of voter weights is too big: got at least %d, allowed max sum is %d",
+			state.totalWeight, parser.MaxTotalWeight))
+	}
+	return voter, nil
+}
+
+// convertScanErr converts an error from the scanner of a voters file, if the line is too long a validation error
+// is returned.
+func (parser *VotersParser) convertScanErr(err error) error {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return lineTooLongError(parser.MaxLineLength, parser.MaxBufferSize)
+	}
+	return err
+}
+
 // ParseVoters parses a list of voters from a reader.
 //
 // Each line must contain one voter entry. Each line must be of the form as described in ParseVotersLine, in short
@@ -290,59 +939,57 @@ func (parser *VotersParser) ParseVotersLine(s string) (*Voter, error) {
 //
 // in which case weight defaults to 1.
 //
-// Empty lines and lines starting with "#" are ignored.
+// Empty lines and lines starting with one of the comment prefixes (by default "#") are ignored.
+// A utf-8 byte order mark at the beginning of r and "\r\n" line endings are allowed.
 //
 // This method will return an internal error whenever for syntax errors / validation errors, all errors from reader are
 // returned directly however.
 //
 // The returned internals errors are either PollingSyntaxError or ParserValidationError.
+// If DuplicatePolicy is set the errors from ResolveDuplicateVoters are returned too.
+// If SourceName is set it is added to all errors, see VotersParser.
 func (parser *VotersParser) ParseVoters(r io.Reader) ([]*Voter, error) {
-	scanner := bufio.NewScanner(r)
-	// if a max line length is set create a buffer with that max length
-	if parser.MaxLineLength >= 0 {
-		// set max length of the buffer to that number
-		// the initial size of the buffer will be 4096, but if max length < 4096 we set it to that
-		buffLength := 4096
-		if parser.MaxLineLength < 4096 {
-			buffLength = parser.MaxLineLength
-		}
-		buff := make([]byte, buffLength)
-		scanner.Buffer(buff, parser.MaxLineLength)
+	res, err := parser.parseVoters(r)
+	if err != nil {
+		return nil, withSourceName(err, parser.SourceName)
 	}
+	return res, nil
+}
+
+func (parser *VotersParser) parseVoters(r io.Reader) ([]*Voter, error) {
+	limited := newMaxBytesReader(r, parser.MaxTotalBytes)
+	scanner := newLineScanner(limited, parser.MaxLineLength, parser.BufferSize, parser.MaxBufferSize)
 	lineNum := 0
 	res := make([]*Voter, 0)
+	state := &votersFileState{}
 	for scanner.Scan() {
 		lineNum++
-		if parser.MaxNumLines >= 0 && lineNum > parser.MaxNumLines {
-			return nil, NewParserValidationError(fmt.Sprintf("there are too many lines: only %d lines in voters files are allowed", parser.MaxNumLines))
-		}
-		line := scanner.Text()
-		// first test if the line should be ignored
-		if !isIgnoredLine(line) {
-			// should not be ignored, must be a valid voter
-			voter, voterErr := parser.ParseVotersLine(line)
-			if voterErr != nil {
-				return nil, convertParserErr(voterErr, lineNum)
-			}
+		line := cleanInputLine(scanner.Text())
+		voter, lineErr := parser.parseVotersFileLine(line, lineNum, state, limited)
+		if lineErr != nil {
+			return nil, lineErr
+		}
+		if voter != nil {
 			res = append(res, voter)
-			if parser.MaxNumVoters >= 0 && len(res) > parser.MaxNumVoters {
-				return nil, NewParserValidationError(fmt.Sprintf("there are too many voters: only %d voters are allowed", parser.MaxNumVoters))
-			}
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		// if the error is that the line is too long return it as an validation error
-		if errors.Is(err, bufio.ErrTooLong) {
-			var errString string
-			if parser.MaxLineLength >= 0 {
-				errString = fmt.Sprintf("line is too long: max allowed number of bytes in line is %d",
-					parser.MaxLineLength)
-			} else {
-				errString = "line is too long: max number of bytes is determined by go scanner buffer size (probably 4096)"
+		return nil, parser.convertScanErr(err)
+	}
+	if parser.DuplicatePolicy != NoDuplicateVoterCheck {
+		resolved, resolveErr := ResolveDuplicateVoters(res, parser.DuplicatePolicy)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		// merged voters can have more delegations than allowed
+		if parser.ParseDelegations && parser.DuplicatePolicy == MergeDuplicateVoters {
+			for _, voter := range resolved {
+				if delegationsErr := voter.ValidateDelegations(parser.MaxDelegations); delegationsErr != nil {
+					return nil, delegationsErr
+				}
 			}
-			return nil, NewParserValidationError(errString)
 		}
-		return nil, err
+		return resolved, nil
 	}
 	return res, nil
 }
@@ -353,6 +1000,21 @@ func (parser *VotersParser) ParseVotersFromString(s string) ([]*Voter, error) {
 	return parser.ParseVoters(reader)
 }
 
+// ParseVotersFile works like ParseVoters but reads from the file with the given path.
+//
+// The file is parsed with a copy of the parser with SourceName set to the base name of path, thus all errors contain
+// the name of the file. Errors opening the file are returned directly.
+func (parser *VotersParser) ParseVotersFile(path string) ([]*Voter, error) {
+	f, openErr := os.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer f.Close()
+	fileParser := *parser
+	fileParser.SourceName = filepath.Base(path)
+	return fileParser.ParseVoters(f)
+}
+
 // parsing a description
 
 // the following regular expressions are used while parsing the input file
@@ -361,6 +1023,77 @@ var groupLineRx = regexp.MustCompile(`^\s*##\s+(.+?)\s*$`)
 var pollLineRx = regexp.MustCompile(`^\s*###\s+(.+?)\s*$`)
 var optionLineRx = regexp.MustCompile(`^\s*[*]\s+(.+?)\s*$`)
 var medianOptionLineRx = regexp.MustCompile(`^\s*[-]\s+(.+?)\s*$`)
+var moneyRangeRx = regexp.MustCompile(`^(.+?)\s*\[\s*(.*?)\s*\]$`)
+var moneyRangeItemRx = regexp.MustCompile(`(?:^|,)\s*(min|step)\s+`)
+
+// pollAnnotationsRx matches the (optional) annotations at the end of a poll name, see PollAnnotations.
+// To not break poll names that end with something in brackets (like "[draft]" or "[2021]") the content must start
+// with one of the keys or with a fraction like "2/3" (the shorthand for the majority), all other bracketed text
+// remains part of the poll name.
+var pollAnnotationsRx = regexp.MustCompile(
+	`^(.+?)\s*\[\s*((?:majority|quorum|empty)\s*=[^\]]*|\d+/\d+\s*(?:,[^\]]*)?)\]$`)
+
+// majorityShorthandRx matches the shorthand for the majority in the annotations of a poll, for example "2/3".
+var majorityShorthandRx = regexp.MustCompile(`^\d+/\d+$`)
+
+// parseAnnotationFraction parses a fraction for an annotation, it must be a value 0 <= value <= 1.
+func parseAnnotationFraction(key, s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	res, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, NewPollingSyntaxError(nil, "invalid fraction \"%s\" for %s", s, key)
+	}
+	if res.Sign() < 0 || res.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, NewPollingSyntaxError(nil, "fraction for %s must be between 0 and 1, got %s", key, s)
+	}
+	return res, nil
+}
+
+// parsePollAnnotations parses the content of the annotations of a poll (without the brackets), for example
+// "majority=2/3, quorum=1/2, empty=abstention" or "2/3, quorum=1/2". A fraction without a key is the majority.
+func parsePollAnnotations(s string) (PollAnnotations, error) {
+	var res PollAnnotations
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		key, value := "majority", entry
+		if index := strings.Index(entry, "="); index >= 0 {
+			key, value = strings.TrimSpace(entry[:index]), entry[index+1:]
+		} else if !majorityShorthandRx.MatchString(entry) {
+			return res, NewPollingSyntaxError(nil,
+				"invalid poll annotation \"%s\", must be of the form key=value or a fraction", entry)
+		}
+		if key == "empty" {
+			if res.EmptyPolicy != nil {
+				return res, NewPollingSyntaxError(nil, "empty vote policy is given multiple times")
+			}
+			policy, policyErr := ParseEmptyVotePolicy(strings.TrimSpace(value))
+			if policyErr != nil {
+				return res, policyErr
+			}
+			res.EmptyPolicy = &policy
+			continue
+		}
+		fraction, fractionErr := parseAnnotationFraction(key, value)
+		if fractionErr != nil {
+			return res, fractionErr
+		}
+		switch key {
+		case "majority":
+			if res.RequiredMajority != nil {
+				return res, NewPollingSyntaxError(nil, "majority is given multiple times")
+			}
+			res.RequiredMajority = fraction
+		case "quorum":
+			if res.Quorum != nil {
+				return res, NewPollingSyntaxError(nil, "quorum is given multiple times")
+			}
+			res.Quorum = fraction
+		default:
+			return res, NewPollingSyntaxError(nil, "unknown poll annotation \"%s\"", key)
+		}
+	}
+	return res, nil
+}
 
 // matchFirst tries to match s against each regex.
 // It returns the index of the first match and the complete match (from rx.FindStringSubmatch).
@@ -393,6 +1126,8 @@ const (
 	groupOrPollState
 	// expect another option, if any
 	optionalOptionState
+	// skip all lines until the next group or poll, only used if errors are collected
+	recoverState
 	// invalid state from which we should never continue
 	invalidState
 )
@@ -400,18 +1135,40 @@ const (
 // parserContext stores information passed around while parsing an input.
 type parserContext struct {
 	*PollSkeletonCollection
-	lastPollName   string
-	currencyParser CurrencyParser
-	numSkels       int
+	lastPollName        string
+	lastPollAnnotations PollAnnotations
+	lastPollMeta        map[string]string
+	lastPollLineNum     int
+	lineNum             int
+	currencyParser      CurrencyParser
+	numSkels            int
+	collectErrors       bool
+	errors              []PollCollectionLineError
+	// indented is true if the current line starts with a whitespace
+	indented bool
 }
 
 func newParserContext(currencyParser CurrencyParser) *parserContext {
 	return &parserContext{
 		PollSkeletonCollection: NewPollSkeletonCollection(""),
 		lastPollName:           "",
+		lastPollLineNum:        -1,
+		lineNum:                0,
 		currencyParser:         currencyParser,
 		numSkels:               0,
+		collectErrors:          false,
+		errors:                 nil,
+	}
+}
+
+// recordError adds err to the collected errors if errors should be collected.
+// It returns false if errors are not collected, in this case err must be returned by the caller.
+func (context *parserContext) recordError(err error, lineNum int) bool {
+	if !context.collectErrors {
+		return false
 	}
+	context.errors = append(context.errors, PollCollectionLineError{LineNum: lineNum, Err: err})
+	return true
 }
 
 // stateHandleFunc is a function that is applied to a certain line and tests if the line meets the expectations.
@@ -430,6 +1187,10 @@ func runSecureStateHandleFunc(f stateHandleFunc, line string, context *parserCon
 	return
 }
 
+// DefaultOptionDescriptionSeparator is the recommended separator between the name and the description of an option,
+// see PollCollectionParser. It is not enabled by default.
+const DefaultOptionDescriptionSeparator = " — "
+
 // PollCollectionParser parses a poll collection from a file / string.
 // See ParseCollectionSkeletons and ParseCollectionSkeletonsFromString.
 //
@@ -437,6 +1198,7 @@ func runSecureStateHandleFunc(f stateHandleFunc, line string, context *parserCon
 // This is the same idea as in VotersParser, see there for details of when you would want to use restrictions.
 //
 // A new parser from NewPollCollectionParser sets all values to -1, meaning no restrictions.
+// The only exception is MinNumOptions which defaults to 2.
 //
 // The following restrictions can be configured:
 // MaxNumLines is the number of lines that are allowed in a polls file.
@@ -446,35 +1208,105 @@ func runSecureStateHandleFunc(f stateHandleFunc, line string, context *parserCon
 // MaxGroupNameLength is the maximal length a group is allowed to have.
 // MaxPollNameLength is the maximal length a poll name is allowed to have.
 // MaxNumOptions should be set to at least two, it describes how many options in a basic poll are allowed.
+// MinNumOptions is the minimal number of options a basic poll must have, set it to 1 (or -1) to allow informational
+// polls with a single option.
 // MaxOptionLength is the maximal length a single option is allowed to have.
-// MaxCurrencyValue is the maximal currency value (in cents) that is allowed. This can be useful to avoid overflows /
-// database limitations.
+// MaxCurrencyValue is the maximal currency value (in cents, or the smallest unit of the CurrencyParser, see
+// PreciseCurrencyHandler) that is allowed. This can be useful to avoid overflows / database limitations.
+// MaxTotalBytes is the maximal number of bytes read from the input.
 //
 // Again, some combinations would not make sense, like setting MaxNumLines=21 and MaxTitleLength=42.
+// ComputeDefaultMaxLineLength sets MaxLineLength depending on the other limits.
+//
+// Progress and ProgressInterval as well as BufferSize and MaxBufferSize work as in VotersParser.
+//
+// By default the parser stops at the first error. If CollectErrors is true the parser instead records the error and
+// skips all lines until the next group ("## ") or poll ("### ") and continues parsing from there, see
+// ParseCollectionSkeletons.
+//
+// OptionDescriptionSeparator separates the name of an option from its description, for example
+// "* Option A — renovate hall". It defaults to the empty string, which disables descriptions in the option line
+// (so existing option names that contain the separator are not changed), set it to DefaultOptionDescriptionSeparator
+// to enable them. Descriptions in indented lines are always allowed, see ParseCollectionSkeletons.
+//
+// If ParseMetadata is true "key: value" lines between a poll line and its first option are parsed as metadata of the
+// poll, see PollMetadata. It defaults to false, in this case such lines are a syntax error.
+//
+// SourceName is the name of the input as in VotersParser, it is included in all errors returned by
+// ParseCollectionSkeletons and ParseCollectionYAML. ParseCollectionSkeletonsFile sets it to the name of the file.
 type PollCollectionParser struct {
-	MaxNumLines        int
-	MaxNumPolls        int
-	MaxLineLength      int
-	MaxTitleLength     int
-	MaxGroupNameLength int
-	MaxPollNameLength  int
-	MaxNumOptions      int
-	MaxOptionLength    int
-	MaxCurrencyValue   int
+	MaxNumLines                int
+	MaxNumPolls                int
+	MaxLineLength              int
+	MaxTitleLength             int
+	MaxGroupNameLength         int
+	MaxPollNameLength          int
+	MaxNumOptions              int
+	MinNumOptions              int
+	MaxOptionLength            int
+	MaxCurrencyValue           int
+	MaxTotalBytes              int
+	Progress                   ProgressFunc
+	ProgressInterval           int
+	CollectErrors              bool
+	BufferSize                 int
+	MaxBufferSize              int
+	OptionDescriptionSeparator string
+	ParseMetadata              bool
+	SourceName                 string
 }
 
 // NewPollCollectionParser returns a new parser with all limitations / restrictions disabled.
 func NewPollCollectionParser() *PollCollectionParser {
 	return &PollCollectionParser{
-		MaxNumLines:        -1,
-		MaxNumPolls:        -1,
-		MaxLineLength:      -1,
-		MaxTitleLength:     -1,
-		MaxGroupNameLength: -1,
-		MaxPollNameLength:  -1,
-		MaxNumOptions:      -1,
-		MaxOptionLength:    -1,
-		MaxCurrencyValue:   -1,
+		MaxNumLines:                -1,
+		MaxNumPolls:                -1,
+		MaxLineLength:              -1,
+		MaxTitleLength:             -1,
+		MaxGroupNameLength:         -1,
+		MaxPollNameLength:          -1,
+		MaxNumOptions:              -1,
+		MinNumOptions:              2,
+		MaxOptionLength:            -1,
+		MaxCurrencyValue:           -1,
+		MaxTotalBytes:              -1,
+		Progress:                   nil,
+		ProgressInterval:           DefaultProgressInterval,
+		CollectErrors:              false,
+		BufferSize:                 DefaultBufferSize,
+		MaxBufferSize:              DefaultMaxBufferSize,
+		OptionDescriptionSeparator: "",
+		ParseMetadata:              false,
+		SourceName:                 "",
+	}
+}
+
+// ComputeDefaultMaxLineLength sets MaxLineLength depending on the values of MaxTitleLength, MaxGroupNameLength,
+// MaxPollNameLength, MaxOptionLength and MaxCurrencyValue.
+//
+// It allows the longest head, group, poll, option and money value line (with the value formatted with two decimal
+// places and thousands separators) and adds a small constant to allow additional whitespaces and the currency.
+// If one of these limits is not set (-1) MaxLineLength is not changed.
+// Poll annotations, option descriptions and metadata lines are not taken into account, if they're used MaxLineLength
+// should be set manually.
+func (parser *PollCollectionParser) ComputeDefaultMaxLineLength() {
+	if parser.MaxTitleLength < 0 || parser.MaxGroupNameLength < 0 || parser.MaxPollNameLength < 0 ||
+		parser.MaxOptionLength < 0 || parser.MaxCurrencyValue < 0 {
+		return
+	}
+	// the prefixes are "# ", "## ", "### ", "* " and "- "
+	parser.MaxLineLength = maxLength(parser.MaxTitleLength+2, parser.MaxGroupNameLength+3,
+		parser.MaxPollNameLength+4, parser.MaxOptionLength+2, currencyStringLength(parser.MaxCurrencyValue)+2) +
+		lengthSlack
+}
+
+// VoteLengthHints returns the hints for VotesCSVReader.ComputeDefaultMaxRecordLength that describe the votes for
+// polls parsed by this parser, i.e. MaxNumOptions, MaxOptionLength and MaxCurrencyValue.
+func (parser *PollCollectionParser) VoteLengthHints() VoteLengthHints {
+	return VoteLengthHints{
+		MaxNumOptions:    parser.MaxNumOptions,
+		MaxOptionLength:  parser.MaxOptionLength,
+		MaxCurrencyValue: parser.MaxCurrencyValue,
 	}
 }
 
@@ -496,42 +1328,78 @@ func (parser *PollCollectionParser) validateLine(line string, lineNum int) error
 }
 
 func (parser *PollCollectionParser) setupScanner(r io.Reader) *bufio.Scanner {
-	scanner := bufio.NewScanner(r)
-	// max line length is set create a buffer with that max length
-	if parser.MaxLineLength >= 0 {
-		// set max length of the buffer to that number
-		// the initial size of the buffer will be 4096, but if max length < 4096 we set it to that
-		buffLength := 4096
-		if parser.MaxLineLength < 4096 {
-			buffLength = parser.MaxLineLength
-		}
-		buff := make([]byte, buffLength)
-		scanner.Buffer(buff, parser.MaxLineLength)
-	}
-	return scanner
+	return newLineScanner(r, parser.MaxLineLength, parser.BufferSize, parser.MaxBufferSize)
 }
 
 // ParseCollectionSkeletons parses a collection of poll descriptions and returns them as skeletons.
 // See wiki and example files for format details.
+//
+// A poll line can have optional annotations, see PollAnnotations. If ParseMetadata is true it can be followed by
+// "key: value" lines that are stored as metadata of the poll, see PollMetadata.
+//
+// The value of a money poll can be followed by a min value and / or a step size in brackets, for example
+// "- 5000,00 € [min 500,00 €, step 50,00 €]". The values are parsed with currencyParser and stored in
+// MoneyPollSkeleton.MinValue and MoneyPollSkeleton.Step, the min value must not be greater than the value and the
+// step must be > 0.
+//
+// An option of a basic poll can have a description: Either in the same line separated by
+// OptionDescriptionSeparator ("* Option A — renovate hall") or in indented lines following the option (the lines are
+// joined by a single space). An indented line that is an option, group or poll line is still parsed as such.
+// Documents without descriptions are not affected.
+//
+// A utf-8 byte order mark at the beginning of r and "\r\n" line endings are allowed.
+//
+// If CollectErrors is true the parser doesn't stop at the first error. Instead the error is recorded and all lines
+// up to the next group or poll line are skipped. In this case the parsed collection is returned together with an
+// error of type PollCollectionErrors (if at least one error occurred). The collection contains everything that could
+// be parsed, note that a poll in which an error occurred might be incomplete (for example contain only the options
+// before the error) or missing.
+// Some errors still stop the parser: reading errors and violations of MaxNumLines, MaxLineLength, MaxTotalBytes and
+// invalid utf-8, these errors are also part of the returned PollCollectionErrors.
+//
+// If SourceName is set it is added to all errors, see VotersParser.
 func (parser *PollCollectionParser) ParseCollectionSkeletons(r io.Reader, currencyParser CurrencyParser) (*PollSkeletonCollection, error) {
+	res, err := parser.parseCollectionSkeletons(r, currencyParser)
+	return res, withSourceName(err, parser.SourceName)
+}
+
+func (parser *PollCollectionParser) parseCollectionSkeletons(r io.Reader,
+	currencyParser CurrencyParser) (*PollSkeletonCollection, error) {
 	if currencyParser == nil {
 		currencyParser = SimpleEuroHandler{}
 	}
 	// create context to pass around
 	context := newParserContext(currencyParser)
+	context.collectErrors = parser.CollectErrors
 	// initial state is head
 	state := headState
 	// read lines from scanner
-	scanner := parser.setupScanner(r)
+	limited := newMaxBytesReader(r, parser.MaxTotalBytes)
+	scanner := parser.setupScanner(limited)
 	lineNum := 0
+	// collectedResult returns the result if errors are collected
+	collectedResult := func() (*PollSkeletonCollection, error) {
+		if len(context.errors) == 0 {
+			return context.PollSkeletonCollection, nil
+		}
+		return context.PollSkeletonCollection, PollCollectionErrors{Errors: context.errors}
+	}
 	for scanner.Scan() {
 		lineNum++
-		line := scanner.Text()
+		context.lineNum = lineNum
+		line := cleanInputLine(scanner.Text())
 		if validateLineErr := parser.validateLine(line, lineNum); validateLineErr != nil {
-			return nil, validateLineErr
+			validateLineErr = limited.checkErr(validateLineErr)
+			if !context.recordError(validateLineErr, lineNum) {
+				return nil, validateLineErr
+			}
+			return collectedResult()
 		}
-		// we can trim the line, no construct needs whitespaces in front / back
-		line = strings.TrimSpace(line)
+		reportProgress(parser.Progress, parser.ProgressInterval, lineNum)
+		// we can trim the line, only descriptions of options depend on the indentation
+		trimmed := strings.TrimSpace(line)
+		context.indented = strings.TrimLeftFunc(line, unicode.IsSpace) != line
+		line = trimmed
 		if line == "" {
 			continue
 		}
@@ -550,44 +1418,50 @@ func (parser *PollCollectionParser) ParseCollectionSkeletons(r io.Reader, curren
 			handler = parser.handleGroupOrPollState
 		case optionalOptionState:
 			handler = parser.handleOptionalOptionState
+		case recoverState:
+			handler = parser.handleRecoverState
 		default:
 			return nil, errors.New("internal error: Parser entered an invalid state")
 		}
 		// call handler and also recover from all panics
 		nextState, stateErr := runSecureStateHandleFunc(handler, line, context)
 		if stateErr != nil {
-			return nil, convertParserErr(stateErr, lineNum)
+			// the line might be cut off because of MaxTotalBytes, report this instead
+			stateErr = limited.checkErr(convertParserErr(stateErr, lineNum))
+			if !context.recordError(stateErr, lineNum) {
+				return nil, stateErr
+			}
+			nextState = recoverState
+			// a missing title should not hide the first group, so try the line again as a group
+			if state == headState {
+				retryState, retryErr := runSecureStateHandleFunc(parser.handleRecoverState, line, context)
+				if retryErr != nil {
+					context.recordError(convertParserErr(retryErr, lineNum), lineNum)
+				} else {
+					nextState = retryState
+				}
+			}
 		}
 		state = nextState
 	}
 	if scanErr := scanner.Err(); scanErr != nil {
 		// if the error is that th line is too long return it as an validation error
 		if errors.Is(scanErr, bufio.ErrTooLong) {
-			var errString string
-			if parser.MaxLineLength >= 0 {
-				errString = fmt.Sprintf("line is too long: max allowed number of bytes in line is %d",
-					parser.MaxLineLength)
-			} else {
-				errString = "line is too long: max number of bytes is determined by go scanner buffer size (probably 4096)"
-			}
-			return nil, NewParserValidationError(errString)
+			scanErr = lineTooLongError(parser.MaxLineLength, parser.MaxBufferSize)
 		}
-		return nil, scanErr
+		if !context.recordError(scanErr, lineNum+1) {
+			return nil, scanErr
+		}
+		return collectedResult()
 	}
 
-	// no test if in all "basic" skeletons there are at least two options, everything
-	// else doesn't make sense
 	res := context.PollSkeletonCollection
 
-	for _, group := range res.Groups {
-		for _, pollSkel := range group.Skeletons {
-			if asPollSkel, ok := pollSkel.(*PollSkeleton); ok {
-				if len(asPollSkel.Options) < 2 {
-					// Not really syntax related (kind of if the formal syntax would specifically say
-					// two), but anyway, should be fine
-					return nil, NewPollingSyntaxError(nil, "poll \"%s\" contains only %d options, expected at most 2",
-						asPollSkel.Name, len(asPollSkel.Options))
-				}
+	// the options of all other polls have already been validated when the next poll / group started
+	if state == optionalOptionState {
+		if numOptionsErr := parser.validateNumOptions(context); numOptionsErr != nil {
+			if !context.recordError(numOptionsErr, context.lastPollLineNum) {
+				return nil, numOptionsErr
 			}
 		}
 	}
@@ -595,11 +1469,20 @@ func (parser *PollCollectionParser) ParseCollectionSkeletons(r io.Reader, curren
 	// now test if we're in a not valid end state
 	switch state {
 	case headState:
-		return nil, NewPollingSyntaxError(nil, "no title found \"# <TITLE>\"")
+		titleErr := NewPollingSyntaxError(nil, "no title found \"# <TITLE>\"")
+		if !context.recordError(titleErr, lineNum) {
+			return nil, titleErr
+		}
 	case optionState:
-		return nil, NewPollingSyntaxError(nil, "found beginning of a poll but no option was given")
+		optionErr := NewPollingSyntaxError(nil, "found beginning of a poll but no option was given")
+		if !context.recordError(optionErr, context.lastPollLineNum) {
+			return nil, optionErr
+		}
 	}
 
+	if parser.CollectErrors {
+		return collectedResult()
+	}
 	return res, nil
 }
 
@@ -609,6 +1492,22 @@ func (parser *PollCollectionParser) ParseCollectionSkeletonsFromString(currencyP
 	return parser.ParseCollectionSkeletons(r, currencyParser)
 }
 
+// ParseCollectionSkeletonsFile works as ParseCollectionSkeletons but parses the file with the given path.
+//
+// As in VotersParser.ParseVotersFile SourceName is set to the base name of path and errors opening the file are
+// returned directly.
+func (parser *PollCollectionParser) ParseCollectionSkeletonsFile(path string,
+	currencyParser CurrencyParser) (*PollSkeletonCollection, error) {
+	f, openErr := os.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer f.Close()
+	fileParser := *parser
+	fileParser.SourceName = filepath.Base(path)
+	return fileParser.ParseCollectionSkeletons(f, currencyParser)
+}
+
 func (parser *PollCollectionParser) validateTitle(title string) error {
 	if parser.MaxTitleLength >= 0 && len(title) > parser.MaxTitleLength {
 		return NewParserValidationError(fmt.Sprintf("title is too long: got length %d, allowed max length is %d",
@@ -667,10 +1566,23 @@ func (parser *PollCollectionParser) handlePollState(line string, context *parser
 	if len(match) == 0 {
 		return invalidState, NewPollingSyntaxError(nil, "invalid poll line, must be of the form \"### <POLL>\"")
 	}
-	context.lastPollName = match[1]
-	if nameValidationErr := parser.validatePollName(context.lastPollName); nameValidationErr != nil {
+	name := match[1]
+	var annotations PollAnnotations
+	if annotationsMatch := pollAnnotationsRx.FindStringSubmatch(name); len(annotationsMatch) > 0 {
+		var annotationsErr error
+		name = annotationsMatch[1]
+		annotations, annotationsErr = parsePollAnnotations(annotationsMatch[2])
+		if annotationsErr != nil {
+			return invalidState, annotationsErr
+		}
+	}
+	if nameValidationErr := parser.validatePollName(name); nameValidationErr != nil {
 		return invalidState, nameValidationErr
 	}
+	context.lastPollName = name
+	context.lastPollAnnotations = annotations
+	context.lastPollMeta = nil
+	context.lastPollLineNum = context.lineNum
 	return optionState, nil
 }
 
@@ -681,6 +1593,23 @@ func (parser *PollCollectionParser) validateNumPolls(numPolls int) error {
 	return nil
 }
 
+// splitOptionDescription splits an option into its name and description, see OptionDescriptionSeparator.
+// If the separator is not found or the name would be empty the whole option is returned as name.
+func (parser *PollCollectionParser) splitOptionDescription(option string) (string, string) {
+	if parser.OptionDescriptionSeparator == "" {
+		return option, ""
+	}
+	index := strings.Index(option, parser.OptionDescriptionSeparator)
+	if index < 0 {
+		return option, ""
+	}
+	name := strings.TrimSpace(option[:index])
+	if name == "" {
+		return option, ""
+	}
+	return name, strings.TrimSpace(option[index+len(parser.OptionDescriptionSeparator):])
+}
+
 func (parser *PollCollectionParser) validateNewOption(options []string) error {
 	last := options[len(options)-1]
 	if parser.MaxOptionLength >= 0 && len(last) > parser.MaxOptionLength {
@@ -695,6 +1624,20 @@ func (parser *PollCollectionParser) validateNewOption(options []string) error {
 	return nil
 }
 
+// validateNumOptions tests if the last poll in the context has at least MinNumOptions options.
+// It must only be called if the last poll is a basic poll, i.e. when leaving optionalOptionState.
+// The returned error has the line number of the poll line.
+func (parser *PollCollectionParser) validateNumOptions(context *parserContext) error {
+	poll := context.getLastPollGroup().getLastPoll()
+	if parser.MinNumOptions >= 0 && len(poll.Options) < parser.MinNumOptions {
+		// Not really syntax related (kind of if the formal syntax would specifically say
+		// two), but anyway, should be fine
+		return NewPollingSyntaxError(nil, "poll \"%s\" contains only %d option(s), expected at least %d",
+			poll.Name, len(poll.Options), parser.MinNumOptions).WithLineNum(context.lastPollLineNum)
+	}
+	return nil
+}
+
 func (parser *PollCollectionParser) validateMoneyValue(value CurrencyValue) error {
 	if parser.MaxCurrencyValue >= 0 && value.ValueCents > parser.MaxCurrencyValue {
 		return NewParserValidationError(fmt.Sprintf("value for money poll is too big, got %d cents, max allowed cents is %d",
@@ -703,6 +1646,20 @@ func (parser *PollCollectionParser) validateMoneyValue(value CurrencyValue) erro
 	return nil
 }
 
+// handleMetadataLine adds a metadata entry to the current poll, the parser stays in optionState because the poll
+// still requires an option.
+func (parser *PollCollectionParser) handleMetadataLine(key, value string, context *parserContext) (parserState, error) {
+	if _, has := context.lastPollMeta[key]; has {
+		return invalidState, NewPollingSemanticError(nil, "duplicate metadata key \"%s\" for poll \"%s\"",
+			key, context.lastPollName)
+	}
+	if context.lastPollMeta == nil {
+		context.lastPollMeta = make(map[string]string)
+	}
+	context.lastPollMeta[key] = value
+	return optionState, nil
+}
+
 func (parser *PollCollectionParser) handleOptionState(line string, context *parserContext) (parserState, error) {
 	// just some assertions to be sure
 	if context.lastPollName == "" {
@@ -713,11 +1670,16 @@ func (parser *PollCollectionParser) handleOptionState(line string, context *pars
 	index, match := matchFirst(line, optionLineRx, medianOptionLineRx)
 	switch index {
 	case -1:
+		if metaMatch := metadataLineRx.FindStringSubmatch(line); parser.ParseMetadata && len(metaMatch) > 0 {
+			return parser.handleMetadataLine(metaMatch[1], metaMatch[2], context)
+		}
 		return invalidState, NewPollingSyntaxError(nil, "invalid option line, must either be a standard option \"*\" or money value \"-}")
 	case 0:
 		// add a new skeleton with this option
 		skeleton := NewPollSkeleton(context.lastPollName)
-		skeleton.Options = append(skeleton.Options, match[1])
+		skeleton.PollAnnotations = context.lastPollAnnotations
+		skeleton.Meta = context.lastPollMeta
+		skeleton.AddOption(parser.splitOptionDescription(match[1]))
 		if validateOptionErr := parser.validateNewOption(skeleton.Options); validateOptionErr != nil {
 			return invalidState, validateOptionErr
 		}
@@ -728,8 +1690,12 @@ func (parser *PollCollectionParser) handleOptionState(line string, context *pars
 		}
 		return optionalOptionState, nil
 	case 1:
+		valueString, rangeString := match[1], ""
+		if rangeMatch := moneyRangeRx.FindStringSubmatch(valueString); len(rangeMatch) > 0 {
+			valueString, rangeString = rangeMatch[1], rangeMatch[2]
+		}
 		// try to parse currency with parser from context
-		currency, currencyErr := context.currencyParser.Parse(match[1])
+		currency, currencyErr := context.currencyParser.Parse(valueString)
 		if currencyErr != nil {
 			return invalidState, NewPollingSyntaxError(currencyErr, "Can't parse money value")
 		}
@@ -744,6 +1710,18 @@ func (parser *PollCollectionParser) handleOptionState(line string, context *pars
 		}
 		// add a new skeleton
 		skeleton := NewMoneyPollSkeleton(context.lastPollName, currency)
+		if rangeString != "" {
+			minValue, step, rangeErr := parseMoneyRange(rangeString, context.currencyParser)
+			if rangeErr != nil {
+				return invalidState, rangeErr
+			}
+			if minErr := checkMoneyRangeMin(minValue, currency); minErr != nil {
+				return invalidState, minErr
+			}
+			skeleton.MinValue, skeleton.Step = minValue, step
+		}
+		skeleton.PollAnnotations = context.lastPollAnnotations
+		skeleton.Meta = context.lastPollMeta
 		group.Skeletons = append(group.Skeletons, skeleton)
 		context.numSkels++
 		if numPollErr := parser.validateNumPolls(context.numSkels); numPollErr != nil {
@@ -755,6 +1733,67 @@ func (parser *PollCollectionParser) handleOptionState(line string, context *pars
 	}
 }
 
+// parseMoneyRange parses the restrictions of a money poll (without the brackets), for example
+// "min 500,00 €, step 50,00 €". Each restriction can appear at most once.
+func parseMoneyRange(s string, currencyParser CurrencyParser) (minValue, step CurrencyValue, err error) {
+	matches := moneyRangeItemRx.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 || matches[0][0] != 0 {
+		err = NewPollingSyntaxError(nil, "invalid restriction \"%s\" for money poll, expected \"min <value>\" and / or "+
+			"\"step <value>\"", s)
+		return
+	}
+	seen := make(map[string]struct{}, 2)
+	for i, match := range matches {
+		key := s[match[2]:match[3]]
+		end := len(s)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		if _, has := seen[key]; has {
+			err = NewPollingSemanticError(nil, "duplicate restriction \"%s\" for money poll", key)
+			return
+		}
+		seen[key] = struct{}{}
+		value, parseErr := parseMoneyRangeValue(key, strings.TrimSpace(s[match[1]:end]), currencyParser)
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+		if key == "min" {
+			minValue = value
+		} else {
+			step = value
+		}
+	}
+	return
+}
+
+// parseMoneyRangeValue parses the value of the restriction key ("min" or "step") of a money poll.
+// The value must not be negative and a step must be > 0.
+func parseMoneyRangeValue(key, s string, currencyParser CurrencyParser) (CurrencyValue, error) {
+	value, parseErr := currencyParser.Parse(s)
+	if parseErr != nil {
+		return value, NewPollingSyntaxError(parseErr, "Can't parse %s value of money poll", key)
+	}
+	if value.ValueCents < 0 {
+		return value, NewPollingSemanticError(nil, "%s value of money poll must not be negative, got %d",
+			key, value.ValueCents)
+	}
+	if key == "step" && value.ValueCents == 0 {
+		return value, NewPollingSemanticError(nil, "step of money poll must be > 0")
+	}
+	return value, nil
+}
+
+// checkMoneyRangeMin returns a PollingSemanticError if the min value of a money poll is greater than its value.
+func checkMoneyRangeMin(minValue, value CurrencyValue) error {
+	if minValue.ValueCents > value.ValueCents {
+		return NewPollingSemanticError(nil, "min value (%d) of money poll is greater than the value of the poll (%d)",
+			minValue.ValueCents, value.ValueCents)
+	}
+	return nil
+}
+
 func (parser *PollCollectionParser) handleGroupOrPollState(line string, context *parserContext) (parserState, error) {
 	// first try group, if this fails (err != nil) try poll state
 	// note that these methods don't change the context if err != nil, so this is fine
@@ -792,12 +1831,29 @@ func (parser *PollCollectionParser) handleOptionalOptionState(line string, conte
 	if len(match) > 0 {
 		// just append to last poll
 		poll := context.getLastPollGroup().getLastPoll()
-		poll.Options = append(poll.Options, match[1])
+		poll.AddOption(parser.splitOptionDescription(match[1]))
 		if validateOptionErr := parser.validateNewOption(poll.Options); validateOptionErr != nil {
 			return invalidState, validateOptionErr
 		}
 		return optionalOptionState, nil
 	}
+	// an indented line that doesn't start a group or poll describes the last option
+	if context.indented && !groupLineRx.MatchString(line) && !pollLineRx.MatchString(line) {
+		poll := context.getLastPollGroup().getLastPoll()
+		lastIndex := len(poll.Options) - 1
+		description := line
+		if previous := poll.Description(lastIndex); previous != "" {
+			description = previous + " " + description
+		}
+		poll.SetDescription(lastIndex, description)
+		return optionalOptionState, nil
+	}
+	// now the last poll is complete, test the number of options before anything else so that the first error in the
+	// document is returned
+	// if errors are collected the error is recorded and the new group / poll is parsed anyway
+	if numOptionsErr := parser.validateNumOptions(context); numOptionsErr != nil && !context.recordError(numOptionsErr, context.lastPollLineNum) {
+		return invalidState, numOptionsErr
+	}
 	// now it must be group or new poll
 	handleRes, handleErr := parser.handleGroupOrPollState(line, context)
 	if handleErr == nil {
@@ -813,3 +1869,15 @@ func (parser *PollCollectionParser) handleOptionalOptionState(line string, conte
 	}
 	return invalidState, NewPollingSyntaxError(nil, "expected either poll option, group or poll")
 }
+
+// handleRecoverState is used after an error if errors are collected, it skips all lines until a group or poll is
+// found. A poll is only parsed if there is a group it can be added to.
+func (parser *PollCollectionParser) handleRecoverState(line string, context *parserContext) (parserState, error) {
+	if groupLineRx.MatchString(line) {
+		return parser.handleGroupState(line, context)
+	}
+	if pollLineRx.MatchString(line) && len(context.Groups) > 0 {
+		return parser.handlePollState(line, context)
+	}
+	return recoverState, nil
+}
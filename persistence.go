@@ -0,0 +1,198 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// voteRecord is the representation of a single vote in the format written by DumpVotes.
+//
+// Depending on Type exactly one of the payload fields Choice, Value, Ranking or Scores is set, Abstain is only set
+// for median votes of voters that abstained.
+// Scores are stored as ints because a []uint8 would be encoded as a base64 string.
+type voteRecord struct {
+	Poll    string         `json:"poll"`
+	Voter   string         `json:"voter"`
+	Type    string         `json:"type"`
+	Choice  string         `json:"choice,omitempty"`
+	Value   *MedianUnit    `json:"value,omitempty"`
+	Abstain bool           `json:"abstain,omitempty"`
+	Ranking SchulzeRanking `json:"ranking,omitempty"`
+	Scores  []int          `json:"scores,omitempty"`
+}
+
+// maxScoreValue is the largest score that can be stored in a ScoreVote.
+const maxScoreValue = int(^uint8(0))
+
+// voteTypeForPoll maps the poll types implemented in this package to the vote types they accept.
+var voteTypeForPoll = map[string]string{
+	BasicPollType:   BasicVoteType,
+	MedianPollType:  MedianVoteType,
+	SchulzePollType: SchulzeVoteType,
+	ScorePollType:   ScoreVoteType,
+}
+
+func newVoteRecord(pollName string, vote AbstractVote) (*voteRecord, error) {
+	res := &voteRecord{
+		Poll:  pollName,
+		Voter: vote.GetVoter().Name,
+		Type:  vote.VoteType(),
+	}
+	switch typedVote := vote.(type) {
+	case *BasicVote:
+		if !typedVote.Choice.IsValid() {
+			return nil, NewPollingSemanticError(nil, "can't dump invalid choice %d of voter \"%s\" in poll \"%s\"",
+				typedVote.Choice, res.Voter, pollName)
+		}
+		res.Choice = typedVote.Choice.String()
+	case *MedianVote:
+		value := typedVote.Value
+		res.Value = &value
+		res.Abstain = typedVote.Abstain
+	case *SchulzeVote:
+		res.Ranking = typedVote.Ranking
+	case *ScoreVote:
+		res.Scores = make([]int, len(typedVote.Scores))
+		for i, score := range typedVote.Scores {
+			res.Scores[i] = int(score)
+		}
+	default:
+		return nil, NewPollTypeError("can't dump vote of type %s", reflect.TypeOf(vote))
+	}
+	return res, nil
+}
+
+func (record *voteRecord) toVote(voter *Voter) (AbstractVote, error) {
+	switch record.Type {
+	case BasicVoteType:
+		choice, choiceErr := ParseBasicPollAnswer(record.Choice)
+		if choiceErr != nil {
+			return nil, choiceErr
+		}
+		return NewBasicVote(voter, choice), nil
+	case MedianVoteType:
+		if record.Value == nil {
+			return nil, NewPollingSemanticError(nil, "median vote for voter \"%s\" in poll \"%s\" has no value",
+				record.Voter, record.Poll)
+		}
+		if record.Abstain {
+			return NewMedianAbstention(voter), nil
+		}
+		return NewMedianVote(voter, *record.Value), nil
+	case SchulzeVoteType:
+		ranking := record.Ranking
+		if ranking == nil {
+			ranking = NewSchulzeRanking()
+		}
+		return NewSchulzeVote(voter, ranking), nil
+	case ScoreVoteType:
+		scores := make([]uint8, len(record.Scores))
+		for i, score := range record.Scores {
+			if score < 0 || score > maxScoreValue {
+				return nil, NewPollingSemanticError(nil, "invalid score %d for voter \"%s\" in poll \"%s\"",
+					score, record.Voter, record.Poll)
+			}
+			scores[i] = uint8(score)
+		}
+		return NewScoreVote(voter, scores), nil
+	default:
+		return nil, NewPollingSemanticError(nil, "unknown vote type \"%s\" for voter \"%s\" in poll \"%s\"",
+			record.Type, record.Voter, record.Poll)
+	}
+}
+
+// DumpVotes writes all votes of all polls to w, they can be read again with LoadVotes.
+//
+// Each vote is written as a JSON object in a single line, containing the poll name, the voter name, the vote type and
+// the type specific payload ("choice" for basic votes, "value" for median votes, "ranking" for Schulze votes and
+// "scores" for score votes).
+// Median abstentions additionally contain "abstain": true.
+// The polls are written sorted by name, the votes of a poll in the order in which they appear in the poll.
+//
+// Only the poll types implemented in this package are supported, for all other types a PollTypeError is returned.
+// Basic votes with an invalid choice are not written, in this case a PollingSemanticError is returned.
+// It also returns any error writing to w.
+func DumpVotes(w io.Writer, polls PollMap) error {
+	names := polls.SortedNames()
+
+	encoder := json.NewEncoder(w)
+	for _, name := range names {
+		votes, votesErr := CollectVotes(polls[name])
+		if votesErr != nil {
+			return votesErr
+		}
+		for _, vote := range votes {
+			record, recordErr := newVoteRecord(name, vote)
+			if recordErr != nil {
+				return recordErr
+			}
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadVotes reads votes as written by DumpVotes and adds them to the polls.
+//
+// The voters are resolved by name against voters and the polls by name against polls.
+// If a voter or poll is unknown or the vote type doesn't match the poll type a PollingSemanticError is returned.
+// If the input is not well-formed a PollingSyntaxError is returned.
+// All errors from AddVote are returned too.
+//
+// Note that, as for FillPollsWithVotes, some polls might already contain votes if an error is returned.
+func LoadVotes(r io.Reader, polls PollMap, voters VoterMap) error {
+	decoder := json.NewDecoder(r)
+	recordNum := 0
+	for {
+		recordNum++
+		var record voteRecord
+		decodeErr := decoder.Decode(&record)
+		if decodeErr == io.EOF {
+			return nil
+		}
+		if decodeErr != nil {
+			switch decodeErr.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				return NewPollingSyntaxError(decodeErr, "invalid vote record (record number %d)", recordNum)
+			default:
+				return decodeErr
+			}
+		}
+		poll, hasPoll := polls[record.Poll]
+		if !hasPoll {
+			return NewPollingSemanticError(nil, "poll \"%s\" not found in allowed polls", record.Poll)
+		}
+		voter, hasVoter := voters[record.Voter]
+		if !hasVoter {
+			return NewPollingSemanticError(nil, "voter \"%s\" not found in allowed voters", record.Voter)
+		}
+		if expected, known := voteTypeForPoll[poll.PollType()]; known && expected != record.Type {
+			return NewPollingSemanticError(nil, "vote of type \"%s\" can't be added to poll \"%s\" of type \"%s\"",
+				record.Type, record.Poll, poll.PollType())
+		}
+		vote, voteErr := record.toVote(voter)
+		if voteErr != nil {
+			return voteErr
+		}
+		if addErr := poll.AddVote(vote); addErr != nil {
+			return addErr
+		}
+	}
+}
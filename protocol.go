@@ -0,0 +1,201 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// protocolWriter is used to write the protocol, it remembers the first error that occurred so that not every single
+// write must be checked.
+type protocolWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *protocolWriter) printf(format string, a ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	_, pw.err = fmt.Fprintf(pw.w, format, a...)
+}
+
+// WriteProtocol writes a protocol of an evaluation as markdown to w.
+//
+// The protocol mirrors the input format of the collection (see PollSkeletonCollection.Dump): It contains the title,
+// all groups and all polls with their options. After each poll a result section is written, the result is looked up
+// by the name of the poll in results:
+// For a *BasicPollResult the (weighted) ayes, noes and abstentions are written together with their percentage.
+// For a *MedianResult the accepted value is written, formatted with currencyFormatter.
+// For a *SchulzeResult the ranked groups are written, using the option names from the skeleton. Options of the same
+// group that are ordered by the secondary order of the result (see SchulzeResult.RankingRules) are listed together
+// with the rule.
+// For a *ScoreResult the ranked groups are written with the score sum and average of each option, followed by the
+// runoff (if computed, see ScorePoll.TallyWithRunoff).
+// Invalid Schulze and score votes, median abstentions and truncated median votes are written if there are any.
+//
+// polls is used to write the number of votes for each poll (only for the poll types implemented in this package).
+// If there is no poll or no result for a skeleton this is written to the protocol, so an incomplete protocol can be
+// spotted easily. The same holds for a result with its Err set (for example because the weights overflow), the
+// error is written instead of the counters.
+//
+// If a result has an unsupported type a PollTypeError is returned, it also returns any error writing to w.
+func WriteProtocol(w io.Writer, coll *PollSkeletonCollection, polls PollMap, results map[string]AbstractPollResult,
+	currencyFormatter CurrencyFormatter) error {
+	pw := &protocolWriter{w: w}
+	pw.printf("# %s\n\n", coll.Title)
+	for _, group := range coll.Groups {
+		pw.printf("## %s\n\n", group.Title)
+		for _, skel := range group.Skeletons {
+			if pw.err != nil {
+				return pw.err
+			}
+			if _, dumpErr := DumpAbstractPollSkeleton(skel, w, currencyFormatter); dumpErr != nil {
+				return dumpErr
+			}
+			if resultErr := writeProtocolResult(pw, skel, polls, results, currencyFormatter); resultErr != nil {
+				return resultErr
+			}
+		}
+	}
+	return pw.err
+}
+
+// protocolOptionName returns the name of the option or "option <i>" if there is no name for the option.
+func protocolOptionName(options []string, option int) string {
+	if option < len(options) {
+		return options[option]
+	}
+	return fmt.Sprintf("option %d", option+1)
+}
+
+// protocolResultError returns the Err field of the result (nil for unknown result types).
+func protocolResultError(result AbstractPollResult) error {
+	switch typedResult := result.(type) {
+	case *BasicPollResult:
+		return typedResult.Err
+	case *MedianResult:
+		return typedResult.Err
+	case *SchulzeResult:
+		return typedResult.Err
+	case *ScoreResult:
+		return typedResult.Err
+	default:
+		return nil
+	}
+}
+
+func writeProtocolResult(pw *protocolWriter, skel AbstractPollSkeleton, polls PollMap,
+	results map[string]AbstractPollResult, currencyFormatter CurrencyFormatter) error {
+	name := skel.GetName()
+	pw.printf("**Result**\n\n")
+	poll, hasPoll := polls[name]
+	if !hasPoll {
+		pw.printf("*No poll found for \"%s\"*\n\n", name)
+		return nil
+	}
+	if votes, votesErr := CollectVotes(poll); votesErr == nil {
+		pw.printf("- Votes: %d\n", len(votes))
+	}
+	result, hasResult := results[name]
+	if !hasResult || result == nil {
+		pw.printf("\n*No result available for \"%s\"*\n\n", name)
+		return nil
+	}
+	if resultErr := protocolResultError(result); resultErr != nil {
+		pw.printf("\n*Result for \"%s\" could not be computed: %s*\n\n", name, resultErr)
+		return nil
+	}
+	switch typedResult := result.(type) {
+	case *BasicPollResult:
+		votes := typedResult.WeightedVotes
+		sum := typedResult.VotesSum
+		pw.printf("- Ayes: %d (%s %%)\n", votes.NumAyes, FormatPercentage(ComputePercentage(votes.NumAyes, sum)))
+		pw.printf("- Noes: %d (%s %%)\n", votes.NumNoes, FormatPercentage(ComputePercentage(votes.NumNoes, sum)))
+		pw.printf("- Abstentions: %d (%s %%)\n", votes.NumAbstention,
+			FormatPercentage(ComputePercentage(votes.NumAbstention, sum)))
+	case *MedianResult:
+		pw.printf("- Required majority: %d of %d\n", typedResult.RequiredMajority, typedResult.WeightSum)
+		if typedResult.MajorityValue == NoMedianUnitValue {
+			pw.printf("- Accepted value: none\n")
+		} else {
+			currency := ""
+			if moneySkel, ok := skel.(*MoneyPollSkeleton); ok {
+				currency = moneySkel.Value.Currency
+			}
+			value := NewCurrencyValue(int(typedResult.MajorityValue), currency)
+			pw.printf("- Accepted value: %s\n", currencyFormatter.Format(value))
+		}
+		if typedResult.AbstentionWeight > 0 {
+			pw.printf("- Abstentions: weight %d\n", typedResult.AbstentionWeight)
+		}
+		if typedResult.TruncatedCount > 0 {
+			pw.printf("- Truncated votes: %d (weight %d)\n", typedResult.TruncatedCount, typedResult.TruncatedWeight)
+		}
+	case *SchulzeResult:
+		var options []string
+		if pollSkel, ok := skel.(*PollSkeleton); ok {
+			options = pollSkel.Options
+		}
+		for i, group := range typedResult.RankedGroups {
+			names := make([]string, len(group))
+			for j, option := range group {
+				names[j] = protocolOptionName(options, option)
+			}
+			pw.printf("- Rank %d: %s\n", i+1, strings.Join(names, ", "))
+		}
+		for i, rule := range typedResult.RankingRules {
+			if rule == SchulzeRankedBySecondaryOrder {
+				pw.printf("- %s before %s by %s\n", protocolOptionName(options, typedResult.Ranking[i]),
+					protocolOptionName(options, typedResult.Ranking[i+1]), typedResult.SecondaryOrder)
+			}
+		}
+		if typedResult.InvalidVotesCount > 0 {
+			pw.printf("- Invalid votes: %d (weight %d)\n", typedResult.InvalidVotesCount, typedResult.InvalidWeight)
+		}
+	case *ScoreResult:
+		var options []string
+		if pollSkel, ok := skel.(*PollSkeleton); ok {
+			options = pollSkel.Options
+		}
+		for i, group := range typedResult.RankedGroups {
+			names := make([]string, len(group))
+			for j, option := range group {
+				names[j] = fmt.Sprintf("%s (score %d, average %s)", protocolOptionName(options, option),
+					typedResult.ScoreSums[option], typedResult.Averages[option].FloatString(2))
+			}
+			pw.printf("- Rank %d: %s\n", i+1, strings.Join(names, ", "))
+		}
+		if runoff := typedResult.Runoff; runoff != nil {
+			winner := "none"
+			if runoff.Winner >= 0 {
+				winner = protocolOptionName(options, runoff.Winner)
+			}
+			pw.printf("- Runoff: %s %d, %s %d, no preference %d, winner %s\n",
+				protocolOptionName(options, runoff.First), runoff.PreferFirst,
+				protocolOptionName(options, runoff.Second), runoff.PreferSecond, runoff.NoPreference, winner)
+		}
+		if typedResult.InvalidVotesCount > 0 {
+			pw.printf("- Invalid votes: %d (weight %d)\n", typedResult.InvalidVotesCount, typedResult.InvalidWeight)
+		}
+	default:
+		return NewPollTypeError("can't write result of type %s for poll \"%s\"", reflect.TypeOf(result), name)
+	}
+	pw.printf("\n")
+	return pw.err
+}
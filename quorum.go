@@ -0,0 +1,183 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"math/big"
+	"reflect"
+)
+
+// Quorum describes the fraction of the eligible weight that must participate in a poll for the poll to be valid.
+//
+// The fraction should be a value 0 <= fraction <= 1, for example 1/2 if at least half of the eligible weight must
+// participate. The same constants as for majorities (FiftyPercentMajority, TwoThirdsMajority) can be used.
+type Quorum struct {
+	Fraction *big.Rat
+}
+
+// NewQuorum returns a new Quorum given the fraction.
+//
+// If fraction is nil or not a value 0 <= fraction <= 1 a PollingSemanticError is returned.
+func NewQuorum(fraction *big.Rat) (*Quorum, error) {
+	quorum := &Quorum{
+		Fraction: fraction,
+	}
+	if err := quorum.Validate(); err != nil {
+		return nil, err
+	}
+	return quorum, nil
+}
+
+// Validate returns a PollingSemanticError if the fraction of the quorum is nil or not a value 0 <= fraction <= 1.
+func (quorum *Quorum) Validate() error {
+	if quorum.Fraction == nil {
+		return NewPollingSemanticError(nil, "fraction of quorum must not be nil")
+	}
+	if quorum.Fraction.Sign() < 0 || quorum.Fraction.Cmp(big.NewRat(1, 1)) > 0 {
+		return NewPollingSemanticError(nil, "fraction of quorum must be between 0 and 1, got %s",
+			quorum.Fraction.RatString())
+	}
+	return nil
+}
+
+// RequiredWeight computes the weight that must participate given the total eligible weight.
+//
+// In contrast to ComputeMajority the quorum must be reached, not exceeded: The value is rounded up and a poll
+// reaches the quorum if the participating weight is >= (not strictly greater) the returned weight.
+// For example a quorum of 1/2 and an eligible weight of 11 returns 6, a quorum of 1/2 and an eligible weight of 10
+// returns 5.
+// The fraction must be valid (see Validate), NewQuorum and Check ensure this.
+func (quorum *Quorum) RequiredWeight(eligible Weight) Weight {
+	required := big.NewRat(int64(eligible), 1)
+	required.Mul(required, quorum.Fraction)
+	num := required.Num()
+	denom := required.Denom()
+	// compute ceil(num / denom), num is >= 0 so we can just check the remainder
+	div, mod := new(big.Int), new(big.Int)
+	div.DivMod(num, denom, mod)
+	if mod.Sign() != 0 {
+		div.Add(div, big.NewInt(1))
+	}
+	// quorum <= 1 ==> should be possible to represent as uint32 (Weight)
+	return Weight(div.Int64())
+}
+
+// Check tests if the quorum is reached for a poll, see CheckQuorum for details.
+// If the fraction of the quorum is invalid (see Validate) a PollingSemanticError is returned.
+func (quorum *Quorum) Check(poll AbstractPoll, eligible Weight, countAbstentions bool) (QuorumResult, error) {
+	if err := quorum.Validate(); err != nil {
+		return QuorumResult{}, err
+	}
+	participating, err := ParticipatingWeight(poll, countAbstentions)
+	if err != nil {
+		return QuorumResult{}, err
+	}
+	required := quorum.RequiredWeight(eligible)
+	res := QuorumResult{
+		EligibleWeight:      eligible,
+		ParticipatingWeight: participating,
+		RequiredWeight:      required,
+		Met:                 participating >= required,
+	}
+	return res, nil
+}
+
+// QuorumResult is the result of checking a quorum for a poll.
+//
+// EligibleWeight is the total weight of all voters that were allowed to vote, ParticipatingWeight the weight of all
+// voters that actually participated and RequiredWeight the weight that had to participate.
+// Met is true if ParticipatingWeight >= RequiredWeight.
+type QuorumResult struct {
+	EligibleWeight      Weight
+	ParticipatingWeight Weight
+	RequiredWeight      Weight
+	Met                 bool
+}
+
+// Percentage returns the participation as a fraction of the eligible weight, see ComputePercentage.
+func (res QuorumResult) Percentage() *big.Rat {
+	return ComputePercentage(res.ParticipatingWeight, res.EligibleWeight)
+}
+
+// CheckQuorum tests if the weight of all voters that participated in a poll reaches the quorum, quorum is the
+// fraction of eligible (the sum of the weights of all voters that were allowed to vote) that must participate.
+//
+// Which votes count as participating depends on the poll type, see ParticipatingWeight.
+// If countAbstentions is false votes that describe an abstention are not counted as participating.
+//
+// It returns a PollTypeError if the poll type is not supported and a PollingSemanticError if quorum is not a value
+// 0 <= quorum <= 1.
+func CheckQuorum(poll AbstractPoll, eligible Weight, quorum *big.Rat, countAbstentions bool) (QuorumResult, error) {
+	return (&Quorum{Fraction: quorum}).Check(poll, eligible, countAbstentions)
+}
+
+// ParticipatingWeight computes the sum of the weights of all voters that participated in a poll.
+//
+// Empty votes that were ignored (see EmptyVotePolicy) don't appear in the poll and are thus never counted.
+// The following rules are applied for the different poll types:
+//
+// For a BasicPoll each vote with a valid choice is counted, abstentions only if countAbstentions is true.
+//
+// For a MedianPoll each vote is counted, median polls have no concept of abstention.
+//
+// For a SchulzePoll each vote with a ranking of length NumOptions is counted, votes with a ranking that ranks all
+// options equally (see SchulzeRanking.IsAbstention) are considered abstentions and are only counted if
+// countAbstentions is true.
+//
+// For a ScorePoll each valid vote (NumOptions scores, each <= MaxScore) is counted, votes with only zero scores (see
+// ScoreVote.IsAbstention) are abstentions.
+//
+// It returns a PollTypeError for all other poll types and an error wrapping ErrWeightOverflow if the sum overflows.
+func ParticipatingWeight(poll AbstractPoll, countAbstentions bool) (Weight, error) {
+	var res Weight
+	switch typedPoll := poll.(type) {
+	case *BasicPoll:
+		for _, vote := range typedPoll.Votes {
+			if !vote.Choice.IsValid() || (!countAbstentions && vote.Choice == Abstention) {
+				continue
+			}
+			var weightErr error
+			if res, weightErr = addEffectiveWeight(res, vote.Voter); weightErr != nil {
+				return NoWeight, weightErr
+			}
+		}
+	case *MedianPoll:
+		return typedPoll.CheckedWeightSum()
+	case *SchulzePoll:
+		for _, vote := range typedPoll.Votes {
+			if len(vote.Ranking) != typedPoll.NumOptions || (!countAbstentions && vote.Ranking.IsAbstention()) {
+				continue
+			}
+			var weightErr error
+			if res, weightErr = addEffectiveWeight(res, vote.Voter); weightErr != nil {
+				return NoWeight, weightErr
+			}
+		}
+	case *ScorePoll:
+		for _, vote := range typedPoll.Votes {
+			if typedPoll.validScores("", vote.Scores) != nil || (!countAbstentions && vote.IsAbstention()) {
+				continue
+			}
+			var weightErr error
+			if res, weightErr = addEffectiveWeight(res, vote.Voter); weightErr != nil {
+				return NoWeight, weightErr
+			}
+		}
+	default:
+		return NoWeight, NewPollTypeError("can't compute participating weight for poll of type %s",
+			reflect.TypeOf(poll))
+	}
+	return res, nil
+}
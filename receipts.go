@@ -0,0 +1,144 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ReceiptEntry describes how the vote of a voter for a single poll was recorded, see GenerateVoterReceipts.
+//
+// Vote is a human-readable representation of the vote. HasVoted is false if the voter didn't vote for the poll, in
+// this case Vote is NoVoteReceiptString.
+type ReceiptEntry struct {
+	Poll     string
+	Vote     string
+	HasVoted bool
+}
+
+// NoVoteReceiptString is the vote string of a ReceiptEntry for a poll the voter didn't vote for.
+const NoVoteReceiptString = "no vote"
+
+// ReceiptOption is an option for GenerateVoterReceipts, see for example WithNoVoteEntries.
+type ReceiptOption func(options *receiptOptions)
+
+type receiptOptions struct {
+	noVoteEntries bool
+}
+
+// WithNoVoteEntries adds an entry (with HasVoted set to false) for each poll a voter didn't vote for.
+func WithNoVoteEntries() ReceiptOption {
+	return func(options *receiptOptions) {
+		options.noVoteEntries = true
+	}
+}
+
+// GenerateVoterReceipts returns for each voter a list of the votes that were recorded for this voter, this can be used
+// to send a confirmation to each voter.
+//
+// The result maps the voter name to the entries, the entries are in the order of the polls in coll (group by group),
+// the poll for a skeleton is looked up by name in polls.
+// The vote strings are: the choice for a BasicVote (see BasicPollAnswer.String), the value formatted with formatter
+// for a MedianVote (with the currency of the MoneyPollSkeleton, "abstention" if the voter abstained) and the ranking with the option names of the
+// PollSkeleton for a SchulzeVote (see SchulzeRanking.FormatWithOptions).
+// If the raw string a vote was parsed from is stored in the vote (see VoteSource) this string is used instead, unless
+// it contains only whitespace (for example for votes generated by an EmptyVotePolicy).
+//
+// The voters are all voters that voted for at least one of the polls. By default a voter only gets entries for the
+// polls they voted for, with WithNoVoteEntries an entry with NoVoteReceiptString is added for all other polls.
+//
+// If there is no poll for a skeleton or the options of a skeleton don't match a Schulze ranking a
+// PollingSemanticError is returned, if a poll or vote has an unsupported type a PollTypeError is returned.
+func GenerateVoterReceipts(polls PollMap, coll *PollSkeletonCollection, formatter CurrencyFormatter,
+	options ...ReceiptOption) (map[string][]ReceiptEntry, error) {
+	var receiptOpts receiptOptions
+	for _, option := range options {
+		option(&receiptOpts)
+	}
+
+	skels := coll.CollectSkeletons()
+	// votes of each poll (in the order of skels), maps voter name to the vote string
+	votesPerPoll := make([]map[string]string, len(skels))
+	res := make(map[string][]ReceiptEntry)
+	for i, skel := range skels {
+		name := skel.GetName()
+		poll, hasPoll := polls[name]
+		if !hasPoll {
+			return nil, NewPollingSemanticError(nil, "no poll found for skeleton \"%s\"", name)
+		}
+		votes, votesErr := CollectVotes(poll)
+		if votesErr != nil {
+			return nil, votesErr
+		}
+		pollVotes := make(map[string]string, len(votes))
+		for _, vote := range votes {
+			voteString, formatErr := formatReceiptVote(vote, skel, formatter)
+			if formatErr != nil {
+				return nil, formatErr
+			}
+			voterName := vote.GetVoter().Name
+			pollVotes[voterName] = voteString
+			if _, has := res[voterName]; !has {
+				res[voterName] = make([]ReceiptEntry, 0, len(skels))
+			}
+		}
+		votesPerPoll[i] = pollVotes
+	}
+
+	for voterName := range res {
+		entries := res[voterName]
+		for i, skel := range skels {
+			voteString, hasVoted := votesPerPoll[i][voterName]
+			switch {
+			case hasVoted:
+				entries = append(entries, ReceiptEntry{Poll: skel.GetName(), Vote: voteString, HasVoted: true})
+			case receiptOpts.noVoteEntries:
+				entries = append(entries, ReceiptEntry{Poll: skel.GetName(), Vote: NoVoteReceiptString, HasVoted: false})
+			}
+		}
+		res[voterName] = entries
+	}
+	return res, nil
+}
+
+func formatReceiptVote(vote AbstractVote, skel AbstractPollSkeleton, formatter CurrencyFormatter) (string, error) {
+	if raw, hasRaw := RawVoteString(vote); hasRaw && strings.TrimSpace(raw) != "" {
+		return raw, nil
+	}
+	switch typedVote := vote.(type) {
+	case *BasicVote:
+		return typedVote.Choice.String(), nil
+	case *MedianVote:
+		if typedVote.Abstain {
+			return Abstention.String(), nil
+		}
+		currency := ""
+		if moneySkel, isMoneySkel := skel.(*MoneyPollSkeleton); isMoneySkel {
+			currency = moneySkel.Value.Currency
+		}
+		return formatter.Format(NewCurrencyValue(int(typedVote.Value), currency)), nil
+	case *SchulzeVote:
+		var options []string
+		if pollSkel, isPollSkel := skel.(*PollSkeleton); isPollSkel {
+			options = pollSkel.Options
+		}
+		return typedVote.Ranking.FormatWithOptions(options)
+	case *ScoreVote:
+		return formatScores(typedVote.Scores), nil
+	default:
+		return "", NewPollTypeError("can't generate receipt for vote of type %s", reflect.TypeOf(vote))
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // SchulzeMatrix is a matrix used to represent the matrices d and p.
@@ -54,6 +55,66 @@ func (m SchulzeMatrix) Equals(other SchulzeMatrix) bool {
 	return true
 }
 
+// SchulzeMarginMatrix is a matrix used to represent the margins d[i][j] - d[j][i].
+// Because Weight is unsigned the entries are stored as int64, thus there is no underflow.
+// It is assumed to be of dimension n × n.
+type SchulzeMarginMatrix [][]int64
+
+// NewSchulzeMarginMatrix computes the margin matrix from the matrix d, i.e. the entry m[i][j] is d[i][j] - d[j][i].
+func NewSchulzeMarginMatrix(d SchulzeMatrix) SchulzeMarginMatrix {
+	n := len(d)
+	res := make(SchulzeMarginMatrix, n)
+	for i := 0; i < n; i++ {
+		res[i] = make([]int64, n)
+		for j := 0; j < n; j++ {
+			res[i][j] = int64(d[i][j]) - int64(d[j][i])
+		}
+	}
+	return res
+}
+
+// Equals tests if two matrices are the same.
+// Note that this method (like all others) assume a matrix of size n × n.
+func (m SchulzeMarginMatrix) Equals(other SchulzeMarginMatrix) bool {
+	n1, n2 := len(m), len(other)
+	if n1 != n2 {
+		return false
+	}
+	n := n1
+	for i := 0; i < n; i++ {
+		row1, row2 := m[i], other[i]
+		for j := 0; j < n; j++ {
+			if row1[j] != row2[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SchulzeVariant describes how the strength of a link (and thus of a path) is defined in the Schulze method.
+//
+// SchulzeWinningVotes (the default) uses the number of voters (by weight) that prefer i to j, that is d[i][j], as the
+// strength of the link i → j (if d[i][j] > d[j][i]).
+// SchulzeMargins uses the margin d[i][j] - d[j][i] as the strength of the link i → j (if the margin is positive).
+type SchulzeVariant int8
+
+const (
+	SchulzeWinningVotes SchulzeVariant = iota
+	SchulzeMargins
+)
+
+func (variant SchulzeVariant) String() string {
+	switch variant {
+	case SchulzeWinningVotes:
+		return "winning votes"
+	case SchulzeMargins:
+		return "margins"
+	default:
+		return fmt.Sprintf("Unknown schulze variant %d", variant)
+	}
+}
+
 // SchulzeRanking is a ranking for a Schulze poll.
 //
 // The ranking must have one entry for each option of the poll.
@@ -79,9 +140,18 @@ func NewSchulzeAbstention(numOptions int) SchulzeRanking {
 // In this case it is assumed that the last option stands for no.
 // Thus the ranking returned is [1, 1, ..., 0].
 func NewSchulzeNo(numOptions int) SchulzeRanking {
+	return NewSchulzeNoAt(numOptions, numOptions-1)
+}
+
+// NewSchulzeNoAt works as NewSchulzeNo but the no option has the index noIndex, all other options are ranked 1 and
+// no is ranked 0.
+// If noIndex is not a valid index all options are ranked 1.
+func NewSchulzeNoAt(numOptions, noIndex int) SchulzeRanking {
 	res := make(SchulzeRanking, numOptions)
-	for i := 0; i < numOptions-1; i++ {
-		res[i] = 1
+	for i := range res {
+		if i != noIndex {
+			res[i] = 1
+		}
 	}
 	return res
 }
@@ -90,17 +160,36 @@ func NewSchulzeNo(numOptions int) SchulzeRanking {
 // meaning for every option with the same weight, except no.
 // Thus the ranking returned is [0, 0, ...,1].
 func NewSchulzeAye(numOptions int) SchulzeRanking {
+	return NewSchulzeAyeAt(numOptions, numOptions-1)
+}
+
+// NewSchulzeAyeAt works as NewSchulzeAye but the no option has the index noIndex, all other options are ranked 0 and
+// no is ranked 1.
+// If noIndex is not a valid index all options are ranked 0.
+func NewSchulzeAyeAt(numOptions, noIndex int) SchulzeRanking {
 	res := make(SchulzeRanking, numOptions)
 
-	noPos := numOptions - 1
 	// this should always be true, just to be sure
-	if noPos >= 0 {
-		res[noPos] = 1
+	if noIndex >= 0 && noIndex < numOptions {
+		res[noIndex] = 1
 	}
 
 	return res
 }
 
+// Equals tests if two rankings are the same.
+func (ranking SchulzeRanking) Equals(other SchulzeRanking) bool {
+	if len(ranking) != len(other) {
+		return false
+	}
+	for i, rank := range ranking {
+		if rank != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // IsAbstention returns true if all options are ranked with exactly the same number.
 func (ranking SchulzeRanking) IsAbstention() bool {
 	if len(ranking) == 0 {
@@ -116,32 +205,158 @@ func (ranking SchulzeRanking) IsAbstention() bool {
 	return true
 }
 
-// private because from outside the parser implementing the parser interface should be used
-func parseSchulzeRanking(s string, length int) (SchulzeRanking, error) {
-	split := strings.FieldsFunc(s, func(r rune) bool {
-		return r == ',' || r == '/'
+// FormatWithOptions formats the ranking with the option names, for example "B > A = C" for the ranking [1, 0, 1] and
+// options ["A", "B", "C"]. Options with the same rank are joined with "=", options with a higher rank (a smaller value)
+// come first. Options with the same rank appear in the order of options.
+//
+// The result can be parsed by a SchulzeVoteParser with the same options (see WithOptions).
+// If the number of options doesn't match the length of the ranking a PollingSemanticError is returned.
+func (ranking SchulzeRanking) FormatWithOptions(options []string) (string, error) {
+	if len(options) != len(ranking) {
+		return "", NewPollingSemanticError(nil, "can't format schulze ranking of length %d with %d options",
+			len(ranking), len(options))
+	}
+	indices := make([]int, len(ranking))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return ranking[indices[i]] < ranking[indices[j]]
 	})
-	if length >= 0 && len(split) != length {
-		return nil, NewPollingSemanticError(nil, "schulze ranking of length %d was expected, got length %d",
-			length, len(split))
+	var builder strings.Builder
+	for i, index := range indices {
+		if i > 0 {
+			if ranking[index] == ranking[indices[i-1]] {
+				builder.WriteString(" = ")
+			} else {
+				builder.WriteString(" > ")
+			}
+		}
+		builder.WriteString(options[index])
 	}
-	res := make(SchulzeRanking, len(split))
-	for i, asString := range split {
-		asString = strings.TrimSpace(asString)
-		asInt, intErr := strconv.Atoi(asString)
+	return builder.String(), nil
+}
+
+// isSchulzeRankingSeparator returns true for the runes that separate the values of a numeric ranking.
+func isSchulzeRankingSeparator(r rune) bool {
+	return r == ',' || r == '/'
+}
+
+// private because from outside the parser implementing the parser interface should be used
+//
+// The values are read one by one (instead of splitting s first), this way very long strings are rejected as soon as
+// more than maxLength (if >= 0) or length (if >= 0) values have been read.
+// If partial is true rankings with less than length values are allowed (they're not padded).
+func parseSchulzeRanking(s string, length, maxLength int, partial bool) (SchulzeRanking, error) {
+	capacity := 0
+	if length >= 0 {
+		capacity = length
+	}
+	res := make(SchulzeRanking, 0, capacity)
+	for {
+		start := strings.IndexFunc(s, func(r rune) bool {
+			return !isSchulzeRankingSeparator(r)
+		})
+		if start < 0 {
+			break
+		}
+		s = s[start:]
+		var asString string
+		if end := strings.IndexFunc(s, isSchulzeRankingSeparator); end < 0 {
+			asString, s = s, ""
+		} else {
+			asString, s = s[:end], s[end:]
+		}
+		if maxLength >= 0 && len(res) >= maxLength {
+			return nil, NewParserValidationError(
+				fmt.Sprintf("schulze ranking is too long: at most %d values are allowed", maxLength))
+		}
+		if length >= 0 && len(res) >= length {
+			return nil, NewPollingSemanticError(nil, "schulze ranking of length %d was expected, got more values",
+				length)
+		}
+		asInt, intErr := strconv.Atoi(strings.TrimSpace(asString))
 		if intErr != nil {
 			return nil, NewPollingSyntaxError(intErr, "can't parse schulze ranking, invalid ranking string")
 		}
-		res[i] = asInt
+		res = append(res, asInt)
+	}
+	if length >= 0 && len(res) != length && !partial {
+		return nil, NewPollingSemanticError(nil, "schulze ranking of length %d was expected, got length %d",
+			length, len(res))
+	}
+	return res, nil
+}
+
+// isNumericSchulzeRanking returns true if s contains only digits, whitespaces and the separators of the numeric
+// ranking syntax.
+func isNumericSchulzeRanking(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == ',', r == '/', r == '-', unicode.IsSpace(r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseNamedSchulzeRanking parses a ranking of the form "A > B = C", see SchulzeVoteParser.
+//
+// If partial is true options that don't appear in s are ranked with unranked, otherwise all options must appear.
+func parseNamedSchulzeRanking(s string, options []string, partial bool, unranked int) (SchulzeRanking, error) {
+	res := make(SchulzeRanking, len(options))
+	found := make([]bool, len(options))
+	for rank, group := range strings.Split(s, ">") {
+		for _, name := range strings.Split(group, "=") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return nil, NewPollingSyntaxError(nil, "can't parse schulze ranking, empty option name in \"%s\"", s)
+			}
+			index := -1
+			for i, option := range options {
+				if strings.EqualFold(name, option) {
+					if index >= 0 {
+						return nil, NewPollingSemanticError(nil, "option name \"%s\" is ambiguous", name)
+					}
+					index = i
+				}
+			}
+			if index < 0 {
+				return nil, NewPollingSemanticError(nil, "unknown option \"%s\" in schulze ranking", name)
+			}
+			if found[index] {
+				return nil, NewPollingSemanticError(nil, "option \"%s\" appears multiple times in schulze ranking",
+					options[index])
+			}
+			found[index] = true
+			res[index] = rank
+		}
+	}
+	var missing []string
+	for i, wasFound := range found {
+		if !wasFound {
+			if partial {
+				res[i] = unranked
+			} else {
+				missing = append(missing, options[i])
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, NewPollingSemanticError(nil, "schulze ranking must contain all options, missing: %s",
+			strings.Join(missing, ", "))
 	}
 	return res, nil
 }
 
 // SchulzeVote is a vote for a SchulzePoll.
 // It is described by the voter and the ranking of said voter. It implements the interface AbstractVote.
+// The source of the vote is stored in the embedded VoteSourceHolder, see SourcedVote.
 type SchulzeVote struct {
 	Voter   *Voter
 	Ranking SchulzeRanking
+	VoteSourceHolder
 }
 
 // NewSchulzeVote returns a new SchulzeVote.
@@ -161,29 +376,115 @@ func NewSchulzeVote(voter *Voter, ranking SchulzeRanking) *SchulzeVote {
 // It allows to set the length that is expected from the ranking string. If the string describes a ranking
 // not equal to length an error is returned.
 //
+// If Options is set (see WithOptions) the ranking can also be written with the option names and comparison signs,
+// for example "Budget B > Budget A = No": Budget B is ranked highest, Budget A and No are ranked equally.
+// Names are matched case-insensitively and each option must appear exactly once, otherwise a PollingSemanticError is
+// returned. Option names must therefore not contain ">" or "=".
+// Strings that contain only digits and separators are always parsed with the numeric syntax.
+//
+// The values in a ranking can be restricted: If HasBounds is true each value must be >= MinValue and <= MaxValue
+// (WithBounds sets all three fields). NewSchulzeVoteParser sets HasBounds to false (this is also the zero value), so
+// all values are allowed, MinValue and MaxValue are set to the smallest and largest int.
+// If RequirePermutationLike is true each value must also be in [0, n) where n is the length of the ranking, this
+// makes rankings easier to audit. Violations are reported with a PollingSemanticError.
+//
+// MaxRankingLength is the maximal number of values in a numeric ranking, it is checked while the string is read, so
+// even if the length check is disabled a very long string is rejected early (with a ParserValidationError) instead of
+// being parsed completely. NewSchulzeVoteParser sets it to DefaultMaxRankingLength, if it is 0
+// DefaultMaxRankingLength is used as well. A negative value disables the limit.
+//
+// If AllowPartial is true (and Length >= 0) a ranking may contain less than Length values (or not all options if
+// the named syntax is used), for example if voters rank only their top three options. The unranked options are set
+// to UnrankedValue, thus they're tied with each other and ranked below all options that have been ranked explicitly.
+// Explicit values must therefore be <= UnrankedValue, the value itself can be used to mark an option as unranked.
+// The bounds (and RequirePermutationLike with n being the number of explicit values) are checked before the ranking
+// is padded and are not checked for UnrankedValue. NewSchulzeVoteParser sets AllowPartial to false and UnrankedValue
+// to SchulzeUnranked.
+//
+// If KeepRaw is true the parsed string is stored as the source of each vote, see VoteSource.
+//
 // It also implements ParserCustomizer.
 type SchulzeVoteParser struct {
-	Length int
+	Length                 int
+	Options                []string
+	MinValue               int
+	MaxValue               int
+	HasBounds              bool
+	RequirePermutationLike bool
+	MaxRankingLength       int
+	AllowPartial           bool
+	UnrankedValue          int
+	KeepRaw                bool
 }
 
+// DefaultMaxRankingLength is the default value of SchulzeVoteParser.MaxRankingLength.
+const DefaultMaxRankingLength = 1024
+
+// SchulzeUnranked is the default value of SchulzeVoteParser.UnrankedValue, it is the largest int and thus ranks an
+// option below every other value.
+const SchulzeUnranked = maxInt
+
 // NewSchulzeVoteParser returns a new SchulzeVoteParser.
 //
 // The length argument is allowed to be negative in which case the length check is disabled.
 // Set it to a length >= 0 to enable the check or use WithLength.
+// The values are not restricted, see WithBounds and RequirePermutationLike.
 func NewSchulzeVoteParser(length int) *SchulzeVoteParser {
-	return &SchulzeVoteParser{Length: length}
+	return &SchulzeVoteParser{
+		Length:                 length,
+		MinValue:               minInt,
+		MaxValue:               maxInt,
+		HasBounds:              false,
+		RequirePermutationLike: false,
+		MaxRankingLength:       DefaultMaxRankingLength,
+		AllowPartial:           false,
+		UnrankedValue:          SchulzeUnranked,
+		KeepRaw:                false,
+	}
 }
 
 // WithLength returns a shallow copy of the parser with only length set to the new value.
 func (parser *SchulzeVoteParser) WithLength(length int) *SchulzeVoteParser {
-	return &SchulzeVoteParser{Length: length}
+	res := *parser
+	res.Length = length
+	return &res
+}
+
+// WithOptions returns a shallow copy of the parser with the option names set to options, this enables the named
+// ranking syntax. Length is set to the number of options.
+func (parser *SchulzeVoteParser) WithOptions(options []string) *SchulzeVoteParser {
+	res := *parser
+	res.Length = len(options)
+	res.Options = options
+	return &res
+}
+
+// WithBounds returns a shallow copy of the parser with only MinValue and MaxValue set to the new values and
+// HasBounds set to true. For example WithBounds(0, 0) allows only the value 0.
+func (parser *SchulzeVoteParser) WithBounds(minValue, maxValue int) *SchulzeVoteParser {
+	res := *parser
+	res.MinValue = minValue
+	res.MaxValue = maxValue
+	res.HasBounds = true
+	return &res
 }
 
 // CustomizeForPoll implements ParserCustomizer and returns a new parser with Length set if a
 // *SchulzePoll is given.
+//
+// Options are kept if their number matches the number of options in the poll, otherwise they're removed.
+// If RequirePermutationLike is true the bounds are set to [0, NumOptions - 1] (and HasBounds to true).
+// AllowPartial is kept, so partial rankings are padded to the number of options in the poll.
 func (parser *SchulzeVoteParser) CustomizeForPoll(poll AbstractPoll) (ParserCustomizer, error) {
 	if asSchulzePoll, ok := poll.(*SchulzePoll); ok {
-		return parser.WithLength(asSchulzePoll.NumOptions), nil
+		res := parser.WithLength(asSchulzePoll.NumOptions)
+		if len(res.Options) != asSchulzePoll.NumOptions {
+			res.Options = nil
+		}
+		if res.RequirePermutationLike {
+			res.MinValue, res.MaxValue, res.HasBounds = 0, asSchulzePoll.NumOptions-1, true
+		}
+		return res, nil
 	}
 	return nil, NewPollTypeError("can't customize SchulzeVoteParser for type %s, expected type *SchulzePoll",
 		reflect.TypeOf(poll))
@@ -191,11 +492,99 @@ func (parser *SchulzeVoteParser) CustomizeForPoll(poll AbstractPoll) (ParserCust
 
 // ParseFromString implements the VoteParser interface, for details see type description.
 func (parser *SchulzeVoteParser) ParseFromString(s string, voter *Voter) (AbstractVote, error) {
-	ranking, err := parseSchulzeRanking(s, parser.Length)
+	var ranking SchulzeRanking
+	var err error
+	partial := parser.AllowPartial && parser.Length >= 0
+	if parser.Options != nil && !isNumericSchulzeRanking(s) {
+		ranking, err = parseNamedSchulzeRanking(s, parser.Options, partial, parser.UnrankedValue)
+	} else {
+		maxLength := parser.MaxRankingLength
+		if maxLength == 0 {
+			maxLength = DefaultMaxRankingLength
+		}
+		ranking, err = parseSchulzeRanking(s, parser.Length, maxLength, partial)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return NewSchulzeVote(voter, ranking), nil
+	if boundsErr := parser.validateBounds(ranking, partial); boundsErr != nil {
+		return nil, boundsErr
+	}
+	if partial {
+		for len(ranking) < parser.Length {
+			ranking = append(ranking, parser.UnrankedValue)
+		}
+	}
+	return keepRawSource(NewSchulzeVote(voter, ranking), s, parser.KeepRaw), nil
+}
+
+// validateBounds tests if all values of the ranking are in the bounds of the parser (only if HasBounds is true).
+//
+// If partial is true UnrankedValue is not checked and all other values must be smaller than UnrankedValue.
+func (parser *SchulzeVoteParser) validateBounds(ranking SchulzeRanking, partial bool) error {
+	minValue, maxValue := minInt, maxInt
+	if parser.HasBounds {
+		minValue, maxValue = parser.MinValue, parser.MaxValue
+	}
+	for i, value := range ranking {
+		if partial {
+			if value == parser.UnrankedValue {
+				continue
+			}
+			if value > parser.UnrankedValue {
+				return NewPollingSemanticError(nil,
+					"value %d at position %d of schulze ranking must not be greater than the unranked value %d",
+					value, i, parser.UnrankedValue)
+			}
+		}
+		if value < minValue || value > maxValue {
+			return NewPollingSemanticError(nil, "value %d at position %d of schulze ranking is out of bounds [%d, %d]",
+				value, i, minValue, maxValue)
+		}
+		if parser.RequirePermutationLike && (value < 0 || value >= len(ranking)) {
+			return NewPollingSemanticError(nil, "value %d at position %d of schulze ranking must be in [0, %d)",
+				value, i, len(ranking))
+		}
+	}
+	return nil
+}
+
+// SchulzeVoteFormatter implements VoteFormatter for a SchulzeVote.
+//
+// If Options is nil the ranking is formatted as a comma separated list of integers, for example "1, 0, 1".
+// Otherwise the option names are used, see SchulzeRanking.FormatWithOptions (the parser must then be created with the
+// same options, see SchulzeVoteParser.WithOptions).
+type SchulzeVoteFormatter struct {
+	Options []string
+}
+
+// NewSchulzeVoteFormatter returns a new SchulzeVoteFormatter that formats rankings as a list of integers.
+func NewSchulzeVoteFormatter() *SchulzeVoteFormatter {
+	return &SchulzeVoteFormatter{
+		Options: nil,
+	}
+}
+
+// FormatVote implements VoteFormatter, vote must be a *SchulzeVote.
+func (formatter *SchulzeVoteFormatter) FormatVote(vote AbstractVote) (string, error) {
+	schulzeVote, ok := vote.(*SchulzeVote)
+	if !ok {
+		return "", NewPollTypeError("can't format vote of type %s with SchulzeVoteFormatter, expected type *SchulzeVote",
+			reflect.TypeOf(vote))
+	}
+	if formatter.Options != nil {
+		return schulzeVote.Ranking.FormatWithOptions(formatter.Options)
+	}
+	parts := make([]string, len(schulzeVote.Ranking))
+	for i, value := range schulzeVote.Ranking {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// Equals tests if two votes are equal, i.e. have the same ranking and equal voters (see Voter.Equals).
+func (vote *SchulzeVote) Equals(other *SchulzeVote) bool {
+	return vote.Ranking.Equals(other.Ranking) && votersEqual(vote.Voter, other.Voter)
 }
 
 // GetVoter returns the voter of the vote.
@@ -214,6 +603,19 @@ func (vote *SchulzeVote) VoteType() string {
 // Each option should appear in at least one of the lists.
 type SchulzeWinsList [][]int
 
+// Equals tests if two lists contain the same groups (in the same order).
+func (wins SchulzeWinsList) Equals(other SchulzeWinsList) bool {
+	if len(wins) != len(other) {
+		return false
+	}
+	for i, group := range wins {
+		if !SchulzeRanking(group).Equals(other[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // SchulzePoll is a poll that can be evaluated with the Schulze method, see https://en.wikipedia.org/wiki/Schulze_method
 // for details.
 // It implements the interface AbstractPoll.
@@ -221,31 +623,107 @@ type SchulzeWinsList [][]int
 // A poll instance has the number of options in the poll (must be a positive int) and all votes for the poll.
 //
 // Note that all votes must have a ranking of length NumVotes. If this is not the case the the vote
-// will be silently dropped. You should use TruncateVoters first to identify problematic cases.
+// will be dropped and counted in SchulzeResult.InvalidVotesCount. You can use TruncateVoters first to remove
+// problematic cases.
 //
 // The implementation was inspired by the German Wikipedia article (https://de.wikipedia.org/wiki/Schulze-Methode)
 // and https://github.com/mgp/schulze-method.
 //
 // This type also implements VoteGenerator.
+//
+// NoOptionIndex is the index of the option that stands for "no" (the status quo), it is used to generate votes from
+// basic answers and is stored in the result (see SchulzeResult.StrictlyBetterThanNo).
+// NoSchulzeNoOption (-1) means that the poll has no such option.
+//
+// SecondaryOrder describes how options in the same group of the result are ordered in SchulzeResult.Ranking, it
+// doesn't change the groups. It defaults to SchulzeNoSecondaryOrder.
+//
+// Sealed votes are stored in the embedded SealedVoteBuffer, see Reveal.
+// Duplicate votes can be rejected with the embedded DuplicateVoterGuard.
+// Application data can be attached with the embedded PollMetadata.
+// The poll can be closed with the embedded PollCloser, a closed poll rejects new votes.
 type SchulzePoll struct {
-	NumOptions int
-	Votes      []*SchulzeVote
+	NumOptions     int
+	NoOptionIndex  int
+	SecondaryOrder SchulzeSecondaryOrder
+	Votes          []*SchulzeVote
+	SealedVoteBuffer
+	DuplicateVoterGuard
+	PollMetadata
+	PollCloser
 }
 
+// NoSchulzeNoOption is the NoOptionIndex of a SchulzePoll without a "no" option.
+const NoSchulzeNoOption = -1
+
 // NewSchulzePoll returns a new SchulzePoll.
 // numOptions must be >= 0, otherwise this function panics.
 // Note that the votes are not validated (have the correct ranking length).
 // Use TruncateVoters to identify invalid votes.
+//
+// NoOptionIndex is set to the last option (NoSchulzeNoOption if numOptions is 0).
 func NewSchulzePoll(numOptions int, votes []*SchulzeVote) *SchulzePoll {
 	if numOptions < 0 {
 		panic(fmt.Sprintf("Num options in SchulzePoll must be >= 0, got %d", numOptions))
 	}
 	return &SchulzePoll{
-		NumOptions: numOptions,
-		Votes:      votes,
+		NumOptions:     numOptions,
+		NoOptionIndex:  numOptions - 1,
+		SecondaryOrder: SchulzeNoSecondaryOrder,
+		Votes:          votes,
 	}
 }
 
+// hasNoOption returns true if NoOptionIndex is a valid option index.
+func (poll *SchulzePoll) hasNoOption() bool {
+	return poll.NoOptionIndex >= 0 && poll.NoOptionIndex < poll.NumOptions
+}
+
+// DeepClone returns a copy of the poll with a new votes slice and new vote objects (including a copy of the
+// ranking).
+// The voter objects are not copied, the votes of the clone point to the same voters as the original.
+func (poll *SchulzePoll) DeepClone() *SchulzePoll {
+	votes := make([]*SchulzeVote, len(poll.Votes))
+	for i, vote := range poll.Votes {
+		var ranking SchulzeRanking
+		if vote.Ranking != nil {
+			ranking = make(SchulzeRanking, len(vote.Ranking))
+			copy(ranking, vote.Ranking)
+		}
+		votes[i] = NewSchulzeVote(vote.Voter, ranking)
+		votes[i].VoteSourceHolder = vote.VoteSourceHolder.clone()
+	}
+	res := NewSchulzePoll(poll.NumOptions, votes)
+	res.NoOptionIndex = poll.NoOptionIndex
+	res.SecondaryOrder = poll.SecondaryOrder
+	res.SealedVoteBuffer = poll.SealedVoteBuffer.clone()
+	res.DuplicateVoterGuard = poll.DuplicateVoterGuard.clone()
+	res.PollMetadata = poll.PollMetadata.clone()
+	res.PollCloser = poll.PollCloser.clone()
+	return res
+}
+
+// AddSealed implements SealedVoteAcceptor and adds a sealed vote to the embedded SealedVoteBuffer.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+func (poll *SchulzePoll) AddSealed(vote *SealedVote) error {
+	return poll.SealedVoteBuffer.addSealed(&poll.PollCloser, vote)
+}
+
+// Equals tests if two polls have the same number of options, the same no option and the same secondary order and
+// contain equal votes (in the same order).
+func (poll *SchulzePoll) Equals(other *SchulzePoll) bool {
+	if poll.NumOptions != other.NumOptions || poll.NoOptionIndex != other.NoOptionIndex ||
+		poll.SecondaryOrder != other.SecondaryOrder || len(poll.Votes) != len(other.Votes) {
+		return false
+	}
+	for i, vote := range poll.Votes {
+		if !vote.Equals(other.Votes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // PollType returns the constant SchulzePollType.
 func (poll *SchulzePoll) PollType() string {
 	return SchulzePollType
@@ -253,6 +731,10 @@ func (poll *SchulzePoll) PollType() string {
 
 // AddVote adds a vote to the poll, the vote must be of type *SchulzeVote.
 //
+// If RejectDuplicateVoters is true a DuplicateError is returned if the voter already has a vote, see
+// DuplicateVoterGuard.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+//
 // Note that no length check is happening here! I.e. the vote can have a different number of answers than
 // poll.NumOptions.
 // We do this because in general it is also allowed to append any vote, it is the job of the user of this library
@@ -263,19 +745,132 @@ func (poll *SchulzePoll) AddVote(vote AbstractVote) error {
 		return NewPollTypeError("can't add vote to SchulzePoll, vote must be of type *SchulzeVote, got type %s",
 			reflect.TypeOf(vote))
 	}
+	if closedErr := poll.checkOpenForVoter("add vote", asSchulzeVote.Voter); closedErr != nil {
+		return closedErr
+	}
+	if duplicateErr := poll.checkNewVoter(asSchulzeVote.Voter, len(poll.Votes), poll.forEachVoter); duplicateErr != nil {
+		return duplicateErr
+	}
 	poll.Votes = append(poll.Votes, asSchulzeVote)
 	return nil
 }
 
+// ForEachVote implements VoteIterator, it calls f for each vote in the order in which they appear in the poll.
+func (poll *SchulzePoll) ForEachVote(f func(vote AbstractVote) error) error {
+	return forEachVote(poll, f)
+}
+
+// NumVotes implements VoteIterator and returns the number of votes in the poll.
+func (poll *SchulzePoll) NumVotes() int {
+	return len(poll.Votes)
+}
+
+// voteAt returns the vote with index i, it implements indexedVotes.
+func (poll *SchulzePoll) voteAt(i int) AbstractVote {
+	return poll.Votes[i]
+}
+
+// forEachVoter calls f with the voter of each vote, used by DuplicateVoterGuard.
+func (poll *SchulzePoll) forEachVoter(f func(voter *Voter)) {
+	for _, vote := range poll.Votes {
+		f(vote.Voter)
+	}
+}
+
+// HasVoteFrom implements VoteEditor and returns true if the voter with the given name has a vote in the poll.
+func (poll *SchulzePoll) HasVoteFrom(voterName string) bool {
+	return voteIndex(poll, voterName) >= 0
+}
+
+// RemoveVote implements VoteEditor and removes the vote of the voter with the given name, the removed vote is
+// returned.
+//
+// If the voter has multiple votes only the first one is removed. The order of the remaining votes is retained.
+// If the voter has no vote a VoteNotFoundError is returned.
+func (poll *SchulzePoll) RemoveVote(voterName string) (AbstractVote, error) {
+	if closedErr := poll.checkOpen("remove vote", voterName); closedErr != nil {
+		return nil, closedErr
+	}
+	index := voteIndex(poll, voterName)
+	if index < 0 {
+		return nil, NewVoteNotFoundError(voterName)
+	}
+	res := poll.Votes[index]
+	poll.Votes = append(poll.Votes[:index], poll.Votes[index+1:]...)
+	poll.ResetVoterIndex()
+	return res, nil
+}
+
+// ReplaceVote implements VoteEditor, the vote must be of type *SchulzeVote.
+//
+// The vote replaces the first vote of its voter (at the same position), all other votes of the voter are removed.
+// If the voter has no vote yet the vote is added.
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if the vote has no voter.
+func (poll *SchulzePoll) ReplaceVote(vote AbstractVote) error {
+	asSchulzeVote, ok := vote.(*SchulzeVote)
+	if !ok {
+		return NewPollTypeError("can't replace vote in SchulzePoll, vote must be of type *SchulzeVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asSchulzeVote.Voter == nil {
+		return NewPollingSemanticError(nil, "can't replace vote in SchulzePoll, vote has no voter")
+	}
+	name := asSchulzeVote.Voter.Name
+	if closedErr := poll.checkOpen("replace vote", name); closedErr != nil {
+		return closedErr
+	}
+	index := voteIndex(poll, name)
+	if index < 0 {
+		return poll.AddVote(vote)
+	}
+	poll.Votes[index] = asSchulzeVote
+	// remove all other votes of the voter
+	filtered := poll.Votes[:index+1]
+	for _, other := range poll.Votes[index+1:] {
+		if other.Voter == nil || other.Voter.Name != name {
+			filtered = append(filtered, other)
+		}
+	}
+	poll.Votes = filtered
+	poll.ResetVoterIndex()
+	return nil
+}
+
+// ValidateVote tests if vote can be added to the poll, it must be of type *SchulzeVote with a voter and a ranking
+// of length poll.NumOptions.
+//
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if it is invalid.
+func (poll *SchulzePoll) ValidateVote(vote AbstractVote) error {
+	asSchulzeVote, ok := vote.(*SchulzeVote)
+	if !ok {
+		return NewPollTypeError("invalid vote for SchulzePoll, vote must be of type *SchulzeVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asSchulzeVote.Voter == nil {
+		return NewPollingSemanticError(nil, "vote for SchulzePoll has no voter")
+	}
+	if len(asSchulzeVote.Ranking) != poll.NumOptions {
+		return NewPollingSemanticError(nil, "ranking of voter \"%s\" has length %d, expected length %d",
+			asSchulzeVote.Voter.Name, len(asSchulzeVote.Ranking), poll.NumOptions)
+	}
+	return nil
+}
+
 // GenerateVoteFromBasicAnswer implements VoteGenerator and returns a SchulzeVote.
 //
-// It will return [0, 0, ..., 1] for Aye, [1, 1, ..., 0] for No and [0, 0, ..., 0] for Abstention.
+// It will return [0, 0, ..., 1] for Aye, [1, 1, ..., 0] for No and [0, 0, ..., 0] for Abstention (assuming that
+// the no option is the last one, see NewSchulzeAyeAt and NewSchulzeNoAt for other positions).
+// If the poll has no no option (see NoOptionIndex) a PollTypeError is returned for Aye and No.
 func (poll *SchulzePoll) GenerateVoteFromBasicAnswer(voter *Voter, answer BasicPollAnswer) (AbstractVote, error) {
 	switch answer {
-	case No:
-		return NewSchulzeVote(voter, NewSchulzeNo(poll.NumOptions)), nil
-	case Aye:
-		return NewSchulzeVote(voter, NewSchulzeAye(poll.NumOptions)), nil
+	case No, Aye:
+		if !poll.hasNoOption() {
+			return nil, NewPollTypeError("can't generate vote for answer \"%s\", poll has no \"no\" option", answer)
+		}
+		if answer == No {
+			return NewSchulzeVote(voter, NewSchulzeNoAt(poll.NumOptions, poll.NoOptionIndex)), nil
+		}
+		return NewSchulzeVote(voter, NewSchulzeAyeAt(poll.NumOptions, poll.NoOptionIndex)), nil
 	case Abstention:
 		return NewSchulzeVote(voter, NewSchulzeAbstention(poll.NumOptions)), nil
 	default:
@@ -317,37 +912,219 @@ func (poll *SchulzePoll) TruncateVoters() []*SchulzeVote {
 	return culprits
 }
 
-func (poll *SchulzePoll) computeD() (SchulzeMatrix, SchulzeMatrix, Weight) {
+// AddOption adds a new option at the given position (0 <= position <= NumOptions), the options at position and
+// after it are moved one position back.
+//
+// If the poll already contains votes and padExisting is false a PollingSemanticError is returned, otherwise a value
+// for the new option is inserted in each ranking: The new option is ranked below all other options, except for
+// abstentions (all options ranked equally) which remain abstentions. Thus the results for the existing options are
+// not changed. If a ranking contains SchulzeUnranked (see SchulzeVoteParser.AllowPartial) the new option is unranked
+// as well.
+// The rankings are copied before they're changed, so rankings shared with other votes are not modified.
+//
+// NoOptionIndex is updated if the no option is moved. Sealed votes are not changed, they must be given for the new
+// number of options.
+// If position is out of range or a ranking has a wrong length (see checkRankingLengths) a PollingSemanticError is
+// returned and the poll is not changed.
+func (poll *SchulzePoll) AddOption(position int, padExisting bool) error {
+	if position < 0 || position > poll.NumOptions {
+		return NewPollingSemanticError(nil, "can't add option at position %d, poll has %d options",
+			position, poll.NumOptions)
+	}
+	if len(poll.Votes) > 0 && !padExisting {
+		return NewPollingSemanticError(nil, "can't add option to poll with %d votes", len(poll.Votes))
+	}
+	if lengthErr := poll.checkRankingLengths(); lengthErr != nil {
+		return lengthErr
+	}
+	for _, vote := range poll.Votes {
+		value := 0
+		if len(vote.Ranking) > 0 {
+			value = vote.Ranking[0]
+			for _, rank := range vote.Ranking[1:] {
+				if rank > value {
+					value = rank
+				}
+			}
+			if !vote.Ranking.IsAbstention() && value < SchulzeUnranked {
+				value++
+			}
+		}
+		ranking := make(SchulzeRanking, 0, len(vote.Ranking)+1)
+		ranking = append(ranking, vote.Ranking[:position]...)
+		ranking = append(ranking, value)
+		ranking = append(ranking, vote.Ranking[position:]...)
+		vote.Ranking = ranking
+	}
+	if poll.hasNoOption() && position <= poll.NoOptionIndex {
+		poll.NoOptionIndex++
+	}
+	poll.NumOptions++
+	return nil
+}
+
+// RemoveOption removes the option with the given index, the options after it are moved one position forward.
+//
+// The entry for the option is removed from each ranking, the comparisons between the remaining options are not
+// changed.
+// The rankings are copied before they're changed, so rankings shared with other votes are not modified.
+//
+// If the removed option is the no option NoOptionIndex is set to NoSchulzeNoOption, otherwise it is updated if the
+// no option is moved. Sealed votes are not changed.
+// If index is out of range or a ranking has a wrong length (see checkRankingLengths) a PollingSemanticError is
+// returned and the poll is not changed.
+func (poll *SchulzePoll) RemoveOption(index int) error {
+	if index < 0 || index >= poll.NumOptions {
+		return NewPollingSemanticError(nil, "can't remove option %d, poll has %d options", index, poll.NumOptions)
+	}
+	if lengthErr := poll.checkRankingLengths(); lengthErr != nil {
+		return lengthErr
+	}
+	for _, vote := range poll.Votes {
+		ranking := make(SchulzeRanking, 0, len(vote.Ranking)-1)
+		ranking = append(ranking, vote.Ranking[:index]...)
+		ranking = append(ranking, vote.Ranking[index+1:]...)
+		vote.Ranking = ranking
+	}
+	switch {
+	case index == poll.NoOptionIndex:
+		poll.NoOptionIndex = NoSchulzeNoOption
+	case poll.hasNoOption() && index < poll.NoOptionIndex:
+		poll.NoOptionIndex--
+	}
+	poll.NumOptions--
+	return nil
+}
+
+// checkRankingLengths returns a PollingSemanticError if the ranking of a vote doesn't have NumOptions entries.
+// Such a ranking must not be changed by AddOption or RemoveOption: After the change it could have the correct length
+// and would be counted. Invalid votes can be removed with TruncateVoters first.
+func (poll *SchulzePoll) checkRankingLengths() error {
+	for _, vote := range poll.Votes {
+		if len(vote.Ranking) != poll.NumOptions {
+			return NewPollingSemanticError(nil,
+				"ranking of voter \"%s\" has %d entries, poll has %d options (use TruncateVoters first)",
+				vote.Voter.Name, len(vote.Ranking), poll.NumOptions)
+		}
+	}
+	return nil
+}
+
+// CheckedWeightSum returns the sum of the effective weights of all votes, if the sum overflows an error wrapping
+// ErrWeightOverflow is returned.
+func (poll *SchulzePoll) CheckedWeightSum() (Weight, error) {
+	return checkedWeightSum(poll, nil)
+}
+
+// computeD computes the matrices d and dNonStrict.
+// Each entry is bounded by the weight sum, thus if the sum doesn't overflow no entry overflows.
+//
+// Comparing each pair of options for each vote is rather slow for polls with many options and votes.
+// Therefore abstentions are not compared at all: They contribute the same weight to each entry of dNonStrict (except
+// the diagonal), so their weights are summed up and added in the end.
+// Votes with the same ranking are grouped together (this is common for polls with an aye / no style) and each
+// distinct ranking is added only once with the sum of the weights, see addRankingToD.
+// Because all entries are sums of weights the result is exactly the same as comparing each pair for each vote.
+//
+// Votes with a ranking of the wrong length are ignored, their number and weight are returned as invalid and
+// invalidWeight.
+// Partial ballots (see SchulzeVoteParser.AllowPartial) need no special handling: The unranked options have the
+// largest value in the ranking, so they're tied with each other and beaten by all ranked options.
+func (poll *SchulzePoll) computeD() (d, dNonStrict SchulzeMatrix, sum Weight, invalid int, invalidWeight Weight,
+	err error) {
 	n := poll.NumOptions
 	res := NewSchulzeMatrix(n)
 	resNonStrict := NewSchulzeMatrix(n)
-	var sum Weight
+	sum, sumErr := poll.CheckedWeightSum()
+	if sumErr != nil {
+		return nil, nil, NoWeight, 0, NoWeight, sumErr
+	}
 
+	var abstentionWeight Weight
+	// rankings contains each distinct ranking once (in the order in which they appear), weights the sum of the
+	// weights for each ranking
+	var rankings []SchulzeRanking
+	var weights []Weight
+	rankingIndices := make(map[string]int)
+	var keyBuffer []byte
 	for _, vote := range poll.Votes {
-		sum += vote.Voter.Weight
-		w := vote.Voter.Weight
+		w := vote.Voter.EffectiveWeight()
 		ranking := vote.Ranking
 		if len(ranking) != n {
+			invalid++
+			invalidWeight += w
+			continue
+		}
+		if ranking.IsAbstention() {
+			abstentionWeight += w
 			continue
 		}
+		keyBuffer = keyBuffer[:0]
+		for _, rank := range ranking {
+			keyBuffer = strconv.AppendInt(keyBuffer, int64(rank), 10)
+			keyBuffer = append(keyBuffer, ',')
+		}
+		key := string(keyBuffer)
+		if index, has := rankingIndices[key]; has {
+			weights[index] += w
+		} else {
+			rankingIndices[key] = len(rankings)
+			rankings = append(rankings, ranking)
+			weights = append(weights, w)
+		}
+	}
+
+	order := make([]int, n)
+	for index, ranking := range rankings {
+		addRankingToD(res, resNonStrict, ranking, weights[index], order)
+	}
+
+	if abstentionWeight > 0 {
 		for i := 0; i < n; i++ {
-			for j := i + 1; j < n; j++ {
-				switch {
-				case ranking[i] < ranking[j]:
-					res[i][j] += w
-					resNonStrict[i][j] += w
-				case ranking[j] < ranking[i]:
-					res[j][i] += w
-					resNonStrict[j][i] += w
-				case ranking[i] == ranking[j]:
-					resNonStrict[i][j] += w
-					resNonStrict[j][i] += w
+			for j := 0; j < n; j++ {
+				if i != j {
+					resNonStrict[i][j] += abstentionWeight
 				}
 			}
 		}
 	}
 
-	return res, resNonStrict, sum
+	return res, resNonStrict, sum, invalid, invalidWeight, nil
+}
+
+// addRankingToD adds the weight w of a ranking to d and dNonStrict.
+//
+// The options are sorted by their rank (order is used as buffer and must have the length of the ranking), this
+// splits them into levels of options with the same rank. Each option is then preferred over all options in the
+// following levels and tied with all other options in its own level, no further comparisons are required.
+func addRankingToD(d, dNonStrict SchulzeMatrix, ranking SchulzeRanking, w Weight, order []int) {
+	n := len(ranking)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ranking[order[i]] < ranking[order[j]]
+	})
+	levelStart := 0
+	for levelStart < n {
+		levelEnd := levelStart + 1
+		for levelEnd < n && ranking[order[levelEnd]] == ranking[order[levelStart]] {
+			levelEnd++
+		}
+		for _, a := range order[levelStart:levelEnd] {
+			dRow, dNonStrictRow := d[a], dNonStrict[a]
+			for _, b := range order[levelStart:levelEnd] {
+				if a != b {
+					dNonStrictRow[b] += w
+				}
+			}
+			for _, b := range order[levelEnd:] {
+				dRow[b] += w
+				dNonStrictRow[b] += w
+			}
+		}
+		levelStart = levelEnd
+	}
 }
 
 func (poll *SchulzePoll) computeP(d SchulzeMatrix) SchulzeMatrix {
@@ -362,6 +1139,32 @@ func (poll *SchulzePoll) computeP(d SchulzeMatrix) SchulzeMatrix {
 		}
 	}
 
+	poll.computeStrongestPaths(res)
+	return res
+}
+
+// computePMargins works as computeP but uses the margins as the link strength.
+// A positive margin is always <= the entry in d, so the margins can be represented as Weight in p.
+func (poll *SchulzePoll) computePMargins(margins SchulzeMarginMatrix) SchulzeMatrix {
+	n := poll.NumOptions
+	res := NewSchulzeMatrix(n)
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && margins[i][j] > 0 {
+				res[i][j] = Weight(margins[i][j])
+			}
+		}
+	}
+
+	poll.computeStrongestPaths(res)
+	return res
+}
+
+// computeStrongestPaths computes the strength of the strongest paths in-place, res must be initialized with the
+// strength of the direct links.
+func (poll *SchulzePoll) computeStrongestPaths(res SchulzeMatrix) {
+	n := poll.NumOptions
 	for i := 0; i < n; i++ {
 		for j := 0; j < n; j++ {
 			if i != j {
@@ -373,8 +1176,6 @@ func (poll *SchulzePoll) computeP(d SchulzeMatrix) SchulzeMatrix {
 			}
 		}
 	}
-
-	return res
 }
 
 // inspired by https://github.com/mgp/schulze-method/blob/master/schulze.py
@@ -420,75 +1221,225 @@ func (poll *SchulzePoll) rankP(p SchulzeMatrix) SchulzeWinsList {
 // (ranking[i] < ranking[j] vs ranking[i] <= ranking[j]).
 //
 // WeightSum is the sum of the weights of all votes in the poll.
+//
+// Variant is the variant that was used to compute P, Margins contains the margins d[i][j] - d[j][i] (computed for
+// all variants).
+//
+// CondorcetConsistent is true if there is a Condorcet winner (see CondorcetWinner) and the first group in
+// RankedGroups consists only of this winner.
+//
+// NoOptionIndex is the index of the "no" option of the poll (NoSchulzeNoOption if there is none), see
+// StrictlyBetterThanNo.
+//
+// Ranking contains all options of RankedGroups in a single list, the options in each group are ordered according to
+// SecondaryOrder (see SchulzePoll.SecondaryOrder). RankingRules[i] is the rule that ranked Ranking[i] before
+// Ranking[i + 1].
+//
+// InvalidVotesCount is the number of votes that were ignored because their ranking doesn't have the length
+// NumOptions, InvalidWeight is the sum of their weights. The weights of these votes are still included in
+// WeightSum.
+//
+// Err is nil unless the weights of the votes overflow, in this case it wraps ErrWeightOverflow, all matrices contain
+// only zeros and RankedGroups is nil.
 type SchulzeResult struct {
-	D, P         SchulzeMatrix
-	DNonStrict   SchulzeMatrix
-	RankedGroups SchulzeWinsList
-	WeightSum    Weight
+	D, P                SchulzeMatrix
+	DNonStrict          SchulzeMatrix
+	Margins             SchulzeMarginMatrix
+	RankedGroups        SchulzeWinsList
+	WeightSum           Weight
+	Variant             SchulzeVariant
+	CondorcetConsistent bool
+	NoOptionIndex       int
+	SecondaryOrder      SchulzeSecondaryOrder
+	Ranking             []int
+	RankingRules        []SchulzeRankingRule
+	InvalidVotesCount   int
+	InvalidWeight       Weight
+	Err                 error
 }
 
 // NewSchulzeResult returns a new SchulzeResult.
+//
+// The variant is set to SchulzeWinningVotes, NoOptionIndex to the last option, the margins and CondorcetConsistent
+// are computed from d. The secondary order is SchulzeNoSecondaryOrder, see SetSecondaryOrder.
 func NewSchulzeResult(d, dNonStrict, p SchulzeMatrix, rankedGroups SchulzeWinsList, votesSum Weight) *SchulzeResult {
-	return &SchulzeResult{
-		D:            d,
-		DNonStrict:   dNonStrict,
-		P:            p,
-		RankedGroups: rankedGroups,
-		WeightSum:    votesSum,
+	res := &SchulzeResult{
+		D:             d,
+		DNonStrict:    dNonStrict,
+		P:             p,
+		Margins:       NewSchulzeMarginMatrix(d),
+		RankedGroups:  rankedGroups,
+		WeightSum:     votesSum,
+		Variant:       SchulzeWinningVotes,
+		NoOptionIndex: len(d) - 1,
+	}
+	res.SetSecondaryOrder(SchulzeNoSecondaryOrder)
+	if winner, hasWinner := res.CondorcetWinner(); hasWinner {
+		res.CondorcetConsistent = len(rankedGroups) > 0 && len(rankedGroups[0]) == 1 && rankedGroups[0][0] == winner
 	}
+	return res
 }
 
-// StrictlyBetterThanNo returns a list of weights, each weight says how many voters (by weight) considered
-// the option strictly better than no.
-//
-// That is result[i] says: How many voters (by weight) have voted option strictly higher than no.
-// Higher means that the ranking position of i is smaller than the ranking position of no.
+// SetSecondaryOrder sets SecondaryOrder and computes Ranking and RankingRules from D and RankedGroups.
+func (schulzeRes *SchulzeResult) SetSecondaryOrder(order SchulzeSecondaryOrder) {
+	schulzeRes.SecondaryOrder = order
+	schulzeRes.Ranking, schulzeRes.RankingRules = computeSchulzeRanking(schulzeRes.D, schulzeRes.RankedGroups, order)
+}
+
+// CondorcetWinner returns the Condorcet winner and true if it exists, otherwise -1 and false.
 //
-// It simply returns the last column of the matrix d, thus assumes that no is always the last option.
-// Note that due to this the last entry in the returned list will always be 0.
-func (schulzeRes *SchulzeResult) StrictlyBetterThanNo() []Weight {
-	n := len(schulzeRes.D)
-	if n == 0 {
-		return nil
+// The Condorcet winner is the option i that beats all other options pairwise, i.e. d[i][j] > d[j][i] for all j != i.
+// It is computed from D only, independent of the Schulze paths.
+// For a matrix of dimension 0 or 1 there is no Condorcet winner.
+func (schulzeRes *SchulzeResult) CondorcetWinner() (int, bool) {
+	d := schulzeRes.D
+	n := len(d)
+	if n < 2 {
+		return -1, false
 	}
-	res := make([]Weight, n)
-
 	for i := 0; i < n; i++ {
-		res[i] = schulzeRes.D[i][n-1]
+		beatsAll := true
+		for j := 0; j < n; j++ {
+			if i != j && d[i][j] <= d[j][i] {
+				beatsAll = false
+				break
+			}
+		}
+		if beatsAll {
+			return i, true
+		}
 	}
+	return -1, false
+}
 
+// PairwiseTies returns all pairs of options (i, j) with i < j and d[i][j] == d[j][i], i.e. options that are tied in a
+// direct comparison.
+// Such ties often explain ambiguous outcomes.
+func (schulzeRes *SchulzeResult) PairwiseTies() [][2]int {
+	d := schulzeRes.D
+	var res [][2]int
+	for i := 0; i < len(d); i++ {
+		for j := i + 1; j < len(d); j++ {
+			if d[i][j] == d[j][i] {
+				res = append(res, [2]int{i, j})
+			}
+		}
+	}
 	return res
 }
 
-// BetterOrEqualNo returns a list of weights, each weight says how many voters (by weight) considered
-// the option equal or better than no.
+// Equals tests if two results store the same state.
+func (schulzeRes *SchulzeResult) Equals(other *SchulzeResult) bool {
+	return schulzeRes.D.Equals(other.D) &&
+		schulzeRes.P.Equals(other.P) &&
+		schulzeRes.DNonStrict.Equals(other.DNonStrict) &&
+		schulzeRes.Margins.Equals(other.Margins) &&
+		schulzeRes.RankedGroups.Equals(other.RankedGroups) &&
+		schulzeRes.WeightSum == other.WeightSum &&
+		schulzeRes.Variant == other.Variant &&
+		schulzeRes.NoOptionIndex == other.NoOptionIndex &&
+		schulzeRes.SecondaryOrder == other.SecondaryOrder &&
+		SchulzeRanking(schulzeRes.Ranking).Equals(other.Ranking) &&
+		schulzeRes.InvalidVotesCount == other.InvalidVotesCount &&
+		schulzeRes.InvalidWeight == other.InvalidWeight
+}
+
+// StrictlyBetterThan returns a list of weights, each weight says how many voters (by weight) considered
+// the option strictly better than the option with the given index.
 //
-// That is result[i] says: How many voters (by weight) have voted option higher or equal no.
-// Higher means that the ranking position of i is smaller than the ranking position of no.
+// That is result[i] says: How many voters (by weight) have voted option i strictly higher than option index.
+// Higher means that the ranking position of i is smaller than the ranking position of index.
 //
-// It simply returns the last column of the matrix d in non-strict mode, thus assumes that no is always the last option.
-// Note that due to this the last entry in the returned list will always be 0.
-func (schulzeRes *SchulzeResult) BetterOrEqualNo() []Weight {
-	n := len(schulzeRes.DNonStrict)
-	if n == 0 {
+// It simply returns the column index of the matrix d, note that due to this result[index] will always be 0.
+// If index is not a valid option index nil is returned.
+func (schulzeRes *SchulzeResult) StrictlyBetterThan(index int) []Weight {
+	return matrixColumn(schulzeRes.D, index)
+}
+
+// BetterOrEqual returns a list of weights, each weight says how many voters (by weight) considered
+// the option better than or equal to the option with the given index.
+//
+// That is result[i] says: How many voters (by weight) have voted option i higher or equal option index.
+//
+// It simply returns the column index of the matrix d in non-strict mode, note that due to this result[index] will
+// always be 0.
+// If index is not a valid option index nil is returned.
+func (schulzeRes *SchulzeResult) BetterOrEqual(index int) []Weight {
+	return matrixColumn(schulzeRes.DNonStrict, index)
+}
+
+func matrixColumn(m SchulzeMatrix, index int) []Weight {
+	n := len(m)
+	if index < 0 || index >= n {
 		return nil
 	}
 	res := make([]Weight, n)
 
 	for i := 0; i < n; i++ {
-		res[i] = schulzeRes.DNonStrict[i][n-1]
+		res[i] = m[i][index]
 	}
 
 	return res
 }
 
+// StrictlyBetterThanNo returns a list of weights, each weight says how many voters (by weight) considered
+// the option strictly better than no.
+//
+// It is StrictlyBetterThan(NoOptionIndex), thus nil is returned if the poll has no no option.
+// By default no is the last option.
+func (schulzeRes *SchulzeResult) StrictlyBetterThanNo() []Weight {
+	return schulzeRes.StrictlyBetterThan(schulzeRes.NoOptionIndex)
+}
+
+// BetterOrEqualNo returns a list of weights, each weight says how many voters (by weight) considered
+// the option equal or better than no.
+//
+// It is BetterOrEqual(NoOptionIndex), thus nil is returned if the poll has no no option.
+// By default no is the last option.
+func (schulzeRes *SchulzeResult) BetterOrEqualNo() []Weight {
+	return schulzeRes.BetterOrEqual(schulzeRes.NoOptionIndex)
+}
+
 // Tally computes the result of a Schulze poll.
 //
 // Note that all voters with an invalid ranking (length is not poll.NumOptions) are silently discarded.
 // Use TruncateVoters before to find such votes.
+// If the weights of the votes overflow (see CheckedWeightSum) the returned result is empty and has its Err set.
+//
+// It uses the variant SchulzeWinningVotes, see TallyWithVariant for other variants.
+// The options in each group of RankedGroups are ordered in Ranking according to poll.SecondaryOrder.
 func (poll *SchulzePoll) Tally() *SchulzeResult {
-	d, dNonStrict, votesSum := poll.computeD()
-	p := poll.computeP(d)
+	return poll.TallyWithVariant(SchulzeWinningVotes)
+}
+
+// TallyWithVariant works as Tally but allows to define how the strength of a link is computed, see SchulzeVariant.
+//
+// An unknown variant is treated as SchulzeWinningVotes.
+func (poll *SchulzePoll) TallyWithVariant(variant SchulzeVariant) *SchulzeResult {
+	d, dNonStrict, votesSum, invalid, invalidWeight, dErr := poll.computeD()
+	if dErr != nil {
+		n := poll.NumOptions
+		res := NewSchulzeResult(NewSchulzeMatrix(n), NewSchulzeMatrix(n), NewSchulzeMatrix(n), nil, NoWeight)
+		res.NoOptionIndex = poll.NoOptionIndex
+		res.Err = dErr
+		return res
+	}
+	margins := NewSchulzeMarginMatrix(d)
+	var p SchulzeMatrix
+	switch variant {
+	case SchulzeMargins:
+		p = poll.computePMargins(margins)
+	default:
+		variant = SchulzeWinningVotes
+		p = poll.computeP(d)
+	}
 	rankedGroups := poll.rankP(p)
-	return NewSchulzeResult(d, dNonStrict, p, rankedGroups, votesSum)
+	res := NewSchulzeResult(d, dNonStrict, p, rankedGroups, votesSum)
+	res.Margins = margins
+	res.Variant = variant
+	res.NoOptionIndex = poll.NoOptionIndex
+	res.InvalidVotesCount = invalid
+	res.InvalidWeight = invalidWeight
+	res.SetSecondaryOrder(poll.SecondaryOrder)
+	return res
 }
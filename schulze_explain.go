@@ -0,0 +1,102 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import "fmt"
+
+// PairwiseFormatter returns the sentence for a pair of options of a Schulze poll, see SchulzeResult.ExplainPairwise.
+//
+// a is the index of the option that is ranked higher in the final ranking and b the index of the other option,
+// aName and bName are the names of these options. votesFor is the weight of all voters that preferred a over b
+// (d[a][b]) and votesAgainst the weight of all voters that preferred b over a (d[b][a]). winner is the index of the
+// option that was preferred by more voters (a or b), it is -1 if both options were tied (votesFor == votesAgainst).
+// The winner is given as an index because option names don't have to be unique.
+type PairwiseFormatter func(a, b int, aName, bName string, winner int, votesFor, votesAgainst Weight) string
+
+// EnglishPairwiseFormatter is a PairwiseFormatter that returns sentences like
+// "Option A was preferred over Option B by 28 votes to 25" and "Option A and Option B were tied with 12 votes each".
+func EnglishPairwiseFormatter(a, b int, aName, bName string, winner int, votesFor, votesAgainst Weight) string {
+	switch {
+	case winner < 0:
+		return fmt.Sprintf("%s and %s were tied with %d votes each", aName, bName, votesFor)
+	case winner == a:
+		return fmt.Sprintf("%s was preferred over %s by %d votes to %d", aName, bName, votesFor, votesAgainst)
+	default:
+		return fmt.Sprintf("%s was preferred over %s by %d votes to %d", bName, aName, votesAgainst, votesFor)
+	}
+}
+
+// GermanPairwiseFormatter is a PairwiseFormatter that returns sentences like
+// "Option A wurde gegenüber Option B mit 28 zu 25 Stimmen bevorzugt" and
+// "Option A und Option B waren mit je 12 Stimmen gleichauf".
+func GermanPairwiseFormatter(a, b int, aName, bName string, winner int, votesFor, votesAgainst Weight) string {
+	switch {
+	case winner < 0:
+		return fmt.Sprintf("%s und %s waren mit je %d Stimmen gleichauf", aName, bName, votesFor)
+	case winner == a:
+		return fmt.Sprintf("%s wurde gegenüber %s mit %d zu %d Stimmen bevorzugt", aName, bName, votesFor,
+			votesAgainst)
+	default:
+		return fmt.Sprintf("%s wurde gegenüber %s mit %d zu %d Stimmen bevorzugt", bName, aName, votesAgainst,
+			votesFor)
+	}
+}
+
+// ExplainPairwise returns one sentence for each pair of options that explains the pairwise comparison (based on the
+// matrix d), for members that don't want to read matrices.
+//
+// The pairs are ordered by the final ranking (see Ranking): First all pairs of the first option in the ranking with
+// the options ranked after it (in the order of the ranking), then the pairs of the second option and so on. So for
+// n options n * (n - 1) / 2 sentences are returned and the output is deterministic.
+// The sentences are created with formatter, see PairwiseFormatter. If formatter is nil EnglishPairwiseFormatter is
+// used.
+//
+// The options are named by optionNames, if optionNames is nil the index of the option is used. If the length of
+// optionNames doesn't match the number of options a PollingSemanticError is returned, if the result has an error
+// (see Err) this error is returned.
+func (schulzeRes *SchulzeResult) ExplainPairwise(optionNames []string, formatter PairwiseFormatter) ([]string, error) {
+	if schulzeRes.Err != nil {
+		return nil, schulzeRes.Err
+	}
+	if formatter == nil {
+		formatter = EnglishPairwiseFormatter
+	}
+	d := schulzeRes.D
+	n := len(d)
+	if optionNames != nil && len(optionNames) != n {
+		return nil, NewPollingSemanticError(nil, "can't explain schulze result with %d options, got %d option names",
+			n, len(optionNames))
+	}
+	name := func(i int) string {
+		if optionNames == nil {
+			return fmt.Sprintf("%d", i)
+		}
+		return optionNames[i]
+	}
+	res := make([]string, 0, n*(n-1)/2)
+	for k, i := range schulzeRes.Ranking {
+		for _, j := range schulzeRes.Ranking[k+1:] {
+			winner := -1
+			switch {
+			case d[i][j] > d[j][i]:
+				winner = i
+			case d[j][i] > d[i][j]:
+				winner = j
+			}
+			res = append(res, formatter(i, j, name(i), name(j), winner, d[i][j], d[j][i]))
+		}
+	}
+	return res, nil
+}
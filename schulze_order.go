@@ -0,0 +1,148 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SchulzeSecondaryOrder describes how options in the same group of SchulzeResult.RankedGroups are ordered, see
+// SchulzePoll.SecondaryOrder.
+//
+// SchulzeNoSecondaryOrder (the default) doesn't order them, all options of a group are tied.
+// SchulzeDirectComparison orders them by the direct comparisons inside the group: An option ranks higher the more
+// options of its group it beats directly (d[i][j] > d[j][i]). For a group of two options this is just the direct
+// comparison of both.
+// SchulzeTotalWinsWeight orders them by the sum of d[i][j] over all other options j, that is the total weight of all
+// pairwise preferences for the option.
+// Options with the same value remain tied.
+type SchulzeSecondaryOrder int8
+
+const (
+	SchulzeNoSecondaryOrder SchulzeSecondaryOrder = iota
+	SchulzeDirectComparison
+	SchulzeTotalWinsWeight
+)
+
+func (order SchulzeSecondaryOrder) String() string {
+	switch order {
+	case SchulzeNoSecondaryOrder:
+		return "none"
+	case SchulzeDirectComparison:
+		return "direct comparison"
+	case SchulzeTotalWinsWeight:
+		return "total wins weight"
+	default:
+		return fmt.Sprintf("SchulzeSecondaryOrder(%d)", order)
+	}
+}
+
+// SchulzeRankingRule describes why an option is ranked before the next option in SchulzeResult.Ranking.
+//
+// SchulzeRankedByPaths is used if both options are in different groups of RankedGroups (i.e. they're ordered by the
+// Schulze method), SchulzeRankedBySecondaryOrder if they're in the same group and ordered by the SecondaryOrder of the
+// result and SchulzeRankedTied if they're in the same group and tied (their order is the order of the options in
+// the poll).
+type SchulzeRankingRule int8
+
+const (
+	SchulzeRankedByPaths SchulzeRankingRule = iota
+	SchulzeRankedBySecondaryOrder
+	SchulzeRankedTied
+)
+
+func (rule SchulzeRankingRule) String() string {
+	switch rule {
+	case SchulzeRankedByPaths:
+		return "beat paths"
+	case SchulzeRankedBySecondaryOrder:
+		return "secondary order"
+	case SchulzeRankedTied:
+		return "tied"
+	default:
+		return fmt.Sprintf("SchulzeRankingRule(%d)", rule)
+	}
+}
+
+// secondaryOrderValue returns the value used to order option inside group, a higher value ranks higher.
+func secondaryOrderValue(d SchulzeMatrix, group []int, option int, order SchulzeSecondaryOrder) uint64 {
+	var res uint64
+	switch order {
+	case SchulzeDirectComparison:
+		for _, other := range group {
+			if other != option && d[option][other] > d[other][option] {
+				res++
+			}
+		}
+	case SchulzeTotalWinsWeight:
+		for other, weight := range d[option] {
+			if other != option {
+				res += uint64(weight)
+			}
+		}
+	}
+	return res
+}
+
+// splitGroup splits a group of RankedGroups into tiers according to order, the first tier contains the options with
+// the highest value (see secondaryOrderValue). The options in each tier are sorted by index.
+func splitGroup(d SchulzeMatrix, group []int, order SchulzeSecondaryOrder) [][]int {
+	sorted := make([]int, len(group))
+	copy(sorted, group)
+	sort.Ints(sorted)
+	if order == SchulzeNoSecondaryOrder {
+		return [][]int{sorted}
+	}
+	values := make(map[int]uint64, len(sorted))
+	for _, option := range sorted {
+		values[option] = secondaryOrderValue(d, sorted, option, order)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return values[sorted[i]] > values[sorted[j]]
+	})
+	var res [][]int
+	for i, option := range sorted {
+		if i == 0 || values[option] != values[sorted[i-1]] {
+			res = append(res, nil)
+		}
+		res[len(res)-1] = append(res[len(res)-1], option)
+	}
+	return res
+}
+
+// computeSchulzeRanking flattens groups into a single ranking, the options in each group are ordered according to
+// order. rules[i] is the rule that ordered ranking[i] before ranking[i + 1].
+func computeSchulzeRanking(d SchulzeMatrix, groups SchulzeWinsList, order SchulzeSecondaryOrder) (ranking []int,
+	rules []SchulzeRankingRule) {
+	for _, group := range groups {
+		for i, tier := range splitGroup(d, group, order) {
+			for j, option := range tier {
+				if len(ranking) > 0 {
+					switch {
+					case i == 0 && j == 0:
+						rules = append(rules, SchulzeRankedByPaths)
+					case j == 0:
+						rules = append(rules, SchulzeRankedBySecondaryOrder)
+					default:
+						rules = append(rules, SchulzeRankedTied)
+					}
+				}
+				ranking = append(ranking, option)
+			}
+		}
+	}
+	return
+}
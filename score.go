@@ -0,0 +1,617 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxScore is the max score of a ScorePoll if nothing else is configured, each option gets 0 to 5 points.
+const DefaultMaxScore uint8 = 5
+
+// ScoreVote is a vote for a ScorePoll.
+// It is described by the voter and the scores the voter gave to each option (Scores[i] is the score of option i).
+// It implements the interface AbstractVote.
+// The source of the vote is stored in the embedded VoteSourceHolder, see SourcedVote.
+type ScoreVote struct {
+	Voter  *Voter
+	Scores []uint8
+	VoteSourceHolder
+}
+
+// NewScoreVote returns a new ScoreVote.
+func NewScoreVote(voter *Voter, scores []uint8) *ScoreVote {
+	return &ScoreVote{
+		Voter:  voter,
+		Scores: scores,
+	}
+}
+
+// Equals tests if two votes are equal, i.e. have the same scores and equal voters (see Voter.Equals).
+func (vote *ScoreVote) Equals(other *ScoreVote) bool {
+	if len(vote.Scores) != len(other.Scores) {
+		return false
+	}
+	for i, score := range vote.Scores {
+		if score != other.Scores[i] {
+			return false
+		}
+	}
+	return votersEqual(vote.Voter, other.Voter)
+}
+
+// GetVoter returns the voter of the vote.
+func (vote *ScoreVote) GetVoter() *Voter {
+	return vote.Voter
+}
+
+// VoteType returns the constant ScoreVoteType.
+func (vote *ScoreVote) VoteType() string {
+	return ScoreVoteType
+}
+
+// IsAbstention returns true if all options got a score of 0.
+func (vote *ScoreVote) IsAbstention() bool {
+	for _, score := range vote.Scores {
+		if score != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// formatScores returns the scores as a comma separated list, i.e. in the format parsed by ScoreVoteParser.
+func formatScores(scores []uint8) string {
+	parts := make([]string, len(scores))
+	for i, score := range scores {
+		parts[i] = strconv.Itoa(int(score))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ScoreVoteParser implements VoteParser and returns an instance of ScoreVote in its ParseFromString method.
+//
+// The scores are assumed to be a comma separated list of integers, for example "5, 0, 3" (slashes are also okay,
+// so "5/0/3" would be the same).
+//
+// Length is the number of scores expected, if it is negative the length check is disabled. Each score must be
+// between 0 and MaxScore. If the length or a score is invalid a PollingSemanticError is returned.
+//
+// If KeepRaw is true the parsed string is stored as the source of each vote, see VoteSource.
+//
+// It also implements ParserCustomizer, CustomizeForPoll sets Length and MaxScore to the values of the poll.
+type ScoreVoteParser struct {
+	Length   int
+	MaxScore uint8
+	KeepRaw  bool
+}
+
+// NewScoreVoteParser returns a new ScoreVoteParser, MaxScore is set to DefaultMaxScore.
+//
+// The length argument is allowed to be negative in which case the length check is disabled.
+func NewScoreVoteParser(length int) *ScoreVoteParser {
+	return &ScoreVoteParser{
+		Length:   length,
+		MaxScore: DefaultMaxScore,
+		KeepRaw:  false,
+	}
+}
+
+// CustomizeForPoll implements ParserCustomizer and returns a new parser with Length and MaxScore set if a
+// *ScorePoll is given.
+func (parser *ScoreVoteParser) CustomizeForPoll(poll AbstractPoll) (ParserCustomizer, error) {
+	if asScorePoll, ok := poll.(*ScorePoll); ok {
+		res := *parser
+		res.Length = asScorePoll.NumOptions
+		res.MaxScore = asScorePoll.MaxScore
+		return &res, nil
+	}
+	return nil, NewPollTypeError("can't customize ScoreVoteParser for type %s, expected type *ScorePoll",
+		reflect.TypeOf(poll))
+}
+
+// ParseFromString implements the VoteParser interface, for details see type description.
+func (parser *ScoreVoteParser) ParseFromString(s string, voter *Voter) (AbstractVote, error) {
+	split := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '/'
+	})
+	if parser.Length >= 0 && len(split) != parser.Length {
+		return nil, NewPollingSemanticError(nil, "%d scores were expected, got %d", parser.Length, len(split))
+	}
+	scores := make([]uint8, len(split))
+	for i, asString := range split {
+		asInt, intErr := strconv.Atoi(strings.TrimSpace(asString))
+		if intErr != nil {
+			return nil, NewPollingSyntaxError(intErr, "can't parse scores, invalid score string")
+		}
+		if asInt < 0 || asInt > int(parser.MaxScore) {
+			return nil, NewPollingSemanticError(nil, "score %d at position %d is out of bounds [0, %d]",
+				asInt, i, parser.MaxScore)
+		}
+		scores[i] = uint8(asInt)
+	}
+	return keepRawSource(NewScoreVote(voter, scores), s, parser.KeepRaw), nil
+}
+
+// ScoreVoteFormatter implements VoteFormatter for a ScoreVote, the scores are formatted as a comma separated list,
+// for example "5, 0, 3".
+type ScoreVoteFormatter struct{}
+
+// NewScoreVoteFormatter returns a new ScoreVoteFormatter.
+func NewScoreVoteFormatter() ScoreVoteFormatter {
+	return ScoreVoteFormatter{}
+}
+
+// FormatVote implements VoteFormatter, vote must be a *ScoreVote.
+func (formatter ScoreVoteFormatter) FormatVote(vote AbstractVote) (string, error) {
+	scoreVote, ok := vote.(*ScoreVote)
+	if !ok {
+		return "", NewPollTypeError("can't format vote of type %s with ScoreVoteFormatter, expected type *ScoreVote",
+			reflect.TypeOf(vote))
+	}
+	return formatScores(scoreVote.Scores), nil
+}
+
+// ScorePoll is a poll in which each voter gives each option a score between 0 and MaxScore. It implements the
+// interface AbstractPoll.
+//
+// The options with the highest (weighted) score sum win, see Tally. With TallyWithRunoff the STAR method
+// (score then automatic runoff) is used: the two options with the highest score sums compete in a runoff, the
+// option that is preferred (has a higher score) by more voters wins.
+//
+// This type also implements VoteGenerator and VoteValidator.
+//
+// NoOptionIndex is the index of the option that stands for "no" (the status quo), it is used to generate votes from
+// basic answers. NoScoreNoOption (-1) means that the poll has no such option.
+//
+// Sealed votes are stored in the embedded SealedVoteBuffer, see Reveal.
+// Duplicate votes can be rejected with the embedded DuplicateVoterGuard.
+// Application data can be attached with the embedded PollMetadata.
+// The poll can be closed with the embedded PollCloser, a closed poll rejects new votes.
+type ScorePoll struct {
+	NumOptions    int
+	MaxScore      uint8
+	NoOptionIndex int
+	Votes         []*ScoreVote
+	SealedVoteBuffer
+	DuplicateVoterGuard
+	PollMetadata
+	PollCloser
+}
+
+// NoScoreNoOption is the NoOptionIndex of a ScorePoll without a "no" option.
+const NoScoreNoOption = -1
+
+// NewScorePoll returns a new ScorePoll.
+// numOptions must be >= 0, otherwise this function panics.
+// Note that the votes are not validated, use TruncateVoters to identify invalid votes.
+//
+// NoOptionIndex is set to the last option (NoScoreNoOption if numOptions is 0).
+func NewScorePoll(numOptions int, maxScore uint8, votes []*ScoreVote) *ScorePoll {
+	if numOptions < 0 {
+		panic(fmt.Sprintf("Num options in ScorePoll must be >= 0, got %d", numOptions))
+	}
+	return &ScorePoll{
+		NumOptions:    numOptions,
+		MaxScore:      maxScore,
+		NoOptionIndex: numOptions - 1,
+		Votes:         votes,
+	}
+}
+
+// hasNoOption returns true if NoOptionIndex is a valid option index.
+func (poll *ScorePoll) hasNoOption() bool {
+	return poll.NoOptionIndex >= 0 && poll.NoOptionIndex < poll.NumOptions
+}
+
+// DeepClone returns a copy of the poll with a new votes slice and new vote objects (including a copy of the
+// scores).
+// The voter objects are not copied, the votes of the clone point to the same voters as the original.
+func (poll *ScorePoll) DeepClone() *ScorePoll {
+	votes := make([]*ScoreVote, len(poll.Votes))
+	for i, vote := range poll.Votes {
+		var scores []uint8
+		if vote.Scores != nil {
+			scores = make([]uint8, len(vote.Scores))
+			copy(scores, vote.Scores)
+		}
+		votes[i] = NewScoreVote(vote.Voter, scores)
+		votes[i].VoteSourceHolder = vote.VoteSourceHolder.clone()
+	}
+	res := NewScorePoll(poll.NumOptions, poll.MaxScore, votes)
+	res.NoOptionIndex = poll.NoOptionIndex
+	res.SealedVoteBuffer = poll.SealedVoteBuffer.clone()
+	res.DuplicateVoterGuard = poll.DuplicateVoterGuard.clone()
+	res.PollMetadata = poll.PollMetadata.clone()
+	res.PollCloser = poll.PollCloser.clone()
+	return res
+}
+
+// AddSealed implements SealedVoteAcceptor and adds a sealed vote to the embedded SealedVoteBuffer.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+func (poll *ScorePoll) AddSealed(vote *SealedVote) error {
+	return poll.SealedVoteBuffer.addSealed(&poll.PollCloser, vote)
+}
+
+// Equals tests if two polls have the same number of options, max score and no option and contain equal votes (in
+// the same order).
+func (poll *ScorePoll) Equals(other *ScorePoll) bool {
+	if poll.NumOptions != other.NumOptions || poll.MaxScore != other.MaxScore ||
+		poll.NoOptionIndex != other.NoOptionIndex || len(poll.Votes) != len(other.Votes) {
+		return false
+	}
+	for i, vote := range poll.Votes {
+		if !vote.Equals(other.Votes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// PollType returns the constant ScorePollType.
+func (poll *ScorePoll) PollType() string {
+	return ScorePollType
+}
+
+// AddVote adds a vote to the poll, the vote must be of type *ScoreVote.
+//
+// If RejectDuplicateVoters is true a DuplicateError is returned if the voter already has a vote, see
+// DuplicateVoterGuard.
+// If the poll is closed an error wrapping ErrPollClosed is returned, see PollCloser.
+//
+// Note that no validation is happening here! I.e. the vote can have a different number of scores than
+// poll.NumOptions or scores > poll.MaxScore, see ValidateVote and TruncateVoters.
+func (poll *ScorePoll) AddVote(vote AbstractVote) error {
+	asScoreVote, ok := vote.(*ScoreVote)
+	if !ok {
+		return NewPollTypeError("can't add vote to ScorePoll, vote must be of type *ScoreVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if closedErr := poll.checkOpenForVoter("add vote", asScoreVote.Voter); closedErr != nil {
+		return closedErr
+	}
+	if duplicateErr := poll.checkNewVoter(asScoreVote.Voter, len(poll.Votes), poll.forEachVoter); duplicateErr != nil {
+		return duplicateErr
+	}
+	poll.Votes = append(poll.Votes, asScoreVote)
+	return nil
+}
+
+// ForEachVote implements VoteIterator, it calls f for each vote in the order in which they appear in the poll.
+func (poll *ScorePoll) ForEachVote(f func(vote AbstractVote) error) error {
+	return forEachVote(poll, f)
+}
+
+// NumVotes implements VoteIterator and returns the number of votes in the poll.
+func (poll *ScorePoll) NumVotes() int {
+	return len(poll.Votes)
+}
+
+// voteAt returns the vote with index i, it implements indexedVotes.
+func (poll *ScorePoll) voteAt(i int) AbstractVote {
+	return poll.Votes[i]
+}
+
+// forEachVoter calls f with the voter of each vote, used by DuplicateVoterGuard.
+func (poll *ScorePoll) forEachVoter(f func(voter *Voter)) {
+	for _, vote := range poll.Votes {
+		f(vote.Voter)
+	}
+}
+
+// HasVoteFrom implements VoteEditor and returns true if the voter with the given name has a vote in the poll.
+func (poll *ScorePoll) HasVoteFrom(voterName string) bool {
+	return voteIndex(poll, voterName) >= 0
+}
+
+// RemoveVote implements VoteEditor and removes the vote of the voter with the given name, the removed vote is
+// returned.
+//
+// If the voter has multiple votes only the first one is removed. The order of the remaining votes is retained.
+// If the voter has no vote a VoteNotFoundError is returned.
+func (poll *ScorePoll) RemoveVote(voterName string) (AbstractVote, error) {
+	if closedErr := poll.checkOpen("remove vote", voterName); closedErr != nil {
+		return nil, closedErr
+	}
+	index := voteIndex(poll, voterName)
+	if index < 0 {
+		return nil, NewVoteNotFoundError(voterName)
+	}
+	res := poll.Votes[index]
+	poll.Votes = append(poll.Votes[:index], poll.Votes[index+1:]...)
+	poll.ResetVoterIndex()
+	return res, nil
+}
+
+// ReplaceVote implements VoteEditor, the vote must be of type *ScoreVote.
+//
+// The vote replaces the first vote of its voter (at the same position), all other votes of the voter are removed.
+// If the voter has no vote yet the vote is added.
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if the vote has no voter.
+func (poll *ScorePoll) ReplaceVote(vote AbstractVote) error {
+	asScoreVote, ok := vote.(*ScoreVote)
+	if !ok {
+		return NewPollTypeError("can't replace vote in ScorePoll, vote must be of type *ScoreVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asScoreVote.Voter == nil {
+		return NewPollingSemanticError(nil, "can't replace vote in ScorePoll, vote has no voter")
+	}
+	name := asScoreVote.Voter.Name
+	if closedErr := poll.checkOpen("replace vote", name); closedErr != nil {
+		return closedErr
+	}
+	index := voteIndex(poll, name)
+	if index < 0 {
+		return poll.AddVote(vote)
+	}
+	poll.Votes[index] = asScoreVote
+	// remove all other votes of the voter
+	filtered := poll.Votes[:index+1]
+	for _, other := range poll.Votes[index+1:] {
+		if other.Voter == nil || other.Voter.Name != name {
+			filtered = append(filtered, other)
+		}
+	}
+	poll.Votes = filtered
+	poll.ResetVoterIndex()
+	return nil
+}
+
+// validScores returns an error if scores doesn't have length poll.NumOptions or contains a score > poll.MaxScore.
+func (poll *ScorePoll) validScores(voterName string, scores []uint8) error {
+	if len(scores) != poll.NumOptions {
+		return NewPollingSemanticError(nil, "scores of voter \"%s\" have length %d, expected length %d",
+			voterName, len(scores), poll.NumOptions)
+	}
+	for i, score := range scores {
+		if score > poll.MaxScore {
+			return NewPollingSemanticError(nil, "score %d of voter \"%s\" at position %d is greater than the max score %d",
+				score, voterName, i, poll.MaxScore)
+		}
+	}
+	return nil
+}
+
+// ValidateVote tests if vote can be added to the poll, it must be of type *ScoreVote with a voter, exactly
+// poll.NumOptions scores and each score must be <= poll.MaxScore.
+//
+// It returns a PollTypeError if the vote has the wrong type and a PollingSemanticError if it is invalid.
+func (poll *ScorePoll) ValidateVote(vote AbstractVote) error {
+	asScoreVote, ok := vote.(*ScoreVote)
+	if !ok {
+		return NewPollTypeError("invalid vote for ScorePoll, vote must be of type *ScoreVote, got type %s",
+			reflect.TypeOf(vote))
+	}
+	if asScoreVote.Voter == nil {
+		return NewPollingSemanticError(nil, "vote for ScorePoll has no voter")
+	}
+	return poll.validScores(asScoreVote.Voter.Name, asScoreVote.Scores)
+}
+
+// GenerateVoteFromBasicAnswer implements VoteGenerator and returns a ScoreVote.
+//
+// For Aye each option gets MaxScore and the no option 0, for No the no option gets MaxScore and all other options 0.
+// For Abstention all options get 0.
+// If the poll has no no option (see NoOptionIndex) a PollTypeError is returned for Aye and No.
+func (poll *ScorePoll) GenerateVoteFromBasicAnswer(voter *Voter, answer BasicPollAnswer) (AbstractVote, error) {
+	scores := make([]uint8, poll.NumOptions)
+	switch answer {
+	case No, Aye:
+		if !poll.hasNoOption() {
+			return nil, NewPollTypeError("can't generate vote for answer \"%s\", poll has no \"no\" option", answer)
+		}
+		for i := range scores {
+			if (i == poll.NoOptionIndex) == (answer == No) {
+				scores[i] = poll.MaxScore
+			}
+		}
+		return NewScoreVote(voter, scores), nil
+	case Abstention:
+		return NewScoreVote(voter, scores), nil
+	default:
+		return nil, NewPollTypeError("invalid poll answer %d", answer)
+	}
+}
+
+// TruncateVoters removes all votes that don't have exactly poll.NumOptions scores or contain a score >
+// poll.MaxScore.
+//
+// If such culprits are found they are removed from poll.Votes. In this case a new slice of votes
+// will be allocated containing the original vote objects.
+// All culprits are returned (for logging or error handling).
+func (poll *ScorePoll) TruncateVoters() []*ScoreVote {
+	culprits := make([]*ScoreVote, 0)
+	filtered := make([]*ScoreVote, 0, len(poll.Votes))
+	for _, vote := range poll.Votes {
+		if poll.validScores("", vote.Scores) != nil {
+			culprits = append(culprits, vote)
+		} else {
+			filtered = append(filtered, vote)
+		}
+	}
+	if len(culprits) > 0 {
+		poll.Votes = filtered
+	}
+	return culprits
+}
+
+// CheckedWeightSum returns the sum of the effective weights of all votes, if the sum overflows an error wrapping
+// ErrWeightOverflow is returned.
+func (poll *ScorePoll) CheckedWeightSum() (Weight, error) {
+	return checkedWeightSum(poll, nil)
+}
+
+// ScoreRunoff is the automatic runoff of a STAR poll, see ScorePoll.TallyWithRunoff.
+//
+// First and Second are the two options with the highest score sums (First has the higher sum or the smaller index
+// if the sums are equal). PreferFirst is the weight of all voters that gave First a higher score than Second,
+// PreferSecond the other way round and NoPreference the weight of the voters that gave both the same score.
+//
+// Winner is the option preferred by more weight, if both are preferred by the same weight the option with the
+// higher score sum wins (i.e. First). If the score sums are equal too Winner is -1.
+type ScoreRunoff struct {
+	First, Second int
+	PreferFirst   Weight
+	PreferSecond  Weight
+	NoPreference  Weight
+	Winner        int
+}
+
+// ScoreResult is the result of evaluating a score poll, see Tally and TallyWithRunoff.
+//
+// ScoreSums[i] is the weighted sum of the scores of option i (each score is multiplied with the weight of the
+// voter), Averages[i] is ScoreSums[i] divided by the weight of the valid votes (WeightSum - InvalidWeight, the
+// average is 0 if this weight is 0).
+// Only valid votes (exactly NumOptions scores, each <= MaxScore) are counted, WeightSum is the sum of the weights of
+// all votes in the poll (including invalid votes, see TruncateVoters). InvalidVotesCount is the number of invalid
+// votes and InvalidWeight the sum of their weights.
+//
+// RankedGroups contains the options grouped by score sum, the options with the highest sum come first.
+//
+// Runoff is only set by TallyWithRunoff (for polls with at least two options), otherwise it is nil.
+//
+// Err is nil unless the weights of the votes overflow, in this case it wraps ErrWeightOverflow and all sums are 0.
+type ScoreResult struct {
+	ScoreSums    []uint64
+	Averages     []*big.Rat
+	WeightSum    Weight
+	RankedGroups [][]int
+	Runoff       *ScoreRunoff
+	// InvalidVotesCount and InvalidWeight describe the votes that were ignored, see above.
+	InvalidVotesCount int
+	InvalidWeight     Weight
+	Err               error
+}
+
+// Tally computes the weighted score sums and averages of all options and ranks them by score sum.
+func (poll *ScorePoll) Tally() *ScoreResult {
+	n := poll.NumOptions
+	res := &ScoreResult{
+		ScoreSums: make([]uint64, n),
+		Averages:  make([]*big.Rat, n),
+	}
+	sum, sumErr := poll.CheckedWeightSum()
+	if sumErr != nil {
+		for i := range res.Averages {
+			res.Averages[i] = big.NewRat(0, 1)
+		}
+		res.WeightSum = NoWeight
+		res.Err = sumErr
+		return res
+	}
+	res.WeightSum = sum
+	// each sum is bounded by sum * 255, thus it can't overflow
+	for _, vote := range poll.Votes {
+		w := vote.Voter.EffectiveWeight()
+		if poll.validScores("", vote.Scores) != nil {
+			res.InvalidVotesCount++
+			// can't overflow because the sum of all weights didn't overflow
+			res.InvalidWeight += w
+			continue
+		}
+		for i, score := range vote.Scores {
+			res.ScoreSums[i] += uint64(w) * uint64(score)
+		}
+	}
+	validWeight := sum - res.InvalidWeight
+	for i, scoreSum := range res.ScoreSums {
+		if validWeight == 0 {
+			res.Averages[i] = big.NewRat(0, 1)
+		} else {
+			res.Averages[i] = new(big.Rat).SetFrac(new(big.Int).SetUint64(scoreSum),
+				new(big.Int).SetUint64(uint64(validWeight)))
+		}
+	}
+	res.RankedGroups = rankScoreSums(res.ScoreSums)
+	return res
+}
+
+// rankScoreSums groups the option indices by score sum, highest sum first.
+func rankScoreSums(sums []uint64) [][]int {
+	var res [][]int
+	remaining := make([]bool, len(sums))
+	for i := range remaining {
+		remaining[i] = true
+	}
+	for numRanked := 0; numRanked < len(sums); {
+		var best uint64
+		found := false
+		for i, sum := range sums {
+			if remaining[i] && (!found || sum > best) {
+				best, found = sum, true
+			}
+		}
+		var group []int
+		for i, sum := range sums {
+			if remaining[i] && sum == best {
+				group = append(group, i)
+				remaining[i] = false
+			}
+		}
+		numRanked += len(group)
+		res = append(res, group)
+	}
+	return res
+}
+
+// TallyWithRunoff works as Tally but also computes the automatic runoff between the two options with the highest
+// score sums (STAR voting), see ScoreRunoff.
+//
+// If the poll has less than two options or the weights overflow Runoff is nil.
+func (poll *ScorePoll) TallyWithRunoff() *ScoreResult {
+	res := poll.Tally()
+	if res.Err != nil || poll.NumOptions < 2 {
+		return res
+	}
+	// the first two options in the ranked groups have the highest sums, groups are sorted by index
+	var top []int
+	for _, group := range res.RankedGroups {
+		top = append(top, group...)
+		if len(top) >= 2 {
+			break
+		}
+	}
+	runoff := &ScoreRunoff{First: top[0], Second: top[1], Winner: -1}
+	for _, vote := range poll.Votes {
+		if poll.validScores("", vote.Scores) != nil {
+			continue
+		}
+		w := vote.Voter.EffectiveWeight()
+		first, second := vote.Scores[runoff.First], vote.Scores[runoff.Second]
+		switch {
+		case first > second:
+			runoff.PreferFirst += w
+		case second > first:
+			runoff.PreferSecond += w
+		default:
+			runoff.NoPreference += w
+		}
+	}
+	switch {
+	case runoff.PreferFirst > runoff.PreferSecond:
+		runoff.Winner = runoff.First
+	case runoff.PreferSecond > runoff.PreferFirst:
+		runoff.Winner = runoff.Second
+	case res.ScoreSums[runoff.First] > res.ScoreSums[runoff.Second]:
+		runoff.Winner = runoff.First
+	}
+	res.Runoff = runoff
+	return res
+}
@@ -0,0 +1,280 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SealedVoteType is the VoteType of a SealedVote.
+const SealedVoteType = "sealed-vote"
+
+// SealedVote is a vote that has not been parsed yet, it stores the raw string as given by the voter.
+//
+// This is useful if votes are collected during a meeting but must stay secret until voting is closed, for example
+// because they're encrypted. Sealed votes are added to a poll with AddSealed (see SealedVoteAcceptor) and are
+// converted into real votes by Reveal.
+type SealedVote struct {
+	Voter *Voter
+	Raw   string
+}
+
+// NewSealedVote returns a new SealedVote.
+func NewSealedVote(voter *Voter, raw string) *SealedVote {
+	return &SealedVote{
+		Voter: voter,
+		Raw:   raw,
+	}
+}
+
+// GetVoter returns the voter of the vote.
+func (vote *SealedVote) GetVoter() *Voter {
+	return vote.Voter
+}
+
+// VoteType returns the constant SealedVoteType.
+func (vote *SealedVote) VoteType() string {
+	return SealedVoteType
+}
+
+// SealedVoteAcceptor describes polls that accept sealed votes.
+//
+// Sealed votes are stored in a side buffer and are never seen by Tally, they must be converted into real votes with
+// Reveal first.
+// AddSealed adds a vote to the buffer (if the poll is closed an error wrapping ErrPollClosed is returned, see
+// PollCloser), NumSealed returns the number of votes in the buffer and TakeSealed returns all votes from the buffer
+// and clears it.
+//
+// All polls implemented at the moment also implement this interface by embedding a SealedVoteBuffer.
+type SealedVoteAcceptor interface {
+	AbstractPoll
+	AddSealed(vote *SealedVote) error
+	NumSealed() int
+	TakeSealed() []*SealedVote
+}
+
+// SealedVoteBuffer is a buffer of sealed votes, it is embedded in all polls of this package to implement
+// SealedVoteAcceptor. The polls implement AddSealed themselves, because they must check if they're closed.
+//
+// The sealed votes are not compared by the Equals methods of the polls.
+// The zero value is an empty buffer.
+type SealedVoteBuffer struct {
+	sealed []*SealedVote
+}
+
+// addSealed adds a vote to the buffer if the poll (given by its closer) is open, otherwise an error wrapping
+// ErrPollClosed is returned.
+func (buffer *SealedVoteBuffer) addSealed(closer *PollCloser, vote *SealedVote) error {
+	if closedErr := closer.checkOpenForVoter("add sealed vote", vote.Voter); closedErr != nil {
+		return closedErr
+	}
+	buffer.sealed = append(buffer.sealed, vote)
+	return nil
+}
+
+// NumSealed returns the number of votes in the buffer.
+func (buffer *SealedVoteBuffer) NumSealed() int {
+	return len(buffer.sealed)
+}
+
+// TakeSealed returns all votes from the buffer (in the order in which they were added) and clears the buffer.
+func (buffer *SealedVoteBuffer) TakeSealed() []*SealedVote {
+	res := buffer.sealed
+	buffer.sealed = nil
+	return res
+}
+
+// restoreSealed adds votes to the buffer without checking if the poll is closed, it is used by Reveal to return votes
+// that could not be revealed (for example because the poll was closed).
+func (buffer *SealedVoteBuffer) restoreSealed(votes []*SealedVote) {
+	buffer.sealed = append(buffer.sealed, votes...)
+}
+
+// sealedVoteRestorer is implemented by all polls that embed a SealedVoteBuffer, see restoreSealed.
+type sealedVoteRestorer interface {
+	restoreSealed(votes []*SealedVote)
+}
+
+// clone returns a copy of the buffer with new vote objects, used in DeepClone of the polls.
+func (buffer *SealedVoteBuffer) clone() SealedVoteBuffer {
+	if buffer.sealed == nil {
+		return SealedVoteBuffer{}
+	}
+	sealed := make([]*SealedVote, len(buffer.sealed))
+	for i, vote := range buffer.sealed {
+		sealed[i] = NewSealedVote(vote.Voter, vote.Raw)
+	}
+	return SealedVoteBuffer{sealed: sealed}
+}
+
+// filter removes all votes from the buffer for which keep returns false, used in FilterVotes.
+func (buffer *SealedVoteBuffer) filter(keep func(voter *Voter) bool) {
+	if buffer.sealed == nil {
+		return
+	}
+	sealed := buffer.sealed[:0]
+	for _, vote := range buffer.sealed {
+		if keep(vote.Voter) {
+			sealed = append(sealed, vote)
+		}
+	}
+	buffer.sealed = sealed
+}
+
+// SealedVoteError is an error that occurred while revealing a single sealed vote.
+//
+// It contains the name of the poll and the name of the voter, Err is the original error, it is returned by Unwrap.
+type SealedVoteError struct {
+	Poll  string
+	Voter string
+	Err   error
+}
+
+func (err SealedVoteError) Error() string {
+	return fmt.Sprintf("poll \"%s\" (voter \"%s\"): %s", err.Poll, err.Voter, err.Err.Error())
+}
+
+// Unwrap returns the wrapped error.
+func (err SealedVoteError) Unwrap() error {
+	return err.Err
+}
+
+// RevealErrors is returned by Reveal if one or more sealed votes could not be revealed.
+//
+// Errors contains one entry for each vote that failed, sorted by poll name and then in the order in which the votes
+// were added.
+// errors.Is and errors.As test all of the wrapped errors, just like for PollMatrixErrors.
+type RevealErrors struct {
+	Errors []SealedVoteError
+}
+
+func (err RevealErrors) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, voteErr := range err.Errors {
+		messages[i] = voteErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the first error.
+//
+// Use Is and As (or the functions from the errors package) to test all errors.
+func (err RevealErrors) Unwrap() error {
+	if len(err.Errors) == 0 {
+		return nil
+	}
+	return err.Errors[0]
+}
+
+// Is returns true if errors.Is returns true for any of the errors.
+func (err RevealErrors) Is(target error) bool {
+	for _, voteErr := range err.Errors {
+		if errors.Is(voteErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target, see errors.As.
+func (err RevealErrors) As(target interface{}) bool {
+	for _, voteErr := range err.Errors {
+		if errors.As(voteErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// revealPoll reveals the sealed votes of a single poll, the votes that failed are returned to the buffer.
+func revealPoll(name string, poll AbstractPoll, parsers map[string]ParserCustomizer) ([]SealedVoteError, error) {
+	acceptor, ok := poll.(SealedVoteAcceptor)
+	if !ok || acceptor.NumSealed() == 0 {
+		return nil, nil
+	}
+	parser, hasParser := parsers[name]
+	if !hasParser {
+		return nil, NewPollTypeError("no parser for poll %s (type %s) found", name, reflect.TypeOf(poll))
+	}
+	var failed []SealedVoteError
+	var failedSealed []*SealedVote
+	for _, sealed := range acceptor.TakeSealed() {
+		var voteErr error
+		vote, parseErr := parser.ParseFromString(strings.TrimSpace(sealed.Raw), sealed.Voter)
+		if parseErr != nil {
+			voteErr = parseErr
+		} else {
+			voteErr = acceptor.AddVote(vote)
+		}
+		if voteErr != nil {
+			voterName := ""
+			if sealed.Voter != nil {
+				voterName = sealed.Voter.Name
+			}
+			failed = append(failed, SealedVoteError{Poll: name, Voter: voterName, Err: voteErr})
+			failedSealed = append(failedSealed, sealed)
+		}
+	}
+	// AddSealed fails for a closed poll, thus the buffer is used directly if possible
+	if restorer, ok := acceptor.(sealedVoteRestorer); ok {
+		restorer.restoreSealed(failedSealed)
+	} else {
+		for _, sealed := range failedSealed {
+			if addErr := acceptor.AddSealed(sealed); addErr != nil {
+				return failed, addErr
+			}
+		}
+	}
+	return failed, nil
+}
+
+// Reveal converts the sealed votes of all polls into real votes.
+//
+// parsers must contain the customized parser for each poll that has sealed votes, it can be created with
+// CustomizeParsersToMap. Each sealed vote is parsed with the parser of its poll and added with AddVote.
+// Polls that don't implement SealedVoteAcceptor are ignored. A SynchronizedPoll is revealed while holding its lock.
+//
+// Votes that can't be parsed (or added) stay in the sealed buffer of the poll, all others are removed from the
+// buffer. Thus the failed votes can be corrected (or removed with TakeSealed) and Reveal can be called again.
+// All failures are reported in a RevealErrors. If a poll with sealed votes has no parser a PollTypeError is
+// returned, the polls are processed sorted by name and processing stops at such a poll.
+func Reveal(polls PollMap, parsers map[string]ParserCustomizer) error {
+	var failed []SealedVoteError
+	for _, name := range polls.SortedNames() {
+		poll := polls[name]
+		var pollFailed []SealedVoteError
+		var err error
+		if synchronized, ok := poll.(SynchronizedPoll); ok {
+			err = synchronized.WithLock(func(unwrapped AbstractPoll) error {
+				var revealErr error
+				pollFailed, revealErr = revealPoll(name, unwrapped, parsers)
+				return revealErr
+			})
+		} else {
+			pollFailed, err = revealPoll(name, poll, parsers)
+		}
+		if err != nil {
+			return err
+		}
+		failed = append(failed, pollFailed...)
+	}
+	if len(failed) > 0 {
+		return RevealErrors{Errors: failed}
+	}
+	return nil
+}
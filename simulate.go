@@ -0,0 +1,244 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+)
+
+// This file contains functions to generate random voters, polls and votes.
+// They're intended for load testing and benchmarks, for example to test how an application behaves with thousands of
+// voters and hundreds of polls. All random values are generated from a seed, so the same seed always produces the
+// same output.
+
+// GenerateVoters generates n voters with the names "voter-1" to "voter-n".
+//
+// weightDist is called for each voter (with the index i of the voter, starting at 0) and returns the weight of that
+// voter. If weightDist is nil all voters have weight 1.
+func GenerateVoters(n int, weightDist func(i int) Weight) []*Voter {
+	if n < 0 {
+		n = 0
+	}
+	res := make([]*Voter, n)
+	for i := 0; i < n; i++ {
+		var weight Weight = 1
+		if weightDist != nil {
+			weight = weightDist(i)
+		}
+		res[i] = NewVoter(fmt.Sprintf("voter-%d", i+1), weight)
+	}
+	return res
+}
+
+// DefaultSimulationSchulzeOptions is the number of options used by GenerateCollection if
+// CollectionSpec.NumSchulzeOptions is not set.
+const DefaultSimulationSchulzeOptions = 3
+
+// DefaultSimulationMedianValue is the value used by GenerateCollection for median polls if CollectionSpec.MedianValue
+// is not set, it is 1000.00 €.
+var DefaultSimulationMedianValue = NewCurrencyValue(100000, "€")
+
+// CollectionSpec describes the collection generated by GenerateCollection.
+//
+// NumBasicPolls, NumMedianPolls and NumSchulzePolls are the number of polls of each type. The polls are distributed
+// over NumGroups groups, no group is empty (so if NumGroups is greater than the number of polls there are less
+// groups). A NumGroups < 1 is treated as 1.
+//
+// NumSchulzeOptions is the number of options for each Schulze poll, it must be at least 3 (with two options
+// DefaultSkeletonConverter would create a basic poll), if it is smaller DefaultSimulationSchulzeOptions is used.
+//
+// MedianValue is the value of each median poll, if its ValueCents is 0 DefaultSimulationMedianValue is used.
+type CollectionSpec struct {
+	Title             string
+	NumGroups         int
+	NumBasicPolls     int
+	NumMedianPolls    int
+	NumSchulzePolls   int
+	NumSchulzeOptions int
+	MedianValue       CurrencyValue
+}
+
+// GenerateCollection generates a collection as described by spec.
+//
+// The polls have the names "Basic poll i", "Median poll i" and "Schulze poll i", the poll types alternate (basic,
+// median, Schulze, basic, ...) as long as polls of this type are left. The groups are named "Group i".
+// Basic polls have the options "Yes" and "No", Schulze polls the options "Option 1" to "Option n".
+func GenerateCollection(spec CollectionSpec) *PollSkeletonCollection {
+	numSchulzeOptions := spec.NumSchulzeOptions
+	if numSchulzeOptions < 3 {
+		numSchulzeOptions = DefaultSimulationSchulzeOptions
+	}
+	medianValue := spec.MedianValue
+	if medianValue.ValueCents == 0 {
+		medianValue = DefaultSimulationMedianValue
+	}
+
+	skels := make([]AbstractPollSkeleton, 0, spec.NumBasicPolls+spec.NumMedianPolls+spec.NumSchulzePolls)
+	numBasic, numMedian, numSchulze := 0, 0, 0
+	for numBasic < spec.NumBasicPolls || numMedian < spec.NumMedianPolls || numSchulze < spec.NumSchulzePolls {
+		if numBasic < spec.NumBasicPolls {
+			numBasic++
+			skel := NewPollSkeleton(fmt.Sprintf("Basic poll %d", numBasic))
+			skel.AddOption("Yes", "")
+			skel.AddOption("No", "")
+			skels = append(skels, skel)
+		}
+		if numMedian < spec.NumMedianPolls {
+			numMedian++
+			skels = append(skels, NewMoneyPollSkeleton(fmt.Sprintf("Median poll %d", numMedian), medianValue))
+		}
+		if numSchulze < spec.NumSchulzePolls {
+			numSchulze++
+			skel := NewPollSkeleton(fmt.Sprintf("Schulze poll %d", numSchulze))
+			for i := 1; i <= numSchulzeOptions; i++ {
+				skel.AddOption(fmt.Sprintf("Option %d", i), "")
+			}
+			skels = append(skels, skel)
+		}
+	}
+
+	res := NewPollSkeletonCollection(spec.Title)
+	if len(skels) == 0 {
+		return res
+	}
+	numGroups := spec.NumGroups
+	if numGroups < 1 {
+		numGroups = 1
+	}
+	if numGroups > len(skels) {
+		numGroups = len(skels)
+	}
+	// distribute the polls evenly, the first groups get one more poll if the polls can't be distributed evenly
+	start := 0
+	for i := 0; i < numGroups; i++ {
+		size := len(skels) / numGroups
+		if i < len(skels)%numGroups {
+			size++
+		}
+		group := NewPollGroup(fmt.Sprintf("Group %d", i+1))
+		group.Skeletons = append(group.Skeletons, skels[start:start+size]...)
+		res.Groups = append(res.Groups, group)
+		start += size
+	}
+	return res
+}
+
+// FillRandomVotes adds a random vote for each voter to each poll in polls.
+//
+// The polls are filled in the order of their sorted names and for each poll the voters are used in the order of
+// voters. With the probability emptyRate (a value between 0 and 1) a voter doesn't vote for a poll (this simulates
+// empty cells in a csv file). The random values are generated from seed, so calling this function with the same
+// seed and equal (empty) polls always creates the same votes.
+//
+// Supported polls are *BasicPoll, *MedianPoll, *SchulzePoll and *ScorePoll, the votes are always valid for the poll:
+// Median votes are between 0 and the value of the poll, Schulze rankings contain values between 0 and NumOptions - 1
+// and scores are between 0 and MaxScore. For all other types a PollTypeError is returned.
+// Any error from adding a vote (for example if the voter already voted and RejectDuplicateVoters is set) is returned.
+func FillRandomVotes(polls PollMap, voters []*Voter, seed int64, emptyRate float64) error {
+	rnd := rand.New(rand.NewSource(seed))
+	for _, name := range polls.SortedNames() {
+		poll := polls[name]
+		for _, voter := range voters {
+			if rnd.Float64() < emptyRate {
+				continue
+			}
+			vote, voteErr := generateRandomVote(rnd, poll, voter)
+			if voteErr != nil {
+				return voteErr
+			}
+			if addErr := poll.AddVote(vote); addErr != nil {
+				return addErr
+			}
+		}
+	}
+	return nil
+}
+
+func generateRandomVote(rnd *rand.Rand, poll AbstractPoll, voter *Voter) (AbstractVote, error) {
+	switch typedPoll := poll.(type) {
+	case *BasicPoll:
+		return NewBasicVote(voter, BasicPollAnswer(rnd.Intn(3))), nil
+	case *MedianPoll:
+		if typedPoll.Value >= math.MaxInt64 {
+			return NewMedianVote(voter, MedianUnit(rnd.Int63())), nil
+		}
+		return NewMedianVote(voter, MedianUnit(rnd.Int63n(int64(typedPoll.Value)+1))), nil
+	case *SchulzePoll:
+		ranking := make(SchulzeRanking, typedPoll.NumOptions)
+		for i := range ranking {
+			ranking[i] = rnd.Intn(typedPoll.NumOptions)
+		}
+		return NewSchulzeVote(voter, ranking), nil
+	case *ScorePoll:
+		scores := make([]uint8, typedPoll.NumOptions)
+		for i := range scores {
+			scores[i] = uint8(rnd.Intn(int(typedPoll.MaxScore) + 1))
+		}
+		return NewScoreVote(voter, scores), nil
+	default:
+		return nil, NewPollTypeError("can't generate random votes for poll of type %s", reflect.TypeOf(poll))
+	}
+}
+
+// WriteRandomCSV writes a votes csv file with random votes to w.
+//
+// The skeletons from collection are converted with DefaultSkeletonConverter and filled with FillRandomVotes (see
+// there for seed and emptyRate). The votes are formatted with DefaultVoteFormatterMap and written with
+// VotesCSVWriter.GenerateTemplateWithVotes, thus the file can be read by Evaluate with the default options.
+//
+// It returns any error from converting the skeletons, generating the votes or writing to w.
+func WriteRandomCSV(w io.Writer, voters []*Voter, collection *PollSkeletonCollection, seed int64,
+	emptyRate float64) error {
+	skelMap, skelErr := collection.SkeletonsToMap()
+	if skelErr != nil {
+		return skelErr
+	}
+	polls, convertErr := ConvertSkeletonMapToEmptyPolls(skelMap, nil)
+	if convertErr != nil {
+		return convertErr
+	}
+	if fillErr := FillRandomVotes(polls, voters, seed, emptyRate); fillErr != nil {
+		return fillErr
+	}
+	existing := make(map[string]map[string]string, len(voters))
+	for pollName, poll := range polls {
+		iterator, ok := poll.(VoteIterator)
+		if !ok {
+			return NewPollTypeError("can't iterate votes of poll of type %s", reflect.TypeOf(poll))
+		}
+		err := iterator.ForEachVote(func(vote AbstractVote) error {
+			formatted, formatErr := FormatVote(vote, DefaultVoteFormatterMap)
+			if formatErr != nil {
+				return formatErr
+			}
+			voterName := vote.GetVoter().Name
+			voterVotes, has := existing[voterName]
+			if !has {
+				voterVotes = make(map[string]string)
+				existing[voterName] = voterVotes
+			}
+			voterVotes[pollName] = formatted
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return NewVotesCSVWriter(w).GenerateTemplateWithVotes(voters, collection.CollectSkeletons(), existing)
+}
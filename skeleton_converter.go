@@ -0,0 +1,201 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	skeletonConvertersMutex sync.RWMutex
+	skeletonConverters      = map[string]SkeletonConverter{
+		MoneyPollSkeletonType:   DefaultSkeletonConverter,
+		GeneralPollSkeletonType: DefaultSkeletonConverter,
+	}
+)
+
+// RegisterSkeletonConverter registers the converter f for all skeletons with the given skeleton type (as returned
+// by AbstractPollSkeleton.SkeletonType), it is used by RegistryConverter.
+//
+// This way new skeleton and poll types can be used without writing a SkeletonConverter that handles all types.
+// MoneyPollSkeletonType and GeneralPollSkeletonType are registered with DefaultSkeletonConverter, registering another
+// converter for them replaces it. If f is nil the converter for skeletonType is removed.
+//
+// It is safe to call this function concurrently, but usually converters should be registered once (for example in
+// an init function).
+func RegisterSkeletonConverter(skeletonType string, f func(skel AbstractPollSkeleton) (AbstractPoll, error)) {
+	skeletonConvertersMutex.Lock()
+	defer skeletonConvertersMutex.Unlock()
+	if f == nil {
+		delete(skeletonConverters, skeletonType)
+		return
+	}
+	skeletonConverters[skeletonType] = f
+}
+
+// RegistryConverter is a SkeletonConverter that uses the converter registered for the type of skel, see
+// RegisterSkeletonConverter.
+//
+// If no converter is registered for the type a PollTypeError is returned. If the registered converter doesn't set the
+// metadata of the poll the metadata of the skeleton is copied, see PollMetadata.
+func RegistryConverter(skel AbstractPollSkeleton) (AbstractPoll, error) {
+	skeletonConvertersMutex.RLock()
+	converter, has := skeletonConverters[skel.SkeletonType()]
+	skeletonConvertersMutex.RUnlock()
+	if !has {
+		return nil, NewPollTypeError("no converter registered for skeleton type \"%s\" (poll \"%s\")",
+			skel.SkeletonType(), skel.GetName())
+	}
+	poll, err := converter(skel)
+	if err != nil {
+		return nil, err
+	}
+	copySkeletonMeta(skel, poll)
+	return poll, nil
+}
+
+// ConvertedGroup is a group of a ConvertedCollection.
+//
+// Skeletons are the skeletons of the original PollGroup and Polls[i] is the poll created for Skeletons[i].
+type ConvertedGroup struct {
+	Title     string
+	Skeletons []AbstractPollSkeleton
+	Polls     []AbstractPoll
+}
+
+// ConvertedCollection is the result of ConvertCollection, it keeps the group structure of a PollSkeletonCollection
+// and the association between skeletons and the polls created for them.
+//
+// Skeletons contains all skeletons (in the order in which they appear in the collection) and Polls[i] is the poll
+// created for Skeletons[i]. The slices in the groups share the underlying arrays with these slices.
+//
+// The polls can be accessed by position (Skeletons and Polls), by name (see IndexOf, PollByName and SkeletonByName)
+// and the skeleton of a poll can be found with SkeletonOf.
+type ConvertedCollection struct {
+	Title     string
+	Groups    []*ConvertedGroup
+	Skeletons []AbstractPollSkeleton
+	Polls     []AbstractPoll
+	indices   map[string]int
+}
+
+// ConvertCollection converts all skeletons in coll with conv (DefaultSkeletonConverter if conv is nil).
+//
+// The metadata is copied as in ConvertSkeletonsToPolls.
+// If the collection contains duplicate names a DuplicateError is returned, errors from conv are returned
+// unchanged.
+func ConvertCollection(coll *PollSkeletonCollection, conv SkeletonConverter) (*ConvertedCollection, error) {
+	if conv == nil {
+		conv = DefaultSkeletonConverter
+	}
+	skeletons := coll.CollectSkeletons()
+	res := &ConvertedCollection{
+		Title:     coll.Title,
+		Groups:    make([]*ConvertedGroup, len(coll.Groups)),
+		Skeletons: skeletons,
+		Polls:     make([]AbstractPoll, len(skeletons)),
+		indices:   make(map[string]int, len(skeletons)),
+	}
+	for i, skel := range skeletons {
+		name := skel.GetName()
+		if _, has := res.indices[name]; has {
+			return nil, NewDuplicateError(fmt.Sprintf("duplicate entry for poll %s", name))
+		}
+		res.indices[name] = i
+		poll, convertErr := conv(skel)
+		if convertErr != nil {
+			return nil, convertErr
+		}
+		copySkeletonMeta(skel, poll)
+		res.Polls[i] = poll
+	}
+	start := 0
+	for i, group := range coll.Groups {
+		end := start + len(group.Skeletons)
+		res.Groups[i] = &ConvertedGroup{
+			Title:     group.Title,
+			Skeletons: res.Skeletons[start:end:end],
+			Polls:     res.Polls[start:end:end],
+		}
+		start = end
+	}
+	return res, nil
+}
+
+// NumPolls returns the number of polls in all groups.
+func (coll *ConvertedCollection) NumPolls() int {
+	return len(coll.Polls)
+}
+
+// IndexOf returns the position of the poll with the given name in Skeletons and Polls, -1 if there is no such poll.
+func (coll *ConvertedCollection) IndexOf(name string) int {
+	if index, has := coll.indices[name]; has {
+		return index
+	}
+	return -1
+}
+
+// PollByName returns the poll with the given name, the bool is false if there is no such poll.
+func (coll *ConvertedCollection) PollByName(name string) (AbstractPoll, bool) {
+	if index, has := coll.indices[name]; has {
+		return coll.Polls[index], true
+	}
+	return nil, false
+}
+
+// SkeletonByName returns the skeleton with the given name, the bool is false if there is no such skeleton.
+func (coll *ConvertedCollection) SkeletonByName(name string) (AbstractPollSkeleton, bool) {
+	if index, has := coll.indices[name]; has {
+		return coll.Skeletons[index], true
+	}
+	return nil, false
+}
+
+// SkeletonOf returns the skeleton for which poll was created, the bool is false if poll is not part of the
+// collection.
+//
+// Polls are compared with ==, so this only works for comparable poll types (like pointers, all polls in this package
+// are pointers). For other types false is returned.
+func (coll *ConvertedCollection) SkeletonOf(poll AbstractPoll) (AbstractPollSkeleton, bool) {
+	if poll == nil || !reflect.TypeOf(poll).Comparable() {
+		return nil, false
+	}
+	for i, other := range coll.Polls {
+		if other != nil && reflect.TypeOf(other) == reflect.TypeOf(poll) && other == poll {
+			return coll.Skeletons[i], true
+		}
+	}
+	return nil, false
+}
+
+// PollMap returns a new map from poll name to poll.
+func (coll *ConvertedCollection) PollMap() PollMap {
+	res := make(PollMap, len(coll.Polls))
+	for i, skel := range coll.Skeletons {
+		res[skel.GetName()] = coll.Polls[i]
+	}
+	return res
+}
+
+// SkeletonMap returns a new map from skeleton name to skeleton.
+func (coll *ConvertedCollection) SkeletonMap() PollSkeletonMap {
+	res := make(PollSkeletonMap, len(coll.Skeletons))
+	for _, skel := range coll.Skeletons {
+		res[skel.GetName()] = skel
+	}
+	return res
+}
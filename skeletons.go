@@ -17,7 +17,10 @@ package gopolls
 import (
 	"fmt"
 	"io"
+	"math/big"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 const (
@@ -47,9 +50,103 @@ type AbstractPollSkeleton interface {
 	GetName() string
 }
 
+// PollAnnotations contains optional information about a poll that is not required to describe the poll itself but
+// is required for the evaluation.
+//
+// RequiredMajority is the majority required for the poll (for example 2/3), Quorum the fraction of the eligible
+// weight that must participate (see Quorum). EmptyPolicy is the EmptyVotePolicy for the poll (see
+// PoliciesFromCollection). All of them are nil if not set.
+//
+// In a polls file the annotations are given as a bracketed suffix on the poll line, for example
+// "### Budget [majority=2/3, quorum=1/2, empty=abstention]". A fraction without a key is the shorthand for the
+// majority, for example "### Budget [2/3]". Bracketed text that doesn't start with one of the keys or a fraction
+// (like "### Budget [2021]") is part of the poll name. See ParseEmptyVotePolicy for the names of the policies.
+type PollAnnotations struct {
+	RequiredMajority *big.Rat
+	Quorum           *big.Rat
+	EmptyPolicy      *EmptyVotePolicy
+}
+
+// HasAnnotations returns true if at least one annotation is set.
+func (annotations *PollAnnotations) HasAnnotations() bool {
+	return annotations.RequiredMajority != nil || annotations.Quorum != nil || annotations.EmptyPolicy != nil
+}
+
+// MajorityWeight computes the required majority for the sum of weights votesSum with ComputeMajority.
+// If RequiredMajority is not set it returns NoWeight, thus the result can directly be passed to MedianPoll.Tally.
+func (annotations *PollAnnotations) MajorityWeight(votesSum Weight) Weight {
+	if annotations.RequiredMajority == nil {
+		return NoWeight
+	}
+	return ComputeMajority(annotations.RequiredMajority, votesSum)
+}
+
+// GetAnnotations returns the annotations, it is used to implement AnnotatedSkeleton by embedding PollAnnotations.
+func (annotations *PollAnnotations) GetAnnotations() *PollAnnotations {
+	return annotations
+}
+
+// String returns the annotations in the format used in a polls file (without the brackets), for example
+// "majority=2/3, quorum=1/2, empty=abstention". If no annotations are set an empty string is returned.
+func (annotations *PollAnnotations) String() string {
+	parts := make([]string, 0, 3)
+	if annotations.RequiredMajority != nil {
+		parts = append(parts, "majority="+annotations.RequiredMajority.RatString())
+	}
+	if annotations.Quorum != nil {
+		parts = append(parts, "quorum="+annotations.Quorum.RatString())
+	}
+	if annotations.EmptyPolicy != nil {
+		parts = append(parts, "empty="+annotations.EmptyPolicy.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatPollLine returns the poll line for a skeleton, including the annotations (if any).
+func (annotations *PollAnnotations) formatPollLine(name string) string {
+	if !annotations.HasAnnotations() {
+		return fmt.Sprintf("### %s", name)
+	}
+	return fmt.Sprintf("### %s [%s]", name, annotations)
+}
+
+// AnnotatedSkeleton is a skeleton that has PollAnnotations, both skeleton types from this package implement it.
+type AnnotatedSkeleton interface {
+	AbstractPollSkeleton
+	GetAnnotations() *PollAnnotations
+}
+
+// ExtractAnnotations returns the annotations for all skeletons that implement AnnotatedSkeleton.
+//
+// The map uses the same keys as skeletons, so it can be used together with the polls returned by
+// ConvertSkeletonMapToEmptyPolls, for example to compute the required majority for a poll with
+// PollAnnotations.MajorityWeight.
+// Skeletons without any annotations are not contained in the result.
+func ExtractAnnotations(skeletons PollSkeletonMap) map[string]*PollAnnotations {
+	res := make(map[string]*PollAnnotations, len(skeletons))
+	for name, skel := range skeletons {
+		if annotated, ok := skel.(AnnotatedSkeleton); ok {
+			if annotations := annotated.GetAnnotations(); annotations.HasAnnotations() {
+				res[name] = annotations
+			}
+		}
+	}
+	return res
+}
+
 // PollSkeletonMap is a map from a poll name to the poll skeleton with that name.
 type PollSkeletonMap map[string]AbstractPollSkeleton
 
+// SortedNames returns the names of all skeletons in the map, sorted in increasing order.
+func (skels PollSkeletonMap) SortedNames() []string {
+	res := make([]string, 0, len(skels))
+	for name := range skels {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // DumpAbstractPollSkeleton writes a skeleton description to a writer.
 // It works only with the two "default" implementations.
 //
@@ -69,25 +166,72 @@ func DumpAbstractPollSkeleton(skel AbstractPollSkeleton, w io.Writer, currencyFo
 }
 
 // MoneyPollSkeleton is an AbstractPollSkeleton for a poll about some currency value (money).
+//
+// MinValue and Step restrict the values a voter can vote for, a value of 0 (ValueCents) means unrestricted. See
+// MedianPoll for the allowed values.
+//
+// It also implements AnnotatedSkeleton and MetadataHolder.
 type MoneyPollSkeleton struct {
-	Name  string
-	Value CurrencyValue
+	PollAnnotations
+	PollMetadata
+	Name     string
+	Value    CurrencyValue
+	MinValue CurrencyValue
+	Step     CurrencyValue
 }
 
-// NewMoneyPollSkeleton returns a new MoneyPollSkeleton.
+// NewMoneyPollSkeleton returns a new MoneyPollSkeleton, MinValue and Step are unrestricted.
 func NewMoneyPollSkeleton(name string, value CurrencyValue) *MoneyPollSkeleton {
 	return &MoneyPollSkeleton{
-		Name:  name,
-		Value: value,
+		Name:     name,
+		Value:    value,
+		MinValue: CurrencyValue{},
+		Step:     CurrencyValue{},
+	}
+}
+
+// HasRange returns true if MinValue or Step restrict the allowed values.
+func (skel *MoneyPollSkeleton) HasRange() bool {
+	return skel.MinValue.ValueCents != 0 || skel.Step.ValueCents != 0
+}
+
+// formatRange returns the restrictions of the skeleton in the form "[min 500,00 €, step 50,00 €]", the empty string
+// is returned if there are no restrictions.
+func (skel *MoneyPollSkeleton) formatRange(currencyFormatter CurrencyFormatter) string {
+	parts := make([]string, 0, 2)
+	if skel.MinValue.ValueCents != 0 {
+		parts = append(parts, "min "+currencyFormatter.Format(skel.MinValue))
 	}
+	if skel.Step.ValueCents != 0 {
+		parts = append(parts, "step "+currencyFormatter.Format(skel.Step))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
 }
 
 // Dump writes the skeleton to some writer w, it needs a currencyFormatter to write currency values.
+// The metadata (if any) is written in "key: value" lines after the poll line, see PollMetadata.
+// If the skeleton has a MinValue or Step they're written after the value, for example "- 5000,00 € [min 500,00 €]".
 //
 // It returns the number of bytes written as well as any error writing to w.
 func (skel *MoneyPollSkeleton) Dump(w io.Writer, currencyFormatter CurrencyFormatter) (int, error) {
+	res, writeErr := fmt.Fprintln(w, skel.formatPollLine(skel.Name))
+	if writeErr != nil {
+		return res, writeErr
+	}
+	written, metaErr := skel.dumpMeta(w)
+	res += written
+	if metaErr != nil {
+		return res, metaErr
+	}
 	currencyString := currencyFormatter.Format(skel.Value)
-	return fmt.Fprintf(w, "### %s\n- %s\n\n", skel.Name, currencyString)
+	if skel.HasRange() {
+		currencyString += " " + skel.formatRange(currencyFormatter)
+	}
+	written, writeErr = fmt.Fprintf(w, "- %s\n\n", currencyString)
+	return res + written, writeErr
 }
 
 // SkeletonType returns the constant MoneyPollSkeletonType.
@@ -100,10 +244,27 @@ func (skel *MoneyPollSkeleton) GetName() string {
 	return skel.Name
 }
 
+// SkeletonOption is an option of a PollSkeleton together with its (optional) description, see
+// PollSkeleton.OptionList.
+type SkeletonOption struct {
+	Name        string
+	Description string
+}
+
 // PollSkeleton is an AbstractPollSkeleton for a poll with a list of options (strings).
+//
+// Options contains the names of the options, they're used for example in ballots, csv files and Schulze rankings.
+// Each option can have a description, Descriptions[i] is the description of Options[i]. Descriptions is nil if no
+// option has a description and might be shorter than Options (for example if options are appended to Options
+// directly), therefore use Description to read a description and AddOption / SetDescription to change them.
+//
+// It also implements AnnotatedSkeleton and MetadataHolder.
 type PollSkeleton struct {
-	Name    string
-	Options []string
+	PollAnnotations
+	PollMetadata
+	Name         string
+	Options      []string
+	Descriptions []string
 }
 
 // NewPollSkeleton returns a new PollSkeleton given the name and an empty list of options.
@@ -114,8 +275,68 @@ func NewPollSkeleton(name string) *PollSkeleton {
 	}
 }
 
+// AddOption appends a new option with the given description (can be empty).
+func (skel *PollSkeleton) AddOption(name, description string) {
+	skel.Options = append(skel.Options, name)
+	if description != "" {
+		skel.SetDescription(len(skel.Options)-1, description)
+	}
+}
+
+// Description returns the description of the option with index i, if the option has no description the empty string
+// is returned.
+func (skel *PollSkeleton) Description(i int) string {
+	if i < 0 || i >= len(skel.Descriptions) {
+		return ""
+	}
+	return skel.Descriptions[i]
+}
+
+// SetDescription sets the description of the option with index i, Descriptions is extended if required.
+// It panics if i is not a valid index in Options.
+func (skel *PollSkeleton) SetDescription(i int, description string) {
+	if i < 0 || i >= len(skel.Options) {
+		panic(fmt.Sprintf("option index %d out of range, poll has %d options", i, len(skel.Options)))
+	}
+	if i >= len(skel.Descriptions) {
+		if description == "" {
+			return
+		}
+		descriptions := make([]string, len(skel.Options))
+		copy(descriptions, skel.Descriptions)
+		skel.Descriptions = descriptions
+	}
+	skel.Descriptions[i] = description
+}
+
+// HasDescriptions returns true if at least one option has a description.
+func (skel *PollSkeleton) HasDescriptions() bool {
+	for _, description := range skel.Descriptions {
+		if description != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// OptionList returns the options together with their descriptions.
+func (skel *PollSkeleton) OptionList() []SkeletonOption {
+	res := make([]SkeletonOption, len(skel.Options))
+	for i, option := range skel.Options {
+		res[i] = SkeletonOption{
+			Name:        option,
+			Description: skel.Description(i),
+		}
+	}
+	return res
+}
+
 // Dump writes the skeleton to some writer w.
 //
+// The description of an option is written in an indented line after the option, see
+// PollCollectionParser.ParseCollectionSkeletons. The metadata (if any) is written in "key: value" lines after the
+// poll line, see PollMetadata.
+//
 // It returns the number of bytes written as well as any error writing to w.
 func (skel *PollSkeleton) Dump(w io.Writer) (int, error) {
 	res := 0
@@ -123,18 +344,31 @@ func (skel *PollSkeleton) Dump(w io.Writer) (int, error) {
 	written := 0
 	var writeErr error
 
-	written, writeErr = fmt.Fprintf(w, "### %s\n", skel.Name)
+	written, writeErr = fmt.Fprintln(w, skel.formatPollLine(skel.Name))
+	res += written
+	if writeErr != nil {
+		return res, writeErr
+	}
+
+	written, writeErr = skel.dumpMeta(w)
 	res += written
 	if writeErr != nil {
 		return res, writeErr
 	}
 
-	for _, option := range skel.Options {
+	for i, option := range skel.Options {
 		written, writeErr = fmt.Fprintf(w, "* %s\n", option)
 		res += written
 		if writeErr != nil {
 			return res, writeErr
 		}
+		if description := skel.Description(i); description != "" {
+			written, writeErr = fmt.Fprintf(w, "  %s\n", description)
+			res += written
+			if writeErr != nil {
+				return res, writeErr
+			}
+		}
 	}
 
 	written, writeErr = fmt.Fprintln(w)
@@ -262,11 +496,32 @@ func (coll *PollSkeletonCollection) CollectSkeletons() []AbstractPollSkeleton {
 	return res
 }
 
+// GroupByTitle returns the group with the given title.
+//
+// If there is no such group a PollingSemanticError is returned, if multiple groups have this title a DuplicateError
+// is returned.
+func (coll *PollSkeletonCollection) GroupByTitle(title string) (*PollGroup, error) {
+	var res *PollGroup
+	for _, group := range coll.Groups {
+		if group.Title != title {
+			continue
+		}
+		if res != nil {
+			return nil, NewDuplicateError(fmt.Sprintf("group \"%s\" was found multiple times in the collection", title))
+		}
+		res = group
+	}
+	if res == nil {
+		return nil, NewPollingSemanticError(nil, "no group with title \"%s\" found", title)
+	}
+	return res, nil
+}
+
 // HasDuplicateSkeleton tests if the names in the collection are unique (which they should).
 // It returns an empty string and false if no duplicates where found, otherwise it returns the name
 // of the skeleton and true.
 func (coll *PollSkeletonCollection) HasDuplicateSkeleton() (string, bool) {
-	nameSet := make(map[string]struct{}, len(coll.Groups))
+	nameSet := make(map[string]struct{}, coll.NumSkeletons())
 	for _, group := range coll.Groups {
 		for _, skel := range group.Skeletons {
 			name := skel.GetName()
@@ -284,17 +539,30 @@ func (coll *PollSkeletonCollection) HasDuplicateSkeleton() (string, bool) {
 //
 // Otherwise it returns the map and nil.
 func (coll *PollSkeletonCollection) SkeletonsToMap() (PollSkeletonMap, error) {
-	res := make(PollSkeletonMap, len(coll.Groups))
+	res, name, hasDuplicates := coll.SkeletonsToMapChecked()
+	if hasDuplicates {
+		return nil, NewDuplicateError(fmt.Sprintf("duplicate entry for poll %s", name))
+	}
+	return res, nil
+}
+
+// SkeletonsToMapChecked returns a map from skeleton name to skeleton and checks for duplicates in a single pass over
+// the skeletons, it should be used instead of calling HasDuplicateSkeleton and SkeletonsToMap.
+//
+// If a name appears multiple times it returns nil, the first name that was found multiple times and true. Otherwise
+// it returns the map, an empty string and false.
+func (coll *PollSkeletonCollection) SkeletonsToMapChecked() (PollSkeletonMap, string, bool) {
+	res := make(PollSkeletonMap, coll.NumSkeletons())
 	for _, group := range coll.Groups {
 		for _, skel := range group.Skeletons {
 			name := skel.GetName()
 			if _, has := res[name]; has {
-				return nil, NewDuplicateError(fmt.Sprintf("duplicate entry for poll %s", name))
+				return nil, name, true
 			}
 			res[name] = skel
 		}
 	}
-	return res, nil
+	return res, "", false
 }
 
 // Dump writes the collection to some writer w, it needs a currencyFormatter to write currency values.
@@ -0,0 +1,284 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// StateArchiveVersion is the version of the state archives written by StateArchiver.Save.
+// Archives with a greater version are rejected by StateArchiver.Load.
+const StateArchiveVersion = 1
+
+// The entries of a state archive, see StateArchiver.
+const (
+	StateManifestEntry   = "manifest.json"
+	StateVotersEntry     = "voters.txt"
+	StateCollectionEntry = "polls.txt"
+	StateVotesEntry      = "votes.jsonl"
+)
+
+// stateManifest is the content of the StateManifestEntry.
+type stateManifest struct {
+	Version int `json:"version"`
+}
+
+// StateArchiveError is returned by StateArchiver.Load if an entry of the archive is invalid.
+//
+// It contains the name of the entry, Err is the original error, it is returned by Unwrap.
+type StateArchiveError struct {
+	Entry string
+	Err   error
+}
+
+func (err StateArchiveError) Error() string {
+	return fmt.Sprintf("state archive entry \"%s\": %s", err.Entry, err.Err.Error())
+}
+
+// Unwrap returns the wrapped error.
+func (err StateArchiveError) Unwrap() error {
+	return err.Err
+}
+
+// ArchivedState is the state read by StateArchiver.Load.
+//
+// Version is the version of the archive, Polls contains the polls created from the skeletons of Collection, filled
+// with the votes from the archive.
+type ArchivedState struct {
+	Version    int
+	Voters     []*Voter
+	Collection *PollSkeletonCollection
+	Polls      PollMap
+}
+
+// StateArchiver writes and reads the state of an application (voters, collection and votes) as one zip archive.
+//
+// The archive contains the entries StateManifestEntry (a JSON object with the version of the archive),
+// StateVotersEntry (the voters, see Voter.Format), StateCollectionEntry (the collection, see
+// PollSkeletonCollection.Dump) and StateVotesEntry (the votes, see DumpVotes). Thus all entries are in the textual
+// formats of this package and the archive can be inspected with any zip tool.
+//
+// CurrencyHandler is used to write and parse the values of money polls. VotersParser and CollectionParser are used
+// to read the voters and the collection (the voters parser must allow delegations and the collection parser must
+// parse metadata to restore it, see PollMetadata), SkeletonConverter converts the skeletons to polls.
+// MaxTotalBytes is the maximal size of the archive, -1 means no limit.
+// MaxUncompressedBytes is the maximal size of all entries read from the archive after decompression, -1 means no
+// limit. Set it when reading untrusted archives, MaxTotalBytes alone doesn't protect against archives that
+// decompress to huge entries.
+//
+// Only the information of the textual formats is stored: The polls are created from the skeletons again (so
+// settings of the polls that are not part of the skeletons are lost) and the attributes of the voters are not stored.
+type StateArchiver struct {
+	CurrencyHandler      CurrencyHandler
+	VotersParser         *VotersParser
+	CollectionParser     *PollCollectionParser
+	SkeletonConverter    SkeletonConverter
+	MaxTotalBytes        int
+	MaxUncompressedBytes int
+}
+
+// NewStateArchiver returns a new StateArchiver that uses DefaultCurrencyHandler, DefaultSkeletonConverter and
+// parsers without limits (delegations and fractional weights are enabled in the voters parser and metadata in the
+// collection parser).
+// MaxTotalBytes and MaxUncompressedBytes are set to -1.
+func NewStateArchiver() *StateArchiver {
+	votersParser := NewVotersParser()
+	votersParser.ParseDelegations = true
+	votersParser.FractionalWeights = true
+	collectionParser := NewPollCollectionParser()
+	collectionParser.ParseMetadata = true
+	return &StateArchiver{
+		CurrencyHandler:      DefaultCurrencyHandler,
+		VotersParser:         votersParser,
+		CollectionParser:     collectionParser,
+		SkeletonConverter:    DefaultSkeletonConverter,
+		MaxTotalBytes:        -1,
+		MaxUncompressedBytes: -1,
+	}
+}
+
+// SaveState writes the state with a StateArchiver created by NewStateArchiver, see StateArchiver.Save.
+func SaveState(w io.Writer, voters []*Voter, coll *PollSkeletonCollection, polls PollMap) error {
+	return NewStateArchiver().Save(w, voters, coll, polls)
+}
+
+// LoadState reads the state with a StateArchiver created by NewStateArchiver, see StateArchiver.Load.
+func LoadState(r io.Reader) (*ArchivedState, error) {
+	return NewStateArchiver().Load(r)
+}
+
+// Save writes voters, coll and the votes of polls as a zip archive to w.
+//
+// polls can be nil, in this case the votes entry is empty. The names of the polls must be the names of the skeletons
+// in coll and the voters of the votes must be in voters, otherwise the archive can't be loaded.
+// All errors from DumpVotes and from writing to w are returned.
+func (archiver *StateArchiver) Save(w io.Writer, voters []*Voter, coll *PollSkeletonCollection, polls PollMap) error {
+	zipWriter := zip.NewWriter(w)
+	writeEntry := func(name string, write func(w io.Writer) error) error {
+		entryWriter, createErr := zipWriter.Create(name)
+		if createErr != nil {
+			return createErr
+		}
+		return write(entryWriter)
+	}
+	err := writeEntry(StateManifestEntry, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(stateManifest{Version: StateArchiveVersion})
+	})
+	if err != nil {
+		return err
+	}
+	err = writeEntry(StateVotersEntry, func(w io.Writer) error {
+		for _, voter := range voters {
+			if _, writeErr := io.WriteString(w, voter.Format("")+"\n"); writeErr != nil {
+				return writeErr
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = writeEntry(StateCollectionEntry, func(w io.Writer) error {
+		_, dumpErr := coll.Dump(w, archiver.CurrencyHandler)
+		return dumpErr
+	})
+	if err != nil {
+		return err
+	}
+	err = writeEntry(StateVotesEntry, func(w io.Writer) error {
+		return DumpVotes(w, polls)
+	})
+	if err != nil {
+		return err
+	}
+	return zipWriter.Close()
+}
+
+// Load reads a state archive as written by Save.
+//
+// If r is not a valid zip archive a PollingSyntaxError is returned, if an entry is missing a PollingSemanticError.
+// If the version of the archive is not supported a PollingSemanticError is returned as well.
+// Errors in an entry (including corrupt data) are returned as StateArchiveError, wrapping the error from the
+// parser of the entry. A ParserValidationError is returned if the archive is bigger than MaxTotalBytes or if the
+// entries are bigger than MaxUncompressedBytes after decompression (the size of an entry is checked before it is
+// decompressed and the decompressed bytes are counted, so entries with a wrong size in the header are detected too).
+// Errors from reading r are returned unchanged.
+func (archiver *StateArchiver) Load(r io.Reader) (*ArchivedState, error) {
+	limited := newMaxBytesReader(r, archiver.MaxTotalBytes)
+	data, readErr := ioutil.ReadAll(limited)
+	if readErr != nil {
+		return nil, limited.checkErr(readErr)
+	}
+	zipReader, zipErr := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if zipErr != nil {
+		return nil, NewPollingSyntaxError(zipErr, "invalid state archive")
+	}
+	entries := make(map[string]*zip.File, len(zipReader.File))
+	for _, file := range zipReader.File {
+		entries[file.Name] = file
+	}
+	// the number of decompressed bytes that can still be read, -1 means no limit
+	remaining := int64(archiver.MaxUncompressedBytes)
+	readEntry := func(name string) ([]byte, error) {
+		file, has := entries[name]
+		if !has {
+			return nil, NewPollingSemanticError(nil, "state archive has no entry \"%s\"", name)
+		}
+		if remaining >= 0 && file.UncompressedSize64 > uint64(remaining) {
+			return nil, StateArchiveError{Entry: name, Err: NewParserValidationError(
+				fmt.Sprintf("entry is too big: only %d uncompressed bytes are allowed", archiver.MaxUncompressedBytes))}
+		}
+		entryReader, openErr := file.Open()
+		if openErr != nil {
+			return nil, StateArchiveError{Entry: name, Err: NewPollingSyntaxError(openErr, "can't open entry")}
+		}
+		defer entryReader.Close()
+		limitedEntry := newMaxBytesReader(entryReader, int(remaining))
+		content, entryErr := ioutil.ReadAll(limitedEntry)
+		if entryErr != nil {
+			if limitedEntry.exceeded() {
+				return nil, StateArchiveError{Entry: name, Err: limitedEntry.limitErr()}
+			}
+			return nil, StateArchiveError{Entry: name, Err: NewPollingSyntaxError(entryErr, "corrupt entry")}
+		}
+		if remaining >= 0 {
+			remaining -= int64(len(content))
+		}
+		return content, nil
+	}
+
+	manifestData, manifestErr := readEntry(StateManifestEntry)
+	if manifestErr != nil {
+		return nil, manifestErr
+	}
+	var manifest stateManifest
+	if jsonErr := json.Unmarshal(manifestData, &manifest); jsonErr != nil {
+		return nil, StateArchiveError{Entry: StateManifestEntry,
+			Err: NewPollingSyntaxError(jsonErr, "invalid manifest")}
+	}
+	if manifest.Version < 1 || manifest.Version > StateArchiveVersion {
+		return nil, NewPollingSemanticError(nil, "unsupported state archive version %d, supported versions are 1 to %d",
+			manifest.Version, StateArchiveVersion)
+	}
+	res := &ArchivedState{Version: manifest.Version}
+
+	votersData, votersDataErr := readEntry(StateVotersEntry)
+	if votersDataErr != nil {
+		return nil, votersDataErr
+	}
+	voters, votersErr := archiver.VotersParser.ParseVoters(bytes.NewReader(votersData))
+	if votersErr != nil {
+		return nil, StateArchiveError{Entry: StateVotersEntry, Err: votersErr}
+	}
+	voterMap, voterMapErr := VotersToMap(voters)
+	if voterMapErr != nil {
+		return nil, StateArchiveError{Entry: StateVotersEntry, Err: voterMapErr}
+	}
+	res.Voters = voters
+
+	collectionData, collectionDataErr := readEntry(StateCollectionEntry)
+	if collectionDataErr != nil {
+		return nil, collectionDataErr
+	}
+	coll, collErr := archiver.CollectionParser.ParseCollectionSkeletons(bytes.NewReader(collectionData),
+		archiver.CurrencyHandler)
+	if collErr != nil {
+		return nil, StateArchiveError{Entry: StateCollectionEntry, Err: collErr}
+	}
+	skeletons, skeletonsErr := coll.SkeletonsToMap()
+	if skeletonsErr != nil {
+		return nil, StateArchiveError{Entry: StateCollectionEntry, Err: skeletonsErr}
+	}
+	polls, pollsErr := ConvertSkeletonMapToEmptyPolls(skeletons, archiver.SkeletonConverter)
+	if pollsErr != nil {
+		return nil, StateArchiveError{Entry: StateCollectionEntry, Err: pollsErr}
+	}
+	res.Collection = coll
+
+	votesData, votesDataErr := readEntry(StateVotesEntry)
+	if votesDataErr != nil {
+		return nil, votesDataErr
+	}
+	if votesErr := LoadVotes(bytes.NewReader(votesData), polls, voterMap); votesErr != nil {
+		return nil, StateArchiveError{Entry: StateVotesEntry, Err: votesErr}
+	}
+	res.Polls = polls
+	return res, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import "sync"
+
+// SynchronizedPoll is an AbstractPoll that can be used concurrently by multiple goroutines, see
+// NewSynchronizedPoll.
+//
+// AddVote can be called concurrently, the calls are serialized. WithLock calls f with the wrapped poll while holding
+// the lock, it should be used whenever the wrapped poll is accessed directly while votes may still be added (for
+// example to tally the poll). f must not call methods of the SynchronizedPoll, this would deadlock.
+// Unwrap returns the wrapped poll, for example to do a type switch.
+type SynchronizedPoll interface {
+	AbstractPoll
+	WithLock(f func(poll AbstractPoll) error) error
+	Unwrap() AbstractPoll
+}
+
+// synchronizedPoll is the SynchronizedPoll for polls that don't implement VoteGenerator.
+type synchronizedPoll struct {
+	mutex sync.Mutex
+	poll  AbstractPoll
+}
+
+// NewSynchronizedPoll returns a SynchronizedPoll wrapping poll.
+//
+// If poll implements VoteGenerator the returned poll implements VoteGenerator too.
+// The poll should not be used directly after wrapping it (only with WithLock).
+func NewSynchronizedPoll(poll AbstractPoll) SynchronizedPoll {
+	res := &synchronizedPoll{poll: poll}
+	if _, isGenerator := poll.(VoteGenerator); isGenerator {
+		return &synchronizedVoteGenerator{res}
+	}
+	return res
+}
+
+// PollType returns the type of the wrapped poll.
+func (poll *synchronizedPoll) PollType() string {
+	return poll.poll.PollType()
+}
+
+// AddVote adds the vote to the wrapped poll while holding the lock.
+func (poll *synchronizedPoll) AddVote(vote AbstractVote) error {
+	poll.mutex.Lock()
+	defer poll.mutex.Unlock()
+	return poll.poll.AddVote(vote)
+}
+
+// WithLock calls f with the wrapped poll while holding the lock and returns the error returned by f.
+func (poll *synchronizedPoll) WithLock(f func(poll AbstractPoll) error) error {
+	poll.mutex.Lock()
+	defer poll.mutex.Unlock()
+	return f(poll.poll)
+}
+
+// Unwrap returns the wrapped poll.
+func (poll *synchronizedPoll) Unwrap() AbstractPoll {
+	return poll.poll
+}
+
+// synchronizedVoteGenerator is the SynchronizedPoll for polls that implement VoteGenerator.
+type synchronizedVoteGenerator struct {
+	*synchronizedPoll
+}
+
+// GenerateVoteFromBasicAnswer calls GenerateVoteFromBasicAnswer of the wrapped poll while holding the lock.
+func (poll *synchronizedVoteGenerator) GenerateVoteFromBasicAnswer(voter *Voter, answer BasicPollAnswer) (AbstractVote, error) {
+	poll.mutex.Lock()
+	defer poll.mutex.Unlock()
+	return poll.poll.(VoteGenerator).GenerateVoteFromBasicAnswer(voter, answer)
+}
@@ -0,0 +1,259 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import "reflect"
+
+// AbstractPollResult describes the result of any poll.
+//
+// ResultType returns the type of the result as a string, constants are defined for the results of the polls
+// implemented in this package: BasicResultType, MedianResultType, SchulzeResultType and ScoreResultType.
+// GetWeightSum returns the sum of the weights of all votes (it is called GetWeightSum and not WeightSum because
+// some results already have a field with that name).
+//
+// The results of the polls implemented in this package are *BasicPollResult, *MedianResult, *SchulzeResult and
+// *ScoreResult.
+type AbstractPollResult interface {
+	ResultType() string
+	GetWeightSum() Weight
+}
+
+const (
+	BasicResultType   = "basic-result"
+	MedianResultType  = "median-result"
+	SchulzeResultType = "schulze-result"
+	ScoreResultType   = "score-result"
+)
+
+// ResultType returns the constant BasicResultType.
+func (res *BasicPollResult) ResultType() string {
+	return BasicResultType
+}
+
+// GetWeightSum returns VotesSum.
+func (res *BasicPollResult) GetWeightSum() Weight {
+	return res.VotesSum
+}
+
+// ResultType returns the constant MedianResultType.
+func (result *MedianResult) ResultType() string {
+	return MedianResultType
+}
+
+// GetWeightSum returns WeightSum.
+func (result *MedianResult) GetWeightSum() Weight {
+	return result.WeightSum
+}
+
+// ResultType returns the constant SchulzeResultType.
+func (result *SchulzeResult) ResultType() string {
+	return SchulzeResultType
+}
+
+// GetWeightSum returns WeightSum.
+func (result *SchulzeResult) GetWeightSum() Weight {
+	return result.WeightSum
+}
+
+// ResultType returns the constant ScoreResultType.
+func (result *ScoreResult) ResultType() string {
+	return ScoreResultType
+}
+
+// GetWeightSum returns WeightSum.
+func (result *ScoreResult) GetWeightSum() Weight {
+	return result.WeightSum
+}
+
+// TalliedPoll bundles a poll together with its skeleton and result.
+//
+// TieBreak is only set if the poll was tallied with a TieBreaker (see WithTieBreaker) and the result was tied.
+// Meta is a copy of the metadata of the skeleton (see PollMetadata), nil if the skeleton has no metadata.
+type TalliedPoll struct {
+	Skeleton AbstractPollSkeleton
+	Poll     AbstractPoll
+	Result   AbstractPollResult
+	TieBreak *TieBreak
+	Meta     map[string]string
+}
+
+// TalliedGroup contains the tallied polls of a PollGroup, in the same order as the skeletons in the group.
+type TalliedGroup struct {
+	Title string
+	Polls []*TalliedPoll
+}
+
+// TallyPoll tallies a poll of one of the types implemented in this package and returns the result.
+//
+// A MedianPoll is tallied with the default majority (see MedianPoll.Tally), a ScorePoll with the automatic runoff
+// (see ScorePoll.TallyWithRunoff). For all other poll types a PollTypeError is returned.
+// If the weights of the votes overflow the Err of the result is returned (wrapping ErrWeightOverflow).
+func TallyPoll(poll AbstractPoll) (AbstractPollResult, error) {
+	return tallyPoll(poll, NoWeight)
+}
+
+func tallyPoll(poll AbstractPoll, medianMajority Weight) (AbstractPollResult, error) {
+	switch typedPoll := poll.(type) {
+	case *BasicPoll:
+		res := typedPoll.Tally()
+		return res, res.Err
+	case *MedianPoll:
+		res := typedPoll.Tally(medianMajority)
+		return res, res.Err
+	case *SchulzePoll:
+		res := typedPoll.Tally()
+		return res, res.Err
+	case *ScorePoll:
+		res := typedPoll.TallyWithRunoff()
+		return res, res.Err
+	default:
+		return nil, NewPollTypeError("can't tally poll of type %s", reflect.TypeOf(poll))
+	}
+}
+
+// TallyOption is an option for TallyCollection, see for example WithTieBreaker.
+type TallyOption func(options *tallyOptions)
+
+type tallyOptions struct {
+	tieBreaker       TieBreaker
+	zeroWeightPolicy ZeroWeightPolicy
+	eventHandler     PollEventHandler
+}
+
+// WithTieBreaker applies breaker to each tied result (see BreakTie), the tie break is stored in
+// TalliedPoll.TieBreak.
+//
+// The polls are tallied in the order of the collection, so a RandomTieBreaker always chooses the same winners
+// for the same collection and seed.
+func WithTieBreaker(breaker TieBreaker) TallyOption {
+	return func(options *tallyOptions) {
+		options.tieBreaker = breaker
+	}
+}
+
+// WithTallyZeroWeightPolicy sets the ZeroWeightPolicy used to tally a BasicPoll, see
+// BasicPoll.TallyWithZeroWeightPolicy. The default is AllowZeroWeight.
+func WithTallyZeroWeightPolicy(policy ZeroWeightPolicy) TallyOption {
+	return func(options *tallyOptions) {
+		options.zeroWeightPolicy = policy
+	}
+}
+
+// WithTallyEventHandler sets a handler that is called with an InvalidVoteAction event for each vote that is not valid
+// for its poll (see VoteValidator) before the poll is tallied, for example Schulze votes with a ranking of the wrong
+// length that are dropped by the tally. Use LogPollEvents to write the events to a Logger.
+func WithTallyEventHandler(handler PollEventHandler) TallyOption {
+	return func(options *tallyOptions) {
+		options.eventHandler = handler
+	}
+}
+
+// TallyCollection tallies the polls for all skeletons in coll, the poll for a skeleton is looked up by name in polls.
+//
+// The result mirrors the groups of the collection, the tallied polls in each group are in the same order as the
+// skeletons.
+// If a skeleton has a required majority annotation (see PollAnnotations) a MedianPoll is tallied with that majority.
+// A BasicPoll is tallied with the ZeroWeightPolicy set by WithTallyZeroWeightPolicy.
+// Invalid votes can be reported with WithTallyEventHandler.
+//
+// If there is no poll for a skeleton a PollingSemanticError is returned, if a poll has an unsupported type a
+// PollTypeError is returned. Weight overflows are reported as in TallyPoll.
+func TallyCollection(coll *PollSkeletonCollection, polls PollMap, options ...TallyOption) ([]TalliedGroup, error) {
+	var tallyOpts tallyOptions
+	for _, option := range options {
+		option(&tallyOpts)
+	}
+	res := make([]TalliedGroup, len(coll.Groups))
+	for i, group := range coll.Groups {
+		talliedGroup, tallyErr := tallyGroup(group, polls, tallyOpts)
+		if tallyErr != nil {
+			return nil, tallyErr
+		}
+		res[i] = talliedGroup
+	}
+	return res, nil
+}
+
+// TallyGroup tallies only the polls for the skeletons in group, for example to evaluate one group of a collection
+// while the other groups are still collecting votes.
+//
+// The tallied polls are in the same order as the skeletons in group and are tallied as in TallyCollection (with the
+// same options). Polls in polls that are not part of the group are ignored.
+// If there is no poll for a skeleton in the group a PollingSemanticError is returned, if a poll has an unsupported
+// type a PollTypeError is returned.
+//
+// Use PollSkeletonCollection.GroupByTitle to find a group by its title and WithGroupColumns to fill only the polls of
+// a group from a PollMatrix.
+func TallyGroup(group *PollGroup, polls PollMap, options ...TallyOption) (TalliedGroup, error) {
+	var tallyOpts tallyOptions
+	for _, option := range options {
+		option(&tallyOpts)
+	}
+	return tallyGroup(group, polls, tallyOpts)
+}
+
+func tallyGroup(group *PollGroup, polls PollMap, tallyOpts tallyOptions) (TalliedGroup, error) {
+	talliedGroup := TalliedGroup{
+		Title: group.Title,
+		Polls: make([]*TalliedPoll, len(group.Skeletons)),
+	}
+	for i, skel := range group.Skeletons {
+		name := skel.GetName()
+		poll, hasPoll := polls[name]
+		if !hasPoll {
+			return TalliedGroup{}, NewPollingSemanticError(nil, "no poll found for skeleton \"%s\" in group \"%s\"",
+				name, group.Title)
+		}
+		if tallyOpts.eventHandler != nil {
+			reportInvalidVotes(name, poll, tallyOpts.eventHandler)
+		}
+		majority := NoWeight
+		if medianPoll, isMedian := poll.(*MedianPoll); isMedian {
+			if annotated, ok := skel.(AnnotatedSkeleton); ok {
+				// an overflow is reported by tallyPoll
+				if weightSum, sumErr := medianPoll.CheckedWeightSum(); sumErr == nil {
+					majority = annotated.GetAnnotations().MajorityWeight(weightSum)
+				}
+			}
+		}
+		var result AbstractPollResult
+		var tallyErr error
+		if basicPoll, isBasic := poll.(*BasicPoll); isBasic {
+			basicResult := basicPoll.TallyWithZeroWeightPolicy(tallyOpts.zeroWeightPolicy)
+			result, tallyErr = basicResult, basicResult.Err
+		} else {
+			result, tallyErr = tallyPoll(poll, majority)
+		}
+		if tallyErr != nil {
+			return TalliedGroup{}, tallyErr
+		}
+		var tieBreak *TieBreak
+		if tallyOpts.tieBreaker != nil {
+			tieBreak = BreakTie(result, tallyOpts.tieBreaker)
+		}
+		var meta map[string]string
+		if holder, ok := skel.(MetadataHolder); ok {
+			meta = copyMeta(holder.GetMeta())
+		}
+		talliedGroup.Polls[i] = &TalliedPoll{
+			Skeleton: skel,
+			Poll:     poll,
+			Result:   result,
+			TieBreak: tieBreak,
+			Meta:     meta,
+		}
+	}
+	return talliedGroup, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestAnonymization(t *testing.T) {
+	alice, bob, carol := gopolls.NewVoter("Alice", 1), gopolls.NewVoter("Bob", 2), gopolls.NewVoter("Carol", 3)
+	carol.Delegations = []gopolls.Delegation{gopolls.NewDelegation("Dave", 1)}
+	voters := []*gopolls.Voter{alice, bob, carol}
+
+	anonymizer := &gopolls.Anonymizer{Prefix: gopolls.DefaultPseudonymPrefix}
+	pseudonymous, mapping, err := anonymizer.Anonymize(voters)
+	if err != nil {
+		t.Fatalf("Unexpected error anonymizing voters: %v", err)
+	}
+	expectedNames := []string{"Voter 001", "Voter 002", "Voter 003"}
+	for i, voter := range pseudonymous {
+		if voter.Name != expectedNames[i] || voter.Weight != voters[i].Weight {
+			t.Errorf("Expected pseudonym %s with weight %d, got %s with weight %d", expectedNames[i],
+				voters[i].Weight, voter.Name, voter.Weight)
+		}
+		if original, _ := mapping.Original(voter.Name); original != voters[i].Name {
+			t.Errorf("Expected original %s for %s, got %s", voters[i].Name, voter.Name, original)
+		}
+	}
+	if source := pseudonymous[2].Delegations[0].Source; source != "Voter 004" || mapping.DelegationSources[source] != "Dave" {
+		t.Errorf("Expected delegation source to be pseudonymized, got %s", source)
+	}
+	if carol.Name != "Carol" || carol.Delegations[0].Source != "Dave" {
+		t.Error("Original voters must not be changed")
+	}
+
+	newPolls := func() gopolls.PollMap {
+		return gopolls.PollMap{
+			"motion": gopolls.NewBasicPoll([]*gopolls.BasicVote{
+				gopolls.NewBasicVote(alice, gopolls.Aye),
+				gopolls.NewBasicVote(bob, gopolls.No),
+				gopolls.NewBasicVote(carol, gopolls.Aye),
+			}),
+			"budget": gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+				gopolls.NewMedianVote(alice, 1000),
+				gopolls.NewMedianVote(bob, 500),
+				gopolls.NewMedianVote(carol, 0),
+			}),
+			"chair": gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+				gopolls.NewSchulzeVote(alice, gopolls.SchulzeRanking{0, 1}),
+				gopolls.NewSchulzeVote(carol, gopolls.SchulzeRanking{1, 0}),
+			}),
+		}
+	}
+	polls := newPolls()
+	if err := gopolls.ApplyAnonymization(polls, mapping); err != nil {
+		t.Fatalf("Unexpected error applying anonymization: %v", err)
+	}
+	original := newPolls()
+	if !polls["motion"].(*gopolls.BasicPoll).Tally().Equals(original["motion"].(*gopolls.BasicPoll).Tally()) {
+		t.Error("Basic poll results differ after anonymization")
+	}
+	schulzeRes, originalSchulzeRes := polls["chair"].(*gopolls.SchulzePoll).Tally(), original["chair"].(*gopolls.SchulzePoll).Tally()
+	if !schulzeRes.Equals(originalSchulzeRes) {
+		t.Error("Schulze poll results differ after anonymization")
+	}
+	medianRes := polls["budget"].(*gopolls.MedianPoll).Tally(gopolls.NoWeight)
+	originalMedianRes := original["budget"].(*gopolls.MedianPoll).Tally(gopolls.NoWeight)
+	if medianRes.MajorityValue != originalMedianRes.MajorityValue || medianRes.WeightSum != originalMedianRes.WeightSum {
+		t.Error("Median poll results differ after anonymization")
+	}
+	for _, details := range medianRes.ValueDetails {
+		for _, voter := range details {
+			if !strings.HasPrefix(voter.Name, gopolls.DefaultPseudonymPrefix) {
+				t.Errorf("Median result contains original voter %s", voter.Name)
+			}
+		}
+	}
+
+	if err := gopolls.RevertAnonymization(polls, mapping); err != nil {
+		t.Fatalf("Unexpected error reverting anonymization: %v", err)
+	}
+	if polls["motion"].(*gopolls.BasicPoll).Votes[0].Voter != alice {
+		t.Error("Expected original voter after reverting anonymization")
+	}
+
+	// unknown voters are rejected and nothing is changed
+	polls["motion"].AddVote(gopolls.NewBasicVote(gopolls.NewVoter("Eve", 1), gopolls.Aye))
+	err = gopolls.ApplyAnonymization(polls, mapping)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for unknown voter, got %v", err)
+	}
+	if polls["budget"].(*gopolls.MedianPoll).Votes[0].Voter.Name == "Voter 001" {
+		t.Error("Polls must not be changed if an error is returned")
+	}
+}
+
+func TestAnonymizerShuffle(t *testing.T) {
+	voters := make([]*gopolls.Voter, 1000)
+	for i := range voters {
+		voters[i] = gopolls.NewVoter(strings.Repeat("x", i+1), 1)
+	}
+	anonymizer := &gopolls.Anonymizer{Prefix: "V", Rand: rand.New(rand.NewSource(1))}
+	pseudonymous, mapping, err := anonymizer.Anonymize(voters)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(mapping.Originals) != len(voters) {
+		t.Fatalf("Expected %d distinct pseudonyms, got %d", len(voters), len(mapping.Originals))
+	}
+	inOrder := true
+	for i, voter := range pseudonymous {
+		if len(voter.Name) != 5 {
+			t.Errorf("Expected pseudonyms with four digits, got %s", voter.Name)
+		}
+		if mapping.Originals[voter.Name] != voters[i] {
+			t.Errorf("Mapping for %s is wrong", voter.Name)
+		}
+		if i > 0 && voter.Name < pseudonymous[i-1].Name {
+			inOrder = false
+		}
+	}
+	if inOrder {
+		t.Error("Expected pseudonyms to be shuffled")
+	}
+
+	_, _, err = anonymizer.Anonymize([]*gopolls.Voter{voters[0], voters[0]})
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+}
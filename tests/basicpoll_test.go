@@ -15,7 +15,11 @@
 package tests
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/FabianWe/gopolls"
+	"math/rand"
 	"testing"
 )
 
@@ -89,3 +93,215 @@ func TestBasicPollTwo(t *testing.T) {
 			expectedWeightedVotes, *res.WeightedVotes)
 	}
 }
+
+func TestBasicVoteParserWithAnswers(t *testing.T) {
+	template := gopolls.NewBasicVoteParser()
+	parser := template.WithAnswers(nil, []string{"Sure"}, nil)
+
+	vote, err := parser.ParseFromString("sure", gopolls.NewVoter("one", 1))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing \"sure\": %v", err)
+	}
+	if choice := vote.(*gopolls.BasicVote).Choice; choice != gopolls.Aye {
+		t.Errorf("Expected \"sure\" to be parsed as aye, got %s instead", choice)
+	}
+	if _, err := parser.ParseFromString("yes", gopolls.NewVoter("one", 1)); err == nil {
+		t.Errorf("Expected \"yes\" to be rejected after replacing aye values")
+	}
+	if template.AyeValues.Contains("sure") {
+		t.Errorf("WithAnswers must not change the sets of the original parser")
+	}
+}
+
+func TestCustomizeParsersAllDefaultTypes(t *testing.T) {
+	polls := []gopolls.AbstractPoll{
+		gopolls.NewBasicPoll(nil),
+		gopolls.NewMedianPoll(100, nil),
+		gopolls.NewSchulzePoll(3, nil),
+	}
+	templates := gopolls.GenerateDefaultParserTemplateMap()
+	parsers, err := gopolls.CustomizeParsers(polls, templates)
+	if err != nil {
+		t.Fatalf("Unexpected error customizing parsers: %v", err)
+	}
+	if parsers[0] == templates[gopolls.BasicPollType] {
+		t.Errorf("Expected customized basic parser to be a copy of the template")
+	}
+
+	if _, err := templates[gopolls.BasicPollType].CustomizeForPoll(gopolls.NewSchulzePoll(3, nil)); err == nil {
+		t.Errorf("Expected an error customizing the basic parser for a schulze poll")
+	}
+	if _, err := templates[gopolls.BasicPollType].CustomizeForPoll(gopolls.NewMedianPoll(100, nil)); err == nil {
+		t.Errorf("Expected an error customizing the basic parser for a median poll")
+	}
+}
+
+func TestBasicPollLiveTally(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	voters := make([]*gopolls.Voter, 10)
+	for i := range voters {
+		voters[i] = gopolls.NewVoter(fmt.Sprintf("voter%d", i), gopolls.Weight(rnd.Intn(5)+1))
+	}
+	choices := []gopolls.BasicPollAnswer{gopolls.No, gopolls.Aye, gopolls.Abstention, gopolls.BasicPollAnswer(42)}
+
+	for run := 0; run < 20; run++ {
+		live, err := gopolls.NewBasicPollLiveTally(gopolls.NewBasicPoll(nil))
+		if err != nil {
+			t.Fatalf("Unexpected error creating live tally: %v", err)
+		}
+		for op := 0; op < 200; op++ {
+			voter := voters[rnd.Intn(len(voters))]
+			if rnd.Intn(3) == 0 {
+				hasVoted := live.HasVoted(voter.Name)
+				if removed, removeErr := live.RemoveVote(voter.Name); removeErr != nil || removed != hasVoted {
+					t.Fatalf("RemoveVote returned %v (error %v), but HasVoted was %v", removed, removeErr, hasVoted)
+				}
+			} else {
+				vote := gopolls.NewBasicVote(voter, choices[rnd.Intn(len(choices))])
+				if addErr := live.AddVote(vote); addErr != nil {
+					t.Fatalf("Unexpected error adding vote: %v", addErr)
+				}
+			}
+			expected := live.Poll().Tally()
+			if got := live.Result(); !got.Equals(expected) {
+				t.Fatalf("Live tally is inconsistent after operation %d: expected %+v, got %+v", op, expected, got)
+			}
+		}
+	}
+}
+
+func TestBasicPollLiveTallyErrors(t *testing.T) {
+	one := gopolls.NewVoter("one", 1)
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(one, gopolls.Aye),
+		gopolls.NewBasicVote(one, gopolls.No),
+	})
+	_, err := gopolls.NewBasicPollLiveTally(poll)
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+
+	live, _ := gopolls.NewBasicPollLiveTally(gopolls.NewBasicPoll(nil))
+	if err := live.AddVote(gopolls.NewMedianVote(one, 1)); err == nil {
+		t.Error("Expected an error adding a median vote")
+	}
+	if err := live.AddVote(gopolls.NewBasicVote(gopolls.NewVoter("big", gopolls.NoWeight-1), gopolls.Aye)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := live.AddVote(gopolls.NewBasicVote(one, gopolls.Aye)); !errors.Is(err, gopolls.ErrWeightOverflow) {
+		t.Errorf("Expected ErrWeightOverflow, got %v", err)
+	}
+	if live.HasVoted("one") || len(live.Poll().Votes) != 1 {
+		t.Error("A failed AddVote must not change the poll")
+	}
+
+	live.Poll().Close()
+	if err := live.AddVote(gopolls.NewBasicVote(one, gopolls.Aye)); !errors.Is(err, gopolls.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed adding a vote to a closed poll, got %v", err)
+	}
+	if removed, err := live.RemoveVote("big"); removed || !errors.Is(err, gopolls.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed removing a vote from a closed poll, got %v", err)
+	}
+	if !live.HasVoted("big") || live.Result().VotersCount != 1 {
+		t.Error("Expected a closed poll not to be changed by the live tally")
+	}
+}
+
+func TestBasicPollTallyDetailed(t *testing.T) {
+	one, two, three, four := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 1),
+		gopolls.NewVoter("four", 1)
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(one, gopolls.Aye),
+		gopolls.NewBasicVote(two, gopolls.No),
+		gopolls.NewBasicVote(three, gopolls.Aye),
+		gopolls.NewBasicVote(four, gopolls.BasicPollAnswer(42)),
+	})
+	if res := poll.Tally(); res.VotersByChoice != nil {
+		t.Errorf("Expected Tally to not set VotersByChoice, got %v", res.VotersByChoice)
+	}
+	res := poll.TallyDetailed()
+	if !res.NumberVoters.Equals(poll.Tally().NumberVoters) {
+		t.Errorf("Expected the same counters as Tally, got %v", res.NumberVoters)
+	}
+	expected := map[gopolls.BasicPollAnswer][]*gopolls.Voter{
+		gopolls.Aye:                    {one, three},
+		gopolls.No:                     {two},
+		gopolls.InvalidBasicPollAnswer: {four},
+	}
+	if len(res.VotersByChoice) != len(expected) {
+		t.Fatalf("Expected %d entries in VotersByChoice, got %v", len(expected), res.VotersByChoice)
+	}
+	for choice, voters := range expected {
+		got := res.VotersByChoice[choice]
+		if len(got) != len(voters) {
+			t.Errorf("Expected voters %v for %s, got %v", voters, choice, got)
+			continue
+		}
+		for i, voter := range voters {
+			if got[i] != voter {
+				t.Errorf("Expected voter %s at position %d for %s, got %s", voter.Name, i, choice, got[i].Name)
+			}
+		}
+	}
+	if res.Equals(poll.Tally()) {
+		t.Error("Expected detailed result to not be equal to the result without details")
+	}
+	encoded, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var decoded struct {
+		VotersByChoice map[string][]string `json:"voters_by_choice"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if names := decoded.VotersByChoice["invalid"]; len(names) != 1 || names[0] != "four" {
+		t.Errorf("Expected voter four for invalid in JSON, got %s", encoded)
+	}
+}
+
+func TestBasicPollResultSummary(t *testing.T) {
+	votes := []*gopolls.BasicVote{
+		gopolls.NewBasicVote(gopolls.NewVoter("one", 12), gopolls.Aye),
+		gopolls.NewBasicVote(gopolls.NewVoter("two", 7), gopolls.No),
+		gopolls.NewBasicVote(gopolls.NewVoter("three", 1), gopolls.Abstention),
+		gopolls.NewBasicVote(gopolls.NewVoter("four", 5), 42),
+	}
+	res := gopolls.NewBasicPoll(votes).Tally()
+
+	tests := []struct {
+		includeAbstentions bool
+		ayes, noes         string
+		summary            string
+	}{
+		{true, "60.000", "35.000", "Ayes: 12 (60.000%), Noes: 7 (35.000%), Abstentions: 1 (5.000%)"},
+		{false, "63.158", "36.842", "Ayes: 12 (63.158%), Noes: 7 (36.842%), Abstentions: 1"},
+	}
+	for _, tc := range tests {
+		if got := gopolls.FormatPercentage(res.PercentAyes(tc.includeAbstentions)); got != tc.ayes {
+			t.Errorf("Expected %s%% ayes (abstentions: %v), got %s%%", tc.ayes, tc.includeAbstentions, got)
+		}
+		if got := gopolls.FormatPercentage(res.PercentNoes(tc.includeAbstentions)); got != tc.noes {
+			t.Errorf("Expected %s%% noes (abstentions: %v), got %s%%", tc.noes, tc.includeAbstentions, got)
+		}
+		if got := res.Summary(tc.includeAbstentions); got != tc.summary {
+			t.Errorf("Expected summary \"%s\", got \"%s\"", tc.summary, got)
+		}
+	}
+
+	expectedUnweighted := "Ayes: 1 (33.333%), Noes: 1 (33.333%), Abstentions: 1 (33.333%)"
+	if got := res.NumberVoters.Summary(true); got != expectedUnweighted {
+		t.Errorf("Expected unweighted summary \"%s\", got \"%s\"", expectedUnweighted, got)
+	}
+
+	empty := gopolls.NewBasicPoll(nil).Tally()
+	if empty.PercentAyes(true).Sign() != 0 || empty.PercentNoes(false).Sign() != 0 {
+		t.Error("Expected 0% for a poll without votes")
+	}
+	expectedEmpty := "Ayes: 0 (0.000%), Noes: 0 (0.000%), Abstentions: 0 (0.000%)"
+	if got := empty.Summary(true); got != expectedEmpty {
+		t.Errorf("Expected summary \"%s\", got \"%s\"", expectedEmpty, got)
+	}
+}
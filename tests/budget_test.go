@@ -0,0 +1,97 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+// singleVoteMedianPoll returns a median poll with a single vote for value, thus value wins.
+func singleVoteMedianPoll(value gopolls.MedianUnit) *gopolls.MedianPoll {
+	votes := []*gopolls.MedianVote{gopolls.NewMedianVote(gopolls.NewVoter("voter", 1), value)}
+	return gopolls.NewMedianPoll(value, votes)
+}
+
+func TestEvaluateWithBudget(t *testing.T) {
+	polls := map[string]*gopolls.MedianPoll{
+		"a": singleVoteMedianPoll(500),
+		"b": singleVoteMedianPoll(300),
+		"c": singleVoteMedianPoll(400),
+		"d": gopolls.NewMedianPoll(100, nil),
+	}
+	priorities := []string{"b", "a", "c", "d"}
+	evaluation, err := gopolls.EvaluateWithBudgetDetails(polls, priorities, 1000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if evaluation.Requested != 1200 || evaluation.Remaining != 0 {
+		t.Errorf("Expected requested 1200 and remaining 0, got %d and %d", evaluation.Requested, evaluation.Remaining)
+	}
+	expected := []struct {
+		poll                     string
+		value, capped, remaining gopolls.MedianUnit
+	}{
+		{"b", 300, 300, 700},
+		{"a", 500, 500, 200},
+		{"c", 400, 200, 0},
+		{"d", 0, 0, 0},
+	}
+	for i, entry := range evaluation.Entries {
+		exp := expected[i]
+		if entry.Poll != exp.poll || entry.Value != exp.value || entry.Capped != exp.capped || entry.Remaining != exp.remaining {
+			t.Errorf("Expected entry %v, got poll %s, value %d, capped %d, remaining %d", exp, entry.Poll, entry.Value,
+				entry.Capped, entry.Remaining)
+		}
+	}
+
+	capped, err := gopolls.EvaluateWithBudget(polls, priorities, 2000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedCapped := map[string]gopolls.MedianUnit{"a": 500, "b": 300, "c": 400, "d": 0}
+	if !reflect.DeepEqual(capped, expectedCapped) {
+		t.Errorf("Expected %v, got %v", expectedCapped, capped)
+	}
+}
+
+func TestEvaluateWithBudgetErrors(t *testing.T) {
+	polls := map[string]*gopolls.MedianPoll{
+		"a": singleVoteMedianPoll(500),
+		"b": singleVoteMedianPoll(300),
+	}
+	var semanticErr gopolls.PollingSemanticError
+	if _, err := gopolls.EvaluateWithBudget(polls, []string{"a"}, 1000); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for missing poll, got %v", err)
+	}
+	if _, err := gopolls.EvaluateWithBudget(polls, []string{"a", "b", "c"}, 1000); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for unknown poll, got %v", err)
+	}
+	var duplicateErr gopolls.DuplicateError
+	if _, err := gopolls.EvaluateWithBudget(polls, []string{"a", "b", "a"}, 1000); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+
+	huge := gopolls.NoMedianUnitValue - 1
+	polls = map[string]*gopolls.MedianPoll{
+		"a": singleVoteMedianPoll(huge),
+		"b": singleVoteMedianPoll(huge),
+	}
+	if _, err := gopolls.EvaluateWithBudget(polls, []string{"a", "b"}, 1000); !errors.Is(err, gopolls.ErrMedianUnitOverflow) {
+		t.Errorf("Expected ErrMedianUnitOverflow, got %v", err)
+	}
+}
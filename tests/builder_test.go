@@ -0,0 +1,175 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectionBuilderRoundTrip(t *testing.T) {
+	coll, err := gopolls.NewCollectionBuilder("Meeting").
+		Group("Finance").
+		MoneyPoll("New laptop", "1500 €").
+		MoneyPollValue("Office chairs", gopolls.NewCurrencyValue(42000, "€")).
+		Group("Statute").
+		Poll("Statute change", "Yes", "No").
+		Poll("Chair", "Alice", "Bob", "Charlie").
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if coll.NumGroups() != 2 || coll.NumSkeletons() != 4 {
+		t.Fatalf("Expected 2 groups and 4 polls, got %d and %d", coll.NumGroups(), coll.NumSkeletons())
+	}
+	laptop, ok := coll.Groups[0].Skeletons[0].(*gopolls.MoneyPollSkeleton)
+	if !ok || laptop.Value.ValueCents != 150000 {
+		t.Errorf("Expected money poll with value 150000 cents, got %v", coll.Groups[0].Skeletons[0])
+	}
+	var builder strings.Builder
+	if _, dumpErr := coll.Dump(&builder, gopolls.DefaultCurrencyHandler); dumpErr != nil {
+		t.Fatalf("Unexpected error: %s", dumpErr)
+	}
+	parsed, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(
+		gopolls.DefaultCurrencyHandler, builder.String())
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing the dump: %s", parseErr)
+	}
+	if !reflect.DeepEqual(coll, parsed) {
+		t.Errorf("Expected re-parsed collection to be equal to %v, got %v", coll, parsed)
+	}
+}
+
+func TestCollectionBuilderOptionDescriptions(t *testing.T) {
+	coll, err := gopolls.NewCollectionBuilder("Meeting").
+		Group("Building").
+		PollWithOptions("Hall",
+			gopolls.SkeletonOption{Name: "Option A", Description: "renovate hall (est. 20k€)"},
+			gopolls.SkeletonOption{Name: "Option B"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var builder strings.Builder
+	if _, dumpErr := coll.Dump(&builder, gopolls.DefaultCurrencyHandler); dumpErr != nil {
+		t.Fatalf("Unexpected error: %s", dumpErr)
+	}
+	expectedDump := "# Meeting\n\n## Building\n\n### Hall\n* Option A\n  renovate hall (est. 20k€)\n* Option B\n\n"
+	if builder.String() != expectedDump {
+		t.Errorf("Expected dump %q, got %q", expectedDump, builder.String())
+	}
+	parsed, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(
+		gopolls.DefaultCurrencyHandler, builder.String())
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing the dump: %s", parseErr)
+	}
+	if !reflect.DeepEqual(coll, parsed) {
+		t.Errorf("Expected re-parsed collection to be equal to %v, got %v", coll, parsed)
+	}
+
+	// the separator is only reserved if it is enabled
+	separated := gopolls.NewCollectionBuilder("Meeting")
+	separated.Parser.OptionDescriptionSeparator = gopolls.DefaultOptionDescriptionSeparator
+	_, err = separated.Group("Building").
+		PollWithOptions("Hall", gopolls.SkeletonOption{Name: "A — B"}, gopolls.SkeletonOption{Name: "Other"}).
+		Build()
+	var separatorErr gopolls.PollingSyntaxError
+	if !errors.As(err, &separatorErr) {
+		t.Errorf("Expected syntax error for an option name containing the separator, got %v", err)
+	}
+	if _, err = gopolls.NewCollectionBuilder("Meeting").Group("Building").
+		PollWithOptions("Hall", gopolls.SkeletonOption{Name: "A — B"}, gopolls.SkeletonOption{Name: "Other"}).
+		Build(); err != nil {
+		t.Errorf("Unexpected error for an option name with the separator disabled: %v", err)
+	}
+
+	invalid := []gopolls.SkeletonOption{
+		{Name: "A", Description: "* B"},
+		{Name: "A", Description: "### B"},
+		{Name: "A", Description: "two\nlines"},
+		{Name: "A", Description: " spaces"},
+	}
+	for _, option := range invalid {
+		_, err = gopolls.NewCollectionBuilder("Meeting").
+			Group("Building").
+			PollWithOptions("Hall", option, gopolls.SkeletonOption{Name: "Other"}).
+			Build()
+		var syntaxErr gopolls.PollingSyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected syntax error for option %v, got %v", option, err)
+		}
+	}
+}
+
+func TestCollectionBuilderErrors(t *testing.T) {
+	limited := gopolls.NewPollCollectionParser()
+	limited.MaxNumOptions = 2
+	limited.MaxCurrencyValue = 1000
+	tests := []struct {
+		name    string
+		builder *gopolls.CollectionBuilder
+		step    int
+		target  interface{}
+	}{
+		{"poll without group", gopolls.NewCollectionBuilder("T").Poll("A", "x", "y"), 1,
+			&gopolls.PollingSyntaxError{}},
+		{"one option", gopolls.NewCollectionBuilder("T").Group("G").Poll("A", "x"), 2,
+			&gopolls.PollingSyntaxError{}},
+		{"duplicate name", gopolls.NewCollectionBuilder("T").Group("G").Poll("A", "x", "y").
+			Group("H").MoneyPoll("A", "1 €"), 4, &gopolls.DuplicateError{}},
+		{"invalid money", gopolls.NewCollectionBuilder("T").Group("G").MoneyPoll("A", "abc"), 2,
+			&gopolls.PollingSyntaxError{}},
+		{"empty group", gopolls.NewCollectionBuilder("T").Group("G").Group("H"), 2,
+			&gopolls.PollingSyntaxError{}},
+		{"multi line option", gopolls.NewCollectionBuilder("T").Group("G").Poll("A", "x", "y\nz"), 2,
+			&gopolls.PollingSyntaxError{}},
+		{"annotations in name", gopolls.NewCollectionBuilder("T").Group("G").Poll("A [majority=2/3]", "x", "y"), 2,
+			&gopolls.PollingSyntaxError{}},
+		{"empty title", gopolls.NewCollectionBuilder(""), 0, &gopolls.PollingSyntaxError{}},
+	}
+	for _, tc := range tests {
+		_, err := tc.builder.Build()
+		var builderErr gopolls.CollectionBuilderError
+		if !errors.As(err, &builderErr) {
+			t.Errorf("%s: Expected CollectionBuilderError, got %v", tc.name, err)
+			continue
+		}
+		if builderErr.Step != tc.step {
+			t.Errorf("%s: Expected error in step %d, got %d (%s)", tc.name, tc.step, builderErr.Step, err)
+		}
+		if !errors.As(err, tc.target) {
+			t.Errorf("%s: Expected error of type %T, got %v", tc.name, tc.target, err)
+		}
+		if !errors.Is(err, gopolls.ErrPoll) {
+			t.Errorf("%s: Expected an internal error, got %v", tc.name, err)
+		}
+	}
+
+	// limits of the parser are applied
+	builder := gopolls.NewCollectionBuilder("T").Group("G").MoneyPoll("A", "20 €")
+	builder.Parser = limited
+	var validationErr *gopolls.ParserValidationError
+	if _, err := builder.Build(); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for max currency value, got %v", err)
+	}
+	builder = gopolls.NewCollectionBuilder("T").Group("G").Poll("A", "x", "y", "z")
+	builder.Parser = limited
+	if _, err := builder.Build(); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for max number of options, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestBasicPollDeepClone(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2)
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(one, gopolls.Aye),
+		gopolls.NewBasicVote(two, gopolls.No),
+	})
+	clone := poll.DeepClone()
+	if !clone.Equals(poll) {
+		t.Fatal("Expected clone to be equal to the original")
+	}
+	if clone.Votes[0].Voter != one {
+		t.Error("Expected clone to share the voter objects")
+	}
+	clone.Votes[0].Choice = gopolls.Abstention
+	clone.Votes = append(clone.Votes, gopolls.NewBasicVote(gopolls.NewVoter("three", 1), gopolls.Aye))
+	if poll.Votes[0].Choice != gopolls.Aye || len(poll.Votes) != 2 {
+		t.Error("Changing the clone changed the original")
+	}
+	if clone.Equals(poll) {
+		t.Error("Expected clone to be different after changes")
+	}
+	if !poll.Tally().Equals(poll.DeepClone().Tally()) {
+		t.Error("Expected equal results for original and clone")
+	}
+}
+
+func TestMedianPollDeepClone(t *testing.T) {
+	poll := gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(gopolls.NewVoter("one", 1), 10),
+		gopolls.NewMedianVote(gopolls.NewVoter("two", 1), 50),
+		gopolls.NewMedianVote(gopolls.NewVoter("three", 1), 30),
+	})
+	clone := poll.DeepClone()
+	if !clone.Equals(poll) {
+		t.Fatal("Expected clone to be equal to the original")
+	}
+	clone.SortVotes()
+	if poll.Votes[0].Value != 10 || poll.Votes[1].Value != 50 || poll.Votes[2].Value != 30 || poll.Sorted {
+		t.Error("Sorting the clone changed the original")
+	}
+	clone.Votes[0].Value = 99
+	if poll.Votes[1].Value != 50 {
+		t.Error("Changing a vote of the clone changed the original")
+	}
+	res := poll.Tally(gopolls.NoWeight)
+	if !res.Equals(poll.DeepClone().Tally(gopolls.NoWeight)) {
+		t.Error("Expected equal results for original and clone")
+	}
+	if res.Equals(clone.Tally(gopolls.NoWeight)) {
+		t.Error("Expected different results after changing the clone")
+	}
+}
+
+func TestSchulzePollDeepClone(t *testing.T) {
+	poll := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(gopolls.NewVoter("one", 1), gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("two", 2), gopolls.SchulzeRanking{2, 1, 0}),
+	})
+	clone := poll.DeepClone()
+	if !clone.Equals(poll) {
+		t.Fatal("Expected clone to be equal to the original")
+	}
+	clone.Votes[0].Ranking[0] = 3
+	if poll.Votes[0].Ranking[0] != 0 {
+		t.Error("Changing the ranking of the clone changed the original")
+	}
+	if clone.Equals(poll) {
+		t.Error("Expected clone to be different after changes")
+	}
+	res := poll.Tally()
+	if !res.Equals(poll.DeepClone().Tally()) {
+		t.Error("Expected equal results for original and clone")
+	}
+	if res.Equals(poll.TallyWithVariant(gopolls.SchulzeMargins)) {
+		t.Error("Expected results with different variants to be different")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"strings"
+	"testing"
+)
+
+func TestClosePoll(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2)
+	poll := gopolls.NewBasicPoll(nil)
+	if err := poll.AddVote(gopolls.NewBasicVote(one, gopolls.Aye)); err != nil {
+		t.Fatalf("Unexpected error adding vote: %v", err)
+	}
+	poll.Close()
+	if !poll.Closed() {
+		t.Fatal("Expected poll to be closed")
+	}
+	addErr := poll.AddVote(gopolls.NewBasicVote(two, gopolls.No))
+	if !errors.Is(addErr, gopolls.ErrPollClosed) || !errors.Is(addErr, gopolls.ErrPoll) {
+		t.Errorf("Expected ErrPollClosed, got %v", addErr)
+	}
+	if err := poll.ReplaceVote(gopolls.NewBasicVote(one, gopolls.No)); !errors.Is(err, gopolls.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed replacing a vote, got %v", err)
+	}
+	if _, err := poll.RemoveVote("one"); !errors.Is(err, gopolls.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed removing a vote, got %v", err)
+	}
+	if err := poll.AddSealed(gopolls.NewSealedVote(two, "+")); !errors.Is(err, gopolls.ErrPollClosed) ||
+		poll.NumSealed() != 0 {
+		t.Errorf("Expected ErrPollClosed adding a sealed vote, got %v", err)
+	}
+	if res := poll.Tally(); res.NumberVoters.NumAyes != 1 || res.NumberVoters.NumNoes != 0 {
+		t.Errorf("Expected closed poll to be tallied with one aye, got %v", res.NumberVoters)
+	}
+	if clone := poll.DeepClone(); !clone.Closed() {
+		t.Error("Expected clone of closed poll to be closed")
+	}
+
+	if err := poll.Reopen(); !errors.Is(err, gopolls.ErrReopenNotAllowed) || !poll.Closed() {
+		t.Errorf("Expected ErrReopenNotAllowed and a closed poll, got %v", err)
+	}
+	poll.AllowReopen = true
+	if err := poll.Reopen(); err != nil || poll.Closed() {
+		t.Fatalf("Expected poll to be reopened, got error %v", err)
+	}
+	if err := poll.AddVote(gopolls.NewBasicVote(two, gopolls.No)); err != nil {
+		t.Errorf("Unexpected error adding vote to reopened poll: %v", err)
+	}
+}
+
+func TestClosePollSynchronized(t *testing.T) {
+	schulze := gopolls.NewSchulzePoll(3, nil)
+	syncPoll := gopolls.NewSynchronizedPoll(schulze)
+	if err := gopolls.ClosePoll(syncPoll); err != nil {
+		t.Fatalf("Unexpected error closing poll: %v", err)
+	}
+	if !schulze.Closed() {
+		t.Error("Expected wrapped poll to be closed")
+	}
+	vote := gopolls.NewSchulzeVote(gopolls.NewVoter("one", 1), gopolls.SchulzeRanking{0, 1, 2})
+	if err := syncPoll.AddVote(vote); !errors.Is(err, gopolls.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed, got %v", err)
+	}
+	var typeErr gopolls.PollTypeError
+	if err := gopolls.ClosePoll(&experimentalPoll{}); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError for unsupported poll, got %v", err)
+	}
+}
+
+func TestFillClosedPoll(t *testing.T) {
+	matrix, polls, voters, parsers, policies := matrixTestSetup(t, 2)
+	polls["p1"].(*gopolls.BasicPoll).Close()
+	_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false)
+	if !errors.Is(err, gopolls.ErrPollClosed) {
+		t.Fatalf("Expected ErrPollClosed, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "p1") || !strings.Contains(msg, "one") {
+		t.Errorf("Expected poll and voter name in error, got %s", msg)
+	}
+	if numVotes := len(polls["p0"].(*gopolls.BasicPoll).Votes); numVotes != 2 {
+		t.Errorf("Expected open poll to be filled, got %d votes", numVotes)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+const diffOldCollection = `# Agenda draft 1
+
+## Finances
+
+### Budget
+- 1000.00 €
+
+### Travel costs
+- 200.00 €
+
+### Auditor
+* Alice
+* Bob
+* Carol
+
+## Events
+
+### Summer party
+* Yes
+* No
+
+### Venue
+* Hall
+* Garden
+
+`
+
+const diffNewCollection = `# Agenda draft 2
+
+## Finances
+
+### Budget
+- 800.00 €
+
+### Travel costs
+- 250.00 €
+
+### Auditor
+* Carol
+* Alice
+* Dave
+
+## Festivities
+
+### Summer celebration
+* Yes
+* No
+
+### Venue
+* Garden
+* Hall
+
+## Misc
+
+### Newsletter
+* Yes
+* No
+
+`
+
+func parseDiffCollection(t *testing.T, s string) *gopolls.PollSkeletonCollection {
+	coll, err := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{}, s)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	return coll
+}
+
+func TestDiffCollections(t *testing.T) {
+	oldColl, newColl := parseDiffCollection(t, diffOldCollection), parseDiffCollection(t, diffNewCollection)
+	diff := gopolls.DiffCollections(oldColl, newColl)
+	expected := gopolls.CollectionDiff{
+		TitleChanged:  true,
+		OldTitle:      "Agenda draft 1",
+		NewTitle:      "Agenda draft 2",
+		AddedGroups:   []string{"Festivities", "Misc"},
+		RemovedGroups: []string{"Events"},
+		AddedPolls:    []string{"Summer celebration", "Newsletter"},
+		RemovedPolls:  []string{"Summer party"},
+		ChangedPolls: []gopolls.PollChange{
+			{
+				Name:         "Budget",
+				ValueChanged: true,
+				OldValue:     gopolls.NewCurrencyValue(100000, "€"),
+				NewValue:     gopolls.NewCurrencyValue(80000, "€"),
+			},
+			{
+				Name:         "Travel costs",
+				ValueChanged: true,
+				OldValue:     gopolls.NewCurrencyValue(20000, "€"),
+				NewValue:     gopolls.NewCurrencyValue(25000, "€"),
+			},
+			{
+				Name:             "Auditor",
+				AddedOptions:     []string{"Dave"},
+				RemovedOptions:   []string{"Bob"},
+				OptionsReordered: true,
+			},
+			{Name: "Venue", OptionsReordered: true},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected diff\n%+v\ngot\n%+v", expected, diff)
+	}
+	if invalidated := gopolls.InvalidatedPolls(diff); !reflect.DeepEqual(invalidated,
+		[]string{"Budget", "Auditor", "Venue"}) {
+		t.Errorf("Expected Budget, Auditor and Venue to be invalidated, got %v", invalidated)
+	}
+
+	// the diff must be deterministic
+	for i := 0; i < 10; i++ {
+		if other := gopolls.DiffCollections(oldColl, newColl); !reflect.DeepEqual(diff, other) {
+			t.Fatalf("Expected the same diff in each run, got %+v and %+v", diff, other)
+		}
+	}
+}
+
+func TestDiffCollectionsDetectRenames(t *testing.T) {
+	oldColl, newColl := parseDiffCollection(t, diffOldCollection), parseDiffCollection(t, diffNewCollection)
+	diff := gopolls.DiffCollectionsWithOptions(oldColl, newColl, gopolls.CollectionDiffOptions{DetectRenames: true})
+	expectedRename := []gopolls.PollRename{{OldName: "Summer party", NewName: "Summer celebration"}}
+	if !reflect.DeepEqual(diff.RenamedPolls, expectedRename) {
+		t.Errorf("Expected Summer party to be renamed, got %v", diff.RenamedPolls)
+	}
+	if len(diff.RemovedPolls) != 0 || !reflect.DeepEqual(diff.AddedPolls, []string{"Newsletter"}) {
+		t.Errorf("Expected only Newsletter to be added, got added %v and removed %v", diff.AddedPolls,
+			diff.RemovedPolls)
+	}
+	// the group "Events" contains the same polls (after renaming) as "Festivities"
+	if !reflect.DeepEqual(diff.RenamedGroups, []gopolls.GroupRename{{OldTitle: "Events", NewTitle: "Festivities"}}) {
+		t.Errorf("Expected Events to be renamed, got %v", diff.RenamedGroups)
+	}
+	if len(diff.RemovedGroups) != 0 || !reflect.DeepEqual(diff.AddedGroups, []string{"Misc"}) {
+		t.Errorf("Expected only Misc to be added, got added %v and removed %v", diff.AddedGroups, diff.RemovedGroups)
+	}
+	if invalidated := gopolls.InvalidatedPolls(diff); !reflect.DeepEqual(invalidated,
+		[]string{"Budget", "Auditor", "Venue"}) {
+		t.Errorf("Expected renamed polls not to be invalidated, got %v", invalidated)
+	}
+}
+
+func TestDiffCollectionsRevisions(t *testing.T) {
+	base := func() *gopolls.PollSkeletonCollection {
+		coll := gopolls.NewPollSkeletonCollection("Agenda")
+		group := gopolls.NewPollGroup("Group")
+		skel := gopolls.NewPollSkeleton("Poll")
+		skel.AddOption("A", "")
+		skel.AddOption("B", "")
+		skel.AddOption("C", "")
+		group.Skeletons = append(group.Skeletons, skel, gopolls.NewMoneyPollSkeleton("Money",
+			gopolls.NewCurrencyValue(1000, "€")))
+		coll.Groups = append(coll.Groups, group)
+		return coll
+	}
+	tests := []struct {
+		name        string
+		revise      func(coll *gopolls.PollSkeletonCollection)
+		changed     bool
+		invalidated []string
+	}{
+		{"unchanged", func(coll *gopolls.PollSkeletonCollection) {}, false, nil},
+		{"description changed", func(coll *gopolls.PollSkeletonCollection) {
+			coll.Groups[0].Skeletons[0].(*gopolls.PollSkeleton).SetDescription(0, "The first option")
+		}, false, nil},
+		{"option appended", func(coll *gopolls.PollSkeletonCollection) {
+			coll.Groups[0].Skeletons[0].(*gopolls.PollSkeleton).AddOption("D", "")
+		}, true, []string{"Poll"}},
+		{"option renamed", func(coll *gopolls.PollSkeletonCollection) {
+			coll.Groups[0].Skeletons[0].(*gopolls.PollSkeleton).Options[1] = "B'"
+		}, true, []string{"Poll"}},
+		{"value raised", func(coll *gopolls.PollSkeletonCollection) {
+			coll.Groups[0].Skeletons[1].(*gopolls.MoneyPollSkeleton).Value.ValueCents = 2000
+		}, true, nil},
+		{"currency changed", func(coll *gopolls.PollSkeletonCollection) {
+			coll.Groups[0].Skeletons[1].(*gopolls.MoneyPollSkeleton).Value.Currency = "$"
+		}, true, []string{"Money"}},
+		{"type changed", func(coll *gopolls.PollSkeletonCollection) {
+			coll.Groups[0].Skeletons[1] = gopolls.NewPollSkeleton("Money")
+		}, true, []string{"Money"}},
+		{"group renamed", func(coll *gopolls.PollSkeletonCollection) {
+			coll.Groups[0].Title = "Other group"
+		}, true, nil},
+	}
+	for _, tc := range tests {
+		newColl := base()
+		tc.revise(newColl)
+		diff := gopolls.DiffCollections(base(), newColl)
+		if diff.Changed() != tc.changed {
+			t.Errorf("%s: expected changed = %v, got %v (%s)", tc.name, tc.changed, diff.Changed(), diff)
+		}
+		if invalidated := gopolls.InvalidatedPolls(diff); !reflect.DeepEqual(invalidated, tc.invalidated) {
+			t.Errorf("%s: expected invalidated polls %v, got %v", tc.name, tc.invalidated, invalidated)
+		}
+	}
+}
+
+func TestCollectionDiffString(t *testing.T) {
+	oldColl, newColl := parseDiffCollection(t, diffOldCollection), parseDiffCollection(t, diffNewCollection)
+	diff := gopolls.DiffCollectionsWithOptions(oldColl, newColl, gopolls.CollectionDiffOptions{DetectRenames: true})
+	expected := "title Agenda draft 1 -> Agenda draft 2, added group \"Misc\", " +
+		"renamed group \"Events\" -> \"Festivities\", added poll \"Newsletter\", " +
+		"renamed poll \"Summer party\" -> \"Summer celebration\", poll \"Budget\": value 1000.00 € -> 800.00 €, " +
+		"poll \"Travel costs\": value 200.00 € -> 250.00 €, " +
+		"poll \"Auditor\": added options Dave, removed options Bob, options reordered, " +
+		"poll \"Venue\": options reordered"
+	if got := diff.String(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+	if got := gopolls.DiffCollections(oldColl, oldColl).String(); got != "no changes" {
+		t.Errorf("Expected \"no changes\", got \"%s\"", got)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const yamlRoundTripCollection = `# Meeting
+## Finance
+### New laptop [majority=2/3, quorum=1/2]
+- 1500,00 €
+
+### Statute change [empty=abstention]
+* Yes
+* No
+  keep the old statute
+
+## Elections
+### Board
+* Alice — "the chair"
+* Bob's team
+* Carol # 1
+* No
+
+`
+
+func dumpCollectionTesting(t *testing.T, coll *gopolls.PollSkeletonCollection, yaml bool) string {
+	t.Helper()
+	var builder strings.Builder
+	var err error
+	if yaml {
+		_, err = coll.DumpYAML(&builder, gopolls.DefaultCurrencyHandler)
+	} else {
+		_, err = coll.Dump(&builder, gopolls.DefaultCurrencyHandler)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error dumping collection: %v", err)
+	}
+	return builder.String()
+}
+
+func TestCollectionYAMLRoundTrip(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, yamlRoundTripCollection)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	dsl := dumpCollectionTesting(t, coll, false)
+
+	// DSL -> yaml -> DSL
+	yaml := dumpCollectionTesting(t, coll, true)
+	fromYAML, err := parser.ParseCollectionYAMLFromString(nil, yaml)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing yaml: %v\n%s", err, yaml)
+	}
+	if got := dumpCollectionTesting(t, fromYAML, false); got != dsl {
+		t.Errorf("Expected collection from yaml to be dumped as\n%s\ngot\n%s", dsl, got)
+	}
+
+	// yaml -> DSL -> yaml
+	fromDSL, err := parser.ParseCollectionSkeletonsFromString(nil, dumpCollectionTesting(t, fromYAML, false))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing dumped collection: %v", err)
+	}
+	if got := dumpCollectionTesting(t, fromDSL, true); got != yaml {
+		t.Errorf("Expected yaml\n%s\ngot\n%s", yaml, got)
+	}
+
+	money := fromYAML.Groups[0].Skeletons[0].(*gopolls.MoneyPollSkeleton)
+	if money.Value.ValueCents != 150000 || money.RequiredMajority.RatString() != "2/3" ||
+		money.Quorum.RatString() != "1/2" {
+		t.Errorf("Expected money poll with value 150000 and annotations, got %+v", money)
+	}
+}
+
+func TestParseCollectionYAML(t *testing.T) {
+	in := `---
+# the agenda
+title: Meeting # the title
+groups:
+- title: 'Finance # 1'
+  polls:
+    - name: New laptop
+      value: 1500,00 €
+      majority: 2/3
+    - name: "Statute: change"
+      options: [Yes, 'No', "Maybe, later"]
+      empty: no
+- title: Elections
+  polls:
+    -
+      name: Board
+      options:
+        - Alice
+        - name: Bob
+          description: Bob's team
+`
+	coll, err := gopolls.NewPollCollectionParser().ParseCollectionYAMLFromString(nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if coll.Title != "Meeting" || coll.NumGroups() != 2 || coll.NumSkeletons() != 3 {
+		t.Fatalf("Expected collection Meeting with 2 groups and 3 polls, got %+v", coll)
+	}
+	if coll.Groups[0].Title != "Finance # 1" {
+		t.Errorf("Expected group title \"Finance # 1\", got \"%s\"", coll.Groups[0].Title)
+	}
+	statute := coll.Groups[0].Skeletons[1].(*gopolls.PollSkeleton)
+	if statute.Name != "Statute: change" || !reflect.DeepEqual(statute.Options, []string{"Yes", "No", "Maybe, later"}) {
+		t.Errorf("Unexpected poll %+v", statute)
+	}
+	if statute.EmptyPolicy == nil || *statute.EmptyPolicy != gopolls.AddAsNoEmptyVote {
+		t.Errorf("Expected empty policy no, got %v", statute.EmptyPolicy)
+	}
+	board := coll.Groups[1].Skeletons[0].(*gopolls.PollSkeleton)
+	expectedOptions := []gopolls.SkeletonOption{{Name: "Alice"}, {Name: "Bob", Description: "Bob's team"}}
+	if options := board.OptionList(); !reflect.DeepEqual(options, expectedOptions) {
+		t.Errorf("Expected options %v, got %v", expectedOptions, options)
+	}
+}
+
+func TestParseCollectionYAMLErrors(t *testing.T) {
+	tests := []struct {
+		in       string
+		lineNum  int
+		contains string
+	}{
+		{"", -1, "no title"},
+		{"groups: []\n", 1, "must have a \"title\""},
+		{"title: A\nfoo: bar\n", 2, "unknown key \"foo\""},
+		{"title: A\ntitle: B\n", 2, "duplicate key"},
+		{"title: A\n  groups: []\n", 2, "unexpected indentation"},
+		{"title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n", 5, "either options or a value"},
+		{"title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n        value: 1\n        options: [a, b]\n",
+			5, "both options and a value"},
+		{"title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n        options: [a]\n", 5, "only 1 option"},
+		{"title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n        value: foo\n", 5, "money value"},
+		{"title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n        value: 1\n        majority: 3/2\n",
+			7, "between 0 and 1"},
+		{"title: A\ngroups:\n  - title: G\n  - title: H\n", 4, "contains no polls"},
+		{"title: A\ngroups: {}\n", 2, "unsupported yaml syntax"},
+		{"title: \"A\n", 1, "invalid double quoted string"},
+		{"title: A\n---\ntitle: B\n", 2, "multiple yaml documents"},
+	}
+	for _, tc := range tests {
+		_, err := gopolls.NewPollCollectionParser().ParseCollectionYAMLFromString(nil, tc.in)
+		var syntaxErr gopolls.PollingSyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected PollingSyntaxError for input %q, got %v", tc.in, err)
+			continue
+		}
+		if syntaxErr.LineNum != tc.lineNum || !strings.Contains(err.Error(), tc.contains) {
+			t.Errorf("Expected error in line %d containing \"%s\" for input %q, got line %d: %v",
+				tc.lineNum, tc.contains, tc.in, syntaxErr.LineNum, err)
+		}
+	}
+}
+
+func TestParseCollectionYAMLLimits(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	parser.MaxPollNameLength = 5
+	parser.MaxNumOptions = 2
+	parser.MaxCurrencyValue = 1000
+
+	tests := []struct {
+		dsl, yaml string
+	}{
+		{
+			"# A\n## G\n### Too long\n* a\n* b\n",
+			"title: A\ngroups:\n  - title: G\n    polls:\n      - name: Too long\n        options: [a, b]\n",
+		},
+		{
+			"# A\n## G\n### P\n* a\n* b\n* c\n",
+			"title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n        options: [a, b, c]\n",
+		},
+		{
+			"# A\n## G\n### P\n- 11,00 €\n",
+			"title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n        value: 11,00 €\n",
+		},
+	}
+	for _, tc := range tests {
+		var dslErr, yamlErr *gopolls.ParserValidationError
+		_, err := parser.ParseCollectionSkeletonsFromString(nil, tc.dsl)
+		if !errors.As(err, &dslErr) {
+			t.Fatalf("Expected ParserValidationError for %q, got %v", tc.dsl, err)
+		}
+		_, err = parser.ParseCollectionYAMLFromString(nil, tc.yaml)
+		if !errors.As(err, &yamlErr) {
+			t.Fatalf("Expected ParserValidationError for %q, got %v", tc.yaml, err)
+		}
+		if dslErr.Error() != yamlErr.Error() {
+			t.Errorf("Expected same error for both formats, got \"%v\" and \"%v\"", dslErr, yamlErr)
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package tests
 
 import (
+	"errors"
 	"github.com/FabianWe/gopolls"
 	"testing"
 )
@@ -74,3 +75,256 @@ func TestSimpleEuroHandlerParse(t *testing.T) {
 		}
 	}
 }
+
+func TestCurrencyValueArithmetic(t *testing.T) {
+	maxInt := int(^uint(0) >> 1)
+	euro := func(cents int) gopolls.CurrencyValue {
+		return gopolls.NewCurrencyValue(cents, "€")
+	}
+
+	sum, err := euro(100).Add(gopolls.NewCurrencyValue(42, ""))
+	if err != nil || !sum.Equals(euro(142)) {
+		t.Errorf("Expected 142 €, got %v (error %v)", sum, err)
+	}
+	diff, err := gopolls.NewCurrencyValue(100, "").Sub(euro(142))
+	if err != nil || !diff.Equals(euro(-42)) {
+		t.Errorf("Expected -42 €, got %v (error %v)", diff, err)
+	}
+	product, err := euro(21).Mul(-2)
+	if err != nil || !product.Equals(euro(-42)) {
+		t.Errorf("Expected -42 €, got %v (error %v)", product, err)
+	}
+	if cmp, cmpErr := euro(1).Cmp(euro(2)); cmp != -1 || cmpErr != nil {
+		t.Errorf("Expected -1, got %d (error %v)", cmp, cmpErr)
+	}
+
+	if _, mismatchErr := euro(1).Add(gopolls.NewCurrencyValue(1, "$")); !errors.Is(mismatchErr, gopolls.ErrCurrencyMismatch) {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", mismatchErr)
+	}
+	if _, mismatchErr := euro(1).Cmp(gopolls.NewCurrencyValue(1, "$")); !errors.Is(mismatchErr, gopolls.ErrCurrencyMismatch) {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", mismatchErr)
+	}
+	overflows := []func() error{
+		func() error { _, e := euro(maxInt).Add(euro(1)); return e },
+		func() error { _, e := euro(-maxInt - 1).Sub(euro(1)); return e },
+		func() error { _, e := euro(maxInt/2 + 1).Mul(2); return e },
+		func() error { _, e := euro(-maxInt - 1).Mul(-1); return e },
+	}
+	for i, f := range overflows {
+		if overflowErr := f(); !errors.Is(overflowErr, gopolls.ErrCurrencyOverflow) {
+			t.Errorf("Expected ErrCurrencyOverflow in case %d, got %v", i, overflowErr)
+		}
+	}
+	if _, noOverflowErr := euro(maxInt - 1).Add(euro(1)); noOverflowErr != nil {
+		t.Errorf("Unexpected error at the boundary: %v", noOverflowErr)
+	}
+}
+
+func TestSumApprovedAmounts(t *testing.T) {
+	approved := gopolls.NewMedianResult()
+	approved.MajorityValue = 1000
+	other := gopolls.NewMedianResult()
+	other.MajorityValue = 250
+	rejected := gopolls.NewMedianResult()
+	results := map[string]*gopolls.MedianResult{"a": approved, "b": other, "c": rejected}
+	sum, err := gopolls.SumApprovedAmounts(results, "€")
+	if err != nil || !sum.Equals(gopolls.NewCurrencyValue(1250, "€")) {
+		t.Errorf("Expected 12.50 €, got %v (error %v)", sum, err)
+	}
+	huge := gopolls.NewMedianResult()
+	huge.MajorityValue = gopolls.MedianUnit(^uint(0) >> 1)
+	results["d"] = huge
+	if _, overflowErr := gopolls.SumApprovedAmounts(results, "€"); !errors.Is(overflowErr, gopolls.ErrCurrencyOverflow) {
+		t.Errorf("Expected ErrCurrencyOverflow, got %v", overflowErr)
+	}
+}
+
+func TestConfigurableCurrencyHandler(t *testing.T) {
+	german, us := gopolls.NewGermanEuroHandler(), gopolls.NewUSDollarHandler()
+	formatTests := []struct {
+		handler  gopolls.CurrencyHandler
+		value    gopolls.CurrencyValue
+		expected string
+	}{
+		{german, gopolls.NewCurrencyValue(123456, "€"), "1.234,56 €"},
+		{german, gopolls.NewCurrencyValue(5, "€"), "0,05 €"},
+		{german, gopolls.NewCurrencyValue(-123456789, "€"), "-1.234.567,89 €"},
+		{german, gopolls.NewCurrencyValue(100000, ""), "1.000,00"},
+		{us, gopolls.NewCurrencyValue(123456, "$"), "$1,234.56"},
+		{us, gopolls.NewCurrencyValue(99, "$"), "$0.99"},
+		{us, gopolls.NewCurrencyValue(-12345678, "$"), "-$123,456.78"},
+	}
+	for _, tc := range formatTests {
+		got := tc.handler.Format(tc.value)
+		if got != tc.expected {
+			t.Errorf("Expected %s to be formatted as \"%s\", got \"%s\"", tc.value, tc.expected, got)
+		}
+		parsed, err := tc.handler.Parse(got)
+		if err != nil || !parsed.Equals(tc.value) {
+			t.Errorf("Expected \"%s\" to be parsed back to %s, got %s (%v)", got, tc.value, parsed, err)
+		}
+	}
+
+	parseTests := []struct {
+		handler  gopolls.CurrencyHandler
+		in       string
+		expected gopolls.CurrencyValue
+	}{
+		{german, "1.234", gopolls.NewCurrencyValue(123400, "")},
+		{german, "1234,5 €", gopolls.NewCurrencyValue(123450, "€")},
+		{german, "1.234,5€", gopolls.NewCurrencyValue(123450, "€")},
+		{german, " - 42 € ", gopolls.NewCurrencyValue(-4200, "€")},
+		{us, "1,234", gopolls.NewCurrencyValue(123400, "")},
+		{us, "$ 1234.5", gopolls.NewCurrencyValue(123450, "$")},
+		{us, "-$0.01", gopolls.NewCurrencyValue(-1, "$")},
+	}
+	for _, tc := range parseTests {
+		got, err := tc.handler.Parse(tc.in)
+		if err != nil {
+			t.Errorf("Unexpected error parsing \"%s\": %v", tc.in, err)
+			continue
+		}
+		if !got.Equals(tc.expected) {
+			t.Errorf("Expected \"%s\" to be parsed as %s, got %s", tc.in, tc.expected, got)
+		}
+	}
+
+	invalid := []struct {
+		handler gopolls.CurrencyHandler
+		in      string
+	}{
+		{german, "12.34,5"},
+		{german, "1.23"},
+		{german, "1,234"},
+		{german, "1.234,567"},
+		{german, "$1,00"},
+		{german, ""},
+		{us, "1.234,56"},
+		{us, "1,23.45"},
+		{us, "1.5$"},
+		{us, "99999999999999999999"},
+	}
+	for _, tc := range invalid {
+		if got, err := tc.handler.Parse(tc.in); err == nil {
+			t.Errorf("Expected an error parsing \"%s\", got %s", tc.in, got)
+		}
+	}
+}
+
+func newPreciseHandler(t *testing.T, decimalPlaces int) *gopolls.PreciseCurrencyHandler {
+	handler, err := gopolls.NewPreciseCurrencyHandler(decimalPlaces)
+	if err != nil {
+		t.Fatalf("Unexpected error creating handler with %d decimal places: %v", decimalPlaces, err)
+	}
+	return handler
+}
+
+func TestPreciseCurrencyHandler(t *testing.T) {
+	three, four := newPreciseHandler(t, 3), newPreciseHandler(t, 4)
+	formatTests := []struct {
+		handler  *gopolls.PreciseCurrencyHandler
+		value    gopolls.CurrencyValue
+		expected string
+	}{
+		{three, gopolls.NewCurrencyValue(12345, "€"), "12.345 €"},
+		{three, gopolls.NewCurrencyValue(5, "€"), "0.005 €"},
+		{three, gopolls.NewCurrencyValue(-1050, ""), "-1.050"},
+		{three, gopolls.NewCurrencyValue(0, ""), "0.000"},
+		{four, gopolls.NewCurrencyValue(123456, "€"), "12.3456 €"},
+		{four, gopolls.NewCurrencyValue(42, "€"), "0.0042 €"},
+		{four, gopolls.NewCurrencyValue(-10000, ""), "-1.0000"},
+	}
+	for _, tc := range formatTests {
+		got := tc.handler.Format(tc.value)
+		if got != tc.expected {
+			t.Errorf("Expected %s to be formatted as \"%s\" with %d decimal places, got \"%s\"", tc.value,
+				tc.expected, tc.handler.DecimalPlaces, got)
+		}
+		parsed, err := tc.handler.Parse(got)
+		if err != nil || !parsed.Equals(tc.value) {
+			t.Errorf("Expected \"%s\" to be parsed back to %s, got %s (%v)", got, tc.value, parsed, err)
+		}
+	}
+
+	parseTests := []struct {
+		handler  *gopolls.PreciseCurrencyHandler
+		in       string
+		expected gopolls.CurrencyValue
+	}{
+		{three, "12,345€", gopolls.NewCurrencyValue(12345, "€")},
+		{three, "12.3", gopolls.NewCurrencyValue(12300, "")},
+		{three, " - 42 € ", gopolls.NewCurrencyValue(-42000, "€")},
+		{four, "12.345 €", gopolls.NewCurrencyValue(123450, "€")},
+		{four, "0,0001", gopolls.NewCurrencyValue(1, "")},
+	}
+	for _, tc := range parseTests {
+		got, err := tc.handler.Parse(tc.in)
+		if err != nil {
+			t.Errorf("Unexpected error parsing \"%s\": %v", tc.in, err)
+			continue
+		}
+		if !got.Equals(tc.expected) {
+			t.Errorf("Expected \"%s\" to be parsed as %s, got %s", tc.in, tc.expected, got)
+		}
+	}
+
+	invalid := []struct {
+		handler *gopolls.PreciseCurrencyHandler
+		in      string
+	}{
+		{three, "1.2345"},
+		{three, "1."},
+		{three, "1.2.3"},
+		{three, "$1"},
+		{four, ""},
+		{four, "99999999999999999999"},
+	}
+	for _, tc := range invalid {
+		if got, err := tc.handler.Parse(tc.in); err == nil {
+			t.Errorf("Expected an error parsing \"%s\", got %s", tc.in, got)
+		}
+	}
+
+	for _, decimalPlaces := range []int{-1, gopolls.MaxCurrencyDecimalPlaces + 1} {
+		if _, err := gopolls.NewPreciseCurrencyHandler(decimalPlaces); err == nil {
+			t.Errorf("Expected an error for %d decimal places", decimalPlaces)
+		}
+	}
+}
+
+func TestPreciseCurrencyMedianPoll(t *testing.T) {
+	handler := newPreciseHandler(t, 3)
+	parser := gopolls.NewPollCollectionParser()
+	coll, parseErr := parser.ParseCollectionSkeletonsFromString(handler,
+		"# Title\n## Group\n### Hourly rate\n- 12.345 €\n")
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	skel := coll.Groups[0].Skeletons[0].(*gopolls.MoneyPollSkeleton)
+	if skel.Value.ValueCents != 12345 {
+		t.Fatalf("Expected value 12345, got %d", skel.Value.ValueCents)
+	}
+	poll, convertErr := gopolls.DefaultSkeletonConverter(skel)
+	if convertErr != nil {
+		t.Fatalf("Unexpected error converting skeleton: %v", convertErr)
+	}
+	customizer, customizeErr := gopolls.NewMedianVoteParser(handler).CustomizeForPoll(poll)
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error customizing parser: %v", customizeErr)
+	}
+	voteParser := customizer.(gopolls.VoteParser)
+	vote, voteErr := voteParser.ParseFromString("12.3 €", gopolls.NewVoter("one", 1))
+	if voteErr != nil {
+		t.Fatalf("Unexpected error parsing vote: %v", voteErr)
+	}
+	if value := vote.(*gopolls.MedianVote).Value; value != 12300 {
+		t.Errorf("Expected vote value 12300, got %d", value)
+	}
+	if _, tooBigErr := voteParser.ParseFromString("12.346", gopolls.NewVoter("two", 1)); tooBigErr == nil {
+		t.Error("Expected an error for a vote greater than the poll value")
+	}
+	formatted := handler.Format(gopolls.NewCurrencyValue(int(vote.(*gopolls.MedianVote).Value), "€"))
+	if formatted != "12.300 €" {
+		t.Errorf("Expected vote to be formatted as \"12.300 €\", got \"%s\"", formatted)
+	}
+}
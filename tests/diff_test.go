@@ -0,0 +1,154 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestDiffBasicPollResults(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2)
+	oldResult := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(two, gopolls.No),
+	}).Tally()
+	newResult := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(two, gopolls.Aye),
+	}).Tally()
+	diff := gopolls.DiffBasicPollResults(oldResult, newResult)
+	if !diff.Changed() {
+		t.Fatal("Expected diff to be changed")
+	}
+	expected := gopolls.BasicPollCounterDiff{NumNoes: -2, NumAyes: 2}
+	if diff.WeightedVotes != expected {
+		t.Errorf("Expected weighted votes diff %v, got %v", expected, diff.WeightedVotes)
+	}
+	if diff.OldOutcome != gopolls.Rejected || diff.NewOutcome != gopolls.Passed {
+		t.Errorf("Expected outcome to change from Rejected to Passed, got %s -> %s", diff.OldOutcome, diff.NewOutcome)
+	}
+	expectedStr := "outcome Rejected -> Passed, noes (weighted) -2, ayes (weighted) +2, noes (voters) -1, ayes (voters) +1"
+	if got := diff.String(); got != expectedStr {
+		t.Errorf("Expected string \"%s\", got \"%s\"", expectedStr, got)
+	}
+	same := gopolls.DiffBasicPollResults(oldResult, oldResult)
+	if same.Changed() || same.String() != "no changes" {
+		t.Errorf("Expected no changes, got \"%s\"", same)
+	}
+}
+
+func TestDiffMedianResults(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	oldResult := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 500), gopolls.NewMedianVote(two, 300),
+	}).Tally(gopolls.NoWeight)
+	newResult := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 500), gopolls.NewMedianVote(two, 500),
+	}).Tally(gopolls.NoWeight)
+	diff := gopolls.DiffMedianResults(oldResult, newResult)
+	if !diff.Changed() {
+		t.Fatal("Expected diff to be changed")
+	}
+	if diff.OldMajorityValue != oldResult.MajorityValue || diff.NewMajorityValue != 500 {
+		t.Errorf("Expected majority value to change from %d to 500, got %d -> %d",
+			oldResult.MajorityValue, diff.OldMajorityValue, diff.NewMajorityValue)
+	}
+	if diff.WeightSum != 0 {
+		t.Errorf("Expected weight sum delta 0, got %d", diff.WeightSum)
+	}
+	if same := gopolls.DiffMedianResults(newResult, newResult); same.Changed() {
+		t.Errorf("Expected no changes, got \"%s\"", same)
+	}
+}
+
+func TestDiffSchulzeResults(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	oldResult := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 2}),
+	}).Tally()
+	newResult := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{2, 0, 1}),
+	}).Tally()
+	diff, err := gopolls.DiffSchulzeResults(oldResult, newResult)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !diff.Changed() || !diff.RankingChanged() {
+		t.Errorf("Expected ranking to change, got %v -> %v", diff.OldRankedGroups, diff.NewRankedGroups)
+	}
+	if diff.WeightSum != 1 {
+		t.Errorf("Expected weight sum delta 1, got %d", diff.WeightSum)
+	}
+	for _, cell := range diff.DChanges {
+		if oldResult.D[cell.Row][cell.Column] != cell.Old || newResult.D[cell.Row][cell.Column] != cell.New {
+			t.Errorf("Invalid cell change %v", cell)
+		}
+	}
+	if len(diff.DChanges) == 0 {
+		t.Error("Expected changes in d")
+	}
+	other := gopolls.NewSchulzePoll(2, nil).Tally()
+	_, err = gopolls.DiffSchulzeResults(oldResult, other)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for different dimensions, got %v", err)
+	}
+}
+
+func TestDiffResults(t *testing.T) {
+	one := gopolls.NewVoter("one", 1)
+	basic := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye)}).Tally()
+	basicChanged := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.No)}).Tally()
+	median := gopolls.NewMedianPoll(100, []*gopolls.MedianVote{gopolls.NewMedianVote(one, 50)}).Tally(gopolls.NoWeight)
+	score := gopolls.NewScorePoll(2, 5, []*gopolls.ScoreVote{gopolls.NewScoreVote(one, []uint8{1, 4})}).Tally()
+	oldResults := map[string]gopolls.AbstractPollResult{
+		"same":    basic,
+		"changed": basic,
+		"removed": median,
+		"type":    median,
+	}
+	newResults := map[string]gopolls.AbstractPollResult{
+		"same":    basic,
+		"changed": basicChanged,
+		"added":   score,
+		"type":    basic,
+	}
+	diffs, err := gopolls.DiffResults(oldResults, newResults)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := map[string]gopolls.ResultDiffStatus{
+		"same":    gopolls.ResultUnchanged,
+		"changed": gopolls.ResultChanged,
+		"removed": gopolls.ResultRemoved,
+		"added":   gopolls.ResultAdded,
+		"type":    gopolls.ResultTypeChanged,
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d diffs, got %d", len(expected), len(diffs))
+	}
+	for name, status := range expected {
+		if got := diffs[name].Status; got != status {
+			t.Errorf("Expected status %s for poll \"%s\", got %s", status, name, got)
+		}
+	}
+	if diffs["changed"].Details == nil || diffs["added"].Details != nil {
+		t.Error("Expected details only for polls in both results")
+	}
+	if got := diffs["type"].String(); got != "type changed: median-result -> basic-result" {
+		t.Errorf("Unexpected string for changed type: \"%s\"", got)
+	}
+}
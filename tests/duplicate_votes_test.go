@@ -0,0 +1,173 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+func TestRejectDuplicateVoters(t *testing.T) {
+	basic := gopolls.NewBasicPoll(nil)
+	basic.RejectDuplicateVoters = true
+	median := gopolls.NewMedianPoll(100, nil)
+	median.RejectDuplicateVoters = true
+	schulze := gopolls.NewSchulzePoll(2, nil)
+	schulze.RejectDuplicateVoters = true
+	score := gopolls.NewScorePoll(2, 5, nil)
+	score.RejectDuplicateVoters = true
+
+	tests := []struct {
+		poll    gopolls.VoteEditor
+		newVote func(voter *gopolls.Voter) gopolls.AbstractVote
+	}{
+		{basic, func(voter *gopolls.Voter) gopolls.AbstractVote { return gopolls.NewBasicVote(voter, gopolls.Aye) }},
+		{median, func(voter *gopolls.Voter) gopolls.AbstractVote { return gopolls.NewMedianVote(voter, 50) }},
+		{schulze, func(voter *gopolls.Voter) gopolls.AbstractVote {
+			return gopolls.NewSchulzeVote(voter, gopolls.SchulzeRanking{0, 1})
+		}},
+		{score, func(voter *gopolls.Voter) gopolls.AbstractVote { return gopolls.NewScoreVote(voter, []uint8{1, 2}) }},
+	}
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	for _, tc := range tests {
+		pollType := tc.poll.PollType()
+		if err := tc.poll.AddVote(tc.newVote(one)); err != nil {
+			t.Fatalf("Unexpected error for poll of type %s: %s", pollType, err)
+		}
+		if err := tc.poll.AddVote(tc.newVote(two)); err != nil {
+			t.Fatalf("Unexpected error for poll of type %s: %s", pollType, err)
+		}
+		// a different voter object with the same name is a duplicate too
+		err := tc.poll.AddVote(tc.newVote(gopolls.NewVoter("one", 2)))
+		var duplicateErr gopolls.DuplicateError
+		if !errors.As(err, &duplicateErr) {
+			t.Errorf("Expected DuplicateError for poll of type %s, got %v", pollType, err)
+		}
+		if numVotes, _ := gopolls.VoteCount(tc.poll); numVotes != 2 {
+			t.Errorf("Expected two votes in poll of type %s after duplicate, got %d", pollType, numVotes)
+		}
+		// replacing a vote is not a duplicate
+		if err = tc.poll.ReplaceVote(tc.newVote(one)); err != nil {
+			t.Errorf("Unexpected error replacing vote in poll of type %s: %s", pollType, err)
+		}
+		// after removing the vote the voter can vote again
+		if _, err = tc.poll.RemoveVote("one"); err != nil {
+			t.Fatalf("Unexpected error for poll of type %s: %s", pollType, err)
+		}
+		if err = tc.poll.AddVote(tc.newVote(one)); err != nil {
+			t.Errorf("Unexpected error adding vote again to poll of type %s: %s", pollType, err)
+		}
+		if err = tc.poll.AddVote(tc.newVote(two)); !errors.As(err, &duplicateErr) {
+			t.Errorf("Expected DuplicateError after RemoveVote for poll of type %s, got %v", pollType, err)
+		}
+	}
+
+	// votes appended directly are found as well
+	three := gopolls.NewVoter("three", 1)
+	schulze.Votes = append(schulze.Votes, gopolls.NewSchulzeVote(three, gopolls.SchulzeRanking{1, 0}))
+	err := schulze.AddVote(gopolls.NewSchulzeVote(three, gopolls.SchulzeRanking{0, 1}))
+	if !errors.As(err, &gopolls.DuplicateError{}) {
+		t.Errorf("Expected DuplicateError for vote appended directly, got %v", err)
+	}
+	// the flag is copied by DeepClone
+	if clone := median.DeepClone(); !clone.RejectDuplicateVoters {
+		t.Error("Expected RejectDuplicateVoters to be copied by DeepClone")
+	} else if err = clone.AddVote(gopolls.NewMedianVote(two, 10)); !errors.As(err, &gopolls.DuplicateError{}) {
+		t.Errorf("Expected DuplicateError for clone, got %v", err)
+	}
+}
+
+func TestAddVoteAllowsDuplicatesByDefault(t *testing.T) {
+	one := gopolls.NewVoter("one", 1)
+	poll := gopolls.NewBasicPoll(nil)
+	for i := 0; i < 2; i++ {
+		if err := poll.AddVote(gopolls.NewBasicVote(one, gopolls.Aye)); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if len(poll.Votes) != 2 {
+		t.Errorf("Expected two votes, got %d", len(poll.Votes))
+	}
+}
+
+func TestFindDuplicateVoters(t *testing.T) {
+	one, two, three := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1), gopolls.NewVoter("three", 1)
+	poll := gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(two, 10), gopolls.NewMedianVote(one, 20), gopolls.NewMedianVote(three, 30),
+		gopolls.NewMedianVote(one, 40), gopolls.NewMedianVote(two, 50), gopolls.NewMedianVote(two, 60),
+		gopolls.NewMedianVote(nil, 70), gopolls.NewMedianVote(nil, 80),
+	})
+	expected := []string{"one", "two"}
+	duplicates, err := gopolls.FindDuplicateVoters(poll)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(duplicates, expected) {
+		t.Errorf("Expected duplicates %v, got %v", expected, duplicates)
+	}
+	duplicates, err = gopolls.FindDuplicateVoters(gopolls.NewSynchronizedPoll(poll))
+	if err != nil || !reflect.DeepEqual(duplicates, expected) {
+		t.Errorf("Expected duplicates %v for synchronized poll, got %v (error %v)", expected, duplicates, err)
+	}
+	duplicates, err = gopolls.FindDuplicateVoters(gopolls.NewBasicPoll(nil))
+	if err != nil || len(duplicates) != 0 {
+		t.Errorf("Expected no duplicates for empty poll, got %v (error %v)", duplicates, err)
+	}
+	_, err = gopolls.FindDuplicateVoters(nonIteratorPoll{})
+	if !errors.As(err, &gopolls.PollTypeError{}) {
+		t.Errorf("Expected PollTypeError for poll without VoteIterator, got %v", err)
+	}
+}
+
+// nonIteratorPoll is a poll that doesn't implement VoteIterator.
+type nonIteratorPoll struct{}
+
+func (poll nonIteratorPoll) PollType() string {
+	return "non-iterator"
+}
+
+func (poll nonIteratorPoll) AddVote(vote gopolls.AbstractVote) error {
+	return nil
+}
+
+func TestRejectDuplicateVotersAfterAnonymization(t *testing.T) {
+	alice, bob := gopolls.NewVoter("Alice", 1), gopolls.NewVoter("Bob", 1)
+	poll := gopolls.NewBasicPoll(nil)
+	poll.RejectDuplicateVoters = true
+	if err := poll.AddVote(gopolls.NewBasicVote(alice, gopolls.Aye)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	anonymizer := &gopolls.Anonymizer{Prefix: gopolls.DefaultPseudonymPrefix}
+	pseudonymous, mapping, err := anonymizer.Anonymize([]*gopolls.Voter{alice, bob})
+	if err != nil {
+		t.Fatalf("Unexpected error anonymizing voters: %s", err)
+	}
+	polls := gopolls.PollMap{"motion": poll}
+	if err := gopolls.ApplyAnonymization(polls, mapping); err != nil {
+		t.Fatalf("Unexpected error applying anonymization: %s", err)
+	}
+	var duplicateErr gopolls.DuplicateError
+	if err := poll.AddVote(gopolls.NewBasicVote(pseudonymous[0], gopolls.No)); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError for the pseudonym after anonymization, got %v", err)
+	}
+	if err := gopolls.RevertAnonymization(polls, mapping); err != nil {
+		t.Fatalf("Unexpected error reverting anonymization: %s", err)
+	}
+	if err := poll.AddVote(gopolls.NewBasicVote(alice, gopolls.No)); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError for the original voter after reverting, got %v", err)
+	}
+}
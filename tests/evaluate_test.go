@@ -0,0 +1,105 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"strings"
+	"testing"
+)
+
+const evaluateVoters = "* one: 1\n* two: 2\n"
+
+const evaluateCollection = "# Assembly\n\n## Group\n\n### Motion\n* yes\n* no\n\n### Budget\n- 100.00 €\n\n### Chair\n* A\n* B\n* C\n"
+
+func TestEvaluateFromReaders(t *testing.T) {
+	votes := "voter,Motion,Budget,Chair\none,aye,50.00 €,A > B > C\ntwo,no,30.00 €,\"0, 1, 2\"\n"
+	res, err := gopolls.EvaluateFromReaders(strings.NewReader(evaluateVoters), strings.NewReader(evaluateCollection),
+		strings.NewReader(votes), gopolls.EvaluateOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(res.Voters) != 2 || res.Collection.NumSkeletons() != 3 || len(res.Polls) != 3 {
+		t.Fatalf("Expected two voters and three polls, got %d voters and %d polls", len(res.Voters), len(res.Polls))
+	}
+	if len(res.Groups) != 1 || len(res.Groups[0].Polls) != 3 {
+		t.Fatalf("Expected one group with three polls, got %v", res.Groups)
+	}
+	motion := res.Results["Motion"].(*gopolls.BasicPollResult)
+	if motion.Outcome() != gopolls.Rejected {
+		t.Errorf("Expected motion to be rejected, got %s", motion.Outcome())
+	}
+	budget := res.Results["Budget"].(*gopolls.MedianResult)
+	if budget.MajorityValue != 3000 {
+		t.Errorf("Expected budget value 3000, got %d", budget.MajorityValue)
+	}
+	chair := res.Results["Chair"].(*gopolls.SchulzeResult)
+	if expected := (gopolls.SchulzeWinsList{{0}, {1}, {2}}); !chair.RankedGroups.Equals(expected) {
+		t.Errorf("Expected ranked groups %v, got %v", expected, chair.RankedGroups)
+	}
+	if res.FillReport == nil || res.FillReport.Polls["Chair"].Parsed != 2 {
+		t.Errorf("Expected two parsed votes for Chair in fill report, got %v", res.FillReport)
+	}
+}
+
+func TestEvaluateFromReadersErrors(t *testing.T) {
+	tests := []struct {
+		voters, votes string
+		mode          gopolls.CSVReadMode
+		stage         gopolls.EvaluationStage
+	}{
+		{"one: 1\n", "voter,Motion\n", gopolls.StrictCSVMode, gopolls.StageParsingVoters},
+		{"* one\n* one\n", "voter,Motion\n", gopolls.StrictCSVMode, gopolls.StageMappingVoters},
+		{evaluateVoters, "voter,Motion\none,aye,no\n", gopolls.StrictCSVMode, gopolls.StageReadingCSV},
+		{evaluateVoters, "voter,Motion\none,aye,no\n", gopolls.LenientCSVMode, gopolls.StageReadingCSV},
+		{evaluateVoters, "voter,Motion,Budget,Chair\nthree,aye,,\n", gopolls.StrictCSVMode, gopolls.StageValidatingMatrix},
+		{evaluateVoters, "voter,Motion,Budget,Chair\none,maybe,,\n", gopolls.StrictCSVMode, gopolls.StageFillingPolls},
+	}
+	for _, tc := range tests {
+		mode := tc.mode
+		opts := gopolls.EvaluateOptions{
+			AllowMissingVoters: true,
+			ConfigureCSVReader: func(reader *gopolls.VotesCSVReader) {
+				reader.Mode = mode
+			},
+		}
+		res, err := gopolls.EvaluateFromReaders(strings.NewReader(tc.voters), strings.NewReader(evaluateCollection),
+			strings.NewReader(tc.votes), opts)
+		var evalErr gopolls.EvaluationError
+		if !errors.As(err, &evalErr) {
+			t.Errorf("Expected EvaluationError for stage %s, got %v", tc.stage, err)
+			continue
+		}
+		if evalErr.Stage != tc.stage {
+			t.Errorf("Expected error in stage %s, got %s", tc.stage, err)
+		}
+		if !strings.HasPrefix(err.Error(), string(tc.stage)+": ") {
+			t.Errorf("Expected error message to start with stage %s, got %s", tc.stage, err)
+		}
+		if !errors.Is(err, gopolls.ErrPoll) {
+			t.Errorf("Expected error to wrap ErrPoll, got %v", err)
+		}
+		if res == nil || res.Results != nil {
+			t.Errorf("Expected partial result without results for stage %s", tc.stage)
+		}
+		if tc.mode == gopolls.LenientCSVMode && (res.CSVReport == nil || len(res.CSVReport.RowErrors) != 1) {
+			t.Errorf("Expected one malformed row in csv report, got %v", res.CSVReport)
+		}
+		if tc.stage == gopolls.StageValidatingMatrix && (res.MatrixReport == nil || res.MatrixReport.Empty()) {
+			t.Errorf("Expected issues in matrix report")
+		}
+	}
+}
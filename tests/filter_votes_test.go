@@ -0,0 +1,142 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// filterTestPolls returns polls of all supported types filled with random votes.
+func filterTestPolls(t *testing.T, voters []*gopolls.Voter, seed int64) gopolls.PollMap {
+	collection := gopolls.GenerateCollection(gopolls.CollectionSpec{
+		NumGroups: 1, NumBasicPolls: 2, NumMedianPolls: 2, NumSchulzePolls: 2, NumSchulzeOptions: 4,
+	})
+	skelMap, skelErr := collection.SkeletonsToMap()
+	if skelErr != nil {
+		t.Fatalf("Unexpected error collecting skeletons: %v", skelErr)
+	}
+	polls, convertErr := gopolls.ConvertSkeletonMapToEmptyPolls(skelMap, nil)
+	if convertErr != nil {
+		t.Fatalf("Unexpected error converting skeletons: %v", convertErr)
+	}
+	polls["Score poll"] = gopolls.NewScorePoll(3, 5, nil)
+	if fillErr := gopolls.FillRandomVotes(polls, voters, seed, 0.2); fillErr != nil {
+		t.Fatalf("Unexpected error filling polls: %v", fillErr)
+	}
+	return polls
+}
+
+// emptyCopy returns a new poll of the same type without votes.
+func emptyCopy(t *testing.T, poll gopolls.AbstractPoll) gopolls.AbstractPoll {
+	switch typedPoll := poll.(type) {
+	case *gopolls.BasicPoll:
+		return gopolls.NewBasicPoll(nil)
+	case *gopolls.MedianPoll:
+		return gopolls.NewMedianPoll(typedPoll.Value, nil)
+	case *gopolls.SchulzePoll:
+		return gopolls.NewSchulzePoll(typedPoll.NumOptions, nil)
+	case *gopolls.ScorePoll:
+		return gopolls.NewScorePoll(typedPoll.NumOptions, typedPoll.MaxScore, nil)
+	default:
+		t.Fatalf("Unexpected poll type %s", reflect.TypeOf(poll))
+		return nil
+	}
+}
+
+func tallyForFilterTest(poll gopolls.AbstractPoll) interface{} {
+	switch typedPoll := poll.(type) {
+	case *gopolls.BasicPoll:
+		return typedPoll.Tally()
+	case *gopolls.MedianPoll:
+		return typedPoll.Tally(typedPoll.WeightSum() / 2)
+	case *gopolls.SchulzePoll:
+		return typedPoll.Tally()
+	case *gopolls.ScorePoll:
+		return typedPoll.Tally()
+	default:
+		return nil
+	}
+}
+
+func TestFilterVotes(t *testing.T) {
+	voters := gopolls.GenerateVoters(30, func(i int) gopolls.Weight { return gopolls.Weight(i%3 + 1) })
+	for seed := int64(0); seed < 20; seed++ {
+		polls := filterTestPolls(t, voters, seed)
+		rnd := rand.New(rand.NewSource(seed))
+		present := make(map[*gopolls.Voter]bool, len(voters))
+		for _, voter := range voters {
+			present[voter] = rnd.Intn(2) == 0
+		}
+		keep := func(voter *gopolls.Voter) bool { return present[voter] }
+		for name, poll := range polls {
+			before, _ := gopolls.CollectVotes(poll)
+			filtered, filterErr := gopolls.FilterVotes(poll, keep)
+			if filterErr != nil {
+				t.Fatalf("Unexpected error filtering poll %s: %v", name, filterErr)
+			}
+			after, _ := gopolls.CollectVotes(poll)
+			if !reflect.DeepEqual(before, after) {
+				t.Fatalf("Filtering poll %s changed the original poll", name)
+			}
+			fresh := emptyCopy(t, poll)
+			for _, vote := range before {
+				if !keep(vote.GetVoter()) {
+					continue
+				}
+				if addErr := fresh.AddVote(vote); addErr != nil {
+					t.Fatalf("Unexpected error adding vote to poll %s: %v", name, addErr)
+				}
+			}
+			filteredVotes, _ := gopolls.CollectVotes(filtered)
+			freshVotes, _ := gopolls.CollectVotes(fresh)
+			if !reflect.DeepEqual(filteredVotes, freshVotes) {
+				t.Fatalf("Expected filtered votes of poll %s to be %v, got %v", name, freshVotes, filteredVotes)
+			}
+			if got, expected := tallyForFilterTest(filtered), tallyForFilterTest(fresh); !reflect.DeepEqual(got,
+				expected) {
+				t.Errorf("Expected result of filtered poll %s (seed %d) to be %v, got %v", name, seed, expected, got)
+			}
+		}
+	}
+}
+
+func TestFilterVotesSealedAndErrors(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye)})
+	poll.AddSealed(gopolls.NewSealedVote(one, "no"))
+	poll.AddSealed(gopolls.NewSealedVote(two, "aye"))
+	keepTwo := func(voter *gopolls.Voter) bool { return voter == two }
+	filtered, err := gopolls.FilterVotes(gopolls.NewSynchronizedPoll(poll), keepTwo)
+	if err != nil {
+		t.Fatalf("Unexpected error filtering synchronized poll: %v", err)
+	}
+	asBasic := filtered.(*gopolls.BasicPoll)
+	if len(asBasic.Votes) != 0 || asBasic.NumSealed() != 1 {
+		t.Errorf("Expected no votes and one sealed vote, got %d votes and %d sealed votes", len(asBasic.Votes),
+			asBasic.NumSealed())
+	}
+	if len(poll.Votes) != 1 || poll.NumSealed() != 2 {
+		t.Errorf("Filtering changed the original poll")
+	}
+
+	var typeErr gopolls.PollTypeError
+	if _, err := gopolls.FilterVotes(&experimentalPoll{}, keepTwo); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError for unsupported poll, got %v", err)
+	}
+}
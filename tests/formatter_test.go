@@ -0,0 +1,118 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomVote returns a random vote of the given vote type.
+func randomVote(rnd *rand.Rand, voteType string, voter *gopolls.Voter) gopolls.AbstractVote {
+	switch voteType {
+	case gopolls.BasicVoteType:
+		return gopolls.NewBasicVote(voter, gopolls.BasicPollAnswer(rnd.Intn(3)))
+	case gopolls.MedianVoteType:
+		return gopolls.NewMedianVote(voter, gopolls.MedianUnit(rnd.Int63n(1000000)))
+	case gopolls.SchulzeVoteType:
+		ranking := make(gopolls.SchulzeRanking, 1+rnd.Intn(6))
+		for i := range ranking {
+			ranking[i] = rnd.Intn(10) - 2
+		}
+		return gopolls.NewSchulzeVote(voter, ranking)
+	case gopolls.ScoreVoteType:
+		scores := make([]uint8, 1+rnd.Intn(6))
+		for i := range scores {
+			scores[i] = uint8(rnd.Intn(int(gopolls.DefaultMaxScore) + 1))
+		}
+		return gopolls.NewScoreVote(voter, scores)
+	default:
+		panic("unknown vote type " + voteType)
+	}
+}
+
+func TestVoteFormatterRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	voter := gopolls.NewVoter("voter", 1)
+	types := []struct {
+		voteType, pollType string
+	}{
+		{gopolls.BasicVoteType, gopolls.BasicPollType},
+		{gopolls.MedianVoteType, gopolls.MedianPollType},
+		{gopolls.SchulzeVoteType, gopolls.SchulzePollType},
+		{gopolls.ScoreVoteType, gopolls.ScorePollType},
+	}
+	for _, tc := range types {
+		voteType := tc.voteType
+		parser := gopolls.DefaultParserTemplateMap[tc.pollType]
+		for i := 0; i < 100; i++ {
+			vote := randomVote(rnd, voteType, voter)
+			s, formatErr := gopolls.FormatVote(vote, gopolls.DefaultVoteFormatterMap)
+			if formatErr != nil {
+				t.Fatalf("Can't format vote %v: %v", vote, formatErr)
+			}
+			parsed, parseErr := parser.ParseFromString(s, voter)
+			if parseErr != nil {
+				t.Fatalf("Can't parse formatted vote %q: %v", s, parseErr)
+			}
+			if !reflect.DeepEqual(vote, parsed) {
+				t.Fatalf("Round trip failed for %q: expected %v, got %v", s, vote, parsed)
+			}
+		}
+	}
+}
+
+func TestBasicVoteFormatterFromParser(t *testing.T) {
+	parser := gopolls.NewBasicVoteParser().WithAnswers([]string{"Nay", "x"}, []string{"Yea", "x"}, []string{"abstain"})
+	formatter := gopolls.NewBasicVoteFormatterFromParser(parser)
+	expected := &gopolls.BasicVoteFormatter{No: "nay", Aye: "yea", Abstention: "abstain"}
+	if !reflect.DeepEqual(formatter, expected) {
+		t.Fatalf("Expected formatter %v, got %v", expected, formatter)
+	}
+	for _, answer := range []gopolls.BasicPollAnswer{gopolls.No, gopolls.Aye, gopolls.Abstention} {
+		vote := gopolls.NewBasicVote(nil, answer)
+		s, err := formatter.FormatVote(vote)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		parsed, parseErr := parser.ParseFromString(s, nil)
+		if parseErr != nil || !parsed.(*gopolls.BasicVote).Equals(vote) {
+			t.Errorf("Round trip failed for %s: got %v, %v", answer, parsed, parseErr)
+		}
+	}
+}
+
+func TestVoteFormatterErrors(t *testing.T) {
+	var typeErr gopolls.PollTypeError
+	if _, err := gopolls.NewBasicVoteFormatter().FormatVote(gopolls.NewMedianVote(nil, 1)); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+	if _, err := gopolls.FormatVote(gopolls.NewBasicVote(nil, gopolls.Aye), map[string]gopolls.VoteFormatter{}); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError for missing formatter, got %v", err)
+	}
+	if s, err := gopolls.NewMedianVoteFormatter(nil).FormatVote(gopolls.NewMedianVote(nil, 1234)); err != nil || s != "1234" {
+		t.Errorf("Expected raw value \"1234\", got %q (%v)", s, err)
+	}
+	if s, err := gopolls.NewMedianVoteFormatter(gopolls.DefaultCurrencyHandler).FormatVote(gopolls.NewMedianAbstention(nil)); err != nil || s != "" {
+		t.Errorf("Expected empty string for abstention, got %q (%v)", s, err)
+	}
+	formatter := &gopolls.SchulzeVoteFormatter{Options: []string{"A", "B", "C"}}
+	if s, err := formatter.FormatVote(gopolls.NewSchulzeVote(nil, gopolls.SchulzeRanking{1, 0, 1})); err != nil || s != "B > A = C" {
+		t.Errorf("Expected \"B > A = C\", got %q (%v)", s, err)
+	}
+}
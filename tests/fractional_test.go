@@ -0,0 +1,171 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"math/big"
+	"testing"
+)
+
+func fractionalVoter(t *testing.T, name, weight string) *gopolls.Voter {
+	parser := gopolls.NewVotersParser()
+	parser.FractionalWeights = true
+	voter, err := parser.ParseVotersLine("* " + name + ": " + weight)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing voter with weight %s: %v", weight, err)
+	}
+	return voter
+}
+
+func TestParseFractionalWeights(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	// without FractionalWeights a weight in decimal notation is a syntax error
+	var syntaxErr gopolls.PollingSyntaxError
+	if voter, err := parser.ParseVotersLine("* Alice: 2.5"); !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected a syntax error if FractionalWeights is disabled, got %v (error %v)", voter, err)
+	}
+	parser.FractionalWeights = true
+	alice, err := parser.ParseVotersLine("* Alice: 2.50")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if alice.Weight != 2 || alice.FractionalWeight == nil || alice.FractionalWeight.Cmp(big.NewRat(5, 2)) != 0 {
+		t.Errorf("Expected weight 2 and fractional weight 5/2, got %d and %v", alice.Weight, alice.FractionalWeight)
+	}
+	if formatted := alice.Format(""); formatted != "* Alice: 2.5" {
+		t.Errorf("Expected \"* Alice: 2.5\", got \"%s\"", formatted)
+	}
+	bob, err := parser.ParseVotersLine("* Bob: 3.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bob.Weight != 3 || bob.FractionalWeight != nil {
+		t.Errorf("Expected integer weight 3, got %d and %v", bob.Weight, bob.FractionalWeight)
+	}
+	for _, invalid := range []string{"2.", ".5", "5/2", "1e3", "-1", "4294967295.5"} {
+		if _, err := gopolls.ParseRatWeight(invalid); err == nil {
+			t.Errorf("Expected an error parsing weight \"%s\"", invalid)
+		}
+	}
+	parser.MaxVotersWeight = 2
+	if _, err := parser.ParseVotersLine("* Alice: 2.5"); err == nil {
+		t.Error("Expected an error for a fractional weight > MaxVotersWeight")
+	}
+}
+
+func TestFormatRatWeight(t *testing.T) {
+	tests := []struct {
+		in       *big.Rat
+		expected string
+	}{
+		{big.NewRat(5, 2), "2.5"},
+		{big.NewRat(3, 1), "3"},
+		{big.NewRat(1, 8), "0.125"},
+		{big.NewRat(1, 3), "0.3333333333"},
+	}
+	for _, tc := range tests {
+		if got := gopolls.FormatRatWeight(tc.in); got != tc.expected {
+			t.Errorf("Expected %s for %s, got %s", tc.expected, tc.in.RatString(), got)
+		}
+	}
+}
+
+func TestBasicPollTallyRat(t *testing.T) {
+	alice := fractionalVoter(t, "alice", "2.5")
+	bob := fractionalVoter(t, "bob", "1.5")
+	carol := gopolls.NewVoter("carol", 1)
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(alice, gopolls.Aye),
+		gopolls.NewBasicVote(bob, gopolls.No),
+		gopolls.NewBasicVote(carol, gopolls.Aye),
+	})
+	res := poll.TallyRat()
+	if res.WeightedVotes.NumAyes.Cmp(big.NewRat(7, 2)) != 0 || res.WeightedVotes.NumNoes.Cmp(big.NewRat(3, 2)) != 0 {
+		t.Errorf("Expected 7/2 ayes and 3/2 noes, got %s and %s", res.WeightedVotes.NumAyes.RatString(),
+			res.WeightedVotes.NumNoes.RatString())
+	}
+	if res.VotesSum.Cmp(big.NewRat(5, 1)) != 0 || res.VotersCount != 3 || res.NumberVoters.NumAyes != 2 {
+		t.Errorf("Unexpected sums: %s, %d, %d", res.VotesSum.RatString(), res.VotersCount, res.NumberVoters.NumAyes)
+	}
+	if percent := res.WeightedVotes.PercentAyes(false); percent.Cmp(big.NewRat(7, 10)) != 0 {
+		t.Errorf("Expected 7/10 ayes, got %s", percent.RatString())
+	}
+	if !res.PassesMajority(gopolls.TwoThirdsMajority, true) {
+		t.Error("Expected 7/10 ayes to pass a two thirds majority")
+	}
+
+	// without fractional weights the result must be the same as the integer tally
+	integerPoll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(gopolls.NewVoter("one", 2), gopolls.Aye),
+		gopolls.NewBasicVote(gopolls.NewVoter("two", 3), gopolls.Abstention),
+	})
+	if expected, got := integerPoll.Tally().RatResult(), integerPoll.TallyRat(); !expected.Equals(got) {
+		t.Errorf("Expected TallyRat to be the same as Tally, got %v and %v", got, expected)
+	}
+}
+
+func TestMedianPollTallyRat(t *testing.T) {
+	poll := gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(fractionalVoter(t, "alice", "1.5"), 100),
+		gopolls.NewMedianVote(fractionalVoter(t, "bob", "1.5"), 50),
+		gopolls.NewMedianVote(gopolls.NewVoter("carol", 2), 0),
+	})
+	res := poll.TallyRat(nil)
+	if res.WeightSum.Cmp(big.NewRat(5, 1)) != 0 || res.RequiredMajority.Cmp(big.NewRat(5, 2)) != 0 {
+		t.Errorf("Expected weight sum 5 and majority 5/2, got %s and %s", res.WeightSum.RatString(),
+			res.RequiredMajority.RatString())
+	}
+	if res.MajorityValue != 50 {
+		t.Errorf("Expected majority value 50, got %d", res.MajorityValue)
+	}
+	if res = poll.TallyRat(big.NewRat(1, 5)); res.MajorityValue != 100 {
+		t.Errorf("Expected majority value 100 for majority 1/5, got %d", res.MajorityValue)
+	}
+}
+
+func TestSchulzePollTallyRat(t *testing.T) {
+	// with integer weights alice and bob would be tied
+	poll := gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(fractionalVoter(t, "alice", "1.5"), gopolls.SchulzeRanking{0, 1}),
+		gopolls.NewSchulzeVote(fractionalVoter(t, "bob", "1.25"), gopolls.SchulzeRanking{1, 0}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("carol", 1), gopolls.SchulzeRanking{0}),
+	})
+	res := poll.TallyRat()
+	expectedGroups := gopolls.SchulzeWinsList{{0}, {1}}
+	if !res.RankedGroups.Equals(expectedGroups) {
+		t.Errorf("Expected ranked groups %v, got %v", expectedGroups, res.RankedGroups)
+	}
+	if res.D[0][1].Cmp(big.NewRat(3, 2)) != 0 || res.P[1][0].Sign() != 0 {
+		t.Errorf("Unexpected matrices d = %v and p = %v", res.D, res.P)
+	}
+	if res.InvalidVotesCount != 1 || res.InvalidWeight.Cmp(big.NewRat(1, 1)) != 0 ||
+		res.WeightSum.Cmp(big.NewRat(15, 4)) != 0 {
+		t.Errorf("Unexpected weights: %d, %s, %s", res.InvalidVotesCount, res.InvalidWeight.RatString(),
+			res.WeightSum.RatString())
+	}
+
+	// without fractional weights the result must be the same as the integer tally
+	integerPoll := gopolls.NewSchulzePoll(4, getSchulzeVotesTesting(3, []gopolls.Weight{3, 2, 2}, 4))
+	integerPoll.Votes[0].Ranking = gopolls.SchulzeRanking{0, 1, 2, 3}
+	integerPoll.Votes[1].Ranking = gopolls.SchulzeRanking{3, 2, 1, 0}
+	integerPoll.Votes[2].Ranking = gopolls.SchulzeRanking{1, 0, 1, 2}
+	expected, got := integerPoll.Tally(), integerPoll.TallyRat()
+	if !got.D.Equals(expected.D.RatMatrix()) || !got.P.Equals(expected.P.RatMatrix()) ||
+		!got.RankedGroups.Equals(expected.RankedGroups) {
+		t.Errorf("Expected TallyRat to be the same as Tally, got %v and %v", got, expected)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"strings"
+	"testing"
+)
+
+const groupEvaluationCollection = `# Assembly
+## Finances
+### Budget
+- 100.00 €
+### Audit
+* yes
+* no
+## Board
+### Chair
+* Alice
+* Bob
+* Carol
+### Treasurer
+* yes
+* no
+`
+
+func groupEvaluationSetup(t *testing.T) (*gopolls.PollSkeletonCollection, gopolls.PollMap, gopolls.VoterMap) {
+	coll, err := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{},
+		groupEvaluationCollection)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	skelMap, skelErr := coll.SkeletonsToMap()
+	if skelErr != nil {
+		t.Fatalf("Unexpected error creating skeleton map: %v", skelErr)
+	}
+	polls, convertErr := gopolls.ConvertSkeletonMapToEmptyPolls(skelMap, nil)
+	if convertErr != nil {
+		t.Fatalf("Unexpected error converting skeletons: %v", convertErr)
+	}
+	voters := gopolls.VoterMap{"one": gopolls.NewVoter("one", 1), "two": gopolls.NewVoter("two", 2)}
+	return coll, polls, voters
+}
+
+func TestGroupByTitle(t *testing.T) {
+	coll, _, _ := groupEvaluationSetup(t)
+	group, err := coll.GroupByTitle("Board")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if group != coll.Groups[1] {
+		t.Errorf("Expected the second group, got %v", group)
+	}
+	var semanticErr gopolls.PollingSemanticError
+	if _, err = coll.GroupByTitle("board"); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for unknown group, got %v", err)
+	}
+	coll.Groups = append(coll.Groups, gopolls.NewPollGroup("Board"))
+	var duplicateErr gopolls.DuplicateError
+	if _, err = coll.GroupByTitle("Board"); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError for duplicate group titles, got %v", err)
+	}
+}
+
+func TestFillAndTallyGroup(t *testing.T) {
+	coll, polls, voters := groupEvaluationSetup(t)
+	board, err := coll.GroupByTitle("Board")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// the budget column is invalid and there is a column for an unknown poll, both must be ignored
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "Budget", "Treasurer", "Unknown", "Chair"},
+		Body: [][]string{
+			{"one", "not a value", "yes", "?", "Bob > Alice > Carol"},
+			{"two", "", "no", "?", "Carol > Bob > Alice"},
+		},
+	}
+	parsers, customizeErr := gopolls.CustomizeParsersToMap(polls, gopolls.GenerateDefaultParserTemplateMap())
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error creating parsers: %v", customizeErr)
+	}
+	voteParsers := map[string]gopolls.VoteParser{
+		"Treasurer": parsers["Treasurer"],
+		"Chair":     parsers["Chair"].(*gopolls.SchulzeVoteParser).WithOptions([]string{"Alice", "Bob", "Carol"}),
+	}
+	policies := gopolls.PolicyMap{"Treasurer": gopolls.RaiseErrorEmptyVote, "Chair": gopolls.RaiseErrorEmptyVote}
+	_, actualPolls, report, fillErr := matrix.FillPollsWithVotesWithReport(polls, voters, voteParsers, policies,
+		false, false, gopolls.WithGroupColumns(board), gopolls.WithRawVoteSources())
+	if fillErr != nil {
+		t.Fatalf("Unexpected error filling group: %v", fillErr)
+	}
+	if len(actualPolls) != 2 || actualPolls["Treasurer"] == nil || actualPolls["Chair"] == nil {
+		t.Errorf("Expected only the polls of the group, got %v", actualPolls.SortedNames())
+	}
+	if len(report.Polls) != 2 || report.CastVotes["one"] != 2 {
+		t.Errorf("Expected report only for the polls of the group, got %v", report)
+	}
+	chairVotes := polls["Chair"].(*gopolls.SchulzePoll).Votes
+	if len(chairVotes) != 2 || chairVotes[0].GetSource().Column != 4 {
+		t.Errorf("Expected two votes with the columns of the whole matrix, got %v", chairVotes)
+	}
+	if len(polls["Budget"].(*gopolls.MedianPoll).Votes) != 0 {
+		t.Errorf("Expected polls of other groups not to be filled")
+	}
+
+	tallied, tallyErr := gopolls.TallyGroup(board, polls)
+	if tallyErr != nil {
+		t.Fatalf("Unexpected error tallying group: %v", tallyErr)
+	}
+	if tallied.Title != "Board" || len(tallied.Polls) != 2 {
+		t.Fatalf("Expected two tallied polls in group Board, got %v", tallied)
+	}
+	if tallied.Polls[0].Skeleton.GetName() != "Chair" || tallied.Polls[1].Skeleton.GetName() != "Treasurer" {
+		t.Errorf("Expected the tallied polls in the order of the skeletons")
+	}
+	basicResult := tallied.Polls[1].Result.(*gopolls.BasicPollResult)
+	if basicResult.WeightedVotes.NumAyes != 1 || basicResult.WeightedVotes.NumNoes != 2 {
+		t.Errorf("Expected 1 aye and 2 noes, got %v", basicResult.WeightedVotes)
+	}
+	schulzeResult := tallied.Polls[0].Result.(*gopolls.SchulzeResult)
+	if len(schulzeResult.RankedGroups) == 0 || schulzeResult.RankedGroups[0][0] != 2 {
+		t.Errorf("Expected Carol to win, got %v", schulzeResult.RankedGroups)
+	}
+
+	// a missing poll is reported with the group
+	delete(polls, "Chair")
+	_, tallyErr = gopolls.TallyGroup(board, polls)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(tallyErr, &semanticErr) || !strings.Contains(tallyErr.Error(), "\"Chair\" in group \"Board\"") {
+		t.Errorf("Expected PollingSemanticError for missing poll, got %v", tallyErr)
+	}
+}
+
+func TestFillGroupMissingPolls(t *testing.T) {
+	coll, polls, voters := groupEvaluationSetup(t)
+	finances, err := coll.GroupByTitle("Finances")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "Audit", "Chair"},
+		Body: [][]string{{"one", "yes", "0, 1, 2"}, {"two", "no", ""}},
+	}
+	parsers := map[string]gopolls.VoteParser{"Audit": gopolls.NewBasicVoteParser()}
+	policies := gopolls.PolicyMap{"Audit": gopolls.RaiseErrorEmptyVote}
+	var semanticErr gopolls.PollingSemanticError
+	_, _, err = matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false,
+		gopolls.WithGroupColumns(finances))
+	if !errors.As(err, &semanticErr) || !strings.Contains(err.Error(), "Budget") {
+		t.Errorf("Expected PollingSemanticError for missing poll Budget, got %v", err)
+	}
+	_, actualPolls, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, true,
+		gopolls.WithGroupColumns(finances))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actualPolls) != 1 || len(polls["Audit"].(*gopolls.BasicPoll).Votes) != 2 {
+		t.Errorf("Expected only poll Audit to be filled, got %v", actualPolls.SortedNames())
+	}
+
+	// rows are still validated
+	matrix.Body[1] = matrix.Body[1][:2]
+	var syntaxErr gopolls.PollingSyntaxError
+	if _, _, err = matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, true,
+		gopolls.WithGroupColumns(finances)); !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected PollingSyntaxError for a row with an invalid length, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/FabianWe/gopolls"
+	"github.com/FabianWe/gopolls/httpapi"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveAPI sends a request with the given body to api and returns the recorded response.
+func serveAPI(api *httpapi.API, method, path, contentType string, body *bytes.Buffer) *httptest.ResponseRecorder {
+	if body == nil {
+		body = &bytes.Buffer{}
+	}
+	req := httptest.NewRequest(method, path, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	return rec
+}
+
+// multipartBody returns a multipart form with content in the field httpapi.FileField.
+func multipartBody(t *testing.T, content string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(httpapi.FileField, "upload.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err = part.Write([]byte(content)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return body, writer.FormDataContentType()
+}
+
+func decodeErrorResponse(t *testing.T, rec *httptest.ResponseRecorder) *httpapi.ErrorResponse {
+	var res httpapi.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Can't decode error response %s: %s", rec.Body.String(), err)
+	}
+	return &res
+}
+
+func TestHTTPAPI(t *testing.T) {
+	api := httpapi.NewAPI(httpapi.NewMemoryStore())
+
+	rec := serveAPI(api, http.MethodPost, "/voters", "text/plain", bytes.NewBufferString(evaluateVoters))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for voters, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var votersRes httpapi.VotersResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &votersRes); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(votersRes.Voters) != 2 || votersRes.Voters[1].Name != "two" || votersRes.Voters[1].Weight != 2 {
+		t.Errorf("Expected voters one and two, got %v", votersRes.Voters)
+	}
+
+	body, contentType := multipartBody(t, evaluateCollection)
+	rec = serveAPI(api, http.MethodPost, "/polls", contentType, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for polls, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var collectionRes httpapi.CollectionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &collectionRes); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if collectionRes.Title != "Assembly" || len(collectionRes.Groups) != 1 || len(collectionRes.Groups[0].Polls) != 3 {
+		t.Fatalf("Expected one group with three polls, got %v", collectionRes)
+	}
+	budget := collectionRes.Groups[0].Polls[1]
+	if budget.Type != gopolls.MoneyPollSkeletonType || budget.Value == nil || budget.Value.Cents != 10000 {
+		t.Errorf("Expected money poll Budget with value 10000, got %v", budget)
+	}
+	if chair := collectionRes.Groups[0].Polls[2]; len(chair.Options) != 3 {
+		t.Errorf("Expected three options for Chair, got %v", chair.Options)
+	}
+
+	rec = serveAPI(api, http.MethodGet, "/csv-template", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for csv template, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if expected := "voter,Motion,Budget,Chair\none,,,\ntwo,,,\n"; rec.Body.String() != expected {
+		t.Errorf("Expected template %q, got %q", expected, rec.Body.String())
+	}
+
+	votes := "voter,Motion,Budget,Chair\none,aye,50.00 €,A > B > C\ntwo,no,30.00 €,\"0, 1, 2\"\n"
+	rec = serveAPI(api, http.MethodPost, "/votes", "text/csv", bytes.NewBufferString(votes))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for votes, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var evaluationRes struct {
+		Title  string
+		Groups []struct {
+			Polls []struct {
+				Name       string
+				ResultType string `json:"result_type"`
+				Result     map[string]interface{}
+			}
+		}
+		Turnout struct {
+			NumVotedAll int `json:"num_voted_all"`
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &evaluationRes); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(evaluationRes.Groups) != 1 || len(evaluationRes.Groups[0].Polls) != 3 {
+		t.Fatalf("Expected one group with three polls, got %s", rec.Body.String())
+	}
+	motion := evaluationRes.Groups[0].Polls[0]
+	if motion.Name != "Motion" || motion.ResultType != gopolls.BasicResultType {
+		t.Errorf("Expected basic result for Motion, got %v", motion)
+	}
+	if _, has := motion.Result["weighted_votes"]; !has {
+		t.Errorf("Expected result to be encoded with the result marshalling, got %v", motion.Result)
+	}
+	if budgetRes := evaluationRes.Groups[0].Polls[1]; budgetRes.Result["majority_value"] != float64(3000) {
+		t.Errorf("Expected budget value 3000, got %v", budgetRes.Result)
+	}
+	if evaluationRes.Turnout.NumVotedAll != 2 {
+		t.Errorf("Expected both voters to vote in all polls, got %s", rec.Body.String())
+	}
+}
+
+func TestHTTPAPIErrors(t *testing.T) {
+	api := httpapi.NewAPI(httpapi.NewMemoryStore())
+	api.EvaluateOptions.ConfigureCSVReader = func(reader *gopolls.VotesCSVReader) {
+		reader.Mode = gopolls.LenientCSVMode
+	}
+
+	rec := serveAPI(api, http.MethodGet, "/voters", "", nil)
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("Expected status 405 with Allow POST, got %d", rec.Code)
+	}
+
+	rec = serveAPI(api, http.MethodPost, "/votes", "text/csv", bytes.NewBufferString("voter,Motion\n"))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for votes without voters, got %d", rec.Code)
+	}
+	if res := decodeErrorResponse(t, rec); res.Kind != httpapi.NotStoredErrorKind {
+		t.Errorf("Expected error kind %s, got %s", httpapi.NotStoredErrorKind, res.Kind)
+	}
+
+	rec = serveAPI(api, http.MethodPost, "/voters", "text/plain", bytes.NewBufferString("* one: 1\ntwo: 2\n"))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422 for invalid voters, got %d", rec.Code)
+	}
+	res := decodeErrorResponse(t, rec)
+	if res.Kind != httpapi.SyntaxErrorKind || res.Line == nil || *res.Line != 2 {
+		t.Errorf("Expected syntax error in line 2, got %v", res)
+	}
+
+	rec = serveAPI(api, http.MethodPost, "/voters", "text/plain", bytes.NewBufferString("* one: 1\n* one: 2\n"))
+	if res = decodeErrorResponse(t, rec); rec.Code != http.StatusUnprocessableEntity ||
+		res.Kind != httpapi.DuplicateErrorKind {
+		t.Errorf("Expected duplicate error with status 422, got %d: %v", rec.Code, res)
+	}
+
+	body, contentType := multipartBody(t, evaluateVoters)
+	rec = serveAPI(api, http.MethodPost, "/voters", strings.Replace(contentType, "boundary=", "boundary=x", 1), body)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for malformed multipart form, got %d", rec.Code)
+	}
+
+	rec = serveAPI(api, http.MethodPost, "/voters", "text/plain", bytes.NewBufferString(evaluateVoters))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for voters, got %d", rec.Code)
+	}
+	rec = serveAPI(api, http.MethodPost, "/polls", "text/plain", bytes.NewBufferString(evaluateCollection))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for polls, got %d", rec.Code)
+	}
+
+	rec = serveAPI(api, http.MethodPost, "/votes", "text/csv",
+		bytes.NewBufferString("voter,Motion,Budget,Chair\none,aye,,\ntwo,no\n"))
+	res = decodeErrorResponse(t, rec)
+	if rec.Code != http.StatusUnprocessableEntity || res.Stage != string(gopolls.StageReadingCSV) {
+		t.Errorf("Expected error while reading csv with status 422, got %d: %v", rec.Code, res)
+	}
+	if len(res.CSVRowErrors) != 1 || res.CSVRowErrors[0].NumColumns != 2 {
+		t.Errorf("Expected one malformed row with two columns, got %v", res.CSVRowErrors)
+	}
+
+	rec = serveAPI(api, http.MethodPost, "/votes", "text/csv",
+		bytes.NewBufferString("voter,Motion,Budget,Chair\nthree,aye,,\n"))
+	res = decodeErrorResponse(t, rec)
+	if rec.Code != http.StatusUnprocessableEntity || res.Stage != string(gopolls.StageValidatingMatrix) ||
+		len(res.MatrixIssues) == 0 {
+		t.Errorf("Expected matrix issues with status 422, got %d: %v", rec.Code, res)
+	}
+}
+
+func TestHTTPAPIMoneyPollRange(t *testing.T) {
+	api := httpapi.NewAPI(httpapi.NewMemoryStore())
+	body, contentType := multipartBody(t, medianRangeCollection)
+	rec := serveAPI(api, http.MethodPost, "/polls", contentType, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for polls, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var collectionRes httpapi.CollectionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &collectionRes); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	hall, garden := collectionRes.Groups[0].Polls[0], collectionRes.Groups[0].Polls[1]
+	if hall.MinValue == nil || hall.MinValue.Cents != 50000 || hall.Step == nil || hall.Step.Cents != 5000 {
+		t.Errorf("Expected min value 50000 and step 5000 for Hall, got %v and %v", hall.MinValue, hall.Step)
+	}
+	if garden.MinValue != nil || garden.Step == nil || garden.Step.Cents != 1000 {
+		t.Errorf("Expected no min value and step 1000 for Garden, got %v and %v", garden.MinValue, garden.Step)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"github.com/FabianWe/gopolls"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// assertGoldenJSON encodes value (indented) and compares the result with the content of testdata/name, see
+// assertGolden.
+func assertGoldenJSON(t *testing.T, name string, value interface{}) {
+	got, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatalf("Unexpected error encoding %s: %v", name, err)
+	}
+	assertGolden(t, name, got)
+}
+
+// assertGolden compares got with the content of testdata/name.
+// If the flag -update is set the golden file is written instead.
+func assertGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if writeErr := ioutil.WriteFile(path, got, 0644); writeErr != nil {
+			t.Fatalf("Can't write golden file %s: %v", path, writeErr)
+		}
+		return
+	}
+	expected, readErr := ioutil.ReadFile(path)
+	if readErr != nil {
+		t.Fatalf("Can't read golden file %s: %v", path, readErr)
+	}
+	if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(got)) {
+		t.Errorf("Output for %s does not match golden file, expected\n%s\ngot\n%s", name, expected, got)
+	}
+}
+
+func TestBasicPollResultJSON(t *testing.T) {
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(gopolls.NewVoter("one", 1), gopolls.Aye),
+		gopolls.NewBasicVote(gopolls.NewVoter("two", 2), gopolls.No),
+		gopolls.NewBasicVote(gopolls.NewVoter("three", 3), gopolls.Abstention),
+	})
+	assertGoldenJSON(t, "basic_result.json", poll.Tally())
+}
+
+func TestMedianResultJSON(t *testing.T) {
+	poll := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(gopolls.NewVoter("one", 4), 200),
+		gopolls.NewMedianVote(gopolls.NewVoter("two", 3), 1000),
+		gopolls.NewMedianVote(gopolls.NewVoter("three", 2), 700),
+		gopolls.NewMedianVote(gopolls.NewVoter("four", 2), 700),
+	})
+	assertGoldenJSON(t, "median_result.json", poll.Tally(gopolls.NoWeight))
+
+	abstentions := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(gopolls.NewVoter("one", 4), 200),
+		gopolls.NewMedianAbstention(gopolls.NewVoter("two", 3)),
+	})
+	assertGoldenJSON(t, "median_result_abstention.json", abstentions.Tally(gopolls.NoWeight))
+
+	empty := gopolls.NewMedianPoll(1000, nil)
+	assertGoldenJSON(t, "median_result_empty.json", empty.Tally(gopolls.NoWeight))
+}
+
+func TestSchulzeResultJSON(t *testing.T) {
+	votes := getSchulzeVotesTesting(4, []gopolls.Weight{3, 2, 2, 2}, 4)
+	votes[0].Ranking = gopolls.SchulzeRanking{1, 2, 3, 4}
+	votes[1].Ranking = gopolls.SchulzeRanking{2, 3, 4, 1}
+	votes[2].Ranking = gopolls.SchulzeRanking{4, 2, 3, 1}
+	votes[3].Ranking = gopolls.SchulzeRanking{4, 2, 1, 3}
+	poll := gopolls.NewSchulzePoll(4, votes)
+	assertGoldenJSON(t, "schulze_result.json", poll.Tally())
+}
+
+func TestResultJSONWeightOverflow(t *testing.T) {
+	big1, big2 := gopolls.NewVoter("one", gopolls.NoWeight-1), gopolls.NewVoter("two", 1)
+	results := []interface{}{
+		gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(big1, gopolls.Aye),
+			gopolls.NewBasicVote(big2, gopolls.Aye),
+		}).Tally(),
+		gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(big1, 100),
+			gopolls.NewMedianVote(big2, 100),
+		}).Tally(gopolls.NoWeight),
+		gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(big1, gopolls.SchulzeRanking{0, 1}),
+			gopolls.NewSchulzeVote(big2, gopolls.SchulzeRanking{0, 1}),
+		}).Tally(),
+	}
+	for _, res := range results {
+		if encoded, err := json.Marshal(res); !errors.Is(err, gopolls.ErrWeightOverflow) {
+			t.Errorf("Expected ErrWeightOverflow encoding %T, got %s and error %v", res, encoded, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestLintVoters(t *testing.T) {
+	voters := []*gopolls.Voter{gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 0)}
+	warnings := gopolls.LintVoters(voters)
+	if len(warnings) != 1 {
+		t.Fatalf("Expected one warning, got %v", warnings)
+	}
+	warning := warnings[0]
+	if warning.Code != gopolls.LintZeroWeightVoter || warning.VoterIndex != 1 || warning.GroupIndex != -1 {
+		t.Errorf("Unexpected warning %+v", warning)
+	}
+	if expected := "voter \"two\" (number 2) has weight 0"; warning.Msg != expected {
+		t.Errorf("Expected message \"%s\", got \"%s\"", expected, warning.Msg)
+	}
+}
+
+func TestLintCollection(t *testing.T) {
+	collection := gopolls.NewPollSkeletonCollection("Assembly")
+	group := gopolls.NewPollGroup("Budget")
+	poll := gopolls.NewPollSkeleton("Budget")
+	poll.Options = []string{"yes", "no", "yes"}
+	group.Skeletons = append(group.Skeletons, gopolls.NewMoneyPollSkeleton("Money", gopolls.NewCurrencyValue(0, "€")),
+		poll)
+	collection.Groups = append(collection.Groups, group, gopolls.NewPollGroup("Assembly"))
+	// a collection without any problems
+	valid := gopolls.NewPollSkeletonCollection("Assembly")
+	validGroup := gopolls.NewPollGroup("Group")
+	validGroup.Skeletons = append(validGroup.Skeletons, gopolls.NewMoneyPollSkeleton("Money", gopolls.NewCurrencyValue(100, "€")))
+	valid.Groups = append(valid.Groups, validGroup)
+	if warnings := gopolls.LintCollection(valid); len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+
+	expected := []gopolls.LintWarning{
+		{Code: gopolls.LintZeroMoneyValue, Msg: "money poll \"Money\" has value 0", VoterIndex: -1, GroupIndex: 0, PollIndex: 0},
+		{Code: gopolls.LintPollNameEqualsGroupTitle, Msg: "poll \"Budget\" (number 2 in group 1) has the same name as its group",
+			VoterIndex: -1, GroupIndex: 0, PollIndex: 1},
+		{Code: gopolls.LintDuplicateOption, Msg: "options 1 and 3 of poll \"Budget\" are both \"yes\"",
+			VoterIndex: -1, GroupIndex: 0, PollIndex: 1},
+		{Code: gopolls.LintGroupTitleEqualsCollectionTitle, Msg: "group \"Assembly\" (number 2) has the same title as the collection",
+			VoterIndex: -1, GroupIndex: 1, PollIndex: -1},
+		{Code: gopolls.LintEmptyGroup, Msg: "group \"Assembly\" (number 2) contains no polls",
+			VoterIndex: -1, GroupIndex: 1, PollIndex: -1},
+	}
+	warnings := gopolls.LintCollection(collection)
+	if len(warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, warning := range warnings {
+		if warning != expected[i] {
+			t.Errorf("Expected warning %+v, got %+v", expected[i], warning)
+		}
+	}
+}
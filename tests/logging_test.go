@@ -0,0 +1,143 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"github.com/FabianWe/gopolls"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sortEvents(events []gopolls.PollEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].String() < events[j].String()
+	})
+}
+
+func TestFillEventHandler(t *testing.T) {
+	voters := gopolls.VoterMap{
+		"one":  gopolls.NewVoter("one", 1),
+		"two":  gopolls.NewVoter("two", 1),
+		"zero": gopolls.NewVoter("zero", 0),
+	}
+	polls := gopolls.PollMap{"a": gopolls.NewBasicPoll(nil), "b": gopolls.NewBasicPoll(nil)}
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "a", "b"},
+		Body: [][]string{{"one", "aye", ""}, {"two", "", "no"}, {"zero", "aye", "aye"}},
+	}
+	parsers := map[string]gopolls.VoteParser{"a": gopolls.NewBasicVoteParser(), "b": gopolls.NewBasicVoteParser()}
+	policies := gopolls.PolicyMap{"a": gopolls.IgnoreEmptyVote, "b": gopolls.AddAsNoEmptyVote}
+	var events []gopolls.PollEvent
+	_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false,
+		gopolls.WithZeroWeightPolicy(gopolls.ExcludeZeroWeightFromCounts),
+		gopolls.WithFillEventHandler(func(event gopolls.PollEvent) {
+			events = append(events, event)
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error filling polls: %v", err)
+	}
+	sortEvents(events)
+	expected := []gopolls.PollEvent{
+		{Poll: "a", Voter: "two", Action: gopolls.EmptyVoteIgnoredAction, Reason: "empty vote policy ignore"},
+		{Poll: "a", Voter: "zero", Action: gopolls.ZeroWeightSkippedAction, Reason: "voter has weight 0"},
+		{Poll: "b", Voter: "one", Action: gopolls.EmptyVoteGeneratedAction, Reason: "empty vote policy no"},
+		{Poll: "b", Voter: "zero", Action: gopolls.ZeroWeightSkippedAction, Reason: "voter has weight 0"},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+}
+
+func TestTallyEventHandler(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	skel := gopolls.NewPollSkeleton("schulze")
+	skel.AddOption("a", "")
+	skel.AddOption("b", "")
+	skel.AddOption("c", "")
+	group := gopolls.NewPollGroup("group")
+	group.Skeletons = append(group.Skeletons, skel)
+	coll := gopolls.NewPollSkeletonCollection("collection")
+	coll.Groups = append(coll.Groups, group)
+	poll := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{0, 1}),
+	})
+	var events []gopolls.PollEvent
+	_, err := gopolls.TallyCollection(coll, gopolls.PollMap{"schulze": poll},
+		gopolls.WithTallyEventHandler(func(event gopolls.PollEvent) {
+			events = append(events, event)
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error tallying collection: %v", err)
+	}
+	if len(events) != 1 || events[0].Poll != "schulze" || events[0].Voter != "two" ||
+		events[0].Action != gopolls.InvalidVoteAction || events[0].Reason == "" {
+		t.Errorf("Expected one invalid vote event for voter two, got %v", events)
+	}
+}
+
+func TestTruncatePollVoters(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	median := gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 200), gopolls.NewMedianVote(two, 50),
+	})
+	var buf bytes.Buffer
+	culprits, err := gopolls.TruncatePollVoters("median", median, gopolls.LogPollEvents(log.New(&buf, "", 0)))
+	if err != nil {
+		t.Fatalf("Unexpected error truncating voters: %v", err)
+	}
+	if len(culprits) != 1 || median.Votes[0].Value != 100 {
+		t.Errorf("Expected one truncated vote, got %v", culprits)
+	}
+	expectedLog := "poll=\"median\" voter=\"one\" action=vote-truncated reason=\"value 200 is greater than 100\"\n"
+	if buf.String() != expectedLog {
+		t.Errorf("Expected log\n%s\ngot\n%s", expectedLog, buf.String())
+	}
+
+	schulze := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{0}),
+	})
+	var events []gopolls.PollEvent
+	culprits, err = gopolls.TruncatePollVoters("schulze", schulze, func(event gopolls.PollEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error truncating voters: %v", err)
+	}
+	if len(culprits) != 1 || len(schulze.Votes) != 1 || len(events) != 1 ||
+		events[0].Action != gopolls.VoteRemovedAction || !strings.Contains(events[0].Reason, "length 1") {
+		t.Errorf("Expected one removed vote, got culprits %v and events %v", culprits, events)
+	}
+
+	// votes without a voter are reported with an empty name
+	basic := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(nil, gopolls.BasicPollAnswer(42))})
+	events = nil
+	culprits, err = gopolls.TruncatePollVoters("basic", basic, func(event gopolls.PollEvent) {
+		events = append(events, event)
+	})
+	if err != nil || len(culprits) != 1 || len(events) != 1 || events[0].Voter != "" {
+		t.Errorf("Expected one removed vote without voter, got culprits %v, events %v and error %v", culprits, events,
+			err)
+	}
+
+	if _, err := gopolls.TruncatePollVoters("experimental", &experimentalPoll{}, nil); err == nil {
+		t.Error("Expected an error for an unsupported poll type")
+	}
+}
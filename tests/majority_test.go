@@ -15,6 +15,8 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
 	"github.com/FabianWe/gopolls"
 	"math/big"
 	"testing"
@@ -46,6 +48,163 @@ func TestComputeMajority(t *testing.T) {
 	}
 }
 
+func TestComputeRequiredVotes(t *testing.T) {
+	tests := []struct {
+		majority *big.Rat
+		total    gopolls.Weight
+		strict   bool
+		expected gopolls.Weight
+	}{
+		{gopolls.FiftyPercentMajority, 10, true, 6},
+		{gopolls.FiftyPercentMajority, 10, false, 5},
+		{gopolls.FiftyPercentMajority, 11, true, 6},
+		{gopolls.FiftyPercentMajority, 11, false, 6},
+		{gopolls.FiftyPercentMajority, 1, true, 1},
+		{gopolls.FiftyPercentMajority, 1, false, 1},
+		{gopolls.FiftyPercentMajority, 2, true, 2},
+		{gopolls.FiftyPercentMajority, 2, false, 1},
+		{gopolls.TwoThirdsMajority, 9, true, 7},
+		{gopolls.TwoThirdsMajority, 9, false, 6},
+		{gopolls.TwoThirdsMajority, 10, true, 7},
+		{gopolls.TwoThirdsMajority, 10, false, 7},
+		{gopolls.TwoThirdsMajority, 11, true, 8},
+		{gopolls.TwoThirdsMajority, 11, false, 8},
+		{gopolls.TwoThirdsMajority, 12, true, 9},
+		{gopolls.TwoThirdsMajority, 12, false, 8},
+		{gopolls.TwoThirdsMajority, 3, true, 3},
+		{gopolls.TwoThirdsMajority, 3, false, 2},
+		{big.NewRat(3, 4), 4, true, 4},
+		{big.NewRat(3, 4), 4, false, 3},
+		{big.NewRat(3, 4), 5, true, 4},
+		{big.NewRat(3, 4), 5, false, 4},
+		{big.NewRat(1, 3), 3, true, 2},
+		{big.NewRat(1, 3), 3, false, 1},
+		{big.NewRat(1, 3), 4, false, 2},
+		{big.NewRat(1, 1), 10, true, 11},
+		{big.NewRat(1, 1), 10, false, 10},
+		{big.NewRat(0, 1), 10, true, 1},
+		{big.NewRat(0, 1), 10, false, 0},
+		{gopolls.FiftyPercentMajority, 0, true, 1},
+		{gopolls.FiftyPercentMajority, 0, false, 0},
+		{gopolls.FiftyPercentMajority, gopolls.NoWeight, true, 2147483648},
+		{gopolls.FiftyPercentMajority, gopolls.NoWeight, false, 2147483648},
+		{big.NewRat(1, 1), gopolls.NoWeight, false, gopolls.NoWeight},
+		{big.NewRat(1, 1), gopolls.NoWeight, true, gopolls.NoWeight},
+	}
+
+	for _, tc := range tests {
+		res := gopolls.ComputeRequiredVotes(tc.majority, tc.total, tc.strict)
+		if res != tc.expected {
+			t.Errorf("Expected required votes for %s of %d (strict=%v) to be %d, got %d",
+				tc.majority, tc.total, tc.strict, tc.expected, res)
+		}
+	}
+}
+
+func TestMeetsMajorityBoundaries(t *testing.T) {
+	majorities := []*big.Rat{
+		gopolls.FiftyPercentMajority,
+		gopolls.TwoThirdsMajority,
+		big.NewRat(1, 3),
+		big.NewRat(3, 4),
+		big.NewRat(3, 5),
+		big.NewRat(7, 10),
+		big.NewRat(1, 1),
+		big.NewRat(0, 1),
+	}
+	for _, majority := range majorities {
+		for total := gopolls.Weight(0); total <= 60; total++ {
+			for _, strict := range []bool{true, false} {
+				required := gopolls.ComputeRequiredVotes(majority, total, strict)
+				for votes := gopolls.Weight(0); votes <= total+1; votes++ {
+					// votes / total compared to majority, without any rounding
+					share := big.NewRat(int64(votes)*majority.Denom().Int64(), 1)
+					bound := big.NewRat(int64(total)*majority.Num().Int64(), 1)
+					expected := share.Cmp(bound) >= 0
+					if strict {
+						expected = share.Cmp(bound) > 0
+					}
+					if met := gopolls.MeetsMajority(votes, total, majority, strict); met != expected {
+						t.Errorf("Expected MeetsMajority(%d, %d, %s, %v) to be %v", votes, total, majority, strict,
+							expected)
+					}
+					if (votes >= required) != expected {
+						t.Errorf("Expected %d votes of %d to meet %s (strict=%v) iff >= %d", votes, total, majority,
+							strict, required)
+					}
+				}
+				if strict && required != gopolls.ComputeMajority(majority, total)+1 {
+					t.Errorf("Expected strict required votes for %s of %d to be ComputeMajority + 1, got %d",
+						majority, total, required)
+				}
+			}
+		}
+	}
+}
+
+func TestBasicPollPassesMajority(t *testing.T) {
+	tests := []struct {
+		ayes, noes, abstentions gopolls.Weight
+		majority                *big.Rat
+		strict                  bool
+		expected                bool
+	}{
+		{5, 5, 0, gopolls.FiftyPercentMajority, true, false},
+		{5, 5, 0, gopolls.FiftyPercentMajority, false, true},
+		{6, 3, 0, gopolls.TwoThirdsMajority, true, false},
+		{6, 3, 0, gopolls.TwoThirdsMajority, false, true},
+		{6, 3, 10, gopolls.TwoThirdsMajority, false, true},
+		{5, 3, 0, gopolls.TwoThirdsMajority, false, false},
+		{7, 3, 0, gopolls.TwoThirdsMajority, true, true},
+		{0, 0, 3, gopolls.FiftyPercentMajority, true, false},
+		{0, 0, 3, gopolls.FiftyPercentMajority, false, true},
+		{gopolls.NoWeight, gopolls.NoWeight, 0, gopolls.FiftyPercentMajority, false, true},
+	}
+	for _, tc := range tests {
+		res := gopolls.NewBasicPollResult()
+		res.WeightedVotes.NumAyes = tc.ayes
+		res.WeightedVotes.NumNoes = tc.noes
+		res.WeightedVotes.NumAbstention = tc.abstentions
+		if passed := res.PassesMajority(tc.majority, tc.strict); passed != tc.expected {
+			t.Errorf("Expected %d ayes and %d noes to pass %s (strict=%v): %v, got %v",
+				tc.ayes, tc.noes, tc.majority, tc.strict, tc.expected, passed)
+		}
+	}
+}
+
+func TestMedianTallyWithMajority(t *testing.T) {
+	// nine voters with weight one: three for 300, three for 200 and three for 100
+	var votes []*gopolls.MedianVote
+	for i := 0; i < 9; i++ {
+		voter := gopolls.NewVoter(fmt.Sprintf("voter%d", i), 1)
+		votes = append(votes, gopolls.NewMedianVote(voter, gopolls.MedianUnit(300-100*(i/3))))
+	}
+	poll := gopolls.NewMedianPoll(300, votes)
+
+	tests := []struct {
+		majority *big.Rat
+		strict   bool
+		expected gopolls.MedianUnit
+	}{
+		// at least 6 votes for two thirds, 6 votes support >= 200
+		{gopolls.TwoThirdsMajority, false, 200},
+		// more than 6 votes for two thirds
+		{gopolls.TwoThirdsMajority, true, 100},
+		{big.NewRat(1, 3), false, 300},
+		{big.NewRat(1, 3), true, 200},
+		{gopolls.FiftyPercentMajority, true, 200},
+		{big.NewRat(1, 1), false, 100},
+		{big.NewRat(1, 1), true, gopolls.NoMedianUnitValue},
+	}
+	for _, tc := range tests {
+		res := poll.TallyWithMajority(tc.majority, tc.strict)
+		if res.MajorityValue != tc.expected {
+			t.Errorf("Expected value %d for %s (strict=%v), got %d", tc.expected, tc.majority, tc.strict,
+				res.MajorityValue)
+		}
+	}
+}
+
 func TestComputePercentage(t *testing.T) {
 	tests := []struct {
 		votes, total gopolls.Weight
@@ -85,3 +244,118 @@ func TestFormatPercentage(t *testing.T) {
 		}
 	}
 }
+
+func TestBasicPollCheckMajority(t *testing.T) {
+	tests := []struct {
+		ayes, noes, abstentions gopolls.Weight
+		majority                *big.Rat
+		strict                  bool
+		base                    gopolls.MajorityBase
+		eligible                gopolls.Weight
+		denominator, required   gopolls.Weight
+		outcome                 gopolls.BasicPollOutcome
+	}{
+		// abstentions count like noes for CastVotes
+		{4, 3, 5, gopolls.FiftyPercentMajority, true, gopolls.CastVotes, gopolls.NoWeight, 12, 7, gopolls.Rejected},
+		{4, 3, 5, gopolls.FiftyPercentMajority, true, gopolls.CastVotesExcludingAbstentions, gopolls.NoWeight,
+			7, 4, gopolls.Passed},
+		{4, 3, 5, gopolls.FiftyPercentMajority, true, gopolls.EligibleWeight, 20, 20, 11, gopolls.Rejected},
+		// only abstentions and a single aye
+		{1, 0, 10, gopolls.TwoThirdsMajority, false, gopolls.CastVotes, gopolls.NoWeight, 11, 8, gopolls.Rejected},
+		{1, 0, 10, gopolls.TwoThirdsMajority, false, gopolls.CastVotesExcludingAbstentions, gopolls.NoWeight,
+			1, 1, gopolls.Passed},
+		{1, 0, 10, gopolls.TwoThirdsMajority, false, gopolls.EligibleWeight, 11, 11, 8, gopolls.Rejected},
+		// only abstentions
+		{0, 0, 10, gopolls.FiftyPercentMajority, false, gopolls.CastVotesExcludingAbstentions, gopolls.NoWeight,
+			0, 0, gopolls.Passed},
+		{0, 0, 10, gopolls.FiftyPercentMajority, true, gopolls.CastVotesExcludingAbstentions, gopolls.NoWeight,
+			0, 1, gopolls.Rejected},
+		{0, 0, 10, gopolls.FiftyPercentMajority, false, gopolls.CastVotes, gopolls.NoWeight, 10, 5, gopolls.Rejected},
+		// all eligible voters voted aye or abstained
+		{8, 1, 3, gopolls.TwoThirdsMajority, false, gopolls.CastVotes, gopolls.NoWeight, 12, 8, gopolls.Passed},
+		{8, 1, 3, gopolls.TwoThirdsMajority, false, gopolls.EligibleWeight, 12, 12, 8, gopolls.Passed},
+		{8, 1, 3, gopolls.TwoThirdsMajority, true, gopolls.EligibleWeight, 12, 12, 9, gopolls.Rejected},
+	}
+	for _, tc := range tests {
+		var votes []*gopolls.BasicVote
+		addVotes := func(weight gopolls.Weight, choice gopolls.BasicPollAnswer) {
+			if weight > 0 {
+				voter := gopolls.NewVoter(fmt.Sprintf("voter%d", len(votes)), weight)
+				votes = append(votes, gopolls.NewBasicVote(voter, choice))
+			}
+		}
+		addVotes(tc.ayes, gopolls.Aye)
+		addVotes(tc.noes, gopolls.No)
+		addVotes(tc.abstentions, gopolls.Abstention)
+		check, err := gopolls.NewBasicPoll(votes).Tally().CheckMajority(tc.majority, tc.strict, tc.base, tc.eligible)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.base, err)
+			continue
+		}
+		if check.Denominator != tc.denominator || check.RequiredWeight != tc.required || check.Outcome != tc.outcome {
+			t.Errorf("Expected denominator %d, required weight %d and outcome %s for %d/%d/%d with %s (%s, strict=%v), "+
+				"got %d, %d and %s", tc.denominator, tc.required, tc.outcome, tc.ayes, tc.noes, tc.abstentions, tc.base,
+				tc.majority, tc.strict, check.Denominator, check.RequiredWeight, check.Outcome)
+		}
+		if check.Base != tc.base || check.AyesWeight != tc.ayes {
+			t.Errorf("Expected base %s and ayes %d, got %s and %d", tc.base, tc.ayes, check.Base, check.AyesWeight)
+		}
+	}
+}
+
+func TestBasicPollCheckMajorityErrors(t *testing.T) {
+	res := gopolls.NewBasicPollResult()
+	res.WeightedVotes.NumAyes = 5
+	res.WeightedVotes.NumAbstention = 5
+	var semanticErr gopolls.PollingSemanticError
+	_, err := res.CheckMajority(gopolls.FiftyPercentMajority, true, gopolls.EligibleWeight, 9)
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for an eligible weight < cast votes, got %v", err)
+	}
+	_, err = res.CheckMajority(gopolls.FiftyPercentMajority, true, gopolls.MajorityBase(42), 0)
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for an invalid base, got %v", err)
+	}
+}
+
+func TestMedianTallyWithBase(t *testing.T) {
+	// six voters with weight one: two for 300, two for 200 and one for 100, one abstention with weight 4
+	var votes []*gopolls.MedianVote
+	for i, value := range []gopolls.MedianUnit{300, 300, 200, 200, 100} {
+		votes = append(votes, gopolls.NewMedianVote(gopolls.NewVoter(fmt.Sprintf("voter%d", i), 1), value))
+	}
+	abstention := gopolls.NewMedianVote(gopolls.NewVoter("abstention", 4), 0)
+	abstention.Abstain = true
+	votes = append(votes, abstention)
+	poll := gopolls.NewMedianPoll(300, votes)
+
+	tests := []struct {
+		base             gopolls.MajorityBase
+		eligible         gopolls.Weight
+		requiredMajority gopolls.Weight
+		expected         gopolls.MedianUnit
+	}{
+		// more than half of 5
+		{gopolls.CastVotesExcludingAbstentions, gopolls.NoWeight, 2, 200},
+		// more than half of 9
+		{gopolls.CastVotes, gopolls.NoWeight, 4, 100},
+		// more than half of 10, not reachable without the abstentions
+		{gopolls.EligibleWeight, 10, 5, gopolls.NoMedianUnitValue},
+	}
+	for _, tc := range tests {
+		res := poll.TallyWithBase(gopolls.FiftyPercentMajority, true, tc.base, tc.eligible)
+		if res.Err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.base, res.Err)
+			continue
+		}
+		if res.MajorityValue != tc.expected || res.RequiredMajority != tc.requiredMajority || res.WeightSum != 5 {
+			t.Errorf("Expected value %d with required majority %d for %s, got %d and %d (weight sum %d)",
+				tc.expected, tc.requiredMajority, tc.base, res.MajorityValue, res.RequiredMajority, res.WeightSum)
+		}
+	}
+	res := poll.TallyWithBase(gopolls.FiftyPercentMajority, true, gopolls.EligibleWeight, 8)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(res.Err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for an eligible weight < cast votes, got %v", res.Err)
+	}
+}
@@ -0,0 +1,697 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// matrixTestSetup creates numPolls basic polls "p0", ... and a matrix with two voters, invalidPolls contains the
+// indices of the polls for which the second voter gets an invalid vote.
+func matrixTestSetup(t *testing.T, numPolls int, invalidPolls ...int) (*gopolls.PollMatrix, gopolls.PollMap,
+	gopolls.VoterMap, map[string]gopolls.VoteParser, gopolls.PolicyMap) {
+	invalid := make(map[int]bool, len(invalidPolls))
+	for _, i := range invalidPolls {
+		invalid[i] = true
+	}
+	voters := gopolls.VoterMap{"one": gopolls.NewVoter("one", 1), "two": gopolls.NewVoter("two", 1)}
+	polls := make(gopolls.PollMap, numPolls)
+	head := []string{"voter"}
+	rowOne := []string{"one"}
+	rowTwo := []string{"two"}
+	for i := 0; i < numPolls; i++ {
+		name := fmt.Sprintf("p%d", i)
+		polls[name] = gopolls.NewBasicPoll(nil)
+		head = append(head, name)
+		rowOne = append(rowOne, "aye")
+		if invalid[i] {
+			rowTwo = append(rowTwo, "maybe")
+		} else {
+			rowTwo = append(rowTwo, "no")
+		}
+	}
+	customizers, customizeErr := gopolls.CustomizeParsersToMap(polls, gopolls.GenerateDefaultParserTemplateMap())
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error creating parsers: %v", customizeErr)
+	}
+	parsers := make(map[string]gopolls.VoteParser, len(customizers))
+	for name, p := range customizers {
+		parsers[name] = p
+	}
+	matrix := &gopolls.PollMatrix{Head: head, Body: [][]string{rowOne, rowTwo}}
+	return matrix, polls, voters, parsers, gopolls.GeneratePoliciesMap(gopolls.RaiseErrorEmptyVote, polls)
+}
+
+func TestFillPollsWithVotesWorkerLimit(t *testing.T) {
+	for _, limit := range []int{-1, 1, 3, 100} {
+		matrix, polls, voters, parsers, policies := matrixTestSetup(t, 20)
+		_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false,
+			gopolls.WithWorkerLimit(limit))
+		if err != nil {
+			t.Fatalf("Unexpected error filling polls with limit %d: %v", limit, err)
+		}
+		for name, poll := range polls {
+			if numVotes := len(poll.(*gopolls.BasicPoll).Votes); numVotes != 2 {
+				t.Errorf("Expected two votes for poll %s with limit %d, got %d", name, limit, numVotes)
+			}
+		}
+	}
+}
+
+func TestFillPollsWithVotesPollFilledCallback(t *testing.T) {
+	matrix, polls, voters, parsers, policies := matrixTestSetup(t, 10, 3)
+	filled := make(map[string]int)
+	callback := func(pollName string, numVotes int) {
+		if _, has := filled[pollName]; has {
+			t.Errorf("Callback called multiple times for poll %s", pollName)
+		}
+		filled[pollName] = numVotes
+	}
+	_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false,
+		gopolls.WithWorkerLimit(4), gopolls.WithPollFilledCallback(callback))
+	if err == nil {
+		t.Fatal("Expected an error for poll p3")
+	}
+	// the poll with an error must not be reported
+	if len(filled) != 9 {
+		t.Fatalf("Expected callback to be called for 9 polls, got %v", filled)
+	}
+	for pollName, numVotes := range filled {
+		if pollName == "p3" {
+			t.Error("Callback must not be called for poll p3 that contains an error")
+		}
+		if numVotes != 2 {
+			t.Errorf("Expected two votes for poll %s, got %d", pollName, numVotes)
+		}
+	}
+}
+
+func TestFillPollsWithVotesReport(t *testing.T) {
+	voters := gopolls.VoterMap{
+		"one":   gopolls.NewVoter("one", 1),
+		"two":   gopolls.NewVoter("two", 1),
+		"three": gopolls.NewVoter("three", 1),
+	}
+	polls := gopolls.PollMap{
+		"ignore":  gopolls.NewBasicPoll(nil),
+		"no":      gopolls.NewBasicPoll(nil),
+		"invalid": gopolls.NewBasicPoll(nil),
+	}
+	parsers := map[string]gopolls.VoteParser{
+		"ignore":  gopolls.NewBasicVoteParser(),
+		"no":      gopolls.NewBasicVoteParser(),
+		"invalid": gopolls.NewBasicVoteParser(),
+	}
+	policies := gopolls.PolicyMap{
+		"ignore":  gopolls.IgnoreEmptyVote,
+		"no":      gopolls.AddAsNoEmptyVote,
+		"invalid": gopolls.IgnoreEmptyVote,
+	}
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "ignore", "no", "invalid"},
+		Body: [][]string{
+			{"one", "aye", "", ""},
+			{"two", " ", "", "maybe"},
+			{"three", "no", "aye", "aye"},
+		},
+	}
+	_, _, report, err := matrix.FillPollsWithVotesWithReport(polls, voters, parsers, policies, false, false)
+	var matrixErrs gopolls.PollMatrixErrors
+	if !errors.As(err, &matrixErrs) || len(matrixErrs.Errors) != 1 || matrixErrs.Errors[0].Poll != "invalid" {
+		t.Fatalf("Expected an error for poll invalid, got %v", err)
+	}
+	expectedPolls := map[string]gopolls.PollFillStats{
+		"ignore":  {Parsed: 2, PolicyGenerated: 0, SkippedEmpty: 1},
+		"no":      {Parsed: 1, PolicyGenerated: 2, SkippedEmpty: 0},
+		"invalid": {Parsed: 0, PolicyGenerated: 0, SkippedEmpty: 1},
+	}
+	if !reflect.DeepEqual(report.Polls, expectedPolls) {
+		t.Errorf("Expected poll stats %v, got %v", expectedPolls, report.Polls)
+	}
+	// the entry of three for poll invalid is not counted because of the error in the row of two
+	expectedVoters := map[string]int{"one": 1, "two": 0, "three": 2}
+	if !reflect.DeepEqual(report.CastVotes, expectedVoters) {
+		t.Errorf("Expected cast votes %v, got %v", expectedVoters, report.CastVotes)
+	}
+	if emptyVoters := report.EmptyVoters(); !reflect.DeepEqual(emptyVoters, []string{"two"}) {
+		t.Errorf("Expected empty voters [two], got %v", emptyVoters)
+	}
+	if noVotes := report.PollsWithoutVotes(); !reflect.DeepEqual(noVotes, []string{"invalid"}) {
+		t.Errorf("Expected polls without votes [invalid], got %v", noVotes)
+	}
+}
+
+func TestFillPollsWithVotesErrors(t *testing.T) {
+	matrix, polls, voters, parsers, policies := matrixTestSetup(t, 10, 7, 2, 5)
+	_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false,
+		gopolls.WithWorkerLimit(2))
+	var matrixErrs gopolls.PollMatrixErrors
+	if !errors.As(err, &matrixErrs) {
+		t.Fatalf("Expected PollMatrixErrors, got %v", err)
+	}
+	expected := []int{2, 5, 7}
+	if len(matrixErrs.Errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), matrixErrs.Errors)
+	}
+	for i, colErr := range matrixErrs.Errors {
+		if colErr.Poll != fmt.Sprintf("p%d", expected[i]) || colErr.Column != expected[i]+1 {
+			t.Errorf("Expected error for poll p%d, got %v", expected[i], colErr)
+		}
+	}
+	if !errors.Is(err, gopolls.ErrPoll) {
+		t.Error("Expected errors.Is(err, ErrPoll) to be true")
+	}
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Error("Expected the errors to contain a PollingSyntaxError")
+	}
+}
+
+func TestPollMatrixValidate(t *testing.T) {
+	voters := gopolls.VoterMap{"one": gopolls.NewVoter("one", 1), "two": gopolls.NewVoter("two", 1)}
+	polls := gopolls.PollMap{"a": gopolls.NewBasicPoll(nil), "b": gopolls.NewBasicPoll(nil)}
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "a", "x", "a", "b"},
+		Body: [][]string{
+			{"one", "aye", "", "", ""},
+			{"three", "aye", "", "", "no"},
+			{"one", "aye", "", "", "no"},
+			{"two", "aye"},
+		},
+	}
+	policies := gopolls.PolicyMap{"a": gopolls.IgnoreEmptyVote, "b": gopolls.RaiseErrorEmptyVote}
+	report := matrix.Validate(voters, polls, policies)
+	expected := []gopolls.MatrixIssue{
+		{Type: gopolls.UnknownPollIssue, Row: 1, Column: 2, Poll: "x"},
+		{Type: gopolls.DuplicatePollIssue, Row: 1, Column: 3, Poll: "a"},
+		{Type: gopolls.EmptyCellIssue, Row: 2, Column: 4, Voter: "one", Poll: "b"},
+		{Type: gopolls.UnknownVoterIssue, Row: 3, Column: 0, Voter: "three"},
+		{Type: gopolls.DuplicateVoterIssue, Row: 4, Column: 0, Voter: "one"},
+		{Type: gopolls.InvalidRowLengthIssue, Row: 5, Column: -1, NumColumns: 2},
+	}
+	if len(report.Issues) != len(expected) {
+		t.Fatalf("Expected issues %v, got %v instead", expected, report.Issues)
+	}
+	for i, issue := range report.Issues {
+		if issue != expected[i] {
+			t.Errorf("Expected issue %v, got %v instead", expected[i], issue)
+		}
+	}
+	if len(report.IssuesOfType(gopolls.UnknownVoterIssue)) != 1 {
+		t.Errorf("Expected exactly one unknown voter")
+	}
+	err := report.AsError()
+	var validationErr gopolls.MatrixValidationError
+	if !errors.As(err, &validationErr) || !errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected a MatrixValidationError, got %v", err)
+	}
+
+	valid := &gopolls.PollMatrix{Head: []string{"voter", "a"}, Body: [][]string{{"one", "aye"}, {"two", ""}}}
+	if validReport := valid.Validate(voters, polls, policies); !validReport.Empty() || validReport.AsError() != nil {
+		t.Errorf("Expected no issues, got %v", validReport.Issues)
+	}
+}
+
+func TestGenerateTemplateWithVotes(t *testing.T) {
+	voters := []*gopolls.Voter{gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1), gopolls.NewVoter("three", 1)}
+	skels := []gopolls.AbstractPollSkeleton{
+		&gopolls.PollSkeleton{Name: "b", Options: []string{"yes", "no"}},
+		&gopolls.PollSkeleton{Name: "a", Options: []string{"yes", "no"}},
+	}
+	existing := map[string]map[string]string{
+		"one":   {"a": "aye", "b": "no"},
+		"three": {"a": "abstention"},
+	}
+	var buf bytes.Buffer
+	if err := gopolls.NewVotesCSVWriter(&buf).GenerateTemplateWithVotes(voters, skels, existing); err != nil {
+		t.Fatalf("Unexpected error writing template: %v", err)
+	}
+	expected := "voter,b,a\none,no,aye\ntwo,,\nthree,,abstention\n"
+	if buf.String() != expected {
+		t.Errorf("Expected template\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	existing["four"] = map[string]string{"c": "aye"}
+	existing["one"]["d"] = "no"
+	err := gopolls.NewVotesCSVWriter(&buf).GenerateTemplateWithVotes(voters, skels, existing)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) || !strings.Contains(err.Error(), "four") ||
+		!strings.Contains(err.Error(), "c, d") {
+		t.Errorf("Expected PollingSemanticError listing unknown voters and polls, got %v", err)
+	}
+}
+
+// failingWriter returns errFailingWriter once more than limit bytes have been written.
+type failingWriter struct {
+	limit   int
+	written int
+}
+
+var errFailingWriter = errors.New("writer failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		n := w.limit - w.written
+		w.written = w.limit
+		return n, errFailingWriter
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
+func TestVotesCSVWriterRows(t *testing.T) {
+	skels := []gopolls.AbstractPollSkeleton{
+		&gopolls.PollSkeleton{Name: "a", Options: []string{"yes", "no"}},
+		&gopolls.PollSkeleton{Name: "b", Options: []string{"yes", "no"}},
+	}
+	var buf bytes.Buffer
+	writer := gopolls.NewVotesCSVWriter(&buf)
+	if err := writer.WriteVoterRow(gopolls.NewVoter("one", 1), []string{"", ""}); err == nil {
+		t.Error("Expected an error writing a row before the head")
+	}
+	if err := writer.WriteHead(skels); err != nil {
+		t.Fatalf("Unexpected error writing head: %v", err)
+	}
+	if err := writer.WriteVoterRow(gopolls.NewVoter("one", 1), []string{"aye"}); err == nil {
+		t.Error("Expected an error writing a row with the wrong number of values")
+	}
+	if err := writer.WriteVoterRow(gopolls.NewVoter("one", 1), []string{"aye", "no"}); err != nil {
+		t.Fatalf("Unexpected error writing row: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Unexpected error flushing: %v", err)
+	}
+	if expected := "voter,a,b\none,aye,no\n"; buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	// the csv writer is buffered, so write enough rows to fill the buffer
+	writer = gopolls.NewVotesCSVWriter(&failingWriter{limit: 100})
+	if err := writer.WriteHead(skels); err != nil {
+		t.Fatalf("Unexpected error writing head: %v", err)
+	}
+	var rowErr error
+	var row int
+	for i := 0; i < 10000 && rowErr == nil; i++ {
+		row = i + 2
+		rowErr = writer.WriteVoterRow(gopolls.NewVoter(fmt.Sprintf("voter%d", i), 1), []string{"aye", "no"})
+	}
+	if !errors.Is(rowErr, errFailingWriter) {
+		t.Fatalf("Expected an error wrapping the writer error, got %v", rowErr)
+	}
+	expectedMsg := fmt.Sprintf("row %d (voter \"voter%d\")", row, row-2)
+	if !strings.Contains(rowErr.Error(), expectedMsg) {
+		t.Errorf("Expected error to contain \"%s\", got \"%s\"", expectedMsg, rowErr)
+	}
+
+	// if the buffer is not full the error is reported by flush
+	writer = gopolls.NewVotesCSVWriter(&failingWriter{limit: 0})
+	if err := writer.WriteHead(skels); err != nil {
+		t.Fatalf("Unexpected error writing head: %v", err)
+	}
+	if err := writer.Flush(); !errors.Is(err, errFailingWriter) {
+		t.Errorf("Expected flush to return an error wrapping the writer error, got %v", err)
+	}
+}
+
+func TestPollMatrixOrientation(t *testing.T) {
+	matrix, polls, voters, parsers, policies := matrixTestSetup(t, 3, 1)
+	transposed, transposeErr := matrix.Transpose()
+	if transposeErr != nil {
+		t.Fatalf("Unexpected error transposing matrix: %v", transposeErr)
+	}
+	expected := &gopolls.PollMatrix{
+		Head: []string{"voter", "one", "two"},
+		Body: [][]string{{"p0", "aye", "no"}, {"p1", "aye", "maybe"}, {"p2", "aye", "no"}},
+	}
+	if !reflect.DeepEqual(transposed, expected) {
+		t.Fatalf("Expected transposed matrix %v, got %v", expected, transposed)
+	}
+	if back, _ := transposed.Transpose(); !reflect.DeepEqual(back, matrix) {
+		t.Errorf("Transposing twice must return the original matrix, got %v", back)
+	}
+
+	for _, m := range []*gopolls.PollMatrix{matrix, transposed} {
+		oriented, orientErr := m.Orient(gopolls.DetectOrientation, voters, polls)
+		if orientErr != nil {
+			t.Fatalf("Unexpected error detecting orientation: %v", orientErr)
+		}
+		if !reflect.DeepEqual(oriented, matrix) {
+			t.Errorf("Expected oriented matrix %v, got %v", matrix, oriented)
+		}
+	}
+	if orientation, _ := transposed.DetectOrientation(voters, polls); orientation != gopolls.PollsInRows {
+		t.Errorf("Expected %s, got %s", gopolls.PollsInRows, orientation)
+	}
+	// the oriented matrix can be used as usual
+	oriented, _ := transposed.Orient(gopolls.PollsInRows, voters, polls)
+	if _, _, fillErr := oriented.FillPollsWithVotes(polls, voters, parsers, policies, false, false); fillErr == nil {
+		t.Error("Expected an error for the invalid vote in p1")
+	}
+
+	unknown := &gopolls.PollMatrix{Head: []string{"voter", "p0", "three"}, Body: [][]string{{"one", "aye", "no"}}}
+	_, detectErr := unknown.DetectOrientation(voters, polls)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(detectErr, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError if neither orientation matches, got %v", detectErr)
+	}
+}
+
+func TestPollMatrixReorderColumns(t *testing.T) {
+	coll := gopolls.NewPollSkeletonCollection("reorder")
+	first, second := gopolls.NewPollGroup("first"), gopolls.NewPollGroup("second")
+	first.Skeletons = append(first.Skeletons, gopolls.NewPollSkeleton("a"), gopolls.NewPollSkeleton("b"))
+	second.Skeletons = append(second.Skeletons, gopolls.NewPollSkeleton("c"))
+	coll.Groups = append(coll.Groups, first, second)
+	order := gopolls.CanonicalOrder(coll)
+	if !reflect.DeepEqual(order, []string{"a", "b", "c"}) {
+		t.Fatalf("Expected canonical order [a b c], got %v", order)
+	}
+
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "c", "a", "b"},
+		Body: [][]string{{"one", "c1", "a1", "b1"}, {"two", "c2", "a2", "b2"}},
+	}
+	if err := matrix.ReorderColumns(order); err != nil {
+		t.Fatalf("Unexpected error reordering columns: %v", err)
+	}
+	expected := &gopolls.PollMatrix{
+		Head: []string{"voter", "a", "b", "c"},
+		Body: [][]string{{"one", "a1", "b1", "c1"}, {"two", "a2", "b2", "c2"}},
+	}
+	if !reflect.DeepEqual(matrix, expected) {
+		t.Fatalf("Expected reordered matrix %v, got %v", expected, matrix)
+	}
+
+	for _, invalid := range [][]string{{"a", "b"}, {"a", "b", "d"}, {"a", "a", "b"}} {
+		err := matrix.ReorderColumns(invalid)
+		var semanticErr gopolls.PollingSemanticError
+		if !errors.As(err, &semanticErr) {
+			t.Errorf("Expected PollingSemanticError for order %v, got %v", invalid, err)
+		}
+		if !reflect.DeepEqual(matrix, expected) {
+			t.Errorf("Matrix must not be changed by a failed reorder, got %v", matrix)
+		}
+	}
+}
+
+func TestValidateVote(t *testing.T) {
+	voter := gopolls.NewVoter("one", 1)
+	tests := []struct {
+		poll  gopolls.VoteValidator
+		vote  gopolls.AbstractVote
+		valid bool
+	}{
+		{gopolls.NewBasicPoll(nil), gopolls.NewBasicVote(voter, gopolls.Aye), true},
+		{gopolls.NewBasicPoll(nil), gopolls.NewBasicVote(voter, gopolls.BasicPollAnswer(42)), false},
+		{gopolls.NewBasicPoll(nil), gopolls.NewBasicVote(nil, gopolls.Aye), false},
+		{gopolls.NewMedianPoll(100, nil), gopolls.NewMedianVote(voter, 100), true},
+		{gopolls.NewMedianPoll(100, nil), gopolls.NewMedianVote(voter, 101), false},
+		{gopolls.NewSchulzePoll(3, nil), gopolls.NewSchulzeVote(voter, gopolls.SchulzeRanking{0, 1, 1}), true},
+		{gopolls.NewSchulzePoll(3, nil), gopolls.NewSchulzeVote(voter, gopolls.SchulzeRanking{0, 1}), false},
+	}
+	for _, tc := range tests {
+		err := tc.poll.ValidateVote(tc.vote)
+		if tc.valid && err != nil {
+			t.Errorf("Unexpected error validating vote for %s: %v", tc.poll.PollType(), err)
+		}
+		var semanticErr gopolls.PollingSemanticError
+		if !tc.valid && !errors.As(err, &semanticErr) {
+			t.Errorf("Expected PollingSemanticError validating vote for %s, got %v", tc.poll.PollType(), err)
+		}
+	}
+	var typeErr gopolls.PollTypeError
+	if err := gopolls.NewSchulzePoll(2, nil).ValidateVote(gopolls.NewBasicVote(voter, gopolls.Aye)); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+
+	// with validation a median vote that is too big is rejected when filling the polls
+	polls := gopolls.PollMap{"budget": gopolls.NewMedianPoll(100, nil)}
+	voters := gopolls.VoterMap{"one": voter}
+	parsers := map[string]gopolls.VoteParser{"budget": gopolls.NewMedianVoteParser(gopolls.NewRawCentCurrencyParser())}
+	policies := gopolls.GeneratePoliciesMap(gopolls.IgnoreEmptyVote, polls)
+	matrix := &gopolls.PollMatrix{Head: []string{"voter", "budget"}, Body: [][]string{{"one", "500"}}}
+	if _, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false); err != nil {
+		t.Fatalf("Unexpected error without validation: %v", err)
+	}
+	polls["budget"] = gopolls.NewMedianPoll(100, nil)
+	_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false, gopolls.WithVoteValidation())
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError with validation, got %v", err)
+	}
+	if votes := polls["budget"].(*gopolls.MedianPoll).Votes; len(votes) != 0 {
+		t.Errorf("Invalid vote must not be added, got %v", votes)
+	}
+}
+
+func TestSortedNames(t *testing.T) {
+	polls := gopolls.PollMap{"c": gopolls.NewBasicPoll(nil), "a": gopolls.NewBasicPoll(nil), "b": gopolls.NewBasicPoll(nil)}
+	if names := polls.SortedNames(); !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+		t.Errorf("Expected sorted poll names, got %v", names)
+	}
+	voters := gopolls.VoterMap{
+		"zoe":   gopolls.NewVoter("zoe", 1),
+		"bob":   gopolls.NewVoter("bob", 1),
+		"alice": gopolls.NewVoter("alice", 1),
+		"dave":  gopolls.NewVoter("dave", 1),
+	}
+	if names := voters.SortedNames(); !reflect.DeepEqual(names, []string{"alice", "bob", "dave", "zoe"}) {
+		t.Errorf("Expected sorted voter names, got %v", names)
+	}
+
+	// missing names in errors are sorted
+	parsers := map[string]gopolls.VoteParser{}
+	for name := range polls {
+		parsers[name] = gopolls.NewBasicVoteParser()
+	}
+	policies := gopolls.GeneratePoliciesMap(gopolls.IgnoreEmptyVote, polls)
+	matrix := &gopolls.PollMatrix{Head: []string{"voter", "b"}, Body: [][]string{{"bob", "aye"}}}
+	_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, true)
+	if err == nil || err.Error() != "the following voters are missing: alice, dave, zoe" {
+		t.Errorf("Expected sorted missing voters, got %v", err)
+	}
+	_, _, err = matrix.FillPollsWithVotes(polls, voters, parsers, policies, true, false)
+	if err == nil || err.Error() != "the following polls are missing: a, c" {
+		t.Errorf("Expected sorted missing polls, got %v", err)
+	}
+}
+
+func TestEmptyVotePolicyNames(t *testing.T) {
+	policies := []gopolls.EmptyVotePolicy{gopolls.IgnoreEmptyVote, gopolls.RaiseErrorEmptyVote,
+		gopolls.AddAsAyeEmptyVote, gopolls.AddAsNoEmptyVote, gopolls.AddAsAbstentionEmptyVote}
+	for _, policy := range policies {
+		parsed, err := gopolls.ParseEmptyVotePolicy(" " + strings.ToUpper(policy.String()) + " ")
+		if err != nil || parsed != policy {
+			t.Errorf("Expected policy %s, got %s (error %v)", policy, parsed, err)
+		}
+	}
+	if _, err := gopolls.ParseEmptyVotePolicy("maybe"); !errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected internal error for unknown policy, got %v", err)
+	}
+}
+
+func TestEmptyVotePolicyJSON(t *testing.T) {
+	policies := gopolls.PolicyMap{"a": gopolls.AddAsNoEmptyVote, "b": gopolls.IgnoreEmptyVote}
+	encoded, err := json.Marshal(policies)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(encoded) != `{"a":"no","b":"ignore"}` {
+		t.Errorf("Unexpected encoding %s", encoded)
+	}
+	var decoded gopolls.PolicyMap
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(decoded, policies) {
+		t.Errorf("Expected decoded policies %v, got %v", policies, decoded)
+	}
+	var policy gopolls.EmptyVotePolicy
+	if err := json.Unmarshal([]byte(`"maybe"`), &policy); !errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected internal error for unknown policy, got %v", err)
+	}
+	if err := json.Unmarshal([]byte(`1`), &policy); !errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected internal error for a number, got %v", err)
+	}
+	if _, err := json.Marshal(gopolls.EmptyVotePolicy(42)); err == nil {
+		t.Error("Expected error encoding an unknown policy")
+	}
+}
+
+func TestParsePoliciesMap(t *testing.T) {
+	polls := gopolls.PollMap{"a": gopolls.NewBasicPoll(nil), "b": gopolls.NewBasicPoll(nil)}
+	policies, err := gopolls.ParsePoliciesMap(map[string]string{"a": "Aye", "b": "abstention"}, polls)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := gopolls.PolicyMap{"a": gopolls.AddAsAyeEmptyVote, "b": gopolls.AddAsAbstentionEmptyVote}
+	if !reflect.DeepEqual(policies, expected) {
+		t.Errorf("Expected policies %v, got %v", expected, policies)
+	}
+	_, err = gopolls.ParsePoliciesMap(map[string]string{"c": "no"}, polls)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for unknown poll, got %v", err)
+	}
+	if _, err = gopolls.ParsePoliciesMap(map[string]string{"a": "maybe"}, polls); !errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected internal error for unknown policy, got %v", err)
+	}
+}
+
+// experimentalPoll is a poll type without a parser template.
+type experimentalPoll struct{}
+
+func (poll *experimentalPoll) PollType() string {
+	return "experimental-poll"
+}
+
+func (poll *experimentalPoll) AddVote(vote gopolls.AbstractVote) error {
+	return errors.New("experimental poll doesn't accept votes")
+}
+
+func TestSkipPollsWithoutParser(t *testing.T) {
+	polls := gopolls.PollMap{
+		"basic":        gopolls.NewBasicPoll(nil),
+		"broken":       gopolls.NewMedianPoll(100, nil),
+		"experimental": &experimentalPoll{},
+	}
+	templates := map[string]gopolls.ParserCustomizer{
+		gopolls.BasicPollType: gopolls.NewBasicVoteParser(),
+		// can't be customized for a median poll
+		gopolls.MedianPollType: gopolls.NewSchulzeVoteParser(-1),
+	}
+	var typeErr gopolls.PollTypeError
+	if _, err := gopolls.CustomizeParsersToMap(polls, templates); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+	_, _, err := gopolls.CustomizeParsersToMapWithOptions(polls, templates,
+		gopolls.CustomizeOptions{SkipMissingTemplates: true})
+	if err == nil || !strings.Contains(err.Error(), "SchulzeVoteParser") {
+		t.Errorf("Expected customize error to be fatal, got %v", err)
+	}
+	customizers, skipped, err := gopolls.CustomizeParsersToMapWithOptions(polls, templates,
+		gopolls.CustomizeOptions{SkipMissingTemplates: true, SkipCustomizeErrors: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(customizers) != 1 || customizers["basic"] == nil {
+		t.Errorf("Expected only a parser for basic, got %v", customizers)
+	}
+	if len(skipped) != 2 || skipped[0].Name != "broken" || skipped[1].Name != "experimental" {
+		t.Fatalf("Expected polls broken and experimental to be skipped, got %v", skipped)
+	}
+	if !errors.As(skipped[1].Err, &typeErr) || !strings.Contains(skipped[1].Err.Error(), "experimental-poll") {
+		t.Errorf("Expected PollTypeError for missing template, got %v", skipped[1].Err)
+	}
+
+	voters := gopolls.VoterMap{"one": gopolls.NewVoter("one", 1), "two": gopolls.NewVoter("two", 1)}
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "experimental", "basic", "broken"},
+		Body: [][]string{
+			{"one", "foo", "aye", "1, 2"},
+			{"two", "bar", "no", ""},
+		},
+	}
+	parsers := map[string]gopolls.VoteParser{"basic": customizers["basic"]}
+	policies := gopolls.PolicyMap{"basic": gopolls.RaiseErrorEmptyVote}
+	if _, _, err = matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false); err == nil {
+		t.Error("Expected an error for polls without parser")
+	}
+	_, actualPolls, report, err := matrix.FillPollsWithVotesWithReport(polls, voters, parsers, policies, false, false,
+		gopolls.WithSkipPollsWithoutParser())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actualPolls) != 1 || actualPolls["basic"] == nil {
+		t.Errorf("Expected only poll basic to be filled, got %v", actualPolls)
+	}
+	if numVotes := len(polls["basic"].(*gopolls.BasicPoll).Votes); numVotes != 2 {
+		t.Errorf("Expected two votes for poll basic, got %d", numVotes)
+	}
+	if !reflect.DeepEqual(report.SkippedPolls, []string{"broken", "experimental"}) {
+		t.Errorf("Expected skipped polls [broken experimental], got %v", report.SkippedPolls)
+	}
+	expectedVotes := map[string]int{"one": 1, "two": 1}
+	if !reflect.DeepEqual(report.CastVotes, expectedVotes) || len(report.Polls) != 1 {
+		t.Errorf("Expected only votes for basic in report, got %v and %v", report.CastVotes, report.Polls)
+	}
+}
+
+func TestEmptyTemplateRoundTrip(t *testing.T) {
+	voters := []*gopolls.Voter{gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 1)}
+	skels := []gopolls.AbstractPollSkeleton{
+		&gopolls.PollSkeleton{Name: "basic", Options: []string{"yes", "no"}},
+		gopolls.NewMoneyPollSkeleton("median", gopolls.NewCurrencyValue(1000, "€")),
+	}
+	var buf bytes.Buffer
+	writer := gopolls.NewVotesCSVWriter(&buf)
+	writer.Sep = ';'
+	if err := writer.GenerateEmptyTemplate(voters, skels); err != nil {
+		t.Fatalf("Unexpected error writing template: %v", err)
+	}
+	expected := "voter;basic;median\none;;\ntwo;;\nthree;;\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected template\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	// fill some cells, the median poll of voter three stays empty
+	filled := strings.NewReplacer("one;;", "one;aye;5", "two;;", "two;no;8", "three;;", "three;aye;").
+		Replace(buf.String())
+	reader := gopolls.NewVotesCSVReader(strings.NewReader(filled))
+	reader.Sep = ';'
+	matrix, readErr := gopolls.ReadMatrixFromCSV(reader)
+	if readErr != nil {
+		t.Fatalf("Unexpected error reading filled template: %v", readErr)
+	}
+	polls, convertErr := gopolls.ConvertSkeletonsToPolls(skels, nil)
+	if convertErr != nil {
+		t.Fatalf("Unexpected error converting skeletons: %v", convertErr)
+	}
+	pollMap := make(gopolls.PollMap, len(polls))
+	for i, skel := range skels {
+		pollMap[skel.GetName()] = polls[i]
+	}
+	customizers, customizeErr := gopolls.CustomizeParsersToMap(pollMap, gopolls.GenerateDefaultParserTemplateMap())
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error creating parsers: %v", customizeErr)
+	}
+	parsers := make(map[string]gopolls.VoteParser, len(customizers))
+	for name, p := range customizers {
+		parsers[name] = p
+	}
+	voterMap := make(gopolls.VoterMap, len(voters))
+	for _, voter := range voters {
+		voterMap[voter.Name] = voter
+	}
+	policies := gopolls.GeneratePoliciesMap(gopolls.IgnoreEmptyVote, pollMap)
+	if _, _, err := matrix.FillPollsWithVotes(pollMap, voterMap, parsers, policies, false, false); err != nil {
+		t.Fatalf("Unexpected error filling polls: %v", err)
+	}
+	basicRes := pollMap["basic"].(*gopolls.BasicPoll).Tally()
+	if basicRes.NumberVoters.NumAyes != 2 || basicRes.NumberVoters.NumNoes != 1 {
+		t.Errorf("Expected two ayes and one no, got %v", basicRes.NumberVoters)
+	}
+	if numVotes := len(pollMap["median"].(*gopolls.MedianPoll).Votes); numVotes != 2 {
+		t.Errorf("Expected two median votes, got %d", numVotes)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"strings"
+	"testing"
+)
+
+const medianRangeCollection = `# Assembly
+
+## Budget
+
+### Hall
+- 5000,00 € [min 500,00 €, step 50,00 €]
+
+### Garden
+- 1000,00 € [step 10,00 €]
+`
+
+func parseMedianRangeCollection(t *testing.T, s string) *gopolls.PollSkeletonCollection {
+	coll, err := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(gopolls.DefaultCurrencyHandler, s)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %s", err)
+	}
+	return coll
+}
+
+func TestParseMoneyPollRange(t *testing.T) {
+	coll := parseMedianRangeCollection(t, medianRangeCollection)
+	hall, ok := coll.Groups[0].Skeletons[0].(*gopolls.MoneyPollSkeleton)
+	if !ok {
+		t.Fatalf("Expected a money poll, got %v", coll.Groups[0].Skeletons[0])
+	}
+	if hall.Value.ValueCents != 500000 || hall.MinValue.ValueCents != 50000 || hall.Step.ValueCents != 5000 {
+		t.Errorf("Expected value 500000, min 50000 and step 5000, got %v, %v and %v",
+			hall.Value, hall.MinValue, hall.Step)
+	}
+	garden := coll.Groups[0].Skeletons[1].(*gopolls.MoneyPollSkeleton)
+	if garden.MinValue.ValueCents != 0 || garden.Step.ValueCents != 1000 {
+		t.Errorf("Expected no min value and step 1000, got %v and %v", garden.MinValue, garden.Step)
+	}
+
+	// dump and parse again
+	var builder strings.Builder
+	if _, dumpErr := coll.Dump(&builder, gopolls.DefaultCurrencyHandler); dumpErr != nil {
+		t.Fatalf("Unexpected error: %s", dumpErr)
+	}
+	if !strings.Contains(builder.String(), "[min 500.00 €, step 50.00 €]") {
+		t.Errorf("Expected restrictions in dump, got %s", builder.String())
+	}
+	parsed := parseMedianRangeCollection(t, builder.String())
+	parsedHall := parsed.Groups[0].Skeletons[0].(*gopolls.MoneyPollSkeleton)
+	if !parsedHall.MinValue.Equals(hall.MinValue) || !parsedHall.Step.Equals(hall.Step) {
+		t.Errorf("Expected restrictions to be kept in dump, got %v", parsedHall)
+	}
+}
+
+func TestParseMoneyPollRangeErrors(t *testing.T) {
+	tests := []struct {
+		line     string
+		semantic bool
+	}{
+		{"- 5,00 € [max 1,00 €]", false},
+		{"- 5,00 € [min]", false},
+		{"- 5,00 € [min abc]", false},
+		{"- 5,00 € [min 6,00 €]", true},
+		{"- 5,00 € [step 0,00 €]", true},
+		{"- 5,00 € [min 1,00 €, min 2,00 €]", true},
+	}
+	for _, tc := range tests {
+		s := "# Assembly\n\n## Budget\n\n### Hall\n" + tc.line + "\n"
+		_, err := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(gopolls.DefaultCurrencyHandler, s)
+		if err == nil {
+			t.Errorf("Expected an error for line \"%s\"", tc.line)
+			continue
+		}
+		var semanticErr gopolls.PollingSemanticError
+		if isSemantic := errors.As(err, &semanticErr); isSemantic != tc.semantic {
+			t.Errorf("Expected semantic error to be %v for line \"%s\", got %v", tc.semantic, tc.line, err)
+		}
+	}
+}
+
+func TestConvertMoneyPollRange(t *testing.T) {
+	coll := parseMedianRangeCollection(t, medianRangeCollection)
+	poll, err := gopolls.DefaultSkeletonConverter(coll.Groups[0].Skeletons[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	medianPoll, ok := poll.(*gopolls.MedianPoll)
+	if !ok {
+		t.Fatalf("Expected a median poll, got %v", poll)
+	}
+	if medianPoll.Value != 500000 || medianPoll.MinValue != 50000 || medianPoll.Step != 5000 {
+		t.Errorf("Expected value 500000, min 50000 and step 5000, got %d, %d and %d",
+			medianPoll.Value, medianPoll.MinValue, medianPoll.Step)
+	}
+}
+
+func TestMedianVoteParserRange(t *testing.T) {
+	poll := gopolls.NewMedianPoll(500000, nil)
+	poll.MinValue, poll.Step = 50000, 5000
+	customized, err := gopolls.NewMedianVoteParser(gopolls.DefaultCurrencyHandler).CustomizeForPoll(poll)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	parser := customized.(*gopolls.MedianVoteParser)
+	voter := gopolls.NewVoter("one", 1)
+	for _, valid := range []string{"0,00 €", "500,00 €", "550,00 €", "5000,00 €"} {
+		if _, parseErr := parser.ParseFromString(valid, voter); parseErr != nil {
+			t.Errorf("Expected \"%s\" to be valid, got error %s", valid, parseErr)
+		}
+	}
+	for _, invalid := range []string{"100,00 €", "520,00 €", "5050,00 €"} {
+		_, parseErr := parser.ParseFromString(invalid, voter)
+		var semanticErr gopolls.PollingSemanticError
+		if !errors.As(parseErr, &semanticErr) {
+			t.Errorf("Expected a semantic error for \"%s\", got %v", invalid, parseErr)
+		}
+	}
+	_, parseErr := parser.ParseFromString("520,00 €", voter)
+	if parseErr == nil || !strings.Contains(parseErr.Error(), "values >= 50000 in steps of 5000") {
+		t.Errorf("Expected error to explain the allowed values, got %v", parseErr)
+	}
+}
+
+func TestMedianValidateVoteRange(t *testing.T) {
+	poll := gopolls.NewMedianPoll(1000, nil)
+	poll.MinValue, poll.Step = 100, 50
+	voter := gopolls.NewVoter("one", 1)
+	tests := []struct {
+		vote  *gopolls.MedianVote
+		valid bool
+	}{
+		{gopolls.NewMedianVote(voter, 0), true},
+		{gopolls.NewMedianVote(voter, 150), true},
+		{gopolls.NewMedianVote(voter, 1000), true},
+		{gopolls.NewMedianAbstention(voter), true},
+		{gopolls.NewMedianVote(voter, 50), false},
+		{gopolls.NewMedianVote(voter, 175), false},
+	}
+	for _, tc := range tests {
+		if err := poll.ValidateVote(tc.vote); (err == nil) != tc.valid {
+			t.Errorf("Expected vote %d to be valid: %v, got error %v", tc.vote.Value, tc.valid, err)
+		}
+	}
+}
+
+func TestMedianTruncateVotersRoundOffStep(t *testing.T) {
+	voterOne := gopolls.NewVoter("one", 1)
+	voterTwo := gopolls.NewVoter("two", 2)
+	voterThree := gopolls.NewVoter("three", 3)
+	voterFour := gopolls.NewVoter("four", 4)
+
+	newPoll := func() *gopolls.MedianPoll {
+		poll := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(voterOne, 1200),
+			gopolls.NewMedianVote(voterTwo, 575),
+			gopolls.NewMedianVote(voterThree, 500),
+			gopolls.NewMedianVote(voterFour, 50),
+		})
+		poll.MinValue, poll.Step = 100, 200
+		poll.Sorted = true
+		return poll
+	}
+
+	// without RoundOffStep only values > poll.Value are truncated
+	poll := newPoll()
+	if culprits := poll.TruncateVoters(); len(culprits) != 1 || culprits[0].Voter.Name != "one" {
+		t.Errorf("Expected only voter one to be truncated, got %v", culprits)
+	}
+
+	poll = newPoll()
+	poll.RoundOffStep = true
+	culprits := poll.TruncateVoters()
+	if len(culprits) != 2 || culprits[0].Value != 1200 || culprits[1].Voter.Name != "two" || culprits[1].Value != 575 {
+		t.Fatalf("Expected voters one and two (with the original values) to be reported, got %v", culprits)
+	}
+	expected := []gopolls.MedianUnit{1000, 500, 500, 50}
+	for i, vote := range poll.Votes {
+		if vote.Value != expected[i] {
+			t.Errorf("Expected value %d for voter %s, got %d", expected[i], vote.Voter.Name, vote.Value)
+		}
+	}
+}
+
+func TestCollectionYAMLMoneyPollRange(t *testing.T) {
+	coll := parseMedianRangeCollection(t, medianRangeCollection)
+	yaml := dumpCollectionTesting(t, coll, true)
+	if !strings.Contains(yaml, "min: \"500.00 €\"") || !strings.Contains(yaml, "step: \"50.00 €\"") {
+		t.Errorf("Expected min value and step in yaml, got\n%s", yaml)
+	}
+	parsed, err := gopolls.NewPollCollectionParser().ParseCollectionYAMLFromString(gopolls.DefaultCurrencyHandler, yaml)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing yaml: %v\n%s", err, yaml)
+	}
+	for i, skel := range coll.Groups[0].Skeletons {
+		expected := skel.(*gopolls.MoneyPollSkeleton)
+		got := parsed.Groups[0].Skeletons[i].(*gopolls.MoneyPollSkeleton)
+		if !got.Value.Equals(expected.Value) || !got.MinValue.Equals(expected.MinValue) ||
+			!got.Step.Equals(expected.Step) {
+			t.Errorf("Expected money poll %+v, got %+v", expected, got)
+		}
+	}
+
+	tests := []struct {
+		poll     string
+		semantic bool
+	}{
+		{"        min: 1,00 €\n", false},
+		{"        value: 5,00 €\n        step: abc\n", false},
+		{"        value: 5,00 €\n        step: 0,00 €\n", true},
+		{"        value: 5,00 €\n        min: 6,00 €\n", true},
+	}
+	for _, tc := range tests {
+		in := "title: A\ngroups:\n  - title: G\n    polls:\n      - name: P\n" + tc.poll
+		_, parseErr := gopolls.NewPollCollectionParser().ParseCollectionYAMLFromString(gopolls.DefaultCurrencyHandler, in)
+		if parseErr == nil {
+			t.Errorf("Expected an error for input %q", in)
+			continue
+		}
+		var semanticErr gopolls.PollingSemanticError
+		if isSemantic := errors.As(parseErr, &semanticErr); isSemantic != tc.semantic {
+			t.Errorf("Expected semantic error to be %v for input %q, got %v", tc.semantic, in, parseErr)
+		}
+	}
+}
+
+func TestBuilderMoneyPollWithRange(t *testing.T) {
+	coll, err := gopolls.NewCollectionBuilder("Assembly").
+		Group("Budget").
+		MoneyPollWithRange("Hall", "5000,00 €", "500,00 €", "50,00 €").
+		MoneyPollWithRange("Garden", "1000,00 €", "", "").
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hall := coll.Groups[0].Skeletons[0].(*gopolls.MoneyPollSkeleton)
+	if hall.MinValue.ValueCents != 50000 || hall.Step.ValueCents != 5000 {
+		t.Errorf("Expected min 50000 and step 5000, got %v and %v", hall.MinValue, hall.Step)
+	}
+	garden := coll.Groups[0].Skeletons[1].(*gopolls.MoneyPollSkeleton)
+	if garden.MinValue.ValueCents != 0 || garden.Step.ValueCents != 0 {
+		t.Errorf("Expected no restrictions, got %v and %v", garden.MinValue, garden.Step)
+	}
+
+	_, err = gopolls.NewCollectionBuilder("Assembly").
+		Group("Budget").
+		MoneyPollWithRange("Hall", "5,00 €", "6,00 €", "").
+		Build()
+	var builderErr gopolls.CollectionBuilderError
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &builderErr) || builderErr.Step != 2 || !errors.As(err, &semanticErr) {
+		t.Errorf("Expected semantic builder error in step 2, got %v", err)
+	}
+}
@@ -16,6 +16,7 @@ package tests
 
 import (
 	"github.com/FabianWe/gopolls"
+	"math/big"
 	"testing"
 )
 
@@ -153,3 +154,230 @@ func TestMedianTruncateVoters(t *testing.T) {
 		t.Errorf("Choice for \"one\" should have been truncated to 150, got %d instead", poll.Votes[0].Value)
 	}
 }
+
+func TestMedianSetValue(t *testing.T) {
+	newPoll := func(value gopolls.MedianUnit) *gopolls.MedianPoll {
+		return gopolls.NewMedianPoll(value, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(gopolls.NewVoter("one", 1), 200),
+			gopolls.NewMedianVote(gopolls.NewVoter("two", 2), 150),
+			gopolls.NewMedianVote(gopolls.NewVoter("three", 3), 100),
+			gopolls.NewMedianAbstention(gopolls.NewVoter("four", 1)),
+		})
+	}
+	poll := newPoll(200)
+	if err := poll.SetValue(300, false); err != nil || poll.Value != 300 {
+		t.Errorf("Expected value 300 without error, got %d and %v", poll.Value, err)
+	}
+	if err := poll.SetValue(120, false); err == nil || poll.Value != 300 || poll.Votes[0].Value != 200 {
+		t.Errorf("Expected an error and an unchanged poll, got %v, value %d", err, poll.Value)
+	}
+	if err := poll.SetValue(120, true); err != nil {
+		t.Fatalf("Unexpected error setting value: %v", err)
+	}
+	if poll.Value != 120 || poll.Votes[0].Value != 120 || poll.Votes[1].Value != 120 || poll.Votes[2].Value != 100 {
+		t.Errorf("Expected votes to be truncated to 120, got %v", poll.Votes)
+	}
+	// the result must be the same as for a poll created with the new value and truncated votes
+	expected := newPoll(120)
+	expected.TruncateVoters()
+	res, expectedRes := poll.Tally(gopolls.NoWeight), expected.Tally(gopolls.NoWeight)
+	if res.MajorityValue != expectedRes.MajorityValue || res.WeightSum != expectedRes.WeightSum {
+		t.Errorf("Expected result %+v, got %+v", expectedRes, res)
+	}
+}
+
+func TestMedianDistribution(t *testing.T) {
+	voterOne := gopolls.NewVoter("one", 4)
+	voterTwo := gopolls.NewVoter("two", 3)
+	voterThree := gopolls.NewVoter("three", 2)
+	voterFour := gopolls.NewVoter("four", 1)
+
+	poll := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(voterOne, 200),
+		gopolls.NewMedianVote(voterTwo, 1000),
+		gopolls.NewMedianVote(voterThree, 700),
+		gopolls.NewMedianVote(voterFour, 700),
+	})
+
+	res := poll.Tally(gopolls.NoWeight)
+	distribution := res.Distribution()
+	expected := []gopolls.MedianDistributionEntry{
+		{Value: 1000, CumulativeWeight: 3, Percentage: big.NewRat(3, 10)},
+		{Value: 700, CumulativeWeight: 6, Percentage: big.NewRat(6, 10)},
+		{Value: 200, CumulativeWeight: 10, Percentage: big.NewRat(1, 1)},
+	}
+	if len(distribution) != len(expected) {
+		t.Fatalf("Expected distribution %v, got %v instead", expected, distribution)
+	}
+	for i, entry := range distribution {
+		expectedEntry := expected[i]
+		if entry.Value != expectedEntry.Value || entry.CumulativeWeight != expectedEntry.CumulativeWeight ||
+			entry.Percentage.Cmp(expectedEntry.Percentage) != 0 {
+			t.Errorf("Expected distribution entry %d to be %v, got %v instead", i, expectedEntry, entry)
+		}
+	}
+
+	if value := res.ValueForMajority(gopolls.NoWeight); value != res.MajorityValue {
+		t.Errorf("Expected value for default majority to be %d, got %d instead", res.MajorityValue, value)
+	}
+	twoThirds := gopolls.ComputeMajority(gopolls.TwoThirdsMajority, res.WeightSum)
+	if value := res.ValueForMajority(twoThirds); value != 200 {
+		t.Errorf("Expected value for two thirds majority to be 200, got %d instead", value)
+	}
+	if value := res.ValueForMajority(10); value != gopolls.NoMedianUnitValue {
+		t.Errorf("Expected no value for majority 10, got %d instead", value)
+	}
+}
+
+func TestMedianDistributionEmpty(t *testing.T) {
+	poll := gopolls.NewMedianPoll(1000, nil)
+	res := poll.Tally(gopolls.NoWeight)
+	if distribution := res.Distribution(); len(distribution) != 0 {
+		t.Errorf("Expected empty distribution, got %v instead", distribution)
+	}
+	if value := res.ValueForMajority(gopolls.NoWeight); value != gopolls.NoMedianUnitValue {
+		t.Errorf("Expected no value for empty poll, got %d instead", value)
+	}
+}
+
+func TestMedianAbstentions(t *testing.T) {
+	one, two, three, four := gopolls.NewVoter("one", 2), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 3),
+		gopolls.NewVoter("four", 4)
+	poll := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 1000),
+		gopolls.NewMedianVote(two, 500),
+		gopolls.NewMedianVote(three, 0),
+	})
+	vote, err := poll.GenerateVoteFromBasicAnswer(four, gopolls.Abstention)
+	if err != nil {
+		t.Fatalf("Unexpected error generating abstention: %v", err)
+	}
+	if asMedian := vote.(*gopolls.MedianVote); !asMedian.Equals(gopolls.NewMedianAbstention(four)) {
+		t.Errorf("Expected abstention, got %+v", asMedian)
+	}
+	// the value of an abstention is ignored
+	vote.(*gopolls.MedianVote).Value = 2000
+	if err = poll.ValidateVote(vote); err != nil {
+		t.Errorf("Unexpected error validating abstention: %v", err)
+	}
+	if err = poll.AddVote(vote); err != nil {
+		t.Fatalf("Unexpected error adding vote: %v", err)
+	}
+	if culprits := poll.TruncateVoters(); len(culprits) != 0 {
+		t.Errorf("Expected abstentions not to be truncated, got %v", culprits)
+	}
+	if sum := poll.WeightSum(); sum != 7 {
+		t.Errorf("Expected weight sum 7 without abstentions, got %d", sum)
+	}
+
+	res := poll.Tally(gopolls.NoWeight)
+	if res.Err != nil {
+		t.Fatalf("Unexpected error in result: %v", res.Err)
+	}
+	// with the abstention (weight 11) the majority would be 5 and the value 0, without it is 3 and the value 500
+	if res.WeightSum != 7 || res.AbstentionWeight != 4 || res.RequiredMajority != 3 || res.MajorityValue != 500 {
+		t.Errorf("Expected weight sum 7, abstention weight 4, majority 3 and value 500, got %d, %d, %d and %d",
+			res.WeightSum, res.AbstentionWeight, res.RequiredMajority, res.MajorityValue)
+	}
+	for value, voters := range res.ValueDetails {
+		for _, voter := range voters {
+			if voter == four {
+				t.Errorf("Abstention must not be in value details, found for value %d", value)
+			}
+		}
+	}
+}
+
+func TestMedianAbstentionEmptyVotePolicy(t *testing.T) {
+	voters := gopolls.VoterMap{
+		"one": gopolls.NewVoter("one", 1),
+		"two": gopolls.NewVoter("two", 1),
+	}
+	polls := gopolls.PollMap{"Budget": gopolls.NewMedianPoll(1000, nil)}
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "Budget"},
+		Body: [][]string{{"one", "5"}, {"two", ""}},
+	}
+	parsers := map[string]gopolls.VoteParser{
+		"Budget": gopolls.NewMedianVoteParser(gopolls.NewRawCentCurrencyParser()),
+	}
+	policies := gopolls.PolicyMap{"Budget": gopolls.AddAsAbstentionEmptyVote}
+	if _, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false); err != nil {
+		t.Fatalf("Unexpected error filling polls: %v", err)
+	}
+	votes := polls["Budget"].(*gopolls.MedianPoll).Votes
+	if len(votes) != 2 || votes[0].Abstain || !votes[1].Abstain {
+		t.Errorf("Expected a vote of one and an abstention of two, got %v", votes)
+	}
+}
+
+func TestMedianTruncateInTally(t *testing.T) {
+	one, two, three := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 1)
+	votes := []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 150), gopolls.NewMedianVote(two, 200), gopolls.NewMedianVote(three, 50),
+	}
+	poll := gopolls.NewMedianPoll(100, votes)
+	res := poll.Tally(gopolls.NoWeight)
+	if res.MajorityValue != 150 || res.TruncatedCount != 0 || res.TruncatedWeight != 0 {
+		t.Errorf("Expected majority value 150 without truncation, got %d (truncated %d, weight %d)",
+			res.MajorityValue, res.TruncatedCount, res.TruncatedWeight)
+	}
+
+	poll.TruncateInTally = true
+	res = poll.Tally(gopolls.NoWeight)
+	if res.MajorityValue != 100 {
+		t.Errorf("Expected majority value 100, got %d", res.MajorityValue)
+	}
+	if res.TruncatedCount != 2 || res.TruncatedWeight != 3 {
+		t.Errorf("Expected 2 truncated votes with weight 3, got %d and %d", res.TruncatedCount, res.TruncatedWeight)
+	}
+	if len(res.ValueDetails[100]) != 2 || len(res.ValueDetails) != 2 {
+		t.Errorf("Expected truncated values in details, got %v", res.ValueDetails)
+	}
+	// the votes are not changed
+	for _, vote := range poll.Votes {
+		if vote.Voter == two && vote.Value != 200 {
+			t.Errorf("Expected vote of two to be unchanged, got %d", vote.Value)
+		}
+	}
+	// the counters match TruncateVoters
+	culprits := poll.DeepClone().TruncateVoters()
+	if len(culprits) != res.TruncatedCount {
+		t.Errorf("TruncateVoters found %d votes, Tally truncated %d", len(culprits), res.TruncatedCount)
+	}
+}
+
+func TestMedianNextHigherValue(t *testing.T) {
+	one, two, three, four := gopolls.NewVoter("one", 2), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 2),
+		gopolls.NewVoter("four", 1)
+	tests := []struct {
+		name           string
+		votes          []*gopolls.MedianVote
+		expectedValue  gopolls.MedianUnit
+		expectedWeight gopolls.Weight
+		expectedVoter  *gopolls.Voter
+	}{
+		{"no votes", nil, gopolls.NoMedianUnitValue, gopolls.NoWeight, nil},
+		{"highest value wins", []*gopolls.MedianVote{
+			gopolls.NewMedianVote(one, 500), gopolls.NewMedianVote(four, 100),
+		}, gopolls.NoMedianUnitValue, gopolls.NoWeight, one},
+		{"one voter per value", []*gopolls.MedianVote{
+			gopolls.NewMedianVote(one, 700), gopolls.NewMedianVote(two, 500), gopolls.NewMedianVote(four, 0),
+		}, 700, 2, two},
+		// the majority is 3, so the second vote for 500 is not required
+		{"same value at the boundary", []*gopolls.MedianVote{
+			gopolls.NewMedianVote(four, 0), gopolls.NewMedianVote(three, 500), gopolls.NewMedianVote(two, 500),
+			gopolls.NewMedianVote(one, 700),
+		}, 700, 2, three},
+	}
+	for _, tc := range tests {
+		res := gopolls.NewMedianPoll(1000, tc.votes).Tally(gopolls.NoWeight)
+		if res.NextHigherValue != tc.expectedValue || res.NextHigherWeight != tc.expectedWeight {
+			t.Errorf("%s: expected next higher value %d with weight %d, got %d with weight %d", tc.name,
+				tc.expectedValue, tc.expectedWeight, res.NextHigherValue, res.NextHigherWeight)
+		}
+		if res.MarginalVoter != tc.expectedVoter {
+			t.Errorf("%s: expected marginal voter %v, got %v", tc.name, tc.expectedVoter, res.MarginalVoter)
+		}
+	}
+}
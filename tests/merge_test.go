@@ -0,0 +1,309 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// mergeTestVoters returns the voters one, two and three.
+func mergeTestVoters() (*gopolls.Voter, *gopolls.Voter, *gopolls.Voter) {
+	return gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 3)
+}
+
+// voterNames returns the names of the voters of all votes in poll.
+func voterNames(t *testing.T, poll gopolls.AbstractPoll) []string {
+	votes, err := gopolls.CollectVotes(poll)
+	if err != nil {
+		t.Fatalf("Unexpected error collecting votes: %s", err)
+	}
+	res := make([]string, len(votes))
+	for i, vote := range votes {
+		res[i] = vote.GetVoter().Name
+	}
+	return res
+}
+
+func TestMergeBasicPolls(t *testing.T) {
+	one, two, three := mergeTestVoters()
+	newPolls := func() (*gopolls.BasicPoll, *gopolls.BasicPoll) {
+		dst := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(one, gopolls.Aye),
+			gopolls.NewBasicVote(two, gopolls.No),
+		})
+		src := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(two, gopolls.Abstention),
+			gopolls.NewBasicVote(three, gopolls.Aye),
+		})
+		return dst, src
+	}
+
+	dst, src := newPolls()
+	err := gopolls.MergePolls(dst, src, gopolls.RejectDuplicateVoters)
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError, got %v", err)
+	}
+	if len(dst.Votes) != 2 {
+		t.Errorf("Expected dst to be unchanged after an error, got %d votes", len(dst.Votes))
+	}
+
+	dst, src = newPolls()
+	if err = gopolls.MergePolls(dst, src, gopolls.KeepFirstDuplicateVoter); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if names := voterNames(t, dst); !reflect.DeepEqual(names, []string{"one", "two", "three"}) {
+		t.Errorf("Expected voters one, two and three, got %v", names)
+	}
+	if dst.Votes[1].Choice != gopolls.No {
+		t.Errorf("Expected the vote of two in dst to be kept, got %v", dst.Votes[1].Choice)
+	}
+
+	dst, src = newPolls()
+	if err = gopolls.MergePolls(dst, src, gopolls.KeepLastDuplicateVoter); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if names := voterNames(t, dst); !reflect.DeepEqual(names, []string{"one", "two", "three"}) {
+		t.Errorf("Expected voters one, two and three, got %v", names)
+	}
+	if dst.Votes[1].Choice != gopolls.Abstention {
+		t.Errorf("Expected the vote of two to be overwritten, got %v", dst.Votes[1].Choice)
+	}
+
+	dst, src = newPolls()
+	if err = gopolls.MergePolls(dst, src, gopolls.NoDuplicateVoterCheck); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(dst.Votes) != 4 {
+		t.Errorf("Expected all four votes without a duplicate check, got %d", len(dst.Votes))
+	}
+
+	dst, src = newPolls()
+	var semanticErr gopolls.PollingSemanticError
+	if err = gopolls.MergePolls(dst, src, gopolls.MergeDuplicateVoters); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for MergeDuplicateVoters, got %v", err)
+	}
+}
+
+func TestMergeMedianPolls(t *testing.T) {
+	one, two, three := mergeTestVoters()
+	newPolls := func() (*gopolls.MedianPoll, *gopolls.MedianPoll) {
+		dst := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(one, 1000),
+			gopolls.NewMedianVote(two, 500),
+		})
+		dst.SortVotes()
+		src := gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(two, 800),
+			gopolls.NewMedianVote(three, 200),
+		})
+		return dst, src
+	}
+
+	dst, src := newPolls()
+	var duplicateErr gopolls.DuplicateError
+	if err := gopolls.MergePolls(dst, src, gopolls.RejectDuplicateVoters); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError, got %v", err)
+	}
+	if !dst.Sorted || len(dst.Votes) != 2 {
+		t.Errorf("Expected dst to be unchanged after an error")
+	}
+
+	dst, src = newPolls()
+	if err := gopolls.MergePolls(dst, src, gopolls.KeepFirstDuplicateVoter); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if dst.Sorted {
+		t.Error("Expected Sorted to be false after merging")
+	}
+	if len(dst.Votes) != 3 || dst.Votes[1].Value != 500 {
+		t.Errorf("Expected three votes with the value 500 of two kept, got %v", dst.Votes)
+	}
+
+	dst, src = newPolls()
+	if err := gopolls.MergePolls(dst, src, gopolls.KeepLastDuplicateVoter); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(dst.Votes) != 3 || dst.Votes[1].Value != 800 {
+		t.Errorf("Expected three votes with the value 800 of two, got %v", dst.Votes)
+	}
+	if res := dst.Tally(gopolls.NoWeight); res.MajorityValue != 200 {
+		t.Errorf("Expected majority value 200 after merging, got %d", res.MajorityValue)
+	}
+
+	dst, _ = newPolls()
+	other := gopolls.NewMedianPoll(2000, nil)
+	var semanticErr gopolls.PollingSemanticError
+	if err := gopolls.MergePolls(dst, other, gopolls.RejectDuplicateVoters); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for different values, got %v", err)
+	}
+}
+
+func TestMergeSchulzePolls(t *testing.T) {
+	one, two, three := mergeTestVoters()
+	newPolls := func() (*gopolls.SchulzePoll, *gopolls.SchulzePoll) {
+		dst := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 2}),
+			gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{0, 1, 2}),
+		})
+		src := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(three, gopolls.SchulzeRanking{2, 1, 0}),
+			gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{2, 0, 1}),
+		})
+		return dst, src
+	}
+
+	dst, src := newPolls()
+	var duplicateErr gopolls.DuplicateError
+	if err := gopolls.MergePolls(dst, src, gopolls.RejectDuplicateVoters); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError, got %v", err)
+	}
+
+	dst, src = newPolls()
+	if err := gopolls.MergePolls(dst, src, gopolls.KeepFirstDuplicateVoter); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if names := voterNames(t, dst); !reflect.DeepEqual(names, []string{"one", "two", "three"}) {
+		t.Errorf("Expected voters one, two and three, got %v", names)
+	}
+	if !reflect.DeepEqual(dst.Votes[1].Ranking, gopolls.SchulzeRanking{0, 1, 2}) {
+		t.Errorf("Expected the ranking of two in dst to be kept, got %v", dst.Votes[1].Ranking)
+	}
+
+	dst, src = newPolls()
+	if err := gopolls.MergePolls(dst, src, gopolls.KeepLastDuplicateVoter); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(dst.Votes[1].Ranking, gopolls.SchulzeRanking{2, 0, 1}) {
+		t.Errorf("Expected the ranking of two to be overwritten, got %v", dst.Votes[1].Ranking)
+	}
+
+	dst, _ = newPolls()
+	var semanticErr gopolls.PollingSemanticError
+	err := gopolls.MergePolls(dst, gopolls.NewSchulzePoll(4, nil), gopolls.RejectDuplicateVoters)
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for a different number of options, got %v", err)
+	}
+}
+
+func TestMergePollsTypes(t *testing.T) {
+	basic := gopolls.NewBasicPoll(nil)
+	median := gopolls.NewMedianPoll(100, nil)
+	var typeErr gopolls.PollTypeError
+	if err := gopolls.MergePolls(basic, median, gopolls.RejectDuplicateVoters); !errors.As(err, &typeErr) {
+		t.Errorf("Expected a PollTypeError for different types, got %v", err)
+	}
+
+	one, _, _ := mergeTestVoters()
+	src := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye)})
+	syncDst := gopolls.NewSynchronizedPoll(basic)
+	if err := gopolls.MergePolls(syncDst, gopolls.NewSynchronizedPoll(src), gopolls.RejectDuplicateVoters); err != nil {
+		t.Fatalf("Unexpected error merging synchronized polls: %s", err)
+	}
+	if len(basic.Votes) != 1 {
+		t.Errorf("Expected one vote after merging synchronized polls, got %d", len(basic.Votes))
+	}
+}
+
+func TestMergePollsSameAndConcurrent(t *testing.T) {
+	one, two, _ := mergeTestVoters()
+	basic := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye)})
+	syncPoll := gopolls.NewSynchronizedPoll(basic)
+	var semanticErr gopolls.PollingSemanticError
+	for _, dst := range []gopolls.AbstractPoll{basic, syncPoll} {
+		for _, src := range []gopolls.AbstractPoll{basic, syncPoll} {
+			if err := gopolls.MergePolls(dst, src, gopolls.NoDuplicateVoterCheck); !errors.As(err, &semanticErr) {
+				t.Errorf("Expected a PollingSemanticError merging a poll into itself, got %v", err)
+			}
+		}
+	}
+
+	// merging in both directions concurrently must not deadlock
+	a := gopolls.NewSynchronizedPoll(gopolls.NewBasicPoll(nil))
+	b := gopolls.NewSynchronizedPoll(gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(two, gopolls.No)}))
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = gopolls.MergePolls(a, b, gopolls.KeepFirstDuplicateVoter)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = gopolls.MergePolls(b, a, gopolls.KeepFirstDuplicateVoter)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMergePollsSealedVotes(t *testing.T) {
+	one, two, _ := mergeTestVoters()
+	dst := gopolls.NewBasicPoll(nil)
+	src := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye)})
+	src.AddSealed(gopolls.NewSealedVote(two, "+"))
+	var semanticErr gopolls.PollingSemanticError
+	if err := gopolls.MergePolls(dst, src, gopolls.RejectDuplicateVoters); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for sealed votes in src, got %v", err)
+	}
+	if len(dst.Votes) != 0 {
+		t.Errorf("Expected dst to be unchanged, got %d votes", len(dst.Votes))
+	}
+	err := gopolls.MergePollMaps(gopolls.PollMap{"poll": dst}, gopolls.PollMap{"poll": src},
+		gopolls.RejectDuplicateVoters)
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for sealed votes in src, got %v", err)
+	}
+}
+
+func TestMergePollMaps(t *testing.T) {
+	one, two, _ := mergeTestVoters()
+	dst := gopolls.PollMap{
+		"Motion": gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye)}),
+		"Budget": gopolls.NewMedianPoll(100, []*gopolls.MedianVote{gopolls.NewMedianVote(one, 100)}),
+		"Other":  gopolls.NewBasicPoll(nil),
+	}
+	src := gopolls.PollMap{
+		"Motion": gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(two, gopolls.No)}),
+		"Budget": gopolls.NewMedianPoll(100, []*gopolls.MedianVote{gopolls.NewMedianVote(two, 50)}),
+	}
+	if err := gopolls.MergePollMaps(dst, src, gopolls.RejectDuplicateVoters); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, name := range []string{"Motion", "Budget"} {
+		if names := voterNames(t, dst[name]); !reflect.DeepEqual(names, []string{"one", "two"}) {
+			t.Errorf("Expected voters one and two in poll %s, got %v", name, names)
+		}
+	}
+
+	src["Missing"] = gopolls.NewBasicPoll(nil)
+	var semanticErr gopolls.PollingSemanticError
+	if err := gopolls.MergePollMaps(dst, src, gopolls.KeepFirstDuplicateVoter); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected a PollingSemanticError for a missing poll, got %v", err)
+	}
+	if names := voterNames(t, dst["Motion"]); len(names) != 2 {
+		t.Errorf("Expected no poll to be changed if a poll is missing, got %v", names)
+	}
+
+	delete(src, "Missing")
+	src["Motion"] = gopolls.NewMedianPoll(100, nil)
+	var typeErr gopolls.PollTypeError
+	if err := gopolls.MergePollMaps(dst, src, gopolls.KeepFirstDuplicateVoter); !errors.As(err, &typeErr) {
+		t.Errorf("Expected a PollTypeError for different types, got %v", err)
+	}
+}
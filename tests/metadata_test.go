@@ -0,0 +1,177 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const metadataCollection = `# Meeting
+
+## Finances
+
+### Budget [majority=2/3]
+agenda: 3.1
+speaker: Jane Doe
+- 1000.00 €
+
+### Hall
+db-id: 42
+* Yes
+* No
+
+`
+
+func TestParseCollectionMetadata(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	parser.ParseMetadata = true
+	coll, err := parser.ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{}, metadataCollection)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	skels := coll.CollectSkeletons()
+	budget := skels[0].(*gopolls.MoneyPollSkeleton)
+	if expected := map[string]string{"agenda": "3.1", "speaker": "Jane Doe"}; !reflect.DeepEqual(budget.Meta, expected) {
+		t.Errorf("Expected metadata %v, got %v", expected, budget.Meta)
+	}
+	hall := skels[1].(*gopolls.PollSkeleton)
+	if expected := map[string]string{"db-id": "42"}; !reflect.DeepEqual(hall.Meta, expected) {
+		t.Errorf("Expected metadata %v, got %v", expected, hall.Meta)
+	}
+
+	var buf strings.Builder
+	if _, dumpErr := coll.Dump(&buf, gopolls.SimpleEuroHandler{}); dumpErr != nil {
+		t.Fatalf("Unexpected error dumping collection: %v", dumpErr)
+	}
+	if buf.String() != metadataCollection {
+		t.Errorf("Expected dump\n%s\ngot\n%s", metadataCollection, buf.String())
+	}
+
+	// without the flag metadata lines are not allowed
+	_, syntaxErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{},
+		metadataCollection)
+	var pollingSyntaxErr gopolls.PollingSyntaxError
+	if !errors.As(syntaxErr, &pollingSyntaxErr) {
+		t.Errorf("Expected PollingSyntaxError without ParseMetadata, got %v", syntaxErr)
+	}
+
+	duplicate := strings.Replace(metadataCollection, "db-id: 42\n", "db-id: 42\ndb-id: 21\n", 1)
+	_, duplicateErr := parser.ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{}, duplicate)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(duplicateErr, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for duplicate key, got %v", duplicateErr)
+	}
+}
+
+func TestDumpInvalidMetadata(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	parser.ParseMetadata = true
+	for _, meta := range []map[string]string{
+		{"bad key": "value"},
+		{"key": "two\n* lines"},
+		{"key": " padded"},
+	} {
+		coll, err := parser.ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{}, metadataCollection)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing collection: %v", err)
+		}
+		coll.Groups[0].Skeletons[1].(*gopolls.PollSkeleton).Meta = meta
+		var syntaxErr gopolls.PollingSyntaxError
+		if _, dumpErr := coll.Dump(ioutil.Discard, gopolls.SimpleEuroHandler{}); !errors.As(dumpErr, &syntaxErr) {
+			t.Errorf("Expected PollingSyntaxError dumping metadata %v, got %v", meta, dumpErr)
+		}
+		if _, dumpErr := coll.DumpYAML(ioutil.Discard, gopolls.SimpleEuroHandler{}); !errors.As(dumpErr, &syntaxErr) {
+			t.Errorf("Expected PollingSyntaxError dumping metadata %v as yaml, got %v", meta, dumpErr)
+		}
+	}
+}
+
+func TestCollectionYAMLMetadata(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	parser.ParseMetadata = true
+	coll, err := parser.ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{}, metadataCollection)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	var buf strings.Builder
+	if _, dumpErr := coll.DumpYAML(&buf, gopolls.SimpleEuroHandler{}); dumpErr != nil {
+		t.Fatalf("Unexpected error dumping collection: %v", dumpErr)
+	}
+	if !strings.Contains(buf.String(), "        meta:\n          agenda: \"3.1\"\n          speaker: \"Jane Doe\"\n") {
+		t.Errorf("Expected metadata in yaml, got\n%s", buf.String())
+	}
+	parsed, parseErr := parser.ParseCollectionYAMLFromString(gopolls.SimpleEuroHandler{}, buf.String())
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing yaml: %v", parseErr)
+	}
+	for i, skel := range parsed.CollectSkeletons() {
+		expected := coll.CollectSkeletons()[i].(gopolls.MetadataHolder).GetMeta()
+		if got := skel.(gopolls.MetadataHolder).GetMeta(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected metadata %v, got %v", expected, got)
+		}
+	}
+
+	// without the flag the key is not allowed
+	var syntaxErr gopolls.PollingSyntaxError
+	_, parseErr = gopolls.NewPollCollectionParser().ParseCollectionYAMLFromString(gopolls.SimpleEuroHandler{},
+		buf.String())
+	if !errors.As(parseErr, &syntaxErr) || !strings.Contains(parseErr.Error(), "meta") {
+		t.Errorf("Expected PollingSyntaxError for meta without ParseMetadata, got %v", parseErr)
+	}
+	invalid := "title: T\ngroups:\n  - title: G\n    polls:\n      - name: P\n        meta:\n          bad key: x\n" +
+		"        options: [a, b]\n"
+	if _, parseErr = parser.ParseCollectionYAMLFromString(nil, invalid); !errors.As(parseErr, &syntaxErr) {
+		t.Errorf("Expected PollingSyntaxError for invalid metadata key, got %v", parseErr)
+	}
+}
+
+func TestMetadataConversionAndTally(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	parser.ParseMetadata = true
+	coll, err := parser.ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{}, metadataCollection)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	skelMap, _ := coll.SkeletonsToMap()
+	polls, convertErr := gopolls.ConvertSkeletonMapToEmptyPolls(skelMap, nil)
+	if convertErr != nil {
+		t.Fatalf("Unexpected error converting skeletons: %v", convertErr)
+	}
+	hall := polls["Hall"].(*gopolls.BasicPoll)
+	if hall.Meta["db-id"] != "42" {
+		t.Errorf("Expected metadata to be copied to the poll, got %v", hall.Meta)
+	}
+	// the poll has its own copy
+	hall.Meta["db-id"] = "43"
+	if skelMap["Hall"].(*gopolls.PollSkeleton).Meta["db-id"] != "42" {
+		t.Error("Changing the metadata of the poll must not change the skeleton")
+	}
+	if clone := hall.DeepClone(); !reflect.DeepEqual(clone.Meta, hall.Meta) {
+		t.Errorf("Expected DeepClone to copy the metadata, got %v", clone.Meta)
+	}
+
+	groups, tallyErr := gopolls.TallyCollection(coll, polls)
+	if tallyErr != nil {
+		t.Fatalf("Unexpected error tallying collection: %v", tallyErr)
+	}
+	if meta := groups[0].Polls[0].Meta; meta["speaker"] != "Jane Doe" {
+		t.Errorf("Expected metadata in tallied poll, got %v", meta)
+	}
+}
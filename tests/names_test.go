@@ -0,0 +1,163 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"strings"
+	"testing"
+)
+
+const (
+	// "René" with a precomposed é (NFC) and with e followed by a combining acute accent (NFD)
+	reneNFC = "Ren\u00e9"
+	reneNFD = "Rene\u0301"
+)
+
+func TestNameNormalizer(t *testing.T) {
+	tests := []struct {
+		normalizer *gopolls.NameNormalizer
+		in         string
+		expected   string
+	}{
+		{nil, " Alice  Smith ", " Alice  Smith "},
+		{&gopolls.NameNormalizer{TrimSpace: true}, " Alice  Smith ", "Alice  Smith"},
+		{&gopolls.NameNormalizer{CollapseWhitespace: true}, " Alice \t Smith ", "Alice Smith"},
+		{&gopolls.NameNormalizer{NFC: true}, reneNFD, reneNFC},
+		{&gopolls.NameNormalizer{NFC: true}, reneNFC, reneNFC},
+		{&gopolls.NameNormalizer{}, reneNFD, reneNFD},
+		{&gopolls.NameNormalizer{CaseFold: true}, "ALICE", "alice"},
+		{&gopolls.NameNormalizer{CaseFold: true, NFC: true}, "RENE\u0301", "ren\u00e9"},
+		{gopolls.NewNameNormalizer(), " ALICE ", "ALICE"},
+	}
+	for _, tc := range tests {
+		if got := tc.normalizer.Normalize(tc.in); got != tc.expected {
+			t.Errorf("Normalizing %q with %+v: expected %q, got %q", tc.in, tc.normalizer, tc.expected, got)
+		}
+	}
+}
+
+func TestNameNormalizerVotersAndMatrix(t *testing.T) {
+	normalizer := gopolls.NewNameNormalizer()
+	votersParser := gopolls.NewVotersParser()
+	votersParser.NameNormalizer = normalizer
+	votersParser.DuplicatePolicy = gopolls.RejectDuplicateVoters
+	voters, err := votersParser.ParseVotersFromString("* " + reneNFC + ": 1\n* Alice   Smith: 2\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing voters: %v", err)
+	}
+	if voters[1].Name != "Alice Smith" {
+		t.Errorf("Expected normalized name \"Alice Smith\", got %q", voters[1].Name)
+	}
+	// duplicates are detected on the normalized names
+	var duplicateErr gopolls.DuplicateError
+	if _, err := votersParser.ParseVotersFromString("* " + reneNFC + "\n* " + reneNFD + "\n"); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected duplicate error for NFC / NFD names, got %v", err)
+	}
+
+	voterMap, err := gopolls.VotersToMapWithNormalizer(voters, normalizer)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	polls := gopolls.PollMap{"Motion": gopolls.NewBasicPoll(nil)}
+	parsers := map[string]gopolls.VoteParser{"Motion": gopolls.NewBasicVoteParser()}
+	policies := gopolls.PolicyMap{"Motion": gopolls.IgnoreEmptyVote}
+	csvIn := "voter,Motion\n" + reneNFD + " ,aye\nAlice Smith,no\n"
+
+	// without normalization the decomposed name is not found
+	plainMatrix, err := gopolls.ReadMatrixFromCSV(gopolls.NewVotesCSVReader(strings.NewReader(csvIn)))
+	if err != nil {
+		t.Fatalf("Unexpected error reading csv: %v", err)
+	}
+	var semanticErr gopolls.PollingSemanticError
+	if _, _, err := plainMatrix.FillPollsWithVotes(polls, voterMap, parsers, policies, false, false); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected voter not found error without normalization, got %v", err)
+	}
+
+	reader := gopolls.NewVotesCSVReader(strings.NewReader(csvIn))
+	reader.NameNormalizer = normalizer
+	matrix, err := gopolls.ReadMatrixFromCSV(reader)
+	if err != nil {
+		t.Fatalf("Unexpected error reading csv: %v", err)
+	}
+	if _, _, err := matrix.FillPollsWithVotes(polls, voterMap, parsers, policies, false, false); err != nil {
+		t.Fatalf("Unexpected error filling polls: %v", err)
+	}
+	if votes := polls["Motion"].(*gopolls.BasicPoll).Votes; len(votes) != 2 {
+		t.Errorf("Expected two votes, got %d", len(votes))
+	}
+
+	// normalizing an existing matrix works the same
+	plainMatrix.NormalizeVoterNames(normalizer)
+	if plainMatrix.Body[0][0] != reneNFC {
+		t.Errorf("Expected normalized name %q, got %q", reneNFC, plainMatrix.Body[0][0])
+	}
+
+	// duplicates in VotersToMapWithNormalizer
+	duplicates := []*gopolls.Voter{gopolls.NewVoter(reneNFC, 1), gopolls.NewVoter(reneNFD, 1)}
+	if _, err := gopolls.VotersToMap(duplicates); err != nil {
+		t.Errorf("Expected no error without normalization, got %v", err)
+	}
+	if _, err := gopolls.VotersToMapWithNormalizer(duplicates, normalizer); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+	if duplicates[1].Name != reneNFD {
+		t.Errorf("Expected names to be unchanged after an error, got %q", duplicates[1].Name)
+	}
+
+	// names of the voters are normalized as well
+	spaced := []*gopolls.Voter{gopolls.NewVoter(" Bob  Smith ", 1), gopolls.NewVoter(reneNFD, 1)}
+	spacedMap, err := gopolls.VotersToMapWithNormalizer(spaced, normalizer)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for name, voter := range spacedMap {
+		if voter.Name != name {
+			t.Errorf("Expected voter name %q to equal the key %q", voter.Name, name)
+		}
+	}
+	if spacedMap["Bob Smith"] == nil || spacedMap[reneNFC] == nil {
+		t.Errorf("Expected normalized keys, got %v", spacedMap)
+	}
+	if spaced[0].Name != " Bob  Smith " || spaced[1].Name != reneNFD {
+		t.Errorf("Expected voters not to be changed, got %q and %q", spaced[0].Name, spaced[1].Name)
+	}
+	carol := gopolls.NewVoter("Carol", 1)
+	carolMap, err := gopolls.VotersToMapWithNormalizer([]*gopolls.Voter{carol}, normalizer)
+	if err != nil || carolMap["Carol"] != carol {
+		t.Errorf("Expected voters with a normalized name to be stored directly, got %v (%v)", carolMap, err)
+	}
+}
+
+func TestNameNormalizerVotersCSV(t *testing.T) {
+	in := "name,weight\n" + reneNFC + ",1\n" + reneNFD + " ,2\n Bob  Smith,1\n"
+	reader := gopolls.NewVotersCSVReader(strings.NewReader(in))
+	reader.DuplicatePolicy = gopolls.RejectDuplicateVoters
+	if _, err := reader.ReadVoters(); err != nil {
+		t.Fatalf("Expected no duplicate without normalization, got %v", err)
+	}
+
+	reader = gopolls.NewVotersCSVReader(strings.NewReader(in))
+	reader.NameNormalizer = gopolls.NewNameNormalizer()
+	reader.DuplicatePolicy = gopolls.MergeDuplicateVoters
+	voters, err := reader.ReadVoters()
+	if err != nil {
+		t.Fatalf("Unexpected error reading voters: %v", err)
+	}
+	if len(voters) != 2 || voters[0].Name != reneNFC || voters[0].Weight != 3 || voters[1].Name != "Bob Smith" {
+		t.Errorf("Expected normalized and merged voters, got %v", voters)
+	}
+}
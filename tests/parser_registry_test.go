@@ -0,0 +1,127 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+func TestParserRegistryRegister(t *testing.T) {
+	registry := gopolls.DefaultRegistry()
+	expectedTypes := []string{gopolls.BasicPollType, gopolls.MedianPollType, gopolls.SchulzePollType,
+		gopolls.ScorePollType}
+	if types := registry.PollTypes(); len(types) != len(expectedTypes) {
+		t.Errorf("Expected poll types %v, got %v", expectedTypes, types)
+	}
+	var duplicateErr gopolls.DuplicateError
+	if err := registry.Register(gopolls.BasicPollType, gopolls.NewBasicVoteParser()); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError registering basic poll twice, got %v", err)
+	}
+	// the default registry is a fresh registry each time and doesn't change the default map
+	if err := registry.Register("experimental-poll", gopolls.NewBasicVoteParser()); err != nil {
+		t.Fatalf("Unexpected error registering template: %v", err)
+	}
+	if _, has := gopolls.DefaultRegistry().Template("experimental-poll"); has {
+		t.Errorf("Registering a template must not change other registries")
+	}
+	if _, has := gopolls.DefaultParserTemplateMap["experimental-poll"]; has {
+		t.Errorf("Registering a template must not change DefaultParserTemplateMap")
+	}
+
+	if !registry.Unregister(gopolls.BasicPollType) || registry.Unregister(gopolls.BasicPollType) {
+		t.Errorf("Expected basic poll to be unregistered exactly once")
+	}
+	if err := registry.Register(gopolls.BasicPollType, gopolls.NewBasicVoteParser()); err != nil {
+		t.Errorf("Unexpected error registering basic poll again: %v", err)
+	}
+
+	empty := gopolls.NewParserRegistry()
+	if len(empty.PollTypes()) != 0 || len(empty.Map()) != 0 {
+		t.Errorf("Expected an empty registry, got %v", empty.PollTypes())
+	}
+}
+
+func TestParserRegistryClone(t *testing.T) {
+	registry := gopolls.NewParserRegistry()
+	basicTemplate := gopolls.NewBasicVoteParser()
+	if err := registry.Register(gopolls.BasicPollType, basicTemplate); err != nil {
+		t.Fatalf("Unexpected error registering template: %v", err)
+	}
+	clone := registry.Clone()
+	if err := clone.Register(gopolls.MedianPollType, gopolls.NewMedianVoteParser(nil)); err != nil {
+		t.Fatalf("Unexpected error registering template: %v", err)
+	}
+	if _, has := registry.Template(gopolls.MedianPollType); has {
+		t.Errorf("Registering a template in the clone must not change the original registry")
+	}
+	if template, _ := clone.Template(gopolls.BasicPollType); template != basicTemplate {
+		t.Errorf("Expected the clone to contain the template of the original registry")
+	}
+	if !reflect.DeepEqual(clone.PollTypes(), []string{gopolls.BasicPollType, gopolls.MedianPollType}) {
+		t.Errorf("Expected basic and median poll in the clone, got %v", clone.PollTypes())
+	}
+}
+
+func TestParserRegistryCustomize(t *testing.T) {
+	registry := gopolls.DefaultRegistry()
+	polls := gopolls.PollMap{
+		"basic":        gopolls.NewBasicPoll(nil),
+		"median":       gopolls.NewMedianPoll(100, nil),
+		"experimental": &experimentalPoll{},
+	}
+	var typeErr gopolls.PollTypeError
+	if _, err := registry.CustomizeAll(polls); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError for poll without template, got %v", err)
+	}
+	customizers, skipped, err := registry.CustomizeAllWithOptions(polls,
+		gopolls.CustomizeOptions{SkipMissingTemplates: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(customizers) != 2 || len(skipped) != 1 || skipped[0].Name != "experimental" {
+		t.Errorf("Expected experimental poll to be skipped, got %v and %v", customizers, skipped)
+	}
+
+	if err := registry.Register("experimental-poll", gopolls.NewBasicVoteParser()); err != nil {
+		t.Fatalf("Unexpected error registering template: %v", err)
+	}
+	// the basic parser can't be customized for the experimental poll
+	if _, err := registry.CustomizeAll(polls); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError customizing the basic parser, got %v", err)
+	}
+	delete(polls, "experimental")
+	customizers, err = registry.CustomizeAll(polls)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vote, err := customizers["median"].ParseFromString("101", gopolls.NewVoter("one", 1))
+	if err == nil {
+		t.Errorf("Expected median parser to be customized with the value of the poll, got %v", vote)
+	}
+
+	// the result must be the same as with the plain map
+	list := []gopolls.AbstractPoll{polls["basic"], polls["median"]}
+	fromRegistry, registryErr := registry.Customize(list)
+	fromMap, mapErr := gopolls.CustomizeParsers(list, registry.Map())
+	if registryErr != nil || mapErr != nil {
+		t.Fatalf("Unexpected errors: %v, %v", registryErr, mapErr)
+	}
+	if !reflect.DeepEqual(fromRegistry, fromMap) {
+		t.Errorf("Expected same parsers from registry and map, got %v and %v", fromRegistry, fromMap)
+	}
+}
@@ -0,0 +1,1077 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const bom = "\xEF\xBB\xBF"
+
+func TestParseVotersBOMAndCRLF(t *testing.T) {
+	in := bom + "* one: 2\r\n# c\r\n\r\n* two\r\n"
+	parser := gopolls.NewVotersParser()
+	// first line is exactly 8 bytes long, the BOM must not count
+	parser.MaxLineLength = 8
+	voters, err := parser.ParseVotersFromString(in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing voters: %v", err)
+	}
+	expected := []*gopolls.Voter{gopolls.NewVoter("one", 2), gopolls.NewVoter("two", 1)}
+	if len(voters) != len(expected) {
+		t.Fatalf("Expected voters %v, got %v instead", expected, voters)
+	}
+	for i, voter := range voters {
+		if !voter.Equals(expected[i]) {
+			t.Errorf("Expected voter %v, got %v instead", expected[i], voter)
+		}
+	}
+}
+
+func TestParseCollectionBOMAndCRLF(t *testing.T) {
+	in := bom + "# Title\r\n## Group\r\n### Poll\r\n* yes\r\n* no\r\n### Money\r\n- 42 €\r\n"
+	parser := gopolls.NewPollCollectionParser()
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	if coll.Title != "Title" {
+		t.Errorf("Expected title \"Title\", got \"%s\" instead", coll.Title)
+	}
+	skels := coll.CollectSkeletons()
+	if len(skels) != 2 {
+		t.Fatalf("Expected two skeletons, got %d instead", len(skels))
+	}
+	poll := skels[0].(*gopolls.PollSkeleton)
+	if poll.Name != "Poll" || len(poll.Options) != 2 || poll.Options[0] != "yes" || poll.Options[1] != "no" {
+		t.Errorf("Poll was not parsed correctly, got %v", poll)
+	}
+}
+
+func TestVotesCSVReaderBOMAndCRLF(t *testing.T) {
+	in := bom + "voter,poll\r\none,yes\r\ntwo,no\r\n"
+	reader := gopolls.NewVotesCSVReader(strings.NewReader(in))
+	head, lines, err := reader.ReadRecords()
+	if err != nil {
+		t.Fatalf("Unexpected error reading csv: %v", err)
+	}
+	if head[0] != "voter" || head[1] != "poll" {
+		t.Errorf("Expected head [voter poll], got %v instead", head)
+	}
+	if len(lines) != 2 || lines[1][1] != "no" {
+		t.Errorf("Expected two lines, got %v instead", lines)
+	}
+}
+
+func TestParseVotersComments(t *testing.T) {
+	in := "# comment\n; another comment\n* Alice: 3  ; board member\n* Team #1: 2\n"
+	parser := gopolls.NewVotersParser()
+	parser.CommentPrefixes = []string{"#", ";"}
+	parser.AllowInlineComments = true
+	voters, err := parser.ParseVotersFromString(in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing voters: %v", err)
+	}
+	expected := []*gopolls.Voter{gopolls.NewVoter("Alice", 3), gopolls.NewVoter("Team", 1)}
+	if len(voters) != len(expected) {
+		t.Fatalf("Expected voters %v, got %v instead", expected, voters)
+	}
+	for i, voter := range voters {
+		if !voter.Equals(expected[i]) {
+			t.Errorf("Expected voter %v, got %v instead", expected[i], voter)
+		}
+	}
+
+	// without inline comments the names must not be changed
+	parser.AllowInlineComments = false
+	voter, voterErr := parser.ParseVotersLine("* Team #1: 2")
+	if voterErr != nil {
+		t.Fatalf("Unexpected error parsing voter: %v", voterErr)
+	}
+	if !voter.Equals(gopolls.NewVoter("Team #1", 2)) {
+		t.Errorf("Expected voter \"Team #1\" with weight 2, got %v instead", voter)
+	}
+
+	// default parser must not ignore ";"
+	if _, defaultErr := gopolls.NewVotersParser().ParseVotersFromString(in); defaultErr == nil {
+		t.Errorf("Expected an error for lines starting with \";\" with the default parser")
+	}
+
+	// an empty prefix must not ignore all lines
+	parser.CommentPrefixes = []string{"", "#"}
+	voters, err = parser.ParseVotersFromString("# comment\n* Alice: 3\n")
+	if err != nil || len(voters) != 1 || !voters[0].Equals(gopolls.NewVoter("Alice", 3)) {
+		t.Errorf("Expected voter Alice with an empty comment prefix, got %v (error %v)", voters, err)
+	}
+}
+
+func TestParseCollectionAnnotations(t *testing.T) {
+	in := "# Title\n## Group\n### Budget [majority=2/3]\n- 100 €\n### Board [majority=1/2, quorum=1/3]\n* A\n* B\n### Poll [draft]\n* yes\n* no\n" +
+		"### Budget [2021]\n- 200 €\n"
+	parser := gopolls.NewPollCollectionParser()
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	skels := coll.CollectSkeletons()
+	if len(skels) != 4 {
+		t.Fatalf("Expected four skeletons, got %d instead", len(skels))
+	}
+	budget := skels[0].(*gopolls.MoneyPollSkeleton)
+	if budget.Name != "Budget" || budget.RequiredMajority == nil || budget.RequiredMajority.Cmp(big.NewRat(2, 3)) != 0 ||
+		budget.Quorum != nil {
+		t.Errorf("Budget was not parsed correctly, got %v", budget)
+	}
+	board := skels[1].(*gopolls.PollSkeleton)
+	if board.Name != "Board" || board.RequiredMajority.Cmp(big.NewRat(1, 2)) != 0 ||
+		board.Quorum.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("Board was not parsed correctly, got %v", board)
+	}
+	poll := skels[2].(*gopolls.PollSkeleton)
+	if poll.Name != "Poll [draft]" || poll.HasAnnotations() {
+		t.Errorf("Poll without annotations was not parsed correctly, got %v", poll)
+	}
+	if year := skels[3].(*gopolls.MoneyPollSkeleton); year.Name != "Budget [2021]" || year.HasAnnotations() {
+		t.Errorf("Poll with a year in brackets was not parsed correctly, got %v", year)
+	}
+
+	if majority := budget.MajorityWeight(9); majority != 6 {
+		t.Errorf("Expected majority 6 for weight sum 9, got %d instead", majority)
+	}
+	if majority := poll.MajorityWeight(9); majority != gopolls.NoWeight {
+		t.Errorf("Expected NoWeight for poll without majority, got %d instead", majority)
+	}
+	skelMap, mapErr := coll.SkeletonsToMap()
+	if mapErr != nil {
+		t.Fatalf("Unexpected error creating skeleton map: %v", mapErr)
+	}
+	annotations := gopolls.ExtractAnnotations(skelMap)
+	if len(annotations) != 2 || annotations["Budget"] == nil || annotations["Board"] == nil {
+		t.Errorf("Expected annotations for Budget and Board, got %v instead", annotations)
+	}
+
+	// dump and parse again
+	var builder strings.Builder
+	if _, dumpErr := coll.Dump(&builder, gopolls.DefaultCurrencyHandler); dumpErr != nil {
+		t.Fatalf("Unexpected error dumping collection: %v", dumpErr)
+	}
+	dumped := builder.String()
+	if !strings.Contains(dumped, "### Budget [majority=2/3]\n") ||
+		!strings.Contains(dumped, "### Board [majority=1/2, quorum=1/3]\n") {
+		t.Errorf("Annotations were not written correctly, got\n%s", dumped)
+	}
+	again, againErr := parser.ParseCollectionSkeletonsFromString(nil, dumped)
+	if againErr != nil {
+		t.Fatalf("Unexpected error parsing dumped collection: %v", againErr)
+	}
+	againBoard := again.CollectSkeletons()[1].(*gopolls.PollSkeleton)
+	if againBoard.Quorum.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("Expected quorum 1/3 after dump, got %v instead", againBoard.Quorum)
+	}
+
+	// a fraction without a key is the shorthand for the majority
+	shorthand, err := parser.ParseCollectionSkeletonsFromString(nil,
+		"# Title\n## Group\n### Budget [2/3]\n- 100 €\n### Board [1/2, quorum=1/3]\n* A\n* B\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing shorthand: %v", err)
+	}
+	shorthandBudget := shorthand.CollectSkeletons()[0].(*gopolls.MoneyPollSkeleton)
+	if shorthandBudget.Name != "Budget" || shorthandBudget.RequiredMajority == nil ||
+		shorthandBudget.RequiredMajority.Cmp(big.NewRat(2, 3)) != 0 {
+		t.Errorf("Expected Budget with majority 2/3, got %v", shorthandBudget)
+	}
+	shorthandBoard := shorthand.CollectSkeletons()[1].(*gopolls.PollSkeleton)
+	if shorthandBoard.Name != "Board" || shorthandBoard.RequiredMajority.Cmp(big.NewRat(1, 2)) != 0 ||
+		shorthandBoard.Quorum.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("Expected Board with majority 1/2 and quorum 1/3, got %v", shorthandBoard)
+	}
+}
+
+func TestParseCollectionOptionDescriptions(t *testing.T) {
+	in := "# Title\n## Group\n### Hall\n* Option A — renovate hall (est. 20k€)\n* Option B\n  build a new hall\n" +
+		"  on the old parking lot\n  * Option C\n### Plain\n* yes\n* no\n"
+	parser := gopolls.NewPollCollectionParser()
+	parser.OptionDescriptionSeparator = gopolls.DefaultOptionDescriptionSeparator
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	hall := coll.Groups[0].Skeletons[0].(*gopolls.PollSkeleton)
+	expected := []gopolls.SkeletonOption{
+		{Name: "Option A", Description: "renovate hall (est. 20k€)"},
+		{Name: "Option B", Description: "build a new hall on the old parking lot"},
+		{Name: "Option C", Description: ""},
+	}
+	if options := hall.OptionList(); !reflect.DeepEqual(options, expected) {
+		t.Errorf("Expected options %v, got %v", expected, options)
+	}
+	if expectedNames := []string{"Option A", "Option B", "Option C"}; !reflect.DeepEqual(hall.Options, expectedNames) {
+		t.Errorf("Expected option names %v, got %v", expectedNames, hall.Options)
+	}
+	plain := coll.Groups[0].Skeletons[1].(*gopolls.PollSkeleton)
+	if plain.Descriptions != nil || plain.HasDescriptions() || plain.Description(0) != "" {
+		t.Errorf("Expected no descriptions for poll without descriptions, got %v", plain.Descriptions)
+	}
+
+	// dump and parse again, descriptions are written in indented lines
+	var builder strings.Builder
+	if _, dumpErr := coll.Dump(&builder, gopolls.DefaultCurrencyHandler); dumpErr != nil {
+		t.Fatalf("Unexpected error: %s", dumpErr)
+	}
+	parsed, parseErr := parser.ParseCollectionSkeletonsFromString(nil, builder.String())
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing the dump: %s", parseErr)
+	}
+	if !reflect.DeepEqual(coll, parsed) {
+		t.Errorf("Expected re-parsed collection to be equal to %v, got %v", coll, parsed)
+	}
+
+	// without separator (the default) the whole line is the name
+	parser = gopolls.NewPollCollectionParser()
+	coll, err = parser.ParseCollectionSkeletonsFromString(nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	hall = coll.Groups[0].Skeletons[0].(*gopolls.PollSkeleton)
+	if hall.Options[0] != "Option A — renovate hall (est. 20k€)" || hall.Description(0) != "" ||
+		hall.Description(1) != "build a new hall on the old parking lot" {
+		t.Errorf("Expected description separator to be disabled, got %v", hall.OptionList())
+	}
+
+	// a description line without an option is still an error
+	if _, err = parser.ParseCollectionSkeletonsFromString(nil, "# Title\n## Group\n### Hall\n  no option\n"); err == nil {
+		t.Error("Expected error for description before the first option")
+	}
+}
+
+func TestPollSkeletonDescriptions(t *testing.T) {
+	skel := gopolls.NewPollSkeleton("Poll")
+	skel.AddOption("A", "")
+	if skel.Descriptions != nil {
+		t.Errorf("Expected no descriptions after adding an option without description, got %v", skel.Descriptions)
+	}
+	// append directly, this must still work
+	skel.Options = append(skel.Options, "B", "C")
+	skel.SetDescription(1, "second")
+	if len(skel.Descriptions) != 3 || skel.Description(1) != "second" || skel.Description(2) != "" {
+		t.Errorf("Expected description for option 1 only, got %v", skel.Descriptions)
+	}
+	skel.AddOption("D", "fourth")
+	if skel.Description(3) != "fourth" || skel.Description(4) != "" || skel.Description(-1) != "" {
+		t.Errorf("Expected description for option 3, got %v", skel.Descriptions)
+	}
+}
+
+func TestParseCollectionInvalidAnnotations(t *testing.T) {
+	tests := []string{
+		"### Poll [majority=2/0]\n* a\n",
+		"### Poll [quorum=3/2]\n* a\n",
+		"### Poll [majority=abc]\n* a\n",
+		"### Poll [quorum=1/2, quorum=1/3]\n* a\n",
+		"### Poll [majority=2/3, foo=1/2]\n* a\n",
+		"### Poll [majority=2/3, 1/2]\n* a\n",
+	}
+	parser := gopolls.NewPollCollectionParser()
+	for _, tc := range tests {
+		if _, err := parser.ParseCollectionSkeletonsFromString(nil, "# Title\n## Group\n"+tc); err == nil {
+			t.Errorf("Expected an error for %q", tc)
+		}
+	}
+}
+
+func TestParseCollectionEmptyVotePolicies(t *testing.T) {
+	in := "# Title\n## Group\n### Election [empty=abstention]\n* A\n* B\n* C\n### Budget [majority=2/3, empty=Error]\n- 100 €\n### Motion\n* yes\n* no\n"
+	parser := gopolls.NewPollCollectionParser()
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", err)
+	}
+	policies := gopolls.PoliciesFromCollection(coll, gopolls.AddAsNoEmptyVote)
+	expected := gopolls.PolicyMap{
+		"Election": gopolls.AddAsAbstentionEmptyVote,
+		"Budget":   gopolls.RaiseErrorEmptyVote,
+		"Motion":   gopolls.AddAsNoEmptyVote,
+	}
+	if !reflect.DeepEqual(policies, expected) {
+		t.Errorf("Expected policies %v, got %v", expected, policies)
+	}
+
+	var builder strings.Builder
+	if _, dumpErr := coll.Dump(&builder, gopolls.DefaultCurrencyHandler); dumpErr != nil {
+		t.Fatalf("Unexpected error dumping collection: %v", dumpErr)
+	}
+	if dumped := builder.String(); !strings.Contains(dumped, "### Election [empty=abstention]\n") ||
+		!strings.Contains(dumped, "### Budget [majority=2/3, empty=error]\n") {
+		t.Errorf("Empty vote policies were not written correctly, got\n%s", dumped)
+	}
+
+	_, err = parser.ParseCollectionSkeletonsFromString(nil, "# Title\n## Group\n### Poll [empty=maybe]\n* yes\n* no\n")
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 3 {
+		t.Errorf("Expected PollingSyntaxError in line 3 for unknown policy, got %v", err)
+	}
+}
+
+func TestMaxTotalBytes(t *testing.T) {
+	voters := "* Alice: 1\n* Bob: 2\n"
+	collection := "# Title\n## Group\n### Poll\n* yes\n* no\n"
+	csvIn := "voter,poll\none,yes\ntwo,no\n"
+
+	parseVoters := func(r io.Reader, limit int) error {
+		parser := gopolls.NewVotersParser()
+		parser.MaxTotalBytes = limit
+		_, err := parser.ParseVoters(r)
+		return err
+	}
+	parseCollection := func(r io.Reader, limit int) error {
+		parser := gopolls.NewPollCollectionParser()
+		parser.MaxTotalBytes = limit
+		_, err := parser.ParseCollectionSkeletons(r, nil)
+		return err
+	}
+	readCSV := func(r io.Reader, limit int) error {
+		reader := gopolls.NewVotesCSVReader(r)
+		reader.Sep = ','
+		reader.MaxTotalBytes = limit
+		_, _, err := reader.ReadRecords()
+		return err
+	}
+
+	tests := []struct {
+		name  string
+		in    string
+		parse func(r io.Reader, limit int) error
+	}{
+		{"voters", voters, parseVoters},
+		{"collection", collection, parseCollection},
+		{"csv", csvIn, readCSV},
+	}
+	for _, tc := range tests {
+		// test with normal and short reads
+		readers := map[string]func() io.Reader{
+			"normal":   func() io.Reader { return strings.NewReader(tc.in) },
+			"one byte": func() io.Reader { return iotest.OneByteReader(strings.NewReader(tc.in)) },
+			"half":     func() io.Reader { return iotest.HalfReader(strings.NewReader(tc.in)) },
+		}
+		for readerName, newReader := range readers {
+			if err := tc.parse(newReader(), len(tc.in)); err != nil {
+				t.Errorf("%s (%s): unexpected error with limit equal to input size: %v", tc.name, readerName, err)
+			}
+			if err := tc.parse(newReader(), -1); err != nil {
+				t.Errorf("%s (%s): unexpected error without limit: %v", tc.name, readerName, err)
+			}
+			err := tc.parse(newReader(), len(tc.in)-1)
+			var validationErr *gopolls.ParserValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("%s (%s): expected ParserValidationError with limit one byte too small, got %v",
+					tc.name, readerName, err)
+			} else if !strings.Contains(err.Error(), strconv.Itoa(len(tc.in)-1)) {
+				t.Errorf("%s (%s): expected error message to contain the limit, got \"%s\"", tc.name, readerName, err)
+			}
+		}
+	}
+}
+
+func TestParserProgress(t *testing.T) {
+	// seven lines each, including comments / empty lines
+	voters := "# voters\n* Alice: 1\n* Bob: 2\n\n* Carol: 1\n* Dave: 1\n* Eve: 1\n"
+	collection := "# Title\n\n## Group\n### Poll\n* yes\n* no\n* maybe\n"
+	csvIn := "voter,poll\none,yes\ntwo,no\nthree,yes\nfour,no\nfive,yes\nsix,no\n"
+
+	tests := []struct {
+		name  string
+		parse func(progress gopolls.ProgressFunc, interval int) error
+	}{
+		{"voters", func(progress gopolls.ProgressFunc, interval int) error {
+			parser := gopolls.NewVotersParser()
+			parser.Progress, parser.ProgressInterval = progress, interval
+			_, err := parser.ParseVotersFromString(voters)
+			return err
+		}},
+		{"collection", func(progress gopolls.ProgressFunc, interval int) error {
+			parser := gopolls.NewPollCollectionParser()
+			parser.Progress, parser.ProgressInterval = progress, interval
+			_, err := parser.ParseCollectionSkeletonsFromString(nil, collection)
+			return err
+		}},
+		{"csv", func(progress gopolls.ProgressFunc, interval int) error {
+			reader := gopolls.NewVotesCSVReader(strings.NewReader(csvIn))
+			reader.Sep = ','
+			reader.Progress, reader.ProgressInterval = progress, interval
+			_, _, err := reader.ReadRecords()
+			return err
+		}},
+	}
+
+	intervals := []struct {
+		interval int
+		expected []int
+	}{
+		{1, []int{1, 2, 3, 4, 5, 6, 7}},
+		{3, []int{3, 6}},
+		{100, nil},
+		{0, nil},
+	}
+	for _, tc := range tests {
+		for _, intervalCase := range intervals {
+			var calls []int
+			progress := func(linesProcessed int) {
+				calls = append(calls, linesProcessed)
+			}
+			if err := tc.parse(progress, intervalCase.interval); err != nil {
+				t.Fatalf("%s: unexpected error: %v", tc.name, err)
+			}
+			if !reflect.DeepEqual(calls, intervalCase.expected) {
+				t.Errorf("%s: expected progress calls %v with interval %d, got %v", tc.name, intervalCase.expected,
+					intervalCase.interval, calls)
+			}
+		}
+		// a nil callback must not change anything
+		if err := tc.parse(nil, 1); err != nil {
+			t.Errorf("%s: unexpected error without progress callback: %v", tc.name, err)
+		}
+	}
+}
+
+func TestParseCollectionMinNumOptions(t *testing.T) {
+	tests := []struct {
+		in      string
+		lineNum int
+	}{
+		{"# Title\n## Group\n### A\n* yes\n* no\n\n### B\n* only\n### C\n* yes\n* no\n", 7},
+		{"# Title\n## Group\n### A\n* yes\n* no\n### B\n* only\n", 6},
+		{"# Title\n## Group\n### A\n* yes\n## Group 2\n### B\n* only\n", 3},
+		// first error in document order wins
+		{"# Title\n## Group\n### A\n* yes\n### B\n* yes\n", 3},
+	}
+	parser := gopolls.NewPollCollectionParser()
+	for _, tc := range tests {
+		_, err := parser.ParseCollectionSkeletonsFromString(nil, tc.in)
+		var syntaxErr gopolls.PollingSyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected PollingSyntaxError for %q, got %v", tc.in, err)
+			continue
+		}
+		if syntaxErr.LineNum != tc.lineNum {
+			t.Errorf("Expected error in line %d for %q, got line %d (%v)", tc.lineNum, tc.in, syntaxErr.LineNum, err)
+		}
+		if !strings.Contains(err.Error(), "expected at least 2") {
+			t.Errorf("Unexpected error message %q", err.Error())
+		}
+	}
+
+	parser.MinNumOptions = 1
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, "# Title\n## Group\n### Info\n* noted\n")
+	if err != nil {
+		t.Fatalf("Unexpected error with MinNumOptions = 1: %v", err)
+	}
+	if skel := coll.Groups[0].Skeletons[0].(*gopolls.PollSkeleton); len(skel.Options) != 1 {
+		t.Errorf("Expected one option, got %v", skel.Options)
+	}
+}
+
+func TestParseCollectionCollectErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		title    string
+		polls    []string
+		lineNums []int
+	}{
+		{
+			"option list",
+			"# Title\n## Group\n### A\n* yes\n* no\nfoo\n* maybe\n### B\n* yes\n* no\n",
+			"Title", []string{"A", "B"}, []int{6},
+		},
+		{
+			"money value",
+			"# Title\n## Group\n### Budget\n- abc €\n* ignored\n### B\n* yes\n* no\n## Group 2\n### C\n- 10,00 €\n",
+			"Title", []string{"B", "C"}, []int{4},
+		},
+		{
+			"title",
+			"Title\n## Group\n### A\n* yes\n* no\n",
+			"", []string{"A"}, []int{1},
+		},
+		{
+			"multiple errors",
+			"Title\n## Group\n### A\n* yes\n### B\n- abc €\n### C\n* yes\n* no\n",
+			"", []string{"A", "C"}, []int{1, 3, 6},
+		},
+	}
+	parser := gopolls.NewPollCollectionParser()
+	parser.CollectErrors = true
+	for _, tc := range tests {
+		coll, err := parser.ParseCollectionSkeletonsFromString(nil, tc.in)
+		if coll == nil {
+			t.Errorf("%s: expected a partial collection, got nil", tc.name)
+			continue
+		}
+		var collectionErrs gopolls.PollCollectionErrors
+		if !errors.As(err, &collectionErrs) {
+			t.Errorf("%s: expected PollCollectionErrors, got %v", tc.name, err)
+			continue
+		}
+		var lineNums []int
+		for _, lineErr := range collectionErrs.Errors {
+			lineNums = append(lineNums, lineErr.LineNum)
+			var syntaxErr gopolls.PollingSyntaxError
+			if errors.As(lineErr, &syntaxErr) && syntaxErr.LineNum != lineErr.LineNum {
+				t.Errorf("%s: syntax error has line %d, expected %d", tc.name, syntaxErr.LineNum, lineErr.LineNum)
+			}
+		}
+		if !reflect.DeepEqual(lineNums, tc.lineNums) {
+			t.Errorf("%s: expected errors in lines %v, got %v (%v)", tc.name, tc.lineNums, lineNums, err)
+		}
+		if coll.Title != tc.title {
+			t.Errorf("%s: expected title %q, got %q", tc.name, tc.title, coll.Title)
+		}
+		var polls []string
+		for _, group := range coll.Groups {
+			for _, skel := range group.Skeletons {
+				polls = append(polls, skel.GetName())
+			}
+		}
+		if !reflect.DeepEqual(polls, tc.polls) {
+			t.Errorf("%s: expected polls %v, got %v", tc.name, tc.polls, polls)
+		}
+	}
+
+	// errors.As finds validation errors, too
+	parser.MaxOptionLength = 3
+	_, err := parser.ParseCollectionSkeletonsFromString(nil, "# Title\n## Group\n### A\n* yes\n* maybe\n### B\n* yes\n* no\n")
+	var validationErr *gopolls.ParserValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "error in line 5: ") {
+		t.Errorf("Unexpected error message %q", err.Error())
+	}
+
+	// a valid document returns no error
+	if _, err := parser.ParseCollectionSkeletonsFromString(nil, "# Title\n## Group\n### A\n* yes\n* no\n"); err != nil {
+		t.Errorf("Unexpected error for valid document: %v", err)
+	}
+
+	// strict mode stops at the first error
+	parser = gopolls.NewPollCollectionParser()
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, tests[0].in)
+	var syntaxErr gopolls.PollingSyntaxError
+	if coll != nil || !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 6 {
+		t.Errorf("Expected syntax error in line 6 and no collection in strict mode, got %v", err)
+	}
+}
+
+func TestParserBufferSize(t *testing.T) {
+	longOption := strings.Repeat("a", 1<<20)
+	in := "# Title\n## Group\n### Poll\n* " + longOption + "\n* no\n"
+
+	parser := gopolls.NewPollCollectionParser()
+	_, err := parser.ParseCollectionSkeletonsFromString(nil, in)
+	var validationErr *gopolls.ParserValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ParserValidationError with default buffer size, got %v", err)
+	}
+	if !strings.Contains(err.Error(), strconv.Itoa(gopolls.DefaultMaxBufferSize)) {
+		t.Errorf("Expected buffer size in error message, got %q", err.Error())
+	}
+
+	parser.MaxBufferSize = 2 << 20
+	coll, err := parser.ParseCollectionSkeletonsFromString(nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error with MaxBufferSize = %d: %v", parser.MaxBufferSize, err)
+	}
+	if option := coll.Groups[0].Skeletons[0].(*gopolls.PollSkeleton).Options[0]; option != longOption {
+		t.Errorf("Expected option of length %d, got length %d", len(longOption), len(option))
+	}
+
+	votersParser := gopolls.NewVotersParser()
+	votersParser.MaxBufferSize = 2 << 20
+	voters, err := votersParser.ParseVotersFromString("* " + longOption + ": 2\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing long voter name: %v", err)
+	}
+	if len(voters) != 1 || voters[0].Name != longOption {
+		t.Errorf("Expected one voter with a long name, got %d voters", len(voters))
+	}
+
+	// a max line length of 0 must still allow empty lines
+	votersParser = gopolls.NewVotersParser()
+	votersParser.MaxLineLength = 0
+	if voters, err := votersParser.ParseVotersFromString("\n\n"); err != nil || len(voters) != 0 {
+		t.Errorf("Expected no voters and no error, got %v, %v", voters, err)
+	}
+}
+
+func TestVotesCSVReaderModes(t *testing.T) {
+	csvIn := "voter,a,b\none,yes,no\ntwo,yes\nthree,yes,no\nfour,\"ye\"s,no\nfive,no,no,no\n"
+
+	strict := gopolls.NewVotesCSVReader(strings.NewReader(csvIn))
+	strict.Sep = ','
+	_, _, err := strict.ReadRecords()
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected PollingSyntaxError in strict mode, got %v", err)
+	}
+	if syntaxErr.LineNum != 3 {
+		t.Errorf("Expected error in line 3, got %d", syntaxErr.LineNum)
+	}
+
+	lenient := gopolls.NewVotesCSVReader(strings.NewReader(csvIn))
+	lenient.Sep = ','
+	lenient.Mode = gopolls.LenientCSVMode
+	matrix, report, err := gopolls.ReadMatrixFromCSVWithReport(lenient)
+	if err != nil {
+		t.Fatalf("Unexpected error in lenient mode: %v", err)
+	}
+	if len(matrix.Body) != 2 || matrix.Body[0][0] != "one" || matrix.Body[1][0] != "three" {
+		t.Errorf("Expected rows of one and three, got %v", matrix.Body)
+	}
+	if len(report.RowErrors) != 3 {
+		t.Fatalf("Expected three malformed rows, got %v", report.RowErrors)
+	}
+	expected := []struct {
+		row, numColumns int
+	}{
+		{3, 2}, {5, -1}, {6, 4},
+	}
+	for i, tc := range expected {
+		rowErr := report.RowErrors[i]
+		if rowErr.Row != tc.row || rowErr.NumColumns != tc.numColumns || rowErr.ExpectedColumns != 3 || rowErr.Msg == "" {
+			t.Errorf("Expected row %d with %d columns, got %+v", tc.row, tc.numColumns, rowErr)
+		}
+	}
+	if !errors.As(report.AsError(), &syntaxErr) || syntaxErr.LineNum != 3 {
+		t.Errorf("Expected report error in line 3, got %v", report.AsError())
+	}
+
+	// ReadRecords must report the broken rows in lenient mode
+	lenient = gopolls.NewVotesCSVReader(strings.NewReader(csvIn))
+	lenient.Sep = ','
+	lenient.Mode = gopolls.LenientCSVMode
+	if _, _, err = lenient.ReadRecords(); !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected PollingSyntaxError from ReadRecords in lenient mode, got %v", err)
+	}
+}
+
+func TestSkeletonsToMapChecked(t *testing.T) {
+	coll := gopolls.GenerateCollection(gopolls.CollectionSpec{NumGroups: 2, NumBasicPolls: 2, NumMedianPolls: 1})
+	skelMap, name, hasDuplicates := coll.SkeletonsToMapChecked()
+	if hasDuplicates || name != "" || len(skelMap) != 3 || skelMap["Median poll 1"] == nil {
+		t.Errorf("Expected map with three skeletons, got %v, \"%s\", %v", skelMap, name, hasDuplicates)
+	}
+
+	coll.Groups[1].Skeletons = append(coll.Groups[1].Skeletons, gopolls.NewPollSkeleton("Basic poll 1"))
+	skelMap, name, hasDuplicates = coll.SkeletonsToMapChecked()
+	if skelMap != nil || name != "Basic poll 1" || !hasDuplicates {
+		t.Errorf("Expected duplicate \"Basic poll 1\", got %v, \"%s\", %v", skelMap, name, hasDuplicates)
+	}
+	var duplicateErr gopolls.DuplicateError
+	if _, err := coll.SkeletonsToMap(); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+}
+
+func benchmarkSkeletonCollection() *gopolls.PollSkeletonCollection {
+	return gopolls.GenerateCollection(gopolls.CollectionSpec{NumGroups: 100, NumBasicPolls: 10000,
+		NumMedianPolls: 10000})
+}
+
+func BenchmarkHasDuplicateSkeletonAndSkeletonsToMap(b *testing.B) {
+	coll := benchmarkSkeletonCollection()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, hasDuplicates := coll.HasDuplicateSkeleton(); hasDuplicates {
+			b.Fatal("Unexpected duplicate")
+		}
+		if _, err := coll.SkeletonsToMap(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSkeletonsToMapChecked(b *testing.B) {
+	coll := benchmarkSkeletonCollection()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, hasDuplicates := coll.SkeletonsToMapChecked(); hasDuplicates {
+			b.Fatal("Unexpected duplicate")
+		}
+	}
+}
+
+func readMultilineFixture(t *testing.T) string {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", "multiline_votes.csv"))
+	if err != nil {
+		t.Fatalf("Unexpected error reading fixture: %v", err)
+	}
+	return string(content)
+}
+
+func TestVotesCSVReaderMultilineCells(t *testing.T) {
+	in := readMultilineFixture(t)
+	readers := map[string]func() io.Reader{
+		"normal":   func() io.Reader { return strings.NewReader(in) },
+		"one byte": func() io.Reader { return iotest.OneByteReader(strings.NewReader(in)) },
+	}
+	for readerName, newReader := range readers {
+		_, lines, err := gopolls.NewVotesCSVReader(newReader()).ReadRecords()
+		if err != nil {
+			t.Fatalf("%s: unexpected error reading csv: %v", readerName, err)
+		}
+		if len(lines) != 4 || lines[0][1] != "1\n2" || lines[2][1] != "a\nb\nc" || lines[3][0] != "four" {
+			t.Errorf("%s: unexpected lines %q", readerName, lines)
+		}
+
+		// the row of voter three starts on line 6
+		reader := gopolls.NewVotesCSVReader(newReader())
+		reader.MaxVotersNameLength = 4
+		_, _, err = reader.ReadRecords()
+		var validationErr *gopolls.ParserValidationError
+		if !errors.As(err, &validationErr) || !strings.Contains(validationErr.Message, "(line 6)") {
+			t.Errorf("%s: expected a ParserValidationError for line 6, got %v", readerName, err)
+		}
+	}
+}
+
+func TestVotesCSVReaderMaxNumLinesCountsPhysicalLines(t *testing.T) {
+	in := readMultilineFixture(t)
+	tests := []struct {
+		maxNumLines int
+		valid       bool
+	}{
+		// the fixture has four rows and nine lines
+		{4, false},
+		{8, false},
+		{9, true},
+		{-1, true},
+	}
+	for _, tc := range tests {
+		reader := gopolls.NewVotesCSVReader(strings.NewReader(in))
+		reader.MaxNumLines = tc.maxNumLines
+		_, _, err := reader.ReadRecords()
+		var validationErr *gopolls.ParserValidationError
+		switch {
+		case tc.valid && err != nil:
+			t.Errorf("Unexpected error for MaxNumLines = %d: %v", tc.maxNumLines, err)
+		case !tc.valid && !errors.As(err, &validationErr):
+			t.Errorf("Expected a ParserValidationError for MaxNumLines = %d, got %v", tc.maxNumLines, err)
+		}
+	}
+}
+
+func TestVotesCSVReaderRejectMultilineCells(t *testing.T) {
+	reader := gopolls.NewVotesCSVReader(strings.NewReader(readMultilineFixture(t)))
+	reader.AllowMultilineCells = false
+	_, _, err := reader.ReadRecords()
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 2 || !strings.Contains(syntaxErr.Msg, "\"one\"") {
+		t.Errorf("Expected a PollingSyntaxError in line 2 naming voter one, got %v", err)
+	}
+
+	reader = gopolls.NewVotesCSVReader(strings.NewReader("voter,\"poll\n1\"\none,yes\n"))
+	reader.AllowMultilineCells = false
+	if _, _, err = reader.ReadRecords(); !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 1 {
+		t.Errorf("Expected a PollingSyntaxError in line 1 for a line break in the head, got %v", err)
+	}
+
+	reader = gopolls.NewVotesCSVReader(strings.NewReader(readMultilineFixture(t)))
+	reader.AllowMultilineCells = false
+	reader.Mode = gopolls.LenientCSVMode
+	_, lines, report, err := reader.ReadRecordsWithReport()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lines) != 2 || len(report.RowErrors) != 2 {
+		t.Fatalf("Expected two lines and two malformed rows, got %q and %v", lines, report.RowErrors)
+	}
+	if first, second := report.RowErrors[0], report.RowErrors[1]; first.Row != 2 || first.Line != 2 ||
+		second.Row != 4 || second.Line != 6 {
+		t.Errorf("Expected malformed rows 2 (line 2) and 4 (line 6), got %v", report.RowErrors)
+	}
+}
+
+func TestVotesCSVReaderLenientPhysicalLines(t *testing.T) {
+	in := "voter,poll\none,\"a\nb\"\ntwo,x,y\nthree,\"unterminated\n"
+	reader := gopolls.NewVotesCSVReader(strings.NewReader(in))
+	reader.Mode = gopolls.LenientCSVMode
+	_, lines, report, err := reader.ReadRecordsWithReport()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lines) != 1 || len(report.RowErrors) != 2 {
+		t.Fatalf("Expected one line and two malformed rows, got %q and %v", lines, report.RowErrors)
+	}
+	if first := report.RowErrors[0]; first.Row != 3 || first.Line != 4 || first.NumColumns != 3 {
+		t.Errorf("Expected row 3 (line 4) with three columns, got %v", first)
+	}
+	if second := report.RowErrors[1]; second.Row != 4 || second.Line != 5 || second.NumColumns != -1 {
+		t.Errorf("Expected unparsable row 4 (line 5), got %v", second)
+	}
+	var syntaxErr gopolls.PollingSyntaxError
+	if err = report.AsError(); !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 4 {
+		t.Errorf("Expected a PollingSyntaxError in line 4, got %v", err)
+	}
+}
+
+func TestParseVotersLineExplicitWeight(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	if voter, err := parser.ParseVotersLine("* Alice"); err != nil || voter.Weight != 1 {
+		t.Errorf("Expected weight 1 for a missing weight by default, got %v (error %v)", voter, err)
+	}
+	if voter, err := parser.ParseVotersLine("* Guest: 0"); err != nil || voter.Weight != 0 {
+		t.Errorf("Expected weight 0 to be allowed by default, got %v (error %v)", voter, err)
+	}
+
+	parser.RequireExplicitWeight = true
+	_, err := parser.ParseVotersLine("* Alice")
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) || !strings.Contains(syntaxErr.Msg, "\"Alice\"") {
+		t.Errorf("Expected a PollingSyntaxError naming Alice for a missing weight, got %v", err)
+	}
+	if voter, err := parser.ParseVotersLine("* Alice: 1"); err != nil || voter.Weight != 1 {
+		t.Errorf("Expected an explicit weight to be accepted, got %v (error %v)", voter, err)
+	}
+
+	parser.RejectZeroWeight = true
+	_, err = parser.ParseVotersLine("* Guest: 0")
+	var validationErr *gopolls.ParserValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(validationErr.Message, "\"Guest\"") {
+		t.Errorf("Expected a ParserValidationError naming Guest for weight 0, got %v", err)
+	}
+	// only the own weight of the voter is checked
+	parser.ParseDelegations = true
+	if _, err = parser.ParseVotersLine("* Guest: 0 (+Bob: 2)"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ParserValidationError for weight 0 with delegations, got %v", err)
+	}
+	parser.FractionalWeights = true
+	if voter, err := parser.ParseVotersLine("* Alice: 0.5"); err != nil || voter.FractionalWeight == nil {
+		t.Errorf("Expected fractional weight 0.5 to be accepted, got %v (error %v)", voter, err)
+	}
+	if _, err = parser.ParseVotersLine("* Guest: 0.0"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ParserValidationError for fractional weight 0.0, got %v", err)
+	}
+}
+
+func TestParseVotersExplicitWeightLineNumbers(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	parser.RequireExplicitWeight = true
+	_, err := parser.ParseVotersFromString("* Alice: 1\n\n* Bob\n")
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 3 || !strings.Contains(syntaxErr.Msg, "\"Bob\"") {
+		t.Errorf("Expected a PollingSyntaxError in line 3 naming Bob, got %v", err)
+	}
+
+	parser = gopolls.NewVotersParser()
+	parser.RejectZeroWeight = true
+	_, err = parser.ParseVotersFromString("# guests\n* Alice: 1\n* Guest: 0\n")
+	var validationErr *gopolls.ParserValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(validationErr.Message, "\"Guest\"") ||
+		!strings.Contains(validationErr.Message, "line 3") {
+		t.Errorf("Expected a ParserValidationError in line 3 naming Guest, got %v", err)
+	}
+
+	// the encoding error is not changed
+	if _, err = parser.ParseVotersFromString("* Alice: 1\n* \xff: 2\n"); !errors.Is(err, gopolls.ErrInvalidEncoding) {
+		t.Errorf("Expected ErrInvalidEncoding, got %v", err)
+	}
+
+	// the messages of other validation errors are not changed
+	parser.MaxVotersNameLength = 3
+	_, err = parser.ParseVotersFromString("* Alice: 1\n")
+	expected := "voter name is too long: got length 5, allowed max length is 3"
+	if !errors.As(err, &validationErr) || validationErr.Message != expected {
+		t.Errorf("Expected ParserValidationError %q, got %v", expected, err)
+	}
+}
+
+func TestParseVotersLineWeightFormat(t *testing.T) {
+	tests := []struct {
+		in     string
+		name   string
+		weight gopolls.Weight
+	}{
+		{"* Alice: 007", "Alice", 7},
+		{"* Alice: 0", "Alice", 0},
+		{"* Alice: 00", "Alice", 0},
+		{"*   Alice  :   3   ", "Alice", 3},
+		{"\t* Alice:\t010\t", "Alice", 10},
+	}
+	parser := gopolls.NewVotersParser()
+	parser.RequireExplicitWeight = true
+	for _, tc := range tests {
+		voter, err := parser.ParseVotersLine(tc.in)
+		if err != nil {
+			t.Errorf("Unexpected error parsing \"%s\": %v", tc.in, err)
+			continue
+		}
+		if voter.Name != tc.name || voter.Weight != tc.weight {
+			t.Errorf("Expected %s with weight %d for \"%s\", got %s with weight %d", tc.name, tc.weight, tc.in,
+				voter.Name, voter.Weight)
+		}
+	}
+}
+
+func TestVotersParserComputeDefaultMaxLineLength(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	parser.MaxNumLines = 100
+	parser.ComputeDefaultMaxLineLength()
+	if parser.MaxLineLength != -1 {
+		t.Errorf("Expected MaxLineLength to stay -1 without MaxVotersNameLength, got %d", parser.MaxLineLength)
+	}
+
+	parser = gopolls.NewVotersParser()
+	parser.MaxVotersNameLength = 8
+	parser.MaxVotersWeight = 1000
+	parser.ComputeDefaultMaxLineLength()
+	maximal := "* " + strings.Repeat("a", 8) + ": 1000"
+	if _, err := parser.ParseVotersFromString(maximal + "\n"); err != nil {
+		t.Errorf("Expected maximal voter line to be accepted, got %v", err)
+	}
+	padded := maximal + strings.Repeat(" ", parser.MaxLineLength-len(maximal))
+	if _, err := parser.ParseVotersFromString(padded + "\n"); err != nil {
+		t.Errorf("Expected line of length MaxLineLength to be accepted, got %v", err)
+	}
+	var validationErr *gopolls.ParserValidationError
+	for _, in := range []string{padded + " ", "* " + strings.Repeat("a", 9) + ": 1000", maximal[:len(maximal)-1] + "1"} {
+		if _, err := parser.ParseVotersFromString(in + "\n"); !errors.As(err, &validationErr) {
+			t.Errorf("Expected ParserValidationError for \"%s\", got %v", in, err)
+		}
+	}
+}
+
+func TestPollCollectionParserComputeDefaultMaxLineLength(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	parser.ComputeDefaultMaxLineLength()
+	if parser.MaxLineLength != -1 {
+		t.Errorf("Expected MaxLineLength to stay -1 without limits, got %d", parser.MaxLineLength)
+	}
+
+	parser.MaxTitleLength = 10
+	parser.MaxGroupNameLength = 11
+	parser.MaxPollNameLength = 12
+	parser.MaxOptionLength = 13
+	parser.MaxCurrencyValue = 123456789
+	parser.ComputeDefaultMaxLineLength()
+	if parser.MaxLineLength < 0 {
+		t.Fatalf("Expected MaxLineLength to be set")
+	}
+	lines := []string{
+		"# " + strings.Repeat("t", 10),
+		"## " + strings.Repeat("g", 11),
+		"### " + strings.Repeat("p", 12),
+		"* " + strings.Repeat("a", 13),
+		"* " + strings.Repeat("b", 13),
+		"### " + strings.Repeat("m", 12),
+		"- 1234567.89 €",
+	}
+	parse := func(lines []string) error {
+		_, err := parser.ParseCollectionSkeletonsFromString(gopolls.SimpleEuroHandler{}, strings.Join(lines, "\n")+"\n")
+		return err
+	}
+	if err := parse(lines); err != nil {
+		t.Fatalf("Expected maximal collection to be accepted, got %v", err)
+	}
+	for i, line := range lines {
+		changed := append([]string(nil), lines...)
+		changed[i] = line + strings.Repeat(" ", parser.MaxLineLength-len(line))
+		if err := parse(changed); err != nil {
+			t.Errorf("Expected line %d of length MaxLineLength to be accepted, got %v", i+1, err)
+		}
+		changed[i] += " "
+		var validationErr *gopolls.ParserValidationError
+		if err := parse(changed); !errors.As(err, &validationErr) {
+			t.Errorf("Expected ParserValidationError for line %d longer than MaxLineLength, got %v", i+1, err)
+		}
+	}
+	// one byte longer than the individual limits
+	longer := []string{
+		"# " + strings.Repeat("t", 11),
+		"## " + strings.Repeat("g", 12),
+		"### " + strings.Repeat("p", 13),
+		"* " + strings.Repeat("a", 14),
+		"* " + strings.Repeat("b", 14),
+		"### " + strings.Repeat("m", 13),
+		"- 1234567.90 €",
+	}
+	for i, line := range longer {
+		changed := append([]string(nil), lines...)
+		changed[i] = line
+		var validationErr *gopolls.ParserValidationError
+		if err := parse(changed); !errors.As(err, &validationErr) {
+			t.Errorf("Expected ParserValidationError for \"%s\", got %v", line, err)
+		}
+	}
+}
+
+func TestVotesCSVReaderComputeDefaultMaxRecordLength(t *testing.T) {
+	collectionParser := gopolls.NewPollCollectionParser()
+	collectionParser.MaxNumOptions = 3
+	collectionParser.MaxOptionLength = 13
+	collectionParser.MaxCurrencyValue = 123456789
+	hints := collectionParser.VoteLengthHints()
+	newReader := func(in string) *gopolls.VotesCSVReader {
+		reader := gopolls.NewVotesCSVReader(strings.NewReader(in))
+		reader.MaxVotersNameLength = 8
+		reader.MaxPollNameLength = 12
+		reader.ComputeDefaultMaxRecordLength(hints)
+		return reader
+	}
+
+	reader := gopolls.NewVotesCSVReader(strings.NewReader(""))
+	reader.MaxPollNameLength = 12
+	reader.ComputeDefaultMaxRecordLength(hints)
+	if reader.MaxRecordLength != -1 {
+		t.Errorf("Expected MaxRecordLength to stay -1 without MaxVotersNameLength, got %d", reader.MaxRecordLength)
+	}
+
+	maxRecordLength := newReader("").MaxRecordLength
+	named := strings.Join([]string{strings.Repeat("a", 13), strings.Repeat("b", 13), strings.Repeat("c", 13)}, " > ")
+	votes := []string{"abstention", "2, 1, 0", "255, 255, 255", named, "1234567.89 €", strings.Repeat(" ", maxRecordLength)}
+	head := "voter," + strings.Repeat("p", 12)
+	for _, vote := range votes {
+		in := head + "\n" + strings.Repeat("v", 8) + ",\"" + vote + "\"\n"
+		if _, _, err := newReader(in).ReadRecords(); err != nil {
+			t.Errorf("Expected vote \"%s\" to be accepted, got %v", vote, err)
+		}
+	}
+	var validationErr *gopolls.ParserValidationError
+	in := head + "\none,\"" + strings.Repeat(" ", maxRecordLength+1) + "\"\n"
+	if _, _, err := newReader(in).ReadRecords(); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for a record longer than MaxRecordLength, got %v", err)
+	}
+	in = head + "\n" + strings.Repeat("v", 9) + ",aye\n"
+	if _, _, err := newReader(in).ReadRecords(); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for a voter name longer than MaxVotersNameLength, got %v", err)
+	}
+
+	// without hints only basic votes are expected
+	reader = newReader("")
+	reader.ComputeDefaultMaxRecordLength(gopolls.VoteLengthHints{MaxNumOptions: -1, MaxOptionLength: -1,
+		MaxCurrencyValue: -1})
+	if reader.MaxRecordLength >= maxRecordLength {
+		t.Errorf("Expected a smaller MaxRecordLength without hints, got %d", reader.MaxRecordLength)
+	}
+}
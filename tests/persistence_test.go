@@ -0,0 +1,91 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"strings"
+	"testing"
+)
+
+func TestDumpAndLoadVotes(t *testing.T) {
+	voterOne := gopolls.NewVoter("one", 1)
+	voterTwo := gopolls.NewVoter("two", 2)
+	voters := gopolls.VoterMap{"one": voterOne, "two": voterTwo}
+
+	polls := gopolls.PollMap{
+		"basic": gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(voterOne, gopolls.Aye),
+			gopolls.NewBasicVote(voterTwo, gopolls.No),
+		}),
+		"median": gopolls.NewMedianPoll(1000, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(voterOne, 0),
+			gopolls.NewMedianVote(voterTwo, 500),
+		}),
+		"schulze": gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(voterOne, gopolls.SchulzeRanking{0, 1, 2}),
+			gopolls.NewSchulzeVote(voterTwo, gopolls.SchulzeRanking{2, 2, 0}),
+		}),
+	}
+
+	var buff bytes.Buffer
+	if err := gopolls.DumpVotes(&buff, polls); err != nil {
+		t.Fatalf("unexpected error dumping votes: %v", err)
+	}
+
+	loaded := gopolls.PollMap{
+		"basic":   gopolls.NewBasicPoll(nil),
+		"median":  gopolls.NewMedianPoll(1000, nil),
+		"schulze": gopolls.NewSchulzePoll(3, nil),
+	}
+	if err := gopolls.LoadVotes(&buff, loaded, voters); err != nil {
+		t.Fatalf("unexpected error loading votes: %v", err)
+	}
+
+	basic := loaded["basic"].(*gopolls.BasicPoll)
+	if len(basic.Votes) != 2 || basic.Votes[0].Choice != gopolls.Aye || basic.Votes[1].Choice != gopolls.No ||
+		basic.Votes[1].Voter != voterTwo {
+		t.Errorf("basic votes were not restored correctly, got %v", basic.Votes)
+	}
+
+	median := loaded["median"].(*gopolls.MedianPoll)
+	if len(median.Votes) != 2 || median.Votes[0].Value != 0 || median.Votes[1].Value != 500 {
+		t.Errorf("median votes were not restored correctly, got %v", median.Votes)
+	}
+
+	schulze := loaded["schulze"].(*gopolls.SchulzePoll)
+	if len(schulze.Votes) != 2 || !schulze.Tally().D.Equals(polls["schulze"].(*gopolls.SchulzePoll).Tally().D) {
+		t.Errorf("schulze votes were not restored correctly, got %v", schulze.Votes)
+	}
+}
+
+func TestLoadVotesErrors(t *testing.T) {
+	voters := gopolls.VoterMap{"one": gopolls.NewVoter("one", 1)}
+	tests := []string{
+		`{"poll":"basic","voter":"unknown","type":"basic-vote","choice":"aye"}`,
+		`{"poll":"unknown","voter":"one","type":"basic-vote","choice":"aye"}`,
+		`{"poll":"basic","voter":"one","type":"median-vote","value":42}`,
+	}
+	for _, in := range tests {
+		polls := gopolls.PollMap{"basic": gopolls.NewBasicPoll(nil)}
+		err := gopolls.LoadVotes(strings.NewReader(in), polls, voters)
+		var semanticErr gopolls.PollingSemanticError
+		if !errors.As(err, &semanticErr) {
+			t.Errorf("expected a PollingSemanticError for input %s, got %v instead", in, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"github.com/FabianWe/gopolls"
+	"strings"
+	"testing"
+)
+
+func TestWriteProtocol(t *testing.T) {
+	in := "# Assembly\n\n## Group\n\n### Motion\n* yes\n* no\n\n### Budget\n- 100.00 €\n\n### Chair\n* A\n* B\n* C\n\n### Missing\n* yes\n* no\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 3)
+
+	motion := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(one, gopolls.Aye),
+		gopolls.NewBasicVote(two, gopolls.No),
+	})
+	budget := gopolls.NewMedianPoll(10000, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 10000),
+		gopolls.NewMedianVote(two, 5000),
+		gopolls.NewMedianAbstention(gopolls.NewVoter("three", 2)),
+	})
+	chair := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 1}),
+		gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{1, 0, 0}),
+	})
+	polls := gopolls.PollMap{
+		"Motion":  motion,
+		"Budget":  budget,
+		"Chair":   chair,
+		"Missing": gopolls.NewBasicPoll(nil),
+	}
+	results := map[string]gopolls.AbstractPollResult{
+		"Motion": motion.Tally(),
+		"Budget": budget.Tally(gopolls.NoWeight),
+		"Chair":  chair.Tally(),
+	}
+	var buf bytes.Buffer
+	if err := gopolls.WriteProtocol(&buf, coll, polls, results, gopolls.DefaultCurrencyHandler); err != nil {
+		t.Fatalf("Unexpected error writing protocol: %v", err)
+	}
+	assertGolden(t, "protocol.md", buf.Bytes())
+
+	results["Missing"] = unsupportedResult{}
+	if err := gopolls.WriteProtocol(&buf, coll, polls, results, gopolls.DefaultCurrencyHandler); err == nil {
+		t.Error("Expected an error for an unsupported result type")
+	}
+}
+
+type unsupportedResult struct{}
+
+func (unsupportedResult) ResultType() string {
+	return "unsupported-result"
+}
+
+func (unsupportedResult) GetWeightSum() gopolls.Weight {
+	return 0
+}
+
+func TestWriteProtocolSecondaryOrder(t *testing.T) {
+	in := "# Assembly\n\n## Group\n\n### Chair\n* A\n* B\n* C\n* D\n* E\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	poll := gopolls.NewSchulzePoll(5, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(gopolls.NewVoter("one", 2), gopolls.SchulzeRanking{0, 1, 2, 1, 1}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("two", 1), gopolls.SchulzeRanking{2, 1, 1, 2, 0}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("three", 3), gopolls.SchulzeRanking{0, 0, 0, 0, 0}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("four", 2), gopolls.SchulzeRanking{1, 1, 0, 1, 2}),
+	})
+	poll.SecondaryOrder = gopolls.SchulzeDirectComparison
+	polls := gopolls.PollMap{"Chair": poll}
+	results := map[string]gopolls.AbstractPollResult{"Chair": poll.Tally()}
+	var buf bytes.Buffer
+	if err := gopolls.WriteProtocol(&buf, coll, polls, results, gopolls.DefaultCurrencyHandler); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "- Rank 2: C, E\n- Rank 3: B, D\n- E before C by direct comparison\n- B before D by direct comparison\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected protocol to contain\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteProtocolScore(t *testing.T) {
+	in := "# Assembly\n\n## Group\n\n### Colour\n* Red\n* Green\n* Blue\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	colour := gopolls.NewScorePoll(3, 5, []*gopolls.ScoreVote{
+		gopolls.NewScoreVote(gopolls.NewVoter("one", 1), []uint8{5, 2, 0}),
+		gopolls.NewScoreVote(gopolls.NewVoter("two", 1), []uint8{4, 3, 1}),
+		gopolls.NewScoreVote(gopolls.NewVoter("three", 1), []uint8{1, 1}),
+	})
+	polls := gopolls.PollMap{"Colour": colour}
+	results := map[string]gopolls.AbstractPollResult{"Colour": colour.TallyWithRunoff()}
+	var buf bytes.Buffer
+	if err := gopolls.WriteProtocol(&buf, coll, polls, results, gopolls.DefaultCurrencyHandler); err != nil {
+		t.Fatalf("Unexpected error writing protocol: %v", err)
+	}
+	protocol := buf.String()
+	for _, expected := range []string{
+		"- Rank 1: Red (score 9, average 4.50)\n",
+		"- Rank 2: Green (score 5, average 2.50)\n",
+		"- Rank 3: Blue (score 1, average 0.50)\n",
+		"- Runoff: Red 2, Green 0, no preference 0, winner Red\n",
+		"- Invalid votes: 1 (weight 1)\n",
+	} {
+		if !strings.Contains(protocol, expected) {
+			t.Errorf("Expected protocol to contain %q, got\n%s", expected, protocol)
+		}
+	}
+}
+
+func TestWriteProtocolResultError(t *testing.T) {
+	in := "# Assembly\n\n## Group\n\n### Motion\n* yes\n* no\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	motion := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(gopolls.NewVoter("one", gopolls.NoWeight-1), gopolls.Aye),
+		gopolls.NewBasicVote(gopolls.NewVoter("two", 1), gopolls.Aye),
+	})
+	polls := gopolls.PollMap{"Motion": motion}
+	results := map[string]gopolls.AbstractPollResult{"Motion": motion.Tally()}
+	var buf bytes.Buffer
+	if err := gopolls.WriteProtocol(&buf, coll, polls, results, gopolls.DefaultCurrencyHandler); err != nil {
+		t.Fatalf("Unexpected error writing protocol: %v", err)
+	}
+	protocol := buf.String()
+	if !strings.Contains(protocol, "*Result for \"Motion\" could not be computed: ") {
+		t.Errorf("Expected error in protocol, got\n%s", protocol)
+	}
+	if strings.Contains(protocol, "- Ayes:") {
+		t.Errorf("Expected no counters in protocol, got\n%s", protocol)
+	}
+}
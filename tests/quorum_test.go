@@ -0,0 +1,147 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"math/big"
+	"testing"
+)
+
+func TestQuorumRequiredWeight(t *testing.T) {
+	tests := []struct {
+		fraction *big.Rat
+		eligible gopolls.Weight
+		expected gopolls.Weight
+	}{
+		{gopolls.FiftyPercentMajority, 10, 5},
+		{gopolls.FiftyPercentMajority, 11, 6},
+		{gopolls.TwoThirdsMajority, 10, 7},
+		{big.NewRat(1, 1), 42, 42},
+		{big.NewRat(0, 1), 42, 0},
+		{gopolls.FiftyPercentMajority, 0, 0},
+	}
+
+	for _, tc := range tests {
+		quorum, err := gopolls.NewQuorum(tc.fraction)
+		if err != nil {
+			t.Errorf("unexpected error for quorum %s: %v", tc.fraction, err)
+			continue
+		}
+		res := quorum.RequiredWeight(tc.eligible)
+		if res != tc.expected {
+			t.Errorf("expected required weight for quorum %s and %d to be %d, but got %d",
+				tc.fraction, tc.eligible, tc.expected, res)
+		}
+	}
+}
+
+func TestQuorumInvalidFraction(t *testing.T) {
+	for _, fraction := range []*big.Rat{nil, big.NewRat(-1, 2), big.NewRat(3, 2)} {
+		var semanticErr gopolls.PollingSemanticError
+		if _, err := gopolls.NewQuorum(fraction); !errors.As(err, &semanticErr) {
+			t.Errorf("expected PollingSemanticError for quorum %v, got %v", fraction, err)
+		}
+		poll := gopolls.NewBasicPoll(nil)
+		if _, err := gopolls.CheckQuorum(poll, 10, fraction, true); !errors.As(err, &semanticErr) {
+			t.Errorf("expected PollingSemanticError checking quorum %v, got %v", fraction, err)
+		}
+	}
+}
+
+func TestCheckQuorumBasicPoll(t *testing.T) {
+	voterOne := gopolls.NewVoter("one", 1)
+	voterTwo := gopolls.NewVoter("two", 2)
+	voterThree := gopolls.NewVoter("three", 3)
+
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(voterOne, gopolls.Aye),
+		gopolls.NewBasicVote(voterTwo, gopolls.No),
+		gopolls.NewBasicVote(voterThree, gopolls.Abstention),
+	})
+
+	res, err := gopolls.CheckQuorum(poll, 10, gopolls.FiftyPercentMajority, true)
+	if err != nil {
+		t.Fatalf("unexpected error checking quorum: %v", err)
+	}
+	if res.ParticipatingWeight != 6 || res.RequiredWeight != 5 || !res.Met {
+		t.Errorf("expected quorum to be met with participating weight 6 and required weight 5, got %+v", res)
+	}
+
+	res, err = gopolls.CheckQuorum(poll, 10, gopolls.FiftyPercentMajority, false)
+	if err != nil {
+		t.Fatalf("unexpected error checking quorum: %v", err)
+	}
+	if res.ParticipatingWeight != 3 || res.Met {
+		t.Errorf("expected quorum not to be met with participating weight 3, got %+v", res)
+	}
+}
+
+func TestCheckQuorumSchulzePoll(t *testing.T) {
+	voterOne := gopolls.NewVoter("one", 4)
+	voterTwo := gopolls.NewVoter("two", 2)
+	voterThree := gopolls.NewVoter("three", 1)
+
+	poll := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(voterOne, gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(voterTwo, gopolls.NewSchulzeAbstention(3)),
+		// invalid length, never counted
+		gopolls.NewSchulzeVote(voterThree, gopolls.SchulzeRanking{0, 1}),
+	})
+
+	participating, err := gopolls.ParticipatingWeight(poll, true)
+	if err != nil {
+		t.Fatalf("unexpected error computing participating weight: %v", err)
+	}
+	if participating != 6 {
+		t.Errorf("expected participating weight to be 6, got %d instead", participating)
+	}
+
+	participating, err = gopolls.ParticipatingWeight(poll, false)
+	if err != nil {
+		t.Fatalf("unexpected error computing participating weight: %v", err)
+	}
+	if participating != 4 {
+		t.Errorf("expected participating weight to be 4, got %d instead", participating)
+	}
+}
+
+func TestParticipatingWeightOverflow(t *testing.T) {
+	big1, big2 := gopolls.NewVoter("one", gopolls.NoWeight-1), gopolls.NewVoter("two", 2)
+	polls := []gopolls.AbstractPoll{
+		gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(big1, gopolls.Aye),
+			gopolls.NewBasicVote(big2, gopolls.No),
+		}),
+		gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(big1, 100),
+			gopolls.NewMedianVote(big2, 0),
+		}),
+		gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(big1, gopolls.SchulzeRanking{0, 1}),
+			gopolls.NewSchulzeVote(big2, gopolls.SchulzeRanking{1, 0}),
+		}),
+		gopolls.NewScorePoll(2, 5, []*gopolls.ScoreVote{
+			gopolls.NewScoreVote(big1, []uint8{5, 0}),
+			gopolls.NewScoreVote(big2, []uint8{0, 5}),
+		}),
+	}
+	for _, poll := range polls {
+		if _, err := gopolls.ParticipatingWeight(poll, true); !errors.Is(err, gopolls.ErrWeightOverflow) {
+			t.Errorf("Expected ErrWeightOverflow for poll of type %s, got %v", poll.PollType(), err)
+		}
+	}
+}
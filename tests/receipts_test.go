@@ -0,0 +1,103 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+func TestFormatSchulzeRankingWithOptions(t *testing.T) {
+	options := []string{"A", "B", "C", "No"}
+	ranking := gopolls.SchulzeRanking{1, 0, 1, 2}
+	formatted, err := ranking.FormatWithOptions(options)
+	if err != nil {
+		t.Fatalf("Unexpected error formatting ranking: %v", err)
+	}
+	if formatted != "B > A = C > No" {
+		t.Errorf("Expected \"B > A = C > No\", got \"%s\"", formatted)
+	}
+	parsed, parseErr := gopolls.NewSchulzeVoteParser(-1).WithOptions(options).ParseFromString(formatted, nil)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing formatted ranking: %v", parseErr)
+	}
+	if got := parsed.(*gopolls.SchulzeVote).Ranking; !got.Equals(gopolls.SchulzeRanking{1, 0, 1, 2}) {
+		t.Errorf("Expected ranking to be parsed again, got %v", got)
+	}
+	if _, err = ranking.FormatWithOptions(options[:2]); err == nil {
+		t.Error("Expected error for wrong number of options")
+	}
+}
+
+func TestGenerateVoterReceipts(t *testing.T) {
+	in := "# Title\n## Group 1\n### Motion\n* yes\n* no\n### Budget\n- 100€\n## Group 2\n### Chair\n* A\n* B\n* C\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2)
+	polls := gopolls.PollMap{
+		"Motion": gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(one, gopolls.Aye),
+			gopolls.NewBasicVote(two, gopolls.Abstention),
+		}),
+		"Budget": gopolls.NewMedianPoll(10000, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(one, 4250),
+		}),
+		"Chair": gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{1, 0, 0}),
+		}),
+	}
+	formatter := gopolls.SimpleEuroHandler{}
+	receipts, err := gopolls.GenerateVoterReceipts(polls, coll, formatter)
+	if err != nil {
+		t.Fatalf("Unexpected error generating receipts: %v", err)
+	}
+	expected := map[string][]gopolls.ReceiptEntry{
+		"one": {
+			{Poll: "Motion", Vote: "aye", HasVoted: true},
+			{Poll: "Budget", Vote: formatter.Format(gopolls.NewCurrencyValue(4250, "€")), HasVoted: true},
+		},
+		"two": {
+			{Poll: "Motion", Vote: "abstention", HasVoted: true},
+			{Poll: "Chair", Vote: "B = C > A", HasVoted: true},
+		},
+	}
+	if !reflect.DeepEqual(receipts, expected) {
+		t.Errorf("Expected receipts %v, got %v", expected, receipts)
+	}
+
+	receipts, err = gopolls.GenerateVoterReceipts(polls, coll, formatter, gopolls.WithNoVoteEntries())
+	if err != nil {
+		t.Fatalf("Unexpected error generating receipts: %v", err)
+	}
+	expectedTwo := []gopolls.ReceiptEntry{
+		{Poll: "Motion", Vote: "abstention", HasVoted: true},
+		{Poll: "Budget", Vote: gopolls.NoVoteReceiptString, HasVoted: false},
+		{Poll: "Chair", Vote: "B = C > A", HasVoted: true},
+	}
+	if !reflect.DeepEqual(receipts["two"], expectedTwo) {
+		t.Errorf("Expected receipt %v, got %v", expectedTwo, receipts["two"])
+	}
+
+	delete(polls, "Chair")
+	_, err = gopolls.GenerateVoterReceipts(polls, coll, formatter)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for a missing poll, got %v", err)
+	}
+}
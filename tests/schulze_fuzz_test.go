@@ -0,0 +1,54 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+// FuzzSchulzeVoteParser makes sure that the parser never panics and that each ranking it accepts respects the
+// configured length and bounds.
+func FuzzSchulzeVoteParser(f *testing.F) {
+	seeds := []string{"0, 1, 2", "1/0/1", "-1, 2, 3", "9223372036854775807, 0, 0", "A > B = C", "", ",,,", "0, 1"}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	options := []string{"A", "B", "C"}
+	parser := gopolls.NewSchulzeVoteParser(3).WithOptions(options)
+	parser.RequirePermutationLike = true
+	f.Fuzz(func(t *testing.T, s string) {
+		vote, err := parser.ParseFromString(s, nil)
+		if err != nil {
+			if !errors.Is(err, gopolls.ErrPoll) {
+				t.Fatalf("Expected an internal error for %q, got %v", s, err)
+			}
+			return
+		}
+		ranking := vote.(*gopolls.SchulzeVote).Ranking
+		if len(ranking) != len(options) {
+			t.Fatalf("Ranking %v for %q has the wrong length", ranking, s)
+		}
+		for _, value := range ranking {
+			if value < 0 || value >= len(options) {
+				t.Fatalf("Ranking %v for %q contains a value out of bounds", ranking, s)
+			}
+		}
+	})
+}
@@ -15,8 +15,13 @@
 package tests
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/FabianWe/gopolls"
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -231,3 +236,778 @@ func TestSmallComputeD(t *testing.T) {
 		t.Errorf("Expected better or equal than no list to be %v, but got %v instead", expectedBetterOrEqualNo, betterOrEqualNo)
 	}
 }
+
+func TestSchulzeWikiMargins(t *testing.T) {
+	// same example as in TestSchulzeWikiOne, but with margins as link strength
+	votes := getSchulzeVotesTesting(8, []gopolls.Weight{5, 5, 8, 3, 7, 2, 7, 8}, 5)
+	votes[0].Ranking = gopolls.SchulzeRanking{1, 3, 2, 5, 4}
+	votes[1].Ranking = gopolls.SchulzeRanking{1, 5, 4, 2, 3}
+	votes[2].Ranking = gopolls.SchulzeRanking{4, 1, 5, 3, 2}
+	votes[3].Ranking = gopolls.SchulzeRanking{2, 3, 1, 5, 4}
+	votes[4].Ranking = gopolls.SchulzeRanking{2, 4, 1, 5, 3}
+	votes[5].Ranking = gopolls.SchulzeRanking{3, 2, 1, 4, 5}
+	votes[6].Ranking = gopolls.SchulzeRanking{5, 4, 2, 1, 3}
+	votes[7].Ranking = gopolls.SchulzeRanking{3, 2, 5, 4, 1}
+
+	poll := gopolls.NewSchulzePoll(5, votes)
+	res := poll.TallyWithVariant(gopolls.SchulzeMargins)
+
+	if res.Variant != gopolls.SchulzeMargins {
+		t.Errorf("Expected variant to be %s, got %s instead", gopolls.SchulzeMargins, res.Variant)
+	}
+
+	expectedMargins := gopolls.SchulzeMarginMatrix{
+		{0, -5, 7, 15, -1},
+		{5, 0, -13, 21, -9},
+		{-7, 13, 0, -11, 3},
+		{-15, -21, 11, 0, -17},
+		{1, 9, -3, 17, 0},
+	}
+	if !expectedMargins.Equals(res.Margins) {
+		t.Errorf("Expected margins to be %v, but got %v instead", expectedMargins, res.Margins)
+		return
+	}
+
+	expectedP := gopolls.SchulzeMatrix{
+		{0, 11, 11, 15, 3},
+		{5, 0, 11, 21, 3},
+		{5, 13, 0, 13, 3},
+		{5, 11, 11, 0, 3},
+		{5, 11, 11, 17, 0},
+	}
+	if !expectedP.Equals(res.P) {
+		t.Errorf("Expected matrix p to be %v, but got %v instead", expectedP, res.P)
+		return
+	}
+
+	// the winners are the same as with winning votes: E > A > C > B > D
+	expectedRanking := [][]int{{4}, {0}, {2}, {1}, {3}}
+	if len(res.RankedGroups) != len(expectedRanking) {
+		t.Fatalf("Expected ranked groups %v, got %v instead", expectedRanking, res.RankedGroups)
+	}
+	for i, group := range expectedRanking {
+		if !compareCandidateGroup(group, res.RankedGroups[i]) {
+			t.Errorf("Expected in group %d the following list of options: %v. got %v instead",
+				i, group, res.RankedGroups[i])
+		}
+	}
+}
+
+func TestSchulzeVoteParserNamedRanking(t *testing.T) {
+	parser := gopolls.NewSchulzeVoteParser(-1).WithOptions([]string{"Budget A", "Budget B", "No"})
+	voter := gopolls.NewVoter("voter", 1)
+	tests := []struct {
+		in       string
+		expected gopolls.SchulzeRanking
+	}{
+		{"Budget B > Budget A = No", gopolls.SchulzeRanking{1, 0, 1}},
+		{"  no > budget a > BUDGET B ", gopolls.SchulzeRanking{1, 2, 0}},
+		{"Budget A = Budget B = No", gopolls.SchulzeRanking{0, 0, 0}},
+		{"1, 0, 1", gopolls.SchulzeRanking{1, 0, 1}},
+	}
+	for _, tc := range tests {
+		vote, err := parser.ParseFromString(tc.in, voter)
+		if err != nil {
+			t.Errorf("Unexpected error parsing \"%s\": %v", tc.in, err)
+			continue
+		}
+		if ranking := vote.(*gopolls.SchulzeVote).Ranking; !reflect.DeepEqual(ranking, tc.expected) {
+			t.Errorf("Expected ranking %v for \"%s\", got %v instead", tc.expected, tc.in, ranking)
+		}
+	}
+
+	invalid := []string{
+		"Budget A > Budget B",
+		"Budget A > Budget B > No > No",
+		"Budget A > Budget C > No",
+		"Budget A >> Budget B = No",
+	}
+	for _, in := range invalid {
+		if _, err := parser.ParseFromString(in, voter); err == nil {
+			t.Errorf("Expected an error for \"%s\"", in)
+		}
+	}
+	_, missingErr := parser.ParseFromString("Budget A > No", voter)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(missingErr, &semanticErr) || !strings.Contains(missingErr.Error(), "Budget B") {
+		t.Errorf("Expected a PollingSemanticError mentioning the missing option, got %v", missingErr)
+	}
+
+	// customizing keeps the options if the length matches
+	customized, customizeErr := parser.CustomizeForPoll(gopolls.NewSchulzePoll(3, nil))
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error customizing parser: %v", customizeErr)
+	}
+	if len(customized.(*gopolls.SchulzeVoteParser).Options) != 3 {
+		t.Error("Expected options to be kept by CustomizeForPoll")
+	}
+	customized, _ = parser.CustomizeForPoll(gopolls.NewSchulzePoll(4, nil))
+	if customized.(*gopolls.SchulzeVoteParser).Options != nil {
+		t.Error("Expected options to be removed by CustomizeForPoll for a different length")
+	}
+}
+
+func TestSchulzeCondorcetWinner(t *testing.T) {
+	one, two, three := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1), gopolls.NewVoter("three", 1)
+	// option 1 beats 0 and 2 in direct comparison
+	res := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{1, 0, 2}),
+		gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(three, gopolls.SchulzeRanking{2, 0, 1}),
+	}).Tally()
+	if winner, ok := res.CondorcetWinner(); !ok || winner != 1 {
+		t.Errorf("Expected Condorcet winner 1, got %d (%v)", winner, ok)
+	}
+	if !res.CondorcetConsistent {
+		t.Error("Expected Schulze winner to be the Condorcet winner")
+	}
+	if ties := res.PairwiseTies(); len(ties) != 0 {
+		t.Errorf("Expected no ties, got %v", ties)
+	}
+
+	// cycle: no Condorcet winner
+	res = gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{2, 0, 1}),
+		gopolls.NewSchulzeVote(three, gopolls.SchulzeRanking{1, 2, 0}),
+	}).Tally()
+	if winner, ok := res.CondorcetWinner(); ok || winner != -1 {
+		t.Errorf("Expected no Condorcet winner, got %d", winner)
+	}
+	if res.CondorcetConsistent {
+		t.Error("CondorcetConsistent must be false without a Condorcet winner")
+	}
+
+	// ties between 0 and 2
+	res = gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{1, 0, 1}),
+	}).Tally()
+	if ties := res.PairwiseTies(); !reflect.DeepEqual(ties, [][2]int{{0, 2}}) {
+		t.Errorf("Expected ties [[0 2]], got %v", ties)
+	}
+
+	// edge cases must not panic
+	for _, numOptions := range []int{0, 1, 3} {
+		res = gopolls.NewSchulzePoll(numOptions, nil).Tally()
+		if winner, ok := res.CondorcetWinner(); ok || winner != -1 {
+			t.Errorf("Expected no Condorcet winner for %d options without votes, got %d", numOptions, winner)
+		}
+	}
+}
+
+func TestSchulzeNoOptionIndex(t *testing.T) {
+	voter := gopolls.NewVoter("one", 1)
+	poll := gopolls.NewSchulzePoll(3, nil)
+	if poll.NoOptionIndex != 2 {
+		t.Errorf("Expected no option to be the last option by default, got %d", poll.NoOptionIndex)
+	}
+
+	poll.NoOptionIndex = 0
+	tests := []struct {
+		answer   gopolls.BasicPollAnswer
+		expected gopolls.SchulzeRanking
+	}{
+		{gopolls.Aye, gopolls.SchulzeRanking{1, 0, 0}},
+		{gopolls.No, gopolls.SchulzeRanking{0, 1, 1}},
+		{gopolls.Abstention, gopolls.SchulzeRanking{0, 0, 0}},
+	}
+	for _, tc := range tests {
+		vote, err := poll.GenerateVoteFromBasicAnswer(voter, tc.answer)
+		if err != nil {
+			t.Fatalf("Unexpected error generating vote for %s: %v", tc.answer, err)
+		}
+		if got := vote.(*gopolls.SchulzeVote).Ranking; !got.Equals(tc.expected) {
+			t.Errorf("Expected ranking %v for %s, got %v", tc.expected, tc.answer, got)
+		}
+		if err = poll.AddVote(vote); err != nil {
+			t.Fatalf("Unexpected error adding vote: %v", err)
+		}
+	}
+	res := poll.Tally()
+	if res.NoOptionIndex != 0 {
+		t.Errorf("Expected no option index 0 in result, got %d", res.NoOptionIndex)
+	}
+	// option 1 and 2 are strictly better than no in the No vote
+	if got := res.StrictlyBetterThanNo(); !compareWeightLists([]gopolls.Weight{0, 1, 1}, got) {
+		t.Errorf("Expected strictly better than no list [0 1 1], got %v", got)
+	}
+	if got := res.StrictlyBetterThan(1); !compareWeightLists([]gopolls.Weight{1, 0, 0}, got) {
+		t.Errorf("Expected strictly better than option 1 list [1 0 0], got %v", got)
+	}
+	if got := res.BetterOrEqual(5); got != nil {
+		t.Errorf("Expected nil for invalid index, got %v", got)
+	}
+
+	poll.NoOptionIndex = gopolls.NoSchulzeNoOption
+	for _, answer := range []gopolls.BasicPollAnswer{gopolls.Aye, gopolls.No} {
+		_, err := poll.GenerateVoteFromBasicAnswer(voter, answer)
+		var typeErr gopolls.PollTypeError
+		if !errors.As(err, &typeErr) {
+			t.Errorf("Expected PollTypeError for %s without no option, got %v", answer, err)
+		}
+	}
+	if _, err := poll.GenerateVoteFromBasicAnswer(voter, gopolls.Abstention); err != nil {
+		t.Errorf("Unexpected error for abstention without no option: %v", err)
+	}
+	if got := poll.Tally().StrictlyBetterThanNo(); got != nil {
+		t.Errorf("Expected nil without no option, got %v", got)
+	}
+}
+
+func TestSkeletonConverterWithNoOption(t *testing.T) {
+	skel := gopolls.NewPollSkeleton("Chair")
+	skel.Options = []string{"No", "A", "B"}
+	tests := []struct {
+		index       int
+		expected    int
+		expectError bool
+	}{
+		{gopolls.LastSchulzeNoOption, 2, false},
+		{gopolls.NoSchulzeNoOption, gopolls.NoSchulzeNoOption, false},
+		{0, 0, false},
+		{3, 0, true},
+	}
+	for _, tc := range tests {
+		converter := gopolls.NewDefaultSkeletonConverterWithNoOption(true, tc.index)
+		poll, err := converter(skel)
+		if tc.expectError {
+			var typeErr gopolls.PollTypeError
+			if !errors.As(err, &typeErr) {
+				t.Errorf("Expected PollTypeError for index %d, got %v", tc.index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error converting skeleton with index %d: %v", tc.index, err)
+		}
+		if got := poll.(*gopolls.SchulzePoll).NoOptionIndex; got != tc.expected {
+			t.Errorf("Expected no option index %d, got %d", tc.expected, got)
+		}
+	}
+}
+
+func TestSchulzeVoteParserBounds(t *testing.T) {
+	var semanticErr gopolls.PollingSemanticError
+	parser := gopolls.NewSchulzeVoteParser(3)
+	if _, err := parser.ParseFromString("-5, 100, 2", nil); err != nil {
+		t.Errorf("Unexpected error without bounds: %v", err)
+	}
+	bounded := parser.WithBounds(0, 10)
+	_, err := bounded.ParseFromString("1, 11, 2", nil)
+	if !errors.As(err, &semanticErr) || !strings.Contains(err.Error(), "value 11 at position 1") {
+		t.Errorf("Expected PollingSemanticError for value 11 at position 1, got %v", err)
+	}
+	if _, err = bounded.WithLength(4).ParseFromString("0, 10, 2, 3", nil); err != nil {
+		t.Errorf("Unexpected error: bounds must be kept by WithLength, got %v", err)
+	}
+	// the zero value of the bounds doesn't restrict the values
+	zero := &gopolls.SchulzeVoteParser{Length: 3}
+	if _, err = zero.ParseFromString("2, 0, 1", nil); err != nil {
+		t.Errorf("Unexpected error for a parser without bounds: %v", err)
+	}
+	// bounds (0, 0) allow only the value 0
+	onlyZero := parser.WithBounds(0, 0)
+	if _, err = onlyZero.ParseFromString("0, 0, 0", nil); err != nil {
+		t.Errorf("Unexpected error for bounds [0, 0]: %v", err)
+	}
+	_, err = onlyZero.ParseFromString("0, 1, 0", nil)
+	if !errors.As(err, &semanticErr) || !strings.Contains(err.Error(), "value 1 at position 1") {
+		t.Errorf("Expected PollingSemanticError for value 1 at position 1, got %v", err)
+	}
+
+	permutation := gopolls.NewSchulzeVoteParser(-1)
+	permutation.RequirePermutationLike = true
+	if _, err = permutation.ParseFromString("0, 2, 1", nil); err != nil {
+		t.Errorf("Unexpected error for permutation like ranking: %v", err)
+	}
+	_, err = permutation.ParseFromString("0, 3, 1", nil)
+	if !errors.As(err, &semanticErr) || !strings.Contains(err.Error(), "value 3 at position 1") {
+		t.Errorf("Expected PollingSemanticError for value 3 at position 1, got %v", err)
+	}
+
+	customized, customizeErr := permutation.CustomizeForPoll(gopolls.NewSchulzePoll(4, nil))
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error customizing parser: %v", customizeErr)
+	}
+	asSchulzeParser := customized.(*gopolls.SchulzeVoteParser)
+	if !asSchulzeParser.HasBounds || asSchulzeParser.MinValue != 0 || asSchulzeParser.MaxValue != 3 ||
+		asSchulzeParser.Length != 4 {
+		t.Errorf("Expected bounds [0, 3] and length 4, got %+v", asSchulzeParser)
+	}
+}
+
+func TestSchulzeVoteParserMaxRankingLength(t *testing.T) {
+	var validationErr *gopolls.ParserValidationError
+	var semanticErr gopolls.PollingSemanticError
+	parser := gopolls.NewSchulzeVoteParser(-1)
+	if parser.MaxRankingLength != gopolls.DefaultMaxRankingLength {
+		t.Errorf("Expected MaxRankingLength %d, got %d", gopolls.DefaultMaxRankingLength, parser.MaxRankingLength)
+	}
+	long := strings.Repeat("1,", gopolls.DefaultMaxRankingLength) + "1"
+	if _, err := parser.ParseFromString(long, nil); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for ranking with too many values, got %v", err)
+	}
+	// the limit is checked before the values are parsed
+	if _, err := parser.ParseFromString(long+", foo", nil); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for ranking with too many values, got %v", err)
+	}
+	if _, err := parser.ParseFromString(strings.Repeat("1,", 10)+"1", nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	small := parser.WithLength(-1)
+	small.MaxRankingLength = 3
+	if _, err := small.ParseFromString("1,,2//0", nil); err != nil {
+		t.Errorf("Unexpected error: empty values must be ignored, got %v", err)
+	}
+	if _, err := small.ParseFromString("1, 2, 0, 3", nil); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError, got %v", err)
+	}
+	small.MaxRankingLength = -1
+	if _, err := small.ParseFromString(long, nil); err != nil {
+		t.Errorf("Unexpected error without limit: %v", err)
+	}
+
+	_, err := gopolls.NewSchulzeVoteParser(3).ParseFromString("1, 2, 0, 3", nil)
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for ranking longer than length, got %v", err)
+	}
+}
+
+func TestSchulzeVoteParserPartial(t *testing.T) {
+	var semanticErr gopolls.PollingSemanticError
+	poll := gopolls.NewSchulzePoll(4, nil)
+	template := gopolls.NewSchulzeVoteParser(-1)
+	template.AllowPartial = true
+	customized, customizeErr := template.WithOptions([]string{"A", "B", "C", "D"}).CustomizeForPoll(poll)
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error customizing parser: %v", customizeErr)
+	}
+	parser := customized.(*gopolls.SchulzeVoteParser)
+	unranked := gopolls.SchulzeUnranked
+	tests := []struct {
+		in       string
+		expected gopolls.SchulzeRanking
+	}{
+		{"0, 1", gopolls.SchulzeRanking{0, 1, unranked, unranked}},
+		{"1, 0, 1, 2", gopolls.SchulzeRanking{1, 0, 1, 2}},
+		{"C > A", gopolls.SchulzeRanking{1, unranked, 0, unranked}},
+		{"B = D", gopolls.SchulzeRanking{unranked, 0, unranked, 0}},
+	}
+	voters := []*gopolls.Voter{gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 1),
+		gopolls.NewVoter("four", 3)}
+	explicit := gopolls.NewSchulzePoll(4, nil)
+	for i, tc := range tests {
+		vote, err := parser.ParseFromString(tc.in, voters[i])
+		if err != nil {
+			t.Fatalf("Unexpected error parsing \"%s\": %v", tc.in, err)
+		}
+		ranking := vote.(*gopolls.SchulzeVote).Ranking
+		if !ranking.Equals(tc.expected) {
+			t.Errorf("Expected ranking %v for \"%s\", got %v", tc.expected, tc.in, ranking)
+		}
+		if addErr := poll.AddVote(vote); addErr != nil {
+			t.Fatalf("Unexpected error adding vote: %v", addErr)
+		}
+		// the same ballot with the unranked options ranked last explicitly
+		explicitRanking := make(gopolls.SchulzeRanking, len(ranking))
+		for j, value := range ranking {
+			if value == unranked {
+				value = 4
+			}
+			explicitRanking[j] = value
+		}
+		if addErr := explicit.AddVote(gopolls.NewSchulzeVote(voters[i], explicitRanking)); addErr != nil {
+			t.Fatalf("Unexpected error adding vote: %v", addErr)
+		}
+	}
+	if culprits := poll.TruncateVoters(); len(culprits) != 0 {
+		t.Errorf("Expected no culprits for partial ballots, got %v", culprits)
+	}
+	res, expected := poll.Tally(), explicit.Tally()
+	if res.InvalidVotesCount != 0 || !reflect.DeepEqual(res.D, expected.D) ||
+		!reflect.DeepEqual(res.RankedGroups, expected.RankedGroups) {
+		t.Errorf("Expected result %+v for partial ballots, got %+v", expected, res)
+	}
+
+	if _, err := parser.ParseFromString("0, 1, 2, 3, 4", nil); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for ranking longer than the poll, got %v", err)
+	}
+	custom := parser.WithLength(3)
+	custom.UnrankedValue = 5
+	vote, err := custom.ParseFromString("2", nil)
+	if err != nil || !vote.(*gopolls.SchulzeVote).Ranking.Equals(gopolls.SchulzeRanking{2, 5, 5}) {
+		t.Errorf("Expected ranking [2, 5, 5], got %v (error %v)", vote, err)
+	}
+	if _, err = custom.ParseFromString("6, 0", nil); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for value greater than the unranked value, got %v", err)
+	}
+	if _, err = gopolls.NewSchulzeVoteParser(4).ParseFromString("0, 1", nil); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for partial ranking in strict mode, got %v", err)
+	}
+}
+
+func TestSchulzeAddRemoveOption(t *testing.T) {
+	var semanticErr gopolls.PollingSemanticError
+	votes := []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(gopolls.NewVoter("one", 1), gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("two", 2), gopolls.SchulzeRanking{1, 0, 1}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("three", 1), gopolls.NewSchulzeAbstention(3)),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("four", 3), gopolls.SchulzeRanking{0, gopolls.SchulzeUnranked, 1}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("five", 1), gopolls.SchulzeRanking{0, 1}),
+	}
+	poll := gopolls.NewSchulzePoll(3, votes)
+
+	// the ranking of five has a wrong length, after adding or removing an option it could be counted
+	invalid := poll.DeepClone()
+	if err := poll.AddOption(1, true); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError adding option to poll with an invalid ranking, got %v", err)
+	}
+	if err := poll.RemoveOption(2); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError removing option from poll with an invalid ranking, got %v", err)
+	}
+	if !poll.Equals(invalid) {
+		t.Errorf("Expected poll not to be changed, got %v", poll.Votes)
+	}
+	if culprits := poll.TruncateVoters(); len(culprits) != 1 || culprits[0].Voter.Name != "five" {
+		t.Fatalf("Expected vote of five to be truncated, got %v", culprits)
+	}
+
+	original := poll.DeepClone()
+	before := poll.Tally()
+
+	if err := poll.AddOption(1, false); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError adding option to filled poll, got %v", err)
+	}
+	if err := poll.AddOption(4, true); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for invalid position, got %v", err)
+	}
+	if err := poll.AddOption(1, true); err != nil {
+		t.Fatalf("Unexpected error adding option: %v", err)
+	}
+	expectedRankings := []gopolls.SchulzeRanking{
+		{0, 3, 1, 2},
+		{1, 2, 0, 1},
+		{0, 0, 0, 0},
+		{0, gopolls.SchulzeUnranked, gopolls.SchulzeUnranked, 1},
+	}
+	for i, vote := range poll.Votes {
+		if !vote.Ranking.Equals(expectedRankings[i]) {
+			t.Errorf("Expected ranking %v for vote %d, got %v", expectedRankings[i], i, vote.Ranking)
+		}
+	}
+	if poll.NumOptions != 4 || poll.NoOptionIndex != 3 {
+		t.Errorf("Expected 4 options and no option 3, got %d and %d", poll.NumOptions, poll.NoOptionIndex)
+	}
+	if !original.Votes[0].Ranking.Equals(gopolls.SchulzeRanking{0, 1, 2}) {
+		t.Error("Adding an option changed the rankings of the clone")
+	}
+	// the comparisons between the old options must not change
+	after := poll.Tally()
+	oldIndices := []int{0, 2, 3}
+	for i, oldI := range oldIndices {
+		for j, oldJ := range oldIndices {
+			if before.D[i][j] != after.D[oldI][oldJ] {
+				t.Errorf("Expected d[%d][%d] = %d after adding option, got %d", oldI, oldJ, before.D[i][j],
+					after.D[oldI][oldJ])
+			}
+		}
+	}
+	if after.InvalidVotesCount != before.InvalidVotesCount {
+		t.Errorf("Expected %d invalid votes, got %d", before.InvalidVotesCount, after.InvalidVotesCount)
+	}
+
+	if err := poll.RemoveOption(4); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for invalid index, got %v", err)
+	}
+	if err := poll.RemoveOption(1); err != nil {
+		t.Fatalf("Unexpected error removing option: %v", err)
+	}
+	if !poll.Equals(original) {
+		t.Errorf("Expected poll %v after removing the added option, got %v", original.Votes, poll.Votes)
+	}
+	if res := poll.Tally(); !reflect.DeepEqual(res.D, before.D) || !reflect.DeepEqual(res.RankedGroups,
+		before.RankedGroups) {
+		t.Errorf("Expected result %+v after removing the added option, got %+v", before, res)
+	}
+
+	if err := poll.RemoveOption(2); err != nil {
+		t.Fatalf("Unexpected error removing option: %v", err)
+	}
+	if poll.NumOptions != 2 || poll.NoOptionIndex != gopolls.NoSchulzeNoOption {
+		t.Errorf("Expected 2 options and no no option, got %d and %d", poll.NumOptions, poll.NoOptionIndex)
+	}
+	empty := gopolls.NewSchulzePoll(2, nil)
+	if err := empty.AddOption(0, false); err != nil || empty.NumOptions != 3 || empty.NoOptionIndex != 2 {
+		t.Errorf("Expected option to be added to empty poll, got %v (%d options, no option %d)", err,
+			empty.NumOptions, empty.NoOptionIndex)
+	}
+}
+
+func TestSchulzeResultWriteDOT(t *testing.T) {
+	votes := getSchulzeVotesTesting(4, []gopolls.Weight{3, 2, 2, 2}, 4)
+	votes[0].Ranking = gopolls.SchulzeRanking{1, 2, 3, 4}
+	votes[1].Ranking = gopolls.SchulzeRanking{2, 3, 4, 1}
+	votes[2].Ranking = gopolls.SchulzeRanking{4, 2, 3, 1}
+	votes[3].Ranking = gopolls.SchulzeRanking{4, 2, 1, 3}
+	res := gopolls.NewSchulzePoll(4, votes).Tally()
+
+	var buf bytes.Buffer
+	if err := res.WriteDOT(&buf, []string{"A", "B", "C", "Option \"D\""}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertGolden(t, "schulze_result.dot", buf.Bytes())
+
+	buf.Reset()
+	if err := res.WriteDOT(&buf, nil, gopolls.WithDOTRawMatrix()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertGolden(t, "schulze_result_d.dot", buf.Bytes())
+
+	buf.Reset()
+	var semanticErr gopolls.PollingSemanticError
+	if err := res.WriteDOT(&buf, []string{"A", "B"}); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for wrong number of option names, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output on error, got %q", buf.String())
+	}
+}
+
+func TestSchulzeSecondaryOrder(t *testing.T) {
+	votes := []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(gopolls.NewVoter("one", 2), gopolls.SchulzeRanking{0, 1, 2, 1, 1}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("two", 1), gopolls.SchulzeRanking{2, 1, 1, 2, 0}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("three", 3), gopolls.SchulzeRanking{0, 0, 0, 0, 0}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("four", 2), gopolls.SchulzeRanking{1, 1, 0, 1, 2}),
+	}
+	paths, secondary, tied := gopolls.SchulzeRankedByPaths, gopolls.SchulzeRankedBySecondaryOrder, gopolls.SchulzeRankedTied
+	tests := []struct {
+		order           gopolls.SchulzeSecondaryOrder
+		expectedRanking []int
+		expectedRules   []gopolls.SchulzeRankingRule
+	}{
+		{gopolls.SchulzeNoSecondaryOrder, []int{0, 2, 4, 1, 3}, []gopolls.SchulzeRankingRule{paths, tied, paths, tied}},
+		{gopolls.SchulzeDirectComparison, []int{0, 4, 2, 1, 3}, []gopolls.SchulzeRankingRule{paths, secondary, paths, secondary}},
+		{gopolls.SchulzeTotalWinsWeight, []int{0, 2, 4, 1, 3}, []gopolls.SchulzeRankingRule{paths, secondary, paths, secondary}},
+	}
+	expectedGroups := gopolls.SchulzeWinsList{{0}, {2, 4}, {1, 3}}
+	for _, tc := range tests {
+		poll := gopolls.NewSchulzePoll(5, votes)
+		poll.SecondaryOrder = tc.order
+		res := poll.Tally()
+		if !res.RankedGroups.Equals(expectedGroups) {
+			t.Errorf("Expected groups %v for order %s, got %v", expectedGroups, tc.order, res.RankedGroups)
+		}
+		if res.SecondaryOrder != tc.order {
+			t.Errorf("Expected secondary order %s in result, got %s", tc.order, res.SecondaryOrder)
+		}
+		if !reflect.DeepEqual(res.Ranking, tc.expectedRanking) {
+			t.Errorf("Expected ranking %v for order %s, got %v", tc.expectedRanking, tc.order, res.Ranking)
+		}
+		if !reflect.DeepEqual(res.RankingRules, tc.expectedRules) {
+			t.Errorf("Expected rules %v for order %s, got %v", tc.expectedRules, tc.order, res.RankingRules)
+		}
+	}
+}
+
+// naiveSchulzeD computes d and dNonStrict by comparing each pair of options for each vote, it is used to cross-check
+// the optimized implementation in SchulzePoll.
+func naiveSchulzeD(numOptions int, votes []*gopolls.SchulzeVote) (gopolls.SchulzeMatrix, gopolls.SchulzeMatrix) {
+	d, dNonStrict := gopolls.NewSchulzeMatrix(numOptions), gopolls.NewSchulzeMatrix(numOptions)
+	for _, vote := range votes {
+		w := vote.Voter.EffectiveWeight()
+		ranking := vote.Ranking
+		if len(ranking) != numOptions {
+			continue
+		}
+		for i := 0; i < numOptions; i++ {
+			for j := i + 1; j < numOptions; j++ {
+				switch {
+				case ranking[i] < ranking[j]:
+					d[i][j] += w
+					dNonStrict[i][j] += w
+				case ranking[j] < ranking[i]:
+					d[j][i] += w
+					dNonStrict[j][i] += w
+				default:
+					dNonStrict[i][j] += w
+					dNonStrict[j][i] += w
+				}
+			}
+		}
+	}
+	return d, dNonStrict
+}
+
+// randomSchulzeVotes generates votes with a mix of abstentions, aye / no style rankings, repeated rankings and
+// arbitrary rankings.
+func randomSchulzeVotes(rnd *rand.Rand, numVotes, numOptions int) []*gopolls.SchulzeVote {
+	res := make([]*gopolls.SchulzeVote, numVotes)
+	for i := range res {
+		voter := gopolls.NewVoter(fmt.Sprintf("voter-%d", i), gopolls.Weight(rnd.Intn(5)))
+		var ranking gopolls.SchulzeRanking
+		switch rnd.Intn(4) {
+		case 0:
+			ranking = gopolls.NewSchulzeAbstention(numOptions)
+		case 1:
+			ranking = gopolls.NewSchulzeAyeAt(numOptions, rnd.Intn(numOptions))
+		case 2:
+			ranking = gopolls.NewSchulzeNoAt(numOptions, rnd.Intn(numOptions))
+		default:
+			ranking = make(gopolls.SchulzeRanking, numOptions)
+			for j := range ranking {
+				ranking[j] = rnd.Intn(numOptions) - 1
+			}
+		}
+		if rnd.Intn(20) == 0 {
+			// wrong length, must be ignored
+			ranking = ranking[:numOptions-1]
+		}
+		res[i] = gopolls.NewSchulzeVote(voter, ranking)
+	}
+	return res
+}
+
+func TestSchulzeDCrossCheck(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	for run := 0; run < 200; run++ {
+		numOptions := 2 + rnd.Intn(10)
+		votes := randomSchulzeVotes(rnd, rnd.Intn(50), numOptions)
+		expectedD, expectedNonStrict := naiveSchulzeD(numOptions, votes)
+		res := gopolls.NewSchulzePoll(numOptions, votes).Tally()
+		if res.Err != nil {
+			t.Fatalf("Unexpected error in run %d: %s", run, res.Err)
+		}
+		if !res.D.Equals(expectedD) {
+			t.Fatalf("Wrong matrix d in run %d: Expected %v, got %v", run, expectedD, res.D)
+		}
+		if !res.DNonStrict.Equals(expectedNonStrict) {
+			t.Fatalf("Wrong matrix dNonStrict in run %d: Expected %v, got %v", run, expectedNonStrict, res.DNonStrict)
+		}
+	}
+}
+
+// benchmarkSchulzeVotes returns 2000 votes for 60 options, most of them abstentions or aye / no style rankings.
+func benchmarkSchulzeVotes() []*gopolls.SchulzeVote {
+	rnd := rand.New(rand.NewSource(21))
+	votes := make([]*gopolls.SchulzeVote, 2000)
+	for i := range votes {
+		voter := gopolls.NewVoter(fmt.Sprintf("voter-%d", i), 1)
+		var ranking gopolls.SchulzeRanking
+		switch r := rnd.Intn(10); {
+		case r < 6:
+			ranking = gopolls.NewSchulzeAbstention(60)
+		case r < 9:
+			ranking = gopolls.NewSchulzeAyeAt(60, rnd.Intn(3))
+		default:
+			ranking = make(gopolls.SchulzeRanking, 60)
+			for j := range ranking {
+				ranking[j] = rnd.Intn(60)
+			}
+		}
+		votes[i] = gopolls.NewSchulzeVote(voter, ranking)
+	}
+	return votes
+}
+
+func BenchmarkSchulzeNaiveD(b *testing.B) {
+	votes := benchmarkSchulzeVotes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		naiveSchulzeD(60, votes)
+	}
+}
+
+func BenchmarkSchulzeTally(b *testing.B) {
+	poll := gopolls.NewSchulzePoll(60, benchmarkSchulzeVotes())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		poll.Tally()
+	}
+}
+
+func TestSchulzeInvalidVotesCount(t *testing.T) {
+	votes := []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(gopolls.NewVoter("one", 1), gopolls.SchulzeRanking{0, 1, 2}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("two", 3), gopolls.SchulzeRanking{0, 1}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("three", 2), gopolls.SchulzeRanking{2, 1, 0}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("four", 4), nil),
+	}
+	poll := gopolls.NewSchulzePoll(3, votes)
+	res := poll.Tally()
+	if res.InvalidVotesCount != 2 || res.InvalidWeight != 7 {
+		t.Errorf("Expected 2 invalid votes with weight 7, got %d and %d", res.InvalidVotesCount, res.InvalidWeight)
+	}
+	if res.WeightSum != 10 {
+		t.Errorf("Expected weight sum 10, got %d", res.WeightSum)
+	}
+	// the counters match TruncateVoters
+	culprits := poll.DeepClone().TruncateVoters()
+	if len(culprits) != res.InvalidVotesCount {
+		t.Errorf("TruncateVoters found %d votes, Tally ignored %d", len(culprits), res.InvalidVotesCount)
+	}
+	if res = poll.Tally(); res.InvalidVotesCount != 2 {
+		t.Errorf("Expected Tally to not change the votes, got %d invalid votes", res.InvalidVotesCount)
+	}
+}
+
+func TestSchulzeResultExplainPairwise(t *testing.T) {
+	votes := getSchulzeVotesTesting(4, []gopolls.Weight{3, 2, 2, 2}, 4)
+	votes[0].Ranking = gopolls.SchulzeRanking{1, 2, 3, 4}
+	votes[1].Ranking = gopolls.SchulzeRanking{2, 3, 4, 1}
+	votes[2].Ranking = gopolls.SchulzeRanking{4, 2, 3, 1}
+	votes[3].Ranking = gopolls.SchulzeRanking{4, 2, 1, 3}
+	res := gopolls.NewSchulzePoll(4, votes).Tally()
+	names := []string{"Option A", "Option B", "Option C", "Option D"}
+
+	english, err := res.ExplainPairwise(names, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	german, err := res.ExplainPairwise(names, gopolls.GermanPairwiseFormatter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertGolden(t, "schulze_pairwise.txt", []byte(strings.Join(append(english, german...), "\n")))
+
+	tied := gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(gopolls.NewVoter("one", 1), gopolls.SchulzeRanking{0, 1}),
+		gopolls.NewSchulzeVote(gopolls.NewVoter("two", 1), gopolls.SchulzeRanking{1, 0}),
+	}).Tally()
+	sentences, err := tied.ExplainPairwise(nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"0 and 1 were tied with 1 votes each"}; !reflect.DeepEqual(sentences, expected) {
+		t.Errorf("Expected %v, got %v", expected, sentences)
+	}
+
+	// the winner is identified by its index, not by its name
+	sameNames, err := tied.ExplainPairwise([]string{"Option", "Option"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "Option and Option were tied with 1 votes each"; sameNames[0] != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, sameNames[0])
+	}
+	unique := gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(gopolls.NewVoter("one", 1), gopolls.SchulzeRanking{1, 0}),
+	}).Tally()
+	winners := make([]int, 0, 1)
+	recordWinner := func(a, b int, aName, bName string, winner int, votesFor, votesAgainst gopolls.Weight) string {
+		winners = append(winners, winner)
+		return gopolls.EnglishPairwiseFormatter(a, b, aName, bName, winner, votesFor, votesAgainst)
+	}
+	if _, err := unique.ExplainPairwise([]string{"Option", "Option"}, recordWinner); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(winners, []int{1}) {
+		t.Errorf("Expected option 1 to be the winner, got %v", winners)
+	}
+
+	var semanticErr gopolls.PollingSemanticError
+	if _, err := res.ExplainPairwise(names[:2], nil); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for wrong number of option names, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestScoreVoteParser(t *testing.T) {
+	poll := gopolls.NewScorePoll(3, 5, nil)
+	customized, customizeErr := gopolls.NewScoreVoteParser(-1).CustomizeForPoll(poll)
+	if customizeErr != nil {
+		t.Fatalf("Unexpected error customizing parser: %v", customizeErr)
+	}
+	parser := customized.(*gopolls.ScoreVoteParser)
+	voter := gopolls.NewVoter("one", 1)
+
+	vote, err := parser.ParseFromString(" 5, 0/3 ", voter)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing scores: %v", err)
+	}
+	if expected := gopolls.NewScoreVote(voter, []uint8{5, 0, 3}); !vote.(*gopolls.ScoreVote).Equals(expected) {
+		t.Errorf("Expected vote %v, got %v", expected, vote)
+	}
+
+	var semanticErr gopolls.PollingSemanticError
+	for _, in := range []string{"5, 0", "5, 0, 3, 1", "6, 0, 0", "-1, 0, 0"} {
+		if _, err := parser.ParseFromString(in, voter); !errors.As(err, &semanticErr) {
+			t.Errorf("Expected PollingSemanticError for \"%s\", got %v", in, err)
+		}
+	}
+	var syntaxErr gopolls.PollingSyntaxError
+	if _, err := parser.ParseFromString("5, a, 3", voter); !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected PollingSyntaxError, got %v", err)
+	}
+
+	var typeErr gopolls.PollTypeError
+	if _, err := parser.CustomizeForPoll(gopolls.NewBasicPoll(nil)); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError customizing for a BasicPoll, got %v", err)
+	}
+}
+
+func TestScorePollGenerateVote(t *testing.T) {
+	poll := gopolls.NewScorePoll(3, 5, nil)
+	voter := gopolls.NewVoter("one", 1)
+	tests := []struct {
+		answer   gopolls.BasicPollAnswer
+		expected []uint8
+	}{
+		{gopolls.Aye, []uint8{5, 5, 0}},
+		{gopolls.No, []uint8{0, 0, 5}},
+		{gopolls.Abstention, []uint8{0, 0, 0}},
+	}
+	for _, tc := range tests {
+		vote, err := poll.GenerateVoteFromBasicAnswer(voter, tc.answer)
+		if err != nil {
+			t.Errorf("Unexpected error for answer %s: %v", tc.answer, err)
+			continue
+		}
+		if scores := vote.(*gopolls.ScoreVote).Scores; !reflect.DeepEqual(scores, tc.expected) {
+			t.Errorf("Expected scores %v for answer %s, got %v", tc.expected, tc.answer, scores)
+		}
+		if validateErr := poll.ValidateVote(vote); validateErr != nil {
+			t.Errorf("Unexpected error validating vote for answer %s: %v", tc.answer, validateErr)
+		}
+	}
+
+	poll.NoOptionIndex = gopolls.NoScoreNoOption
+	var typeErr gopolls.PollTypeError
+	if _, err := poll.GenerateVoteFromBasicAnswer(voter, gopolls.Aye); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError for a poll without no option, got %v", err)
+	}
+	if _, err := poll.GenerateVoteFromBasicAnswer(voter, gopolls.Abstention); err != nil {
+		t.Errorf("Unexpected error for abstention in a poll without no option: %v", err)
+	}
+}
+
+func TestScorePollTally(t *testing.T) {
+	votes := []*gopolls.ScoreVote{
+		gopolls.NewScoreVote(gopolls.NewVoter("one", 2), []uint8{5, 4, 0}),
+		gopolls.NewScoreVote(gopolls.NewVoter("two", 1), []uint8{0, 5, 1}),
+		gopolls.NewScoreVote(gopolls.NewVoter("three", 1), []uint8{1, 5, 5}),
+		// invalid length and invalid score, ignored in the sums and averages
+		gopolls.NewScoreVote(gopolls.NewVoter("four", 1), []uint8{5, 5}),
+		gopolls.NewScoreVote(gopolls.NewVoter("five", 3), []uint8{6, 0, 0}),
+	}
+	poll := gopolls.NewScorePoll(3, 5, votes)
+	res := poll.TallyWithRunoff()
+	if res.Err != nil {
+		t.Fatalf("Unexpected error: %v", res.Err)
+	}
+	if expected := []uint64{11, 18, 6}; !reflect.DeepEqual(res.ScoreSums, expected) {
+		t.Errorf("Expected score sums %v, got %v", expected, res.ScoreSums)
+	}
+	if res.WeightSum != 8 || res.InvalidVotesCount != 2 || res.InvalidWeight != 4 {
+		t.Errorf("Expected weight sum 8 and two invalid votes with weight 4, got %d, %d and %d",
+			res.WeightSum, res.InvalidVotesCount, res.InvalidWeight)
+	}
+	expectedAverages := []*big.Rat{big.NewRat(11, 4), big.NewRat(18, 4), big.NewRat(6, 4)}
+	for i, average := range res.Averages {
+		if average.Cmp(expectedAverages[i]) != 0 {
+			t.Errorf("Expected average %s for option %d, got %s", expectedAverages[i], i, average)
+		}
+	}
+	if expected := [][]int{{1}, {0}, {2}}; !reflect.DeepEqual(res.RankedGroups, expected) {
+		t.Errorf("Expected ranked groups %v, got %v", expected, res.RankedGroups)
+	}
+	// runoff between 1 and 0: one prefers 0 (weight 2), two and three prefer 1 (weight 2), 1 wins with higher sum
+	expectedRunoff := &gopolls.ScoreRunoff{First: 1, Second: 0, PreferFirst: 2, PreferSecond: 2, NoPreference: 0,
+		Winner: 1}
+	if !reflect.DeepEqual(res.Runoff, expectedRunoff) {
+		t.Errorf("Expected runoff %+v, got %+v", expectedRunoff, res.Runoff)
+	}
+	if noRunoff := poll.Tally(); noRunoff.Runoff != nil {
+		t.Errorf("Expected no runoff from Tally, got %+v", noRunoff.Runoff)
+	}
+
+	// the option with the highest sum doesn't have to win the runoff
+	star := gopolls.NewScorePoll(2, 5, []*gopolls.ScoreVote{
+		gopolls.NewScoreVote(gopolls.NewVoter("one", 1), []uint8{5, 0}),
+		gopolls.NewScoreVote(gopolls.NewVoter("two", 1), []uint8{3, 4}),
+		gopolls.NewScoreVote(gopolls.NewVoter("three", 1), []uint8{3, 4}),
+	})
+	starRes := star.TallyWithRunoff()
+	if starRes.Runoff == nil || starRes.Runoff.First != 0 || starRes.Runoff.Winner != 1 {
+		t.Errorf("Expected option 1 to win the runoff against option 0, got %+v", starRes.Runoff)
+	}
+
+	if culprits := poll.TruncateVoters(); len(culprits) != 2 || culprits[0].Voter.Name != "four" ||
+		culprits[1].Voter.Name != "five" {
+		t.Errorf("Expected votes of four and five to be truncated, got %v", culprits)
+	}
+	if !poll.DeepClone().Equals(poll) {
+		t.Error("Expected clone to be equal to the poll")
+	}
+}
+
+func TestScorePollPersistence(t *testing.T) {
+	voter := gopolls.NewVoter("one", 1)
+	poll := gopolls.NewScorePoll(3, 5, []*gopolls.ScoreVote{gopolls.NewScoreVote(voter, []uint8{5, 0, 3})})
+	var buf bytes.Buffer
+	if err := gopolls.DumpVotes(&buf, gopolls.PollMap{"score": poll}); err != nil {
+		t.Fatalf("Unexpected error dumping votes: %v", err)
+	}
+	loaded := gopolls.PollMap{"score": gopolls.NewScorePoll(3, 5, nil)}
+	if err := gopolls.LoadVotes(&buf, loaded, gopolls.VoterMap{"one": voter}); err != nil {
+		t.Fatalf("Unexpected error loading votes: %v", err)
+	}
+	if !loaded["score"].(*gopolls.ScorePoll).Equals(poll) {
+		t.Errorf("Expected loaded poll to equal the original, got %v", loaded["score"])
+	}
+}
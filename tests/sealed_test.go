@@ -0,0 +1,130 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestRevealSealedVotes(t *testing.T) {
+	one, two, three := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 1)
+	basic := gopolls.NewBasicPoll(nil)
+	median := gopolls.NewMedianPoll(10000, nil)
+	basic.AddSealed(gopolls.NewSealedVote(one, "+"))
+	basic.AddSealed(gopolls.NewSealedVote(two, "invalid"))
+	basic.AddSealed(gopolls.NewSealedVote(three, " - "))
+	median.AddSealed(gopolls.NewSealedVote(one, "50"))
+	median.AddSealed(gopolls.NewSealedVote(two, "200"))
+
+	// tally before reveal must not see sealed votes
+	if res := basic.Tally(); res.NumberVoters.NumAyes != 0 || res.NumberVoters.NumNoes != 0 {
+		t.Errorf("Sealed votes counted before reveal: %v", res.NumberVoters)
+	}
+	if basic.NumSealed() != 3 || median.NumSealed() != 2 {
+		t.Fatalf("Expected 3 and 2 sealed votes, got %d and %d", basic.NumSealed(), median.NumSealed())
+	}
+
+	polls := gopolls.PollMap{"basic": basic, "median": gopolls.NewSynchronizedPoll(median)}
+	parsers, err := gopolls.CustomizeParsersToMap(gopolls.PollMap{"basic": basic, "median": median},
+		gopolls.DefaultParserTemplateMap)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = gopolls.Reveal(polls, parsers)
+	var revealErr gopolls.RevealErrors
+	if !errors.As(err, &revealErr) {
+		t.Fatalf("Expected RevealErrors, got %v", err)
+	}
+	if len(revealErr.Errors) != 2 {
+		t.Fatalf("Expected two errors, got %v", revealErr.Errors)
+	}
+	if first := revealErr.Errors[0]; first.Poll != "basic" || first.Voter != "two" {
+		t.Errorf("Expected error for voter two in poll basic, got %v", first)
+	}
+	if second := revealErr.Errors[1]; second.Poll != "median" || second.Voter != "two" {
+		t.Errorf("Expected error for voter two in poll median, got %v", second)
+	}
+	if !errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected errors to be ErrPoll, got %v", err)
+	}
+
+	if len(basic.Votes) != 2 || basic.Votes[0].Choice != gopolls.Aye || basic.Votes[1].Choice != gopolls.No {
+		t.Errorf("Expected votes aye and no after reveal, got %v", basic.Votes)
+	}
+	if len(median.Votes) != 1 || median.Votes[0].Value != 5000 {
+		t.Errorf("Expected vote 5000 after reveal, got %v", median.Votes)
+	}
+	// failed votes stay sealed
+	if basic.NumSealed() != 1 || median.NumSealed() != 1 {
+		t.Fatalf("Expected one sealed vote per poll, got %d and %d", basic.NumSealed(), median.NumSealed())
+	}
+
+	// correct the failed votes and reveal again
+	basic.TakeSealed()
+	basic.AddSealed(gopolls.NewSealedVote(two, "+"))
+	median.TakeSealed()
+	if err := gopolls.Reveal(polls, parsers); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if res := basic.Tally(); res.NumberVoters.NumAyes != 2 || res.NumberVoters.NumNoes != 1 {
+		t.Errorf("Expected two ayes and one no, got %v", res.NumberVoters)
+	}
+	if basic.NumSealed() != 0 {
+		t.Errorf("Expected no sealed votes, got %d", basic.NumSealed())
+	}
+}
+
+func TestRevealClosedPoll(t *testing.T) {
+	poll := gopolls.NewBasicPoll(nil)
+	if err := poll.AddSealed(gopolls.NewSealedVote(gopolls.NewVoter("one", 1), "+")); err != nil {
+		t.Fatalf("Unexpected error adding sealed vote: %v", err)
+	}
+	poll.Close()
+	parsers := map[string]gopolls.ParserCustomizer{"basic": gopolls.NewBasicVoteParser()}
+	err := gopolls.Reveal(gopolls.PollMap{"basic": poll}, parsers)
+	if !errors.Is(err, gopolls.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed revealing votes of a closed poll, got %v", err)
+	}
+	if poll.NumSealed() != 1 || len(poll.Votes) != 0 {
+		t.Errorf("Expected sealed vote to stay in the buffer, got %d sealed and %d votes", poll.NumSealed(),
+			len(poll.Votes))
+	}
+}
+
+func TestRevealMissingParser(t *testing.T) {
+	poll := gopolls.NewBasicPoll(nil)
+	poll.AddSealed(gopolls.NewSealedVote(gopolls.NewVoter("one", 1), "+"))
+	err := gopolls.Reveal(gopolls.PollMap{"basic": poll}, map[string]gopolls.ParserCustomizer{})
+	var typeErr gopolls.PollTypeError
+	if !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+	// polls without sealed votes don't need a parser
+	if err := gopolls.Reveal(gopolls.PollMap{"basic": gopolls.NewBasicPoll(nil)}, nil); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestDeepCloneSealedVotes(t *testing.T) {
+	poll := gopolls.NewSchulzePoll(2, nil)
+	poll.AddSealed(gopolls.NewSealedVote(gopolls.NewVoter("one", 1), "0, 1"))
+	clone := poll.DeepClone()
+	poll.TakeSealed()
+	if clone.NumSealed() != 1 {
+		t.Errorf("Expected sealed vote in clone, got %d", clone.NumSealed())
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestGenerateVoters(t *testing.T) {
+	voters := gopolls.GenerateVoters(3, func(i int) gopolls.Weight { return gopolls.Weight(i + 1) })
+	if len(voters) != 3 {
+		t.Fatalf("expected 3 voters, got %d", len(voters))
+	}
+	for i, voter := range voters {
+		expected := gopolls.NewVoter(fmt.Sprintf("voter-%d", i+1), gopolls.Weight(i+1))
+		if !voter.Equals(expected) {
+			t.Errorf("expected voter %v, got %v", expected, voter)
+		}
+	}
+	for _, voter := range gopolls.GenerateVoters(2, nil) {
+		if voter.Weight != 1 {
+			t.Errorf("expected default weight 1, got %d", voter.Weight)
+		}
+	}
+}
+
+func TestGenerateCollection(t *testing.T) {
+	coll := gopolls.GenerateCollection(gopolls.CollectionSpec{
+		Title:           "Load test",
+		NumGroups:       3,
+		NumBasicPolls:   3,
+		NumMedianPolls:  2,
+		NumSchulzePolls: 2,
+	})
+	if coll.NumGroups() != 3 || coll.NumSkeletons() != 7 {
+		t.Fatalf("expected 3 groups with 7 polls, got %d groups with %d polls", coll.NumGroups(), coll.NumSkeletons())
+	}
+	expectedSizes := []int{3, 2, 2}
+	for i, group := range coll.Groups {
+		if group.NumSkeletons() != expectedSizes[i] {
+			t.Errorf("expected %d polls in group %d, got %d", expectedSizes[i], i, group.NumSkeletons())
+		}
+	}
+	if _, has := coll.HasDuplicateSkeleton(); has {
+		t.Error("expected no duplicate skeletons")
+	}
+	schulze, ok := coll.Groups[0].Skeletons[2].(*gopolls.PollSkeleton)
+	if !ok || len(schulze.Options) != gopolls.DefaultSimulationSchulzeOptions {
+		t.Errorf("expected Schulze poll with default number of options, got %v", coll.Groups[0].Skeletons[2])
+	}
+
+	small := gopolls.GenerateCollection(gopolls.CollectionSpec{NumGroups: 5, NumMedianPolls: 2})
+	if small.NumGroups() != 2 {
+		t.Errorf("expected number of groups to be limited to 2, got %d", small.NumGroups())
+	}
+}
+
+func TestFillRandomVotes(t *testing.T) {
+	voters := gopolls.GenerateVoters(50, nil)
+	newPolls := func() gopolls.PollMap {
+		return gopolls.PollMap{
+			"basic":   gopolls.NewBasicPoll(nil),
+			"median":  gopolls.NewMedianPoll(500, nil),
+			"schulze": gopolls.NewSchulzePoll(4, nil),
+			"score":   gopolls.NewScorePoll(3, 5, nil),
+		}
+	}
+	first, second := newPolls(), newPolls()
+	if err := gopolls.FillRandomVotes(first, voters, 42, 0.2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := gopolls.FillRandomVotes(second, voters, 42, 0.2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, poll := range first {
+		numFirst, _ := gopolls.VoteCount(poll)
+		numSecond, _ := gopolls.VoteCount(second[name])
+		if numFirst != numSecond {
+			t.Errorf("poll %s: expected same number of votes for same seed, got %d and %d", name, numFirst, numSecond)
+		}
+		if numFirst == 0 || numFirst == len(voters) {
+			t.Errorf("poll %s: expected some but not all voters to vote, got %d votes", name, numFirst)
+		}
+	}
+	for _, vote := range first["median"].(*gopolls.MedianPoll).Votes {
+		if vote.Value > 500 {
+			t.Errorf("median vote %d exceeds poll value", vote.Value)
+		}
+	}
+
+	err := gopolls.FillRandomVotes(gopolls.PollMap{"foo": nil}, voters, 42, 0)
+	if err == nil {
+		t.Error("expected error for unsupported poll type")
+	}
+}
+
+func TestWriteRandomCSV(t *testing.T) {
+	voters := gopolls.GenerateVoters(20, func(i int) gopolls.Weight { return gopolls.Weight(i%3 + 1) })
+	coll := gopolls.GenerateCollection(gopolls.CollectionSpec{
+		Title:           "Load test",
+		NumGroups:       2,
+		NumBasicPolls:   2,
+		NumMedianPolls:  2,
+		NumSchulzePolls: 2,
+	})
+	var first, second bytes.Buffer
+	if err := gopolls.WriteRandomCSV(&first, voters, coll, 7, 0.1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := gopolls.WriteRandomCSV(&second, voters, coll, 7, 0.1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Error("expected same csv for same seed")
+	}
+
+	res, evalErr := gopolls.Evaluate(voters, coll, &first, gopolls.EvaluateOptions{})
+	if evalErr != nil {
+		t.Fatalf("generated csv could not be evaluated: %v", evalErr)
+	}
+	if len(res.Results) != 6 {
+		t.Errorf("expected 6 results, got %d", len(res.Results))
+	}
+}
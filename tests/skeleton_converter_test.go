@@ -0,0 +1,229 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+const scoreSkeletonType = "test-score-skeleton"
+
+type scoreSkeleton struct {
+	Name       string
+	NumOptions int
+}
+
+func (skel *scoreSkeleton) SkeletonType() string {
+	return scoreSkeletonType
+}
+
+func (skel *scoreSkeleton) GetName() string {
+	return skel.Name
+}
+
+func convertScoreSkeleton(skel gopolls.AbstractPollSkeleton) (gopolls.AbstractPoll, error) {
+	return gopolls.NewScorePoll(skel.(*scoreSkeleton).NumOptions, 10, nil), nil
+}
+
+func TestRegistryConverter(t *testing.T) {
+	skel := &scoreSkeleton{Name: "Score", NumOptions: 3}
+	var typeErr gopolls.PollTypeError
+	if _, err := gopolls.RegistryConverter(skel); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError for unregistered type, got %v", err)
+	}
+
+	gopolls.RegisterSkeletonConverter(scoreSkeletonType, convertScoreSkeleton)
+	defer gopolls.RegisterSkeletonConverter(scoreSkeletonType, nil)
+
+	poll, err := gopolls.RegistryConverter(skel)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if scorePoll, ok := poll.(*gopolls.ScorePoll); !ok || scorePoll.NumOptions != 3 {
+		t.Errorf("Expected score poll with 3 options, got %v", poll)
+	}
+
+	// the default types are registered as well
+	basicSkel := gopolls.NewPollSkeleton("Basic")
+	basicSkel.AddOption("Yes", "")
+	basicSkel.AddOption("No", "")
+	if poll, err = gopolls.RegistryConverter(basicSkel); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := poll.(*gopolls.BasicPoll); !ok {
+		t.Errorf("Expected basic poll, got %v", poll)
+	}
+	moneySkel := gopolls.NewMoneyPollSkeleton("Money", gopolls.NewCurrencyValue(100, "€"))
+	if poll, err = gopolls.RegistryConverter(moneySkel); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := poll.(*gopolls.MedianPoll); !ok {
+		t.Errorf("Expected median poll, got %v", poll)
+	}
+}
+
+func TestConvertCollection(t *testing.T) {
+	gopolls.RegisterSkeletonConverter(scoreSkeletonType, convertScoreSkeleton)
+	defer gopolls.RegisterSkeletonConverter(scoreSkeletonType, nil)
+
+	coll := gopolls.GenerateCollection(gopolls.CollectionSpec{
+		Title:           "Collection",
+		NumGroups:       2,
+		NumBasicPolls:   1,
+		NumMedianPolls:  1,
+		NumSchulzePolls: 1,
+	})
+	coll.Groups[1].Skeletons = append(coll.Groups[1].Skeletons, &scoreSkeleton{Name: "Score", NumOptions: 2})
+
+	converted, err := gopolls.ConvertCollection(coll, gopolls.RegistryConverter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if converted.Title != "Collection" || len(converted.Groups) != 2 || converted.NumPolls() != 4 {
+		t.Fatalf("Expected collection with two groups and four polls, got %+v", converted)
+	}
+	expectedSizes := []int{2, 2}
+	for i, group := range converted.Groups {
+		if len(group.Skeletons) != expectedSizes[i] || len(group.Polls) != expectedSizes[i] {
+			t.Errorf("Expected %d polls in group %d, got %d skeletons and %d polls",
+				expectedSizes[i], i, len(group.Skeletons), len(group.Polls))
+		}
+		if group.Title != coll.Groups[i].Title {
+			t.Errorf("Expected group title %s, got %s", coll.Groups[i].Title, group.Title)
+		}
+	}
+
+	index := converted.IndexOf("Score")
+	if index != 3 {
+		t.Fatalf("Expected index 3 for poll Score, got %d", index)
+	}
+	poll, has := converted.PollByName("Score")
+	if !has || poll != converted.Polls[index] || poll != converted.Groups[1].Polls[1] {
+		t.Errorf("Expected poll Score at index %d, got %v", index, poll)
+	}
+	if _, ok := poll.(*gopolls.ScorePoll); !ok {
+		t.Errorf("Expected score poll, got %v", poll)
+	}
+	skel, has := converted.SkeletonOf(poll)
+	if !has || skel.GetName() != "Score" {
+		t.Errorf("Expected skeleton Score, got %v", skel)
+	}
+	if skelByName, has := converted.SkeletonByName("Median poll 1"); !has || skelByName != converted.Skeletons[1] {
+		t.Errorf("Expected skeleton Median poll 1 at index 1, got %v", skelByName)
+	}
+	if converted.IndexOf("foo") != -1 {
+		t.Error("Expected -1 for unknown poll")
+	}
+	if _, has = converted.SkeletonOf(gopolls.NewBasicPoll(nil)); has {
+		t.Error("Expected no skeleton for poll not in collection")
+	}
+	if len(converted.PollMap()) != 4 || len(converted.SkeletonMap()) != 4 {
+		t.Error("Expected maps with four entries")
+	}
+
+	// without the registry the score skeleton can't be converted
+	var typeErr gopolls.PollTypeError
+	if _, err = gopolls.ConvertCollection(coll, nil); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+
+	coll.Groups[1].Skeletons = append(coll.Groups[1].Skeletons, &scoreSkeleton{Name: "Score", NumOptions: 2})
+	var duplicateErr gopolls.DuplicateError
+	if _, err = gopolls.ConvertCollection(coll, gopolls.RegistryConverter); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+}
+
+func newConverterTestSkeleton(name string, numOptions int) *gopolls.PollSkeleton {
+	skel := gopolls.NewPollSkeleton(name)
+	for i := 0; i < numOptions; i++ {
+		skel.AddOption(string(rune('A'+i)), "")
+	}
+	return skel
+}
+
+func TestSkeletonConverterWithOptions(t *testing.T) {
+	opts := gopolls.NewConverterOptions()
+	opts.BasicThreshold = 3
+	opts.ForceSchulze["Forced"] = true
+	opts.Hook = func(skel *gopolls.PollSkeleton) (gopolls.AbstractPoll, error) {
+		if skel.Name == "Score" {
+			return gopolls.NewScorePoll(len(skel.Options), 5, nil), nil
+		}
+		return nil, nil
+	}
+	conv := gopolls.NewSkeletonConverterWithOptions(opts)
+
+	tests := []struct {
+		skel     *gopolls.PollSkeleton
+		expected string
+	}{
+		{newConverterTestSkeleton("Two", 2), gopolls.BasicPollType},
+		{newConverterTestSkeleton("Three", 3), gopolls.BasicPollType},
+		{newConverterTestSkeleton("Four", 4), gopolls.SchulzePollType},
+		{newConverterTestSkeleton("Forced", 2), gopolls.SchulzePollType},
+		{newConverterTestSkeleton("Score", 2), gopolls.ScorePollType},
+	}
+	for _, tc := range tests {
+		poll, err := conv(tc.skel)
+		if err != nil {
+			t.Errorf("Unexpected error converting %s: %v", tc.skel.Name, err)
+			continue
+		}
+		if poll.PollType() != tc.expected {
+			t.Errorf("Expected poll type %s for %s, got %s", tc.expected, tc.skel.Name, poll.PollType())
+		}
+	}
+
+	var typeErr gopolls.PollTypeError
+	if _, err := conv(newConverterTestSkeleton("One", 1)); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError for a single option, got %v", err)
+	}
+
+	hookErr := errors.New("hook error")
+	opts.Hook = func(skel *gopolls.PollSkeleton) (gopolls.AbstractPoll, error) {
+		return nil, hookErr
+	}
+	if _, err := gopolls.NewSkeletonConverterWithOptions(opts)(newConverterTestSkeleton("Two", 2)); err != hookErr {
+		t.Errorf("Expected error of the hook, got %v", err)
+	}
+}
+
+func TestSkeletonConverterDefaultOptions(t *testing.T) {
+	conv := gopolls.NewSkeletonConverterWithOptions(gopolls.NewConverterOptions())
+	for numOptions := 2; numOptions <= 4; numOptions++ {
+		skel := newConverterTestSkeleton("Poll", numOptions)
+		expected, expectedErr := gopolls.DefaultSkeletonConverter(skel)
+		poll, err := conv(skel)
+		if err != nil || expectedErr != nil {
+			t.Fatalf("Unexpected errors: %v and %v", err, expectedErr)
+		}
+		if poll.PollType() != expected.PollType() {
+			t.Errorf("Expected poll type %s for %d options, got %s", expected.PollType(), numOptions, poll.PollType())
+		}
+		if schulzePoll, ok := poll.(*gopolls.SchulzePoll); ok && schulzePoll.NoOptionIndex != numOptions-1 {
+			t.Errorf("Expected the last option to be the no option, got %d", schulzePoll.NoOptionIndex)
+		}
+	}
+
+	opts := gopolls.NewConverterOptions()
+	opts.BasicThreshold = 0
+	poll, err := gopolls.NewSkeletonConverterWithOptions(opts)(newConverterTestSkeleton("Poll", 2))
+	if err != nil || poll.PollType() != gopolls.SchulzePollType {
+		t.Errorf("Expected a SchulzePoll with threshold 0, got %v and error %v", poll, err)
+	}
+}
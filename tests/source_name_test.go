@@ -0,0 +1,192 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPollingSyntaxErrorSource(t *testing.T) {
+	err := gopolls.NewPollingSyntaxError(nil, "invalid line")
+	tests := []struct {
+		err      gopolls.PollingSyntaxError
+		expected string
+	}{
+		{err, "invalid line"},
+		{err.WithLineNum(3), "syntax error in line 3: invalid line"},
+		{err.WithSource("voters.txt"), "syntax error in voters.txt: invalid line"},
+		{err.WithSource("voters.txt").WithLineNum(3), "syntax error in voters.txt, line 3: invalid line"},
+	}
+	for _, tc := range tests {
+		if got := tc.err.Error(); got != tc.expected {
+			t.Errorf("Expected error message \"%s\", got \"%s\"", tc.expected, got)
+		}
+	}
+}
+
+func TestParseVotersSourceName(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	input := "* one: 1\ntwo: 2\n"
+	_, err := parser.ParseVotersFromString(input)
+	if err == nil || strings.Contains(err.Error(), "voters.txt") {
+		t.Fatalf("Expected an error without source, got %v", err)
+	}
+
+	parser.SourceName = "voters.txt"
+	_, err = parser.ParseVotersFromString(input)
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected a PollingSyntaxError, got %v", err)
+	}
+	if syntaxErr.Source != "voters.txt" || syntaxErr.LineNum != 2 {
+		t.Errorf("Expected source voters.txt and line 2, got %s and %d", syntaxErr.Source, syntaxErr.LineNum)
+	}
+	if !strings.HasPrefix(err.Error(), "syntax error in voters.txt, line 2: ") {
+		t.Errorf("Expected source and line in error message, got %s", err)
+	}
+
+	parser.MaxNumVoters = 1
+	_, err = parser.ParseVotersFromString("* one: 1\n* two: 2\n")
+	var validationErr *gopolls.ParserValidationError
+	if !errors.As(err, &validationErr) || validationErr.Source != "voters.txt" {
+		t.Errorf("Expected a ParserValidationError with source voters.txt, got %v", err)
+	}
+
+	parser.MaxNumVoters = -1
+	parser.DuplicatePolicy = gopolls.RejectDuplicateVoters
+	_, err = parser.ParseVotersFromString("* one: 1\n* one: 2\n")
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(err, &duplicateErr) || !errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected a DuplicateError, got %v", err)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "voters.txt: ") {
+		t.Errorf("Expected source in error message, got %v", err)
+	}
+}
+
+func TestParseCollectionSourceName(t *testing.T) {
+	parser := gopolls.NewPollCollectionParser()
+	parser.SourceName = "polls.md"
+	parser.CollectErrors = true
+	_, err := parser.ParseCollectionSkeletonsFromString(nil,
+		"# Assembly\n\n## Group\n\n### Poll\nfoo\n\n### Other\n* a\nbar\n")
+	var collectionErrs gopolls.PollCollectionErrors
+	if !errors.As(err, &collectionErrs) || len(collectionErrs.Errors) != 3 {
+		t.Fatalf("Expected three errors in the collection, got %v", err)
+	}
+	for _, lineErr := range collectionErrs.Errors {
+		if !strings.Contains(lineErr.Error(), "polls.md") {
+			t.Errorf("Expected source in error message, got %s", lineErr.Error())
+		}
+	}
+}
+
+func TestInvalidEncodingSourceName(t *testing.T) {
+	votersParser := gopolls.NewVotersParser()
+	votersParser.SourceName = "voters.txt"
+	_, err := votersParser.ParseVotersFromString("* A\xff: 1\n")
+	if !errors.Is(err, gopolls.ErrInvalidEncoding) || !strings.Contains(err.Error(), "voters.txt") {
+		t.Errorf("Expected ErrInvalidEncoding with source voters.txt, got %v", err)
+	}
+
+	collectionParser := gopolls.NewPollCollectionParser()
+	collectionParser.SourceName = "polls.md"
+	_, err = collectionParser.ParseCollectionSkeletonsFromString(nil, "# Assembly\n\n## Group\n\n### Poll\xff\n* a\n* b\n")
+	if !errors.Is(err, gopolls.ErrInvalidEncoding) || !strings.Contains(err.Error(), "polls.md") {
+		t.Errorf("Expected ErrInvalidEncoding with source polls.md, got %v", err)
+	}
+
+	reader := gopolls.NewVotesCSVReader(strings.NewReader("voter,Motion\n\xff,aye\n"))
+	reader.SourceName = "votes.csv"
+	_, _, err = reader.ReadRecords()
+	if !errors.Is(err, gopolls.ErrInvalidEncoding) || !strings.Contains(err.Error(), "votes.csv") {
+		t.Errorf("Expected ErrInvalidEncoding with source votes.csv, got %v", err)
+	}
+
+	// other validation errors are not matched
+	if errors.Is(gopolls.NewParserValidationError("line is too long"), gopolls.ErrInvalidEncoding) {
+		t.Error("Expected a different validation error not to match ErrInvalidEncoding")
+	}
+}
+
+func TestParseFilesSourceName(t *testing.T) {
+	dir, dirErr := ioutil.TempDir("", "gopolls")
+	if dirErr != nil {
+		t.Fatalf("Can't create temp dir: %v", dirErr)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"voters.txt": "* one: 1\n* two\n",
+		"polls.md":   "# Assembly\n\n## Group\n\n### Poll\n- abc\n",
+		"votes.csv":  "voter,Poll\none,aye\ntwo\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Can't write file: %v", err)
+		}
+	}
+
+	votersParser := gopolls.NewVotersParser()
+	voters, err := votersParser.ParseVotersFile(filepath.Join(dir, "voters.txt"))
+	if err != nil || len(voters) != 2 {
+		t.Fatalf("Expected two voters, got %v and error %v", voters, err)
+	}
+	votersParser.RequireExplicitWeight = true
+	if _, err = votersParser.ParseVotersFile(filepath.Join(dir, "voters.txt")); err == nil ||
+		!strings.Contains(err.Error(), "voters.txt, line 2") {
+		t.Errorf("Expected error in voters.txt line 2, got %v", err)
+	}
+	if votersParser.SourceName != "" {
+		t.Errorf("Expected SourceName of the parser not to be changed, got %s", votersParser.SourceName)
+	}
+
+	collectionParser := gopolls.NewPollCollectionParser()
+	_, err = collectionParser.ParseCollectionSkeletonsFile(filepath.Join(dir, "polls.md"), nil)
+	if err == nil || !strings.Contains(err.Error(), "polls.md, line 6") {
+		t.Errorf("Expected error in polls.md line 6, got %v", err)
+	}
+
+	_, err = gopolls.ReadMatrixFromCSVFile(filepath.Join(dir, "votes.csv"), func(reader *gopolls.VotesCSVReader) {
+		reader.Sep = ','
+	})
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Source != "votes.csv" || syntaxErr.LineNum != 3 {
+		t.Errorf("Expected syntax error in votes.csv line 3, got %v", err)
+	}
+
+	if _, err = votersParser.ParseVotersFile(filepath.Join(dir, "missing.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error for a missing file, got %v", err)
+	}
+}
+
+func TestEvaluateLenientCSVSourceName(t *testing.T) {
+	_, err := gopolls.EvaluateFromReaders(strings.NewReader(evaluateVoters), strings.NewReader(evaluateCollection),
+		strings.NewReader("voter,Motion\none,aye,no\n"), gopolls.EvaluateOptions{
+			ConfigureCSVReader: func(reader *gopolls.VotesCSVReader) {
+				reader.Mode = gopolls.LenientCSVMode
+				reader.SourceName = "votes.csv"
+			},
+		})
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Source != "votes.csv" || !strings.Contains(err.Error(), "votes.csv") {
+		t.Errorf("Expected syntax error with source votes.csv, got %v", err)
+	}
+}
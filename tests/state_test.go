@@ -0,0 +1,208 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func stateTestInput(t *testing.T) ([]*gopolls.Voter, *gopolls.PollSkeletonCollection, gopolls.PollMap) {
+	voters := gopolls.GenerateVoters(10, func(i int) gopolls.Weight { return gopolls.Weight(i%3 + 1) })
+	voters[0].Delegations = []gopolls.Delegation{{Source: "absent", Weight: 2}}
+	voters[1].Weight, voters[1].FractionalWeight = 2, big.NewRat(5, 2)
+	coll := gopolls.GenerateCollection(gopolls.CollectionSpec{
+		Title: "State", NumGroups: 2, NumBasicPolls: 2, NumMedianPolls: 1, NumSchulzePolls: 1,
+	})
+	skelMap, skelErr := coll.SkeletonsToMap()
+	if skelErr != nil {
+		t.Fatalf("Unexpected error: %v", skelErr)
+	}
+	polls, convertErr := gopolls.ConvertSkeletonMapToEmptyPolls(skelMap, nil)
+	if convertErr != nil {
+		t.Fatalf("Unexpected error: %v", convertErr)
+	}
+	if fillErr := gopolls.FillRandomVotes(polls, voters, 42, 0.1); fillErr != nil {
+		t.Fatalf("Unexpected error: %v", fillErr)
+	}
+	return voters, coll, polls
+}
+
+func TestStateArchiveRoundTrip(t *testing.T) {
+	voters, coll, polls := stateTestInput(t)
+	var buf bytes.Buffer
+	if err := gopolls.SaveState(&buf, voters, coll, polls); err != nil {
+		t.Fatalf("Unexpected error saving state: %v", err)
+	}
+	state, err := gopolls.LoadState(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Unexpected error loading state: %v", err)
+	}
+	if state.Version != gopolls.StateArchiveVersion {
+		t.Errorf("Expected version %d, got %d", gopolls.StateArchiveVersion, state.Version)
+	}
+	if len(state.Voters) != len(voters) {
+		t.Fatalf("Expected %d voters, got %d", len(voters), len(state.Voters))
+	}
+	for i, voter := range voters {
+		if !voter.Equals(state.Voters[i]) {
+			t.Errorf("Expected voter %v, got %v", voter, state.Voters[i])
+		}
+	}
+	var expectedColl, gotColl strings.Builder
+	_, _ = coll.Dump(&expectedColl, gopolls.DefaultCurrencyHandler)
+	_, _ = state.Collection.Dump(&gotColl, gopolls.DefaultCurrencyHandler)
+	if expectedColl.String() != gotColl.String() {
+		t.Errorf("Expected collection\n%s\ngot\n%s", expectedColl.String(), gotColl.String())
+	}
+	var expectedVotes, gotVotes bytes.Buffer
+	if err := gopolls.DumpVotes(&expectedVotes, polls); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := gopolls.DumpVotes(&gotVotes, state.Polls); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expectedVotes.String() != gotVotes.String() {
+		t.Errorf("Expected votes\n%s\ngot\n%s", expectedVotes.String(), gotVotes.String())
+	}
+
+	// without polls the votes entry is empty
+	buf.Reset()
+	if err := gopolls.SaveState(&buf, voters, coll, nil); err != nil {
+		t.Fatalf("Unexpected error saving state: %v", err)
+	}
+	if state, err = gopolls.LoadState(&buf); err != nil || len(state.Polls) != coll.NumSkeletons() {
+		t.Errorf("Expected state with %d empty polls, got %v (error %v)", coll.NumSkeletons(), state, err)
+	}
+}
+
+// writeStateTestArchive writes a zip archive with the given entries (name and content, in this order).
+func writeStateTestArchive(t *testing.T, entries ...string) io.Reader {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(entries); i += 2 {
+		w, err := zipWriter.Create(entries[i])
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err = io.WriteString(w, entries[i+1]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return &buf
+}
+
+func TestStateArchiveErrors(t *testing.T) {
+	var syntaxErr gopolls.PollingSyntaxError
+	var semanticErr gopolls.PollingSemanticError
+	var archiveErr gopolls.StateArchiveError
+
+	if _, err := gopolls.LoadState(strings.NewReader("no zip")); !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected PollingSyntaxError for invalid archive, got %v", err)
+	}
+	manifest := `{"version": 1}`
+	collection := "# Title\n\n## Group\n\n### Poll\n* Yes\n* No\n"
+	_, err := gopolls.LoadState(writeStateTestArchive(t, gopolls.StateManifestEntry, manifest,
+		gopolls.StateVotersEntry, "* one: 1\n", gopolls.StateCollectionEntry, collection))
+	if !errors.As(err, &semanticErr) || !strings.Contains(err.Error(), gopolls.StateVotesEntry) {
+		t.Errorf("Expected PollingSemanticError for missing votes entry, got %v", err)
+	}
+	_, err = gopolls.LoadState(writeStateTestArchive(t, gopolls.StateManifestEntry, `{"version": 2}`))
+	if !errors.As(err, &semanticErr) || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("Expected PollingSemanticError for unsupported version, got %v", err)
+	}
+	_, err = gopolls.LoadState(writeStateTestArchive(t, gopolls.StateManifestEntry, "{"))
+	if !errors.As(err, &archiveErr) || archiveErr.Entry != gopolls.StateManifestEntry ||
+		!errors.As(err, &syntaxErr) {
+		t.Errorf("Expected StateArchiveError for invalid manifest, got %v", err)
+	}
+	_, err = gopolls.LoadState(writeStateTestArchive(t, gopolls.StateManifestEntry, manifest,
+		gopolls.StateVotersEntry, "* one: 1\none\n", gopolls.StateCollectionEntry, collection,
+		gopolls.StateVotesEntry, ""))
+	if !errors.As(err, &archiveErr) || archiveErr.Entry != gopolls.StateVotersEntry ||
+		!errors.Is(err, gopolls.ErrPoll) {
+		t.Errorf("Expected StateArchiveError for invalid voters, got %v", err)
+	}
+	_, err = gopolls.LoadState(writeStateTestArchive(t, gopolls.StateManifestEntry, manifest,
+		gopolls.StateVotersEntry, "* one: 1\n", gopolls.StateCollectionEntry, collection,
+		gopolls.StateVotesEntry, `{"poll": "Poll", "voter": "two", "type": "basic-vote", "choice": "aye"}`))
+	if !errors.As(err, &archiveErr) || archiveErr.Entry != gopolls.StateVotesEntry ||
+		!errors.As(err, &semanticErr) {
+		t.Errorf("Expected StateArchiveError for unknown voter, got %v", err)
+	}
+
+	// corrupt the data of an entry, the checksum doesn't match anymore
+	voters, coll, polls := stateTestInput(t)
+	var buf bytes.Buffer
+	archiver := gopolls.NewStateArchiver()
+	if err = archiver.Save(&buf, voters, coll, polls); err != nil {
+		t.Fatalf("Unexpected error saving state: %v", err)
+	}
+	data := buf.Bytes()
+	zipReader, zipErr := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if zipErr != nil {
+		t.Fatalf("Unexpected error reading archive: %v", zipErr)
+	}
+	var offset int64 = -1
+	for _, file := range zipReader.File {
+		if file.Name == gopolls.StateVotersEntry {
+			if offset, err = file.DataOffset(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+	corrupt := append([]byte(nil), data...)
+	corrupt[offset+2] ^= 0xff
+	if _, err = archiver.Load(bytes.NewReader(corrupt)); !errors.As(err, &archiveErr) {
+		t.Errorf("Expected StateArchiveError for corrupt entry, got %v", err)
+	}
+
+	var validationErr *gopolls.ParserValidationError
+	archiver.MaxTotalBytes = len(data) - 1
+	if _, err = archiver.Load(bytes.NewReader(data)); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for too big archive, got %v", err)
+	}
+	archiver.MaxTotalBytes = -1
+
+	// the uncompressed size of all entries is limited
+	var uncompressed int
+	for _, file := range zipReader.File {
+		uncompressed += int(file.UncompressedSize64)
+	}
+	archiver.MaxUncompressedBytes = uncompressed
+	if _, err = archiver.Load(bytes.NewReader(data)); err != nil {
+		t.Errorf("Unexpected error loading archive with exact uncompressed limit: %v", err)
+	}
+	archiver.MaxUncompressedBytes = uncompressed - 1
+	if _, err = archiver.Load(bytes.NewReader(data)); !errors.As(err, &validationErr) || !errors.As(err, &archiveErr) {
+		t.Errorf("Expected ParserValidationError for too big entries, got %v", err)
+	}
+	// a small archive with a huge entry
+	bomb := writeStateTestArchive(t, gopolls.StateManifestEntry, manifest,
+		gopolls.StateVotersEntry, strings.Repeat(" ", 1<<20))
+	archiver.MaxTotalBytes, archiver.MaxUncompressedBytes = 1<<14, 1<<16
+	if _, err = archiver.Load(bomb); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for a zip bomb, got %v", err)
+	}
+}
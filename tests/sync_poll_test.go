@@ -0,0 +1,113 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"github.com/FabianWe/gopolls"
+	"sync"
+	"testing"
+)
+
+// addVotesConcurrently adds numVotes votes (generated by newVote) to poll from numVotes goroutines.
+func addVotesConcurrently(t *testing.T, poll gopolls.SynchronizedPoll, numVotes int,
+	newVote func(voter *gopolls.Voter) gopolls.AbstractVote) {
+	var wg sync.WaitGroup
+	wg.Add(numVotes)
+	for i := 0; i < numVotes; i++ {
+		go func(i int) {
+			defer wg.Done()
+			if err := poll.AddVote(newVote(gopolls.NewVoter(fmt.Sprintf("voter%d", i), 1))); err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSynchronizedPollAddVote(t *testing.T) {
+	const numVotes = 500
+	tests := []struct {
+		poll     gopolls.AbstractPoll
+		newVote  func(voter *gopolls.Voter) gopolls.AbstractVote
+		numVotes func(poll gopolls.AbstractPoll) int
+	}{
+		{
+			gopolls.NewBasicPoll(nil),
+			func(voter *gopolls.Voter) gopolls.AbstractVote { return gopolls.NewBasicVote(voter, gopolls.Aye) },
+			func(poll gopolls.AbstractPoll) int { return len(poll.(*gopolls.BasicPoll).Votes) },
+		},
+		{
+			gopolls.NewMedianPoll(100, nil),
+			func(voter *gopolls.Voter) gopolls.AbstractVote { return gopolls.NewMedianVote(voter, 42) },
+			func(poll gopolls.AbstractPoll) int { return len(poll.(*gopolls.MedianPoll).Votes) },
+		},
+		{
+			gopolls.NewSchulzePoll(3, nil),
+			func(voter *gopolls.Voter) gopolls.AbstractVote {
+				return gopolls.NewSchulzeVote(voter, gopolls.SchulzeRanking{0, 1, 2})
+			},
+			func(poll gopolls.AbstractPoll) int { return len(poll.(*gopolls.SchulzePoll).Votes) },
+		},
+	}
+	for _, tc := range tests {
+		poll := gopolls.NewSynchronizedPoll(tc.poll)
+		if poll.PollType() != tc.poll.PollType() {
+			t.Errorf("Expected poll type %s, got %s", tc.poll.PollType(), poll.PollType())
+		}
+		if poll.Unwrap() != tc.poll {
+			t.Errorf("Unwrap must return the wrapped poll")
+		}
+		addVotesConcurrently(t, poll, numVotes, tc.newVote)
+		err := poll.WithLock(func(wrapped gopolls.AbstractPoll) error {
+			if got := tc.numVotes(wrapped); got != numVotes {
+				return fmt.Errorf("expected %d votes in %s poll, got %d", numVotes, wrapped.PollType(), got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// pollWithoutGenerator is a poll that doesn't implement VoteGenerator.
+type pollWithoutGenerator struct{}
+
+func (poll pollWithoutGenerator) PollType() string {
+	return "test-poll"
+}
+
+func (poll pollWithoutGenerator) AddVote(vote gopolls.AbstractVote) error {
+	return nil
+}
+
+func TestSynchronizedPollVoteGenerator(t *testing.T) {
+	poll := gopolls.NewSynchronizedPoll(gopolls.NewSchulzePoll(3, nil))
+	generator, ok := poll.(gopolls.VoteGenerator)
+	if !ok {
+		t.Fatal("Expected synchronized SchulzePoll to implement VoteGenerator")
+	}
+	vote, err := generator.GenerateVoteFromBasicAnswer(gopolls.NewVoter("one", 1), gopolls.Aye)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if vote.VoteType() != gopolls.SchulzeVoteType {
+		t.Errorf("Expected vote of type %s, got %s", gopolls.SchulzeVoteType, vote.VoteType())
+	}
+	if _, ok := gopolls.NewSynchronizedPoll(pollWithoutGenerator{}).(gopolls.VoteGenerator); ok {
+		t.Error("Expected synchronized poll not to implement VoteGenerator")
+	}
+}
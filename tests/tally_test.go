@@ -0,0 +1,145 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestTallyCollection(t *testing.T) {
+	in := "# Title\n## Group 1\n### Motion\n* yes\n* no\n### Budget [majority=2/3]\n- 100€\n## Group 2\n### Chair\n* A\n* B\n* C\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	one, two, three := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2), gopolls.NewVoter("three", 3)
+	polls := gopolls.PollMap{
+		"Motion": gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(one, gopolls.Aye),
+			gopolls.NewBasicVote(two, gopolls.No),
+		}),
+		"Budget": gopolls.NewMedianPoll(10000, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(one, 10000),
+			gopolls.NewMedianVote(two, 5000),
+			gopolls.NewMedianVote(three, 0),
+		}),
+		"Chair": gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 1}),
+		}),
+	}
+	groups, err := gopolls.TallyCollection(coll, polls)
+	if err != nil {
+		t.Fatalf("Unexpected error tallying collection: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Title != "Group 1" || len(groups[0].Polls) != 2 || len(groups[1].Polls) != 1 {
+		t.Fatalf("Groups don't mirror the collection: %+v", groups)
+	}
+	expected := []struct {
+		tallied    *gopolls.TalliedPoll
+		name       string
+		resultType string
+		weightSum  gopolls.Weight
+	}{
+		{groups[0].Polls[0], "Motion", gopolls.BasicResultType, 3},
+		{groups[0].Polls[1], "Budget", gopolls.MedianResultType, 6},
+		{groups[1].Polls[0], "Chair", gopolls.SchulzeResultType, 1},
+	}
+	for _, tc := range expected {
+		if tc.tallied.Skeleton.GetName() != tc.name || tc.tallied.Poll != polls[tc.name] {
+			t.Errorf("Expected skeleton and poll for %s, got %v", tc.name, tc.tallied.Skeleton.GetName())
+		}
+		if got := tc.tallied.Result.ResultType(); got != tc.resultType {
+			t.Errorf("Expected result type %s for %s, got %s", tc.resultType, tc.name, got)
+		}
+		if got := tc.tallied.Result.GetWeightSum(); got != tc.weightSum {
+			t.Errorf("Expected weight sum %d for %s, got %d", tc.weightSum, tc.name, got)
+		}
+	}
+	// the majority annotation must be used: 2/3 of 6 is 4, only 0 has a weight > 4
+	medianRes := groups[0].Polls[1].Result.(*gopolls.MedianResult)
+	if medianRes.RequiredMajority != 4 || medianRes.MajorityValue != 0 {
+		t.Errorf("Expected majority 4 and value 0, got %d and %d", medianRes.RequiredMajority, medianRes.MajorityValue)
+	}
+
+	delete(polls, "Chair")
+	_, err = gopolls.TallyCollection(coll, polls)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for a missing poll, got %v", err)
+	}
+}
+
+func TestTallyWeightOverflow(t *testing.T) {
+	big1, big2 := gopolls.NewVoter("one", gopolls.NoWeight-1), gopolls.NewVoter("two", 1)
+	polls := []gopolls.AbstractPoll{
+		gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(big1, gopolls.Aye),
+			gopolls.NewBasicVote(big2, gopolls.Aye),
+		}),
+		gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(big1, 100),
+			gopolls.NewMedianVote(big2, 100),
+		}),
+		gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(big1, gopolls.SchulzeRanking{0, 1}),
+			gopolls.NewSchulzeVote(big2, gopolls.SchulzeRanking{0, 1}),
+		}),
+	}
+	for _, poll := range polls {
+		res, err := gopolls.TallyPoll(poll)
+		if !errors.Is(err, gopolls.ErrWeightOverflow) {
+			t.Errorf("Expected ErrWeightOverflow for %s, got %v", poll.PollType(), err)
+		}
+		var semanticErr gopolls.PollingSemanticError
+		if !errors.As(err, &semanticErr) {
+			t.Errorf("Expected PollingSemanticError for %s, got %v", poll.PollType(), err)
+		}
+		if res == nil {
+			t.Errorf("Expected a result with an error for %s", poll.PollType())
+		}
+	}
+
+	// overflow in the effective weight of a single voter
+	delegating := gopolls.NewVoter("three", gopolls.NoWeight-1)
+	delegating.Delegations = []gopolls.Delegation{gopolls.NewDelegation("four", 1)}
+	if _, err := delegating.CheckedEffectiveWeight(); !errors.Is(err, gopolls.ErrWeightOverflow) {
+		t.Errorf("Expected ErrWeightOverflow for effective weight, got %v", err)
+	}
+	res := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(delegating, gopolls.No)}).Tally()
+	if !errors.Is(res.Err, gopolls.ErrWeightOverflow) || res.WeightedVotes.NumNoes != 0 {
+		t.Errorf("Expected empty result with ErrWeightOverflow, got %v", res.Err)
+	}
+}
+
+func TestAddWeight(t *testing.T) {
+	if sum, err := gopolls.AddWeight(gopolls.NoWeight-2, 1); err != nil || sum != gopolls.NoWeight-1 {
+		t.Errorf("Expected %d, got %d (%v)", gopolls.NoWeight-1, sum, err)
+	}
+	if _, err := gopolls.AddWeight(gopolls.NoWeight-1, 1); !errors.Is(err, gopolls.ErrWeightOverflow) {
+		t.Errorf("Expected ErrWeightOverflow, got %v", err)
+	}
+	counter := gopolls.NewBasicPollCounter()
+	if err := counter.CheckedIncrease(gopolls.Aye, gopolls.NoWeight-1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := counter.CheckedIncrease(gopolls.Aye, 1); !errors.Is(err, gopolls.ErrWeightOverflow) {
+		t.Errorf("Expected ErrWeightOverflow, got %v", err)
+	}
+	if counter.NumAyes != gopolls.NoWeight-1 {
+		t.Errorf("Counter must not change on overflow, got %d", counter.NumAyes)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+func TestBasicPollOutcome(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2)
+	tests := []struct {
+		votes    []*gopolls.BasicVote
+		expected gopolls.BasicPollOutcome
+	}{
+		{[]*gopolls.BasicVote{gopolls.NewBasicVote(two, gopolls.Aye), gopolls.NewBasicVote(one, gopolls.No)}, gopolls.Passed},
+		{[]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(two, gopolls.No)}, gopolls.Rejected},
+		{[]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(two, gopolls.Abstention)}, gopolls.Passed},
+		{[]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(one, gopolls.No)}, gopolls.Tied},
+		{[]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Abstention)}, gopolls.NoVotes},
+		{nil, gopolls.NoVotes},
+	}
+	for _, tc := range tests {
+		res := gopolls.NewBasicPoll(tc.votes).Tally()
+		if got := res.Outcome(); got != tc.expected {
+			t.Errorf("Expected outcome %s, got %s", tc.expected, got)
+		}
+		if tieBreak := gopolls.BreakTie(res, gopolls.NewOrderTieBreaker()); (tieBreak != nil) != (tc.expected == gopolls.Tied) {
+			t.Errorf("Expected a tie break only for a tied outcome, got %v for outcome %s", tieBreak, tc.expected)
+		}
+	}
+}
+
+func TestSchulzeTied(t *testing.T) {
+	one := gopolls.NewVoter("one", 1)
+	tied := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 0, 1}),
+	}).Tally()
+	if !tied.Tied() {
+		t.Errorf("Expected result to be tied, ranked groups: %v", tied.RankedGroups)
+	}
+	unique := gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1, 1}),
+	}).Tally()
+	if unique.Tied() {
+		t.Errorf("Expected result not to be tied, ranked groups: %v", unique.RankedGroups)
+	}
+}
+
+func TestScoreTied(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	poll := gopolls.NewScorePoll(3, 5, []*gopolls.ScoreVote{
+		gopolls.NewScoreVote(one, []uint8{5, 0, 5}),
+		gopolls.NewScoreVote(two, []uint8{0, 4, 0}),
+	})
+	tied := poll.Tally()
+	tieBreak := gopolls.BreakTie(tied, gopolls.NewOrderTieBreaker())
+	if !tied.Tied() || tieBreak == nil || !reflect.DeepEqual(tieBreak.Candidates, []int{0, 2}) || tieBreak.Winner != 0 {
+		t.Errorf("Expected tie between options 0 and 2, got %+v", tieBreak)
+	}
+	// in the runoff option 0 and 2 are still tied
+	withRunoff := poll.TallyWithRunoff()
+	tieBreak = gopolls.BreakTie(withRunoff, gopolls.NewOrderTieBreaker())
+	if !withRunoff.Tied() || tieBreak == nil || !reflect.DeepEqual(tieBreak.Candidates, []int{0, 2}) {
+		t.Errorf("Expected tie in the runoff between options 0 and 2, got %+v", tieBreak)
+	}
+	poll.Votes[1].Scores[0] = 1
+	if unique := poll.TallyWithRunoff(); unique.Tied() || gopolls.BreakTie(unique, gopolls.NewOrderTieBreaker()) != nil {
+		t.Errorf("Expected result not to be tied, got runoff %+v", unique.Runoff)
+	}
+}
+
+func TestTieBreakers(t *testing.T) {
+	candidates := []int{4, 2, 7}
+	if got := gopolls.NewOrderTieBreaker().Choose(candidates); got != 2 {
+		t.Errorf("Expected order tie breaker to choose 2, got %d", got)
+	}
+	choose := func(seed int64) []int {
+		breaker := gopolls.NewRandomTieBreaker(seed)
+		res := make([]int, 20)
+		for i := range res {
+			res[i] = breaker.Choose(candidates)
+		}
+		return res
+	}
+	first, second := choose(42), choose(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Random tie breaker with same seed is not reproducible: %v vs %v", first, second)
+	}
+	for _, winner := range first {
+		if winner != 2 && winner != 4 && winner != 7 {
+			t.Errorf("Random tie breaker chose %d which is not a candidate", winner)
+		}
+	}
+	// the order of the candidates must not matter
+	reordered := gopolls.NewRandomTieBreaker(42)
+	for i, winner := range first {
+		if got := reordered.Choose([]int{7, 4, 2}); got != winner {
+			t.Errorf("Expected winner %d in round %d for reordered candidates, got %d", winner, i, got)
+		}
+	}
+}
+
+func TestTallyCollectionWithTieBreaker(t *testing.T) {
+	in := "# Title\n## Group\n### Motion\n* yes\n* no\n### Chair\n* A\n* B\n* C\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	polls := gopolls.PollMap{
+		"Motion": gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(one, gopolls.Aye),
+			gopolls.NewBasicVote(two, gopolls.No),
+		}),
+		"Chair": gopolls.NewSchulzePoll(3, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{1, 0, 0}),
+		}),
+	}
+	groups, err := gopolls.TallyCollection(coll, polls)
+	if err != nil {
+		t.Fatalf("Unexpected error tallying collection: %v", err)
+	}
+	for _, tallied := range groups[0].Polls {
+		if tallied.TieBreak != nil {
+			t.Errorf("Expected no tie break without a tie breaker, got %+v", tallied.TieBreak)
+		}
+	}
+	groups, err = gopolls.TallyCollection(coll, polls, gopolls.WithTieBreaker(gopolls.NewOrderTieBreaker()))
+	if err != nil {
+		t.Fatalf("Unexpected error tallying collection: %v", err)
+	}
+	expected := []gopolls.TieBreak{
+		{Candidates: []int{int(gopolls.No), int(gopolls.Aye)}, Winner: int(gopolls.No), Method: "option order"},
+		{Candidates: []int{1, 2}, Winner: 1, Method: "option order"},
+	}
+	for i, tallied := range groups[0].Polls {
+		if tallied.TieBreak == nil || !reflect.DeepEqual(*tallied.TieBreak, expected[i]) {
+			t.Errorf("Expected tie break %+v for %s, got %+v", expected[i], tallied.Skeleton.GetName(), tallied.TieBreak)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func turnoutTestSetup(t *testing.T) (gopolls.PollMap, gopolls.VoterMap) {
+	one := gopolls.NewVoter("one", 1)
+	two := gopolls.NewVoter("two", 2)
+	three := gopolls.NewVoter("three", 3)
+	four := gopolls.NewVoter("four", 4)
+	voters, err := gopolls.VotersToMap([]*gopolls.Voter{one, two, three, four})
+	if err != nil {
+		t.Fatalf("unexpected error creating voter map: %v", err)
+	}
+	polls := gopolls.PollMap{
+		"basic": gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(one, gopolls.Aye),
+			gopolls.NewBasicVote(two, gopolls.Abstention),
+			gopolls.NewBasicVote(three, gopolls.No),
+		}),
+		"median": gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(one, 50),
+			gopolls.NewMedianAbstention(four),
+		}),
+	}
+	return polls, voters
+}
+
+func TestComputeTurnout(t *testing.T) {
+	polls, voters := turnoutTestSetup(t)
+	turnout, err := gopolls.ComputeTurnout(polls, voters)
+	if err != nil {
+		t.Fatalf("unexpected error computing turnout: %v", err)
+	}
+	if turnout.NumEligible != 4 || turnout.EligibleWeight != 10 {
+		t.Errorf("expected 4 eligible voters with weight 10, got %d and %d",
+			turnout.NumEligible, turnout.EligibleWeight)
+	}
+	if turnout.NumVotedAny != 4 || turnout.WeightVotedAny != 10 {
+		t.Errorf("expected 4 voters with weight 10 to vote in any poll, got %d and %d",
+			turnout.NumVotedAny, turnout.WeightVotedAny)
+	}
+	if turnout.NumVotedAll != 1 || turnout.WeightVotedAll != 1 {
+		t.Errorf("expected 1 voter with weight 1 to vote in all polls, got %d and %d",
+			turnout.NumVotedAll, turnout.WeightVotedAll)
+	}
+	if len(turnout.Polls) != 2 {
+		t.Fatalf("expected turnout for 2 polls, got %d", len(turnout.Polls))
+	}
+	basic, median := turnout.Polls[0], turnout.Polls[1]
+	if basic.Poll != "basic" || basic.NumVotes != 3 || basic.VotesWeight != 6 ||
+		basic.NumAbstentions != 1 || basic.AbstentionWeight != 2 || !basic.SupportsAbstention {
+		t.Errorf("unexpected turnout for basic poll: %+v", basic)
+	}
+	if gopolls.FormatPercentage(basic.Percentage) != "60.000" {
+		t.Errorf("expected percentage 60.000 for basic poll, got %s", gopolls.FormatPercentage(basic.Percentage))
+	}
+	if median.Poll != "median" || median.NumVotes != 2 || median.VotesWeight != 5 ||
+		median.NumAbstentions != 1 || median.AbstentionWeight != 4 {
+		t.Errorf("unexpected turnout for median poll: %+v", median)
+	}
+}
+
+func TestComputeTurnoutSynchronizedPoll(t *testing.T) {
+	polls, voters := turnoutTestSetup(t)
+	polls["basic"] = gopolls.NewSynchronizedPoll(polls["basic"])
+	turnout, err := gopolls.ComputeTurnout(polls, voters)
+	if err != nil {
+		t.Fatalf("unexpected error computing turnout: %v", err)
+	}
+	if basic := turnout.Polls[0]; basic.NumVotes != 3 || !basic.SupportsAbstention || basic.NumAbstentions != 1 {
+		t.Errorf("unexpected turnout for synchronized basic poll: %+v", basic)
+	}
+}
+
+func TestComputeTurnoutNotEligible(t *testing.T) {
+	polls, voters := turnoutTestSetup(t)
+	delete(voters, "four")
+	_, err := gopolls.ComputeTurnout(polls, voters)
+	if err == nil {
+		t.Fatal("expected an error for a vote of a voter that is not eligible")
+	}
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("expected a PollingSemanticError, got %v", err)
+	}
+}
+
+func TestTurnoutString(t *testing.T) {
+	polls, voters := turnoutTestSetup(t)
+	turnout, err := gopolls.ComputeTurnout(polls, voters)
+	if err != nil {
+		t.Fatalf("unexpected error computing turnout: %v", err)
+	}
+	expected := "Eligible voters: 4 (weight 10)\n" +
+		"Voted in at least one poll: 4 (weight 10, 100.000 %)\n" +
+		"Voted in all polls: 1 (weight 1, 10.000 %)\n" +
+		"basic: 3 votes, weight 6 (60.000 % of eligible weight), 1 abstentions (weight 2)\n" +
+		"median: 2 votes, weight 5 (50.000 % of eligible weight), 1 abstentions (weight 4)\n"
+	if got := turnout.String(); got != expected {
+		t.Errorf("expected turnout string\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestTurnoutJSON(t *testing.T) {
+	polls, voters := turnoutTestSetup(t)
+	turnout, err := gopolls.ComputeTurnout(polls, voters)
+	if err != nil {
+		t.Fatalf("unexpected error computing turnout: %v", err)
+	}
+	encoded, err := json.Marshal(turnout)
+	if err != nil {
+		t.Fatalf("unexpected error encoding turnout: %v", err)
+	}
+	expected := `{"polls":[` +
+		`{"poll":"basic","num_votes":3,"votes_weight":6,"percentage":"60.000","num_abstentions":1,` +
+		`"abstention_weight":2},` +
+		`{"poll":"median","num_votes":2,"votes_weight":5,"percentage":"50.000","num_abstentions":1,` +
+		`"abstention_weight":4}],` +
+		`"num_eligible":4,"eligible_weight":10,"num_voted_any":4,"weight_voted_any":10,` +
+		`"num_voted_all":1,"weight_voted_all":1}`
+	if string(encoded) != expected {
+		t.Errorf("expected JSON\n%s\ngot\n%s", expected, string(encoded))
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestVoteEditorRemoveVote(t *testing.T) {
+	one, two, three := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1), gopolls.NewVoter("three", 1)
+	polls := []gopolls.VoteEditor{
+		gopolls.NewBasicPoll([]*gopolls.BasicVote{
+			gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(two, gopolls.No), gopolls.NewBasicVote(three, gopolls.Aye),
+		}),
+		gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+			gopolls.NewMedianVote(one, 80), gopolls.NewMedianVote(two, 50), gopolls.NewMedianVote(three, 20),
+		}),
+		gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1}),
+			gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{1, 0}),
+			gopolls.NewSchulzeVote(three, gopolls.SchulzeRanking{0, 0}),
+		}),
+		gopolls.NewScorePoll(2, 5, []*gopolls.ScoreVote{
+			gopolls.NewScoreVote(one, []uint8{1, 2}), gopolls.NewScoreVote(two, []uint8{3, 4}), gopolls.NewScoreVote(three, []uint8{5, 0}),
+		}),
+	}
+	for _, poll := range polls {
+		if !poll.HasVoteFrom("two") || poll.HasVoteFrom("four") {
+			t.Errorf("HasVoteFrom returned wrong result for poll of type %s", poll.PollType())
+		}
+		removed, err := poll.RemoveVote("two")
+		if err != nil {
+			t.Fatalf("Unexpected error for poll of type %s: %s", poll.PollType(), err)
+		}
+		if removed.GetVoter() != two {
+			t.Errorf("Expected removed vote of two for poll of type %s, got %v", poll.PollType(), removed)
+		}
+		if poll.HasVoteFrom("two") {
+			t.Errorf("Vote of two still in poll of type %s", poll.PollType())
+		}
+		votes, _ := gopolls.CollectVotes(poll)
+		if len(votes) != 2 || votes[0].GetVoter() != one || votes[1].GetVoter() != three {
+			t.Errorf("Expected votes of one and three for poll of type %s, got %v", poll.PollType(), votes)
+		}
+		_, err = poll.RemoveVote("two")
+		var notFound gopolls.VoteNotFoundError
+		if !errors.As(err, &notFound) || notFound.VoterName != "two" {
+			t.Errorf("Expected VoteNotFoundError for poll of type %s, got %v", poll.PollType(), err)
+		}
+		if !errors.Is(err, gopolls.ErrPoll) {
+			t.Errorf("Expected VoteNotFoundError to be an ErrPoll, got %v", err)
+		}
+	}
+}
+
+func TestVoteEditorReplaceVote(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	poll := gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1}),
+		gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{1, 0}),
+		gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 0}),
+	})
+	replacement := gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{1, 0})
+	if err := poll.ReplaceVote(replacement); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(poll.Votes) != 2 || poll.Votes[0] != replacement || poll.Votes[1].Voter != two {
+		t.Errorf("Expected replaced vote at position 0 and duplicate removed, got %v", poll.Votes)
+	}
+	// voter without a vote is added
+	three := gopolls.NewVoter("three", 1)
+	if err := poll.ReplaceVote(gopolls.NewSchulzeVote(three, gopolls.SchulzeRanking{0, 1})); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(poll.Votes) != 3 || poll.Votes[2].Voter != three {
+		t.Errorf("Expected vote of three to be added, got %v", poll.Votes)
+	}
+	// wrong type
+	err := poll.ReplaceVote(gopolls.NewBasicVote(one, gopolls.Aye))
+	var typeErr gopolls.PollTypeError
+	if !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+	// no voter
+	err = poll.ReplaceVote(gopolls.NewSchulzeVote(nil, gopolls.SchulzeRanking{0, 1}))
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError, got %v", err)
+	}
+}
+
+func TestVoteEditorMedianSorted(t *testing.T) {
+	one, two, three := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1), gopolls.NewVoter("three", 1)
+	poll := gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 20), gopolls.NewMedianVote(two, 80), gopolls.NewMedianVote(three, 50),
+	})
+	poll.SortVotes()
+	// removing a vote keeps the votes sorted
+	if _, err := poll.RemoveVote("three"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !poll.Sorted || poll.Votes[0].Value != 80 || poll.Votes[1].Value != 20 {
+		t.Errorf("Expected sorted votes 80, 20, got %v (sorted: %v)", poll.Votes, poll.Sorted)
+	}
+	if err := poll.ReplaceVote(gopolls.NewMedianVote(one, 90)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if poll.Sorted {
+		t.Error("Expected Sorted to be false after replacing a vote")
+	}
+	res := poll.Tally(0)
+	if res.MajorityValue != 90 {
+		t.Errorf("Expected majority value 90, got %d", res.MajorityValue)
+	}
+	poll.SortVotes()
+	if err := poll.AddVote(gopolls.NewMedianVote(three, 100)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if poll.Sorted {
+		t.Error("Expected Sorted to be false after adding a vote")
+	}
+}
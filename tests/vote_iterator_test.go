@@ -0,0 +1,91 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestCollectVotes(t *testing.T) {
+	one, two := gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 1)
+	median := gopolls.NewMedianPoll(100, []*gopolls.MedianVote{
+		gopolls.NewMedianVote(one, 80), gopolls.NewMedianVote(two, 20),
+	})
+	polls := []gopolls.AbstractPoll{
+		gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(two, gopolls.No)}),
+		median,
+		gopolls.NewSchulzePoll(2, []*gopolls.SchulzeVote{
+			gopolls.NewSchulzeVote(one, gopolls.SchulzeRanking{0, 1}), gopolls.NewSchulzeVote(two, gopolls.SchulzeRanking{1, 0}),
+		}),
+		gopolls.NewScorePoll(2, 5, []*gopolls.ScoreVote{
+			gopolls.NewScoreVote(one, []uint8{1, 2}), gopolls.NewScoreVote(two, []uint8{3, 4}),
+		}),
+	}
+	for _, poll := range polls {
+		votes, err := gopolls.CollectVotes(poll)
+		if err != nil {
+			t.Fatalf("Unexpected error for poll of type %s: %s", poll.PollType(), err)
+		}
+		if len(votes) != 2 || votes[0].GetVoter() != one || votes[1].GetVoter() != two {
+			t.Errorf("Expected votes of one and two for poll of type %s, got %v", poll.PollType(), votes)
+		}
+		count, countErr := gopolls.VoteCount(poll)
+		if countErr != nil || count != 2 {
+			t.Errorf("Expected two votes for poll of type %s, got %d (error %v)", poll.PollType(), count, countErr)
+		}
+	}
+
+	// changing the returned slice must not change the poll
+	votes, _ := gopolls.CollectVotes(median)
+	votes[0], votes[1] = votes[1], votes[0]
+	if median.Votes[0].Voter != one {
+		t.Error("Changing the collected votes changed the poll")
+	}
+
+	// synchronized polls are unwrapped
+	count, err := gopolls.VoteCount(gopolls.NewSynchronizedPoll(median))
+	if err != nil || count != 2 {
+		t.Errorf("Expected two votes for synchronized poll, got %d (error %v)", count, err)
+	}
+
+	var typeErr gopolls.PollTypeError
+	if _, err := gopolls.CollectVotes(pollWithoutGenerator{}); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+	if _, err := gopolls.VoteCount(pollWithoutGenerator{}); !errors.As(err, &typeErr) {
+		t.Errorf("Expected PollTypeError, got %v", err)
+	}
+}
+
+func TestForEachVoteStops(t *testing.T) {
+	one := gopolls.NewVoter("one", 1)
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(one, gopolls.Aye), gopolls.NewBasicVote(one, gopolls.No), gopolls.NewBasicVote(one, gopolls.No),
+	})
+	stop := errors.New("stop")
+	calls := 0
+	err := poll.ForEachVote(func(vote gopolls.AbstractVote) error {
+		calls++
+		if vote.(*gopolls.BasicVote).Choice == gopolls.No {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 2 {
+		t.Errorf("Expected iteration to stop after two votes, got %d calls and error %v", calls, err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+func TestFillPollsWithVotesRawVoteSources(t *testing.T) {
+	matrix, polls, voters, parsers, policies := matrixTestSetup(t, 2)
+	matrix.Body[0][2] = " YES "
+	matrix.Body[1][1] = ""
+	policies["p0"] = gopolls.AddAsAbstentionEmptyVote
+	_, _, err := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false,
+		gopolls.WithRawVoteSources())
+	if err != nil {
+		t.Fatalf("Unexpected error filling polls: %v", err)
+	}
+	expected := map[string][]gopolls.VoteSource{
+		"p0": {{Raw: "aye", Row: 0, Column: 1}, {Raw: "", Row: 1, Column: 1}},
+		"p1": {{Raw: " YES ", Row: 0, Column: 2}, {Raw: "no", Row: 1, Column: 2}},
+	}
+	for name, sources := range expected {
+		votes := polls[name].(*gopolls.BasicPoll).Votes
+		if len(votes) != len(sources) {
+			t.Fatalf("Expected %d votes for poll %s, got %d", len(sources), name, len(votes))
+		}
+		for i, vote := range votes {
+			source := vote.GetSource()
+			if source == nil || *source != sources[i] {
+				t.Errorf("Expected source %v for vote %d in poll %s, got %v", sources[i], i, name, source)
+				continue
+			}
+			if !source.HasCell() || matrix.Body[source.Row][source.Column] != source.Raw {
+				t.Errorf("Expected source %v to point to the matrix entry", source)
+			}
+		}
+	}
+
+	// without the option no source is stored
+	matrix, polls, voters, parsers, policies = matrixTestSetup(t, 1)
+	if _, _, err = matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false); err != nil {
+		t.Fatalf("Unexpected error filling polls: %v", err)
+	}
+	for _, vote := range polls["p0"].(*gopolls.BasicPoll).Votes {
+		if raw, hasRaw := gopolls.RawVoteString(vote); hasRaw {
+			t.Errorf("Expected no raw string without WithRawVoteSources, got \"%s\"", raw)
+		}
+	}
+}
+
+func TestParsersKeepRaw(t *testing.T) {
+	voter := gopolls.NewVoter("one", 1)
+	basicParser := gopolls.NewBasicVoteParser()
+	basicParser.KeepRaw = true
+	medianParser := gopolls.NewMedianVoteParser(gopolls.SimpleEuroHandler{})
+	medianParser.KeepRaw = true
+	schulzeParser := gopolls.NewSchulzeVoteParser(3)
+	schulzeParser.KeepRaw = true
+	scoreParser := gopolls.NewScoreVoteParser(2)
+	scoreParser.KeepRaw = true
+	tests := []struct {
+		parser gopolls.ParserCustomizer
+		poll   gopolls.AbstractPoll
+		in     string
+	}{
+		{basicParser, gopolls.NewBasicPoll(nil), "Ja"},
+		{basicParser, gopolls.NewBasicPoll(nil), "0, 1"},
+		{medianParser, gopolls.NewMedianPoll(10000, nil), "42,50 €"},
+		{schulzeParser, gopolls.NewSchulzePoll(3, nil), "1 / 0 / 2"},
+		{scoreParser, gopolls.NewScorePoll(2, 5, nil), "5,0"},
+	}
+	for _, tc := range tests {
+		// customized parsers keep the flag
+		customized, customizeErr := tc.parser.CustomizeForPoll(tc.poll)
+		if customizeErr != nil {
+			t.Fatalf("Unexpected error customizing parser: %v", customizeErr)
+		}
+		for _, parser := range []gopolls.VoteParser{tc.parser, customized} {
+			vote, err := parser.ParseFromString(tc.in, voter)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing \"%s\": %v", tc.in, err)
+			}
+			source := vote.(gopolls.SourcedVote).GetSource()
+			if source == nil || !reflect.DeepEqual(*source, *gopolls.NewVoteSource(tc.in)) || source.HasCell() {
+				t.Errorf("Expected source for \"%s\" without cell, got %v", tc.in, source)
+			}
+		}
+	}
+
+	vote, err := gopolls.NewBasicVoteParser().ParseFromString("yes", voter)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing vote: %v", err)
+	}
+	if _, hasRaw := gopolls.RawVoteString(vote); hasRaw {
+		t.Errorf("Expected no raw string if KeepRaw is false")
+	}
+}
+
+func TestDeepCloneCopiesVoteSource(t *testing.T) {
+	vote := gopolls.NewBasicVote(gopolls.NewVoter("one", 1), gopolls.Aye)
+	vote.SetSource(&gopolls.VoteSource{Raw: "yes", Row: 3, Column: 1})
+	clone := gopolls.NewBasicPoll([]*gopolls.BasicVote{vote}).DeepClone()
+	source := clone.Votes[0].GetSource()
+	if source == nil || *source != *vote.Source || source == vote.Source {
+		t.Errorf("Expected a copy of source %v in the clone, got %v", vote.Source, source)
+	}
+}
+
+func TestGenerateVoterReceiptsRawStrings(t *testing.T) {
+	in := "# Title\n## Group\n### Motion\n* yes\n* no\n### Budget\n- 100€\n"
+	coll, parseErr := gopolls.NewPollCollectionParser().ParseCollectionSkeletonsFromString(nil, in)
+	if parseErr != nil {
+		t.Fatalf("Unexpected error parsing collection: %v", parseErr)
+	}
+	one := gopolls.NewVoter("one", 1)
+	motionVote := gopolls.NewBasicVote(one, gopolls.Aye)
+	motionVote.SetSource(gopolls.NewVoteSource("Dafür"))
+	// whitespace only strings are not used
+	budgetVote := gopolls.NewMedianAbstention(one)
+	budgetVote.SetSource(&gopolls.VoteSource{Raw: " ", Row: 0, Column: 2})
+	polls := gopolls.PollMap{
+		"Motion": gopolls.NewBasicPoll([]*gopolls.BasicVote{motionVote}),
+		"Budget": gopolls.NewMedianPoll(10000, []*gopolls.MedianVote{budgetVote}),
+	}
+	receipts, err := gopolls.GenerateVoterReceipts(polls, coll, gopolls.SimpleEuroHandler{})
+	if err != nil {
+		t.Fatalf("Unexpected error generating receipts: %v", err)
+	}
+	expected := []gopolls.ReceiptEntry{
+		{Poll: "Motion", Vote: "Dafür", HasVoted: true},
+		{Poll: "Budget", Vote: "abstention", HasVoted: true},
+	}
+	if !reflect.DeepEqual(receipts["one"], expected) {
+		t.Errorf("Expected receipt %v, got %v", expected, receipts["one"])
+	}
+}
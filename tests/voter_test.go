@@ -0,0 +1,369 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveDuplicateVoters(t *testing.T) {
+	voters := []*gopolls.Voter{
+		gopolls.NewVoter("one", 1),
+		gopolls.NewVoter("two", 2),
+		gopolls.NewVoter("one", 3),
+	}
+
+	tests := []struct {
+		policy   gopolls.DuplicateVoterPolicy
+		expected []*gopolls.Voter
+	}{
+		{gopolls.MergeDuplicateVoters, []*gopolls.Voter{gopolls.NewVoter("one", 4), gopolls.NewVoter("two", 2)}},
+		{gopolls.KeepFirstDuplicateVoter, []*gopolls.Voter{gopolls.NewVoter("one", 1), gopolls.NewVoter("two", 2)}},
+		{gopolls.KeepLastDuplicateVoter, []*gopolls.Voter{gopolls.NewVoter("one", 3), gopolls.NewVoter("two", 2)}},
+	}
+
+	for _, tc := range tests {
+		got, err := gopolls.ResolveDuplicateVoters(voters, tc.policy)
+		if err != nil {
+			t.Errorf("Unexpected error for policy %d: %v", tc.policy, err)
+			continue
+		}
+		if len(got) != len(tc.expected) {
+			t.Errorf("Expected voters %v for policy %d, got %v instead", tc.expected, tc.policy, got)
+			continue
+		}
+		for i, voter := range got {
+			if !voter.Equals(tc.expected[i]) {
+				t.Errorf("Expected voter %v for policy %d, got %v instead", tc.expected[i], tc.policy, voter)
+			}
+		}
+	}
+
+	// original voter must not be changed by merging
+	if voters[0].Weight != 1 {
+		t.Errorf("Merging must not change the original voters")
+	}
+
+	_, rejectErr := gopolls.ResolveDuplicateVoters(voters, gopolls.RejectDuplicateVoters)
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(rejectErr, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError for policy reject, got %v instead", rejectErr)
+	}
+}
+
+func TestMergeDuplicateVotersOverflow(t *testing.T) {
+	voters := []*gopolls.Voter{
+		gopolls.NewVoter("one", gopolls.NoWeight-1),
+		gopolls.NewVoter("one", 1),
+	}
+	if _, err := gopolls.VotersToMapWithPolicy(voters, gopolls.MergeDuplicateVoters); err == nil {
+		t.Errorf("Expected an error when merged weight overflows")
+	}
+}
+
+func TestParseVotersDuplicatePolicy(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	parser.DuplicatePolicy = gopolls.MergeDuplicateVoters
+	voters, err := parser.ParseVotersFromString("* one: 2\n* two\n* one: 3\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing voters: %v", err)
+	}
+	if len(voters) != 2 || !voters[0].Equals(gopolls.NewVoter("one", 5)) {
+		t.Errorf("Expected merged voter \"one\" with weight 5, got %v instead", voters)
+	}
+
+	// the zero value doesn't check for duplicates
+	var policy gopolls.DuplicateVoterPolicy
+	if policy != gopolls.NoDuplicateVoterCheck {
+		t.Errorf("Expected zero value to be NoDuplicateVoterCheck, got %d", policy)
+	}
+	parser.DuplicatePolicy = policy
+	if voters, err = parser.ParseVotersFromString("* one: 2\n* one: 3\n"); err != nil || len(voters) != 2 {
+		t.Errorf("Expected both entries without a duplicate check, got %v and error %v", voters, err)
+	}
+}
+
+func TestParseVotersMergeDelegations(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	parser.ParseDelegations = true
+	parser.DuplicatePolicy = gopolls.MergeDuplicateVoters
+	voters, err := parser.ParseVotersFromString("* Alice: 1 (+Bob)\n* Alice: 2 (+Carol)\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing voters: %v", err)
+	}
+	if len(voters) != 1 || len(voters[0].Delegations) != 2 || voters[0].Weight != 3 {
+		t.Errorf("Expected merged voter Alice with two delegations, got %v", voters)
+	}
+
+	// same source in both entries
+	var semanticErr gopolls.PollingSemanticError
+	if _, err := parser.ParseVotersFromString("* Alice: 1 (+Bob)\n* Alice: 2 (+Bob)\n"); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for multiple delegations from Bob, got %v", err)
+	}
+
+	// each entry is valid on its own, but the merged voter delegates to itself or its weight overflows
+	alice := gopolls.NewVoter("Alice", 1)
+	alice.Delegations = []gopolls.Delegation{gopolls.NewDelegation("Bob", 1)}
+	selfDelegation := gopolls.NewVoter("Alice", 1)
+	selfDelegation.Delegations = []gopolls.Delegation{gopolls.NewDelegation("Alice", 1)}
+	heavy := gopolls.NewVoter("Alice", 1)
+	heavy.Delegations = []gopolls.Delegation{gopolls.NewDelegation("Carol", gopolls.NoWeight-2)}
+	for _, other := range []*gopolls.Voter{selfDelegation, heavy} {
+		_, err := gopolls.ResolveDuplicateVoters([]*gopolls.Voter{alice, other}, gopolls.MergeDuplicateVoters)
+		if !errors.As(err, &semanticErr) {
+			t.Errorf("Expected PollingSemanticError merging %v, got %v", other, err)
+		}
+	}
+
+	parser.MaxDelegations = 1
+	if _, err := parser.ParseVotersFromString("* Alice: 1 (+Bob)\n* Alice: 2 (+Carol)\n"); !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for too many merged delegations, got %v", err)
+	}
+}
+
+func TestParseVotersDelegations(t *testing.T) {
+	in := "* Alice: 1 (+Bob, +Carol: 2)\n* Team (Berlin): 3\n* Dave\n"
+	parser := gopolls.NewVotersParser()
+	parser.ParseDelegations = true
+	parser.MaxDelegations = 2
+	voters, err := parser.ParseVotersFromString(in)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing voters: %v", err)
+	}
+	alice := gopolls.NewVoter("Alice", 1)
+	alice.Delegations = []gopolls.Delegation{gopolls.NewDelegation("Bob", 1), gopolls.NewDelegation("Carol", 2)}
+	expected := []*gopolls.Voter{alice, gopolls.NewVoter("Team (Berlin)", 3), gopolls.NewVoter("Dave", 1)}
+	if len(voters) != len(expected) {
+		t.Fatalf("Expected voters %v, got %v instead", expected, voters)
+	}
+	for i, voter := range voters {
+		if !voter.Equals(expected[i]) {
+			t.Errorf("Expected voter %v, got %v instead", expected[i], voter)
+		}
+	}
+	if w := voters[0].EffectiveWeight(); w != 4 {
+		t.Errorf("Expected effective weight 4, got %d instead", w)
+	}
+	if formatted := voters[0].Format(""); formatted != "* Alice: 1 (+Bob: 1, +Carol: 2)" {
+		t.Errorf("Unexpected format result \"%s\"", formatted)
+	}
+
+	invalid := []string{
+		"* Alice: 1 (+Bob, +Carol, +Dave)",
+		"* Alice: 1 (+Alice)",
+		"* Alice: 1 (+Bob, +Bob: 2)",
+		"* Alice: 1 (+Bob: x)",
+	}
+	for _, line := range invalid {
+		if _, lineErr := parser.ParseVotersLine(line); lineErr == nil {
+			t.Errorf("Expected an error for line \"%s\"", line)
+		}
+	}
+}
+
+func TestDelegationsInTally(t *testing.T) {
+	alice := gopolls.NewVoter("Alice", 1)
+	alice.Delegations = []gopolls.Delegation{gopolls.NewDelegation("Bob", 2)}
+	carol := gopolls.NewVoter("Carol", 2)
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(alice, gopolls.Aye),
+		gopolls.NewBasicVote(carol, gopolls.No),
+	})
+	res := poll.Tally()
+	if res.WeightedVotes.NumAyes != 3 || res.WeightedVotes.NumNoes != 2 {
+		t.Errorf("Expected 3 ayes and 2 noes, got %v", res.WeightedVotes)
+	}
+	report, reportErr := gopolls.NewDelegationReport(poll)
+	if reportErr != nil {
+		t.Fatalf("Unexpected error creating report: %v", reportErr)
+	}
+	if report.OwnWeight != 3 || report.DelegatedWeight != 2 || len(report.Delegations) != 1 ||
+		len(report.Delegations["Alice"]) != 1 {
+		t.Errorf("Unexpected delegation report %v", report)
+	}
+
+	heavy := gopolls.NewVoter("Heavy", 1)
+	heavy.Delegations = []gopolls.Delegation{
+		gopolls.NewDelegation("Dave", gopolls.NoWeight-1), gopolls.NewDelegation("Eve", 1),
+	}
+	overflow := gopolls.NewBasicPoll([]*gopolls.BasicVote{gopolls.NewBasicVote(heavy, gopolls.Aye)})
+	if _, err := gopolls.NewDelegationReport(overflow); !errors.Is(err, gopolls.ErrWeightOverflow) {
+		t.Errorf("Expected ErrWeightOverflow for delegated weights, got %v", err)
+	}
+}
+
+func TestParseVotersMaxTotalWeight(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	parser.MaxTotalWeight = 5
+	if _, err := parser.ParseVotersFromString("* Alice: 2\n* Bob: 3\n"); err != nil {
+		t.Errorf("Unexpected error with total weight equal to MaxTotalWeight: %v", err)
+	}
+	_, err := parser.ParseVotersFromString("* Alice: 2\n* Bob: 4\n")
+	validationErr := gopolls.NewParserValidationError("")
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError, got %v", err)
+	}
+
+	// without MaxTotalWeight an overflow must be detected as well
+	parser = gopolls.NewVotersParser()
+	_, err = parser.ParseVotersFromString("* Alice: 4294967294\n* Bob: 4294967294\n")
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError for overflow, got %v", err)
+	}
+}
+
+func TestVotersCSVReader(t *testing.T) {
+	in := bom + "Name;Weight;Email\r\nalice;3;alice@example.com\r\nbob;;bob@example.com\r\nalice;2;other@example.com\r\n"
+	newReader := func(s string) *gopolls.VotersCSVReader {
+		reader := gopolls.NewVotersCSVReader(strings.NewReader(s))
+		reader.Sep = ';'
+		return reader
+	}
+
+	voters, err := newReader(in).ReadVoters()
+	if err != nil {
+		t.Fatalf("Unexpected error reading voters: %v", err)
+	}
+	expected := []*gopolls.Voter{
+		gopolls.NewVoter("alice", 3),
+		gopolls.NewVoter("bob", 1),
+		gopolls.NewVoter("alice", 2),
+	}
+	if len(voters) != len(expected) {
+		t.Fatalf("Expected %d voters, got %d", len(expected), len(voters))
+	}
+	for i, voter := range voters {
+		if !voter.Equals(expected[i]) || voter.Attributes != nil {
+			t.Errorf("Expected voter %v without attributes, got %v", expected[i], voter)
+		}
+	}
+
+	reader := newReader(in)
+	reader.CaptureAttributes = true
+	reader.DuplicatePolicy = gopolls.MergeDuplicateVoters
+	voters, err = reader.ReadVoters()
+	if err != nil {
+		t.Fatalf("Unexpected error reading voters: %v", err)
+	}
+	if len(voters) != 2 || voters[0].Weight != 5 {
+		t.Fatalf("Expected merged voters, got %v", voters)
+	}
+	if expectedAttributes := map[string]string{"Email": "alice@example.com"}; !reflect.DeepEqual(voters[0].Attributes, expectedAttributes) {
+		t.Errorf("Expected attributes %v, got %v", expectedAttributes, voters[0].Attributes)
+	}
+
+	// without a weight column all weights are 1
+	voters, err = newReader("name\nalice\nbob\n").ReadVoters()
+	if err != nil || len(voters) != 2 || voters[0].Weight != 1 || voters[1].Weight != 1 {
+		t.Errorf("Expected two voters with weight 1, got %v (error %v)", voters, err)
+	}
+	// attributes are captured without a weight column too
+	reader = newReader("name;email\nalice;alice@example.com\n")
+	reader.CaptureAttributes = true
+	voters, err = reader.ReadVoters()
+	if err != nil || len(voters) != 1 || !reflect.DeepEqual(voters[0].Attributes, map[string]string{"email": "alice@example.com"}) {
+		t.Errorf("Expected voter with attribute email, got %v (error %v)", voters, err)
+	}
+
+	// errors
+	reader = newReader(in)
+	reader.DuplicatePolicy = gopolls.RejectDuplicateVoters
+	_, err = reader.ReadVoters()
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+
+	var syntaxErr gopolls.PollingSyntaxError
+	_, err = newReader("weight;email\n1;a\n").ReadVoters()
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected PollingSyntaxError for missing name column, got %v", err)
+	}
+	_, err = newReader("name;weight\nalice;1\nbob;x\n").ReadVoters()
+	if !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 3 {
+		t.Errorf("Expected PollingSyntaxError in line 3 for invalid weight, got %v", err)
+	}
+	_, err = newReader("name;weight\nalice;1\nbob\n").ReadVoters()
+	if !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 3 {
+		t.Errorf("Expected PollingSyntaxError in line 3 for short row, got %v", err)
+	}
+	_, err = newReader("name\nalice\n\xff\n").ReadVoters()
+	if !errors.Is(err, gopolls.ErrInvalidEncoding) {
+		t.Errorf("Expected ErrInvalidEncoding, got %v", err)
+	}
+
+	limits := []func(reader *gopolls.VotersCSVReader){
+		func(reader *gopolls.VotersCSVReader) { reader.MaxNumVoters = 2 },
+		func(reader *gopolls.VotersCSVReader) { reader.MaxVotersNameLength = 4 },
+		func(reader *gopolls.VotersCSVReader) { reader.MaxVotersWeight = 2 },
+		func(reader *gopolls.VotersCSVReader) { reader.MaxTotalWeight = 4 },
+	}
+	for i, limit := range limits {
+		reader = newReader(in)
+		limit(reader)
+		_, err = reader.ReadVoters()
+		var validationErr *gopolls.ParserValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ParserValidationError for limit %d, got %v", i, err)
+		}
+	}
+}
+
+func TestVotersToMapChecked(t *testing.T) {
+	voters := gopolls.GenerateVoters(3, nil)
+	voterMap, name, hasDuplicates := gopolls.VotersToMapChecked(voters)
+	expected, err := gopolls.VotersToMap(voters)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hasDuplicates || name != "" || !reflect.DeepEqual(voterMap, expected) {
+		t.Errorf("Expected map %v without duplicates, got %v, \"%s\", %v", expected, voterMap, name, hasDuplicates)
+	}
+
+	voters = append(voters, gopolls.NewVoter("voter-2", 1), gopolls.NewVoter("voter-1", 1))
+	voterMap, name, hasDuplicates = gopolls.VotersToMapChecked(voters)
+	if voterMap != nil || name != "voter-2" || !hasDuplicates {
+		t.Errorf("Expected duplicate \"voter-2\", got %v, \"%s\", %v", voterMap, name, hasDuplicates)
+	}
+}
+
+const benchmarkNumVoters = 100000
+
+func BenchmarkHasDuplicateVotersAndVotersToMap(b *testing.B) {
+	voters := gopolls.GenerateVoters(benchmarkNumVoters, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, hasDuplicates := gopolls.HasDuplicateVoters(voters); hasDuplicates {
+			b.Fatal("Unexpected duplicate")
+		}
+		if _, err := gopolls.VotersToMap(voters); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVotersToMapChecked(b *testing.B) {
+	voters := gopolls.GenerateVoters(benchmarkNumVoters, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, hasDuplicates := gopolls.VotersToMapChecked(voters); hasDuplicates {
+			b.Fatal("Unexpected duplicate")
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVotesKeyValueParser(t *testing.T) {
+	parser := gopolls.NewVotesKeyValueParser()
+	votes, err := parser.ParseFromString("alice", "# my votes\r\nMotion: aye\r\n\r\nBudget: 2021: 42\r\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing votes: %v", err)
+	}
+	if votes.Voter != "alice" || len(votes.Votes) != 2 || votes.Votes["Motion"] != "aye" ||
+		votes.Votes["Budget: 2021"] != "42" {
+		t.Errorf("Votes were not parsed correctly, got %v", votes)
+	}
+
+	_, dupErr := parser.ParseFromString("alice", "Motion: aye\nMotion: no\n")
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(dupErr, &duplicateErr) || !strings.Contains(dupErr.Error(), "alice") {
+		t.Errorf("Expected DuplicateError mentioning the voter, got %v", dupErr)
+	}
+
+	if _, syntaxErr := parser.ParseFromString("alice", "Motion aye\n"); syntaxErr == nil {
+		t.Error("Expected an error for a line without \":\"")
+	}
+
+	parser.MaxVoteLength = 2
+	if _, lengthErr := parser.ParseFromString("alice", "Motion: aye\n"); lengthErr == nil {
+		t.Error("Expected an error for a vote that is too long")
+	}
+}
+
+func TestReadVotesDirectory(t *testing.T) {
+	dir, dirErr := ioutil.TempDir("", "gopolls")
+	if dirErr != nil {
+		t.Fatalf("Can't create temp dir: %v", dirErr)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"two.txt":    "Motion: no\n",
+		"one.txt":    "Motion: aye\nChair: 0, 1\n",
+		"ignore.csv": "foo",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Can't write file: %v", err)
+		}
+	}
+	votes, err := gopolls.ReadVotesDirectory(dir, gopolls.NewVotesKeyValueParser())
+	if err != nil {
+		t.Fatalf("Unexpected error reading directory: %v", err)
+	}
+	if len(votes) != 2 || votes[0].Voter != "one" || votes[1].Voter != "two" {
+		t.Fatalf("Expected votes for voters one and two, got %v", votes)
+	}
+
+	voters := gopolls.VoterMap{"one": gopolls.NewVoter("one", 1), "two": gopolls.NewVoter("two", 1)}
+	motion, chair := gopolls.NewBasicPoll(nil), gopolls.NewSchulzePoll(2, nil)
+	polls := gopolls.PollMap{"Motion": motion, "Chair": chair}
+	matrix, matrixErr := gopolls.VoterVotesToMatrix(votes, polls)
+	if matrixErr != nil {
+		t.Fatalf("Unexpected error creating matrix: %v", matrixErr)
+	}
+	customizers, _ := gopolls.CustomizeParsersToMap(polls, gopolls.GenerateDefaultParserTemplateMap())
+	parsers := make(map[string]gopolls.VoteParser, len(customizers))
+	for name, p := range customizers {
+		parsers[name] = p
+	}
+	policies := gopolls.GeneratePoliciesMap(gopolls.IgnoreEmptyVote, polls)
+	if _, _, fillErr := matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false); fillErr != nil {
+		t.Fatalf("Unexpected error filling polls: %v", fillErr)
+	}
+	if len(motion.Votes) != 2 || len(chair.Votes) != 1 {
+		t.Errorf("Expected two votes for Motion and one for Chair, got %d and %d", len(motion.Votes), len(chair.Votes))
+	}
+
+	votes[1].Votes["Unknown"] = "aye"
+	_, unknownErr := gopolls.VoterVotesToMatrix(votes, polls)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(unknownErr, &semanticErr) || !strings.Contains(unknownErr.Error(), "two") {
+		t.Errorf("Expected PollingSemanticError mentioning the voter, got %v", unknownErr)
+	}
+}
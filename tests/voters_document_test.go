@@ -0,0 +1,122 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"testing"
+)
+
+func TestVotersDocumentRoundTrip(t *testing.T) {
+	inputs := []string{
+		"# voters of the meeting\n\n*   Alice:  3\n* Bob\n\n# guests\n* Carol: 0\n",
+		"\xef\xbb\xbf* Alice: 1\r\n# comment\r\n\r\n* Bob: 2",
+		"",
+		"\n\n",
+		"* Alice: 1 ; board member\n   \n",
+	}
+	parser := gopolls.NewVotersParser()
+	parser.AllowInlineComments = true
+	parser.CommentPrefixes = []string{"#", ";"}
+	for _, input := range inputs {
+		doc, err := parser.ParseVotersDocumentFromString(input)
+		if err != nil {
+			t.Fatalf("Unexpected error for input %q: %s", input, err)
+		}
+		if out := doc.String(); out != input {
+			t.Errorf("Expected round trip to reproduce %q, got %q", input, out)
+		}
+		voters, votersErr := parser.ParseVotersFromString(input)
+		if votersErr != nil {
+			t.Fatalf("Unexpected error for input %q: %s", input, votersErr)
+		}
+		docVoters := doc.Voters()
+		if len(voters) != len(docVoters) {
+			t.Fatalf("Expected %d voters in document, got %d", len(voters), len(docVoters))
+		}
+		for i, voter := range voters {
+			if !voter.Equals(docVoters[i]) {
+				t.Errorf("Expected voter %v in document, got %v", voter, docVoters[i])
+			}
+		}
+	}
+}
+
+func TestVotersDocumentUpdate(t *testing.T) {
+	input := "# voters\n*   Alice:  3 # chair\n\n* Bob\r\n* Carol: 2"
+	parser := gopolls.NewVotersParser()
+	parser.AllowInlineComments = true
+	doc, err := parser.ParseVotersDocumentFromString(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	kinds := []gopolls.VotersEntryKind{gopolls.CommentLineEntry, gopolls.VoterLineEntry, gopolls.BlankLineEntry,
+		gopolls.VoterLineEntry, gopolls.VoterLineEntry}
+	if len(doc.Entries) != len(kinds) {
+		t.Fatalf("Expected %d entries, got %d", len(kinds), len(doc.Entries))
+	}
+	for i, entry := range doc.Entries {
+		if entry.Kind != kinds[i] || entry.LineNum != i+1 {
+			t.Errorf("Expected entry %d to be a %s line in line %d, got %s in line %d", i, kinds[i], i+1,
+				entry.Kind, entry.LineNum)
+		}
+	}
+	if updateErr := doc.UpdateVoter("Alice", func(voter *gopolls.Voter) { voter.Weight = 5 }); updateErr != nil {
+		t.Fatalf("Unexpected error: %s", updateErr)
+	}
+	doc.FindVoter("Bob").Weight = 4
+	if !doc.Entries[1].Modified() || doc.Entries[4].Modified() {
+		t.Error("Expected only the entries of Alice and Bob to be modified")
+	}
+	expected := "# voters\n* Alice: 5 # chair\n\n* Bob: 4\r\n* Carol: 2"
+	if out := doc.String(); out != expected {
+		t.Errorf("Expected output %q, got %q", expected, out)
+	}
+	// changing the weight back restores the original line
+	doc.FindVoter("Bob").Weight = 1
+	if out := doc.String(); out != "# voters\n* Alice: 5 # chair\n\n* Bob\r\n* Carol: 2" {
+		t.Errorf("Expected original line for Bob, got %q", out)
+	}
+	updateErr := doc.UpdateVoter("Dave", func(voter *gopolls.Voter) {})
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(updateErr, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError for unknown voter, got %v", updateErr)
+	}
+}
+
+func TestVotersDocumentValidation(t *testing.T) {
+	parser := gopolls.NewVotersParser()
+	parser.MaxNumVoters = 1
+	_, err := parser.ParseVotersDocumentFromString("* Alice\n# comment\n* Bob\n")
+	var validationErr *gopolls.ParserValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected ParserValidationError, got %v", err)
+	}
+
+	parser = gopolls.NewVotersParser()
+	_, err = parser.ParseVotersDocumentFromString("* Alice\ninvalid\n")
+	var syntaxErr gopolls.PollingSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.LineNum != 2 {
+		t.Errorf("Expected PollingSyntaxError in line 2, got %v", err)
+	}
+
+	parser.DuplicatePolicy = gopolls.RejectDuplicateVoters
+	_, err = parser.ParseVotersDocumentFromString("* Alice\n* Alice: 2\n")
+	var duplicateErr gopolls.DuplicateError
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+}
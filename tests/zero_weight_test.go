@@ -0,0 +1,167 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"errors"
+	"github.com/FabianWe/gopolls"
+	"reflect"
+	"testing"
+)
+
+func TestVotersParserZeroWeightPolicy(t *testing.T) {
+	input := "* Alice: 1\n* Bob: 0\n"
+	parser := gopolls.NewVotersParser()
+	voters, err := parser.ParseVotersFromString(input)
+	if err != nil {
+		t.Fatalf("Unexpected error with default policy: %s", err)
+	}
+	if len(voters) != 2 || !voters[1].HasZeroWeight() {
+		t.Errorf("Expected two voters with Bob having weight 0, got %v", voters)
+	}
+	parser.ZeroWeightPolicy = gopolls.RejectZeroWeightAtParse
+	_, err = parser.ParseVotersFromString(input)
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError, got %v", err)
+	}
+	// a voter with weight 0 but delegated weight is not rejected
+	parser.ParseDelegations = true
+	if _, err = parser.ParseVotersFromString("* Alice: 0 (+Bob: 1)\n"); err != nil {
+		t.Errorf("Unexpected error for voter with delegated weight: %s", err)
+	}
+}
+
+// zeroWeightFillSetup returns a matrix with votes of a voter with weight 2 and a voter with weight 0 for a basic,
+// a median and a schulze poll.
+func zeroWeightFillSetup() (*gopolls.PollMatrix, gopolls.PollMap, gopolls.VoterMap,
+	map[string]gopolls.VoteParser, gopolls.PolicyMap) {
+	voters := gopolls.VoterMap{
+		"one":  gopolls.NewVoter("one", 2),
+		"zero": gopolls.NewVoter("zero", 0),
+	}
+	polls := gopolls.PollMap{
+		"basic":   gopolls.NewBasicPoll(nil),
+		"median":  gopolls.NewMedianPoll(1000, nil),
+		"schulze": gopolls.NewSchulzePoll(2, nil),
+	}
+	parsers := map[string]gopolls.VoteParser{
+		"basic":   gopolls.NewBasicVoteParser(),
+		"median":  gopolls.NewMedianVoteParser(gopolls.NewRawCentCurrencyParser()),
+		"schulze": gopolls.NewSchulzeVoteParser(2),
+	}
+	policies := gopolls.PolicyMap{
+		"basic":   gopolls.IgnoreEmptyVote,
+		"median":  gopolls.IgnoreEmptyVote,
+		"schulze": gopolls.IgnoreEmptyVote,
+	}
+	matrix := &gopolls.PollMatrix{
+		Head: []string{"voter", "basic", "median", "schulze"},
+		Body: [][]string{
+			{"one", "aye", "500", "0, 1"},
+			{"zero", "no", "700", "1, 0"},
+		},
+	}
+	return matrix, polls, voters, parsers, policies
+}
+
+func TestFillPollsWithVotesZeroWeightPolicy(t *testing.T) {
+	// allow: all votes are added
+	matrix, polls, voters, parsers, policies := zeroWeightFillSetup()
+	_, _, report, err := matrix.FillPollsWithVotesWithReport(polls, voters, parsers, policies, false, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(polls["basic"].(*gopolls.BasicPoll).Votes) != 2 || len(polls["median"].(*gopolls.MedianPoll).Votes) != 2 ||
+		len(polls["schulze"].(*gopolls.SchulzePoll).Votes) != 2 {
+		t.Error("Expected two votes in each poll with AllowZeroWeight")
+	}
+	if len(report.ZeroWeightVoters) != 0 {
+		t.Errorf("Expected no zero weight voters in report, got %v", report.ZeroWeightVoters)
+	}
+
+	// reject: an error is returned and no votes are added
+	matrix, polls, voters, parsers, policies = zeroWeightFillSetup()
+	_, _, err = matrix.FillPollsWithVotes(polls, voters, parsers, policies, false, false,
+		gopolls.WithZeroWeightPolicy(gopolls.RejectZeroWeightAtParse))
+	var semanticErr gopolls.PollingSemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Errorf("Expected PollingSemanticError, got %v", err)
+	}
+	if len(polls["basic"].(*gopolls.BasicPoll).Votes) != 0 {
+		t.Error("Expected no votes to be added with RejectZeroWeightAtParse")
+	}
+
+	// exclude: the votes of zero are skipped
+	matrix, polls, voters, parsers, policies = zeroWeightFillSetup()
+	_, _, report, err = matrix.FillPollsWithVotesWithReport(polls, voters, parsers, policies, false, false,
+		gopolls.WithZeroWeightPolicy(gopolls.ExcludeZeroWeightFromCounts))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	basic, median := polls["basic"].(*gopolls.BasicPoll), polls["median"].(*gopolls.MedianPoll)
+	schulze := polls["schulze"].(*gopolls.SchulzePoll)
+	if len(basic.Votes) != 1 || len(median.Votes) != 1 || len(schulze.Votes) != 1 {
+		t.Error("Expected one vote in each poll with ExcludeZeroWeightFromCounts")
+	}
+	if details := median.Tally(gopolls.NoWeight).ValueDetails; len(details[700]) != 0 {
+		t.Errorf("Expected no voter for value 700 in median details, got %v", details[700])
+	}
+	if !reflect.DeepEqual(report.ZeroWeightVoters, []string{"zero"}) {
+		t.Errorf("Expected zero weight voters [zero], got %v", report.ZeroWeightVoters)
+	}
+	for pollName, stats := range report.Polls {
+		if stats.Parsed != 1 || stats.SkippedZeroWeight != 1 {
+			t.Errorf("Expected one parsed and one skipped entry for poll %s, got %+v", pollName, stats)
+		}
+	}
+	if report.CastVotes["zero"] != 3 {
+		t.Errorf("Expected three cast votes for voter zero, got %d", report.CastVotes["zero"])
+	}
+}
+
+func TestBasicPollTallyWithZeroWeightPolicy(t *testing.T) {
+	poll := gopolls.NewBasicPoll([]*gopolls.BasicVote{
+		gopolls.NewBasicVote(gopolls.NewVoter("one", 2), gopolls.Aye),
+		gopolls.NewBasicVote(gopolls.NewVoter("zero", 0), gopolls.No),
+		gopolls.NewBasicVote(gopolls.NewVoter("other", 0), gopolls.No),
+	})
+	for _, policy := range []gopolls.ZeroWeightPolicy{gopolls.AllowZeroWeight, gopolls.RejectZeroWeightAtParse} {
+		res := poll.TallyWithZeroWeightPolicy(policy)
+		if !res.Equals(poll.Tally()) || res.ZeroWeight != nil {
+			t.Errorf("Expected result of Tally for policy %s", policy)
+		}
+	}
+	res := poll.TallyWithZeroWeightPolicy(gopolls.ExcludeZeroWeightFromCounts)
+	if res.VotersCount != 1 || res.VotesSum != 2 || res.NumberVoters.NumNoes != 0 || res.NumberVoters.NumAyes != 1 {
+		t.Errorf("Expected only voter one to be counted, got %+v", res)
+	}
+	if res.ZeroWeight == nil || res.ZeroWeight.NumNoes != 2 {
+		t.Errorf("Expected two noes from zero weight voters, got %+v", res.ZeroWeight)
+	}
+
+	coll := gopolls.NewPollSkeletonCollection("coll")
+	group := gopolls.NewPollGroup("group")
+	group.Skeletons = append(group.Skeletons, gopolls.NewPollSkeleton("basic"))
+	coll.Groups = append(coll.Groups, group)
+	tallied, err := gopolls.TallyCollection(coll, gopolls.PollMap{"basic": poll},
+		gopolls.WithTallyZeroWeightPolicy(gopolls.ExcludeZeroWeightFromCounts))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if talliedRes := tallied[0].Polls[0].Result.(*gopolls.BasicPollResult); !talliedRes.Equals(res) {
+		t.Errorf("Expected TallyCollection to use the zero weight policy, got %+v", talliedRes)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// BasicPollOutcome describes the outcome of a BasicPoll, see BasicPollResult.Outcome.
+type BasicPollOutcome int8
+
+const (
+	Passed BasicPollOutcome = iota
+	Rejected
+	Tied
+	NoVotes
+)
+
+func (outcome BasicPollOutcome) String() string {
+	switch outcome {
+	case Passed:
+		return "Passed"
+	case Rejected:
+		return "Rejected"
+	case Tied:
+		return "Tied"
+	case NoVotes:
+		return "NoVotes"
+	default:
+		return fmt.Sprintf("BasicPollOutcome(%d)", outcome)
+	}
+}
+
+// Outcome returns the outcome of the poll given the weighted votes: Passed if there are more ayes than noes,
+// Rejected if there are more noes than ayes and Tied if both are equal. If the weight of both ayes and noes is 0
+// (for example a poll without any votes or with only abstentions) NoVotes is returned, such a poll is not tied.
+//
+// Abstentions and invalid votes are ignored. If a certain majority is required use PassesMajority instead.
+func (res *BasicPollResult) Outcome() BasicPollOutcome {
+	ayes, noes := res.WeightedVotes.NumAyes, res.WeightedVotes.NumNoes
+	switch {
+	case ayes == 0 && noes == 0:
+		return NoVotes
+	case ayes > noes:
+		return Passed
+	case ayes < noes:
+		return Rejected
+	default:
+		return Tied
+	}
+}
+
+// Tied returns true if the first group in RankedGroups contains more than one option, i.e. there is no unique
+// winner.
+func (result *SchulzeResult) Tied() bool {
+	return len(result.RankedGroups) > 0 && len(result.RankedGroups[0]) > 1
+}
+
+// Tied returns true if there is no unique winner: If the runoff was computed (see ScorePoll.TallyWithRunoff) the
+// result is tied if the runoff has no winner, otherwise if the first group in RankedGroups contains more than one
+// option.
+func (result *ScoreResult) Tied() bool {
+	if result.Runoff != nil {
+		return result.Runoff.Winner < 0
+	}
+	return len(result.RankedGroups) > 0 && len(result.RankedGroups[0]) > 1
+}
+
+// tiedCandidates returns the options that are tied in result (nil if the result is not tied).
+func (result *ScoreResult) tiedCandidates() []int {
+	if !result.Tied() {
+		return nil
+	}
+	if result.Runoff != nil {
+		return []int{result.Runoff.First, result.Runoff.Second}
+	}
+	candidates := make([]int, len(result.RankedGroups[0]))
+	copy(candidates, result.RankedGroups[0])
+	return candidates
+}
+
+// TieBreaker chooses a winner in case of a tie.
+//
+// Choose gets the tied candidates (option indices, at least two of them) and must return one of them.
+// String returns a description of the method, it is stored in TieBreak.Method.
+type TieBreaker interface {
+	Choose(candidates []int) int
+	String() string
+}
+
+// OrderTieBreaker is a deterministic TieBreaker that always chooses the candidate with the smallest index, i.e.
+// the option that appears first in the poll.
+type OrderTieBreaker struct{}
+
+// NewOrderTieBreaker returns a new OrderTieBreaker.
+func NewOrderTieBreaker() OrderTieBreaker {
+	return OrderTieBreaker{}
+}
+
+// Choose returns the smallest candidate.
+func (breaker OrderTieBreaker) Choose(candidates []int) int {
+	res := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate < res {
+			res = candidate
+		}
+	}
+	return res
+}
+
+func (breaker OrderTieBreaker) String() string {
+	return "option order"
+}
+
+// RandomTieBreaker is a TieBreaker that chooses a candidate at random.
+//
+// The random source is seeded with Seed, thus the same sequence of ties always leads to the same winners. This way
+// a result can be reproduced given the seed (for example for an audit).
+// The candidates are sorted before choosing, so their order doesn't matter.
+//
+// A RandomTieBreaker is not safe for concurrent use.
+type RandomTieBreaker struct {
+	Seed int64
+	rnd  *rand.Rand
+}
+
+// NewRandomTieBreaker returns a new RandomTieBreaker seeded with seed.
+func NewRandomTieBreaker(seed int64) *RandomTieBreaker {
+	return &RandomTieBreaker{
+		Seed: seed,
+		rnd:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Choose returns a random candidate.
+func (breaker *RandomTieBreaker) Choose(candidates []int) int {
+	sorted := make([]int, len(candidates))
+	copy(sorted, candidates)
+	sort.Ints(sorted)
+	return sorted[breaker.rnd.Intn(len(sorted))]
+}
+
+func (breaker *RandomTieBreaker) String() string {
+	return fmt.Sprintf("random (seed %d)", breaker.Seed)
+}
+
+// TieBreak records how a tie was broken.
+//
+// Candidates are the tied options and Winner the option chosen by the TieBreaker, Method is the description of the
+// TieBreaker (see TieBreaker.String).
+// For a BasicPoll the candidates are the answers No and Aye (as int), for a SchulzePoll the option indices of the
+// first group of RankedGroups. For a ScorePoll the candidates are the two options of the runoff or (without a
+// runoff) the option indices of the first group of RankedGroups.
+type TieBreak struct {
+	Candidates []int
+	Winner     int
+	Method     string
+}
+
+// BreakTie applies breaker if result is tied and returns the tie break, if there is no tie nil is returned.
+//
+// Ties are detected for *BasicPollResult (see Outcome, NoVotes is not a tie), *SchulzeResult and *ScoreResult (see
+// Tied). A MedianResult is never tied because the highest value that has a majority is chosen, nil is returned in
+// this case.
+func BreakTie(result AbstractPollResult, breaker TieBreaker) *TieBreak {
+	var candidates []int
+	switch typedResult := result.(type) {
+	case *BasicPollResult:
+		if typedResult.Outcome() == Tied {
+			candidates = []int{int(No), int(Aye)}
+		}
+	case *SchulzeResult:
+		if typedResult.Tied() {
+			candidates = make([]int, len(typedResult.RankedGroups[0]))
+			copy(candidates, typedResult.RankedGroups[0])
+		}
+	case *ScoreResult:
+		candidates = typedResult.tiedCandidates()
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return &TieBreak{
+		Candidates: candidates,
+		Winner:     breaker.Choose(candidates),
+		Method:     breaker.String(),
+	}
+}
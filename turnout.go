@@ -0,0 +1,267 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// PollTurnout describes how many voters voted for a single poll, see ComputeTurnout.
+//
+// NumVotes is the number of votes in the poll and VotesWeight the sum of the (effective) weights of the voters of
+// these votes. Percentage is VotesWeight as a fraction of the eligible weight (see ComputePercentage).
+//
+// SupportsAbstention is true if the votes of the poll can describe an abstention (true for all polls implemented in
+// this package), in this case NumAbstentions and AbstentionWeight describe the votes that are abstentions. An
+// abstention is a BasicVote with the choice Abstention, a MedianVote with Abstain set to true and Schulze and score
+// votes that rank all options equally (see SchulzeRanking.IsAbstention and ScoreVote.IsAbstention).
+type PollTurnout struct {
+	Poll               string
+	NumVotes           int
+	VotesWeight        Weight
+	Percentage         *big.Rat
+	SupportsAbstention bool
+	NumAbstentions     int
+	AbstentionWeight   Weight
+}
+
+// Turnout contains the turnout of each poll and an overall summary, it is returned by ComputeTurnout.
+//
+// Polls contains the turnout of each poll, sorted by poll name. NumEligible and EligibleWeight describe all voters
+// that were allowed to vote. NumVotedAny and WeightVotedAny describe the voters that voted for at least one poll,
+// NumVotedAll and WeightVotedAll the voters that voted for all polls.
+//
+// The String method returns a human-readable summary (one line per poll) and the type can be encoded as JSON, so the
+// turnout can be included in a protocol or an API response.
+type Turnout struct {
+	Polls          []PollTurnout
+	NumEligible    int
+	EligibleWeight Weight
+	NumVotedAny    int
+	WeightVotedAny Weight
+	NumVotedAll    int
+	WeightVotedAll Weight
+}
+
+// ComputeTurnout computes the turnout for each poll in polls given the voters that were allowed to vote.
+//
+// All weights are the effective weights of the voters (including delegations). Each vote in a poll is counted, also
+// votes that were generated by an EmptyVotePolicy, empty entries that were ignored don't appear in the poll and are
+// thus not counted.
+//
+// Each poll must implement VoteIterator (see CollectVotes), otherwise a PollTypeError is returned. If a poll contains
+// a vote of a voter that is not in voters a PollingSemanticError is returned. Weight overflows are reported with an
+// error wrapping ErrWeightOverflow.
+func ComputeTurnout(polls PollMap, voters VoterMap) (*Turnout, error) {
+	res := &Turnout{
+		Polls:          make([]PollTurnout, 0, len(polls)),
+		NumEligible:    len(voters),
+		EligibleWeight: 0,
+		NumVotedAny:    0,
+		WeightVotedAny: 0,
+		NumVotedAll:    0,
+		WeightVotedAll: 0,
+	}
+	var err error
+	for _, name := range voters.SortedNames() {
+		if res.EligibleWeight, err = addVoterWeight(res.EligibleWeight, voters[name]); err != nil {
+			return nil, err
+		}
+	}
+	// maps each voter name to the number of polls the voter voted for
+	numPolls := make(map[string]int, len(voters))
+	for _, name := range polls.SortedNames() {
+		poll := polls[name]
+		votes, votesErr := CollectVotes(poll)
+		if votesErr != nil {
+			return nil, votesErr
+		}
+		pollTurnout := PollTurnout{
+			Poll:               name,
+			NumVotes:           len(votes),
+			VotesWeight:        0,
+			Percentage:         nil,
+			SupportsAbstention: supportsAbstention(poll),
+			NumAbstentions:     0,
+			AbstentionWeight:   0,
+		}
+		// a voter with multiple votes in a poll is only counted once for the summary
+		votedInPoll := make(map[string]struct{}, len(votes))
+		for _, vote := range votes {
+			voter := vote.GetVoter()
+			if _, eligible := voters[voter.Name]; !eligible {
+				return nil, NewPollingSemanticError(nil, "voter \"%s\" in poll \"%s\" is not eligible",
+					voter.Name, name)
+			}
+			if pollTurnout.VotesWeight, err = addVoterWeight(pollTurnout.VotesWeight, voter); err != nil {
+				return nil, err
+			}
+			if isAbstentionVote(vote) {
+				pollTurnout.NumAbstentions++
+				if pollTurnout.AbstentionWeight, err = addVoterWeight(pollTurnout.AbstentionWeight, voter); err != nil {
+					return nil, err
+				}
+			}
+			if _, has := votedInPoll[voter.Name]; !has {
+				votedInPoll[voter.Name] = struct{}{}
+				numPolls[voter.Name]++
+			}
+		}
+		pollTurnout.Percentage = ComputePercentage(pollTurnout.VotesWeight, res.EligibleWeight)
+		res.Polls = append(res.Polls, pollTurnout)
+	}
+	for _, name := range voters.SortedNames() {
+		voted := numPolls[name]
+		if voted == 0 {
+			continue
+		}
+		res.NumVotedAny++
+		if res.WeightVotedAny, err = addVoterWeight(res.WeightVotedAny, voters[name]); err != nil {
+			return nil, err
+		}
+		if voted == len(polls) {
+			res.NumVotedAll++
+			if res.WeightVotedAll, err = addVoterWeight(res.WeightVotedAll, voters[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// addVoterWeight adds the effective weight of voter to sum, it returns an error wrapping ErrWeightOverflow if the sum
+// overflows.
+func addVoterWeight(sum Weight, voter *Voter) (Weight, error) {
+	weight, weightErr := voter.CheckedEffectiveWeight()
+	if weightErr != nil {
+		return NoWeight, weightErr
+	}
+	return AddWeight(sum, weight)
+}
+
+// supportsAbstention returns true if poll is one of the polls implemented in this package (also if it is wrapped in
+// a SynchronizedPoll).
+func supportsAbstention(poll AbstractPoll) bool {
+	if syncPoll, isSync := poll.(SynchronizedPoll); isSync {
+		poll = syncPoll.Unwrap()
+	}
+	switch poll.(type) {
+	case *BasicPoll, *MedianPoll, *SchulzePoll, *ScorePoll:
+		return true
+	default:
+		return false
+	}
+}
+
+// isAbstentionVote returns true if vote is a vote from this package that describes an abstention, see PollTurnout.
+func isAbstentionVote(vote AbstractVote) bool {
+	switch typedVote := vote.(type) {
+	case *BasicVote:
+		return typedVote.Choice == Abstention
+	case *MedianVote:
+		return typedVote.Abstain
+	case *SchulzeVote:
+		return typedVote.Ranking.IsAbstention()
+	case *ScoreVote:
+		return typedVote.IsAbstention()
+	default:
+		return false
+	}
+}
+
+// PercentVotedAny returns WeightVotedAny as a fraction of EligibleWeight, see ComputePercentage.
+func (turnout *Turnout) PercentVotedAny() *big.Rat {
+	return ComputePercentage(turnout.WeightVotedAny, turnout.EligibleWeight)
+}
+
+// PercentVotedAll returns WeightVotedAll as a fraction of EligibleWeight, see ComputePercentage.
+func (turnout *Turnout) PercentVotedAll() *big.Rat {
+	return ComputePercentage(turnout.WeightVotedAll, turnout.EligibleWeight)
+}
+
+// String returns a summary of the turnout: The eligible voters, the voters that voted for at least one / all polls
+// and one line for each poll (sorted by name), for example
+// "Budget: 7 votes, weight 18 (72.000 % of eligible weight), 1 abstentions (weight 2)".
+func (turnout *Turnout) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Eligible voters: %d (weight %d)\n", turnout.NumEligible, turnout.EligibleWeight)
+	fmt.Fprintf(&b, "Voted in at least one poll: %d (weight %d, %s %%)\n", turnout.NumVotedAny,
+		turnout.WeightVotedAny, FormatPercentage(turnout.PercentVotedAny()))
+	fmt.Fprintf(&b, "Voted in all polls: %d (weight %d, %s %%)\n", turnout.NumVotedAll, turnout.WeightVotedAll,
+		FormatPercentage(turnout.PercentVotedAll()))
+	for _, poll := range turnout.Polls {
+		fmt.Fprintf(&b, "%s: %d votes, weight %d (%s %% of eligible weight)", poll.Poll, poll.NumVotes,
+			poll.VotesWeight, FormatPercentage(poll.Percentage))
+		if poll.SupportsAbstention {
+			fmt.Fprintf(&b, ", %d abstentions (weight %d)", poll.NumAbstentions, poll.AbstentionWeight)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+type pollTurnoutJSON struct {
+	Poll             string  `json:"poll"`
+	NumVotes         int     `json:"num_votes"`
+	VotesWeight      Weight  `json:"votes_weight"`
+	Percentage       string  `json:"percentage"`
+	NumAbstentions   *int    `json:"num_abstentions,omitempty"`
+	AbstentionWeight *Weight `json:"abstention_weight,omitempty"`
+}
+
+type turnoutJSON struct {
+	Polls          []pollTurnoutJSON `json:"polls"`
+	NumEligible    int               `json:"num_eligible"`
+	EligibleWeight Weight            `json:"eligible_weight"`
+	NumVotedAny    int               `json:"num_voted_any"`
+	WeightVotedAny Weight            `json:"weight_voted_any"`
+	NumVotedAll    int               `json:"num_voted_all"`
+	WeightVotedAll Weight            `json:"weight_voted_all"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// The turnout is encoded as an object with the keys "polls", "num_eligible", "eligible_weight", "num_voted_any",
+// "weight_voted_any", "num_voted_all" and "weight_voted_all". Each poll is encoded as an object with the keys
+// "poll", "num_votes", "votes_weight" and "percentage" (formatted with FormatPercentage), if the poll supports
+// abstentions "num_abstentions" and "abstention_weight" are included.
+func (turnout *Turnout) MarshalJSON() ([]byte, error) {
+	polls := make([]pollTurnoutJSON, len(turnout.Polls))
+	for i, poll := range turnout.Polls {
+		pollJSON := pollTurnoutJSON{
+			Poll:        poll.Poll,
+			NumVotes:    poll.NumVotes,
+			VotesWeight: poll.VotesWeight,
+			Percentage:  FormatPercentage(poll.Percentage),
+		}
+		if poll.SupportsAbstention {
+			numAbstentions, abstentionWeight := poll.NumAbstentions, poll.AbstentionWeight
+			pollJSON.NumAbstentions, pollJSON.AbstentionWeight = &numAbstentions, &abstentionWeight
+		}
+		polls[i] = pollJSON
+	}
+	return json.Marshal(turnoutJSON{
+		Polls:          polls,
+		NumEligible:    turnout.NumEligible,
+		EligibleWeight: turnout.EligibleWeight,
+		NumVotedAny:    turnout.NumVotedAny,
+		WeightVotedAny: turnout.WeightVotedAny,
+		NumVotedAll:    turnout.NumVotedAll,
+		WeightVotedAll: turnout.WeightVotedAll,
+	})
+}
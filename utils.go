@@ -15,6 +15,7 @@
 package gopolls
 
 import (
+	"fmt"
 	"math"
 	"strconv"
 	"strings"
@@ -44,6 +45,19 @@ func ParseWeight(s string) (Weight, error) {
 	return res, nil
 }
 
+// ErrWeightOverflow is returned (wrapped) if a sum of weights can't be represented as a Weight, i.e. the sum
+// is >= NoWeight.
+var ErrWeightOverflow = NewPollingSemanticError(nil, "weight overflow")
+
+// AddWeight returns a + b, if the sum is >= NoWeight an error wrapping ErrWeightOverflow is returned.
+func AddWeight(a, b Weight) (Weight, error) {
+	sum := uint64(a) + uint64(b)
+	if sum >= uint64(NoWeight) {
+		return NoWeight, fmt.Errorf("can't compute %d + %d: %w", a, b, ErrWeightOverflow)
+	}
+	return Weight(sum), nil
+}
+
 // WeightMin returns the minimum of a and b.
 func WeightMin(a, b Weight) Weight {
 	if a < b {
@@ -79,6 +93,24 @@ func (err DuplicateError) Error() string {
 	return err.Msg
 }
 
+// VoteNotFoundError is returned if a vote of a certain voter is required but the voter has no vote, see
+// VoteEditor.RemoveVote.
+type VoteNotFoundError struct {
+	PollError
+	VoterName string
+}
+
+// NewVoteNotFoundError returns a new VoteNotFoundError.
+func NewVoteNotFoundError(voterName string) VoteNotFoundError {
+	return VoteNotFoundError{
+		VoterName: voterName,
+	}
+}
+
+func (err VoteNotFoundError) Error() string {
+	return fmt.Sprintf("no vote of voter \"%s\" found", err.VoterName)
+}
+
 // LowerStringSet is a set of lower case strings.
 type LowerStringSet map[string]struct{}
 
@@ -15,11 +15,20 @@
 package gopolls
 
 import (
+	"bufio"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"unicode/utf8"
 )
 
@@ -35,6 +44,7 @@ const (
 	BasicVoteType   = "basic-vote"
 	MedianVoteType  = "median-vote"
 	SchulzeVoteType = "schulze-vote"
+	ScoreVoteType   = "score-vote"
 )
 
 // VoteParser parses a vote from a string.
@@ -66,17 +76,22 @@ type ParserCustomizer interface {
 	CustomizeForPoll(poll AbstractPoll) (ParserCustomizer, error)
 }
 
-// DefaultParserTemplateMap contains default templates for BasicPollType, MedianPollType and SchulzePollType.
+// DefaultParserTemplateMap contains default templates for BasicPollType, MedianPollType, SchulzePollType and
+// ScorePollType.
 // Of course it can be extended.
 // The easiest way to extend the default parsers is use to either insert values directly here or, if you don't want
 // that, generate a fresh map with GenerateDefaultParserTemplateMap.
+//
+// Changing this map affects all packages that use it, libraries should use their own ParserRegistry instead (see
+// DefaultRegistry).
 var DefaultParserTemplateMap = GenerateDefaultParserTemplateMap()
 
 func GenerateDefaultParserTemplateMap() map[string]ParserCustomizer {
-	res := make(map[string]ParserCustomizer, 3)
+	res := make(map[string]ParserCustomizer, 4)
 	res[BasicPollType] = NewBasicVoteParser()
 	res[MedianPollType] = NewMedianVoteParser(DefaultCurrencyHandler)
 	res[SchulzePollType] = NewSchulzeVoteParser(-1)
+	res[ScorePollType] = NewScoreVoteParser(-1)
 	return res
 }
 
@@ -90,6 +105,9 @@ func GenerateDefaultParserTemplateMap() map[string]ParserCustomizer {
 // that works as the template for all BasicPolls.
 //
 // DefaultParserTemplateMap contains some default templates for BasicPollType, MedianPollType and SchulzePollType.
+// The same templates can be managed in a ParserRegistry. This function only accepts a plain map, registries go
+// through the method ParserRegistry.Customize instead (which does the same for the templates in the registry), or
+// ParserRegistry.Map can be used to get a map.
 //
 // Of course there are other ways to do the conversion, but this is a nice helper function.
 //
@@ -98,47 +116,91 @@ func GenerateDefaultParserTemplateMap() map[string]ParserCustomizer {
 //
 // CustomizeParsersToMap is a function that has the same functionality but for maps.
 func CustomizeParsers(polls []AbstractPoll, templates map[string]ParserCustomizer) ([]ParserCustomizer, error) {
-	res := make([]ParserCustomizer, len(polls))
-	for i, poll := range polls {
-		// get the parserTemplate
-		parserTemplate, hasTemplate := templates[poll.PollType()]
-		if !hasTemplate {
-			return nil,
-				NewPollTypeError("no matching parserTemplate for type %s (name %s) found",
-					reflect.TypeOf(poll), poll.PollType())
-		}
-		// try to customize
-		customized, customizeErr := parserTemplate.CustomizeForPoll(poll)
-		if customizeErr != nil {
-			return nil, customizeErr
-		}
-		res[i] = customized
-	}
-	return res, nil
+	return parserRegistryFromMap(templates).Customize(polls)
 }
 
 // CustomizeParsersToMap customizes parser templates for each poll.
 //
 // For details see CustomizeParsers.
 // This function will return one entry in the result map for each poll in polls.
+// The polls are processed sorted by name, thus if there are multiple errors always the same one is returned.
+//
+// CustomizeParsersToMapWithOptions can skip polls without a template instead of returning an error.
+// As CustomizeParsers it only accepts a plain map, for a ParserRegistry use ParserRegistry.CustomizeAll.
 func CustomizeParsersToMap(polls PollMap, templates map[string]ParserCustomizer) (map[string]ParserCustomizer, error) {
-	res := make(map[string]ParserCustomizer, len(polls))
-	for name, poll := range polls {
-		// get the parserTemplate
-		parserTemplate, hasTemplate := templates[poll.PollType()]
-		if !hasTemplate {
-			return nil,
-				NewPollTypeError("no matching parser parserTemplate for type %s (name %s) found",
-					reflect.TypeOf(poll), poll.PollType())
-		}
-		// try to customize
-		customized, customizeErr := parserTemplate.CustomizeForPoll(poll)
-		if customizeErr != nil {
-			return nil, customizeErr
-		}
-		res[name] = customized
+	res, _, err := CustomizeParsersToMapWithOptions(polls, templates, CustomizeOptions{})
+	return res, err
+}
+
+// CustomizeOptions are the options for CustomizeParsersToMapWithOptions.
+//
+// If SkipMissingTemplates is true polls without a template in templates are skipped instead of returning an error.
+// If SkipCustomizeErrors is true polls for which CustomizeForPoll returns an error are skipped as well.
+// Both are false by default, in this case CustomizeParsersToMapWithOptions works as CustomizeParsersToMap.
+type CustomizeOptions struct {
+	SkipMissingTemplates bool
+	SkipCustomizeErrors  bool
+}
+
+// SkippedPoll describes a poll that was skipped by CustomizeParsersToMapWithOptions.
+//
+// Err is the reason, a PollTypeError if there is no template for the poll or the error returned by CustomizeForPoll.
+type SkippedPoll struct {
+	Name string
+	Err  error
+}
+
+func (skipped SkippedPoll) String() string {
+	return fmt.Sprintf("%s: %s", skipped.Name, skipped.Err)
+}
+
+// CustomizeParsersToMapWithOptions works as CustomizeParsersToMap, but polls can be skipped (see CustomizeOptions).
+//
+// The result map contains an entry for each poll that was not skipped, the skipped polls are returned (sorted by
+// name) together with the reason. Polls that are skipped this way can be ignored when the votes are parsed, see
+// WithSkipPollsWithoutParser.
+// If opts doesn't allow to skip a poll the error is returned (together with nil for the map and the skipped polls).
+// For a ParserRegistry use ParserRegistry.CustomizeAllWithOptions.
+func CustomizeParsersToMapWithOptions(polls PollMap, templates map[string]ParserCustomizer,
+	opts CustomizeOptions) (map[string]ParserCustomizer, []SkippedPoll, error) {
+	return parserRegistryFromMap(templates).CustomizeAllWithOptions(polls, opts)
+}
+
+// VoteFormatter formats a vote as a string, it is the inverse of a VoteParser: The returned string should be parsed
+// by the matching parser (with the same configuration) to an equal vote.
+//
+// This is useful to write existing votes back to a file, for example a CSV file that is then edited.
+// If the vote is of the wrong type a PollTypeError should be returned.
+type VoteFormatter interface {
+	FormatVote(vote AbstractVote) (string, error)
+}
+
+// DefaultVoteFormatterMap contains default formatters for BasicVoteType, MedianVoteType, SchulzeVoteType and
+// ScoreVoteType, the strings returned by these formatters can be parsed by the parsers in DefaultParserTemplateMap.
+//
+// As DefaultParserTemplateMap it can be extended, or a fresh map can be created with GenerateDefaultVoteFormatterMap.
+var DefaultVoteFormatterMap = GenerateDefaultVoteFormatterMap()
+
+func GenerateDefaultVoteFormatterMap() map[string]VoteFormatter {
+	res := make(map[string]VoteFormatter, 4)
+	res[BasicVoteType] = NewBasicVoteFormatter()
+	res[MedianVoteType] = NewMedianVoteFormatter(DefaultCurrencyHandler)
+	res[SchulzeVoteType] = NewSchulzeVoteFormatter()
+	res[ScoreVoteType] = NewScoreVoteFormatter()
+	return res
+}
+
+// FormatVote formats a vote with the formatter from formatters that is registered for the type of the vote (see
+// AbstractVote.VoteType).
+//
+// It returns a PollTypeError if no formatter is found and returns any error from the formatter.
+func FormatVote(vote AbstractVote, formatters map[string]VoteFormatter) (string, error) {
+	formatter, hasFormatter := formatters[vote.VoteType()]
+	if !hasFormatter {
+		return "", NewPollTypeError("no matching formatter for type %s (vote type %s) found",
+			reflect.TypeOf(vote), vote.VoteType())
 	}
-	return res, nil
+	return formatter.FormatVote(vote)
 }
 
 // CSV //
@@ -147,54 +209,246 @@ const DefaultCSVSeparator = ','
 
 // VotesCSVWriter can be used to create a CSV file template for inserting polls in it.
 // Refer to the wiki for details about CSV files.
+//
+// The file can be written at once (GenerateEmptyTemplate and GenerateTemplateWithVotes) or row by row with
+// WriteHead, WriteVoterRow and Flush. The latter can be used to stream big files directly to the destination, for
+// example a http.ResponseWriter.
 type VotesCSVWriter struct {
 	Sep rune
 	csv *csv.Writer
+	// numPolls is the number of polls in the head, -1 if no head was written
+	numPolls int
+	// row is the number of rows written so far (including the head)
+	row int
 }
 
 // NewVotesCSVWriter returns a new VotesCSVWriter writing to w.
 func NewVotesCSVWriter(w io.Writer) *VotesCSVWriter {
 	writer := csv.NewWriter(w)
 	return &VotesCSVWriter{
-		Sep: DefaultCSVSeparator,
-		csv: writer,
+		Sep:      DefaultCSVSeparator,
+		csv:      writer,
+		numPolls: -1,
+		row:      0,
 	}
 }
 
-func (w *VotesCSVWriter) writeCSVHead(skels []AbstractPollSkeleton) error {
+// WriteHead writes the head of the CSV file, it must be called before any call to WriteVoterRow.
+//
+// Errors from writing are wrapped and can be inspected with errors.Is / errors.As.
+func (w *VotesCSVWriter) WriteHead(skels []AbstractPollSkeleton) error {
+	w.csv.Comma = w.Sep
 	row := make([]string, len(skels)+1)
 	row[0] = "voter"
 	for i, skel := range skels {
 		row[i+1] = skel.GetName()
 	}
-	return w.csv.Write(row)
+	if err := w.csv.Write(row); err != nil {
+		return fmt.Errorf("error writing csv head: %w", err)
+	}
+	w.numPolls = len(skels)
+	w.row = 1
+	return nil
 }
 
-func (w *VotesCSVWriter) writeEmptyRecords(voters []*Voter, skels []AbstractPollSkeleton) error {
-	// row will be re-used
-	row := make([]string, len(skels)+1)
-	for _, voter := range voters {
-		row[0] = voter.Name
-		if err := w.csv.Write(row); err != nil {
-			return err
-		}
+// WriteVoterRow writes the row of a single voter, values are the entries for the polls (in the same order as the
+// skeletons given to WriteHead).
+//
+// If the head hasn't been written or the number of values doesn't match the number of polls a PollingSemanticError
+// is returned.
+// Errors from writing are wrapped and mention the voter and the row number (the head is row 1), they can be
+// inspected with errors.Is / errors.As.
+// Note that the output is buffered, thus an error might also be caused by one of the previous rows. Flush must be
+// called after the last row.
+func (w *VotesCSVWriter) WriteVoterRow(voter *Voter, values []string) error {
+	if w.numPolls < 0 {
+		return NewPollingSemanticError(nil, "csv head must be written before the row of voter \"%s\"", voter.Name)
+	}
+	if len(values) != w.numPolls {
+		return NewPollingSemanticError(nil, "row of voter \"%s\" has %d values, expected %d", voter.Name,
+			len(values), w.numPolls)
+	}
+	row := make([]string, 0, len(values)+1)
+	row = append(row, voter.Name)
+	row = append(row, values...)
+	if err := w.csv.Write(row); err != nil {
+		return fmt.Errorf("error writing csv row %d (voter \"%s\"): %w", w.row+1, voter.Name, err)
+	}
+	w.row++
+	return nil
+}
+
+// Flush writes all buffered rows to the underlying writer.
+//
+// Errors from writing are wrapped and can be inspected with errors.Is / errors.As.
+func (w *VotesCSVWriter) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return fmt.Errorf("error writing csv rows (%d rows written before the error): %w", w.row, err)
 	}
 	return nil
 }
 
 // GenerateEmptyTemplate generates an empty CSV template (contains all polls and voters, but no votes).
 //
-// It returns any errors from writing to w.
+// It is implemented with WriteHead, WriteVoterRow and Flush, see there for the errors returned.
 func (w *VotesCSVWriter) GenerateEmptyTemplate(voters []*Voter, skels []AbstractPollSkeleton) error {
-	w.csv.Comma = w.Sep
-	if err := w.writeCSVHead(skels); err != nil {
+	if err := w.WriteHead(skels); err != nil {
 		return err
 	}
-	if err := w.writeEmptyRecords(voters, skels); err != nil {
+	// values will be re-used
+	values := make([]string, len(skels))
+	for _, voter := range voters {
+		if err := w.WriteVoterRow(voter, values); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// validateExistingVotes returns a PollingSemanticError listing all voters and polls from existing that are not in
+// voters / skels (sorted by name).
+func validateExistingVotes(voters []*Voter, skels []AbstractPollSkeleton, existing map[string]map[string]string) error {
+	voterNames := make(map[string]struct{}, len(voters))
+	for _, voter := range voters {
+		voterNames[voter.Name] = struct{}{}
+	}
+	pollNames := make(map[string]struct{}, len(skels))
+	for _, skel := range skels {
+		pollNames[skel.GetName()] = struct{}{}
+	}
+	var unknownVoters []string
+	unknownPollsSet := make(map[string]struct{})
+	for voterName, votes := range existing {
+		if _, has := voterNames[voterName]; !has {
+			unknownVoters = append(unknownVoters, voterName)
+		}
+		for pollName := range votes {
+			if _, has := pollNames[pollName]; !has {
+				unknownPollsSet[pollName] = struct{}{}
+			}
+		}
+	}
+	if len(unknownVoters) == 0 && len(unknownPollsSet) == 0 {
+		return nil
+	}
+	unknownPolls := make([]string, 0, len(unknownPollsSet))
+	for pollName := range unknownPollsSet {
+		unknownPolls = append(unknownPolls, pollName)
+	}
+	sort.Strings(unknownVoters)
+	sort.Strings(unknownPolls)
+	var problems []string
+	if len(unknownVoters) > 0 {
+		problems = append(problems, "unknown voters: "+strings.Join(unknownVoters, ", "))
+	}
+	if len(unknownPolls) > 0 {
+		problems = append(problems, "unknown polls: "+strings.Join(unknownPolls, ", "))
+	}
+	return NewPollingSemanticError(nil, "invalid existing votes, %s", strings.Join(problems, "; "))
+}
+
+// GenerateTemplateWithVotes works as GenerateEmptyTemplate but fills in the votes that are already known.
+//
+// existing maps the voter name to a map that maps the poll name to the vote string, these strings are written to
+// the matching cells, all other cells are empty. The columns have the same order as skels, the rows the same order
+// as voters.
+//
+// If existing contains a voter or poll not contained in voters / skels a PollingSemanticError listing all of them is
+// returned (before anything is written).
+// It also returns any errors from writing to w.
+func (w *VotesCSVWriter) GenerateTemplateWithVotes(voters []*Voter, skels []AbstractPollSkeleton,
+	existing map[string]map[string]string) error {
+	if validateErr := validateExistingVotes(voters, skels, existing); validateErr != nil {
+		return validateErr
+	}
+	if err := w.WriteHead(skels); err != nil {
 		return err
 	}
-	w.csv.Flush()
-	return w.csv.Error()
+	values := make([]string, len(skels))
+	for _, voter := range voters {
+		votes := existing[voter.Name]
+		for i, skel := range skels {
+			values[i] = votes[skel.GetName()]
+		}
+		if err := w.WriteVoterRow(voter, values); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// CSVReadMode describes how a VotesCSVReader handles malformed rows.
+//
+// In StrictCSVMode reading stops on the first malformed row, in LenientCSVMode all malformed rows are collected in a
+// CSVReadReport and reading continues.
+type CSVReadMode int8
+
+const (
+	StrictCSVMode CSVReadMode = iota
+	LenientCSVMode
+)
+
+func (mode CSVReadMode) String() string {
+	switch mode {
+	case StrictCSVMode:
+		return "StrictCSVMode"
+	case LenientCSVMode:
+		return "LenientCSVMode"
+	default:
+		return fmt.Sprintf("CSVReadMode(%d)", mode)
+	}
+}
+
+// CSVRowError describes a malformed row found in LenientCSVMode.
+//
+// Row is the row in the csv file (the head is row 1) and Line the physical line on which the row starts, they differ
+// if the file contains cells with line breaks (see VotesCSVReader.AllowMultilineCells). NumColumns is the number of
+// columns found in the row (-1 if the row could not be parsed at all) and ExpectedColumns the number of columns in the
+// head.
+// Msg is the error message of the csv parser.
+type CSVRowError struct {
+	Row             int
+	Line            int
+	NumColumns      int
+	ExpectedColumns int
+	Msg             string
+}
+
+func (rowErr CSVRowError) String() string {
+	if rowErr.NumColumns < 0 {
+		return fmt.Sprintf("row %d (line %d): %s", rowErr.Row, rowErr.Line, rowErr.Msg)
+	}
+	return fmt.Sprintf("row %d (line %d) has %d columns, expected %d: %s",
+		rowErr.Row, rowErr.Line, rowErr.NumColumns, rowErr.ExpectedColumns, rowErr.Msg)
+}
+
+// CSVReadReport contains all malformed rows found by VotesCSVReader.ReadRecordsWithReport, sorted by row.
+type CSVReadReport struct {
+	RowErrors []CSVRowError
+}
+
+// Empty returns true if no malformed rows were found.
+func (report *CSVReadReport) Empty() bool {
+	return len(report.RowErrors) == 0
+}
+
+func (report *CSVReadReport) add(rowErr CSVRowError) {
+	report.RowErrors = append(report.RowErrors, rowErr)
+}
+
+// AsError returns nil if the report is empty and a PollingSyntaxError containing all malformed rows otherwise.
+// The line number of the error is the line on which the first malformed row starts.
+func (report *CSVReadReport) AsError() error {
+	if report.Empty() {
+		return nil
+	}
+	messages := make([]string, len(report.RowErrors))
+	for i, rowErr := range report.RowErrors {
+		messages[i] = rowErr.String()
+	}
+	return NewPollingSyntaxError(nil, "malformed rows in csv file: %s",
+		strings.Join(messages, "; ")).WithLineNum(report.RowErrors[0].Line)
 }
 
 // VotesCSVReader can be used to parse a CSV file of votes (see wiki for details about CSV files).
@@ -210,68 +464,252 @@ func (w *VotesCSVWriter) GenerateEmptyTemplate(voters []*Voter, skels []Abstract
 //
 // The following restrictions can be configured:
 // MaxNumLines is the number of lines that are allowed in a polls file (including head). Therefor it must be a number >= 1.
+// Lines are always physical lines of the input, a row with a cell that contains line breaks counts as multiple lines.
 // MaxRecordLength is th maximal length in bytes (not runes) a record in a row is allowed to have.
 // MaxVotersNameLength is the maximal length a voter name is allowed to have.
 // MaxPollNameLength is the maximal length a poll name is allowed to have.
+// MaxTotalBytes is the maximal number of bytes read from the input.
+// ComputeDefaultMaxRecordLength sets MaxRecordLength depending on the other limits.
+//
+// Mode controls how malformed rows are handled, see CSVReadMode. NewVotesCSVReader sets it to StrictCSVMode.
+// Violated restrictions always abort reading, also in LenientCSVMode.
+//
+// Progress and ProgressInterval work as in VotersParser, the head counts as the first line.
+//
+// NameNormalizer is applied to the voter name of each row before it is validated, it is nil by default. Use the same
+// normalizer as for the voters (see VotersParser) so that the names can be found.
+//
+// AllowMultilineCells describes if a quoted cell may contain line breaks (as allowed by the csv format), it defaults
+// to true. Spreadsheet software sometimes writes such cells by accident, if it is false a cell that contains a line
+// break is reported with a PollingSyntaxError naming the row (or as a malformed row in LenientCSVMode).
+// All line numbers in errors refer to physical lines of the input, for a row that spans multiple lines it is the line
+// on which the row starts.
+//
+// SourceName is the name of the input as in VotersParser, it is included in all errors returned by ReadRecords and
+// ReadRecordsWithReport. ReadMatrixFromCSVFile sets it to the name of the file.
 type VotesCSVReader struct {
 	Sep                 rune
+	Mode                CSVReadMode
 	csv                 *csv.Reader
+	limited             *maxBytesReader
+	lines               *lineCountingReader
 	MaxNumLines         int
 	MaxVotersNameLength int
 	MaxPollNameLength   int
 	MaxRecordLength     int
+	MaxTotalBytes       int
+	Progress            ProgressFunc
+	ProgressInterval    int
+	NameNormalizer      *NameNormalizer
+	AllowMultilineCells bool
+	SourceName          string
 }
 
-// wrapError wraps an error that occurred during reading, if it is a CSV parse error it returns a PollingSyntaxError.
+// VoteLengthHints describe the votes expected in a csv file, they're used by
+// VotesCSVReader.ComputeDefaultMaxRecordLength.
+//
+// MaxNumOptions is the maximal number of options of Schulze and score polls, MaxOptionLength the maximal length of
+// an option (only required if rankings can be given by option names, see SchulzeVoteParser.Options) and
+// MaxCurrencyValue the maximal value of median polls (in cents).
+// If a value is -1 votes of this kind are not expected, basic votes are always expected.
+//
+// PollCollectionParser.VoteLengthHints returns the hints for the limits of a collection parser.
+type VoteLengthHints struct {
+	MaxNumOptions    int
+	MaxOptionLength  int
+	MaxCurrencyValue int
+}
+
+// maxBasicVoteLength is the length of the longest string accepted by the default BasicVoteParser ("abstention").
+const maxBasicVoteLength = 10
+
+// maxVoteLength returns the length of the longest vote string described by the hints.
+//
+// Rankings and scores are separated by ", " (rankings with option names by " > "), the values of a ranking are
+// assumed to be between 0 and MaxNumOptions - 1 and scores have at most three digits (see ScorePoll.MaxScore).
+func (hints VoteLengthHints) maxVoteLength() int {
+	res := maxBasicVoteLength
+	if n := hints.MaxNumOptions; n > 0 {
+		rankingLength := n*len(strconv.Itoa(n-1)) + 2*(n-1)
+		scoresLength := 3*n + 2*(n-1)
+		res = maxLength(res, rankingLength, scoresLength)
+		if hints.MaxOptionLength >= 0 {
+			res = maxLength(res, n*hints.MaxOptionLength+3*(n-1))
+		}
+	}
+	if hints.MaxCurrencyValue >= 0 {
+		res = maxLength(res, currencyStringLength(hints.MaxCurrencyValue))
+	}
+	return res
+}
+
+// lineCountingReader is used to find the physical line of the records read by a csv.Reader.
+//
+// Each call to Read returns at most one line (or a part of it), thus the bufio.Reader used by the csv.Reader never
+// reads beyond the line it needs: It only reads more data if its buffer doesn't contain a line ending. line is the
+// number of the last line returned (starting with 1), so after a record has been read it is the line on which the
+// record ends.
+type lineCountingReader struct {
+	r       *bufio.Reader
+	pending []byte
+	err     error
+	line    int
+	// atLineStart is true if the next byte returned starts a new line
+	atLineStart bool
+}
+
+func newLineCountingReader(r io.Reader) *lineCountingReader {
+	return &lineCountingReader{
+		r:           bufio.NewReader(r),
+		pending:     nil,
+		err:         nil,
+		line:        0,
+		atLineStart: true,
+	}
+}
+
+func (r *lineCountingReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		line, err := r.r.ReadSlice('\n')
+		switch {
+		case err == bufio.ErrBufferFull:
+			// the line continues, it is returned in multiple parts
+			err = nil
+		case err != nil && len(line) == 0:
+			return 0, err
+		}
+		if r.atLineStart {
+			r.line++
+		}
+		r.atLineStart = line[len(line)-1] == '\n'
+		r.pending, r.err = line, err
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// wrapCSVError wraps an error that occurred during reading, if it is a CSV parse error it returns a
+// PollingSyntaxError.
 // The CSV error is not wrapped so clients don't rely on the csv internal errors, only the string is copied.
+// The line number of the error is the (physical) line reported by the csv parser.
 // It must only be called with err != nil.
-func (r *VotesCSVReader) wrapError(err error) error {
+func wrapCSVError(err error) error {
 	if asCsvErr, ok := err.(*csv.ParseError); ok {
-		return NewPollingSyntaxError(nil, asCsvErr.Error())
+		return NewPollingSyntaxError(nil, asCsvErr.Error()).WithLineNum(asCsvErr.Line)
 	}
 	return err
 }
 
 // NewVotesCSVReader returns a VotesCSVReader reading from r.
+//
+// A utf-8 byte order mark at the beginning of r is ignored.
 func NewVotesCSVReader(r io.Reader) *VotesCSVReader {
-	reader := csv.NewReader(r)
+	// the limit is set in ReadRecords
+	limited := newMaxBytesReader(r, -1)
+	lines := newLineCountingReader(newBOMStrippingReader(limited))
+	reader := csv.NewReader(lines)
 	return &VotesCSVReader{
 		Sep:                 DefaultCSVSeparator,
+		Mode:                StrictCSVMode,
 		csv:                 reader,
+		limited:             limited,
+		lines:               lines,
 		MaxNumLines:         -1,
 		MaxVotersNameLength: -1,
 		MaxPollNameLength:   -1,
 		MaxRecordLength:     -1,
+		MaxTotalBytes:       -1,
+		Progress:            nil,
+		ProgressInterval:    DefaultProgressInterval,
+		NameNormalizer:      nil,
+		AllowMultilineCells: true,
+		SourceName:          "",
 	}
 }
 
-func (r *VotesCSVReader) validateRow(row []string) error {
+// ComputeDefaultMaxRecordLength sets MaxRecordLength depending on the values of MaxVotersNameLength,
+// MaxPollNameLength and the longest vote described by hints.
+//
+// It adds a small constant to allow additional whitespaces (and the currency of median votes), if MaxVotersNameLength
+// or MaxPollNameLength is not set (-1) MaxRecordLength is not changed.
+func (r *VotesCSVReader) ComputeDefaultMaxRecordLength(hints VoteLengthHints) {
+	if r.MaxVotersNameLength < 0 || r.MaxPollNameLength < 0 {
+		return
+	}
+	r.MaxRecordLength = maxLength(r.MaxVotersNameLength, r.MaxPollNameLength, hints.maxVoteLength()) + lengthSlack
+}
+
+// readRecord reads the next record and returns it together with the physical line on which it starts.
+//
+// The start is computed from the line on which the record ends and the number of line breaks in its cells, the csv
+// parser replaces "\r\n" by "\n" in quoted cells, so each line break in a cell is exactly one line.
+// It also checks MaxNumLines (maxNumLines must be adjusted by the caller) for the lines of the record.
+func (r *VotesCSVReader) readRecord(maxNumLines int) ([]string, int, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		return record, r.lines.line, err
+	}
+	if maxNumLines >= 0 && r.lines.line > maxNumLines {
+		msg := fmt.Sprintf("there are too many lines: only %d lines in csv file are allowed (line %d)",
+			r.MaxNumLines, r.lines.line)
+		return nil, r.lines.line, NewParserValidationError(msg)
+	}
+	start := r.lines.line
+	for _, entry := range record {
+		start -= strings.Count(entry, "\n")
+	}
+	return record, start, nil
+}
+
+// multilineCell returns the index of the first cell that contains a line break, -1 if there is no such cell or
+// AllowMultilineCells is true.
+func (r *VotesCSVReader) multilineCell(record []string) int {
+	if r.AllowMultilineCells {
+		return -1
+	}
+	for i, entry := range record {
+		if strings.ContainsAny(entry, "\r\n") {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *VotesCSVReader) validateRow(row []string, lineNum int) error {
 	for _, entry := range row {
 		if !utf8.ValidString(entry) {
 			return ErrInvalidEncoding
 		}
 		if r.MaxRecordLength >= 0 && len(entry) > r.MaxRecordLength {
-			return NewParserValidationError(fmt.Sprintf("entry in csv is too long: got length %d, allowed max length is %d",
-				len(entry), r.MaxRecordLength))
+			return NewParserValidationError(fmt.Sprintf("entry in csv is too long: got length %d, allowed max length is %d (line %d)",
+				len(entry), r.MaxRecordLength, lineNum))
 		}
 	}
 	return nil
 }
 
-func (r *VotesCSVReader) readHead() ([]string, error) {
-	res, err := r.csv.Read()
+func (r *VotesCSVReader) readHead(maxNumLines int) ([]string, error) {
+	res, lineNum, err := r.readRecord(maxNumLines)
 	if err == io.EOF {
 		return nil, NewPollingSyntaxError(nil, "no header found in csv file")
 	}
 	if err != nil {
-		return nil, r.wrapError(err)
+		return nil, wrapCSVError(err)
 	}
 	if len(res) == 0 {
 		return nil, NewPollingSyntaxError(nil, "expected at least the voter column in csv file")
 	}
-	if validateErr := r.validateRow(res); validateErr != nil {
+	if validateErr := r.validateRow(res, lineNum); validateErr != nil {
 		return nil, validateErr
 	}
+	if column := r.multilineCell(res); column >= 0 {
+		return nil, NewPollingSyntaxError(nil, "column %d of the csv head contains a line break", column+1).
+			WithLineNum(lineNum)
+	}
 	// all poll names must be valid too
 	if r.MaxPollNameLength >= 0 {
 		for _, pollName := range res[1:] {
@@ -294,56 +732,125 @@ func (r *VotesCSVReader) readHead() ([]string, error) {
 //
 // It returns any error reading from the source.
 // It might also return a PollingSyntaxError if the file is not correctly formed.
+//
+// In LenientCSVMode the report of ReadRecordsWithReport is returned as an error if it is not empty, use
+// ReadRecordsWithReport to get the well-formed rows and all malformed rows.
 func (r *VotesCSVReader) ReadRecords() (head []string, lines [][]string, err error) {
-	// this function only makes sure to return nil, nil if err != nil
+	var report *CSVReadReport
+	head, lines, report, err = r.ReadRecordsWithReport()
+	if err == nil {
+		err = withSourceName(report.AsError(), r.SourceName)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return
+}
+
+// ReadRecordsWithReport works as ReadRecords, but also returns a report of all malformed rows.
+//
+// In StrictCSVMode the report is always empty because reading stops on the first malformed row, this row is reported
+// as a PollingSyntaxError with a line number.
+// In LenientCSVMode malformed rows (rows that can't be parsed and rows that don't have the same number of columns as
+// the head) are not added to lines but to the report and reading continues with the next row.
+//
+// If err != nil head, lines and report are nil.
+func (r *VotesCSVReader) ReadRecordsWithReport() (head []string, lines [][]string, report *CSVReadReport, err error) {
+	// this function only makes sure to return nil, nil, nil if err != nil
 	defer func() {
 		if err != nil {
+			// the input might be cut off because of MaxTotalBytes, report this instead
+			err = withSourceName(r.limited.checkErr(err), r.SourceName)
 			head = nil
 			lines = nil
+			report = nil
 		}
 	}()
 	r.csv.Comma = r.Sep
-	head, err = r.readHead()
+	r.limited.limit = int64(r.MaxTotalBytes)
+	lenient := r.Mode == LenientCSVMode
+	if lenient {
+		// the number of columns is checked by hand for each row
+		r.csv.FieldsPerRecord = -1
+	}
+	report = &CSVReadReport{}
+	maxNumLines := r.MaxNumLines
+	// 0 doesn't make sense, we set it to 1
+	if maxNumLines == 0 {
+		maxNumLines = 1
+	}
+	head, err = r.readHead(maxNumLines)
 	if err != nil {
 		return
 	}
-	// note that the first call in read head already makes sure that each line has the exact
+	reportProgress(r.Progress, r.ProgressInterval, 1)
+	// note that in strict mode the first call in read head already makes sure that each line has the exact
 	// same length and that the length is > 0
 
 	// for validation we don't use ReadAll but iterate "by hand"
 	lines = make([][]string, 0, defaultVotesSize)
-	// set to 1 because head has been read already
-	lineNum := 1
-	maxNumLines := r.MaxNumLines
-	// 0 doesn't make sense, we set it to 1
-	if maxNumLines == 0 {
-		maxNumLines = 1
-	}
+	// rowNum is the number of the row in the csv file, set to 1 because head has been read already
+	rowNum := 1
 	for {
-		lineNum++
-		// again one here because of head, 0 wouldn't make sense
-		if maxNumLines >= 0 && lineNum > maxNumLines {
-			err = NewParserValidationError(fmt.Sprintf("there are too many lines: only %d lines in csv file are allowed", r.MaxNumLines))
-			return
-		}
-		record, recordErr := r.csv.Read()
+		rowNum++
+		record, lineNum, recordErr := r.readRecord(maxNumLines)
 		if recordErr == io.EOF {
 			return
 		}
+		reportProgress(r.Progress, r.ProgressInterval, rowNum)
 		if recordErr != nil {
-			err = r.wrapError(recordErr)
+			if parseErr, isParseErr := recordErr.(*csv.ParseError); lenient && isParseErr {
+				// the record returned by the csv parser might be incomplete, so the columns are unknown
+				report.add(CSVRowError{
+					Row:             rowNum,
+					Line:            parseErr.StartLine,
+					NumColumns:      -1,
+					ExpectedColumns: len(head),
+					Msg:             recordErr.Error(),
+				})
+				continue
+			}
+			err = wrapCSVError(recordErr)
 			return
 		}
+		if lenient && len(record) != len(head) {
+			report.add(CSVRowError{
+				Row:             rowNum,
+				Line:            lineNum,
+				NumColumns:      len(record),
+				ExpectedColumns: len(head),
+				Msg:             "wrong number of fields",
+			})
+			continue
+		}
 
-		if validateRecordErr := r.validateRow(record); validateRecordErr != nil {
+		if validateRecordErr := r.validateRow(record, lineNum); validateRecordErr != nil {
 			err = validateRecordErr
 			return
 		}
 
+		record[0] = r.NameNormalizer.Normalize(record[0])
+
+		if column := r.multilineCell(record); column >= 0 {
+			msg := fmt.Sprintf("cell in column %d of voter \"%s\" contains a line break", column+1, record[0])
+			if lenient {
+				report.add(CSVRowError{
+					Row:             rowNum,
+					Line:            lineNum,
+					NumColumns:      -1,
+					ExpectedColumns: len(head),
+					Msg:             msg,
+				})
+				continue
+			}
+			err = NewPollingSyntaxError(nil, msg).WithLineNum(lineNum)
+			return
+		}
+
 		// now we must also validate the voter
 		if voterName := record[0]; r.MaxVotersNameLength >= 0 && len(voterName) > r.MaxVotersNameLength {
-			err = NewParserValidationError(fmt.Sprintf("voter name is too long: got length %d, allowed max length is %d",
-				len(voterName), r.MaxVotersNameLength))
+			err = NewParserValidationError(fmt.Sprintf("voter name is too long: got length %d, allowed max length is %d (line %d)",
+				len(voterName), r.MaxVotersNameLength, lineNum))
 			return
 		}
 
@@ -379,6 +886,72 @@ const (
 	AddAsAbstentionEmptyVote
 )
 
+// String returns the name of the policy as used in a polls file, see ParseEmptyVotePolicy.
+func (policy EmptyVotePolicy) String() string {
+	switch policy {
+	case IgnoreEmptyVote:
+		return "ignore"
+	case RaiseErrorEmptyVote:
+		return "error"
+	case AddAsAyeEmptyVote:
+		return "aye"
+	case AddAsNoEmptyVote:
+		return "no"
+	case AddAsAbstentionEmptyVote:
+		return "abstention"
+	default:
+		return fmt.Sprintf("EmptyVotePolicy(%d)", policy)
+	}
+}
+
+// ParseEmptyVotePolicy parses a policy from its name as returned by EmptyVotePolicy.String, i.e. "ignore", "error",
+// "aye", "no" or "abstention" (case-insensitive, leading and trailing whitespaces are ignored). These names are the
+// canonical names of the policies, they're also used in the JSON encoding (see MarshalJSON).
+// For an unknown name a PollingSyntaxError is returned.
+func ParseEmptyVotePolicy(s string) (EmptyVotePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "ignore":
+		return IgnoreEmptyVote, nil
+	case "error":
+		return RaiseErrorEmptyVote, nil
+	case "aye":
+		return AddAsAyeEmptyVote, nil
+	case "no":
+		return AddAsNoEmptyVote, nil
+	case "abstention":
+		return AddAsAbstentionEmptyVote, nil
+	default:
+		return IgnoreEmptyVote, NewPollingSyntaxError(nil, "unknown empty vote policy \"%s\"", s)
+	}
+}
+
+// MarshalJSON implements json.Marshaler, the policy is encoded as a string with its name (see String).
+//
+// An unknown policy returns a PollTypeError.
+func (policy EmptyVotePolicy) MarshalJSON() ([]byte, error) {
+	switch policy {
+	case IgnoreEmptyVote, RaiseErrorEmptyVote, AddAsAyeEmptyVote, AddAsNoEmptyVote, AddAsAbstentionEmptyVote:
+		return json.Marshal(policy.String())
+	default:
+		return nil, NewPollTypeError("can't encode unknown empty vote policy %d", policy)
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the policy must be encoded as a string that is accepted by
+// ParseEmptyVotePolicy.
+func (policy *EmptyVotePolicy) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return NewPollingSyntaxError(err, "empty vote policy must be a string")
+	}
+	parsed, parseErr := ParseEmptyVotePolicy(s)
+	if parseErr != nil {
+		return parseErr
+	}
+	*policy = parsed
+	return nil
+}
+
 // GeneratePoliciesList is just a small helper function that returns a list of num elements, each entry is
 // set to the given policy.
 // GeneratePoliciesMap does the same for a map.
@@ -403,6 +976,51 @@ func GeneratePoliciesMap(policy EmptyVotePolicy, polls PollMap) PolicyMap {
 	return res
 }
 
+// ParsePoliciesMap parses a PolicyMap from a map of poll names to policy names (see ParseEmptyVotePolicy), for
+// example from a config file or form values.
+//
+// Each poll in values must exist in polls, otherwise a PollingSemanticError is returned. The returned map contains
+// only the polls from values, polls without an entry in values are not included.
+// The entries are processed sorted by name, thus if there are multiple errors always the same one is returned.
+func ParsePoliciesMap(values map[string]string, polls PollMap) (PolicyMap, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make(PolicyMap, len(values))
+	for _, name := range names {
+		if _, hasPoll := polls[name]; !hasPoll {
+			return nil, NewPollingSemanticError(nil, "empty vote policy for unknown poll \"%s\"", name)
+		}
+		policy, parseErr := ParseEmptyVotePolicy(values[name])
+		if parseErr != nil {
+			return nil, NewPollingSyntaxError(parseErr, "invalid empty vote policy for poll \"%s\"", name)
+		}
+		res[name] = policy
+	}
+	return res, nil
+}
+
+// PoliciesFromCollection returns a PolicyMap for all skeletons in coll.
+//
+// The policy of a skeleton is the EmptyPolicy of its annotations (see PollAnnotations), for skeletons without this
+// annotation fallback is used.
+func PoliciesFromCollection(coll *PollSkeletonCollection, fallback EmptyVotePolicy) PolicyMap {
+	skels := coll.CollectSkeletons()
+	res := make(PolicyMap, len(skels))
+	for _, skel := range skels {
+		policy := fallback
+		if annotated, ok := skel.(AnnotatedSkeleton); ok {
+			if annotatedPolicy := annotated.GetAnnotations().EmptyPolicy; annotatedPolicy != nil {
+				policy = *annotatedPolicy
+			}
+		}
+		res[skel.GetName()] = policy
+	}
+	return res
+}
+
 // ErrEmptyPollPolicy is an error used if a policy is set to RaiseErrorEmptyVote and an empty vote was found.
 // GenerateEmptyVoteForVoter will in this case return an error e s.t. errors.Is(e, ErrEmptyPollPolicy) returns true.
 // This should of course be checked before errors.Is(e, ErrPoll) because this is true for all internal errors.
@@ -466,6 +1084,9 @@ type PollMatrix struct {
 }
 
 // ReadMatrixFromCSV creates a matrix and reads the content from the csv reader.
+//
+// Malformed rows are handled depending on r.Mode, see VotesCSVReader.ReadRecords: In LenientCSVMode all malformed
+// rows are reported in a single error. Use ReadMatrixFromCSVWithReport to get the report.
 func ReadMatrixFromCSV(r *VotesCSVReader) (*PollMatrix, error) {
 	head, body, err := r.ReadRecords()
 
@@ -479,6 +1100,116 @@ func ReadMatrixFromCSV(r *VotesCSVReader) (*PollMatrix, error) {
 	return &m, nil
 }
 
+// ReadMatrixFromCSVWithReport works as ReadMatrixFromCSV but returns the report of malformed rows (see
+// VotesCSVReader.ReadRecordsWithReport), the matrix contains only the well-formed rows.
+func ReadMatrixFromCSVWithReport(r *VotesCSVReader) (*PollMatrix, *CSVReadReport, error) {
+	head, body, report, err := r.ReadRecordsWithReport()
+
+	if err != nil {
+		return nil, nil, err
+	}
+	m := PollMatrix{
+		Head: head,
+		Body: body,
+	}
+	return &m, report, nil
+}
+
+// ReadMatrixFromCSVFile works as ReadMatrixFromCSV but reads the csv file with the given path.
+//
+// The VotesCSVReader for the file has SourceName set to the base name of path, it can be configured with configure
+// (for example to set limits) before the file is read, configure can be nil.
+// Errors opening the file are returned directly.
+func ReadMatrixFromCSVFile(path string, configure func(reader *VotesCSVReader)) (*PollMatrix, error) {
+	f, openErr := os.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer f.Close()
+	reader := NewVotesCSVReader(f)
+	reader.SourceName = filepath.Base(path)
+	if configure != nil {
+		configure(reader)
+	}
+	return ReadMatrixFromCSV(reader)
+}
+
+// NormalizeVoterNames applies normalizer to the voter names (the first column of each row in Body), see
+// NameNormalizer. This is useful if the matrix was not created by a VotesCSVReader with the normalizer set.
+func (m *PollMatrix) NormalizeVoterNames(normalizer *NameNormalizer) {
+	for _, row := range m.Body {
+		if len(row) > 0 {
+			row[0] = normalizer.Normalize(row[0])
+		}
+	}
+}
+
+// CanonicalOrder returns the names of all polls in coll in the order in which they appear in the collection, this
+// is the order in which VotesCSVWriter writes the columns. It can be used with PollMatrix.ReorderColumns.
+func CanonicalOrder(coll *PollSkeletonCollection) []string {
+	skels := coll.CollectSkeletons()
+	res := make([]string, len(skels))
+	for i, skel := range skels {
+		res[i] = skel.GetName()
+	}
+	return res
+}
+
+// ReorderColumns permutes the poll columns (Head[1:] and the corresponding entries of each row in Body) so that
+// they appear in the given order, the first column (the voters) is retained.
+//
+// This is useful if the columns of a csv file are not in the order of the collection (see CanonicalOrder), for
+// example to get deterministic diffs when the matrix is written again.
+// order must be a permutation of Head[1:], otherwise a PollingSemanticError is returned. All rows must have the same
+// length as the head, otherwise a PollingSyntaxError is returned. If an error is returned the matrix is not changed.
+func (m *PollMatrix) ReorderColumns(order []string) error {
+	if len(m.Head) == 0 {
+		return NewPollingSyntaxError(nil, "poll matrix must contain at least one column")
+	}
+	for _, row := range m.Body {
+		if len(row) != len(m.Head) {
+			return NewPollingSyntaxError(nil,
+				"number of columns in csv is invalid, expected length of %d (head), got length %d instead",
+				len(m.Head), len(row))
+		}
+	}
+	if len(order) != len(m.Head)-1 {
+		return NewPollingSemanticError(nil, "order must contain all %d polls, got %d names instead",
+			len(m.Head)-1, len(order))
+	}
+	columns := make(map[string]int, len(order))
+	for i, name := range m.Head[1:] {
+		if _, has := columns[name]; has {
+			return NewPollingSemanticError(nil, "duplicate poll %s in matrix head", name)
+		}
+		columns[name] = i + 1
+	}
+	// positions[i] is the column in the current matrix that becomes column i + 1
+	positions := make([]int, len(order))
+	for i, name := range order {
+		column, has := columns[name]
+		if !has {
+			return NewPollingSemanticError(nil, "poll %s is not a column of the matrix (or appears twice in the order)",
+				name)
+		}
+		delete(columns, name)
+		positions[i] = column
+	}
+	permute := func(row []string) {
+		reordered := make([]string, len(row))
+		reordered[0] = row[0]
+		for i, column := range positions {
+			reordered[i+1] = row[column]
+		}
+		copy(row, reordered)
+	}
+	permute(m.Head)
+	for _, row := range m.Body {
+		permute(row)
+	}
+	return nil
+}
+
 // MatchEntries tests if the matrix is well-formed.
 //
 // The maps voters and polls are maps that specify the allowed names / voter names.
@@ -518,13 +1249,12 @@ func (m *PollMatrix) MatchEntries(voters VoterMap, polls PollMap) (matchedVoters
 		return
 	}
 
+	if err = m.checkRowLengths(); err != nil {
+		return
+	}
+
 	// now see if all voters exist and the names from csv are uniqe
 	for _, row := range m.Body {
-		if len(row) != len(m.Head) {
-			err = NewPollingSyntaxError(nil, "number of columns in csv is invalid, expected length of %d (head), got length %d instead",
-				len(m.Head), len(row))
-			return
-		}
 		// len(head) >= 0 from check above
 		voterName := row[0]
 		// check if we have a duplicate
@@ -564,6 +1294,47 @@ func (m *PollMatrix) MatchEntries(voters VoterMap, polls PollMap) (matchedVoters
 	return
 }
 
+// checkRowLengths returns a PollingSyntaxError if a row in Body doesn't have the same length as Head.
+func (m *PollMatrix) checkRowLengths() error {
+	for _, row := range m.Body {
+		if len(row) != len(m.Head) {
+			return NewPollingSyntaxError(nil, "number of columns in csv is invalid, expected length of %d (head), got length %d instead",
+				len(m.Head), len(row))
+		}
+	}
+	return nil
+}
+
+// restrictToGroup returns a matrix that contains only the voter column and the columns of the polls in group, all
+// rows must have the same length as the head (see checkRowLengths).
+func (m *PollMatrix) restrictToGroup(group *PollGroup) *PollMatrix {
+	names := make(map[string]struct{}, len(group.Skeletons))
+	for _, skel := range group.Skeletons {
+		names[skel.GetName()] = struct{}{}
+	}
+	columns := []int{0}
+	for column := 1; column < len(m.Head); column++ {
+		if _, has := names[m.Head[column]]; has {
+			columns = append(columns, column)
+		}
+	}
+	res := &PollMatrix{
+		Head: make([]string, len(columns)),
+		Body: make([][]string, len(m.Body)),
+	}
+	for i, column := range columns {
+		res.Head[i] = m.Head[column]
+	}
+	for i, row := range m.Body {
+		restricted := make([]string, len(columns))
+		for j, column := range columns {
+			restricted[j] = row[column]
+		}
+		res.Body[i] = restricted
+	}
+	return res
+}
+
 func (m *PollMatrix) generateSingleVote(poll AbstractPoll, parser VoteParser, policy EmptyVotePolicy, voter *Voter, s string) (AbstractVote, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -572,69 +1343,411 @@ func (m *PollMatrix) generateSingleVote(poll AbstractPoll, parser VoteParser, po
 	return parser.ParseFromString(s, voter)
 }
 
-func (m *PollMatrix) generateVotesForPoll(columnIndex int, voters VoterMap, poll AbstractPoll, parser VoteParser, policy EmptyVotePolicy) error {
+func (m *PollMatrix) generateVotesForPoll(columnIndex int, voters VoterMap, poll AbstractPoll, parser VoteParser,
+	policy EmptyVotePolicy, options fillOptions) (stats PollFillStats, rows int, err error) {
+	var validator VoteValidator
+	if options.validateVotes {
+		validator, _ = poll.(VoteValidator)
+	}
 	// iterate over all voters and generate the vote
 	// this could be nil due to the policy, in which case it should be ignored
-	for _, row := range m.Body {
+	// rows is the number of rows that have been processed successfully
+	for rowIndex, row := range m.Body {
 		voterName := row[0]
 		voter := voters[voterName]
 		voteString := row[columnIndex]
+		if options.zeroWeightPolicy == ExcludeZeroWeightFromCounts && voter.HasZeroWeight() {
+			stats.SkippedZeroWeight++
+			rows++
+			if options.eventHandler != nil {
+				options.eventHandler(PollEvent{Poll: m.Head[columnIndex], Voter: voterName,
+					Action: ZeroWeightSkippedAction, Reason: "voter has weight 0"})
+			}
+			continue
+		}
+		isEmpty := strings.TrimSpace(voteString) == ""
 		vote, voteErr := m.generateSingleVote(poll, parser, policy, voter, voteString)
 		if voteErr != nil {
-			return voteErr
+			err = voteErr
+			return
 		}
 		// only if vote is not nil add it
 		if vote != nil {
+			if sourced, ok := vote.(SourcedVote); ok && options.rawVoteSources {
+				sourced.SetSource(&VoteSource{Raw: voteString, Row: rowIndex, Column: columnIndex})
+			}
+			if validator != nil {
+				if validateErr := validator.ValidateVote(vote); validateErr != nil {
+					err = validateErr
+					return
+				}
+			}
 			if addErr := poll.AddVote(vote); addErr != nil {
-				return addErr
+				err = addErr
+				return
+			}
+		}
+		switch {
+		case !isEmpty:
+			stats.Parsed++
+		case vote != nil:
+			stats.PolicyGenerated++
+			if options.eventHandler != nil {
+				options.eventHandler(PollEvent{Poll: m.Head[columnIndex], Voter: voterName,
+					Action: EmptyVoteGeneratedAction, Reason: fmt.Sprintf("empty vote policy %s", policy)})
+			}
+		default:
+			stats.SkippedEmpty++
+			if options.eventHandler != nil {
+				options.eventHandler(PollEvent{Poll: m.Head[columnIndex], Voter: voterName,
+					Action: EmptyVoteIgnoredAction, Reason: fmt.Sprintf("empty vote policy %s", policy)})
 			}
 		}
+		rows++
 	}
-	return nil
+	return
 }
 
-func (m *PollMatrix) fillAllPolls(voters VoterMap, polls PollMap, parsers map[string]VoteParser, policies PolicyMap) error {
-	// internal struct used in a channel
-	type pollParseRes struct {
-		column int
-		name   string
-		err    error
-	}
-
-	// channel for communication
-	ch := make(chan pollParseRes, 1)
-
-	// parse all votes for all polls (concurrently) with generateVotesForPoll
-	for column, pollName := range m.Head[1:] {
-		go func(column int, pollName string) {
-			poll := polls[pollName]
-			parser := parsers[pollName]
-			policy := policies[pollName]
-			// index + 1 because column starts with 0
-			collErr := m.generateVotesForPoll(column+1, voters, poll, parser, policy)
-			ch <- pollParseRes{
-				column: column,
-				name:   pollName,
-				err:    collErr,
-			}
-		}(column, pollName)
+// PollColumnError is an error that occurred while filling a single poll from a PollMatrix.
+//
+// It contains the name of the poll and the column in the matrix (the first poll has column 1 because column 0
+// contains the voter names). Err is the original error, it is returned by Unwrap.
+type PollColumnError struct {
+	Column int
+	Poll   string
+	Err    error
+}
+
+func (err PollColumnError) Error() string {
+	return fmt.Sprintf("poll \"%s\" (column %d): %s", err.Poll, err.Column, err.Err.Error())
+}
+
+// Unwrap returns the wrapped error.
+func (err PollColumnError) Unwrap() error {
+	return err.Err
+}
+
+// PollMatrixErrors is returned by FillPollsWithVotes if filling one or more polls failed.
+//
+// Errors contains one entry for each poll that failed, sorted by column.
+// errors.Is and errors.As test all of the wrapped errors, thus for example errors.Is(err, ErrPoll) returns true
+// if any of the errors is an internal error.
+type PollMatrixErrors struct {
+	Errors []PollColumnError
+}
+
+func (err PollMatrixErrors) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, colErr := range err.Errors {
+		messages[i] = colErr.Error()
 	}
+	return strings.Join(messages, "; ")
+}
 
-	// we capture the error in the smallest column and return it
-	var err error
-	smallestPollIndex := -1
+// Unwrap returns the first error (the error with the smallest column).
+//
+// Use Is and As (or the functions from the errors package) to test all errors.
+func (err PollMatrixErrors) Unwrap() error {
+	if len(err.Errors) == 0 {
+		return nil
+	}
+	return err.Errors[0]
+}
 
+// Is returns true if errors.Is returns true for any of the errors.
+func (err PollMatrixErrors) Is(target error) bool {
+	for _, colErr := range err.Errors {
+		if errors.Is(colErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error (the one with the smallest column) that matches target, see errors.As.
+func (err PollMatrixErrors) As(target interface{}) bool {
+	for _, colErr := range err.Errors {
+		if errors.As(colErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// FillOption is an option for PollMatrix.FillPollsWithVotes, see for example WithWorkerLimit.
+type FillOption func(options *fillOptions)
+
+type fillOptions struct {
+	workerLimit            int
+	validateVotes          bool
+	pollFilled             PollFilledFunc
+	zeroWeightPolicy       ZeroWeightPolicy
+	skipPollsWithoutParser bool
+	eventHandler           PollEventHandler
+	rawVoteSources         bool
+	group                  *PollGroup
+}
+
+// WithWorkerLimit sets the maximal number of goroutines used to fill the polls.
+//
+// If limit <= 0 the default is used, which is runtime.GOMAXPROCS(0).
+func WithWorkerLimit(limit int) FillOption {
+	return func(options *fillOptions) {
+		options.workerLimit = limit
+	}
+}
+
+// WithVoteValidation enables the validation of each vote before it is added to a poll.
+//
+// If a poll implements VoteValidator each vote is validated with ValidateVote, an invalid vote is reported as an
+// error instead of being added (and truncated later).
+func WithVoteValidation() FillOption {
+	return func(options *fillOptions) {
+		options.validateVotes = true
+	}
+}
+
+// PollFilledFunc is called by PollMatrix.FillPollsWithVotes once all votes of a poll have been added, numVotes is
+// the number of votes added to the poll.
+//
+// The function must not modify the poll.
+type PollFilledFunc func(pollName string, numVotes int)
+
+// WithPollFilledCallback sets a function that is called each time a poll has been filled successfully, for
+// example to report progress. It is not called for polls that returned an error.
+//
+// The polls are filled concurrently, but the calls of f are synchronized, thus f doesn't have to be safe for
+// concurrent use. The order in which the polls are reported is not defined.
+func WithPollFilledCallback(f PollFilledFunc) FillOption {
+	return func(options *fillOptions) {
+		options.pollFilled = f
+	}
+}
+
+// WithZeroWeightPolicy sets the policy for voters with weight 0, the default is AllowZeroWeight.
+//
+// With RejectZeroWeightAtParse a PollingSemanticError is returned if a voter in the matrix has weight 0, with
+// ExcludeZeroWeightFromCounts the entries of such voters are skipped (see FillReport).
+func WithZeroWeightPolicy(policy ZeroWeightPolicy) FillOption {
+	return func(options *fillOptions) {
+		options.zeroWeightPolicy = policy
+	}
+}
+
+// WithSkipPollsWithoutParser ignores polls without a parser instead of returning an error.
+//
+// The columns of such polls are not parsed and the polls are not part of the returned polls (like polls that are
+// missing in the matrix), their names are reported in FillReport.SkippedPolls. Such polls don't need a policy either.
+// This can be used together with CustomizeParsersToMapWithOptions to ignore polls for which no parser exists.
+func WithSkipPollsWithoutParser() FillOption {
+	return func(options *fillOptions) {
+		options.skipPollsWithoutParser = true
+	}
+}
+
+// WithFillEventHandler sets a handler that is called for decisions made while filling the polls: empty entries that
+// were ignored or for which the EmptyVotePolicy generated a vote, skipped entries of voters with weight 0 and polls
+// skipped because of WithSkipPollsWithoutParser (see the action constants like EmptyVoteIgnoredAction).
+//
+// As for WithPollFilledCallback the calls of handler are synchronized. Use LogPollEvents to write the events to a
+// Logger.
+func WithFillEventHandler(handler PollEventHandler) FillOption {
+	return func(options *fillOptions) {
+		options.eventHandler = handler
+	}
+}
+
+// WithRawVoteSources stores the entry of the matrix each vote was created from as the source of the vote (see
+// VoteSource), including the row and column of the entry. This is also done for votes generated by an
+// EmptyVotePolicy, the raw string of such a vote is the (empty) entry.
+//
+// This only works for votes that implement SourcedVote, the source of other votes is not stored. A source set by the
+// parser (see for example BasicVoteParser.KeepRaw) is replaced.
+func WithRawVoteSources() FillOption {
+	return func(options *fillOptions) {
+		options.rawVoteSources = true
+	}
+}
+
+// WithGroupColumns restricts filling the polls to the columns of the polls in group (a group of the collection the
+// polls were created from), for example to evaluate one group while the other groups are still collecting votes.
+//
+// All other columns are ignored: They're not matched against the polls and they don't need a parser or policy. The
+// polls of other groups don't have to be part of the polls map, and if they are they're not filled and are not part
+// of the returned polls. allowMissingPolls only applies to the polls of the group (that are in the polls map).
+// The columns in errors, reports and vote sources are still the columns of the whole matrix.
+func WithGroupColumns(group *PollGroup) FillOption {
+	return func(options *fillOptions) {
+		options.group = group
+	}
+}
+
+// PollFillStats describes how the votes of a single poll were generated by PollMatrix.FillPollsWithVotes.
+//
+// Parsed is the number of non-empty entries that were parsed and added to the poll, PolicyGenerated the number of
+// empty entries for which the EmptyVotePolicy generated a vote and SkippedEmpty the number of empty entries that
+// were ignored (IgnoreEmptyVote). SkippedZeroWeight is the number of entries (empty or not) of voters with weight 0
+// that were skipped because of ExcludeZeroWeightFromCounts, see WithZeroWeightPolicy.
+type PollFillStats struct {
+	Parsed            int
+	PolicyGenerated   int
+	SkippedEmpty      int
+	SkippedZeroWeight int
+}
+
+// NumVotes returns the number of votes added to the poll, i.e. Parsed + PolicyGenerated.
+func (stats PollFillStats) NumVotes() int {
+	return stats.Parsed + stats.PolicyGenerated
+}
+
+// FillReport describes the votes added by PollMatrix.FillPollsWithVotesWithReport, it can for example be used to
+// warn about voters that didn't vote at all before results are announced.
+//
+// Polls maps each poll name to the statistics of the poll, CastVotes maps each voter that appears in the matrix to
+// the number of non-empty entries of the voter (votes generated by an EmptyVotePolicy are not counted).
+//
+// If filling a poll failed the statistics contain the entries up to the failing row, the entries of this row
+// and all following rows are not counted (neither in Polls nor in CastVotes).
+//
+// ZeroWeightVoters contains the (sorted) names of all voters in the matrix with weight 0 whose entries were skipped
+// because of ExcludeZeroWeightFromCounts, see WithZeroWeightPolicy. Their non-empty entries are still counted in
+// CastVotes.
+//
+// SkippedPolls contains the (sorted) names of all polls in the matrix that were skipped because they have no parser,
+// see WithSkipPollsWithoutParser. Entries of these polls are not counted in CastVotes.
+type FillReport struct {
+	Polls            map[string]PollFillStats
+	CastVotes        map[string]int
+	ZeroWeightVoters []string
+	SkippedPolls     []string
+}
+
+// NewFillReport returns an empty report.
+func NewFillReport() *FillReport {
+	return &FillReport{
+		Polls:            make(map[string]PollFillStats),
+		CastVotes:        make(map[string]int),
+		ZeroWeightVoters: make([]string, 0),
+		SkippedPolls:     make([]string, 0),
+	}
+}
+
+// EmptyVoters returns the (sorted) names of all voters that didn't cast any vote, i.e. the row of the voter
+// contains only empty entries.
+func (report *FillReport) EmptyVoters() []string {
+	res := make([]string, 0)
+	for voterName, numVotes := range report.CastVotes {
+		if numVotes == 0 {
+			res = append(res, voterName)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// PollsWithoutVotes returns the (sorted) names of all polls that didn't receive any non-empty vote.
+func (report *FillReport) PollsWithoutVotes() []string {
+	res := make([]string, 0)
+	for pollName, stats := range report.Polls {
+		if stats.Parsed == 0 {
+			res = append(res, pollName)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (m *PollMatrix) fillAllPolls(voters VoterMap, polls PollMap, parsers map[string]VoteParser, policies PolicyMap,
+	options fillOptions, report *FillReport) error {
+	for _, row := range m.Body {
+		report.CastVotes[row[0]] = 0
+	}
 	numPolls := len(m.Head) - 1
+	if numPolls <= 0 {
+		return nil
+	}
+	// columns of polls that are not in polls are skipped (see WithSkipPollsWithoutParser)
+	columns := make([]int, 0, numPolls)
+	for column := 1; column <= numPolls; column++ {
+		if _, has := polls[m.Head[column]]; has {
+			columns = append(columns, column)
+		}
+	}
+	numWorkers := options.workerLimit
+	if numWorkers <= 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	if numWorkers > len(columns) {
+		numWorkers = len(columns)
+	}
 
-	for i := 0; i < numPolls; i++ {
-		colRes := <-ch
-		if colRes.err != nil && (smallestPollIndex < 0 || colRes.column < smallestPollIndex) {
-			err = colRes.err
-			smallestPollIndex = colRes.column
+	// each worker gets column numbers from the jobs channel and writes the result to colErrs[column - 1] (and the same
+	// for colStats and colRows), thus no synchronization for colErrs is required and the errors are already sorted by
+	// column
+	colErrs := make([]error, numPolls)
+	colStats := make([]PollFillStats, numPolls)
+	colRows := make([]int, numPolls)
+	jobs := make(chan int, len(columns))
+	for _, column := range columns {
+		jobs <- column
+	}
+	close(jobs)
 
+	// callbackMutex synchronizes the calls of options.pollFilled and options.eventHandler
+	var callbackMutex sync.Mutex
+	if handler := options.eventHandler; handler != nil {
+		options.eventHandler = func(event PollEvent) {
+			callbackMutex.Lock()
+			defer callbackMutex.Unlock()
+			handler(event)
 		}
 	}
-	return err
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			for column := range jobs {
+				pollName := m.Head[column]
+				stats, rows, colErr := m.generateVotesForPoll(column, voters, polls[pollName], parsers[pollName],
+					policies[pollName], options)
+				colErrs[column-1], colStats[column-1], colRows[column-1] = colErr, stats, rows
+				if colErr == nil && options.pollFilled != nil {
+					callbackMutex.Lock()
+					options.pollFilled(pollName, stats.NumVotes())
+					callbackMutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, stats := range colStats {
+		if _, has := polls[m.Head[i+1]]; !has {
+			continue
+		}
+		report.Polls[m.Head[i+1]] = stats
+		for _, row := range m.Body[:colRows[i]] {
+			if strings.TrimSpace(row[i+1]) != "" {
+				report.CastVotes[row[0]]++
+			}
+		}
+	}
+
+	var res []PollColumnError
+	for i, colErr := range colErrs {
+		if colErr != nil {
+			res = append(res, PollColumnError{
+				Column: i + 1,
+				Poll:   m.Head[i+1],
+				Err:    colErr,
+			})
+		}
+	}
+	if len(res) > 0 {
+		return PollMatrixErrors{Errors: res}
+	}
+	return nil
 }
 
 // FillPollsWithVotes does the actual parsing of votes, it creates new vote entries in the polls.
@@ -646,7 +1759,8 @@ func (m *PollMatrix) fillAllPolls(voters VoterMap, polls PollMap, parsers map[st
 // If the csv entry is empty (string contains only whitespace) not the parse method of the parser is called but
 // GenerateEmptyVoteForVoter for the policy associated with the poll.
 //
-// If a poll does not have parser / policy associated with it a PollingSemanticError is returned.
+// If a poll does not have parser / policy associated with it a PollingSemanticError is returned, polls without a
+// parser can be skipped with WithSkipPollsWithoutParser.
 // Also all errors from AddVote are returned.
 // The matrix is also verified with MatchEntries function and any error from this function is returned.
 // The arguments allowMissingVoters and allowMissingPolls determine what should happen if a voter or poll is missing.
@@ -658,14 +1772,54 @@ func (m *PollMatrix) fillAllPolls(voters VoterMap, polls PollMap, parsers map[st
 // Especially if allowMissingVoters = false and allowMissingPolls = false the result should return maps that are
 // equivalent to the input maps.
 //
+// The polls are filled concurrently, the number of goroutines used can be limited with WithWorkerLimit.
+// With WithVoteValidation each vote is validated before it is added, see VoteValidator.
+// Voters with weight 0 are handled according to WithZeroWeightPolicy, by default their votes are added.
+// Decisions like ignored empty entries can be reported to an audit log with WithFillEventHandler.
+// The entry each vote was created from can be stored in the vote with WithRawVoteSources.
+// With WithGroupColumns only the polls of a single group are filled, all other columns are ignored.
+// Errors while filling the polls are collected, in this case an error of type PollMatrixErrors is returned, it
+// contains the errors of all polls that failed (sorted by column).
+//
 // Note that if an error is returned it is possible that some of the polls got already filled with votes!
 // In this case not all votes for a poll might be present and the whole operation should be marked as failure and
 // probably none of the votes that already appear in some poll should be used.
+//
+// See FillPollsWithVotesWithReport for a version that also returns statistics about the votes.
 func (m *PollMatrix) FillPollsWithVotes(polls PollMap, voters VoterMap,
 	parsers map[string]VoteParser, policies PolicyMap,
-	allowMissingVoters, allowMissingPolls bool) (actualVoters VoterMap, actualPolls PollMap, err error) {
+	allowMissingVoters, allowMissingPolls bool, options ...FillOption) (actualVoters VoterMap, actualPolls PollMap, err error) {
+	actualVoters, actualPolls, _, err = m.FillPollsWithVotesWithReport(polls, voters, parsers, policies,
+		allowMissingVoters, allowMissingPolls, options...)
+	return
+}
+
+// FillPollsWithVotesWithReport works as FillPollsWithVotes, but also returns a FillReport describing the votes
+// that were added.
+//
+// The report is never nil, also if an error is returned. If the matrix could not be verified (see MatchEntries
+// and the arguments allowMissingVoters and allowMissingPolls) the report is empty. If filling some polls failed the
+// report contains all entries up to the failure point, see FillReport.
+func (m *PollMatrix) FillPollsWithVotesWithReport(polls PollMap, voters VoterMap,
+	parsers map[string]VoteParser, policies PolicyMap,
+	allowMissingVoters, allowMissingPolls bool, options ...FillOption) (actualVoters VoterMap, actualPolls PollMap,
+	report *FillReport, err error) {
+	report = NewFillReport()
+	var fillOpts fillOptions
+	for _, option := range options {
+		option(&fillOpts)
+	}
+
 	// first ensure matrix structure
-	actualVoters, actualPolls, err = m.MatchEntries(voters, polls)
+	matched := m
+	if fillOpts.group != nil {
+		if err = m.checkRowLengths(); err != nil {
+			return
+		}
+		matched = m.restrictToGroup(fillOpts.group)
+		polls = polls.restrictToGroup(fillOpts.group)
+	}
+	actualVoters, actualPolls, err = matched.MatchEntries(voters, polls)
 	if err != nil {
 		return
 	}
@@ -674,7 +1828,7 @@ func (m *PollMatrix) FillPollsWithVotes(polls PollMap, voters VoterMap,
 	if !allowMissingVoters && len(actualVoters) != len(voters) {
 		// create a list of all missing voters
 		missing := make([]string, 0, len(voters))
-		for voterName := range voters {
+		for _, voterName := range voters.SortedNames() {
 			if _, has := actualVoters[voterName]; !has {
 				missing = append(missing, voterName)
 			}
@@ -686,7 +1840,7 @@ func (m *PollMatrix) FillPollsWithVotes(polls PollMap, voters VoterMap,
 	if !allowMissingPolls && len(actualPolls) != len(polls) {
 		// create a list of all missing polls
 		missing := make([]string, 0, len(polls))
-		for pollName := range polls {
+		for _, pollName := range polls.SortedNames() {
 			if _, has := actualPolls[pollName]; !has {
 				missing = append(missing, pollName)
 			}
@@ -696,8 +1850,17 @@ func (m *PollMatrix) FillPollsWithVotes(polls PollMap, voters VoterMap,
 	}
 
 	// make sure that each poll has a parser and a policy
-	for pollName := range actualPolls {
+	for _, pollName := range actualPolls.SortedNames() {
 		if _, hasParser := parsers[pollName]; !hasParser {
+			if fillOpts.skipPollsWithoutParser {
+				delete(actualPolls, pollName)
+				report.SkippedPolls = append(report.SkippedPolls, pollName)
+				if fillOpts.eventHandler != nil {
+					fillOpts.eventHandler(PollEvent{Poll: pollName, Action: PollSkippedAction,
+						Reason: "no parser for poll"})
+				}
+				continue
+			}
 			err = NewPollingSemanticError(nil, "there is no parser for poll %s", pollName)
 			return
 		}
@@ -708,7 +1871,18 @@ func (m *PollMatrix) FillPollsWithVotes(polls PollMap, voters VoterMap,
 		}
 	}
 
+	// handle voters with weight 0
+	switch fillOpts.zeroWeightPolicy {
+	case RejectZeroWeightAtParse:
+		if zeroWeight := zeroWeightVoterNames(actualVoters); len(zeroWeight) > 0 {
+			err = NewPollingSemanticError(nil, "the following voters have weight 0: %s", strings.Join(zeroWeight, ", "))
+			return
+		}
+	case ExcludeZeroWeightFromCounts:
+		report.ZeroWeightVoters = zeroWeightVoterNames(actualVoters)
+	}
+
 	// now insert
-	err = m.fillAllPolls(actualVoters, actualPolls, parsers, policies)
+	err = m.fillAllPolls(actualVoters, actualPolls, parsers, policies, fillOpts, report)
 	return
 }
@@ -0,0 +1,105 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+// VoteSource describes the string a vote was parsed from, it can be used for audit trails: If a vote is disputed
+// the original entry can be shown.
+//
+// Raw is the string as it was given to the parser (or the entry of the PollMatrix, also for votes generated by an
+// EmptyVotePolicy). Row and Column are the indices of the entry in the matrix: Row is the index in PollMatrix.Body
+// and Column the index in this row (thus Head[Column] is the name of the poll). For votes that were not created from
+// a matrix both are -1.
+type VoteSource struct {
+	Raw    string
+	Row    int
+	Column int
+}
+
+// NewVoteSource returns a new VoteSource for a vote parsed from raw that is not part of a matrix, Row and Column are
+// set to -1.
+func NewVoteSource(raw string) *VoteSource {
+	return &VoteSource{
+		Raw:    raw,
+		Row:    -1,
+		Column: -1,
+	}
+}
+
+// HasCell returns true if the vote was created from an entry of a PollMatrix, i.e. Row and Column are set.
+func (source *VoteSource) HasCell() bool {
+	return source.Row >= 0 && source.Column >= 0
+}
+
+// SourcedVote is a vote that can store the VoteSource it was created from.
+//
+// All vote types in this package implement this interface by embedding VoteSourceHolder.
+type SourcedVote interface {
+	AbstractVote
+	GetSource() *VoteSource
+	SetSource(source *VoteSource)
+}
+
+// VoteSourceHolder can be embedded in a vote type to implement SourcedVote.
+//
+// The source is nil by default, it is only set by parsers with KeepRaw set to true (for example
+// BasicVoteParser.KeepRaw) or while filling polls with WithRawVoteSources. Thus the only memory overhead for votes
+// without a source is a nil pointer.
+type VoteSourceHolder struct {
+	Source *VoteSource
+}
+
+// GetSource returns the source of the vote, nil if no source was stored.
+func (holder *VoteSourceHolder) GetSource() *VoteSource {
+	return holder.Source
+}
+
+// SetSource sets the source of the vote.
+func (holder *VoteSourceHolder) SetSource(source *VoteSource) {
+	holder.Source = source
+}
+
+// clone returns a copy of the holder, the source itself is copied as well.
+func (holder VoteSourceHolder) clone() VoteSourceHolder {
+	if holder.Source == nil {
+		return VoteSourceHolder{}
+	}
+	source := *holder.Source
+	return VoteSourceHolder{Source: &source}
+}
+
+// RawVoteString returns the raw string vote was parsed from, the bool is false if vote is not a SourcedVote or no
+// source was stored.
+func RawVoteString(vote AbstractVote) (string, bool) {
+	sourced, ok := vote.(SourcedVote)
+	if !ok {
+		return "", false
+	}
+	source := sourced.GetSource()
+	if source == nil {
+		return "", false
+	}
+	return source.Raw, true
+}
+
+// keepRawSource sets the source of vote to raw if keep is true and vote is a SourcedVote, it returns vote.
+func keepRawSource(vote AbstractVote, raw string, keep bool) AbstractVote {
+	if !keep {
+		return vote
+	}
+	if sourced, ok := vote.(SourcedVote); ok {
+		sourced.SetSource(NewVoteSource(raw))
+	}
+	return vote
+}
@@ -16,15 +16,32 @@ package gopolls
 
 import (
 	"fmt"
+	"math/big"
+	"sort"
+	"strings"
 )
 
 // Voter implements everyone who is allowed to participate in polls.
 //
 // A voter has a name and weight. The weight specifies how much the vote of a certain voter counts (in "normal
 //elections" this is 1).
+//
+// A voter can also carry delegated weight from other voters (proxy voting), see Delegation.
+// Weight is always the voters own weight, EffectiveWeight returns the weight including all delegations, this is the
+// weight used in all tallies.
+//
+// Attributes contains additional information about the voter (for example an email address), it is only set by
+// VotersCSVReader with CaptureAttributes enabled and is not considered by Equals.
+//
+// FractionalWeight is nil for voters with an integer weight. Otherwise it is the exact (fractional) weight of the
+// voter and Weight is this weight rounded down, see VotersParser.FractionalWeights. The integer tallies always use
+// Weight, the fractional weight is only used by the TallyRat methods (see Voter.RatWeight).
 type Voter struct {
-	Name   string
-	Weight Weight
+	Name             string
+	Weight           Weight
+	FractionalWeight *big.Rat
+	Delegations      []Delegation
+	Attributes       map[string]string
 }
 
 // NewVoter creates a new Voter given its name and weight.
@@ -36,13 +53,47 @@ func NewVoter(name string, weight Weight) *Voter {
 }
 
 // Format returns a formatted string (one that can be parsed back with the voters parsing methods).
+//
+// Delegations are written in the form "(+Bob: 1, +Carol: 2)", to parse them back VotersParser.ParseDelegations must
+// be enabled. A FractionalWeight is written in decimal notation (see FormatRatWeight), to parse it back
+// VotersParser.FractionalWeights must be enabled.
 func (voter *Voter) Format(indent string) string {
-	return fmt.Sprintf("%s* %s: %d", indent, voter.Name, voter.Weight)
+	res := fmt.Sprintf("%s* %s: %d", indent, voter.Name, voter.Weight)
+	if voter.FractionalWeight != nil {
+		res = fmt.Sprintf("%s* %s: %s", indent, voter.Name, FormatRatWeight(voter.FractionalWeight))
+	}
+	if len(voter.Delegations) > 0 {
+		delegations := make([]string, len(voter.Delegations))
+		for i, delegation := range voter.Delegations {
+			delegations[i] = fmt.Sprintf("+%s: %d", delegation.Source, delegation.Weight)
+		}
+		res += " (" + strings.Join(delegations, ", ") + ")"
+	}
+	return res
 }
 
-// Equals tests if two voters are equal (have the same name and weight).
+// Equals tests if two voters are equal (have the same name, weight, fractional weight and delegations).
 func (voter *Voter) Equals(other *Voter) bool {
-	return voter.Name == other.Name && voter.Weight == other.Weight
+	if voter.Name != other.Name || voter.Weight != other.Weight || len(voter.Delegations) != len(other.Delegations) {
+		return false
+	}
+	if !ratWeightsEqual(voter.FractionalWeight, other.FractionalWeight) {
+		return false
+	}
+	for i, delegation := range voter.Delegations {
+		if delegation != other.Delegations[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// votersEqual tests if two voters are equal with Voter.Equals, two nil voters are considered equal.
+func votersEqual(a, b *Voter) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equals(b)
 }
 
 // HasDuplicateVoters tests if there are duplicate names in a given voters list.
@@ -62,14 +113,216 @@ func HasDuplicateVoters(voters []*Voter) (string, bool) {
 // VoterMap is a mapping from user name to a Voter.
 type VoterMap map[string]*Voter
 
+// SortedNames returns the names of all voters in the map, sorted in increasing order.
+//
+// It should be used whenever the iteration order is visible to the user (output or error messages).
+func (voters VoterMap) SortedNames() []string {
+	res := make([]string, 0, len(voters))
+	for name := range voters {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // VotersToMap returns a map from voter name to voter object.
 // If it finds a a duplicate in the names of voters it returns nil and a DuplicateError.
 func VotersToMap(voters []*Voter) (VoterMap, error) {
+	return VotersToMapWithNormalizer(voters, nil)
+}
+
+// VotersToMapChecked returns a map from voter name to voter object and checks for duplicates in a single pass over
+// voters, it should be used instead of calling HasDuplicateVoters and VotersToMap.
+//
+// If a name appears multiple times it returns nil, the first name that was found multiple times and true. Otherwise
+// it returns the map, an empty string and false.
+func VotersToMapChecked(voters []*Voter) (VoterMap, string, bool) {
 	res := make(VoterMap, len(voters))
 	for _, voter := range voters {
 		if _, has := res[voter.Name]; has {
+			return nil, voter.Name, true
+		}
+		res[voter.Name] = voter
+	}
+	return res, "", false
+}
+
+// VotersToMapWithNormalizer works as VotersToMap, but the names are normalized with normalizer (see NameNormalizer),
+// thus duplicates are detected on the normalized names.
+// The voters in voters are never changed: If the normalized name of a voter differs from its name the map contains a
+// shallow copy of the voter with the normalized name (delegations and attributes are shared with the original), so
+// that the keys of the map and the names of the voters are always the same (as in VotersParser).
+func VotersToMapWithNormalizer(voters []*Voter, normalizer *NameNormalizer) (VoterMap, error) {
+	res := make(VoterMap, len(voters))
+	for _, voter := range voters {
+		name := normalizer.Normalize(voter.Name)
+		if _, has := res[name]; has {
+			return nil, NewDuplicateError(fmt.Sprintf("duplicate entry for user %s", name))
+		}
+		if name != voter.Name {
+			normalized := *voter
+			normalized.Name = name
+			voter = &normalized
+		}
+		res[name] = voter
+	}
+	return res, nil
+}
+
+// DuplicateVoterPolicy describes what should happen if a voter name appears multiple times in a list of voters.
+//
+// RejectDuplicateVoters returns a DuplicateError (this is what VotersToMap does).
+// MergeDuplicateVoters sums up the weights of all entries with the same name.
+// KeepFirstDuplicateVoter keeps only the first entry and KeepLastDuplicateVoter only the last entry with the same
+// name.
+//
+// NoDuplicateVoterCheck is a special value that disables the check, for example in VotersParser. It is not a valid
+// argument for VotersToMapWithPolicy or ResolveDuplicateVoters. It is the zero value, thus a parser that was not
+// created with its constructor doesn't check for duplicates.
+type DuplicateVoterPolicy int8
+
+const (
+	NoDuplicateVoterCheck DuplicateVoterPolicy = iota
+	RejectDuplicateVoters
+	MergeDuplicateVoters
+	KeepFirstDuplicateVoter
+	KeepLastDuplicateVoter
+)
+
+// ZeroWeightPolicy describes how voters with weight 0 (see Voter.HasZeroWeight) are handled.
+//
+// AllowZeroWeight treats them as all other voters: they can vote and are counted as voters (for example in
+// BasicPollResult.NumberVoters) but don't contribute to any weight. This is the default everywhere.
+// RejectZeroWeightAtParse rejects them with a PollingSemanticError in VotersParser and PollMatrix.FillPollsWithVotes,
+// when tallying it is the same as AllowZeroWeight.
+// ExcludeZeroWeightFromCounts skips their votes in PollMatrix.FillPollsWithVotes (see FillReport) and counts their
+// votes in a separate counter when tallying a BasicPoll (see BasicPoll.TallyWithZeroWeightPolicy).
+type ZeroWeightPolicy int8
+
+const (
+	AllowZeroWeight ZeroWeightPolicy = iota
+	RejectZeroWeightAtParse
+	ExcludeZeroWeightFromCounts
+)
+
+func (policy ZeroWeightPolicy) String() string {
+	switch policy {
+	case AllowZeroWeight:
+		return "allow"
+	case RejectZeroWeightAtParse:
+		return "reject"
+	case ExcludeZeroWeightFromCounts:
+		return "exclude"
+	default:
+		return fmt.Sprintf("ZeroWeightPolicy(%d)", policy)
+	}
+}
+
+// HasZeroWeight returns true if the effective weight of the voter (including delegations) is 0.
+func (voter *Voter) HasZeroWeight() bool {
+	if voter.Weight != 0 || (voter.FractionalWeight != nil && voter.FractionalWeight.Sign() != 0) {
+		return false
+	}
+	for _, delegation := range voter.Delegations {
+		if delegation.Weight != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// zeroWeightVoterNames returns the sorted names of all voters with weight 0.
+func zeroWeightVoterNames(voters VoterMap) []string {
+	res := make([]string, 0)
+	for _, name := range voters.SortedNames() {
+		if voters[name].HasZeroWeight() {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// mergeWeights adds two weights, if the sum would be >= NoWeight a PollingSemanticError is returned.
+func mergeWeights(name string, a, b Weight) (Weight, error) {
+	sum, err := AddWeight(a, b)
+	if err != nil {
+		return NoWeight, NewPollingSemanticError(err, "merged weight for voter \"%s\" is too big (overflow)", name)
+	}
+	return sum, nil
+}
+
+// ResolveDuplicateVoters returns a list of voters with unique names, duplicates are handled according to policy.
+//
+// The order of the voters is retained, an entry appears at the position where the name first occurred.
+// For MergeDuplicateVoters a new Voter object is created for each name that appears multiple times, the original
+// voter objects are never changed. The delegations of the merged voter are the delegations of all entries, the
+// attributes are the attributes of the first entry. If one of the entries has a FractionalWeight the merged voter
+// has the sum of the fractional weights.
+// The delegations of the merged voter are validated with Voter.ValidateDelegations (without a limit on the number
+// of delegations).
+// If merging weights leads to an overflow (sum >= NoWeight) or the merged delegations are invalid a
+// PollingSemanticError is returned, for RejectDuplicateVoters a DuplicateError is returned if a duplicate is found.
+func ResolveDuplicateVoters(voters []*Voter, policy DuplicateVoterPolicy) ([]*Voter, error) {
+	res := make([]*Voter, 0, len(voters))
+	// maps name to the position in res
+	positions := make(map[string]int, len(voters))
+	for _, voter := range voters {
+		pos, has := positions[voter.Name]
+		if !has {
+			positions[voter.Name] = len(res)
+			res = append(res, voter)
+			continue
+		}
+		switch policy {
+		case RejectDuplicateVoters:
 			return nil, NewDuplicateError(fmt.Sprintf("duplicate entry for user %s", voter.Name))
+		case MergeDuplicateVoters:
+			merged, mergeErr := mergeWeights(voter.Name, res[pos].Weight, voter.Weight)
+			if mergeErr != nil {
+				return nil, mergeErr
+			}
+			mergedVoter := NewVoter(voter.Name, merged)
+			if res[pos].FractionalWeight != nil || voter.FractionalWeight != nil {
+				mergedVoter.FractionalWeight = new(big.Rat).Add(res[pos].ownRatWeight(), voter.ownRatWeight())
+				// the integer weight must be the fractional weight rounded down, this can differ from the sum of the
+				// rounded weights
+				if merged, mergeErr = RatToWeight(mergedVoter.FractionalWeight); mergeErr != nil {
+					return nil, NewPollingSemanticError(mergeErr, "merged weight for voter \"%s\" is too big (overflow)",
+						voter.Name)
+				}
+				mergedVoter.Weight = merged
+			}
+			mergedVoter.Delegations = append(append(mergedVoter.Delegations, res[pos].Delegations...),
+				voter.Delegations...)
+			mergedVoter.Attributes = res[pos].Attributes
+			// the delegations of the entries are valid on their own, but the merged voter can have multiple
+			// delegations from the same source or its weight can overflow
+			if delegationsErr := mergedVoter.ValidateDelegations(NoDelegationLimit); delegationsErr != nil {
+				return nil, delegationsErr
+			}
+			res[pos] = mergedVoter
+		case KeepFirstDuplicateVoter:
+			// nothing to do
+		case KeepLastDuplicateVoter:
+			res[pos] = voter
+		default:
+			return nil, NewPollingSemanticError(nil, "invalid duplicate voter policy %d", policy)
 		}
+	}
+	return res, nil
+}
+
+// VotersToMapWithPolicy works as VotersToMap but handles duplicates according to policy, see
+// ResolveDuplicateVoters.
+//
+// VotersToMapWithPolicy(voters, RejectDuplicateVoters) is the same as VotersToMap(voters).
+func VotersToMapWithPolicy(voters []*Voter, policy DuplicateVoterPolicy) (VoterMap, error) {
+	resolved, err := ResolveDuplicateVoters(voters, policy)
+	if err != nil {
+		return nil, err
+	}
+	res := make(VoterMap, len(resolved))
+	for _, voter := range resolved {
 		res[voter.Name] = voter
 	}
 	return res, nil
@@ -0,0 +1,233 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// VoterVotes contains all votes of a single voter, Votes maps the poll name to the vote string.
+//
+// The vote strings are not parsed, see VoterVotesToMatrix to convert a list of VoterVotes to a PollMatrix.
+type VoterVotes struct {
+	Voter string
+	Votes map[string]string
+}
+
+// NewVoterVotes returns a new VoterVotes object without any votes.
+func NewVoterVotes(voter string) *VoterVotes {
+	return &VoterVotes{
+		Voter: voter,
+		Votes: make(map[string]string),
+	}
+}
+
+// VotesKeyValueParser parses the votes of a single voter from a document where each line is of the form
+// "<POLL-NAME>: <VOTE>", for example "Motion: aye".
+// Empty lines and lines starting with "#" are ignored.
+//
+// It can be configured with the same kind of restrictions as VotersParser, a parser returned by
+// NewVotesKeyValueParser sets all of them to -1, meaning no restrictions.
+// MaxNumLines is the number of lines allowed in a document, MaxLineLength the maximal number of bytes in a single
+// line, MaxPollNameLength the maximal length of a poll name, MaxVoteLength the maximal length of a vote string and
+// MaxTotalBytes the maximal number of bytes read from the input.
+// Violations of these restrictions are reported with a ParserValidationError.
+type VotesKeyValueParser struct {
+	MaxNumLines       int
+	MaxLineLength     int
+	MaxPollNameLength int
+	MaxVoteLength     int
+	MaxTotalBytes     int
+}
+
+// NewVotesKeyValueParser returns a new parser with all restrictions disabled.
+func NewVotesKeyValueParser() *VotesKeyValueParser {
+	return &VotesKeyValueParser{
+		MaxNumLines:       -1,
+		MaxLineLength:     -1,
+		MaxPollNameLength: -1,
+		MaxVoteLength:     -1,
+		MaxTotalBytes:     -1,
+	}
+}
+
+func (parser *VotesKeyValueParser) parseLine(voter, line string) (string, string, error) {
+	if !utf8.ValidString(line) {
+		return "", "", ErrInvalidEncoding
+	}
+	// poll names are more likely to contain ":" than votes, so the last one is used
+	index := strings.LastIndex(line, ":")
+	if index < 0 {
+		return "", "", NewPollingSyntaxError(nil, "vote line of voter \"%s\" must be of the form \"poll: vote\"", voter)
+	}
+	pollName, vote := strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+1:])
+	if pollName == "" {
+		return "", "", NewPollingSyntaxError(nil, "vote line of voter \"%s\" has an empty poll name", voter)
+	}
+	if parser.MaxPollNameLength >= 0 && len(pollName) > parser.MaxPollNameLength {
+		return "", "", NewParserValidationError(fmt.Sprintf("poll name is too long: got length %d, allowed max length is %d",
+			len(pollName), parser.MaxPollNameLength))
+	}
+	if parser.MaxVoteLength >= 0 && len(vote) > parser.MaxVoteLength {
+		return "", "", NewParserValidationError(fmt.Sprintf("vote is too long: got length %d, allowed max length is %d",
+			len(vote), parser.MaxVoteLength))
+	}
+	return pollName, vote, nil
+}
+
+// Parse parses the votes of the voter with the given name from r.
+//
+// If a poll appears multiple times a DuplicateError is returned, syntax errors are reported as PollingSyntaxError
+// and violated restrictions as ParserValidationError. All other errors from reading r are returned directly.
+// A utf-8 byte order mark at the beginning of r and "\r\n" line endings are allowed.
+func (parser *VotesKeyValueParser) Parse(voter string, r io.Reader) (*VoterVotes, error) {
+	limited := newMaxBytesReader(r, parser.MaxTotalBytes)
+	scanner := newLineScanner(limited, parser.MaxLineLength, DefaultBufferSize, DefaultMaxBufferSize)
+	res := NewVoterVotes(voter)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if parser.MaxNumLines >= 0 && lineNum > parser.MaxNumLines {
+			return nil, NewParserValidationError(fmt.Sprintf("there are too many lines: only %d lines in votes files are allowed", parser.MaxNumLines))
+		}
+		line := cleanInputLine(scanner.Text())
+		if parser.MaxLineLength >= 0 && len(line) > parser.MaxLineLength {
+			return nil, NewParserValidationError(fmt.Sprintf("line is too long: got line of length %d, allowed max length is %d",
+				len(line), parser.MaxLineLength))
+		}
+		if isIgnoredLine(line, DefaultCommentPrefixes) {
+			continue
+		}
+		pollName, vote, lineErr := parser.parseLine(voter, line)
+		if lineErr != nil {
+			// the line might be cut off because of MaxTotalBytes, report this instead
+			return nil, limited.checkErr(convertParserErr(lineErr, lineNum))
+		}
+		if _, has := res.Votes[pollName]; has {
+			return nil, NewDuplicateError(fmt.Sprintf("poll \"%s\" was found multiple times in votes of voter \"%s\"",
+				pollName, voter))
+		}
+		res.Votes[pollName] = vote
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, lineTooLongError(parser.MaxLineLength, DefaultMaxBufferSize)
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// ParseFromString works like Parse but reads from a string.
+func (parser *VotesKeyValueParser) ParseFromString(voter, s string) (*VoterVotes, error) {
+	return parser.Parse(voter, strings.NewReader(s))
+}
+
+// VotesDirectoryFileExtension is the file extension of the files read by ReadVotesDirectory.
+const VotesDirectoryFileExtension = ".txt"
+
+// ReadVotesDirectory reads the votes of all voters from a directory, each voter has a file "<VOTER-NAME>.txt"
+// that is parsed with parser (see VotesKeyValueParser).
+// Files with another extension and sub directories are ignored.
+//
+// The result is sorted by voter name. All errors from parser and errors reading the directory are returned.
+func ReadVotesDirectory(dir string, parser *VotesKeyValueParser) ([]*VoterVotes, error) {
+	entries, readErr := ioutil.ReadDir(dir)
+	if readErr != nil {
+		return nil, readErr
+	}
+	res := make([]*VoterVotes, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != VotesDirectoryFileExtension {
+			continue
+		}
+		voter := strings.TrimSuffix(entry.Name(), VotesDirectoryFileExtension)
+		votes, votesErr := readVotesFile(filepath.Join(dir, entry.Name()), voter, parser)
+		if votesErr != nil {
+			return nil, votesErr
+		}
+		res = append(res, votes)
+	}
+	// ReadDir already sorts by file name, but "a.txt" < "a b.txt" doesn't have to hold for the names
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Voter < res[j].Voter
+	})
+	return res, nil
+}
+
+func readVotesFile(path, voter string, parser *VotesKeyValueParser) (*VoterVotes, error) {
+	f, openErr := os.Open(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer f.Close()
+	return parser.Parse(voter, f)
+}
+
+// VoterVotesToMatrix combines the votes of many voters into a PollMatrix, the matrix can then be used with
+// PollMatrix.FillPollsWithVotes.
+//
+// The head of the matrix contains all polls from polls (sorted by name), each row contains the votes of one voter
+// (in the order of votes). If a voter didn't vote for a poll the entry is empty, thus the EmptyVotePolicy of the
+// poll is applied by FillPollsWithVotes.
+//
+// If a voter voted for a poll that is not in polls a PollingSemanticError is returned, if a voter appears multiple
+// times a DuplicateError is returned.
+func VoterVotesToMatrix(votes []*VoterVotes, polls PollMap) (*PollMatrix, error) {
+	pollNames := polls.SortedNames()
+
+	head := make([]string, 0, len(pollNames)+1)
+	head = append(head, "voter")
+	head = append(head, pollNames...)
+	body := make([][]string, 0, len(votes))
+
+	voterNames := make(map[string]struct{}, len(votes))
+	for _, voterVotes := range votes {
+		if _, has := voterNames[voterVotes.Voter]; has {
+			return nil, NewDuplicateError(fmt.Sprintf("voter \"%s\" was found multiple times", voterVotes.Voter))
+		}
+		voterNames[voterVotes.Voter] = struct{}{}
+		votedPolls := make([]string, 0, len(voterVotes.Votes))
+		for pollName := range voterVotes.Votes {
+			votedPolls = append(votedPolls, pollName)
+		}
+		sort.Strings(votedPolls)
+		for _, pollName := range votedPolls {
+			if _, has := polls[pollName]; !has {
+				return nil, NewPollingSemanticError(nil, "poll \"%s\" in votes of voter \"%s\" not found in allowed polls",
+					pollName, voterVotes.Voter)
+			}
+		}
+		row := make([]string, 0, len(head))
+		row = append(row, voterVotes.Voter)
+		for _, pollName := range pollNames {
+			row = append(row, voterVotes.Votes[pollName])
+		}
+		body = append(body, row)
+	}
+	return &PollMatrix{
+		Head: head,
+		Body: body,
+	}, nil
+}
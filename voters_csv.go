@@ -0,0 +1,239 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultVotersNameColumn is the default name of the column that contains the voter names, see VotersCSVReader.
+	DefaultVotersNameColumn = "name"
+	// DefaultVotersWeightColumn is the default name of the column that contains the voter weights, see
+	// VotersCSVReader.
+	DefaultVotersWeightColumn = "weight"
+)
+
+// VotersCSVReader reads voters from a CSV file, this is an alternative to the voters format parsed by VotersParser.
+//
+// The first row of the file must be a head that describes the columns. The column with the name NameColumn (compared
+// case-insensitively) is required and contains the voter names. The column WeightColumn is optional, if it doesn't
+// exist or an entry is empty the weight of the voter is 1. All other columns are ignored, unless CaptureAttributes is
+// true: In this case they're stored in Voter.Attributes (with the column name from the head as key).
+// For example the head "name;weight;email" with CaptureAttributes set stores the email of each voter in
+// Attributes["email"].
+//
+// The same restrictions as in VotersParser can be configured: MaxNumVoters, MaxVotersNameLength (in runes),
+// MaxVotersWeight, MaxTotalWeight and MaxTotalBytes (see VotersParser for details), the reader returned by
+// NewVotersCSVReader disables all of them.
+// DuplicatePolicy describes what happens if a voter name appears multiple times, see ResolveDuplicateVoters. It
+// defaults to NoDuplicateVoterCheck.
+//
+// NameNormalizer is applied to the name of each voter before it is validated and duplicates are detected, as in
+// VotersParser. It is nil by default, so names are not changed.
+type VotersCSVReader struct {
+	Sep                 rune
+	NameColumn          string
+	WeightColumn        string
+	CaptureAttributes   bool
+	csv                 *csv.Reader
+	limited             *maxBytesReader
+	MaxNumVoters        int
+	MaxVotersNameLength int
+	MaxVotersWeight     Weight
+	MaxTotalWeight      Weight
+	MaxTotalBytes       int
+	DuplicatePolicy     DuplicateVoterPolicy
+	NameNormalizer      *NameNormalizer
+}
+
+// NewVotersCSVReader returns a VotersCSVReader reading from r.
+//
+// The column names are set to DefaultVotersNameColumn and DefaultVotersWeightColumn, all restrictions are disabled.
+// A utf-8 byte order mark at the beginning of r is ignored.
+func NewVotersCSVReader(r io.Reader) *VotersCSVReader {
+	// the limit is set in ReadVoters
+	limited := newMaxBytesReader(r, -1)
+	reader := csv.NewReader(newBOMStrippingReader(limited))
+	return &VotersCSVReader{
+		Sep:                 DefaultCSVSeparator,
+		NameColumn:          DefaultVotersNameColumn,
+		WeightColumn:        DefaultVotersWeightColumn,
+		CaptureAttributes:   false,
+		csv:                 reader,
+		limited:             limited,
+		MaxNumVoters:        -1,
+		MaxVotersNameLength: -1,
+		MaxVotersWeight:     NoWeight,
+		MaxTotalWeight:      NoWeight,
+		MaxTotalBytes:       -1,
+		DuplicatePolicy:     NoDuplicateVoterCheck,
+		NameNormalizer:      nil,
+	}
+}
+
+// votersCSVColumns describes the position of the columns in the head, weight is -1 if there is no weight column.
+type votersCSVColumns struct {
+	name, weight int
+	head         []string
+}
+
+func (r *VotersCSVReader) readHead() (*votersCSVColumns, error) {
+	head, err := r.csv.Read()
+	if err == io.EOF {
+		return nil, NewPollingSyntaxError(nil, "no header found in csv file")
+	}
+	if err != nil {
+		return nil, wrapCSVError(err)
+	}
+	res := &votersCSVColumns{name: -1, weight: -1, head: make([]string, len(head))}
+	seen := make(map[string]struct{}, len(head))
+	for i, column := range head {
+		if !utf8.ValidString(column) {
+			return nil, ErrInvalidEncoding
+		}
+		column = strings.TrimSpace(column)
+		if _, has := seen[column]; has {
+			return nil, NewDuplicateError(fmt.Sprintf("column \"%s\" was found multiple times in csv head", column))
+		}
+		seen[column] = struct{}{}
+		res.head[i] = column
+		switch {
+		case strings.EqualFold(column, r.NameColumn):
+			res.name = i
+		case r.WeightColumn != "" && strings.EqualFold(column, r.WeightColumn):
+			res.weight = i
+		}
+	}
+	if res.name < 0 {
+		return nil, NewPollingSyntaxError(nil, "csv head must contain the column \"%s\"", r.NameColumn)
+	}
+	return res, nil
+}
+
+// hasAttributes returns true if there is a column other than the name and weight column.
+func (columns *votersCSVColumns) hasAttributes() bool {
+	numColumns := 1
+	if columns.weight >= 0 {
+		numColumns++
+	}
+	return len(columns.head) > numColumns
+}
+
+func (r *VotersCSVReader) parseRecord(record []string, columns *votersCSVColumns) (*Voter, error) {
+	for _, entry := range record {
+		if !utf8.ValidString(entry) {
+			return nil, ErrInvalidEncoding
+		}
+	}
+	name := r.NameNormalizer.Normalize(strings.TrimSpace(record[columns.name]))
+	if name == "" {
+		return nil, NewPollingSyntaxError(nil, "voter name must not be empty")
+	}
+	if r.MaxVotersNameLength >= 0 {
+		nameLength := utf8.RuneCountInString(name)
+		if nameLength > r.MaxVotersNameLength {
+			return nil, NewParserValidationError(fmt.Sprintf("voter name is too long: got length %d, allowed max length is %d",
+				nameLength, r.MaxVotersNameLength))
+		}
+	}
+	var weight Weight = 1
+	if columns.weight >= 0 {
+		if weightString := strings.TrimSpace(record[columns.weight]); weightString != "" {
+			var weightErr error
+			weight, weightErr = ParseWeight(weightString)
+			if weightErr != nil {
+				return nil, NewPollingSyntaxError(weightErr, "weight of voter \"%s\" is not a valid integer (got %s)",
+					name, weightString)
+			}
+		}
+	}
+	if r.MaxVotersWeight != NoWeight && weight > r.MaxVotersWeight {
+		return nil, NewParserValidationError(fmt.Sprintf("voter weight is too big, got %d but max allowed length is %d",
+			weight, r.MaxVotersWeight))
+	}
+	res := NewVoter(name, weight)
+	if r.CaptureAttributes && columns.hasAttributes() {
+		res.Attributes = make(map[string]string, len(record))
+		for i, entry := range record {
+			if i != columns.name && i != columns.weight {
+				res.Attributes[columns.head[i]] = strings.TrimSpace(entry)
+			}
+		}
+	}
+	return res, nil
+}
+
+// ReadVoters reads all voters from the csv file.
+//
+// Each row must have the same number of columns as the head, syntax errors are reported as PollingSyntaxError with
+// the row number (the head is row 1), violated restrictions as ParserValidationError.
+// If the head contains a column name multiple times a DuplicateError is returned.
+// If DuplicatePolicy is set the errors from ResolveDuplicateVoters are returned too.
+// All errors from the underlying reader are returned directly.
+func (r *VotersCSVReader) ReadVoters() (voters []*Voter, err error) {
+	defer func() {
+		if err != nil {
+			// the input might be cut off because of MaxTotalBytes, report this instead
+			err = r.limited.checkErr(err)
+			voters = nil
+		}
+	}()
+	r.csv.Comma = r.Sep
+	r.limited.limit = int64(r.MaxTotalBytes)
+	columns, headErr := r.readHead()
+	if headErr != nil {
+		return nil, convertParserErr(headErr, 1)
+	}
+	res := make([]*Voter, 0)
+	var totalWeight Weight
+	// set to 1 because head has been read already
+	rowNum := 1
+	for {
+		rowNum++
+		record, recordErr := r.csv.Read()
+		if recordErr == io.EOF {
+			break
+		}
+		if recordErr != nil {
+			return nil, wrapCSVError(recordErr)
+		}
+		voter, voterErr := r.parseRecord(record, columns)
+		if voterErr != nil {
+			return nil, convertParserErr(voterErr, rowNum)
+		}
+		res = append(res, voter)
+		if r.MaxNumVoters >= 0 && len(res) > r.MaxNumVoters {
+			return nil, NewParserValidationError(fmt.Sprintf("there are too many voters: only %d voters are allowed", r.MaxNumVoters))
+		}
+		var weightErr error
+		if totalWeight, weightErr = AddWeight(totalWeight, voter.Weight); weightErr != nil {
+			return nil, NewParserValidationError(fmt.Sprintf("sum of voter weights is too big in row %d: %s",
+				rowNum, weightErr))
+		}
+		if r.MaxTotalWeight != NoWeight && totalWeight > r.MaxTotalWeight {
+			return nil, NewParserValidationError(fmt.Sprintf("sum of voter weights is too big: got at least %d, allowed max sum is %d",
+				totalWeight, r.MaxTotalWeight))
+		}
+	}
+	if r.DuplicatePolicy != NoDuplicateVoterCheck {
+		return ResolveDuplicateVoters(res, r.DuplicatePolicy)
+	}
+	return res, nil
+}
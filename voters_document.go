@@ -0,0 +1,291 @@
+// Copyright 2021 Fabian Wenzelmann <fabianwen@posteo.eu>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gopolls
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// VotersEntryKind describes the kind of a line in a VotersDocument.
+type VotersEntryKind int8
+
+const (
+	VoterLineEntry VotersEntryKind = iota
+	CommentLineEntry
+	BlankLineEntry
+)
+
+func (kind VotersEntryKind) String() string {
+	switch kind {
+	case VoterLineEntry:
+		return "voter"
+	case CommentLineEntry:
+		return "comment"
+	case BlankLineEntry:
+		return "blank"
+	default:
+		return fmt.Sprintf("VotersEntryKind(%d)", kind)
+	}
+}
+
+// VotersDocumentEntry is a single line of a VotersDocument.
+//
+// LineNum is the number of the line in the parsed input (starting with 1), Raw the original line without the line
+// ending and Ending the original line ending ("\n", "\r\n" or "" for the last line if the input doesn't end with a
+// line ending).
+// Voter is only set for entries of kind VoterLineEntry. It can be changed (see VotersDocument.UpdateVoter), the line
+// is regenerated by VotersDocument.Write if the voter is no longer equal (see Voter.Equals) to the parsed voter.
+type VotersDocumentEntry struct {
+	Kind    VotersEntryKind
+	LineNum int
+	Raw     string
+	Ending  string
+	Voter   *Voter
+	// original is a copy of the parsed voter, used to detect changes
+	original *Voter
+	// inlineComment is the inline comment of a voter line (including the comment prefix), it is kept when the line
+	// is regenerated
+	inlineComment string
+}
+
+// Modified returns true if the entry is a voter line and the voter has been changed since parsing.
+func (entry *VotersDocumentEntry) Modified() bool {
+	if entry.Kind != VoterLineEntry {
+		return false
+	}
+	return !votersEqual(entry.Voter, entry.original)
+}
+
+// line returns the line that is written for the entry (without the line ending).
+func (entry *VotersDocumentEntry) line() string {
+	if !entry.Modified() {
+		return entry.Raw
+	}
+	res := entry.Voter.Format("")
+	if entry.inlineComment != "" {
+		res += " " + entry.inlineComment
+	}
+	return res
+}
+
+// VotersDocument is a lossless representation of a voters file, it is returned by VotersParser.ParseVotersDocument.
+//
+// In contrast to ParseVoters it keeps all lines of the file (voters, comments and blank lines) in Entries, in the
+// order in which they appear in the file. Voters can be changed with FindVoter and UpdateVoter, Write writes the
+// document back: All lines that have not been changed are written byte-for-byte as in the input, lines of changed
+// voters are regenerated in the canonical format of Voter.Format (an inline comment of such a line is kept).
+// Thus writing a document that has not been changed reproduces the input exactly.
+//
+// BOM is true if the input started with a utf-8 byte order mark, it is written again by Write.
+type VotersDocument struct {
+	Entries []*VotersDocumentEntry
+	BOM     bool
+}
+
+// Voters returns the voters of all voter lines (in the order in which they appear in the document).
+//
+// The voters are not copied, so changes are visible to the document.
+func (doc *VotersDocument) Voters() []*Voter {
+	res := make([]*Voter, 0, len(doc.Entries))
+	for _, entry := range doc.Entries {
+		if entry.Kind == VoterLineEntry {
+			res = append(res, entry.Voter)
+		}
+	}
+	return res
+}
+
+// FindEntry returns the first voter entry for the voter with the given name or nil if there is no such voter.
+func (doc *VotersDocument) FindEntry(name string) *VotersDocumentEntry {
+	for _, entry := range doc.Entries {
+		if entry.Kind == VoterLineEntry && entry.Voter.Name == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+// FindVoter returns the first voter with the given name or nil if there is no such voter.
+func (doc *VotersDocument) FindVoter(name string) *Voter {
+	if entry := doc.FindEntry(name); entry != nil {
+		return entry.Voter
+	}
+	return nil
+}
+
+// UpdateVoter calls update with the first voter with the given name, update can change the voter (for example the
+// weight or the name). The line of the voter is regenerated by Write if the voter has been changed.
+//
+// If there is no voter with the given name a PollingSemanticError is returned.
+func (doc *VotersDocument) UpdateVoter(name string, update func(voter *Voter)) error {
+	entry := doc.FindEntry(name)
+	if entry == nil {
+		return NewPollingSemanticError(nil, "no voter with name \"%s\" found in document", name)
+	}
+	update(entry.Voter)
+	return nil
+}
+
+// Write writes the document to w, see VotersDocument for details.
+//
+// It returns any error writing to w.
+func (doc *VotersDocument) Write(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+	if doc.BOM {
+		if _, err := buffered.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+	for _, entry := range doc.Entries {
+		if _, err := buffered.WriteString(entry.line()); err != nil {
+			return err
+		}
+		if _, err := buffered.WriteString(entry.Ending); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// String returns the document as it is written by Write.
+func (doc *VotersDocument) String() string {
+	var builder strings.Builder
+	// writing to a strings.Builder never fails
+	_ = doc.Write(&builder)
+	return builder.String()
+}
+
+// copyVoter returns a copy of voter with a copy of the delegations and the fractional weight, Attributes are not
+// copied.
+func copyVoter(voter *Voter) *Voter {
+	res := NewVoter(voter.Name, voter.Weight)
+	if voter.FractionalWeight != nil {
+		res.FractionalWeight = new(big.Rat).Set(voter.FractionalWeight)
+	}
+	if voter.Delegations != nil {
+		res.Delegations = make([]Delegation, len(voter.Delegations))
+		copy(res.Delegations, voter.Delegations)
+	}
+	return res
+}
+
+// scanRawLines is a bufio.SplitFunc that works like bufio.ScanLines but keeps the line endings.
+func scanRawLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// splitLineEnding splits a line as returned by scanRawLines into the content and the line ending.
+func splitLineEnding(raw string) (line, ending string) {
+	switch {
+	case strings.HasSuffix(raw, "\r\n"):
+		return raw[:len(raw)-2], "\r\n"
+	case strings.HasSuffix(raw, "\n"):
+		return raw[:len(raw)-1], "\n"
+	default:
+		return raw, ""
+	}
+}
+
+// ParseVotersDocument parses a voters file as a VotersDocument, see there for details.
+//
+// The lines are validated and parsed exactly as in ParseVoters (with all limits of the parser), the only
+// difference is that all lines are kept. DuplicatePolicy is only used to validate the voters: If it is
+// RejectDuplicateVoters a DuplicateError is returned for duplicate names, all other policies are ignored (all
+// entries are kept in the document, use ResolveDuplicateVoters on Voters if required).
+// Note that the NameNormalizer changes the names of the voters, but not the lines that are written by Write.
+// If SourceName is set it is added to all errors, see VotersParser.
+func (parser *VotersParser) ParseVotersDocument(r io.Reader) (*VotersDocument, error) {
+	res, err := parser.parseVotersDocument(r)
+	if err != nil {
+		return nil, withSourceName(err, parser.SourceName)
+	}
+	return res, nil
+}
+
+func (parser *VotersParser) parseVotersDocument(r io.Reader) (*VotersDocument, error) {
+	limited := newMaxBytesReader(r, parser.MaxTotalBytes)
+	scanner := bufio.NewScanner(limited)
+	initial, max := scannerBufferSizes(parser.MaxLineLength, parser.BufferSize, parser.MaxBufferSize)
+	scanner.Buffer(make([]byte, initial), max)
+	scanner.Split(scanRawLines)
+	res := &VotersDocument{
+		Entries: make([]*VotersDocumentEntry, 0),
+		BOM:     false,
+	}
+	state := &votersFileState{}
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		raw := scanner.Text()
+		if lineNum == 1 && strings.HasPrefix(raw, string(utf8BOM)) {
+			res.BOM = true
+			raw = raw[len(utf8BOM):]
+		}
+		line, ending := splitLineEnding(raw)
+		// validate the line as in ParseVoters, but keep the original line in the entry
+		voter, lineErr := parser.parseVotersFileLine(cleanInputLine(line), lineNum, state, limited)
+		if lineErr != nil {
+			return nil, lineErr
+		}
+		entry := &VotersDocumentEntry{
+			LineNum: lineNum,
+			Raw:     line,
+			Ending:  ending,
+		}
+		switch {
+		case voter != nil:
+			entry.Kind = VoterLineEntry
+			entry.Voter = voter
+			entry.original = copyVoter(voter)
+			if parser.AllowInlineComments {
+				cleaned := cleanInputLine(line)
+				entry.inlineComment = cleaned[len(stripInlineComment(cleaned, parser.getCommentPrefixes())):]
+			}
+		case strings.TrimSpace(line) == "":
+			entry.Kind = BlankLineEntry
+		default:
+			entry.Kind = CommentLineEntry
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, parser.convertScanErr(err)
+	}
+	if parser.DuplicatePolicy == RejectDuplicateVoters {
+		if name, hasDuplicate := HasDuplicateVoters(res.Voters()); hasDuplicate {
+			return nil, NewDuplicateError(fmt.Sprintf("duplicate entry for user %s", name))
+		}
+	}
+	return res, nil
+}
+
+// ParseVotersDocumentFromString works like ParseVotersDocument but reads from a string.
+func (parser *VotersParser) ParseVotersDocumentFromString(s string) (*VotersDocument, error) {
+	return parser.ParseVotersDocument(strings.NewReader(s))
+}